@@ -15,6 +15,7 @@ import (
 	"syscall"
 	"unsafe"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
 	"golang.org/x/sys/windows"
 )
 
@@ -523,7 +524,7 @@ func deletePlaceholders(rootPath string, dryRun bool) (deleted, preserved, error
 		}
 
 		// Remove read-only attribute if set before deleting
-		pathPtr, _ := windows.UTF16PtrFromString(path)
+		pathPtr, _ := windows.UTF16PtrFromString(longpath.Fix(path))
 		attrs, attrErr := windows.GetFileAttributes(pathPtr)
 		if attrErr == nil && attrs&windows.FILE_ATTRIBUTE_READONLY != 0 {
 			windows.SetFileAttributes(pathPtr, attrs&^windows.FILE_ATTRIBUTE_READONLY)
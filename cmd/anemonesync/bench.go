@@ -0,0 +1,217 @@
+// Scanner benchmark for AnemoneSync CLI.
+// Measures local scan performance so "sync is slow" reports come with objective numbers.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"go.uber.org/zap"
+)
+
+// benchUpsertBatchSize matches the batch size used by the scanner for DB updates.
+const benchUpsertBatchSize = 500
+
+// benchPhase holds the measurements of one benchmark phase.
+type benchPhase struct {
+	Name     string
+	Count    int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// runBenchScan walks, hashes and records the files under path, reporting the
+// throughput of each phase. The user's database is never touched: DB upserts
+// are measured against a temporary database.
+// If profilePath is set, a CPU profile covering all phases is written there.
+func runBenchScan(path string, profilePath string, logger *zap.Logger) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to access path: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", path)
+	}
+
+	// Use the configured hash settings so the numbers match real syncs
+	hashAlgorithm, bufferSizeMB := "sha256", 4
+	if cfg, err := config.Load(""); err == nil {
+		hashAlgorithm = cfg.Sync.Performance.HashAlgorithm
+		bufferSizeMB = cfg.Sync.Performance.BufferSizeMB
+	}
+
+	if profilePath != "" {
+		f, err := os.Create(profilePath)
+		if err != nil {
+			return fmt.Errorf("failed to create profile file: %w", err)
+		}
+		defer f.Close()
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	fmt.Fprintf(statusOut, "Benchmarking scan of: %s\n", path)
+	fmt.Fprintf(statusOut, "System: %s/%s, %d CPUs, hash=%s, buffer=%d MB\n\n",
+		runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), hashAlgorithm, bufferSizeMB)
+
+	// Phase 1: directory walk
+	fmt.Fprintln(statusOut, "[1/3] Walking directory tree...")
+	var files []*scanner.FileMetadata
+	walker := scanner.NewWalker(nil, logger)
+	walk := benchPhase{Name: "Walk"}
+	start := time.Now()
+	err = walker.Walk(0, path, func(filePath string, metadata *scanner.FileMetadata) error {
+		files = append(files, metadata)
+		walk.Bytes += metadata.Size
+		return nil
+	})
+	walk.Duration = time.Since(start)
+	walk.Count = len(files)
+	if err != nil {
+		return fmt.Errorf("walk failed: %w", err)
+	}
+	walkStats := walker.GetStatistics()
+
+	// Phase 2: hashing (placeholders are skipped to avoid triggering hydration)
+	fmt.Fprintf(statusOut, "[2/3] Hashing %d files...\n", len(files))
+	hasher := scanner.NewHasher(hashAlgorithm, bufferSizeMB, logger)
+	hash := benchPhase{Name: "Hash"}
+	hashErrors := 0
+	hashes := make(map[string]string, len(files))
+	start = time.Now()
+	for _, metadata := range files {
+		if metadata.IsPlaceholder {
+			continue
+		}
+		result, err := hasher.ComputeHash(metadata.Path)
+		if err != nil {
+			hashErrors++
+			continue
+		}
+		hashes[metadata.Path] = result.Hash
+		hash.Count++
+		hash.Bytes += result.Size
+	}
+	hash.Duration = time.Since(start)
+
+	// Phase 3: DB upserts into a throwaway database
+	fmt.Fprintf(statusOut, "[3/3] Writing %d file states to a temporary database...\n", len(files))
+	upsert, err := benchUpserts(path, files, hashes, logger)
+	if err != nil {
+		return fmt.Errorf("database benchmark failed: %w", err)
+	}
+
+	// Report
+	fmt.Println()
+	fmt.Println("Results:")
+	fmt.Println("--------")
+	fmt.Printf("  Files:       %d (%d directories, %d errors, %d placeholders skipped for hashing)\n",
+		walk.Count, walkStats.TotalDirs, walkStats.Errors+hashErrors, walk.Count-hash.Count-hashErrors)
+	fmt.Printf("  Total size:  %s\n", formatBytes(walk.Bytes))
+	printBenchPhase(walk, "files/s", false)
+	printBenchPhase(hash, "files/s", true)
+	printBenchPhase(upsert, "rows/s", false)
+
+	if profilePath != "" {
+		fmt.Printf("\nCPU profile written to: %s\n", profilePath)
+		fmt.Println("Attach it to your issue, or inspect it with: go tool pprof " + profilePath)
+	}
+
+	return nil
+}
+
+// benchUpserts measures BulkUpdateFileStates throughput on a temporary database.
+func benchUpserts(basePath string, files []*scanner.FileMetadata, hashes map[string]string, logger *zap.Logger) (benchPhase, error) {
+	phase := benchPhase{Name: "DB upsert"}
+
+	tmpDir, err := os.MkdirTemp("", "anemonesync-bench-")
+	if err != nil {
+		return phase, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := database.Open(database.Config{
+		Path:             filepath.Join(tmpDir, "bench.db"),
+		EncryptionKey:    "AnemoneSync_Bench",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		return phase, err
+	}
+	defer db.Close()
+
+	job := &database.SyncJob{
+		Name:               "bench",
+		LocalPath:          basePath,
+		RemotePath:         `\\bench\share`,
+		ServerCredentialID: "bench",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		return phase, fmt.Errorf("failed to create bench job: %w", err)
+	}
+
+	now := time.Now().Unix()
+	states := make([]*database.FileState, 0, benchUpsertBatchSize)
+	start := time.Now()
+	for _, metadata := range files {
+		states = append(states, &database.FileState{
+			JobID:      job.ID,
+			LocalPath:  metadata.Path,
+			RemotePath: metadata.Path,
+			Size:       metadata.Size,
+			MTime:      metadata.MTime.Unix(),
+			Hash:       hashes[metadata.Path],
+			LastSync:   &now,
+			SyncStatus: "idle",
+		})
+		if len(states) == benchUpsertBatchSize {
+			if err := db.BulkUpdateFileStates(states); err != nil {
+				return phase, err
+			}
+			states = states[:0]
+		}
+	}
+	if len(states) > 0 {
+		if err := db.BulkUpdateFileStates(states); err != nil {
+			return phase, err
+		}
+	}
+	phase.Duration = time.Since(start)
+	phase.Count = len(files)
+
+	logger.Debug("bench upserts completed",
+		zap.Int("rows", phase.Count),
+		zap.Duration("duration", phase.Duration))
+
+	return phase, nil
+}
+
+// printBenchPhase prints one result line: duration, item rate and optional byte rate.
+func printBenchPhase(phase benchPhase, unit string, withBytes bool) {
+	seconds := phase.Duration.Seconds()
+	rate, byteRate := 0.0, 0.0
+	if seconds > 0 {
+		rate = float64(phase.Count) / seconds
+		byteRate = float64(phase.Bytes) / seconds
+	}
+
+	line := fmt.Sprintf("  %-11s  %10s  %10.0f %s", phase.Name+":", phase.Duration.Round(time.Millisecond), rate, unit)
+	if withBytes {
+		line += fmt.Sprintf("  %s/s", formatBytes(int64(byteRate)))
+	}
+	fmt.Println(line)
+}
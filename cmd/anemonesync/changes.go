@@ -0,0 +1,141 @@
+// Report of the files changed by the syncs of a job in a time window.
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+// defaultChangesWindow is the window of "changes" without --since.
+const defaultChangesWindow = 24 * time.Hour
+
+// changeLabels describes the actions listed by "changes", from the point of
+// view of the local folder.
+var changeLabels = map[cache.SyncAction]string{
+	cache.ActionUpload:       "uploaded",
+	cache.ActionDownload:     "downloaded",
+	cache.ActionDeleteLocal:  "deleted here",
+	cache.ActionDeleteRemote: "deleted on server",
+	cache.ActionRenameLocal:  "moved here",
+	cache.ActionRenameRemote: "moved on server",
+}
+
+// parseSince returns the start of the window given to --since: a duration
+// back from now ("90m", "24h", "7d") or a local date ("2025-01-31",
+// "2025-01-31 14:00").
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value '%s' (use e.g. 24h, 7d or 2025-01-31)", value)
+}
+
+// runChanges lists the files uploaded, downloaded, moved or deleted by the syncs of
+// a job since a time.
+func runChanges(db *database.DB, jobID int64, since time.Time) error {
+	job, err := db.GetSyncJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job with ID %d not found", jobID)
+	}
+
+	records, err := db.GetSyncActionsSince(jobID, since)
+	if err != nil {
+		return fmt.Errorf("failed to get sync actions: %w", err)
+	}
+
+	fmt.Printf("Files changed by \"%s\" since %s\n", job.Name, since.Format("2006-01-02 15:04"))
+	if time.Since(since) > database.SyncActionRetention {
+		fmt.Printf("(actions are kept %d days: older changes are not listed)\n", int(database.SyncActionRetention.Hours()/24))
+	}
+	fmt.Println()
+
+	counts := make(map[cache.SyncAction]int)
+	for _, r := range records {
+		action := cache.SyncAction(r.Action)
+		label, listed := changeLabels[action]
+		if !listed || r.Status != string(sync.ActionStatusSuccess) {
+			continue
+		}
+		counts[action]++
+
+		size := ""
+		if r.Bytes > 0 {
+			size = formatBytes(r.Bytes)
+		}
+		fmt.Printf("%s  %-17s %10s  %s%s\n", r.Timestamp.Format("2006-01-02 15:04:05"), label, size, r.Path, changedBy(r.ChangedBy))
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		fmt.Println("No files changed.")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Total: %d files (%d uploaded, %d downloaded, %d moved, %d deleted here, %d deleted on server)\n",
+		total, counts[cache.ActionUpload], counts[cache.ActionDownload],
+		counts[cache.ActionRenameLocal]+counts[cache.ActionRenameRemote],
+		counts[cache.ActionDeleteLocal], counts[cache.ActionDeleteRemote])
+
+	return printConflicts(db, jobID, since)
+}
+
+// printConflicts lists the conflicts of a job since a time whose losing
+// version was kept as a copy.
+func printConflicts(db *database.DB, jobID int64, since time.Time) error {
+	conflicts, err := db.GetConflicts(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get conflicts: %w", err)
+	}
+
+	printed := false
+	for i := len(conflicts) - 1; i >= 0; i-- { // Oldest first, like the changes
+		c := conflicts[i]
+		if c.Timestamp.Before(since) {
+			continue
+		}
+		if !printed {
+			fmt.Println()
+			fmt.Println("Conflicts (the other version was kept as a copy):")
+			printed = true
+		}
+		kept := "server version kept as"
+		if c.Winner == "remote" {
+			kept = "your version kept as"
+		}
+		fmt.Printf("%s  %s  (%s %s)%s\n", c.Timestamp.Format("2006-01-02 15:04:05"), c.Path, kept, c.CopyPath, changedBy(c.ChangedBy))
+	}
+	return nil
+}
+
+// changedBy describes who changed the server version of a file ("" if not
+// recorded).
+func changedBy(owner string) string {
+	if owner == "" {
+		return ""
+	}
+	return "  changed by " + owner
+}
@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,9 @@ import (
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
 )
@@ -22,13 +26,61 @@ import (
 // CLIOptions represents parsed command-line options.
 type CLIOptions struct {
 	ListJobs       bool
-	SyncJobID      int64 // 0 = not set
+	SyncJobID      int64  // 0 = not set
+	SyncPath       string // Folder inside the job for --sync, "" = whole job
+	Approve        bool   // --approve for --sync: run a job paused until its changes are approved
 	SyncAll        bool
-	DehydrateJobID int64 // 0 = not set
-	DehydrateDays  int   // -1 = not set (use job default), 0 = all files
+	DehydrateJobID int64        // 0 = not set
+	DehydrateDays  int          // -1 = not set (use job default), 0 = all files
+	BenchScanPath  string       // "" = not set
+	BenchProfile   string       // CPU profile output for --bench-scan
+	Progress       progressMode // "" = auto (bar on a terminal, plain otherwise)
+	Verbosity      verbosity    // -q, -v or -vv, 0 = default
+	NoColor        bool         // --no-color, or NO_COLOR set
+	DBCommand      string       // "backup", "restore", "list", "isolate", "share" or "reassign" for "db <command>"
+	DBArgs         []string     // Backup file for "db backup" (optional) and "db restore", job IDs for "db isolate/share/reassign"
+	FODCommand     string       // "rebuild" for "fod <command>"
+	ConfigCommand  string       // "keygen", "sign" or "verify" for "config <command>"
+	ConfigArgs     []string     // Files for "config <command>"
+	EventLogCmd    string       // "install" or "uninstall" for "eventlog <command>"
+	AuditCommand   string       // "export" or "verify" for "audit <command>"
+	FODArgs        []string     // Job ID for "fod rebuild"
+	Changes        bool         // "changes": files changed by a job's syncs
+	Usage          bool         // "usage": space a job takes on the server, by top-level folder
+	UsageRefresh   bool         // --refresh for "usage": scan the server instead of showing the last scan
+	Warm           bool         // "warm": download the content of a job ahead of use
+	WarmPattern    string       // --pattern for "warm", "" = whole job
+	WarmMaxKBps    int          // --max-kbps for "warm", 0 = config and job limits only
+	Versions       bool         // "versions": previous versions of a file from the server snapshots
+	VersionsPath   string       // File for "versions", relative to the job or full local path
+	VersionRestore int          // --restore for "versions": version to copy next to the file (1 = newest), 0 = list only
+	Manifest       bool         // "manifest": export the file listing of one side of a job, or compare two
+	ManifestArgs   []string     // "compare" and the two manifest files for "manifest compare"
+	ManifestSide   string       // --side for "manifest": local or remote
+	OutFile        string       // --out for "manifest" and "audit export": file to write
+	ManifestKey    string       // --key for "manifest": private key file signing the manifest ("" = checksum only)
+	ManifestPubKey string       // --pubkey for "manifest compare": public key the manifests must be signed with
+	JobID          int64        // --job for "changes", "usage", "warm", "versions", "manifest", "audit export" and the selective sync flags, 0 = not set
+	ChangesSince   time.Time    // --since for "changes" (zero = last 24 hours) and "audit export" (zero = all)
+	IncludePaths   []string     // --include-path: folders added to the selective sync of --job
+	ExcludePaths   []string     // --exclude-path: folders left out of the selective sync of --job
+	ClearPaths     []string     // --clear-path: folders whose selective sync rule is removed
+	Uninstall      bool         // --uninstall-cleanup
+	UninstallOpts  app.UninstallOptions
 	Help           bool
 }
 
+// dataDirArg returns the folder given with --data-dir ("" = not set). Both
+// the GUI and the CLI accept it.
+func dataDirArg(args []string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--data-dir" {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
 // parseCLIArgs parses command-line arguments.
 // Returns nil if no CLI arguments are present (GUI mode).
 func parseCLIArgs(args []string) *CLIOptions {
@@ -69,6 +121,19 @@ func parseCLIArgs(args []string) *CLIOptions {
 				os.Exit(1)
 			}
 
+		case "-p", "--path":
+			// Get next argument as folder relative to the job
+			if i+1 < len(args) {
+				i++
+				opts.SyncPath = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --path requires a folder inside the job\n")
+				os.Exit(1)
+			}
+
+		case "--approve":
+			opts.Approve = true
+
 		case "-d", "--dehydrate":
 			hasCliArg = true
 			// Get next argument as job ID
@@ -100,11 +165,291 @@ func parseCLIArgs(args []string) *CLIOptions {
 				os.Exit(1)
 			}
 
+		case "--bench-scan":
+			hasCliArg = true
+			// Get next argument as directory path
+			if i+1 < len(args) {
+				i++
+				opts.BenchScanPath = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --bench-scan requires a path\n")
+				os.Exit(1)
+			}
+
+		case "--profile":
+			// Get next argument as profile output file
+			if i+1 < len(args) {
+				i++
+				opts.BenchProfile = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --profile requires a file path\n")
+				os.Exit(1)
+			}
+
+		case "--progress":
+			// Get next argument as progress mode
+			if i+1 < len(args) {
+				i++
+				mode, err := parseProgressMode(args[i])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				opts.Progress = mode
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --progress requires a mode (plain, bar or none)\n")
+				os.Exit(1)
+			}
+
+		case "-q", "--quiet", "-v", "--verbose", "-vv":
+			v := verbosityQuiet
+			switch {
+			case arg == "-vv":
+				v = verbosityDebug
+			case arg == "-v" || arg == "--verbose":
+				// Repeating -v also shows the debug messages
+				v = min(opts.Verbosity+1, verbosityDebug)
+			}
+			if (v == verbosityQuiet && opts.Verbosity > verbosityNormal) || (v != verbosityQuiet && opts.Verbosity == verbosityQuiet) {
+				fmt.Fprintf(os.Stderr, "Error: -q cannot be used with -v or -vv\n")
+				os.Exit(1)
+			}
+			opts.Verbosity = v
+
+		case "--no-color":
+			opts.NoColor = true
+
+		case "--data-dir":
+			// Applied by main before parsing, GUI mode accepts it too
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --data-dir requires a folder\n")
+				os.Exit(1)
+			}
+			i++
+
+		case "db":
+			hasCliArg = true
+			// Get next argument as db command, then an optional file
+			if i+1 < len(args) {
+				i++
+				opts.DBCommand = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: db requires a command (backup, restore, list, isolate, share or reassign)\n")
+				os.Exit(1)
+			}
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.DBArgs = append(opts.DBArgs, args[i])
+			}
+
+		case "config":
+			hasCliArg = true
+			// Get next argument as config command, then its files
+			if i+1 < len(args) {
+				i++
+				opts.ConfigCommand = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: config requires a command (keygen, sign or verify)\n")
+				os.Exit(1)
+			}
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.ConfigArgs = append(opts.ConfigArgs, args[i])
+			}
+
+		case "eventlog":
+			hasCliArg = true
+			if i+1 < len(args) {
+				i++
+				opts.EventLogCmd = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: eventlog requires a command (install or uninstall)\n")
+				os.Exit(1)
+			}
+
+		case "audit":
+			hasCliArg = true
+			if i+1 < len(args) {
+				i++
+				opts.AuditCommand = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: audit requires a command (export or verify)\n")
+				os.Exit(1)
+			}
+
+		case "fod":
+			hasCliArg = true
+			// Get next argument as fod command, then its arguments
+			if i+1 < len(args) {
+				i++
+				opts.FODCommand = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: fod requires a command (rebuild)\n")
+				os.Exit(1)
+			}
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.FODArgs = append(opts.FODArgs, args[i])
+			}
+
+		case "changes":
+			opts.Changes = true
+			hasCliArg = true
+
+		case "usage":
+			opts.Usage = true
+			hasCliArg = true
+
+		case "--refresh":
+			opts.UsageRefresh = true
+
+		case "warm":
+			opts.Warm = true
+			hasCliArg = true
+
+		case "manifest":
+			opts.Manifest = true
+			hasCliArg = true
+			// "compare" and its files
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.ManifestArgs = append(opts.ManifestArgs, args[i])
+			}
+
+		case "--side", "--out", "--key", "--pubkey":
+			// Get next argument as the value of the manifest or audit export option
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			i++
+			switch arg {
+			case "--side":
+				opts.ManifestSide = args[i]
+			case "--out":
+				opts.OutFile = args[i]
+			case "--key":
+				opts.ManifestKey = args[i]
+			case "--pubkey":
+				opts.ManifestPubKey = args[i]
+			}
+
+		case "versions":
+			opts.Versions = true
+			hasCliArg = true
+			// Get next argument as the file, unless given after the flags
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				opts.VersionsPath = args[i]
+			}
+
+		case "--restore":
+			// Get next argument as version number
+			if i+1 < len(args) {
+				i++
+				n, err := strconv.Atoi(args[i])
+				if err != nil || n < 1 {
+					fmt.Fprintf(os.Stderr, "Error: invalid version '%s' (must be a number from the list, 1 = newest)\n", args[i])
+					os.Exit(1)
+				}
+				opts.VersionRestore = n
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --restore requires a version number\n")
+				os.Exit(1)
+			}
+
+		case "--pattern":
+			// Get next argument as glob of the files to warm
+			if i+1 < len(args) {
+				i++
+				opts.WarmPattern = args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --pattern requires a file pattern\n")
+				os.Exit(1)
+			}
+
+		case "--max-kbps":
+			// Get next argument as download limit
+			if i+1 < len(args) {
+				i++
+				kbps, err := strconv.Atoi(args[i])
+				if err != nil || kbps < 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid rate '%s' (must be >= 0 KB/s)\n", args[i])
+					os.Exit(1)
+				}
+				opts.WarmMaxKBps = kbps
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --max-kbps requires a rate in KB/s\n")
+				os.Exit(1)
+			}
+
+		case "--job":
+			// Get next argument as job ID
+			if i+1 < len(args) {
+				i++
+				id, err := strconv.ParseInt(args[i], 10, 64)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: invalid job ID '%s'\n", args[i])
+					os.Exit(1)
+				}
+				opts.JobID = id
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --job requires a job ID\n")
+				os.Exit(1)
+			}
+
+		case "--include-path", "--exclude-path", "--clear-path":
+			hasCliArg = true
+			// Get next argument as folder inside the job
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a folder inside the job\n", args[i])
+				os.Exit(1)
+			}
+			switch args[i] {
+			case "--include-path":
+				opts.IncludePaths = append(opts.IncludePaths, args[i+1])
+			case "--exclude-path":
+				opts.ExcludePaths = append(opts.ExcludePaths, args[i+1])
+			default:
+				opts.ClearPaths = append(opts.ClearPaths, args[i+1])
+			}
+			i++
+
+		case "--since":
+			// Get next argument as window start
+			if i+1 < len(args) {
+				i++
+				since, err := parseSince(args[i], time.Now())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				opts.ChangesSince = since
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: --since requires a duration or a date\n")
+				os.Exit(1)
+			}
+
+		case "--uninstall-cleanup":
+			opts.Uninstall = true
+			hasCliArg = true
+
+		case "--hydrate":
+			opts.UninstallOpts.Hydrate = true
+
+		case "--delete-credentials":
+			opts.UninstallOpts.DeleteCredentials = true
+
 		case "--autostart":
 			// Ignore autostart flag, it's handled separately for GUI mode
 			continue
 
 		default:
+			// File of "versions" given after its flags
+			if opts.Versions && opts.VersionsPath == "" && !strings.HasPrefix(arg, "-") {
+				opts.VersionsPath = arg
+				continue
+			}
 			// Unknown flag - could be GUI mode or error
 			if strings.HasPrefix(arg, "-") {
 				fmt.Fprintf(os.Stderr, "Error: unknown option '%s'\n", arg)
@@ -117,6 +462,9 @@ func parseCLIArgs(args []string) *CLIOptions {
 	if !hasCliArg {
 		return nil // GUI mode
 	}
+	if noColorEnv() {
+		opts.NoColor = true
+	}
 
 	return opts
 }
@@ -129,21 +477,173 @@ func runCLI(opts *CLIOptions, logger *zap.Logger) error {
 		return nil
 	}
 
+	// Benchmark uses its own temporary database
+	if opts.BenchScanPath != "" {
+		return runBenchScan(opts.BenchScanPath, opts.BenchProfile, logger)
+	}
+
+	if opts.SyncPath != "" && opts.SyncJobID == 0 {
+		return fmt.Errorf("--path can only be used with --sync <id>")
+	}
+	if opts.Approve && opts.SyncJobID == 0 {
+		return fmt.Errorf("--approve can only be used with --sync <id>")
+	}
+	if (opts.UninstallOpts.Hydrate || opts.UninstallOpts.DeleteCredentials) && !opts.Uninstall {
+		return fmt.Errorf("--hydrate and --delete-credentials can only be used with --uninstall-cleanup")
+	}
+	selecting := len(opts.IncludePaths)+len(opts.ExcludePaths)+len(opts.ClearPaths) > 0
+	exporting := opts.AuditCommand == "export"
+	if !opts.ChangesSince.IsZero() && !opts.Changes && !exporting {
+		return fmt.Errorf("--since can only be used with changes and audit export")
+	}
+	if opts.JobID != 0 && !opts.Changes && !opts.Usage && !opts.Warm && !opts.Versions && !opts.Manifest && !exporting && !selecting {
+		return fmt.Errorf("--job can only be used with changes, usage, warm, versions, manifest, audit export, --include-path, --exclude-path or --clear-path")
+	}
+	if (opts.ManifestSide != "" || opts.ManifestKey != "") && !opts.Manifest {
+		return fmt.Errorf("--side and --key can only be used with manifest")
+	}
+	if opts.OutFile != "" && !opts.Manifest && !exporting {
+		return fmt.Errorf("--out can only be used with manifest and audit export")
+	}
+	if exporting && opts.OutFile == "" {
+		return fmt.Errorf("audit export requires --out <file>")
+	}
+	comparing := opts.Manifest && len(opts.ManifestArgs) > 0
+	if comparing && (opts.ManifestArgs[0] != "compare" || len(opts.ManifestArgs) != 3) {
+		return fmt.Errorf("use manifest compare <file> <file>")
+	}
+	if opts.ManifestPubKey != "" && !comparing {
+		return fmt.Errorf("--pubkey can only be used with manifest compare")
+	}
+	if opts.Manifest && !comparing && (opts.JobID == 0 || opts.ManifestSide == "" || opts.OutFile == "") {
+		return fmt.Errorf("manifest requires --job <id>, --side local|remote and --out <file>")
+	}
+	if (opts.WarmPattern != "" || opts.WarmMaxKBps != 0) && !opts.Warm {
+		return fmt.Errorf("--pattern and --max-kbps can only be used with warm")
+	}
+	if opts.Warm && opts.JobID == 0 {
+		return fmt.Errorf("warm requires --job <id>")
+	}
+	if opts.VersionRestore != 0 && !opts.Versions {
+		return fmt.Errorf("--restore can only be used with versions")
+	}
+	if opts.Versions && (opts.JobID == 0 || opts.VersionsPath == "") {
+		return fmt.Errorf("versions requires --job <id> and a file of the job")
+	}
+	if selecting && opts.JobID == 0 {
+		return fmt.Errorf("--include-path, --exclude-path and --clear-path require --job <id>")
+	}
+	if opts.Changes && opts.JobID == 0 {
+		return fmt.Errorf("changes requires --job <id>")
+	}
+	if opts.UsageRefresh && !opts.Usage {
+		return fmt.Errorf("--refresh can only be used with usage")
+	}
+	if opts.Usage && opts.JobID == 0 {
+		return fmt.Errorf("usage requires --job <id>")
+	}
+
+	progress := resolveProgressMode(opts.Progress)
+	if quiet {
+		progress = progressNone
+	}
+
+	// Config signing needs neither the database nor the config itself
+	if opts.ConfigCommand != "" {
+		return runConfigCommand(opts.ConfigCommand, opts.ConfigArgs)
+	}
+
+	// Manifests are compared offline
+	if comparing {
+		return runManifestCompare(opts.ManifestArgs[1], opts.ManifestArgs[2], opts.ManifestPubKey)
+	}
+
+	// Event source registration is done by the installer, as administrator
+	if opts.EventLogCmd != "" {
+		return runEventLogCommand(opts.EventLogCmd)
+	}
+
+	// Database maintenance works on the file itself (restore needs it closed)
+	if opts.DBCommand != "" {
+		return runDBCommand(opts.DBCommand, opts.DBArgs)
+	}
+
+	// Uninstall cleanup runs even without a usable database
+	if opts.Uninstall {
+		return runUninstallCleanup(opts.UninstallOpts, logger)
+	}
+
 	// Open database
 	db, err := openDatabase()
 	if err != nil {
+		if database.IsCorrupt(err) {
+			return fmt.Errorf("database is corrupt, start AnemoneSync without arguments to recover it: %w", err)
+		}
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 	defer db.Close()
 
+	// Check the signing/encryption negotiated by every SMB connection, as the GUI does
+	smb.SetSecurityCheck(sync.NewServerSecurity(db, logger).Check)
+	defer smb.SetSecurityCheck(nil)
+
+	if db.SafeMode() {
+		fmt.Fprintln(warnOut, "Warning: database was recovered and not confirmed yet, syncs run in dry-run mode.")
+		fmt.Fprintln(warnOut, "Confirm the recovery in the AnemoneSync window to resume normal syncing.")
+	}
+
 	// Handle list-jobs
 	if opts.ListJobs {
 		return runListJobs(db)
 	}
 
+	// Handle changes report
+	if opts.Changes {
+		since := opts.ChangesSince
+		if since.IsZero() {
+			since = time.Now().Add(-defaultChangesWindow)
+		}
+		return runChanges(db, opts.JobID, since)
+	}
+
+	// Handle remote usage report
+	if opts.Usage {
+		return runUsage(db, opts.JobID, opts.UsageRefresh, logger)
+	}
+
+	// Handle cache warming
+	if opts.Warm {
+		return runWarm(db, opts.JobID, opts.WarmPattern, opts.WarmMaxKBps, progress, logger)
+	}
+
+	// Handle audit log export and verification
+	if opts.AuditCommand != "" {
+		return runAuditCommand(db, opts.AuditCommand, opts.JobID, opts.ChangesSince, opts.OutFile)
+	}
+
+	// Handle manifest export
+	if opts.Manifest {
+		return runManifest(db, opts.JobID, opts.ManifestSide, opts.OutFile, opts.ManifestKey, logger)
+	}
+
+	// Handle previous versions
+	if opts.Versions {
+		return runVersions(db, opts.JobID, opts.VersionsPath, opts.VersionRestore)
+	}
+
+	// Handle selective sync changes
+	if selecting {
+		return runSelection(db, opts.JobID, opts.IncludePaths, opts.ExcludePaths, opts.ClearPaths)
+	}
+
+	// Handle Files On Demand maintenance
+	if opts.FODCommand != "" {
+		return runFODCommand(db, opts.FODCommand, opts.FODArgs, logger)
+	}
+
 	// Handle dehydrate
 	if opts.DehydrateJobID > 0 {
-		return runDehydrate(db, opts.DehydrateJobID, opts.DehydrateDays, logger)
+		return runDehydrate(db, opts.DehydrateJobID, opts.DehydrateDays, progress, logger)
 	}
 
 	// For sync operations, we need the engine
@@ -152,6 +652,7 @@ func runCLI(opts *CLIOptions, logger *zap.Logger) error {
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		eventlog.SetEnabled(cfg.Logging.EventLog)
 
 		engine, err := sync.NewEngine(cfg, db, logger)
 		if err != nil {
@@ -160,10 +661,10 @@ func runCLI(opts *CLIOptions, logger *zap.Logger) error {
 		defer engine.Close()
 
 		if opts.SyncJobID > 0 {
-			return runSyncJob(db, engine, opts.SyncJobID, logger)
+			return runSyncJob(db, engine, opts.SyncJobID, opts.SyncPath, opts.Approve, progress, logger)
 		}
 		if opts.SyncAll {
-			return runSyncAll(db, engine, logger)
+			return runSyncAll(db, engine, progress, logger)
 		}
 	}
 
@@ -172,21 +673,194 @@ func runCLI(opts *CLIOptions, logger *zap.Logger) error {
 	return nil
 }
 
+// runEventLogCommand runs "eventlog install" and "eventlog uninstall".
+func runEventLogCommand(command string) error {
+	switch command {
+	case "install":
+		if err := eventlog.Install(); err != nil {
+			return err
+		}
+		fmt.Fprintf(statusOut, "Event source %q registered in the Application log.\n", eventlog.Source)
+	case "uninstall":
+		if err := eventlog.Uninstall(); err != nil {
+			return err
+		}
+		fmt.Fprintf(statusOut, "Event source %q removed.\n", eventlog.Source)
+	default:
+		return fmt.Errorf("unknown eventlog command %q (expected install or uninstall)", command)
+	}
+	return nil
+}
+
+// logJobFailed writes a failed CLI sync to the Windows Event Log.
+func logJobFailed(job *database.SyncJob, err error) {
+	eventlog.Error(eventlog.EventJobFailed, fmt.Sprintf(
+		"Sync of job \"%s\" (ID %d, %s) failed: %v", job.Name, job.ID, job.RemotePath, err))
+}
+
+// recordTransferIntegrity adds the verified transfers of a sync to the
+// statistics of its server, which the GUI alarms on.
+func recordTransferIntegrity(db *database.DB, job *database.SyncJob, result *sync.SyncResult) {
+	if result.FilesVerified+result.VerifyMismatches == 0 {
+		return
+	}
+	server, err := db.GetSMBServerByHost(job.RemoteEndpoint().Host)
+	if err != nil || server == nil {
+		return
+	}
+	if err := db.AddTransferIntegrity(server.ID, time.Now(), result.FilesVerified, result.VerifyMismatches); err != nil {
+		fmt.Fprintf(warnOut, "Warning: failed to record transfer integrity: %v\n", err)
+	}
+}
+
 // openDatabase opens the encrypted SQLite database.
 func openDatabase() (*database.DB, error) {
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		localAppData = "."
-	}
-	dbPath := filepath.Join(localAppData, "AnemoneSync", "data", "anemonesync.db")
+	return database.Open(databaseConfig())
+}
 
-	cfg := database.Config{
-		Path:             dbPath,
+// databaseConfig returns the configuration of the GUI's database.
+func databaseConfig() database.Config {
+	return database.Config{
+		Path:             config.DatabasePath(),
 		EncryptionKey:    "AnemoneSync_DefaultKey_ChangeMe", // Same as GUI
 		CreateIfNotExist: false,                             // CLI shouldn't create new DB
 	}
+}
+
+// runDBCommand runs "db backup [file]", "db restore <file>", "db list",
+// "db isolate <id>", "db share <id>" or "db reassign <from-id> <to-id>".
+func runDBCommand(command string, args []string) error {
+	cfg := databaseConfig()
+	backupDir := database.BackupDir(cfg.Path)
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+
+	switch command {
+	case "backup":
+		db, err := openDatabase()
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+
+		file := arg
+		if file == "" {
+			file = filepath.Join(backupDir, "manual-"+time.Now().Format("20060102-150405")+".db")
+		}
+		if err := db.Backup(file); err != nil {
+			return err
+		}
+		fmt.Fprintf(statusOut, "Database backed up to %s\n", file)
+		return nil
+
+	case "restore":
+		if arg == "" {
+			return fmt.Errorf("db restore requires a backup file (see 'anemonesync db list')")
+		}
+		if err := database.Restore(cfg, arg); err != nil {
+			return err
+		}
+		fmt.Fprintf(statusOut, "Database restored from %s\n", arg)
+		fmt.Fprintf(statusOut, "Previous database kept as %s.before-restore\n", cfg.Path)
+		return nil
+
+	case "list":
+		backups, err := database.ListBackups(backupDir)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups in %s\n", backupDir)
+			return nil
+		}
+		for _, backup := range backups {
+			info, err := os.Stat(backup)
+			if err != nil {
+				continue
+			}
+			fmt.Printf("%s  %s  %d KB\n", info.ModTime().Format("2006-01-02 15:04"), backup, info.Size()/1024)
+		}
+		return nil
+
+	case "isolate", "share":
+		jobID, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("db %s requires a job ID", command)
+		}
+		db, err := openDatabase()
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+
+		job, err := db.GetSyncJob(jobID)
+		if err != nil {
+			return fmt.Errorf("failed to get job: %w", err)
+		}
+		if job == nil {
+			return fmt.Errorf("job with ID %d not found", jobID)
+		}
+
+		if command == "isolate" {
+			if err := db.IsolateJobState(jobID); err != nil {
+				return err
+			}
+			fmt.Fprintf(statusOut, "State of \"%s\" moved to %s\n", job.Name, database.JobStorePath(cfg.Path, jobID))
+		} else {
+			if err := db.ShareJobState(jobID); err != nil {
+				return err
+			}
+			fmt.Fprintf(statusOut, "State of \"%s\" moved back to the main database\n", job.Name)
+		}
+		return nil
+
+	case "reassign":
+		if len(args) != 2 {
+			return fmt.Errorf("db reassign requires the old and the new job ID")
+		}
+		fromID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid job ID '%s'", args[0])
+		}
+		toID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid job ID '%s'", args[1])
+		}
+		db, err := openDatabase()
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+
+		from, err := db.GetSyncJob(fromID)
+		if err != nil {
+			return fmt.Errorf("failed to get job: %w", err)
+		}
+		to, err := db.GetSyncJob(toID)
+		if err != nil {
+			return fmt.Errorf("failed to get job: %w", err)
+		}
+		if from == nil || to == nil {
+			return fmt.Errorf("job with ID %d or %d not found", fromID, toID)
+		}
 
-	return database.Open(cfg)
+		report, err := db.ReassignJobHistory(fromID, toID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(statusOut, "History of \"%s\" moved to \"%s\": %d files, %d syncs\n",
+			from.Name, to.Name, report.Rows["files_state"], report.Rows["sync_history"])
+		if !strings.EqualFold(from.LocalPath, to.LocalPath) || !smbpath.SameUNC(from.RemotePath, to.RemotePath) {
+			fmt.Fprintln(warnOut, "Warning: the jobs use different folders. The next sync of the new job")
+			fmt.Fprintln(warnOut, "treats files missing from its folders as deleted: check them before syncing.")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown db command '%s' (use backup, restore, list, isolate, share or reassign)", command)
+	}
 }
 
 // printHelp displays usage information.
@@ -199,20 +873,165 @@ Usage:
 Options:
   -l, --list-jobs          List all configured sync jobs
   -s, --sync <id>          Sync a specific job by ID
+  -p, --path <folder>      With --sync, only sync this folder of the job
+                           (relative to the job, or a full local path inside it)
+      --approve            With --sync, run a job paused because its changes
+                           exceeded the safety cap or looked like ransomware
   -a, --sync-all           Sync all enabled jobs
   -d, --dehydrate <id>     Free up space by dehydrating files (Files On Demand)
       --days <n>           Only dehydrate files not accessed for N days (default: job setting, 0 = all)
+      --bench-scan <path>  Measure walk, hash and database speed on a local folder
+      --profile <file>     Write a CPU profile (pprof) during --bench-scan
+      --progress <mode>    Progress output: bar, plain (one line every few seconds) or none
+                           (default: bar on a terminal, plain when output is redirected)
+  -q, --quiet              Only print errors, and the lists asked for (list-jobs, changes,
+                           usage, versions, db list): no progress, summaries or warnings
+  -v, --verbose            Also print the info log messages (default: warnings and errors)
+  -vv                      Also print the debug log messages, in the log file too
+      --no-color           No colors in the output (also when NO_COLOR is set)
+      --data-dir <folder>  Keep the database, backups, logs and config in this folder instead of
+                           %LOCALAPPDATA%\AnemoneSync (also ANEMONESYNC_DATA_DIR, GUI mode too)
+      --uninstall-cleanup  Undo what AnemoneSync registered in Windows before uninstalling:
+                           placeholders become regular files (those not downloaded are removed,
+                           they stay on the server), sync roots are unregistered, autostart is removed
+      --hydrate            With --uninstall-cleanup, first download every file not on disk
+      --delete-credentials With --uninstall-cleanup, also delete saved SMB passwords
   -h, --help               Show this help message
 
+Database:
+  db backup [file]         Write an encrypted backup of the sync state
+                           (default: in the backups folder next to the database)
+  db restore <file>        Replace the sync state with a backup (quit AnemoneSync first)
+  db list                  List automatic and manual backups, newest first
+  db isolate <id>          Keep the file state of a large job in its own database file
+                           (quit AnemoneSync first)
+  db share <id>            Move the file state of a job back into the main database
+  db reassign <old> <new>  Move the history and file state of a job to a recreated job
+                           (quit AnemoneSync first, then delete the old job)
+
+Files On Demand:
+  fod rebuild <id>         Recreate the placeholders of a job from the server listing
+                           (e.g. after deleting them by accident), without downloading files
+
+Managed configuration:
+  config keygen <key-file> Create a signing key pair: writes the private key, prints the public
+                           key to deploy by group policy (HKLM\SOFTWARE\Policies\AnemoneSync)
+  config sign <file> <key-file>
+                           Sign a config file (writes <file>.sig). Once the public key is deployed,
+                           configs that don't match their signature are refused
+  config verify <file>     Check a config file against its signature and the deployed key
+
+Selective sync:
+  --job <id> --include-path <folder>
+                           Only sync this folder of the job (repeat for several folders)
+  --job <id> --exclude-path <folder>
+                           Never sync this folder of the job, nor list it on either side.
+                           The deepest rule wins: a folder can be excluded inside an included one
+  --job <id> --clear-path <folder>
+                           Remove the rule of a folder, which follows its parent folders again
+                           (folders are relative to the job, or full local paths inside it)
+
+Cache warming:
+  warm --job <id>          Download the files of a job ahead of use, e.g. on a new machine:
+                           missing files are downloaded, Files On Demand placeholders hydrated.
+                           Nothing is uploaded or deleted
+      --pattern <glob>     Only the files matching the pattern, relative to the job
+                           ("*" stays in a folder, "**" spans folders)
+      --max-kbps <n>       Download limit for this run, on top of the configured limits
+
+Windows Event Log:
+  eventlog install         Register the AnemoneSync event source (as administrator, done by the installer)
+  eventlog uninstall       Remove the event source
+
+Previous versions:
+  versions --job <id> <file>
+                           List the versions of a file kept by the snapshots of the server share
+                           (Previous Versions), newest first
+      --restore <n>        Copy version n of the list next to the file, as "name (date).ext"
+
+Manifests:
+  manifest --job <id> --side <local|remote> --out <file>
+                           Export the file listing of one side of a job (paths, sizes, times,
+                           and hashes known from the last sync), with a checksum detecting edits
+      --key <key-file>     Also sign the manifest with a key of "config keygen"
+  manifest compare <file> <file>
+                           List the files differing between two manifests, e.g. the PC and the
+                           server, or the server now and at the last audit
+      --pubkey <key>       Refuse manifests not signed with this public key of "config keygen"
+
+History:
+  changes --job <id>       List the files uploaded, downloaded, moved or deleted by the syncs of a job,
+                           and their conflicts
+      --since <when>       Start of the window: 90m, 24h, 7d or a date like 2025-01-31
+                           (default: 24h, actions are kept 30 days)
+
+Server space:
+  usage --job <id>         Show what takes space on the server: the size of each top-level folder
+                           of a job and its largest files, as found by the last sync
+      --refresh            Scan the server now instead
+
+Audit log:
+  audit export --out <file>
+                           Write the files deleted or overwritten and the conflicts resolved by
+                           syncs to a CSV file, with the hash chaining each entry to the previous
+                           one. Entries are never pruned; the chain is checked first
+      --job <id>           Only the entries of a job
+      --since <when>       Only the entries since a time (default: all)
+  audit verify             Check that no entry was edited or removed since it was written, and
+                           print the head of the chain to compare with a later check
+
 Without options, starts the GUI application.
 
 Examples:
   anemonesync --list-jobs
   anemonesync --sync 1
+  anemonesync --sync 3 --path Docs/Reports
   anemonesync --sync-all
+  anemonesync --sync-all --progress plain > sync.log
+  anemonesync --sync-all -q              # Scheduled task: only errors are printed
+  anemonesync --job 2 --include-path Photos --exclude-path Photos/Raw
+  anemonesync warm --job 2 --pattern "Projects/Active/**"
   anemonesync --dehydrate 1              # Use job's auto-dehydrate setting
   anemonesync --dehydrate 1 --days 30    # Files not accessed for 30+ days
-  anemonesync --dehydrate 1 --days 0     # All hydrated files`)
+  anemonesync --dehydrate 1 --days 0     # All hydrated files
+  anemonesync --bench-scan C:\Users\me\Documents --profile scan.pprof
+  anemonesync --uninstall-cleanup --hydrate
+  anemonesync changes --job 1 --since 24h
+  anemonesync usage --job 1 --refresh
+  anemonesync versions --job 1 Reports/budget.xlsx --restore 2
+  anemonesync manifest --job 1 --side remote --out manifest.json
+  anemonesync manifest compare local.json remote.json
+  anemonesync audit export --job 1 --since 2025-01-01 --out audit.csv
+  anemonesync fod rebuild 2
+  anemonesync db backup
+  anemonesync db restore %LOCALAPPDATA%\AnemoneSync\data\backups\anemonesync-20250101-120000.db`)
+}
+
+// runUninstallCleanup runs the uninstall cleanup, prints its report and
+// saves it in the temp folder, which survives the uninstall.
+func runUninstallCleanup(opts app.UninstallOptions, logger *zap.Logger) error {
+	db, err := openDatabase()
+	if err != nil {
+		fmt.Fprintf(warnOut, "Warning: failed to open database: %v\n", err)
+		db = nil
+	} else {
+		defer db.Close()
+	}
+
+	report := app.UninstallCleanup(context.Background(), db, opts, logger)
+	fmt.Fprint(statusOut, report.String())
+
+	reportPath := filepath.Join(os.TempDir(), "AnemoneSync-uninstall-"+time.Now().Format("20060102-150405")+".txt")
+	if err := report.WriteFile(reportPath); err != nil {
+		fmt.Fprintf(warnOut, "Warning: failed to save report: %v\n", err)
+	} else {
+		fmt.Fprintf(statusOut, "Report saved to %s\n", reportPath)
+	}
+
+	if report.Failures > 0 {
+		return fmt.Errorf("%d cleanup steps failed, see the report", report.Failures)
+	}
+	return nil
 }
 
 // runListJobs lists all configured sync jobs.
@@ -232,9 +1051,9 @@ func runListJobs(db *database.DB) error {
 	fmt.Println()
 
 	// Print header
-	fmt.Printf("%-4s %-20s %-35s %-35s %-8s %s\n",
-		"ID", "Name", "Local Path", "Remote", "Enabled", "Last Sync")
-	fmt.Println(strings.Repeat("-", 140))
+	fmt.Printf("%-4s %-20s %-35s %-35s %-8s %-10s %s\n",
+		"ID", "Name", "Local Path", "Remote", "Enabled", "Trigger", "Last Sync")
+	fmt.Println(strings.Repeat("-", 151))
 
 	enabledCount := 0
 	for _, job := range jobs {
@@ -254,8 +1073,8 @@ func runListJobs(db *database.DB) error {
 		remotePath := truncatePath(job.RemotePath, 35)
 		name := truncateString(job.Name, 20)
 
-		fmt.Printf("%-4d %-20s %-35s %-35s %-8s %s\n",
-			job.ID, name, localPath, remotePath, enabled, lastSync)
+		fmt.Printf("%-4d %-20s %-35s %-35s %-8s %-10s %s\n",
+			job.ID, name, localPath, remotePath, enabled, job.TriggerMode, lastSync)
 	}
 
 	fmt.Println()
@@ -265,7 +1084,9 @@ func runListJobs(db *database.DB) error {
 }
 
 // runSyncJob syncs a specific job by ID.
-func runSyncJob(db *database.DB, engine *sync.Engine, jobID int64, logger *zap.Logger) error {
+// A non-empty syncPath restricts the sync to that folder of the job. approve
+// runs a job paused until its changes are approved, whatever it changes.
+func runSyncJob(db *database.DB, engine *sync.Engine, jobID int64, syncPath string, approve bool, progress progressMode, logger *zap.Logger) error {
 	job, err := db.GetSyncJob(jobID)
 	if err != nil {
 		return fmt.Errorf("failed to get job: %w", err)
@@ -274,33 +1095,55 @@ func runSyncJob(db *database.DB, engine *sync.Engine, jobID int64, logger *zap.L
 		return fmt.Errorf("job with ID %d not found", jobID)
 	}
 
-	fmt.Printf("Syncing \"%s\" (ID: %d)\n", job.Name, job.ID)
-	fmt.Printf("  Local:  %s\n", job.LocalPath)
-	fmt.Printf("  Remote: %s\n", job.RemotePath)
-	fmt.Println()
+	subtree, err := resolveSyncPath(job, syncPath)
+	if err != nil {
+		return err
+	}
+	if reason := app.ParseJobOptions(job.NetworkConditions).PendingApproval; reason != "" && !approve {
+		return fmt.Errorf("job paused until its changes are approved (%s): check them, then run again with --approve", reason)
+	}
 
-	req := buildSyncRequest(job, createCLIProgressCallback(job.Name))
+	fmt.Fprintf(statusOut, "Syncing \"%s\" (ID: %d)\n", job.Name, job.ID)
+	fmt.Fprintf(statusOut, "  Local:  %s\n", job.LocalPath)
+	fmt.Fprintf(statusOut, "  Remote: %s\n", job.RemotePath)
+	if subtree != "" {
+		fmt.Fprintf(statusOut, "  Folder: %s\n", subtree)
+	}
+	fmt.Fprintln(statusOut)
+
+	req := buildSyncRequest(job, createCLIProgressCallback(job.Name, progress))
+	req.Subtree = subtree
+	req.DryRun = db.SafeMode()
+	req.ApproveChanges = approve
 
 	ctx := context.Background()
 	startTime := time.Now()
 
 	result, err := engine.Sync(ctx, req)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		fmt.Fprintf(statusOut, "Error: %v\n", err)
+		if holdForApproval(db, job, err) {
+			return fmt.Errorf("approval required: check the changes, then run again with --approve")
+		}
+		logJobFailed(job, err)
 		return err
 	}
 
 	duration := time.Since(startTime)
+	recordTransferIntegrity(db, job, result)
+	if approve && !req.DryRun {
+		setPendingApproval(db, job, "")
+	}
 
 	// Print summary
-	fmt.Println()
+	fmt.Fprintln(statusOut)
 	printSyncSummary(result, duration)
 
 	return nil
 }
 
 // runSyncAll syncs all enabled jobs.
-func runSyncAll(db *database.DB, engine *sync.Engine, logger *zap.Logger) error {
+func runSyncAll(db *database.DB, engine *sync.Engine, progress progressMode, logger *zap.Logger) error {
 	jobs, err := db.GetAllSyncJobs()
 	if err != nil {
 		return fmt.Errorf("failed to get jobs: %w", err)
@@ -315,22 +1158,31 @@ func runSyncAll(db *database.DB, engine *sync.Engine, logger *zap.Logger) error
 	}
 
 	if len(enabledJobs) == 0 {
-		fmt.Println("No enabled jobs to sync.")
+		fmt.Fprintln(statusOut, "No enabled jobs to sync.")
 		return nil
 	}
 
-	fmt.Printf("Syncing all enabled jobs (%d of %d)\n", len(enabledJobs), len(jobs))
-	fmt.Println()
+	fmt.Fprintf(statusOut, "Syncing all enabled jobs (%d of %d)\n", len(enabledJobs), len(jobs))
+	fmt.Fprintln(statusOut)
 
 	totalStartTime := time.Now()
 	totalFiles := 0
 	errorCount := 0
 	jobsSynced := 0
+	jobsPaused := 0
 
 	for i, job := range enabledJobs {
-		fmt.Printf("[%d/%d] Syncing \"%s\"...\n", i+1, len(enabledJobs), job.Name)
+		fmt.Fprintf(statusOut, "[%d/%d] Syncing \"%s\"...\n", i+1, len(enabledJobs), job.Name)
 
-		req := buildSyncRequest(job, createCLIProgressCallback(job.Name))
+		// Paused jobs wait for --sync <id> --approve (or the GUI)
+		if reason := app.ParseJobOptions(job.NetworkConditions).PendingApproval; reason != "" {
+			printJobError(job, fmt.Errorf("paused until its changes are approved (%s)", reason))
+			jobsPaused++
+			continue
+		}
+
+		req := buildSyncRequest(job, createCLIProgressCallback(job.Name, progress))
+		req.DryRun = db.SafeMode()
 
 		ctx := context.Background()
 		startTime := time.Now()
@@ -339,30 +1191,63 @@ func runSyncAll(db *database.DB, engine *sync.Engine, logger *zap.Logger) error
 		duration := time.Since(startTime)
 
 		if err != nil {
-			fmt.Printf("      Error: %v\n", err)
+			printJobError(job, err)
+			if holdForApproval(db, job, err) {
+				jobsPaused++
+				continue
+			}
+			logJobFailed(job, err)
 			errorCount++
 			continue
 		}
 
-		filesProcessed := result.FilesUploaded + result.FilesDownloaded + result.FilesDeleted
+		recordTransferIntegrity(db, job, result)
+		filesProcessed := result.FilesUploaded + result.FilesDownloaded + result.FilesRenamed + result.FilesDeleted
 		totalFiles += filesProcessed
 		jobsSynced++
 
-		fmt.Printf("      Complete (%.1fs, %d files)\n", duration.Seconds(), filesProcessed)
-		fmt.Println()
+		fmt.Fprintf(statusOut, "      Complete (%.1fs, %d files)\n", duration.Seconds(), filesProcessed)
+		fmt.Fprintln(statusOut)
 	}
 
 	totalDuration := time.Since(totalStartTime)
 
-	fmt.Println("All syncs completed.")
-	fmt.Printf("  Total duration: %.1fs\n", totalDuration.Seconds())
-	fmt.Printf("  Jobs synced: %d\n", jobsSynced)
-	fmt.Printf("  Total files: %d\n", totalFiles)
-	fmt.Printf("  Errors: %d\n", errorCount)
+	fmt.Fprintln(statusOut, "All syncs completed.")
+	fmt.Fprintf(statusOut, "  Total duration: %.1fs\n", totalDuration.Seconds())
+	fmt.Fprintf(statusOut, "  Jobs synced: %d\n", jobsSynced)
+	fmt.Fprintf(statusOut, "  Total files: %d\n", totalFiles)
+	fmt.Fprintf(statusOut, "  Errors: %d\n", errorCount)
 
+	if jobsPaused > 0 {
+		return fmt.Errorf("%d job(s) paused until their changes are approved: check them, then run --sync <id> --approve", jobsPaused)
+	}
 	return nil
 }
 
+// holdForApproval pauses the job, as the GUI does, when its run stopped on
+// the change cap or on suspected ransomware: later runs are refused until the
+// changes are approved. Returns false if err is another error.
+func holdForApproval(db *database.DB, job *database.SyncJob, err error) bool {
+	if !errors.Is(err, sync.ErrChangeCapExceeded) && !errors.Is(err, sync.ErrRansomwareSuspected) {
+		return false
+	}
+	setPendingApproval(db, job, err.Error())
+	return true
+}
+
+// setPendingApproval records why the job is paused ("" resumes it).
+func setPendingApproval(db *database.DB, job *database.SyncJob, reason string) {
+	opts := app.ParseJobOptions(job.NetworkConditions)
+	if opts.PendingApproval == reason {
+		return
+	}
+	opts.PendingApproval = reason
+	job.NetworkConditions = opts.ToJSON()
+	if err := db.UpdateSyncJob(job); err != nil {
+		fmt.Fprintf(warnOut, "Warning: failed to save job: %v\n", err)
+	}
+}
+
 // buildSyncRequest creates a SyncRequest from a database SyncJob.
 func buildSyncRequest(job *database.SyncJob, progressCb sync.ProgressCallback) *sync.SyncRequest {
 	mode := sync.SyncMode(job.SyncMode)
@@ -375,37 +1260,83 @@ func buildSyncRequest(job *database.SyncJob, progressCb sync.ProgressCallback) *
 		conflictRes = "recent"
 	}
 
+	opts := app.ParseJobOptions(job.NetworkConditions)
+
 	return &sync.SyncRequest{
 		JobID:              job.ID,
 		LocalPath:          job.LocalPath,
 		RemotePath:         job.RemotePath,
 		Mode:               mode,
 		ConflictResolution: conflictRes,
+		ExclusionGroups:    opts.ExclusionGroups,
+		MaxChangedFiles:    opts.MaxChangedFiles,
+		MaxChangedBytes:    opts.MaxChangedBytes,
 		ProgressCallback:   progressCb,
+		MaxUploadKBps:      opts.MaxUploadKBps,
+		MaxDownloadKBps:    opts.MaxDownloadKBps,
+		ThrottleMetered:    opts.ThrottleMetered,
+		VerifyTransfers:    opts.VerifyTransfers,
+		SyncACLs:           opts.SyncACLs,
+		SyncStreams:        opts.SyncStreams,
+		ReadLockedFiles:    opts.ReadLockedFiles,
+		JobLog:             opts.JobLog,
 	}
 }
 
+// resolveSyncPath converts a --path value into a folder relative to the job.
+// Absolute paths must be inside the job's local folder.
+func resolveSyncPath(job *database.SyncJob, syncPath string) (string, error) {
+	if syncPath == "" {
+		return "", nil
+	}
+
+	relPath := syncPath
+	if filepath.IsAbs(syncPath) || filepath.VolumeName(syncPath) != "" {
+		rel, err := filepath.Rel(job.LocalPath, syncPath)
+		if err != nil {
+			return "", fmt.Errorf("path '%s' is not inside job folder %s", syncPath, job.LocalPath)
+		}
+		relPath = rel
+	}
+
+	subtree, err := sync.NormalizeSubtree(relPath)
+	if err != nil {
+		return "", fmt.Errorf("path '%s' is not inside job folder %s", syncPath, job.LocalPath)
+	}
+	return subtree, nil
+}
+
 // createCLIProgressCallback creates a progress callback for terminal output.
-func createCLIProgressCallback(jobName string) sync.ProgressCallback {
+// Returns nil in progressNone mode.
+func createCLIProgressCallback(jobName string, mode progressMode) sync.ProgressCallback {
+	if mode == progressNone {
+		return nil
+	}
+
 	lastPhase := ""
+	throttle := &plainThrottle{interval: plainProgressInterval}
 	return func(progress *sync.SyncProgress) {
 		if progress.Phase != lastPhase {
 			lastPhase = progress.Phase
 			switch progress.Phase {
 			case "scanning":
-				fmt.Printf("[Scanning]     %s\n", progress.Message)
+				fmt.Fprintf(statusOut, "[Scanning]     %s\n", progress.Message)
 			case "detecting":
-				fmt.Printf("[Detecting]    %s\n", progress.Message)
+				fmt.Fprintf(statusOut, "[Detecting]    %s\n", progress.Message)
 			case "executing":
 				// Will be updated with progress bar
 			case "finalizing":
-				fmt.Printf("[Finalizing]   %s\n", progress.Message)
+				fmt.Fprintf(statusOut, "[Finalizing]   %s\n", progress.Message)
 			}
 		}
 
-		// Show progress bar during execution phase
+		// Show progress during execution phase
 		if progress.Phase == "executing" && progress.FilesTotal > 0 {
-			printProgressBar(progress.FilesProcessed, progress.FilesTotal)
+			if mode == progressBar {
+				printProgressBar(progress.FilesProcessed, progress.FilesTotal)
+			} else if throttle.ready(progress.FilesProcessed >= progress.FilesTotal) {
+				printProgressLine("[Executing]", progress.FilesProcessed, progress.FilesTotal, progress.BytesTransferred)
+			}
 		}
 	}
 }
@@ -418,26 +1349,42 @@ func printProgressBar(current, total int) {
 	filled := int(percent * barWidth)
 
 	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
-	fmt.Printf("\r[Executing]    %s %d/%d (%.0f%%)", bar, current, total, percent*100)
+	fmt.Fprintf(statusOut, "\r[Executing]    %s %d/%d (%.0f%%)", bar, current, total, percent*100)
 
 	if current >= total {
-		fmt.Println()
+		fmt.Fprintln(statusOut)
 	}
 }
 
 // printSyncSummary prints a sync result summary.
 func printSyncSummary(result *sync.SyncResult, duration time.Duration) {
-	fmt.Printf("[Complete]     Duration: %.1fs\n", duration.Seconds())
-	fmt.Println()
-	fmt.Println("Summary:")
-	fmt.Printf("  Uploaded:    %d files\n", result.FilesUploaded)
-	fmt.Printf("  Downloaded:  %d files\n", result.FilesDownloaded)
-	fmt.Printf("  Deleted:     %d files\n", result.FilesDeleted)
-	fmt.Printf("  Skipped:     %d files\n", result.FilesSkipped)
-	fmt.Printf("  Errors:      %d\n", result.FilesError)
+	fmt.Fprintf(statusOut, "[Complete]     Duration: %.1fs\n", duration.Seconds())
+	fmt.Fprintln(statusOut)
+	if result.Subtree != "" {
+		fmt.Fprintf(statusOut, "Summary (%s only):\n", result.Subtree)
+	} else {
+		fmt.Fprintln(statusOut, "Summary:")
+	}
+	fmt.Fprintf(statusOut, "  Uploaded:    %d files\n", result.FilesUploaded)
+	fmt.Fprintf(statusOut, "  Downloaded:  %d files\n", result.FilesDownloaded)
+	if result.FilesRenamed > 0 {
+		fmt.Fprintf(statusOut, "  Moved:       %d files\n", result.FilesRenamed)
+	}
+	fmt.Fprintf(statusOut, "  Deleted:     %d files\n", result.FilesDeleted)
+	fmt.Fprintf(statusOut, "  Skipped:     %d files\n", result.FilesSkipped)
+	fmt.Fprintf(statusOut, "  Errors:      %d\n", result.FilesError)
+	if result.FilesVetoed > 0 {
+		fmt.Fprintf(statusOut, "  Vetoed:      %d files (refused by upload scan)\n", result.FilesVetoed)
+	}
+	if result.FilesVerified > 0 || result.VerifyMismatches > 0 {
+		fmt.Fprintf(statusOut, "  Verified:    %d files (%d corrupted attempts)\n", result.FilesVerified, result.VerifyMismatches)
+	}
 
 	if result.BytesTransferred > 0 {
-		fmt.Printf("  Transferred: %s\n", formatBytes(result.BytesTransferred))
+		fmt.Fprintf(statusOut, "  Transferred: %s\n", formatBytes(result.BytesTransferred))
+	}
+	if result.TransferOrder != "" && result.TransferOrder != string(sync.TransferOrderDefault) {
+		fmt.Fprintf(statusOut, "  Order:       %s\n", result.TransferOrder)
 	}
 }
 
@@ -472,7 +1419,7 @@ func formatBytes(bytes int64) string {
 }
 
 // runDehydrate dehydrates files for a job with Files On Demand enabled.
-func runDehydrate(db *database.DB, jobID int64, days int, logger *zap.Logger) error {
+func runDehydrate(db *database.DB, jobID int64, days int, progress progressMode, logger *zap.Logger) error {
 	// Get job
 	job, err := db.GetSyncJob(jobID)
 	if err != nil {
@@ -494,19 +1441,19 @@ func runDehydrate(db *database.DB, jobID int64, days int, logger *zap.Logger) er
 		// Use job's auto-dehydrate setting
 		daysThreshold = opts.AutoDehydrateDays
 		if daysThreshold == 0 {
-			fmt.Println("Note: Job has no auto-dehydrate setting. Use --days 0 to dehydrate all files.")
+			fmt.Fprintln(statusOut, "Note: Job has no auto-dehydrate setting. Use --days 0 to dehydrate all files.")
 			return nil
 		}
 	}
 
-	fmt.Printf("Dehydrating \"%s\" (ID: %d)\n", job.Name, job.ID)
-	fmt.Printf("  Local path: %s\n", job.LocalPath)
+	fmt.Fprintf(statusOut, "Dehydrating \"%s\" (ID: %d)\n", job.Name, job.ID)
+	fmt.Fprintf(statusOut, "  Local path: %s\n", job.LocalPath)
 	if daysThreshold > 0 {
-		fmt.Printf("  Threshold:  Files not accessed for %d+ days\n", daysThreshold)
+		fmt.Fprintf(statusOut, "  Threshold:  Files not accessed for %d+ days\n", daysThreshold)
 	} else {
-		fmt.Printf("  Threshold:  All hydrated files\n")
+		fmt.Fprintf(statusOut, "  Threshold:  All hydrated files\n")
 	}
-	fmt.Println()
+	fmt.Fprintln(statusOut)
 
 	// Create sync root manager
 	syncRootConfig := cloudfiles.SyncRootConfig{
@@ -530,7 +1477,7 @@ func runDehydrate(db *database.DB, jobID int64, days int, logger *zap.Logger) er
 
 	// Scan for hydrated files
 	ctx := context.Background()
-	fmt.Println("[Scanning]     Looking for hydrated files...")
+	fmt.Fprintln(statusOut, "[Scanning]     Looking for hydrated files...")
 
 	hydratedFiles, err := dm.ScanHydratedFiles(ctx)
 	if err != nil {
@@ -538,37 +1485,47 @@ func runDehydrate(db *database.DB, jobID int64, days int, logger *zap.Logger) er
 	}
 
 	if len(hydratedFiles) == 0 {
-		fmt.Println("[Complete]     No hydrated files found.")
+		fmt.Fprintln(statusOut, "[Complete]     No hydrated files found.")
 		return nil
 	}
 
 	// Filter eligible files
 	var eligible []cloudfiles.HydratedFileInfo
-	var totalSize int64
+	var totalSize, totalAllocated int64
 	for _, f := range hydratedFiles {
 		if daysThreshold == 0 || f.DaysSinceAccess >= daysThreshold {
 			eligible = append(eligible, f)
 			totalSize += f.Size
+			totalAllocated += f.AllocatedSize
 		}
 	}
 
 	if len(eligible) == 0 {
-		fmt.Printf("[Complete]     Found %d hydrated files, but none meet the criteria.\n", len(hydratedFiles))
+		fmt.Fprintf(statusOut, "[Complete]     Found %d hydrated files, but none meet the criteria.\n", len(hydratedFiles))
 		return nil
 	}
 
-	fmt.Printf("[Found]        %d files eligible for dehydration (%s)\n", len(eligible), formatBytes(totalSize))
-	fmt.Println()
+	fmt.Fprintf(statusOut, "[Found]        %d files eligible for dehydration (%s, %s on disk)\n",
+		len(eligible), formatBytes(totalSize), formatBytes(totalAllocated))
+	fmt.Fprintln(statusOut)
 
 	// Dehydrate files
 	dehydrated := 0
-	var freedBytes int64
+	var freedBytes, logicalBytes int64
 	errors := 0
+	throttle := &plainThrottle{interval: plainProgressInterval}
 
 	for i, file := range eligible {
 		// Progress
-		percent := float64(i+1) / float64(len(eligible)) * 100
-		fmt.Printf("\r[Dehydrating]  %d/%d (%.0f%%) - %s", i+1, len(eligible), percent, truncateString(file.Path, 40))
+		switch progress {
+		case progressBar:
+			percent := float64(i+1) / float64(len(eligible)) * 100
+			fmt.Fprintf(statusOut, "\r[Dehydrating]  %d/%d (%.0f%%) - %s", i+1, len(eligible), percent, truncateString(file.Path, 40))
+		case progressPlain:
+			if throttle.ready(i+1 == len(eligible)) {
+				printProgressLine("[Dehydrating]", i+1, len(eligible), freedBytes)
+			}
+		}
 
 		if err := dm.DehydrateFile(ctx, file.Path); err != nil {
 			errors++
@@ -580,18 +1537,21 @@ func runDehydrate(db *database.DB, jobID int64, days int, logger *zap.Logger) er
 		}
 
 		dehydrated++
-		freedBytes += file.Size
+		freedBytes += file.AllocatedSize
+		logicalBytes += file.Size
 	}
 
-	fmt.Println()
-	fmt.Println()
+	if progress == progressBar {
+		fmt.Fprintln(statusOut)
+	}
+	fmt.Fprintln(statusOut)
 
 	// Summary
-	fmt.Println("[Complete]     Dehydration finished.")
-	fmt.Printf("  Files dehydrated: %d\n", dehydrated)
-	fmt.Printf("  Space freed:      %s\n", formatBytes(freedBytes))
+	fmt.Fprintln(statusOut, "[Complete]     Dehydration finished.")
+	fmt.Fprintf(statusOut, "  Files dehydrated: %d\n", dehydrated)
+	fmt.Fprintf(statusOut, "  Space freed:      %s on disk (%s of file data)\n", formatBytes(freedBytes), formatBytes(logicalBytes))
 	if errors > 0 {
-		fmt.Printf("  Errors:           %d\n", errors)
+		fmt.Fprintf(statusOut, "  Errors:           %d\n", errors)
 	}
 
 	return nil
@@ -0,0 +1,81 @@
+// Signing of managed configurations, for administrators.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+)
+
+// runConfigCommand runs "config keygen <key-file>", "config sign <file> <key-file>"
+// or "config verify <file>".
+func runConfigCommand(command string, args []string) error {
+	switch command {
+	case "keygen":
+		if len(args) != 1 {
+			return fmt.Errorf("config keygen requires the file to write the private key to")
+		}
+		publicKey, privateKey, err := config.GenerateSigningKey()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(args[0], []byte(privateKey+"\n"), 0o600); err != nil {
+			return fmt.Errorf("failed to write private key: %w", err)
+		}
+		fmt.Fprintf(statusOut, "Private key written to %s (keep it off the managed machines)\n", args[0])
+		fmt.Printf("Public key, to deploy as HKLM\\SOFTWARE\\Policies\\AnemoneSync\\ConfigPublicKey (REG_SZ):\n%s\n", publicKey)
+		return nil
+
+	case "sign":
+		if len(args) != 2 {
+			return fmt.Errorf("config sign requires the config file and the private key file")
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		privateKey, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+		sig, err := config.SignConfig(data, string(privateKey))
+		if err != nil {
+			return err
+		}
+		sigFile := config.SignatureFile(args[0])
+		if err := os.WriteFile(sigFile, []byte(sig+"\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write signature: %w", err)
+		}
+		fmt.Fprintf(statusOut, "Signature written to %s, deploy it next to the config\n", sigFile)
+		return nil
+
+	case "verify":
+		if len(args) != 1 {
+			return fmt.Errorf("config verify requires the config file")
+		}
+		publicKey, err := config.ManagedPublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to read the managed config policy: %w", err)
+		}
+		if publicKey == "" {
+			return fmt.Errorf("no public key deployed in HKLM\\SOFTWARE\\Policies\\AnemoneSync\\ConfigPublicKey")
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		sig, err := os.ReadFile(config.SignatureFile(args[0]))
+		if err != nil {
+			return fmt.Errorf("failed to read signature: %w", err)
+		}
+		if err := config.VerifyConfig(data, string(sig), publicKey); err != nil {
+			return err
+		}
+		fmt.Fprintf(statusOut, "%s matches its signature\n", args[0])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config command '%s' (use keygen, sign or verify)", command)
+	}
+}
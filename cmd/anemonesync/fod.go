@@ -0,0 +1,60 @@
+// Files On Demand maintenance commands.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/app"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// runFODCommand runs "fod rebuild <id>".
+func runFODCommand(db *database.DB, command string, args []string, logger *zap.Logger) error {
+	switch command {
+	case "rebuild":
+		if len(args) == 0 {
+			return fmt.Errorf("fod rebuild requires a job ID")
+		}
+		jobID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("fod rebuild requires a job ID")
+		}
+		return runFODRebuild(db, jobID, logger)
+
+	default:
+		return fmt.Errorf("unknown fod command '%s' (use rebuild)", command)
+	}
+}
+
+// runFODRebuild recreates the placeholders of a Files On Demand job from the
+// remote listing, without downloading any file content.
+func runFODRebuild(db *database.DB, jobID int64, logger *zap.Logger) error {
+	job, err := db.GetSyncJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job with ID %d not found", jobID)
+	}
+
+	fmt.Fprintf(statusOut, "Rebuilding placeholders of \"%s\" (ID: %d)\n", job.Name, job.ID)
+	fmt.Fprintf(statusOut, "  Local path: %s\n", job.LocalPath)
+	fmt.Fprintln(statusOut)
+	fmt.Fprintln(statusOut, "[Scanning]     Listing remote files...")
+
+	result, err := app.RebuildPlaceholders(context.Background(), job, logger)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(statusOut, "[Complete]     Placeholders rebuilt.")
+	fmt.Fprintf(statusOut, "  Remote files:       %d (%d folders)\n", result.RemoteFiles, result.RemoteDirs)
+	fmt.Fprintf(statusOut, "  Placeholders added: %d\n", result.Recreated)
+	fmt.Fprintln(statusOut)
+	fmt.Fprintln(statusOut, "No data was downloaded: files are fetched from the server when opened")
+	fmt.Fprintln(statusOut, "while AnemoneSync is running.")
+	return nil
+}
@@ -5,22 +5,38 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/app"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 func main() {
+	// Data directory override, before anything opens the database or the log
+	if dir := dataDirArg(os.Args[1:]); dir != "" {
+		if err := config.SetDataDir(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Check CLI mode first, its options set the console output
+	opts := parseCLIArgs(os.Args[1:])
+	if opts != nil {
+		setOutput(opts)
+	}
+
 	// Initialize logger with dynamic level support
-	logger, logLevel := initLogger()
+	logger, logLevel := initLogger(opts)
 	defer logger.Sync()
 
-	// Check CLI mode first
-	if opts := parseCLIArgs(os.Args[1:]); opts != nil {
+	if opts != nil {
 		if err := runCLI(opts, logger); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "%s %v\n", errorLabel(), err)
 			os.Exit(1)
 		}
 		return
@@ -47,7 +63,8 @@ func main() {
 
 // initLogger creates a configured zap logger with a dynamic log level and file rotation.
 // Returns the logger and the AtomicLevel for runtime level changes.
-func initLogger() (*zap.Logger, zap.AtomicLevel) {
+// In CLI mode (cli not nil), console messages go to stderr at the level of -q, -v or -vv.
+func initLogger(cli *CLIOptions) (*zap.Logger, zap.AtomicLevel) {
 	// Dynamic log level (default: Info, can be changed at runtime)
 	atomicLevel := zap.NewAtomicLevelAt(zapcore.InfoLevel)
 
@@ -61,12 +78,28 @@ func initLogger() (*zap.Logger, zap.AtomicLevel) {
 	// Create cores: stdout + file with rotation
 	var cores []zapcore.Core
 
-	// Always log to stdout
-	cores = append(cores, zapcore.NewCore(
-		consoleEncoder,
-		zapcore.AddSync(os.Stdout),
-		atomicLevel,
-	))
+	if cli == nil {
+		// Always log to stdout
+		cores = append(cores, zapcore.NewCore(
+			consoleEncoder,
+			zapcore.AddSync(os.Stdout),
+			atomicLevel,
+		))
+	} else {
+		// Keep stdout for the command output
+		if cli.Verbosity >= verbosityDebug {
+			atomicLevel.SetLevel(zapcore.DebugLevel)
+		}
+		stderrConfig := encoderConfig
+		if stderrColors {
+			stderrConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		cores = append(cores, zapcore.NewCore(
+			zapcore.NewConsoleEncoder(stderrConfig),
+			zapcore.Lock(os.Stderr),
+			cli.Verbosity.consoleLogLevel(),
+		))
+	}
 
 	// Log to file with rotation (lumberjack)
 	logPath := getLogPath()
@@ -83,6 +116,9 @@ func initLogger() (*zap.Logger, zap.AtomicLevel) {
 			zapcore.AddSync(fileWriter),
 			atomicLevel,
 		))
+
+		// Jobs with their own log file (logs\jobs\<job id>.log)
+		cores = append(cores, correlation.NewJobLogCore(filepath.Dir(logPath), consoleEncoder, atomicLevel))
 	}
 
 	// Combine cores
@@ -96,18 +132,18 @@ func initLogger() (*zap.Logger, zap.AtomicLevel) {
 
 // getLogPath returns the path for the log file.
 func getLogPath() string {
-	// Use %LOCALAPPDATA%\AnemoneSync\logs on Windows
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
+	// Use %LOCALAPPDATA%\AnemoneSync\logs on Windows, or the data directory
+	// set by ANEMONESYNC_DATA_DIR / --data-dir
+	if config.DataDirOverride() == "" && os.Getenv("LOCALAPPDATA") == "" {
 		return ""
 	}
 
-	logDir := localAppData + "\\AnemoneSync\\logs"
+	logDir := config.LogDir()
 
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return ""
 	}
 
-	return logDir + "\\anemonesync.log"
+	return filepath.Join(logDir, "anemonesync.log")
 }
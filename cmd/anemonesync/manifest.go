@@ -0,0 +1,123 @@
+// Listing manifests: export one side of a job, compare two exports.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/manifest"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// runManifest writes the manifest of one side of a job to out, signed with
+// the private key of keyFile if set.
+func runManifest(db *database.DB, jobID int64, side, out, keyFile string, logger *zap.Logger) error {
+	var privateKey string
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+		privateKey = string(data)
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	engine, err := sync.NewEngine(cfg, db, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create sync engine: %w", err)
+	}
+	defer engine.Close()
+
+	fmt.Fprintf(statusOut, "[Scanning]     Listing the %s files of job %d...\n", side, jobID)
+	startTime := time.Now()
+	m, err := engine.ListingManifest(context.Background(), jobID, side)
+	if err != nil {
+		return err
+	}
+	if err := m.Seal(privateKey); err != nil {
+		return err
+	}
+	if err := m.Write(out); err != nil {
+		return err
+	}
+
+	hashed := 0
+	for _, f := range m.Files {
+		if f.Hash != "" {
+			hashed++
+		}
+	}
+	fmt.Fprintf(statusOut, "[Complete]     Duration: %.1fs\n", time.Since(startTime).Seconds())
+	fmt.Fprintf(statusOut, "  Files:       %d (%s), %d with a known hash\n", m.FileCount, formatBytes(m.TotalSize), hashed)
+	if m.Signed() {
+		fmt.Fprintf(statusOut, "  Signed:      key %s\n", m.KeyFingerprint())
+	}
+	fmt.Fprintf(statusOut, "  Written to:  %s\n", out)
+	return nil
+}
+
+// diffLabels describes the kinds of differences between manifests.
+var diffLabels = map[manifest.DiffKind]string{
+	manifest.OnlyInA:      "only in A",
+	manifest.OnlyInB:      "only in B",
+	manifest.SizeDiffers:  "size",
+	manifest.HashDiffers:  "content",
+	manifest.MTimeDiffers: "modified",
+}
+
+// runManifestCompare prints the files differing between two manifests, both
+// signed with trustedKey if set.
+func runManifestCompare(fileA, fileB, trustedKey string) error {
+	a, err := manifest.Read(fileA, trustedKey)
+	if err != nil {
+		return err
+	}
+	b, err := manifest.Read(fileB, trustedKey)
+	if err != nil {
+		return err
+	}
+
+	printManifestHeader("A", fileA, a)
+	printManifestHeader("B", fileB, b)
+	fmt.Println()
+
+	diffs := manifest.Compare(a, b)
+	if len(diffs) == 0 {
+		fmt.Println("No difference.")
+		return nil
+	}
+	for _, d := range diffs {
+		detail := ""
+		switch d.Kind {
+		case manifest.SizeDiffers:
+			detail = fmt.Sprintf("  (%d -> %d bytes)", d.A.Size, d.B.Size)
+		case manifest.MTimeDiffers:
+			detail = fmt.Sprintf("  (%s -> %s)",
+				time.Unix(d.A.MTime, 0).Format("2006-01-02 15:04:05"),
+				time.Unix(d.B.MTime, 0).Format("2006-01-02 15:04:05"))
+		}
+		fmt.Printf("%-10s  %s%s\n", diffLabels[d.Kind], d.Path, detail)
+	}
+	fmt.Printf("\n%d differences\n", len(diffs))
+	return nil
+}
+
+// printManifestHeader prints what a manifest lists and who signed it.
+func printManifestHeader(label, file string, m *manifest.Manifest) {
+	signed := "checksum only"
+	if m.Signed() {
+		signed = "signed with key " + m.KeyFingerprint()
+	}
+	fmt.Printf("%s: %s\n", label, file)
+	fmt.Printf("   %s side of \"%s\" (ID: %d), %s\n", m.Side, m.JobName, m.JobID, m.Root)
+	fmt.Printf("   %d files, %s, exported %s, %s\n",
+		m.FileCount, formatBytes(m.TotalSize), m.GeneratedAt.Local().Format("2006-01-02 15:04"), signed)
+}
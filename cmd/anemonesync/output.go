@@ -0,0 +1,99 @@
+// Output tiers and colors for AnemoneSync CLI.
+// Scripts and screen readers need output they can rely on: -q keeps errors only,
+// -v and -vv add the log messages, and colors can be turned off (--no-color, NO_COLOR).
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows"
+)
+
+// verbosity selects how much the CLI prints.
+type verbosity int
+
+const (
+	verbosityQuiet   verbosity = -1 // Errors only (and the lists asked for)
+	verbosityNormal  verbosity = 0  // Progress, summaries and warnings
+	verbosityVerbose verbosity = 1  // Plus the info log messages
+	verbosityDebug   verbosity = 2  // Plus the debug log messages
+)
+
+// statusOut receives the progress, summaries and confirmations of the commands.
+// It is discarded with -q: errors go to stderr, and the lists asked for
+// (list-jobs, changes, versions, db list) to stdout, whatever the verbosity.
+var statusOut io.Writer = os.Stdout
+
+// warnOut receives the warnings, also discarded with -q.
+var warnOut io.Writer = os.Stderr
+
+var (
+	quiet        bool // -q
+	stderrColors bool // ANSI colors in the messages and logs on stderr
+)
+
+// setOutput applies the verbosity and color options of the command line.
+func setOutput(opts *CLIOptions) {
+	quiet = opts.Verbosity == verbosityQuiet
+	if quiet {
+		statusOut = io.Discard
+		warnOut = io.Discard
+	}
+	stderrColors = !opts.NoColor && enableColors(os.Stderr)
+}
+
+// consoleLogLevel returns the lowest level of the log messages shown on the console.
+// The log file keeps its own level.
+func (v verbosity) consoleLogLevel() zapcore.Level {
+	switch {
+	case v <= verbosityQuiet:
+		return zapcore.ErrorLevel
+	case v == verbosityNormal:
+		return zapcore.WarnLevel
+	case v == verbosityVerbose:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+// noColorEnv reports whether the NO_COLOR convention (https://no-color.org)
+// asks for output without colors: the variable is set and not empty.
+func noColorEnv() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// enableColors reports whether ANSI colors can be written to f: it must be a
+// console, whose escape sequence processing is turned on (off by default in
+// the legacy Windows console).
+func enableColors(f *os.File) bool {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false // Redirected to a file or pipe
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
+
+// errorLabel returns the "Error:" prefix of the messages on stderr, in red
+// when colors are on.
+func errorLabel() string {
+	if stderrColors {
+		return "\x1b[31mError:\x1b[0m"
+	}
+	return "Error:"
+}
+
+// printJobError prints the error of one of several jobs, which does not end
+// the command: under the job in the status output, or on stderr with -q.
+func printJobError(job *database.SyncJob, err error) {
+	if quiet {
+		fmt.Fprintf(os.Stderr, "%s \"%s\": %v\n", errorLabel(), job.Name, err)
+		return
+	}
+	fmt.Fprintf(statusOut, "      Error: %v\n", err)
+}
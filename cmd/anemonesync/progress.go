@@ -0,0 +1,84 @@
+// Progress output modes for AnemoneSync CLI.
+// The interactive progress bar relies on carriage returns, which garbles logs
+// when output is redirected (Task Scheduler, CI), so plain line output is used there.
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// progressMode selects how the CLI reports progress.
+type progressMode string
+
+const (
+	progressAuto  progressMode = ""      // bar on a terminal, plain otherwise
+	progressBar   progressMode = "bar"   // Progress bar redrawn in place
+	progressPlain progressMode = "plain" // Periodic progress lines
+	progressNone  progressMode = "none"  // No progress output, summary only
+)
+
+// plainProgressInterval is the minimum delay between two plain progress lines.
+const plainProgressInterval = 5 * time.Second
+
+// parseProgressMode validates a --progress value.
+func parseProgressMode(value string) (progressMode, error) {
+	switch mode := progressMode(value); mode {
+	case progressBar, progressPlain, progressNone:
+		return mode, nil
+	default:
+		return progressAuto, fmt.Errorf("invalid progress mode '%s' (must be plain, bar or none)", value)
+	}
+}
+
+// resolveProgressMode picks the bar or plain mode when no mode was requested.
+func resolveProgressMode(mode progressMode) progressMode {
+	if mode != progressAuto {
+		return mode
+	}
+	if stdoutIsTerminal() {
+		return progressBar
+	}
+	return progressPlain
+}
+
+// stdoutIsTerminal reports whether stdout is a console rather than a file or pipe.
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// plainThrottle limits plain progress output to one line per interval.
+type plainThrottle struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// ready reports whether a line should be printed now.
+// The final line (done) is always printed.
+func (t *plainThrottle) ready(done bool) bool {
+	now := time.Now()
+	if !done && !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// printProgressLine prints one plain progress line.
+func printProgressLine(label string, current, total int, bytes int64) {
+	percent := 0.0
+	if total > 0 {
+		percent = float64(current) / float64(total) * 100
+	}
+
+	line := fmt.Sprintf("%-14s %d/%d (%.0f%%)", label, current, total, percent)
+	if bytes > 0 {
+		line += fmt.Sprintf(", %s", formatBytes(bytes))
+	}
+	fmt.Fprintf(statusOut, "%s [%s]\n", line, time.Now().Format("15:04:05"))
+}
@@ -0,0 +1,83 @@
+// Selective sync: folders of a job synced or left out.
+package main
+
+import (
+	"fmt"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+)
+
+// runSelection applies the --include-path, --exclude-path and --clear-path
+// flags to the selective sync of a job, then prints the resulting selection.
+func runSelection(db *database.DB, jobID int64, include, exclude, cleared []string) error {
+	job, err := db.GetSyncJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job with ID %d not found", jobID)
+	}
+
+	for _, p := range cleared {
+		folder, err := selectionFolder(job, p)
+		if err != nil {
+			return err
+		}
+		removed, err := db.DeleteSelectionRule(jobID, folder)
+		if err != nil {
+			return fmt.Errorf("failed to remove rule of %s: %w", folder, err)
+		}
+		if !removed {
+			fmt.Fprintf(statusOut, "No rule for %s\n", folder)
+		}
+	}
+
+	// A folder given to both flags ends up excluded
+	for _, set := range []struct {
+		mode  string
+		paths []string
+	}{
+		{database.SelectionInclude, include},
+		{database.SelectionExclude, exclude},
+	} {
+		mode := set.mode
+		for _, p := range set.paths {
+			folder, err := selectionFolder(job, p)
+			if err != nil {
+				return err
+			}
+			if err := db.SaveSelectionRule(&database.SelectionRule{JobID: jobID, Path: folder, Mode: mode}); err != nil {
+				return fmt.Errorf("failed to %s %s: %w", mode, folder, err)
+			}
+		}
+	}
+
+	rules, err := db.GetSyncSelection(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get selective sync: %w", err)
+	}
+
+	fmt.Fprintf(statusOut, "Selective sync of \"%s\" (ID %d)\n", job.Name, job.ID)
+	if len(rules) == 0 {
+		fmt.Fprintln(statusOut, "  All folders are synced")
+		return nil
+	}
+	for _, rule := range rules {
+		fmt.Fprintf(statusOut, "  %-8s %s\n", rule.Mode, rule.Path)
+	}
+	fmt.Fprintln(statusOut)
+	fmt.Fprintln(statusOut, "Folders left out are kept on disk and on the server, they are no longer synced.")
+	return nil
+}
+
+// selectionFolder returns the job-relative folder of a selective sync flag.
+func selectionFolder(job *database.SyncJob, path string) (string, error) {
+	folder, err := resolveSyncPath(job, path)
+	if err != nil {
+		return "", err
+	}
+	if folder == "" {
+		return "", fmt.Errorf("'%s' is the whole job: give a folder inside it", path)
+	}
+	return folder, nil
+}
@@ -0,0 +1,78 @@
+// Report of the space a job takes on the server.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// runUsage prints the size of each top-level folder of a job on the server
+// and its largest files, as saved by the last scan, or scanning the server
+// with refresh (or when the job was never scanned).
+func runUsage(db *database.DB, jobID int64, refresh bool, logger *zap.Logger) error {
+	job, err := db.GetSyncJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job with ID %d not found", jobID)
+	}
+
+	var usage *database.RemoteUsage
+	if !refresh {
+		if usage, err = db.GetRemoteUsage(jobID); err != nil {
+			return fmt.Errorf("failed to get remote usage: %w", err)
+		}
+	}
+	if usage == nil {
+		cfg, err := config.Load("")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		engine, err := sync.NewEngine(cfg, db, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create sync engine: %w", err)
+		}
+		defer engine.Close()
+
+		fmt.Fprintf(statusOut, "[Scanning]     Listing the files of \"%s\" on the server...\n", job.Name)
+		if usage, err = engine.RemoteUsage(context.Background(), jobID); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Space used by \"%s\" on %s (scanned %s)\n\n", job.Name, job.RemotePath, usage.ScannedAt.Format("2006-01-02 15:04"))
+	if usage.TotalFiles == 0 {
+		fmt.Println("No files.")
+		return nil
+	}
+
+	for _, f := range usage.Folders {
+		name := f.Path + `\`
+		if f.Path == "" {
+			name = "(files at the root)"
+		}
+		fmt.Printf("%10s  %5.1f%%  %7d files  %s\n", formatBytes(f.Bytes), percentOf(f.Bytes, usage.TotalBytes), f.Files, name)
+	}
+	fmt.Printf("%10s  %5.1f%%  %7d files  Total\n", formatBytes(usage.TotalBytes), 100.0, usage.TotalFiles)
+
+	fmt.Println()
+	fmt.Println("Largest files:")
+	for _, f := range usage.LargestFiles {
+		fmt.Printf("%10s  %s\n", formatBytes(f.Bytes), f.Path)
+	}
+	return nil
+}
+
+// percentOf returns part as a percentage of total.
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,83 @@
+// Previous versions of a file from the snapshots of the server share.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+// runVersions lists the previous versions of a file of a job, or copies
+// version restore of the list (1 = newest) next to the local file.
+func runVersions(db *database.DB, jobID int64, file string, restore int) error {
+	job, err := db.GetSyncJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job with ID %d not found", jobID)
+	}
+
+	relPath, err := resolveSyncPath(job, file)
+	if err != nil {
+		return err
+	}
+	if relPath == "" {
+		return fmt.Errorf("'%s' is the job folder, not a file", file)
+	}
+	root, err := smbpath.ParseUNC(job.RemotePath)
+	if err != nil {
+		return fmt.Errorf("invalid remote path of job: %w", err)
+	}
+	remote, err := smbpath.JoinUNC(root.Host, root.Share, root.Path+`\`+relPath)
+	if err != nil {
+		return err
+	}
+
+	creds, err := smb.NewCredentialManager(nil).Load(job.RemoteEndpoint().CredentialID)
+	if err != nil {
+		return err
+	}
+
+	versions, err := smb.ListPreviousVersions(remote, creds)
+	if err != nil {
+		return err
+	}
+
+	if restore == 0 {
+		fmt.Printf("Previous versions of %s\n\n", relPath)
+		if len(versions) == 0 {
+			fmt.Println("No previous version (no snapshot on the server, or the file never changed between them).")
+			return nil
+		}
+		fmt.Printf("%3s  %-16s  %-16s  %12s\n", "#", "Snapshot", "Modified", "Size")
+		for i, v := range versions {
+			fmt.Printf("%3d  %-16s  %-16s  %12d\n", i+1,
+				v.Snapshot.Local().Format("2006-01-02 15:04"),
+				v.ModTime.Local().Format("2006-01-02 15:04"),
+				v.Size)
+		}
+		fmt.Println("\nRestore a copy with: --restore <#>")
+		return nil
+	}
+
+	if restore > len(versions) {
+		return fmt.Errorf("no version %d (%d previous versions)", restore, len(versions))
+	}
+	v := versions[restore-1]
+	localPath := filepath.Join(job.LocalPath, filepath.FromSlash(relPath))
+	target := filepath.Join(filepath.Dir(localPath), smb.VersionCopyName(filepath.Base(localPath), v))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create folder: %w", err)
+	}
+	if err := smb.RestorePreviousVersion(remote, creds, v, target); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(statusOut, "Restored the version of %s as %s\n", v.ModTime.Local().Format("2006-01-02 15:04"), target)
+	return nil
+}
@@ -0,0 +1,127 @@
+// Cache warming: download the content of a job ahead of use.
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/app"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// runWarm downloads the files of a job matching pattern ("" = all) that are
+// not on disk yet: placeholders are hydrated for Files On Demand jobs,
+// missing or outdated files downloaded for the others. Nothing is uploaded
+// or deleted. maxKBps caps the download rate of the run (0 = config and job
+// limits only).
+func runWarm(db *database.DB, jobID int64, pattern string, maxKBps int, progress progressMode, logger *zap.Logger) error {
+	job, err := db.GetSyncJob(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job with ID %d not found", jobID)
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	opts := app.ParseJobOptions(job.NetworkConditions)
+
+	fmt.Fprintf(statusOut, "Warming \"%s\" (ID: %d)\n", job.Name, job.ID)
+	fmt.Fprintf(statusOut, "  Local:   %s\n", job.LocalPath)
+	fmt.Fprintf(statusOut, "  Remote:  %s\n", job.RemotePath)
+	if pattern != "" {
+		fmt.Fprintf(statusOut, "  Pattern: %s\n", pattern)
+	}
+	if maxKBps > 0 {
+		fmt.Fprintf(statusOut, "  Limit:   %d KB/s\n", maxKBps)
+	}
+	fmt.Fprintln(statusOut)
+
+	if opts.FilesOnDemand {
+		ctx := context.Background()
+		ctx = bandwidth.WithLimiter(ctx, bandwidth.Download, bandwidth.KBps(cfg.Sync.Performance.MaxDownloadKBps))
+		ctx = bandwidth.WithLimiter(ctx, bandwidth.Download, bandwidth.KBps(opts.MaxDownloadKBps))
+		ctx = bandwidth.WithLimiter(ctx, bandwidth.Download, bandwidth.KBps(maxKBps))
+		return warmPlaceholders(ctx, job, pattern, progress, logger)
+	}
+
+	engine, err := sync.NewEngine(cfg, db, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create sync engine: %w", err)
+	}
+	defer engine.Close()
+
+	req := buildSyncRequest(job, createCLIProgressCallback(job.Name, progress))
+	req.DownloadOnly = true
+	req.DryRun = db.SafeMode()
+	if pattern != "" {
+		glob, err := scanner.CompileGlob(pattern)
+		if err != nil {
+			return err
+		}
+		req.Pattern = pattern
+		req.Subtree = glob.Root() // Only scan the folder holding the matches
+	}
+	if maxKBps > 0 && (req.MaxDownloadKBps == 0 || maxKBps < req.MaxDownloadKBps) {
+		req.MaxDownloadKBps = maxKBps
+	}
+
+	startTime := time.Now()
+	result, err := engine.Sync(context.Background(), req)
+	if err != nil {
+		fmt.Fprintf(statusOut, "Error: %v\n", err)
+		return err
+	}
+
+	fmt.Fprintln(statusOut)
+	printSyncSummary(result, time.Since(startTime))
+	return nil
+}
+
+// warmPlaceholders hydrates the placeholders of a Files On Demand job.
+func warmPlaceholders(ctx context.Context, job *database.SyncJob, pattern string, progress progressMode, logger *zap.Logger) error {
+	startTime := time.Now()
+	fmt.Fprintln(statusOut, "[Scanning]     Looking for files not on disk...")
+
+	throttle := &plainThrottle{interval: plainProgressInterval}
+	report := func(done, total int, bytes int64, relPath string) {
+		switch progress {
+		case progressBar:
+			percent := float64(done) / float64(total) * 100
+			fmt.Fprintf(statusOut, "\r[Hydrating]    %d/%d (%.0f%%) - %s", done, total, percent, truncateString(relPath, 40))
+			if done == total {
+				fmt.Fprintln(statusOut)
+			}
+		case progressPlain:
+			if throttle.ready(done == total) {
+				printProgressLine("[Hydrating]", done, total, bytes)
+			}
+		}
+	}
+
+	result, err := app.WarmPlaceholders(ctx, job, pattern, report, logger)
+	if err != nil {
+		return err
+	}
+	if result.Matched == 0 {
+		fmt.Fprintln(statusOut, "[Complete]     Every matching file is already on disk.")
+		return nil
+	}
+
+	fmt.Fprintln(statusOut)
+	fmt.Fprintf(statusOut, "[Complete]     Duration: %.1fs\n", time.Since(startTime).Seconds())
+	fmt.Fprintf(statusOut, "  Files hydrated: %d (%s)\n", result.Hydrated, formatBytes(result.Bytes))
+	if result.Failed > 0 {
+		fmt.Fprintf(statusOut, "  Errors:         %d (see the log, run warm again to retry)\n", result.Failed)
+	}
+	return nil
+}
@@ -12,13 +12,13 @@ import (
 	"syscall"
 	"unsafe"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
 	"golang.org/x/sys/windows"
 )
 
 var (
 	cldapi = windows.NewLazySystemDLL("cldapi.dll")
 
-	procCfGetPlatformInfo       = cldapi.NewProc("CfGetPlatformInfo")
 	procCfRegisterSyncRoot      = cldapi.NewProc("CfRegisterSyncRoot")
 	procCfUnregisterSyncRoot    = cldapi.NewProc("CfUnregisterSyncRoot")
 	procCfGetSyncRootInfoByPath = cldapi.NewProc("CfGetSyncRootInfoByPath")
@@ -109,13 +109,6 @@ type CF_SYNC_REGISTRATION struct {
 	ProviderId             GUID
 }
 
-type CF_PLATFORM_INFO struct {
-	StructSize     uint32
-	BuildNumber    uint32
-	RevisionNumber uint32
-	IntegrationId  [16]byte // GUID
-}
-
 func main() {
 	pathFlag := flag.String("path", "", "Path to check/unregister")
 	unregisterFlag := flag.Bool("unregister", false, "Unregister the sync root")
@@ -127,24 +120,30 @@ func main() {
 	fmt.Println("=== Cloud Files API Diagnostic Tool ===")
 	fmt.Println()
 
-	// Check if cldapi.dll is available
-	if err := cldapi.Load(); err != nil {
-		fmt.Printf("ERROR: cldapi.dll not available: %v\n", err)
-		fmt.Println("Cloud Files API requires Windows 10 version 1709 or later.")
-		os.Exit(1)
+	// Same probes as when Files On Demand is enabled in the app
+	probePath := *pathFlag
+	if probePath == "" {
+		probePath, _ = os.Getwd()
 	}
-	fmt.Println("[OK] cldapi.dll loaded")
-
-	// Get platform info
-	if err := procCfGetPlatformInfo.Find(); err == nil {
-		info := CF_PLATFORM_INFO{StructSize: uint32(unsafe.Sizeof(CF_PLATFORM_INFO{}))}
-		hr, _, _ := procCfGetPlatformInfo.Call(uintptr(unsafe.Pointer(&info)))
-		if hr == S_OK {
-			fmt.Printf("[OK] Platform: Build %d, Revision %d\n", info.BuildNumber, info.RevisionNumber)
+	prereqs := cloudfiles.CheckPrerequisites(probePath)
+	for _, check := range prereqs.Checks {
+		if check.OK {
+			fmt.Printf("[OK] %s: %s\n", check.Name, check.Detail)
 		} else {
-			fmt.Printf("[WARN] CfGetPlatformInfo failed: HRESULT 0x%08X\n", hr)
+			fmt.Printf("[FAIL] %s: %s\n", check.Name, check.Detail)
+			if check.Fix != "" {
+				fmt.Printf("       Fix: %s\n", check.Fix)
+			}
 		}
 	}
+	if !prereqs.Elevated {
+		fmt.Println("[INFO] Not running as Administrator: some checks and fixes need elevation.")
+	}
+
+	// The checks below call cldapi.dll directly
+	if err := cldapi.Load(); err != nil {
+		os.Exit(1)
+	}
 
 	if *pathFlag == "" {
 		fmt.Println("\nUsage: cloudfiles_debug -path <directory> [-unregister] [-force]")
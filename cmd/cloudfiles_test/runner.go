@@ -14,7 +14,6 @@ import (
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	"go.uber.org/zap"
-	"golang.org/x/sys/windows"
 )
 
 // Runner executes test scenarios.
@@ -468,26 +467,11 @@ func (r *Runner) VerifyFileDeleted(relPath string) error {
 
 // getPlaceholderState returns the Cloud Files placeholder state.
 func (r *Runner) getPlaceholderState(fullPath string) (cloudfiles.CF_PLACEHOLDER_STATE, error) {
-	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(fullPath),
-		0, // Query only
-		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
-		nil,
-		windows.OPEN_EXISTING,
-		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT,
-		0,
-	)
+	state, err := cloudfiles.GetFilePlaceholderState(fullPath)
 	if err != nil {
-		return 0, fmt.Errorf("impossible d'ouvrir fichier: %w", err)
+		return 0, fmt.Errorf("impossible de lire l'état du fichier: %w", err)
 	}
-	defer windows.CloseHandle(handle)
-
-	var fileInfo windows.ByHandleFileInformation
-	if err := windows.GetFileInformationByHandle(handle, &fileInfo); err != nil {
-		return 0, fmt.Errorf("impossible de lire attributs: %w", err)
-	}
-
-	return cloudfiles.GetPlaceholderState(fileInfo.FileAttributes, cloudfiles.IO_REPARSE_TAG_CLOUD), nil
+	return state, nil
 }
 
 // ===== Sync Operations =====
@@ -3,15 +3,22 @@ package app
 
 import (
 	"context"
-	"os"
-	"path/filepath"
+	"errors"
+	"fmt"
+	"strings"
 	gosync "sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/errmsg"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
 )
 
@@ -42,20 +49,39 @@ type App struct {
 	// Persistence & Services
 	db        *database.DB
 	notifier  *Notifier
+	webhooks  *Webhooks
 	autoStart *AutoStart
 	credMgr   *smb.CredentialManager
 
+	// Corrupt database recovery (nil if the database opened normally)
+	recoveryReport *database.RecoveryReport
+
 	// Background workers
-	scheduler     *Scheduler
-	watcher       *Watcher
-	remoteWatcher *RemoteWatcher
-	syncManager   *SyncManager
-	shutdownMgr   *ShutdownManager
+	scheduler      *Scheduler
+	networkWatcher *NetworkWatcher
+	offlineQueue   *OfflineQueue // nil when disabled in config.yaml
+	idleWatcher    *IdleWatcher
+	watcher        *Watcher
+	remoteWatcher  *RemoteWatcher
+	syncManager    *SyncManager
+	shutdownMgr    *ShutdownManager
 
 	// Shutdown dialog/progress
 	shutdownProgressDialog *ShutdownProgressDialog
 
+	// Volumes with a pending Cloud Files filter reattach prompt
+	cldFltPrompts map[string]bool
+
+	// Check of the security negotiated by every SMB connection
+	serverSecurity *syncpkg.ServerSecurity
+	// Corruption alarm already raised per server, until restart
+	integrityAlerts map[int64]bool
+
 	// Configuration
+	cfg            *config.Config // Settings of config.yaml, read once at startup
+	configErr      error          // Managed config.yaml refused: no tray, no sync
+	language       string         // Language of error messages (config.yaml app.language)
+	clock          clock.Clock    // Times maintenance windows
 	appSettings    *AppSettings
 	syncJobs       []*SyncJob
 	smbConnections []*SMBConnection
@@ -75,10 +101,33 @@ func New(logger *zap.Logger, logLevel zap.AtomicLevel) *App {
 		syncJobs:       make([]*SyncJob, 0),
 		smbConnections: make([]*SMBConnection, 0),
 		credMgr:        smb.NewCredentialManager(logger),
+		language:       errmsg.DefaultLanguage,
+		clock:          clock.System,
+	}
+
+	// config.yaml is read once here, the components get these settings
+	cfg, err := config.Load("")
+	if err != nil {
+		if errors.Is(err, config.ErrConfigTampered) {
+			// Already in the event log. Run refuses to start: the defaults
+			// below only keep the fields usable until the user quits.
+			logger.Error("Managed configuration refused, not starting", zap.Error(err))
+			a.configErr = err
+		} else {
+			logger.Error("Failed to load config.yaml, using default settings", zap.Error(err))
+		}
+		cfg = config.Default()
+	}
+	a.cfg = cfg
+	a.language = errmsg.Language(cfg.App.Language)
+	eventlog.SetEnabled(cfg.Logging.EventLog)
+	if cfg.Logging.Metrics.Enabled {
+		a.serveMetrics(cfg.Logging.Metrics.Listen)
 	}
 
-	// Initialize notifier
+	// Initialize notifier and webhooks
 	a.notifier = NewNotifier(a)
+	a.webhooks = NewWebhooks(a)
 
 	// Initialize auto-start
 	autoStart, err := NewAutoStart()
@@ -93,17 +142,24 @@ func New(logger *zap.Logger, logLevel zap.AtomicLevel) *App {
 		logger.Error("Failed to initialize database", zap.Error(err))
 	}
 
+	// Check the signing/encryption negotiated by every SMB connection
+	if a.db != nil {
+		a.installServerSecurity()
+	}
+
 	return a
 }
 
+// SetClock replaces the clock of maintenance windows, e.g. with a fake one
+// in tests. It must be called before Run.
+func (a *App) SetClock(c clock.Clock) {
+	a.clock = clock.Or(c)
+}
+
 // initDatabase initializes the SQLite database.
 func (a *App) initDatabase() error {
-	// Get database path in user's app data
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		localAppData = "."
-	}
-	dbPath := filepath.Join(localAppData, "AnemoneSync", "data", "anemonesync.db")
+	// Get database path in user's app data (or ANEMONESYNC_DATA_DIR)
+	dbPath := config.DatabasePath()
 
 	// Create DB with a default key (in production, this should come from keyring)
 	cfg := database.Config{
@@ -113,6 +169,9 @@ func (a *App) initDatabase() error {
 	}
 
 	db, err := database.Open(cfg)
+	if err != nil && database.IsCorrupt(err) {
+		db, err = a.recoverDatabase(cfg, err)
+	}
 	if err != nil {
 		return err
 	}
@@ -127,9 +186,43 @@ func (a *App) initDatabase() error {
 	// Load sync jobs from database
 	a.loadJobsFromDB()
 
+	// Move the state of very large jobs into their own store (before any sync runs)
+	a.isolateLargeJobs()
+
 	return nil
 }
 
+// isolateLargeJobs gives jobs with more files than database.isolate_jobs_over
+// (config.yaml) their own state store, so they don't slow down other jobs.
+func (a *App) isolateLargeJobs() {
+	limit := a.cfg.Database.IsolateJobsOver
+	if limit <= 0 {
+		return
+	}
+
+	for _, job := range a.syncJobs {
+		if a.db.IsJobStateIsolated(job.ID) {
+			continue
+		}
+		stats, err := a.db.GetJobStatistics(job.ID)
+		if err != nil || stats.TotalFiles <= limit {
+			continue
+		}
+
+		if err := a.db.IsolateJobState(job.ID); err != nil {
+			a.logger.Warn("Failed to isolate job state",
+				zap.String("name", job.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+		a.logger.Info("Moved job state into its own store",
+			zap.String("name", job.Name),
+			zap.Int("files", stats.TotalFiles),
+		)
+	}
+}
+
 // loadSettingsFromDB loads app settings from the database.
 func (a *App) loadSettingsFromDB() {
 	if a.db == nil {
@@ -148,6 +241,12 @@ func (a *App) loadSettingsFromDB() {
 	if v, ok := config["notifications_enabled"]; ok && v == "false" {
 		a.appSettings.NotificationsEnabled = false
 	}
+	if v, ok := config["pause_sync_presenting"]; ok && v == "true" {
+		a.appSettings.PauseSyncWhenPresenting = true
+	}
+	if v, ok := config["hold_notifications_presenting"]; ok && v == "false" {
+		a.appSettings.HoldNotificationsWhenPresenting = false
+	}
 	if v, ok := config["log_level"]; ok && v != "" {
 		a.appSettings.LogLevel = v
 		// Apply saved log level at startup
@@ -157,6 +256,13 @@ func (a *App) loadSettingsFromDB() {
 	if v, ok := config["sync_interval"]; ok && v != "" {
 		a.appSettings.SyncInterval = v
 	}
+	if v, ok := config["webhook_urls"]; ok && v != "" {
+		urls, err := ParseWebhookURLs(v)
+		if err != nil {
+			a.logger.Warn("Ignoring invalid webhook URLs", zap.Error(err))
+		}
+		a.appSettings.WebhookURLs = urls
+	}
 }
 
 // loadSMBConnectionsFromDB loads SMB connections from the database.
@@ -200,9 +306,10 @@ func (a *App) loadJobsFromDB() {
 
 		// Find SMBConnectionID based on RemoteHost
 		for _, conn := range a.smbConnections {
-			if conn.Host == job.RemoteHost {
+			if strings.EqualFold(conn.Host, job.RemoteHost) {
 				job.SMBConnectionID = conn.ID
 				job.Username = conn.Username
+				job.RemotePort = conn.Port
 				break
 			}
 		}
@@ -236,18 +343,40 @@ func (a *App) Run() {
 		a.shutdown()
 	})
 
+	// A managed configuration that fails verification must not fall back
+	// to the defaults: the administrator's settings would silently be lost
+	if a.configErr != nil {
+		a.showConfigRefused()
+		fyneApp.Run()
+		return
+	}
+
 	// Initialize and setup system tray
 	a.tray = NewTray(a)
 	a.tray.Setup()
 
 	// Start background workers
 	a.startWorkers()
+	eventlog.Info(eventlog.EventServiceStarted, "AnemoneSync "+AppVersion+" started")
 
 	// Run Fyne main loop (blocks until quit)
 	// Note: We don't create a window, app runs in system tray only
 	fyneApp.Run()
 }
 
+// showConfigRefused explains why the managed configuration was refused.
+// Closing the window quits: nothing else is started.
+func (a *App) showConfigRefused() {
+	w := a.FyneApp().NewWindow("AnemoneSync - Configuration Refused")
+	w.Resize(fyne.NewSize(500, 200))
+	w.SetOnClosed(a.Quit)
+
+	d := dialog.NewError(fmt.Errorf("%w\n\nAnemoneSync does not start or sync until the signed configuration is restored. Contact your administrator.", a.configErr), w)
+	d.SetOnClosed(w.Close)
+	w.Show()
+	d.Show()
+}
+
 // Quit triggers application shutdown.
 func (a *App) Quit() {
 	a.logger.Info("Quit requested")
@@ -270,6 +399,7 @@ func (a *App) Quit() {
 // shutdown performs cleanup when the app exits.
 func (a *App) shutdown() {
 	a.logger.Info("Shutting down...")
+	defer eventlog.Info(eventlog.EventServiceStopped, "AnemoneSync stopped")
 
 	// Stop file watcher
 	if a.watcher != nil {
@@ -281,6 +411,19 @@ func (a *App) shutdown() {
 		a.remoteWatcher.Stop()
 	}
 
+	// Stop network watcher
+	if a.networkWatcher != nil {
+		a.networkWatcher.Stop()
+	}
+
+	// Stop probing offline servers
+	a.offlineQueue.Stop()
+
+	// Stop idle watcher
+	if a.idleWatcher != nil {
+		a.idleWatcher.Stop()
+	}
+
 	// Stop scheduler
 	if a.scheduler != nil {
 		a.scheduler.Stop()
@@ -323,9 +466,12 @@ func (a *App) SetAutoStartMode(isAutoStart bool) {
 
 // startWorkers initializes background workers.
 func (a *App) startWorkers() {
+	// Detect jobs whose drive was remounted under another letter
+	movedJobs := a.detectMovedVolumes()
+
 	// Initialize sync manager (requires DB)
 	if a.db != nil {
-		syncMgr, err := NewSyncManager(a, a.db, a.logger.Named("syncmanager"))
+		syncMgr, err := NewSyncManager(a, a.db, a.cfg, a.logger.Named("syncmanager"))
 		if err != nil {
 			a.logger.Error("Failed to create sync manager", zap.Error(err))
 		} else {
@@ -334,11 +480,23 @@ func (a *App) startWorkers() {
 	}
 
 	// Initialize and start scheduler
-	a.scheduler = NewScheduler(a, a.logger.Named("scheduler"))
+	a.scheduler = NewScheduler(a, a.cfg, a.logger.Named("scheduler"))
 	a.scheduler.Start()
 
+	// Start network watcher (jobs synced on network connect)
+	a.networkWatcher = NewNetworkWatcher(a, a.logger.Named("network"))
+	a.networkWatcher.Start()
+
+	// Start offline queue (changes recorded while a server is unreachable)
+	a.offlineQueue = NewOfflineQueue(a, a.cfg, a.logger.Named("offline"))
+	a.offlineQueue.Start()
+
+	// Start idle watcher (jobs synced when the user is idle)
+	a.idleWatcher = NewIdleWatcher(a, a.logger.Named("idle"))
+	a.idleWatcher.Start()
+
 	// Initialize and start file watcher
-	a.watcher = NewWatcher(a, a.logger.Named("watcher"))
+	a.watcher = NewWatcher(a, a.cfg, a.logger.Named("watcher"))
 	a.watcher.Start()
 
 	// Initialize and start remote watcher
@@ -357,12 +515,20 @@ func (a *App) startWorkers() {
 	// Start size calculator
 	a.startSizeUpdater()
 
+	// Daily backup of the sync state
+	a.startDatabaseBackups()
+
+	// Keep the health banner current between syncs
+	a.startHealthChecks()
+
 	// Trigger sync on startup for:
 	// - Jobs with SyncOnStartup enabled (only when launched via autostart)
 	// - Jobs with FilesOnDemand enabled (always, to detect new/changed files on server)
 	// Delay slightly to let systray fully initialize
 	go func() {
 		time.Sleep(2 * time.Second)
+		a.confirmMovedVolumes(movedJobs)
+		a.confirmSafeMode()
 		a.triggerStartupSync(a.isAutoStart)
 	}()
 }
@@ -382,7 +548,7 @@ func (a *App) reconnectCloudFilesProviders() {
 
 	reconnected := 0
 	for _, job := range jobs {
-		if job.Enabled && job.FilesOnDemand {
+		if job.Enabled && job.FilesOnDemand && job.VolumeMoved == "" {
 			a.logger.Info("Reconnecting Cloud Files provider",
 				zap.String("job", job.Name),
 				zap.String("local_path", job.LocalPath),
@@ -422,6 +588,9 @@ func (a *App) triggerStartupSync(isAutoStart bool) {
 		// FilesOnDemand: always sync at startup (need to detect server changes)
 		// SyncOnStartup: only sync if launched via autostart
 		if job.FilesOnDemand || (job.SyncOnStartup && isAutoStart) {
+			if a.deferAutomaticSync(job) {
+				continue
+			}
 			startupJobs = append(startupJobs, job)
 		}
 	}
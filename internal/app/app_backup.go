@@ -0,0 +1,71 @@
+package app
+
+import (
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// --- Automatic Database Backup ---
+
+const (
+	databaseBackupInterval = 24 * time.Hour
+	databaseBackupCheck    = time.Hour
+)
+
+// startDatabaseBackups writes a daily encrypted backup of the database,
+// keeping the number of copies set in config.yaml (database.backup_keep).
+func (a *App) startDatabaseBackups() {
+	if a.db == nil {
+		return
+	}
+
+	if !a.cfg.Database.AutoBackup {
+		a.logger.Info("Automatic database backup disabled")
+		return
+	}
+	keep := a.cfg.Database.BackupKeep
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		// First check shortly after startup, then hourly
+		timer := time.NewTimer(time.Minute)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				a.backupDatabaseIfDue(keep)
+				timer.Reset(databaseBackupCheck)
+			case <-a.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// backupDatabaseIfDue writes a rotating backup if the last one is older than a day.
+func (a *App) backupDatabaseIfDue(keep int) {
+	// A recovered database must not rotate out the backups taken before the corruption
+	if a.SafeMode() {
+		return
+	}
+
+	dir := database.BackupDir(a.db.Path())
+	if time.Since(database.LastBackupTime(dir)) < databaseBackupInterval {
+		return
+	}
+
+	path, err := a.db.BackupRotate(dir, keep)
+	if err != nil {
+		a.logger.Warn("Automatic database backup failed", zap.Error(err))
+		return
+	}
+	a.logger.Info("Database backed up",
+		zap.String("path", path),
+		zap.Int("keep", keep),
+	)
+}
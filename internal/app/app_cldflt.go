@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"go.uber.org/zap"
+)
+
+// offerCldFltReattach asks the user to reattach the Cloud Files filter to a
+// volume, at most once at a time per volume. syncRootPath is a sync root on
+// the volume, probed to confirm the repair.
+func (a *App) offerCldFltReattach(volume, syncRootPath string) {
+	a.mu.Lock()
+	if a.cldFltPrompts[volume] {
+		a.mu.Unlock()
+		return
+	}
+	if a.cldFltPrompts == nil {
+		a.cldFltPrompts = make(map[string]bool)
+	}
+	a.cldFltPrompts[volume] = true
+	a.mu.Unlock()
+
+	if a.notifier != nil {
+		a.notifier.Send("Files On Demand Unavailable",
+			fmt.Sprintf("The Cloud Files filter was detached from %s: online-only files can't be opened.", volume),
+			NotifyError)
+	}
+
+	fyne.Do(func() {
+		parent := a.FyneApp().NewWindow("AnemoneSync - Files On Demand")
+		parent.Resize(fyne.NewSize(500, 200))
+		parent.Show()
+
+		message := fmt.Sprintf("The Windows Cloud Files filter (CldFlt) is no longer attached to %s, "+
+			"usually after running a cleanup tool.\n\n"+
+			"Until it is reattached, online-only files can't be opened and Files On Demand is paused.\n\n"+
+			"Reattach it now? Windows will ask for administrator rights.", volume)
+
+		dialog.ShowConfirm("Reattach Cloud Files Filter", message, func(confirmed bool) {
+			if !confirmed {
+				a.logger.Info("User declined Cloud Files filter reattach", zap.String("volume", volume))
+				a.clearCldFltPrompt(volume)
+				parent.Close()
+				return
+			}
+			go a.reattachCldFlt(volume, syncRootPath, parent)
+		}, parent)
+	})
+}
+
+// reattachCldFlt reattaches the Cloud Files filter, then reconnects the
+// Files On Demand providers.
+func (a *App) reattachCldFlt(volume, syncRootPath string, parent fyne.Window) {
+	err := cloudfiles.ReattachCldFlt(volume, syncRootPath)
+	a.clearCldFltPrompt(volume)
+
+	if err != nil {
+		a.logger.Error("Failed to reattach Cloud Files filter",
+			zap.String("volume", volume),
+			zap.Error(err),
+		)
+		fyne.Do(func() {
+			d := dialog.NewError(fmt.Errorf("%w\n\nAs administrator, run: fltmc attach CldFlt %s", err, volume), parent)
+			d.SetOnClosed(parent.Close)
+			d.Show()
+		})
+		return
+	}
+
+	a.logger.Info("Cloud Files filter reattached", zap.String("volume", volume))
+	fyne.Do(parent.Close)
+	a.reconnectCloudFilesProviders()
+}
+
+func (a *App) clearCldFltPrompt(volume string) {
+	a.mu.Lock()
+	delete(a.cldFltPrompts, volume)
+	a.mu.Unlock()
+}
@@ -5,7 +5,11 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scheduler"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
 )
@@ -20,6 +24,12 @@ func convertDBJobToAppJob(dbJob *database.SyncJob) *SyncJob {
 	if triggerMode == "" {
 		triggerMode = parseTriggerModeFromDB(dbJob.TriggerMode)
 	}
+	// Cron jobs store their expression instead of an app trigger mode
+	var triggerCron string
+	if _, err := scheduler.ParseCron(dbJob.TriggerParams); err == nil && dbJob.TriggerMode == scheduler.ModeScheduled {
+		triggerMode = SyncTriggerCron
+		triggerCron = dbJob.TriggerParams
+	}
 
 	// Parse job options from network_conditions JSON
 	opts := ParseJobOptions(dbJob.NetworkConditions)
@@ -32,18 +42,49 @@ func convertDBJobToAppJob(dbJob *database.SyncJob) *SyncJob {
 		ConflictResolution: dbJob.ConflictResolution,
 		Enabled:            dbJob.Enabled,
 		TriggerMode:        triggerMode,
+		TriggerCron:        triggerCron,
 		LastStatus:         JobStatusIdle,
 		// Options from JSON
 		SyncOnStartup:     opts.SyncOnStartup,
 		FilesOnDemand:     opts.FilesOnDemand,
 		AutoDehydrateDays: opts.AutoDehydrateDays,
+		StorageSense:      opts.StorageSense,
+		SharedSyncRoot:    opts.SharedSyncRoot,
 		TrustSource:       opts.TrustSource,
 		FirstSyncDone:     opts.FirstSyncDone,
+		VolumeGUID:        opts.VolumeGUID,
+		VolumeRoot:        opts.VolumeRoot,
+		SyncAttributes:    opts.SyncAttributes,
+		VerifyTransfers:   opts.VerifyTransfers,
+		SyncACLs:          opts.SyncACLs,
+		SyncStreams:       opts.SyncStreams,
+		ReadLockedFiles:   opts.ReadLockedFiles,
+		JobLog:            opts.JobLog,
+		ExclusionGroups:   opts.ExclusionGroups,
+		MaxChangedFiles:   opts.MaxChangedFiles,
+		MaxChangedBytes:   opts.MaxChangedBytes,
+		PendingApproval:   opts.PendingApproval,
+		// Maintenance windows
+		MaintenanceWindows: opts.MaintenanceWindows,
+		MaintenancePolicy:  opts.MaintenancePolicy,
+		DebounceSeconds:    opts.DebounceSeconds,
+		IdleMinutes:        opts.IdleMinutes,
+		// Bandwidth
+		MaxUploadKBps:   opts.MaxUploadKBps,
+		MaxDownloadKBps: opts.MaxDownloadKBps,
+		ThrottleMetered: opts.ThrottleMetered,
+		// Network conditions
+		WifiSSIDs:    opts.WifiSSIDs,
+		AvoidMetered: opts.AvoidMetered,
+		VPNInterface: opts.VPNInterface,
+	}
+	if job.PendingApproval != "" {
+		job.LastStatus = JobStatusApproval
 	}
 
 	// Parse remote path into components (format: \\host\share\path)
 	// This sets RemoteHost, RemoteShare, and RemotePath (subfolder only)
-	parseRemotePath(dbJob.RemotePath, job)
+	applyEndpoint(dbJob.RemoteEndpoint(), job)
 
 	if dbJob.LastRun != nil {
 		job.LastSync = *dbJob.LastRun
@@ -62,8 +103,35 @@ func convertAppJobToDBJob(job *SyncJob) *database.SyncJob {
 		SyncOnStartup:     job.SyncOnStartup,
 		FilesOnDemand:     job.FilesOnDemand,
 		AutoDehydrateDays: job.AutoDehydrateDays,
+		StorageSense:      job.StorageSense,
+		SharedSyncRoot:    job.SharedSyncRoot,
 		TrustSource:       job.TrustSource,
 		FirstSyncDone:     job.FirstSyncDone,
+		VolumeGUID:        job.VolumeGUID,
+		VolumeRoot:        job.VolumeRoot,
+		SyncAttributes:    job.SyncAttributes,
+		VerifyTransfers:   job.VerifyTransfers,
+		SyncACLs:          job.SyncACLs,
+		SyncStreams:       job.SyncStreams,
+		ReadLockedFiles:   job.ReadLockedFiles,
+		JobLog:            job.JobLog,
+		ExclusionGroups:   job.ExclusionGroups,
+		MaxChangedFiles:   job.MaxChangedFiles,
+		MaxChangedBytes:   job.MaxChangedBytes,
+		PendingApproval:   job.PendingApproval,
+		// Maintenance windows
+		MaintenanceWindows: job.MaintenanceWindows,
+		MaintenancePolicy:  job.MaintenancePolicy,
+		DebounceSeconds:    job.DebounceSeconds,
+		IdleMinutes:        job.IdleMinutes,
+		// Bandwidth
+		MaxUploadKBps:   job.MaxUploadKBps,
+		MaxDownloadKBps: job.MaxDownloadKBps,
+		ThrottleMetered: job.ThrottleMetered,
+		// Network conditions
+		WifiSSIDs:    job.WifiSSIDs,
+		AvoidMetered: job.AvoidMetered,
+		VPNInterface: job.VPNInterface,
 	}
 
 	dbJob := &database.SyncJob{
@@ -72,9 +140,16 @@ func convertAppJobToDBJob(job *SyncJob) *database.SyncJob {
 		LocalPath:          job.LocalPath,
 		RemotePath:         job.FullRemotePath(),
 		ServerCredentialID: job.RemoteHost + "_" + job.Username,
+		Endpoint: smbpath.RemoteEndpoint{
+			Host:         job.RemoteHost,
+			Port:         job.RemotePort,
+			Share:        job.RemoteShare,
+			Path:         smbpath.CleanSharePath(job.RemotePath),
+			CredentialID: job.RemoteHost, // Credentials are stored per server
+		},
 		SyncMode:           string(job.Mode),
 		TriggerMode:        convertTriggerModeForDB(job.TriggerMode),
-		TriggerParams:      string(job.TriggerMode), // Store exact trigger mode
+		TriggerParams:      triggerParamsForDB(job), // Store exact trigger mode
 		ConflictResolution: job.ConflictResolution,
 		NetworkConditions:  opts.ToJSON(), // Store options as JSON
 		Enabled:            job.Enabled,
@@ -90,51 +165,12 @@ func convertAppJobToDBJob(job *SyncJob) *database.SyncJob {
 	return dbJob
 }
 
-// parseRemotePath parses a UNC path into host, share, and path components.
-func parseRemotePath(remotePath string, job *SyncJob) {
-	// Format: \\host\share\path or //host/share/path
-	path := remotePath
-
-	// Remove leading slashes
-	for len(path) > 0 && (path[0] == '/' || path[0] == '\\') {
-		path = path[1:]
-	}
-
-	// Split by / or \
-	parts := splitPath(path)
-
-	if len(parts) >= 1 {
-		job.RemoteHost = parts[0]
-	}
-	if len(parts) >= 2 {
-		job.RemoteShare = parts[1]
-	}
-	if len(parts) >= 3 {
-		// RemotePath is the subfolder within the share (not the full UNC path)
-		job.RemotePath = filepath.Join(parts[2:]...)
-	} else {
-		job.RemotePath = "" // No subfolder, root of share
-	}
-}
-
-// splitPath splits a path by / or \.
-func splitPath(path string) []string {
-	var parts []string
-	current := ""
-	for _, c := range path {
-		if c == '/' || c == '\\' {
-			if current != "" {
-				parts = append(parts, current)
-				current = ""
-			}
-		} else {
-			current += string(c)
-		}
-	}
-	if current != "" {
-		parts = append(parts, current)
-	}
-	return parts
+// applyEndpoint copies the remote endpoint of a database job to an app job.
+func applyEndpoint(endpoint smbpath.RemoteEndpoint, job *SyncJob) {
+	job.RemoteHost = endpoint.Host
+	job.RemotePort = endpoint.Port
+	job.RemoteShare = endpoint.Share
+	job.RemotePath = endpoint.Path // Subfolder within the share ("" = root of share)
 }
 
 // parseDBSyncMode converts a database sync mode string to SyncMode.
@@ -160,6 +196,10 @@ func parseTriggerModeFromDB(trigger string) SyncTriggerMode {
 		return SyncTriggerRealtime
 	case "scheduled":
 		return SyncTrigger1Hour
+	case "network":
+		return SyncTriggerNetwork
+	case "idle":
+		return SyncTriggerIdle
 	default:
 		return SyncTriggerManual
 	}
@@ -174,11 +214,26 @@ func convertTriggerModeForDB(mode SyncTriggerMode) string {
 		return "scheduled"
 	case SyncTriggerRealtime:
 		return "realtime"
+	case SyncTriggerCron:
+		return "scheduled"
+	case SyncTriggerNetwork:
+		return "network"
+	case SyncTriggerIdle:
+		return "idle"
 	default:
 		return "manual"
 	}
 }
 
+// triggerParamsForDB returns the trigger_params of a job: its cron expression,
+// or its exact trigger mode ("5m" and "15m" are both "interval" jobs).
+func triggerParamsForDB(job *SyncJob) string {
+	if job.TriggerMode == SyncTriggerCron {
+		return job.TriggerCron
+	}
+	return string(job.TriggerMode)
+}
+
 // --- SMB Connection Type Conversions ---
 
 // convertDBServerToAppConnection converts a database SMBServer to an app SMBConnection.
@@ -192,6 +247,12 @@ func convertDBServerToAppConnection(dbServer *database.SMBServer) *SMBConnection
 		Domain:       dbServer.Domain,
 		CredentialID: dbServer.CredentialID,
 		SMBVersion:   dbServer.SMBVersion,
+		MTimeSource:  smb.MTimeSource(dbServer.MTimeSource),
+
+		SecurityLevel:   smb.SecurityLevel(dbServer.SecurityLevel),
+		RequireSecurity: dbServer.RequireSecurity,
+
+		RequireEncryption: dbServer.RequireEncryption,
 	}
 }
 
@@ -206,6 +267,12 @@ func convertAppConnectionToDBServer(conn *SMBConnection) *database.SMBServer {
 		Domain:       conn.Domain,
 		CredentialID: conn.CredentialID,
 		SMBVersion:   conn.SMBVersion,
+		MTimeSource:  string(conn.MTimeSource),
+
+		SecurityLevel:   string(conn.SecurityLevel),
+		RequireSecurity: conn.RequireSecurity,
+
+		RequireEncryption: conn.RequireEncryption,
 	}
 }
 
@@ -242,8 +309,16 @@ func (a *App) UpdateJobSizes() {
 			continue
 		}
 
-		// Calculate size
-		size, count, err := CalculateFolderSize(job.LocalPath)
+		// Calculate size (Files On Demand jobs also track on-disk usage)
+		var usage cloudfiles.SpaceUsage
+		var err error
+		if job.FilesOnDemand {
+			usage, err = cloudfiles.ScanSpaceUsage(a.ctx, job.LocalPath)
+		} else {
+			var count int
+			usage.TotalBytes, count, err = CalculateFolderSize(job.LocalPath)
+			usage.TotalFiles = int64(count)
+		}
 		if err != nil {
 			a.logger.Debug("Failed to calculate folder size",
 				zap.Int64("job_id", job.ID),
@@ -257,8 +332,10 @@ func (a *App) UpdateJobSizes() {
 		a.mu.Lock()
 		for _, j := range a.syncJobs {
 			if j.ID == job.ID {
-				j.LocalSize = size
-				j.LocalFileCount = count
+				j.LocalSize = usage.TotalBytes
+				j.LocalFileCount = int(usage.TotalFiles)
+				j.AllocatedSize = usage.AllocatedBytes
+				j.FODSavedBytes = usage.SavedBytes
 				j.SizeUpdatedAt = time.Now()
 				break
 			}
@@ -1,6 +1,10 @@
 package app
 
 import (
+	"errors"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
 )
 
@@ -13,8 +17,24 @@ func (a *App) GetSyncJobs() []*SyncJob {
 	return a.syncJobs
 }
 
+// GetExclusionGroups returns the curated exclusion groups that jobs can toggle.
+func (a *App) GetExclusionGroups() []*database.ExclusionGroup {
+	if a.db == nil {
+		return nil
+	}
+
+	groups, err := a.db.GetExclusionGroups()
+	if err != nil {
+		a.logger.Warn("Failed to load exclusion groups", zap.Error(err))
+		return nil
+	}
+	return groups
+}
+
 // AddSyncJob adds a new sync job.
 func (a *App) AddSyncJob(job *SyncJob) error {
+	a.recordJobVolume(job)
+
 	// Convert to DB job and save
 	dbJob := convertAppJobToDBJob(job)
 
@@ -51,6 +71,8 @@ func (a *App) AddSyncJob(job *SyncJob) error {
 
 // UpdateSyncJob updates an existing sync job.
 func (a *App) UpdateSyncJob(job *SyncJob) error {
+	a.recordJobVolume(job)
+
 	// Persist to database
 	if a.db != nil {
 		dbJob := convertAppJobToDBJob(job)
@@ -125,6 +147,30 @@ func (a *App) TriggerSyncJob(id int64) {
 	go a.ExecuteJobSync(id)
 }
 
+// ApproveJobChanges lets the next sync of a job paused by the change cap
+// exceed the cap, and starts it.
+func (a *App) ApproveJobChanges(id int64) {
+	a.mu.Lock()
+	var job *SyncJob
+	for _, j := range a.syncJobs {
+		if j.ID == id {
+			j.ChangesApproved = true
+			job = j
+			break
+		}
+	}
+	a.mu.Unlock()
+	if job == nil {
+		return
+	}
+
+	a.logger.Info("Changes approved for job",
+		zap.String("name", job.Name),
+		zap.String("reason", job.PendingApproval),
+	)
+	go a.ExecuteJobSync(id)
+}
+
 // TriggerSync manually triggers a sync for all enabled jobs.
 func (a *App) TriggerSync() {
 	if a.IsSyncing() {
@@ -146,6 +192,12 @@ func (a *App) TriggerSync() {
 
 // ExecuteJobSync executes sync for a specific job (called by scheduler/watcher).
 func (a *App) ExecuteJobSync(jobID int64) {
+	a.ExecuteJobSyncScoped(jobID, "")
+}
+
+// ExecuteJobSyncScoped executes sync for a folder of a job, relative to its
+// local path ("" = whole job). Used by the watcher for changes in one folder.
+func (a *App) ExecuteJobSyncScoped(jobID int64, subtree string) {
 	// Find job
 	a.mu.RLock()
 	var job *SyncJob
@@ -167,16 +219,25 @@ func (a *App) ExecuteJobSync(jobID int64) {
 		return
 	}
 
+	// Runs exceeding the change cap wait for the user (see ApproveJobChanges)
+	if job.PendingApproval != "" && !job.ChangesApproved {
+		a.logger.Warn("Job paused until its changes are approved",
+			zap.String("name", job.Name),
+			zap.String("reason", job.PendingApproval),
+		)
+		return
+	}
+
 	// Use sync manager if available
 	if a.syncManager != nil {
-		if err := a.syncManager.ExecuteSync(job); err != nil {
+		if err := a.syncManager.ExecuteScopedSync(job, subtree); err != nil {
 			// "Sync already in progress" is expected when file watcher detects
 			// changes made by an ongoing sync - log as debug, not error
 			if err.Error() == "sync already in progress for job "+job.Name {
 				a.logger.Debug("Sync skipped (already running)",
 					zap.String("name", job.Name),
 				)
-			} else {
+			} else if !errors.Is(err, errVolumeMoved) { // Paused jobs are logged by the sync manager
 				a.logger.Error("Sync failed",
 					zap.String("name", job.Name),
 					zap.Error(err),
@@ -189,7 +250,7 @@ func (a *App) ExecuteJobSync(jobID int64) {
 	}
 }
 
-// StopSync stops all running syncs.
+// StopSync cancels all running syncs (tray "Cancel All Syncs").
 func (a *App) StopSync() {
 	if a.syncManager == nil {
 		return
@@ -215,6 +276,14 @@ func (a *App) StopJobSync(id int64) {
 	}
 }
 
+// RunningSyncs returns the syncs in progress with their phase and progress.
+func (a *App) RunningSyncs() []syncpkg.RunningSync {
+	if a.syncManager == nil {
+		return nil
+	}
+	return a.syncManager.ListRunningSyncs()
+}
+
 // IsJobSyncing returns whether a specific job is currently syncing.
 func (a *App) IsJobSyncing(id int64) bool {
 	if a.syncManager == nil {
@@ -269,8 +338,30 @@ func (a *App) DisableFilesOnDemand(jobID int64) error {
 		if err := a.db.UpdateSyncJob(dbJob); err != nil {
 			return err
 		}
+		// Placeholders are gone: re-enabling must create them all again
+		if err := a.db.ReplaceRemoteSnapshot(job.ID, nil); err != nil {
+			a.logger.Warn("Failed to clear remote listing snapshot", zap.Error(err))
+		}
 	}
 
 	a.logger.Info("Files On Demand disabled for job", zap.String("name", job.Name))
 	return nil
 }
+
+// ApplySyncRootScope shares or unshares the sync root of a Files On Demand
+// job with the other accounts of the PC. Requires administrator rights.
+func (a *App) ApplySyncRootScope(job *SyncJob) error {
+	if a.syncManager == nil || !job.FilesOnDemand {
+		return nil
+	}
+	return a.syncManager.ApplySyncRootScope(job)
+}
+
+// ApplyStorageSense applies the Storage Sense setting of a job to its
+// registered sync root, after the user changed it.
+func (a *App) ApplyStorageSense(job *SyncJob) error {
+	if a.syncManager == nil || !job.FilesOnDemand {
+		return nil
+	}
+	return a.syncManager.ApplyStorageSense(job)
+}
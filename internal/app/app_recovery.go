@@ -0,0 +1,112 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// --- Safe Mode (corrupt database recovery) ---
+
+// recoverDatabase recovers a corrupt database instead of failing startup:
+// the corrupt file is backed up, readable data is salvaged into a new
+// database and jobs run in dry-run until the user confirms (safe mode).
+func (a *App) recoverDatabase(cfg database.Config, openErr error) (*database.DB, error) {
+	a.logger.Error("Database is corrupt, starting recovery",
+		zap.String("path", cfg.Path),
+		zap.Error(openErr),
+	)
+
+	db, report, err := database.Recover(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("database recovery failed: %w", err)
+	}
+	a.recoveryReport = report
+
+	a.logger.Warn("Database recovered, safe mode enabled",
+		zap.String("backup", report.BackupPath),
+		zap.Any("recovered_rows", report.RecoveredRows),
+		zap.Any("failed_tables", report.FailedTables),
+		zap.Bool("baseline_reset", report.BaselineReset),
+	)
+	return db, nil
+}
+
+// SafeMode reports whether syncs must run in dry-run because the database
+// was recovered and the user has not confirmed it yet.
+func (a *App) SafeMode() bool {
+	return a.db != nil && a.db.SafeMode()
+}
+
+// ConfirmRecoveredDatabase leaves safe mode: syncs apply changes again.
+func (a *App) ConfirmRecoveredDatabase() error {
+	if a.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if err := a.db.SetSafeMode(false); err != nil {
+		return err
+	}
+	a.logger.Info("Recovered database confirmed, leaving safe mode")
+	return nil
+}
+
+// confirmSafeMode asks the user to confirm the recovered database while in safe mode.
+func (a *App) confirmSafeMode() {
+	if !a.SafeMode() {
+		return
+	}
+
+	message := "AnemoneSync's database was damaged and has been recovered.\n\n" +
+		recoverySummary(a.recoveryReport) +
+		"\nSyncs run in dry-run mode (nothing is changed) until you confirm that\n" +
+		"your jobs and servers look right.\n\nResume normal syncing now?"
+
+	fyne.Do(func() {
+		parent := a.FyneApp().NewWindow("AnemoneSync - Database Recovered")
+		parent.Resize(fyne.NewSize(500, 250))
+		parent.Show()
+
+		dialog.ShowConfirm("Database Recovered", message, func(confirmed bool) {
+			if confirmed {
+				if err := a.ConfirmRecoveredDatabase(); err != nil {
+					dialog.ShowError(err, parent)
+					return
+				}
+			} else {
+				a.logger.Info("User kept safe mode, syncs stay in dry-run")
+			}
+			parent.Close()
+		}, parent)
+	})
+}
+
+// recoverySummary describes a recovery report for the user.
+// Returns "" when the recovery happened in a previous session.
+func recoverySummary(report *database.RecoveryReport) string {
+	if report == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Backup of the damaged file: %s\n", report.BackupPath)
+	if len(report.FailedTables) > 0 {
+		tables := make([]string, 0, len(report.FailedTables))
+		for table := range report.FailedTables {
+			if table == "*" {
+				table = "all data"
+			}
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		fmt.Fprintf(&b, "Could not be fully recovered: %s\n", strings.Join(tables, ", "))
+	}
+	if report.BaselineReset {
+		b.WriteString("Sync history was lost: the next sync compares every file again.\n")
+	}
+	return b.String()
+}
@@ -1,6 +1,9 @@
 package app
 
 import (
+	"context"
+	"strings"
+
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -98,6 +101,60 @@ func (a *App) SetNotificationsEnabled(enabled bool) {
 	a.logger.Info("Notifications setting changed", zap.Bool("enabled", enabled))
 }
 
+// GetPauseSyncWhenPresenting returns whether automatic syncs wait while the
+// user presents.
+func (a *App) GetPauseSyncWhenPresenting() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.appSettings.PauseSyncWhenPresenting
+}
+
+// SetPauseSyncWhenPresenting sets whether automatic syncs wait while the user
+// presents or runs a full-screen application.
+func (a *App) SetPauseSyncWhenPresenting(enabled bool) {
+	a.mu.Lock()
+	a.appSettings.PauseSyncWhenPresenting = enabled
+	a.mu.Unlock()
+
+	// Persist to database
+	if a.db != nil {
+		value := "false"
+		if enabled {
+			value = "true"
+		}
+		a.db.SetAppConfig("pause_sync_presenting", value, "bool")
+	}
+
+	a.logger.Info("Pause sync when presenting setting changed", zap.Bool("enabled", enabled))
+}
+
+// GetHoldNotificationsWhenPresenting returns whether notifications wait while
+// the user presents.
+func (a *App) GetHoldNotificationsWhenPresenting() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.appSettings.HoldNotificationsWhenPresenting
+}
+
+// SetHoldNotificationsWhenPresenting sets whether notifications are shown
+// only once the user stops presenting.
+func (a *App) SetHoldNotificationsWhenPresenting(enabled bool) {
+	a.mu.Lock()
+	a.appSettings.HoldNotificationsWhenPresenting = enabled
+	a.mu.Unlock()
+
+	// Persist to database
+	if a.db != nil {
+		value := "true"
+		if !enabled {
+			value = "false"
+		}
+		a.db.SetAppConfig("hold_notifications_presenting", value, "bool")
+	}
+
+	a.logger.Info("Hold notifications when presenting setting changed", zap.Bool("enabled", enabled))
+}
+
 // SetLogLevel changes the logging level dynamically.
 func (a *App) SetLogLevel(level string) {
 	a.mu.Lock()
@@ -162,6 +219,34 @@ func (a *App) SetSyncInterval(interval string) {
 	a.logger.Info("Sync interval changed", zap.String("interval", interval))
 }
 
+// GetWebhookURLs returns the URLs receiving the result of every sync.
+func (a *App) GetWebhookURLs() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]string(nil), a.appSettings.WebhookURLs...)
+}
+
+// SetWebhookURLs changes the URLs receiving the result of every sync.
+func (a *App) SetWebhookURLs(urls []string) {
+	a.mu.Lock()
+	a.appSettings.WebhookURLs = urls
+	a.mu.Unlock()
+
+	// Persist to database
+	if a.db != nil {
+		a.db.SetAppConfig("webhook_urls", strings.Join(urls, "\n"), "string")
+	}
+
+	a.logger.Info("Webhooks changed", zap.Int("count", len(urls)))
+}
+
+// TestWebhook posts a test notification to a webhook URL.
+func (a *App) TestWebhook(url string) error {
+	ctx, cancel := context.WithTimeout(a.ctx, webhookTimeout)
+	defer cancel()
+	return a.webhooks.Test(ctx, url)
+}
+
 // SaveCredential saves credentials to the keyring.
 func (a *App) SaveCredential(host, username, password, domain string, port int) error {
 	a.logger.Debug("Saving credential", zap.String("host", host), zap.String("user", username))
@@ -141,10 +141,11 @@ func (a *App) LoadSMBCredential(host string) (*smb.Credentials, error) {
 	return a.credMgr.Load(host)
 }
 
-// TestSMBConnection tests an SMB connection by listing shares.
-func (a *App) TestSMBConnection(host, username, password, domain string, port int) error {
-	_, err := smb.ListSharesOnServer(host, port, username, password, domain, a.logger.Named("smb-test"))
-	return err
+// TestSMBConnection tests an SMB connection stage by stage, up to listing the
+// shares. With requireEncryption, a server not negotiating encryption fails
+// the test.
+func (a *App) TestSMBConnection(host, username, password, domain string, port int, requireEncryption bool) (*smb.ProbeResult, error) {
+	return smb.ProbeServer(host, port, username, password, domain, requireEncryption, a.logger.Named("smb-test"))
 }
 
 // ListSMBShares lists available shares on an SMB server.
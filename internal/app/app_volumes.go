@@ -0,0 +1,179 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"go.uber.org/zap"
+)
+
+// --- Volume Tracking ---
+
+// recordJobVolume stores the volume GUID path hosting the job's local folder,
+// so a drive letter change can be detected at next startup.
+func (a *App) recordJobVolume(job *SyncJob) {
+	guidPath, root, err := cloudfiles.VolumeGUIDPath(job.LocalPath)
+	if err != nil {
+		// Network paths and missing drives have no volume GUID; keep previous values
+		a.logger.Debug("Failed to get volume GUID for job",
+			zap.String("name", job.Name),
+			zap.String("local_path", job.LocalPath),
+			zap.Error(err),
+		)
+		return
+	}
+	job.VolumeGUID = guidPath
+	job.VolumeRoot = root
+	job.VolumeMoved = ""
+}
+
+// errVolumeMoved is returned for syncs of a job whose drive was remounted
+// elsewhere, until the user confirms the new path.
+var errVolumeMoved = errors.New("paused until the new drive location is confirmed")
+
+// checkVolumeMoved refuses to sync a job against a folder whose drive was
+// remounted elsewhere: the missing folder would look like local deletions.
+func checkVolumeMoved(job *SyncJob) error {
+	if job.VolumeMoved == "" {
+		return nil
+	}
+	return fmt.Errorf("job %s %w (%s)", job.Name, errVolumeMoved, job.VolumeMoved)
+}
+
+// detectMovedVolumes checks, for each job, that its local folder is still on the
+// volume it was created on. When the volume was remounted under another drive
+// letter, the job is paused (VolumeMoved is set) until the user confirms the new path.
+// Returns the jobs that need confirmation.
+func (a *App) detectMovedVolumes() []*SyncJob {
+	a.mu.RLock()
+	jobs := make([]*SyncJob, len(a.syncJobs))
+	copy(jobs, a.syncJobs)
+	a.mu.RUnlock()
+
+	moved := make([]*SyncJob, 0)
+	for _, job := range jobs {
+		if job.VolumeGUID == "" || job.VolumeRoot == "" {
+			continue
+		}
+
+		// Still on the same volume: nothing to do
+		if guidPath, _, err := cloudfiles.VolumeGUIDPath(job.LocalPath); err == nil && guidPath == job.VolumeGUID {
+			if _, err := os.Stat(job.LocalPath); err == nil {
+				continue
+			}
+		}
+
+		mountPoints, err := cloudfiles.VolumeMountPoints(job.VolumeGUID)
+		if err != nil || len(mountPoints) == 0 {
+			a.logger.Warn("Volume for job is not mounted",
+				zap.String("name", job.Name),
+				zap.String("volume", job.VolumeGUID),
+			)
+			continue
+		}
+
+		newPath := remapVolumePath(job.LocalPath, job.VolumeRoot, mountPoints[0])
+		if newPath == "" || strings.EqualFold(newPath, job.LocalPath) {
+			continue
+		}
+		if info, err := os.Stat(newPath); err != nil || !info.IsDir() {
+			continue
+		}
+
+		a.logger.Warn("Volume for job was remounted under a different path",
+			zap.String("name", job.Name),
+			zap.String("old_path", job.LocalPath),
+			zap.String("new_path", newPath),
+		)
+
+		a.mu.Lock()
+		job.VolumeMoved = newPath
+		a.mu.Unlock()
+		moved = append(moved, job)
+	}
+
+	return moved
+}
+
+// confirmMovedVolumes asks the user to confirm the new local path of each moved job.
+func (a *App) confirmMovedVolumes(jobs []*SyncJob) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	fyne.Do(func() {
+		parent := a.FyneApp().NewWindow("AnemoneSync - Drive Changed")
+		parent.Resize(fyne.NewSize(500, 200))
+		parent.Show()
+
+		a.confirmNextMovedVolume(parent, jobs)
+	})
+}
+
+// confirmNextMovedVolume shows the confirmation for jobs[0], then the remaining ones.
+func (a *App) confirmNextMovedVolume(parent fyne.Window, jobs []*SyncJob) {
+	if len(jobs) == 0 {
+		parent.Close()
+		return
+	}
+
+	job := jobs[0]
+	message := fmt.Sprintf("The drive used by job '%s' is now mounted at a different location.\n\n"+
+		"Old folder: %s\nNew folder: %s\n\nUpdate the job to use the new folder?\n"+
+		"The job stays paused until you confirm.",
+		job.Name, job.LocalPath, job.VolumeMoved)
+
+	dialog.ShowConfirm("Drive Letter Changed", message, func(confirmed bool) {
+		if confirmed {
+			if err := a.applyMovedVolume(job); err != nil {
+				dialog.ShowError(err, parent)
+			}
+		} else {
+			a.logger.Info("User declined local path remap, job stays paused",
+				zap.String("name", job.Name),
+			)
+		}
+		a.confirmNextMovedVolume(parent, jobs[1:])
+	}, parent)
+}
+
+// applyMovedVolume switches the job to its remapped local path and persists it.
+func (a *App) applyMovedVolume(job *SyncJob) error {
+	a.mu.Lock()
+	oldPath := job.LocalPath
+	job.LocalPath = job.VolumeMoved
+	a.mu.Unlock()
+
+	if err := a.UpdateSyncJob(job); err != nil {
+		return err
+	}
+
+	a.logger.Info("Remapped job local path after drive letter change",
+		zap.String("name", job.Name),
+		zap.String("old_path", oldPath),
+		zap.String("new_path", job.LocalPath),
+	)
+	return nil
+}
+
+// remapVolumePath rebases localPath from oldRoot onto newRoot.
+// Returns "" if localPath is not under oldRoot.
+func remapVolumePath(localPath, oldRoot, newRoot string) string {
+	localPath = filepath.Clean(localPath)
+	oldRoot = filepath.Clean(oldRoot)
+
+	rel, err := filepath.Rel(oldRoot, localPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	if rel == "." {
+		return filepath.Clean(newRoot)
+	}
+	return filepath.Join(newRoot, rel)
+}
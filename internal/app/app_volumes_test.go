@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemapVolumePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		localPath string
+		oldRoot   string
+		newRoot   string
+		want      string
+	}{
+		{"drive letter change", `E:\Data\Photos`, `E:\`, `F:\`, `F:\Data\Photos`},
+		{"volume root", `E:\`, `E:\`, `F:\`, `F:\`},
+		{"mounted folder", `E:\Data`, `E:\`, `C:\Mount\Disk2\`, `C:\Mount\Disk2\Data`},
+		{"UNC", `\\nas\share\Sync\Docs`, `\\nas\share\`, `\\nas2\share\`, `\\nas2\share\Sync\Docs`},
+		{"trailing separator", `E:\Data\`, `E:\`, `F:\`, `F:\Data`},
+		{"no match", `D:\Data`, `E:\`, `F:\`, ""},
+		{"sibling prefix", `E:\Database`, `E:\Data`, `F:\Data`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remapVolumePath(tt.localPath, tt.oldRoot, tt.newRoot); got != tt.want {
+				t.Errorf("remapVolumePath(%q, %q, %q) = %q, want %q", tt.localPath, tt.oldRoot, tt.newRoot, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckVolumeMoved(t *testing.T) {
+	job := &SyncJob{Name: "Photos"}
+	if err := checkVolumeMoved(job); err != nil {
+		t.Fatalf("checkVolumeMoved() = %v, want nil", err)
+	}
+
+	job.VolumeMoved = `F:\Photos`
+	if err := checkVolumeMoved(job); !errors.Is(err, errVolumeMoved) {
+		t.Errorf("checkVolumeMoved() = %v, want errVolumeMoved", err)
+	}
+}
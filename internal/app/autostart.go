@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
 	"golang.org/x/sys/windows/registry"
 )
 
@@ -50,8 +51,7 @@ func (a *AutoStart) IsEnabled() bool {
 	}
 
 	// Match with quotes and flag
-	expectedCmd := `"` + cleanExe + `" --autostart`
-	return value == expectedCmd
+	return value == a.commandLine()
 }
 
 // Enable enables auto-start by adding a registry entry.
@@ -63,9 +63,18 @@ func (a *AutoStart) Enable() error {
 	}
 	defer key.Close()
 
-	// Add --autostart flag so the app knows it was launched at Windows startup
-	cmdLine := `"` + a.exePath + `" --autostart`
-	return key.SetStringValue(registryKeyName, cmdLine)
+	return key.SetStringValue(registryKeyName, a.commandLine())
+}
+
+// commandLine returns the command started at Windows startup. The --autostart
+// flag lets the app know it was launched at Windows startup, --data-dir keeps
+// the data directory it was enabled from.
+func (a *AutoStart) commandLine() string {
+	cmdLine := `"` + filepath.Clean(a.exePath) + `" --autostart`
+	if dir := config.DataDirOverride(); dir != "" {
+		cmdLine += ` --data-dir "` + dir + `"`
+	}
+	return cmdLine
 }
 
 // Disable disables auto-start by removing the registry entry.
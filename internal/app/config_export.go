@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 	"go.uber.org/zap"
 )
 
@@ -229,11 +230,16 @@ func (a *App) ImportConfig(path string) (*ImportResult, error) {
 	}
 	existingJobPaths := make(map[jobKey]*database.SyncJob)
 	for _, j := range existingJobs {
-		existingJobPaths[jobKey{j.LocalPath, j.RemotePath}] = j
+		existingJobPaths[jobKey{j.LocalPath, smbpath.SplitUNC(j.RemotePath).String()}] = j
 	}
 
 	for _, ej := range export.SyncJobs {
-		key := jobKey{ej.LocalPath, ej.RemotePath}
+		remote, err := smbpath.ParseUNC(ej.RemotePath)
+		if err != nil {
+			a.logger.Warn("Skipping job with invalid remote path", zap.String("name", ej.Name), zap.Error(err))
+			continue
+		}
+		key := jobKey{ej.LocalPath, remote.String()}
 		if existing, ok := existingJobPaths[key]; ok {
 			jobIDMap[ej.ID] = existing.ID
 			result.JobsSkipped++
@@ -243,7 +249,7 @@ func (a *App) ImportConfig(path string) (*ImportResult, error) {
 		dbJob := &database.SyncJob{
 			Name:               ej.Name,
 			LocalPath:          ej.LocalPath,
-			RemotePath:         ej.RemotePath,
+			RemotePath:         remote.String(),
 			ServerCredentialID: ej.ServerCredentialID,
 			SyncMode:           ej.SyncMode,
 			TriggerMode:        ej.TriggerMode,
@@ -225,9 +225,10 @@ func (d *DehydrateDialog) filterFiles() {
 }
 
 func (d *DehydrateDialog) updateStats() {
+	// Compressed or sparse files free less than their logical size
 	var totalSize int64
 	for _, f := range d.filteredFiles {
-		totalSize += f.Size
+		totalSize += f.AllocatedSize
 	}
 
 	d.fileCountLabel.SetText(fmt.Sprintf("%d files", len(d.filteredFiles)))
@@ -246,7 +247,7 @@ func (d *DehydrateDialog) onDehydrate() {
 
 	var totalSize int64
 	for _, f := range d.filteredFiles {
-		totalSize += f.Size
+		totalSize += f.AllocatedSize
 	}
 
 	msg := fmt.Sprintf("Free up %s from %d files?\n\nFiles will become placeholders and will be downloaded again when opened.",
@@ -297,7 +298,7 @@ func (d *DehydrateDialog) doDehydrate() {
 				continue
 			}
 			successCount++
-			bytesFreed += file.Size
+			bytesFreed += file.AllocatedSize
 		}
 
 		fyne.Do(func() {
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/errmsg"
+)
+
+// friendlyError returns the localized user message for err. server names the
+// SMB server involved ("" if unknown).
+func (a *App) friendlyError(err error, server string) string {
+	return errmsg.Message(err, a.language, server)
+}
+
+// showFriendlyError shows the user message for err, with the raw error in a
+// collapsed "Details" section.
+func (a *App) showFriendlyError(err error, server string, parent fyne.Window) {
+	showErrorDetails(a.friendlyError(err, server), err.Error(), parent)
+}
+
+// showErrorDetails shows a user message with the technical details in a
+// collapsed "Details" section.
+func showErrorDetails(message, details string, parent fyne.Window) {
+	messageLabel := widget.NewLabel(message)
+	messageLabel.Wrapping = fyne.TextWrapWord
+
+	detailsLabel := widget.NewLabelWithStyle(details, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	detailsLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(
+		messageLabel,
+		widget.NewAccordion(widget.NewAccordionItem("Details", detailsLabel)),
+	)
+
+	d := dialog.NewCustom("Error", "OK", content, parent)
+	d.Resize(fyne.NewSize(480, 0))
+	d.Show()
+}
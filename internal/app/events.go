@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+// authFailuresReported is the number of consecutive authentication failures
+// on a server from which they are written to the event log: a single refusal
+// is often a password being changed.
+const authFailuresReported = 3
+
+// logJobFailure writes a failed sync to the event log and counts the
+// authentication failures of its server. Cancelled syncs are not failures.
+func (m *SyncManager) logJobFailure(job *SyncJob, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	eventlog.Error(eventlog.EventJobFailed, fmt.Sprintf(
+		"Sync of job \"%s\" (ID %d, %s) failed: %v", job.Name, job.ID, job.FullRemotePath(), err))
+
+	switch syncpkg.ErrorCodeOf(err) {
+	case syncpkg.ErrorCodeAuthFailed, syncpkg.ErrorCodePasswordExpired:
+	default:
+		return
+	}
+
+	host := strings.ToLower(job.RemoteHost)
+	m.eventsMu.Lock()
+	if m.authFailures == nil {
+		m.authFailures = make(map[string]int)
+	}
+	m.authFailures[host]++
+	count := m.authFailures[host]
+	m.eventsMu.Unlock()
+
+	// Once per series, until a sync on the server succeeds again
+	if count == authFailuresReported {
+		eventlog.Warning(eventlog.EventAuthFailures, fmt.Sprintf(
+			"Server %s refused the credentials %d times in a row (last job: \"%s\"). Check the password saved in AnemoneSync or the account on the server.",
+			job.RemoteHost, count, job.Name))
+	}
+}
+
+// resetAuthFailures ends the series of authentication failures of the server of a job.
+func (m *SyncManager) resetAuthFailures(job *SyncJob) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	delete(m.authFailures, strings.ToLower(job.RemoteHost))
+}
+
+// logRansomwareSuspected writes a sync paused by the ransomware guard to the event log.
+func logRansomwareSuspected(job *SyncJob, ransomErr *syncpkg.RansomwareError) {
+	eventlog.Error(eventlog.EventRansomwareSuspected, fmt.Sprintf(
+		"Sync of job \"%s\" (ID %d, %s) was paused: local changes look like ransomware (%s). Nothing was sent to the server. Examples: %s",
+		job.Name, job.ID, job.LocalPath, strings.Join(ransomErr.Reasons, "; "), strings.Join(ransomErr.Examples, ", ")))
+}
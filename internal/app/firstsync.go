@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
@@ -44,9 +46,14 @@ type FileDifference struct {
 	RemoteMTime  time.Time
 	LocalHash    string
 	RemoteHash   string
+	RemoteOwner  string // Owner of the server version, as DOMAIN\user ("" = not read)
 	Type         DifferenceType
 }
 
+// maxListedConflicts is the number of conflicting files listed by the first
+// sync wizard.
+const maxListedConflicts = 10
+
 // DifferenceType indicates the type of difference
 type DifferenceType string
 
@@ -167,6 +174,15 @@ func (a *FirstSyncAnalyzer) Analyze(ctx context.Context, job *SyncJob) (*FirstSy
 		}
 	}
 
+	// On shares written by several users, show who changed the server
+	// version of the listed conflicts
+	sort.Slice(analysis.ConflictFiles, func(i, j int) bool {
+		return analysis.ConflictFiles[i].Path < analysis.ConflictFiles[j].Path
+	})
+	if a.app.syncManager != nil && a.app.syncManager.RemoteOwners() {
+		a.readOwners(smbClient, job.RemotePath, analysis.ConflictFiles[:min(len(analysis.ConflictFiles), maxListedConflicts)])
+	}
+
 	analysis.AnalysisDuration = time.Since(start)
 
 	a.logger.Info("first sync analysis completed",
@@ -240,6 +256,19 @@ func (a *FirstSyncAnalyzer) scanRemote(ctx context.Context, smbClient *smb.SMBCl
 	return scanResult.Files, nil
 }
 
+// readOwners reads the owner of the server version of files. Owners that
+// can't be read are left empty.
+func (a *FirstSyncAnalyzer) readOwners(smbClient *smb.SMBClient, remotePath string, files []FileDifference) {
+	for i := range files {
+		owner, err := smbClient.Owner(path.Join(remotePath, files[i].Path))
+		if err != nil {
+			a.logger.Debug("remote owner not read", zap.String("path", files[i].Path), zap.Error(err))
+			continue
+		}
+		files[i].RemoteOwner = owner
+	}
+}
+
 // compareFiles compares two files and returns the difference type
 func (a *FirstSyncAnalyzer) compareFiles(path string, local, remote *cache.FileInfo) FileDifference {
 	diff := FileDifference{Path: path}
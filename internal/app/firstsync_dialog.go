@@ -8,6 +8,8 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 )
 
 // FirstSyncDialog handles the first sync wizard UI
@@ -182,11 +184,12 @@ func (d *FirstSyncDialog) showResults() {
 		// Default to "recent" which is safest
 		d.selectedTrust = TrustSourceRecent
 
+		keepBoth := d.keepBothLabel()
 		conflictGroup := widget.NewRadioGroup([]string{
 			"Most recent wins (recommended)",
 			"PC version wins (upload to server)",
 			"Server version wins (download to PC)",
-			"Keep both (download server version with .server suffix)",
+			keepBoth,
 		}, func(selected string) {
 			switch selected {
 			case "Most recent wins (recommended)":
@@ -195,7 +198,7 @@ func (d *FirstSyncDialog) showResults() {
 				d.selectedTrust = TrustSourceLocal
 			case "Server version wins (download to PC)":
 				d.selectedTrust = TrustSourceServer
-			case "Keep both (download server version with .server suffix)":
+			case keepBoth:
 				d.selectedTrust = TrustSourceKeepBoth
 			}
 		})
@@ -204,6 +207,7 @@ func (d *FirstSyncDialog) showResults() {
 		contentItems = append(contentItems,
 			widget.NewSeparator(),
 			conflictTitle,
+			conflictFileList(a.ConflictFiles),
 			conflictGroup,
 		)
 	} else {
@@ -246,6 +250,33 @@ func (d *FirstSyncDialog) showResults() {
 	d.window.Resize(fyne.NewSize(550, 450))
 }
 
+// keepBothLabel describes the "keep both" choice with the name the server
+// copy gets, as configured by sync.conflict_name_pattern.
+func (d *FirstSyncDialog) keepBothLabel() string {
+	pattern := syncpkg.DefaultConflictNamePattern
+	if p := d.app.cfg.Sync.ConflictNamePattern; p != "" && syncpkg.ValidateConflictNamePattern(p) == nil {
+		pattern = p
+	}
+	return fmt.Sprintf("Keep both (download server version as %s)", pattern)
+}
+
+// conflictFileList lists the first conflicting files, with who changed the
+// server version when it was read.
+func conflictFileList(files []FileDifference) fyne.CanvasObject {
+	list := container.NewVBox()
+	for _, f := range files[:min(len(files), maxListedConflicts)] {
+		text := "  " + f.Path
+		if f.RemoteOwner != "" {
+			text += fmt.Sprintf(" (server version changed by %s)", f.RemoteOwner)
+		}
+		list.Add(widget.NewLabel(text))
+	}
+	if more := len(files) - maxListedConflicts; more > 0 {
+		list.Add(widget.NewLabel(fmt.Sprintf("  ... and %d more", more)))
+	}
+	return list
+}
+
 // FormatBytes formats bytes to human readable string
 func FormatBytes(bytes int64) string {
 	const (
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// healthCheckInterval is how often the health checks run in the background
+	healthCheckInterval = 5 * time.Minute
+
+	// unreachableAlertAfter is how long a server may stay unreachable before
+	// it is reported (short outages are normal for a NAS or a laptop)
+	unreachableAlertAfter = 24 * time.Hour
+
+	// lowDiskSpaceBytes is the free space under which the database volume is
+	// reported as nearly full
+	lowDiskSpaceBytes = 1 << 30
+)
+
+// HealthIssueKind identifies a degraded state, and so its remediation.
+type HealthIssueKind string
+
+const (
+	// Onboarding checklist
+	HealthNoServer  HealthIssueKind = "no_server"  // No SMB server configured yet
+	HealthNoJob     HealthIssueKind = "no_job"     // No sync job configured yet
+	HealthFirstSync HealthIssueKind = "first_sync" // No job has completed a sync yet
+	// Degraded states
+	HealthCredentials HealthIssueKind = "credentials" // Server refuses the saved credentials
+	HealthUnreachable HealthIssueKind = "unreachable" // Server unreachable for over a day
+	HealthDiskSpace   HealthIssueKind = "disk_space"  // Database volume nearly full
+	HealthSyncRoot    HealthIssueKind = "sync_root"   // Files On Demand sync root missing or unregistered
+	HealthCorruption  HealthIssueKind = "corruption"  // Transfers with a server often read back corrupted
+)
+
+// HealthIssue is a degraded state shown in the health banner.
+type HealthIssue struct {
+	Kind    HealthIssueKind
+	JobID   int64  // Job concerned (0 = none)
+	ConnID  int64  // SMB connection concerned (0 = none)
+	Message string // What is wrong, for the user
+	Action  string // Label of the one-click remediation ("" = none)
+}
+
+// Onboarding reports whether the issue is a setup step rather than a degraded state.
+func (i HealthIssue) Onboarding() bool {
+	return i.Kind == HealthNoServer || i.Kind == HealthNoJob || i.Kind == HealthFirstSync
+}
+
+// CheckHealth returns the degraded states of the app, onboarding steps first.
+func (a *App) CheckHealth() []HealthIssue {
+	issues := a.checkOnboarding()
+	if len(issues) > 0 {
+		return issues
+	}
+
+	a.mu.RLock()
+	jobs := make([]*SyncJob, len(a.syncJobs))
+	copy(jobs, a.syncJobs)
+	a.mu.RUnlock()
+
+	issues = append(issues, a.checkServerHealth(jobs)...)
+	issues = append(issues, a.checkTransferIntegrity()...)
+	if issue := a.checkDatabaseDiskSpace(); issue != nil {
+		issues = append(issues, *issue)
+	}
+	for _, job := range jobs {
+		if issue := a.checkSyncRoot(job); issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+	return issues
+}
+
+// checkOnboarding returns the next setup step left: add a server, add a job,
+// run a first sync.
+func (a *App) checkOnboarding() []HealthIssue {
+	if len(a.GetSMBConnections()) == 0 {
+		return []HealthIssue{{
+			Kind:    HealthNoServer,
+			Message: "Getting started: add the SMB server holding your files.",
+			Action:  "Add Server",
+		}}
+	}
+
+	jobs := a.GetSyncJobs()
+	if len(jobs) == 0 {
+		return []HealthIssue{{
+			Kind:    HealthNoJob,
+			Message: "Getting started: create a sync job pairing a local folder with a server folder.",
+			Action:  "Add Job",
+		}}
+	}
+
+	for _, job := range jobs {
+		if !job.LastSync.IsZero() {
+			return nil
+		}
+	}
+	return []HealthIssue{{
+		Kind:    HealthFirstSync,
+		JobID:   jobs[0].ID,
+		Message: fmt.Sprintf("Getting started: run the first sync of '%s'.", jobs[0].Name),
+		Action:  "Sync Now",
+	}}
+}
+
+// checkServerHealth reports servers refusing their credentials, and servers
+// unreachable for more than unreachableAlertAfter (one issue per server).
+func (a *App) checkServerHealth(jobs []*SyncJob) []HealthIssue {
+	var issues []HealthIssue
+	seen := make(map[int64]bool)
+	for _, job := range jobs {
+		if !job.Enabled || seen[job.SMBConnectionID] {
+			continue
+		}
+
+		switch job.LastErrorCode {
+		case syncpkg.ErrorCodeAuthFailed, syncpkg.ErrorCodePasswordExpired, syncpkg.ErrorCodeCredentialsMissing:
+			seen[job.SMBConnectionID] = true
+			issues = append(issues, HealthIssue{
+				Kind:    HealthCredentials,
+				JobID:   job.ID,
+				ConnID:  job.SMBConnectionID,
+				Message: job.LastError,
+				Action:  "Update Password",
+			})
+		case syncpkg.ErrorCodeServerUnreachable:
+			if job.LastSync.IsZero() || time.Since(job.LastSync) < unreachableAlertAfter {
+				continue
+			}
+			seen[job.SMBConnectionID] = true
+			issues = append(issues, HealthIssue{
+				Kind:   HealthUnreachable,
+				JobID:  job.ID,
+				ConnID: job.SMBConnectionID,
+				Message: fmt.Sprintf("%s has been unreachable since %s: nothing is synced.",
+					job.RemoteHost, job.LastSync.Format("2006-01-02 15:04")),
+				Action: "Retry Now",
+			})
+		}
+	}
+	return issues
+}
+
+// checkDatabaseDiskSpace reports a nearly full volume under the database:
+// the sync state can't be saved when it fills up.
+func (a *App) checkDatabaseDiskSpace() *HealthIssue {
+	if a.db == nil {
+		return nil
+	}
+	dir := filepath.Dir(a.db.Path())
+	dirPtr, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return nil
+	}
+
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(dirPtr, &free, &total, &totalFree); err != nil {
+		a.logger.Debug("Failed to read free disk space", zap.String("path", dir), zap.Error(err))
+		return nil
+	}
+	if free >= lowDiskSpaceBytes {
+		return nil
+	}
+	return &HealthIssue{
+		Kind: HealthDiskSpace,
+		Message: fmt.Sprintf("Only %s left on the drive holding the AnemoneSync database (%s): syncs will fail when it is full.",
+			formatBytes(int64(free)), filepath.VolumeName(dir)),
+		Action: "Free Up Space",
+	}
+}
+
+// checkSyncRoot reports a Files On Demand job whose folder is gone or whose
+// sync root registration was lost (placeholders can't be opened anymore).
+func (a *App) checkSyncRoot(job *SyncJob) *HealthIssue {
+	if !job.Enabled || !job.FilesOnDemand || job.VolumeMoved != "" || a.IsJobSyncing(job.ID) {
+		return nil
+	}
+	localPath := filepath.FromSlash(job.LocalPath)
+
+	problem := ""
+	if _, err := os.Stat(localPath); err != nil {
+		problem = "its folder is missing"
+	} else if _, err := cloudfiles.FindSyncRootID(localPath); err != nil {
+		problem = "its folder is no longer registered with Windows"
+	}
+	if problem == "" {
+		return nil
+	}
+	return &HealthIssue{
+		Kind:    HealthSyncRoot,
+		JobID:   job.ID,
+		Message: fmt.Sprintf("Files On Demand of '%s' is broken: %s.", job.Name, problem),
+		Action:  "Repair",
+	}
+}
+
+// RepairSyncRoot registers the sync root of a Files On Demand job again and
+// syncs it to recreate its placeholders.
+func (a *App) RepairSyncRoot(jobID int64) error {
+	a.mu.RLock()
+	var job *SyncJob
+	for _, j := range a.syncJobs {
+		if j.ID == jobID {
+			job = j
+			break
+		}
+	}
+	a.mu.RUnlock()
+
+	if job == nil {
+		return errJobNotFound
+	}
+	if a.syncManager == nil {
+		return fmt.Errorf("sync manager not available")
+	}
+
+	a.logger.Info("Repairing sync root", zap.String("job", job.Name), zap.String("local_path", job.LocalPath))
+	if err := a.syncManager.RepairSyncRoot(job); err != nil {
+		return err
+	}
+
+	// Placeholders may be gone with the registration: create them all again
+	if a.db != nil {
+		if err := a.db.ReplaceRemoteSnapshot(job.ID, nil); err != nil {
+			a.logger.Warn("Failed to clear remote listing snapshot", zap.Error(err))
+		}
+	}
+	a.TriggerSyncJob(job.ID)
+	return nil
+}
+
+// startHealthChecks refreshes the health banner periodically, so states that
+// degrade without a sync (disk filling up, sync root removed) show up.
+func (a *App) startHealthChecks() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if a.settings != nil {
+					a.settings.RefreshHealth()
+				}
+			case <-a.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"net/url"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"go.uber.org/zap"
+)
+
+// HealthBanner shows the onboarding steps left and the degraded states of the
+// app at the top of the settings window, each with a one-click remediation.
+type HealthBanner struct {
+	sw        *SettingsWindow
+	container *fyne.Container
+}
+
+// NewHealthBanner creates an empty health banner; call Refresh to fill it.
+func NewHealthBanner(sw *SettingsWindow) *HealthBanner {
+	hb := &HealthBanner{
+		sw:        sw,
+		container: container.NewVBox(),
+	}
+	hb.container.Hide()
+	return hb
+}
+
+// Container returns the banner container.
+func (hb *HealthBanner) Container() fyne.CanvasObject {
+	return hb.container
+}
+
+// Refresh runs the health checks and rebuilds the banner (hidden when healthy).
+func (hb *HealthBanner) Refresh() {
+	issues := hb.sw.app.CheckHealth()
+
+	fyne.Do(func() {
+		hb.container.RemoveAll()
+		for _, issue := range issues {
+			hb.container.Add(hb.createIssueRow(issue))
+		}
+		if len(issues) == 0 {
+			hb.container.Hide()
+			return
+		}
+		hb.container.Add(widget.NewSeparator())
+		hb.container.Show()
+	})
+}
+
+// createIssueRow creates the banner line of one issue: icon, message, action.
+func (hb *HealthBanner) createIssueRow(issue HealthIssue) fyne.CanvasObject {
+	icon := widget.NewIcon(theme.WarningIcon())
+	if issue.Onboarding() {
+		icon = widget.NewIcon(theme.InfoIcon())
+	}
+
+	message := widget.NewLabel(issue.Message)
+	message.Wrapping = fyne.TextWrapWord
+
+	var action fyne.CanvasObject
+	if issue.Action != "" {
+		btn := widget.NewButton(issue.Action, func() {
+			hb.sw.remediate(issue)
+		})
+		if !issue.Onboarding() {
+			btn.Importance = widget.HighImportance
+		}
+		action = btn
+	}
+
+	return container.NewBorder(nil, nil, icon, action, message)
+}
+
+// remediate runs the one-click fix of a health issue.
+func (sw *SettingsWindow) remediate(issue HealthIssue) {
+	sw.app.Logger().Info("Health remediation",
+		zap.String("kind", string(issue.Kind)),
+		zap.Int64("job_id", issue.JobID),
+	)
+
+	switch issue.Kind {
+	case HealthNoServer:
+		sw.showSMBForm(nil)
+	case HealthNoJob:
+		sw.showJobForm(nil)
+	case HealthFirstSync, HealthUnreachable:
+		sw.app.TriggerSyncJob(issue.JobID)
+	case HealthCredentials, HealthCorruption:
+		if conn := sw.app.GetSMBConnection(issue.ConnID); conn != nil {
+			sw.showSMBForm(conn)
+		}
+	case HealthDiskSpace:
+		storageSettings, _ := url.Parse("ms-settings:storagesense")
+		if err := sw.app.FyneApp().OpenURL(storageSettings); err != nil {
+			dialog.ShowError(err, sw.window)
+		}
+	case HealthSyncRoot:
+		go func() {
+			err := sw.app.RepairSyncRoot(issue.JobID)
+			fyne.Do(func() {
+				if err != nil {
+					sw.app.showFriendlyError(err, "", sw.window)
+				} else {
+					dialog.ShowInformation("Repair", "The folder was registered again. Its files are being restored from the server.", sw.window)
+				}
+			})
+			sw.RefreshHealth()
+		}()
+		return
+	}
+	sw.RefreshHealth()
+}
@@ -0,0 +1,179 @@
+// Package app provides user inactivity detection for jobs synced when the
+// user is idle: their syncs start after a few idle minutes and stop as soon
+// as the user is back.
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unsafe"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scheduler"
+)
+
+var (
+	user32 = windows.NewLazySystemDLL("user32.dll")
+
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+)
+
+// idlePoll is how often the last keyboard or mouse input is checked. A sync
+// started while idle stops at most this long after the user is back.
+const idlePoll = 30 * time.Second
+
+// lastInputInfo is LASTINPUTINFO (winuser.h).
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32 // Tick count of the last input
+}
+
+// userIdleTime returns the time since the last keyboard or mouse input of the
+// session (0 if unknown).
+func userIdleTime() time.Duration {
+	if procGetLastInputInfo.Find() != nil {
+		return 0
+	}
+	info := lastInputInfo{cbSize: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if ok, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0
+	}
+	// 32-bit tick counts wrap every 49.7 days: the difference stays right
+	now := uint32(windows.DurationSinceBoot().Milliseconds())
+	return time.Duration(now-info.dwTime) * time.Millisecond
+}
+
+// idleAfter returns how long the user must be idle before an idle job syncs.
+func (j *SyncJob) idleAfter() time.Duration {
+	if j.IdleMinutes > 0 {
+		return time.Duration(j.IdleMinutes) * time.Minute
+	}
+	return scheduler.DefaultIdleAfter
+}
+
+// IdleWatcher tells the scheduler how long the user has been idle, for jobs
+// synced when idle, and stops their syncs when the user is back.
+type IdleWatcher struct {
+	app    *App
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	runs   map[int64]*idleRun // Syncs started while idle, by job ID
+}
+
+// idleRun is a sync stopped when the user is back.
+type idleRun struct {
+	cancel context.CancelFunc
+	paused bool
+}
+
+// NewIdleWatcher creates a new idle watcher.
+func NewIdleWatcher(app *App, logger *zap.Logger) *IdleWatcher {
+	return &IdleWatcher{
+		app:    app,
+		logger: logger,
+		runs:   make(map[int64]*idleRun),
+	}
+}
+
+// Start begins checking user inactivity.
+func (iw *IdleWatcher) Start() {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	if iw.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	iw.cancel = cancel
+	go iw.loop(ctx)
+
+	iw.logger.Info("Idle watcher started", zap.Duration("poll", idlePoll))
+}
+
+// Stop stops checking user inactivity.
+func (iw *IdleWatcher) Stop() {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	if iw.cancel == nil {
+		return
+	}
+	iw.cancel()
+	iw.cancel = nil
+
+	iw.logger.Info("Idle watcher stopped")
+}
+
+func (iw *IdleWatcher) loop(ctx context.Context) {
+	ticker := time.NewTicker(idlePoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			iw.check()
+		}
+	}
+}
+
+// check plans the idle jobs due, or stops their syncs if the user is back.
+func (iw *IdleWatcher) check() {
+	idle := userIdleTime()
+	if idle < idlePoll {
+		iw.userReturned()
+		return
+	}
+	if iw.app.scheduler != nil {
+		iw.app.scheduler.UserIdle(idle)
+	}
+}
+
+// userReturned stops the syncs started while the user was idle.
+func (iw *IdleWatcher) userReturned() {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	for jobID, run := range iw.runs {
+		if run.paused {
+			continue
+		}
+		iw.logger.Info("User is back, pausing idle sync", zap.Int64("job_id", jobID))
+		run.paused = true
+		run.cancel()
+	}
+}
+
+// watchRun stops a sync when the user is back. The returned function must be
+// called when the sync ends: it reports whether the run was stopped.
+func (iw *IdleWatcher) watchRun(jobID int64, cancel context.CancelFunc) func() bool {
+	run := &idleRun{cancel: cancel}
+	iw.mu.Lock()
+	iw.runs[jobID] = run
+	iw.mu.Unlock()
+
+	return func() bool {
+		iw.mu.Lock()
+		defer iw.mu.Unlock()
+		delete(iw.runs, jobID)
+		return run.paused
+	}
+}
+
+// pauseOnUserReturn stops the sync of an idle job when the user is back, if
+// it was started by the user being idle (not by Sync Now). The returned
+// function must be called when the sync ends: it reports whether the run was
+// stopped.
+func (m *SyncManager) pauseOnUserReturn(job *SyncJob, cancel context.CancelFunc) func() bool {
+	iw := m.app.idleWatcher
+	if iw == nil || job.TriggerMode != SyncTriggerIdle || userIdleTime() < job.idleAfter() {
+		return func() bool { return false }
+	}
+	return iw.watchRun(job.ID, cancel)
+}
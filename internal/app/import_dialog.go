@@ -0,0 +1,347 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/jobimport"
+	"go.uber.org/zap"
+)
+
+// DraftImportedJob turns an import proposal into an unsaved job to review in
+// the job form. The server folder of the proposal is matched against the
+// configured servers: SMBConnectionID stays 0 when none matches.
+func (a *App) DraftImportedJob(p jobimport.Proposal) *SyncJob {
+	job := &SyncJob{
+		Name:               p.Name,
+		LocalPath:          p.LocalPath,
+		Mode:               p.Mode,
+		ConflictResolution: "recent",
+		Enabled:            true,
+		TriggerMode:        SyncTriggerManual,
+	}
+	if !p.HasRemote() {
+		return job
+	}
+	for _, conn := range a.GetSMBConnections() {
+		if strings.EqualFold(conn.Host, p.Remote.Host) {
+			job.SMBConnectionID = conn.ID
+			job.RemoteHost = conn.Host
+			job.RemotePort = conn.Port
+			job.Username = conn.Username
+			job.RemoteShare = p.Remote.Share
+			job.RemotePath = p.Remote.Path
+			break
+		}
+	}
+	return job
+}
+
+// AddJobExclusions adds exclusion rules (.anemoneignore syntax) to a job.
+func (a *App) AddJobExclusions(jobID int64, rules []string, reason string) error {
+	if a.db == nil {
+		return nil
+	}
+	for _, rule := range rules {
+		excl := &database.Exclusion{
+			Type:          "job",
+			PatternOrPath: rule,
+			Reason:        reason,
+			JobID:         &jobID,
+		}
+		if err := a.db.CreateExclusion(excl); err != nil {
+			return err
+		}
+	}
+	a.logger.Info("Added job exclusions", zap.Int64("job_id", jobID), zap.Int("rules", len(rules)))
+	return nil
+}
+
+// Sources the import dialog reads jobs from
+const (
+	importSourceRobocopy  = "Robocopy script"
+	importSourceSyncthing = "Syncthing configuration"
+	importSourceOneDrive  = "OneDrive folders"
+	importSourcePair      = "Existing folder pair"
+)
+
+// ImportJobsDialog proposes jobs from the setup of another sync tool.
+type ImportJobsDialog struct {
+	app       *App
+	window    fyne.Window
+	onCreated func() // Called after each job created
+
+	// UI elements
+	sourceSelect *widget.Select
+	proposalList *widget.List
+	detailsLabel *widget.Label
+	statusLabel  *widget.Label
+	createBtn    *widget.Button
+
+	// Data
+	proposals []jobimport.Proposal
+	skipped   []string
+	created   map[int]bool // Proposals already turned into a job
+	selected  int          // -1 = none
+}
+
+// ShowImportJobsDialog displays the Import Jobs dialog. onCreated is called
+// after each job created from it.
+func (a *App) ShowImportJobsDialog(onCreated func()) {
+	d := &ImportJobsDialog{
+		app:       a,
+		onCreated: onCreated,
+		created:   make(map[int]bool),
+		selected:  -1,
+	}
+	d.show()
+}
+
+func (d *ImportJobsDialog) show() {
+	d.window = d.app.fyneApp.NewWindow("Import Jobs")
+	d.window.Resize(fyne.NewSize(700, 500))
+
+	// Source
+	d.sourceSelect = widget.NewSelect([]string{
+		importSourceRobocopy,
+		importSourceSyncthing,
+		importSourceOneDrive,
+		importSourcePair,
+	}, nil)
+	d.sourceSelect.SetSelectedIndex(0)
+	readBtn := widget.NewButton("Read...", d.read)
+
+	sourceContainer := container.NewBorder(
+		nil, nil,
+		widget.NewLabel("Import from:"),
+		readBtn,
+		d.sourceSelect,
+	)
+
+	// Proposals
+	d.proposalList = widget.NewList(
+		func() int { return len(d.proposals) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel("Job name"),
+				widget.NewLabel("C:\\Users\\Me\\Documents"),
+				widget.NewLabel("download"),
+			)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(d.proposals) {
+				return
+			}
+			p := d.proposals[id]
+			name := p.Name
+			if d.created[id] {
+				name += " (created)"
+			}
+			hbox := obj.(*fyne.Container)
+			hbox.Objects[0].(*widget.Label).SetText(name)
+			hbox.Objects[1].(*widget.Label).SetText(p.LocalPath)
+			hbox.Objects[2].(*widget.Label).SetText(p.Mode.String())
+		},
+	)
+	d.proposalList.OnSelected = func(id widget.ListItemID) {
+		d.selected = id
+		d.showDetails()
+	}
+	d.proposalList.OnUnselected = func(widget.ListItemID) {
+		d.selected = -1
+		d.showDetails()
+	}
+
+	d.detailsLabel = widget.NewLabel("")
+	d.detailsLabel.Wrapping = fyne.TextWrapWord
+	d.statusLabel = widget.NewLabel("Choose what to import from, then read it. Each proposed job is reviewed before it is created.")
+	d.statusLabel.Wrapping = fyne.TextWrapWord
+
+	// Buttons
+	d.createBtn = widget.NewButton("Create Job...", d.onCreate)
+	d.createBtn.Importance = widget.HighImportance
+	d.createBtn.Disable()
+
+	closeBtn := widget.NewButton("Close", func() {
+		d.window.Close()
+	})
+
+	// Layout
+	content := container.NewBorder(
+		container.NewVBox(sourceContainer, widget.NewSeparator()),
+		container.NewVBox(
+			widget.NewSeparator(),
+			d.statusLabel,
+			container.NewHBox(closeBtn, d.createBtn),
+		),
+		nil, nil,
+		container.NewVSplit(d.proposalList, container.NewVScroll(d.detailsLabel)),
+	)
+
+	d.window.SetContent(content)
+	d.window.Show()
+}
+
+// read reads the proposals of the chosen source.
+func (d *ImportJobsDialog) read() {
+	switch d.sourceSelect.Selected {
+	case importSourceRobocopy:
+		d.openFile([]string{".cmd", ".bat", ".ps1"}, "", func(reader fyne.URIReadCloser) (jobimport.Result, error) {
+			return jobimport.ParseRobocopy(reader, reader.URI().Name())
+		})
+	case importSourceSyncthing:
+		d.openFile([]string{".xml"}, jobimport.DefaultSyncthingConfig(), func(reader fyne.URIReadCloser) (jobimport.Result, error) {
+			return jobimport.ParseSyncthingConfig(reader)
+		})
+	case importSourceOneDrive:
+		d.setResult(jobimport.Result{Proposals: jobimport.OneDriveFolders()})
+	case importSourcePair:
+		d.askFolderPair()
+	}
+}
+
+// openFile picks a file and parses it. location is the file suggested, if any.
+func (d *ImportJobsDialog) openFile(exts []string, location string, parse func(fyne.URIReadCloser) (jobimport.Result, error)) {
+	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, d.window)
+			return
+		}
+		if reader == nil {
+			return // cancelled
+		}
+		defer reader.Close()
+
+		result, err := parse(reader)
+		if err != nil {
+			dialog.ShowError(err, d.window)
+			return
+		}
+		d.setResult(result)
+	}, d.window)
+	fd.SetFilter(storage.NewExtensionFileFilter(exts))
+	if location != "" {
+		if lister, err := storage.ListerForURI(storage.NewFileURI(filepath.Dir(location))); err == nil {
+			fd.SetLocation(lister)
+		}
+	}
+	fd.Show()
+}
+
+// askFolderPair asks for a local folder and the server folder it is kept in
+// sync with.
+func (d *ImportJobsDialog) askFolderPair() {
+	localEntry := widget.NewEntry()
+	localEntry.SetPlaceHolder("C:\\Users\\Me\\Documents")
+	remoteEntry := widget.NewEntry()
+	remoteEntry.SetPlaceHolder("\\\\server\\share\\folder")
+
+	dialog.ShowForm("Existing Folder Pair", "Propose", "Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("Local folder", localEntry),
+			widget.NewFormItem("Server folder", remoteEntry),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			p, err := jobimport.FolderPair(localEntry.Text, remoteEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, d.window)
+				return
+			}
+			d.setResult(jobimport.Result{Proposals: []jobimport.Proposal{p}})
+		},
+		d.window)
+}
+
+// setResult lists the proposals of an import.
+func (d *ImportJobsDialog) setResult(result jobimport.Result) {
+	d.proposals = result.Proposals
+	d.skipped = result.Skipped
+	d.created = make(map[int]bool)
+	d.selected = -1
+	d.proposalList.UnselectAll()
+	d.proposalList.Refresh()
+	d.showDetails()
+
+	switch {
+	case len(d.proposals) == 0 && len(d.skipped) == 0:
+		d.statusLabel.SetText("Nothing to import was found.")
+	case len(d.proposals) == 0:
+		d.statusLabel.SetText("No job could be proposed: see why below.")
+	default:
+		d.statusLabel.SetText(fmt.Sprintf("%d jobs proposed. Select one to review what is carried over, then create it.", len(d.proposals)))
+	}
+}
+
+// showDetails shows what the selected proposal carries over, or what was
+// skipped when none is selected.
+func (d *ImportJobsDialog) showDetails() {
+	d.createBtn.Disable()
+	if d.selected < 0 || d.selected >= len(d.proposals) {
+		text := ""
+		if len(d.skipped) > 0 {
+			text = "Not imported:\n- " + strings.Join(d.skipped, "\n- ")
+		}
+		d.detailsLabel.SetText(text)
+		return
+	}
+	p := d.proposals[d.selected]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\n", p.Source)
+	fmt.Fprintf(&b, "Local folder: %s\n", p.LocalPath)
+	if p.HasRemote() {
+		fmt.Fprintf(&b, "Server folder: %s\n", p.Remote.String())
+	} else {
+		b.WriteString("Server folder: to choose\n")
+	}
+	fmt.Fprintf(&b, "Mode: %s\n", p.Mode.String())
+	if len(p.Excludes) > 0 {
+		fmt.Fprintf(&b, "Exclusions: %s\n", strings.Join(p.Excludes, "  "))
+	}
+	if p.HasRemote() && d.app.DraftImportedJob(p).SMBConnectionID == 0 {
+		p.Notes = append(p.Notes, fmt.Sprintf("no server %s is configured: add it in the SMB Servers tab first", p.Remote.Host))
+	}
+	if len(p.Notes) > 0 {
+		b.WriteString("\nTo review:\n- " + strings.Join(p.Notes, "\n- "))
+	}
+	d.detailsLabel.SetText(b.String())
+
+	if !d.created[d.selected] {
+		d.createBtn.Enable()
+	}
+}
+
+// onCreate opens the job form prefilled with the selected proposal; its
+// exclusions are added once the job is saved.
+func (d *ImportJobsDialog) onCreate() {
+	if d.selected < 0 || d.selected >= len(d.proposals) {
+		return
+	}
+	index := d.selected
+	p := d.proposals[index]
+
+	form := NewJobForm(d.app, d.app.DraftImportedJob(p), func(saved *SyncJob) {
+		if err := d.app.AddJobExclusions(saved.ID, p.Excludes, "Imported from "+p.Source); err != nil {
+			d.app.Logger().Warn("Failed to add imported exclusions", zap.String("job", saved.Name), zap.Error(err))
+			dialog.ShowError(fmt.Errorf("the job was created, but not its exclusions: %w", err), d.window)
+		}
+		d.created[index] = true
+		d.proposalList.Refresh()
+		d.showDetails()
+		if d.onCreated != nil {
+			d.onCreated()
+		}
+	})
+	form.Show(d.window)
+}
@@ -0,0 +1,334 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
+	"go.uber.org/zap"
+)
+
+// Previews fetched for the file inspector
+const (
+	previewMaxAge  = 24 * time.Hour  // Older previews are deleted
+	previewTimeout = 2 * time.Minute // Fetching a preview gives up after
+	previewTextMax = 4 << 10         // Start of a text preview shown in the dialog
+)
+
+// previewDir returns the folder previews are fetched to.
+func previewDir() string {
+	return filepath.Join(os.TempDir(), "AnemoneSync", "previews")
+}
+
+// FetchPreview copies the first bytes of a file of a Files On Demand job
+// (cloudfiles.PreviewFetchSize) into a temporary file, without hydrating its
+// placeholder. It returns the path of the copy and the bytes copied.
+func (a *App) FetchPreview(ctx context.Context, job *SyncJob, relPath string) (string, int64, error) {
+	_, localPath, err := jobFilePaths(job, relPath)
+	if err != nil {
+		return "", 0, err
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(job.LocalPath), localPath)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var provider *cloudfiles.CloudFilesProvider
+	if a.syncManager != nil {
+		provider = a.syncManager.GetProvider(job.ID)
+	}
+	if provider == nil {
+		return "", 0, fmt.Errorf("Files On Demand is not active for %s: its files are on disk", job.Name)
+	}
+
+	dir := previewDir()
+	removeOldPreviews(dir, a.logger)
+	path, n, err := provider.FetchPreview(ctx, filepath.ToSlash(rel), dir)
+	if err != nil {
+		return "", 0, err
+	}
+	a.logger.Info("preview fetched",
+		zap.String("job", job.Name),
+		zap.String("file", rel),
+		zap.Int64("bytes", n))
+	return path, n, nil
+}
+
+// removeOldPreviews deletes the previews fetched more than previewMaxAge ago.
+func removeOldPreviews(dir string, logger *zap.Logger) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < previewMaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			logger.Debug("failed to delete old preview", zap.String("file", entry.Name()), zap.Error(err))
+		}
+	}
+}
+
+// InspectDialog shows the state of a file of a job and previews it.
+type InspectDialog struct {
+	app    *App
+	job    *SyncJob
+	window fyne.Window
+
+	// UI elements
+	fileEntry   *widget.Entry
+	infoLabel   *widget.Label
+	previewText *widget.Entry
+	statusLabel *widget.Label
+	fetchBtn    *widget.Button
+	openBtn     *widget.Button
+
+	// Data
+	previewPath string // Last preview fetched ("" = none)
+
+	// Cancels the fetch in progress when the window closes
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ShowInspectDialog displays the File Inspector for a job.
+func (a *App) ShowInspectDialog(job *SyncJob) {
+	if job == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &InspectDialog{
+		app:    a,
+		job:    job,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	d.show()
+}
+
+func (d *InspectDialog) show() {
+	d.window = d.app.fyneApp.NewWindow(fmt.Sprintf("File Inspector - %s", d.job.Name))
+	d.window.Resize(fyne.NewSize(600, 450))
+
+	// File of the job
+	d.fileEntry = widget.NewEntry()
+	d.fileEntry.SetPlaceHolder("File, relative to the job folder")
+	d.fileEntry.OnSubmitted = func(string) { d.inspect() }
+	browseBtn := widget.NewButton("Browse...", d.browse)
+	inspectBtn := widget.NewButton("Inspect", d.inspect)
+
+	fileContainer := container.NewBorder(
+		nil, nil,
+		widget.NewLabel("File:"),
+		container.NewHBox(browseBtn, inspectBtn),
+		d.fileEntry,
+	)
+
+	d.infoLabel = widget.NewLabel("")
+	d.infoLabel.Wrapping = fyne.TextWrapWord
+
+	d.previewText = widget.NewMultiLineEntry()
+	d.previewText.Wrapping = fyne.TextWrapWord
+	d.previewText.Disable()
+
+	d.statusLabel = widget.NewLabel(fmt.Sprintf(
+		"Fetching a preview copies the first %s of the file to a temporary file, without downloading the whole file.",
+		formatBytes(cloudfiles.PreviewFetchSize)))
+	d.statusLabel.Wrapping = fyne.TextWrapWord
+
+	// Buttons
+	d.fetchBtn = widget.NewButton("Fetch Preview", d.onFetch)
+	d.fetchBtn.Importance = widget.HighImportance
+	d.fetchBtn.Disable()
+	d.openBtn = widget.NewButton("Open Preview", d.onOpen)
+	d.openBtn.Disable()
+
+	closeBtn := widget.NewButton("Close", func() {
+		d.window.Close()
+	})
+
+	// Layout
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("Local folder: %s", d.job.LocalPath)),
+			widget.NewSeparator(),
+			fileContainer,
+			d.infoLabel,
+		),
+		container.NewVBox(
+			widget.NewSeparator(),
+			d.statusLabel,
+			container.NewHBox(closeBtn, d.openBtn, d.fetchBtn),
+		),
+		nil, nil,
+		d.previewText,
+	)
+
+	d.window.SetContent(content)
+	d.window.SetOnClosed(d.cancel)
+	d.window.Show()
+}
+
+// browse picks the file in the job folder. Picking a file doesn't read it:
+// a placeholder is not hydrated.
+func (d *InspectDialog) browse() {
+	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, d.window)
+			return
+		}
+		if reader == nil {
+			return // cancelled
+		}
+		path := reader.URI().Path()
+		reader.Close()
+
+		rel, err := filepath.Rel(filepath.FromSlash(d.job.LocalPath), filepath.FromSlash(path))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			dialog.ShowError(fmt.Errorf("%s is not inside the job folder", path), d.window)
+			return
+		}
+		d.fileEntry.SetText(filepath.ToSlash(rel))
+		d.inspect()
+	}, d.window)
+	if lister, err := storage.ListerForURI(storage.NewFileURI(d.job.LocalPath)); err == nil {
+		fd.SetLocation(lister)
+	}
+	fd.Show()
+}
+
+// inspect shows the local state of the file in the entry.
+func (d *InspectDialog) inspect() {
+	relPath := strings.TrimSpace(d.fileEntry.Text)
+	d.previewPath = ""
+	d.previewText.SetText("")
+	d.openBtn.Disable()
+	d.fetchBtn.Disable()
+	if relPath == "" {
+		d.infoLabel.SetText("")
+		return
+	}
+
+	_, localPath, err := jobFilePaths(d.job, relPath)
+	if err != nil {
+		d.infoLabel.SetText(err.Error())
+		return
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		d.infoLabel.SetText("Not in the local folder.")
+		return
+	}
+	if info.IsDir() {
+		d.infoLabel.SetText("This is a folder.")
+		return
+	}
+
+	state := "on disk"
+	if cfState, err := cloudfiles.GetFilePlaceholderState(localPath); err == nil &&
+		cfState&cloudfiles.CF_PLACEHOLDER_STATE_PLACEHOLDER != 0 {
+		switch {
+		case cfState&cloudfiles.CF_PLACEHOLDER_STATE_PARTIAL == 0:
+			state = "on disk (placeholder)"
+		case cfState&cloudfiles.CF_PLACEHOLDER_STATE_PARTIALLY_ON_DISK != 0:
+			state = "partly on disk"
+		default:
+			state = "only on the server"
+		}
+	}
+	d.infoLabel.SetText(fmt.Sprintf("%s, %s, modified %s, %s",
+		formatBytes(info.Size()),
+		filetype.ClassOf(localPath),
+		info.ModTime().Format("2006-01-02 15:04"),
+		state))
+	if d.job.FilesOnDemand {
+		d.fetchBtn.Enable()
+	}
+}
+
+func (d *InspectDialog) onFetch() {
+	relPath := strings.TrimSpace(d.fileEntry.Text)
+	if relPath == "" {
+		return
+	}
+
+	d.fetchBtn.Disable()
+	d.statusLabel.SetText("Fetching the start of the file from the server...")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(d.ctx, previewTimeout)
+		defer cancel()
+		path, n, err := d.app.FetchPreview(ctx, d.job, relPath)
+		if d.ctx.Err() != nil {
+			return // Window closed
+		}
+
+		text := ""
+		if err == nil && filetype.ClassOf(path) == filetype.ClassText {
+			text = previewSnippet(path)
+		}
+		fyne.Do(func() {
+			d.fetchBtn.Enable()
+			if err != nil {
+				d.statusLabel.SetText("Could not fetch the preview.")
+				d.app.showFriendlyError(err, d.job.RemoteHost, d.window)
+				return
+			}
+			d.previewPath = path
+			d.previewText.SetText(text)
+			d.openBtn.Enable()
+			d.statusLabel.SetText(fmt.Sprintf("Fetched the first %s to %s.", formatBytes(n), path))
+		})
+	}()
+}
+
+// previewSnippet returns the start of a text preview, or "" when it is not
+// valid text.
+func previewSnippet(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if len(data) > previewTextMax {
+		data = data[:previewTextMax]
+	}
+	// The cut may split a character
+	for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	if !utf8.Valid(data) {
+		return ""
+	}
+	return string(data)
+}
+
+// onOpen opens the preview with the application of its type.
+func (d *InspectDialog) onOpen() {
+	if d.previewPath == "" {
+		return
+	}
+	u, err := url.Parse(storage.NewFileURI(d.previewPath).String())
+	if err != nil {
+		dialog.ShowError(err, d.window)
+		return
+	}
+	if err := d.app.FyneApp().OpenURL(u); err != nil {
+		dialog.ShowError(err, d.window)
+	}
+}
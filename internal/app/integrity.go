@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// --- Transfer Integrity ---
+
+const (
+	// integrityWindow is the period the corruption rate of a server is
+	// computed over
+	integrityWindow = 30 * 24 * time.Hour
+
+	// integrityAlarmMismatches is the number of corrupted transfers under
+	// which a server is never reported (a single bad read is not a trend)
+	integrityAlarmMismatches = 3
+
+	// integrityAlarmRate is the share of corrupted transfers over which a
+	// server is reported: healthy hardware never corrupts a transfer
+	integrityAlarmRate = 0.005
+)
+
+// corruptionSuspected reports whether the statistics of a server point to
+// failing hardware between the PC and its disks.
+func corruptionSuspected(ti database.TransferIntegrity) bool {
+	return ti.Mismatches >= integrityAlarmMismatches && ti.Rate() >= integrityAlarmRate
+}
+
+// recordTransferIntegrity adds the verified transfers of a sync to the
+// statistics of its server, and raises the corruption alarm (once per server
+// until restart) when its rate gets too high.
+func (a *App) recordTransferIntegrity(job *SyncJob, result *syncpkg.SyncResult) {
+	if a.db == nil || job.SMBConnectionID == 0 || result.FilesVerified+result.VerifyMismatches == 0 {
+		return
+	}
+	if err := a.db.AddTransferIntegrity(job.SMBConnectionID, time.Now(), result.FilesVerified, result.VerifyMismatches); err != nil {
+		a.logger.Warn("Failed to record transfer integrity", zap.Error(err))
+		return
+	}
+	if result.VerifyMismatches == 0 {
+		return
+	}
+
+	ti, err := a.db.GetTransferIntegrity(job.SMBConnectionID, time.Now().Add(-integrityWindow))
+	if err != nil {
+		a.logger.Warn("Failed to read transfer integrity", zap.Error(err))
+		return
+	}
+	if !corruptionSuspected(ti) {
+		return
+	}
+
+	a.mu.Lock()
+	conn := a.serverConnection(job.RemoteHost)
+	alerted := a.integrityAlerts[job.SMBConnectionID]
+	if a.integrityAlerts == nil {
+		a.integrityAlerts = make(map[int64]bool)
+	}
+	a.integrityAlerts[job.SMBConnectionID] = true
+	a.mu.Unlock()
+
+	name := job.RemoteHost
+	if conn != nil {
+		name = conn.DisplayName()
+	}
+	attempts := ti.Verified + ti.Mismatches
+	a.logger.Warn("Transfers corrupted",
+		zap.String("server", name),
+		zap.Int64("mismatches", ti.Mismatches),
+		zap.Int64("attempts", attempts),
+	)
+	if alerted {
+		return
+	}
+	eventlog.Warning(eventlog.EventCorruptionSuspected, fmt.Sprintf(
+		"%d of %d verified transfers with server '%s' were corrupted in the last %d days. "+
+			"Check the network cable, network card, and the memory (RAM) and disks of the server.",
+		ti.Mismatches, attempts, name, int(integrityWindow.Hours()/24)))
+	if a.notifier != nil {
+		a.notifier.CorruptionSuspected(name, ti.Mismatches, attempts)
+	}
+	if a.settings != nil {
+		a.settings.RefreshHealth()
+	}
+}
+
+// checkTransferIntegrity reports the servers whose transfers were read back
+// corrupted too often over integrityWindow.
+func (a *App) checkTransferIntegrity() []HealthIssue {
+	if a.db == nil {
+		return nil
+	}
+
+	var issues []HealthIssue
+	for _, conn := range a.GetSMBConnections() {
+		ti, err := a.db.GetTransferIntegrity(conn.ID, time.Now().Add(-integrityWindow))
+		if err != nil || !corruptionSuspected(ti) {
+			continue
+		}
+		issues = append(issues, HealthIssue{
+			Kind:   HealthCorruption,
+			ConnID: conn.ID,
+			Message: fmt.Sprintf("%d of %d verified transfers with %s were corrupted lately (%.1f%%): "+
+				"check the network cable, network card, and the memory and disks of the server.",
+				ti.Mismatches, ti.Verified+ti.Mismatches, conn.DisplayName(), ti.Rate()*100),
+			Action: "Details",
+		})
+	}
+	return issues
+}
+
+// TransferIntegrity returns the transfer statistics of a server over
+// integrityWindow and since they are recorded.
+func (a *App) TransferIntegrity(connID int64) (recent, total database.TransferIntegrity, err error) {
+	if a.db == nil {
+		return recent, total, fmt.Errorf("database not available")
+	}
+	if recent, err = a.db.GetTransferIntegrity(connID, time.Now().Add(-integrityWindow)); err != nil {
+		return recent, total, err
+	}
+	total, err = a.db.GetTransferIntegrity(connID, time.Time{})
+	return recent, total, err
+}
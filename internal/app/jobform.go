@@ -1,11 +1,20 @@
 package app
 
 import (
+	"strconv"
+	"strings"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scheduler"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
 )
 
 // JobForm is a form for creating or editing a sync job.
@@ -25,11 +34,39 @@ type JobForm struct {
 	modeSelect          *widget.Select
 	conflictSelect      *widget.Select
 	triggerModeSelect   *widget.Select
+	debounceEntry       *widget.Entry
+	idleEntry           *widget.Entry
+	cronEntry           *widget.Entry
 	enabledCheck        *widget.Check
 	syncOnStartupCheck  *widget.Check
+	syncAttributesCheck *widget.Check
+	verifyCheck         *widget.Check
+	syncACLsCheck       *widget.Check
+	syncStreamsCheck    *widget.Check
+	lockedFilesCheck    *widget.Check
+	jobLogCheck         *widget.Check
+	maxChangesEntry     *widget.Entry
+	maxBytesEntry       *widget.Entry
+	// Bandwidth limits
+	uploadLimitEntry     *widget.Entry
+	downloadLimitEntry   *widget.Entry
+	throttleMeteredCheck *widget.Check
+	// Network conditions
+	wifiSSIDsEntry    *widget.Entry
+	avoidMeteredCheck *widget.Check
+	vpnEntry          *widget.Entry
+	// Maintenance windows
+	maintenanceEntry      *widget.Entry
+	maintenancePauseCheck *widget.Check
+	// Curated exclusion groups (one checkbox per group, same order)
+	exclusionGroups      []*database.ExclusionGroup
+	exclusionGroupChecks []*widget.Check
 	// Files On Demand
 	filesOnDemandCheck      *widget.Check
 	autoDehydrateDaysSelect *widget.Select
+	storageSenseCheck       *widget.Check
+	storageSenseLabel       *widget.Label
+	sharedSyncRootCheck     *widget.Check
 
 	// SMB connections and shares
 	smbConnections  []*SMBConnection
@@ -41,16 +78,17 @@ type JobForm struct {
 	filesOnDemandHelpLabel *widget.Label
 }
 
-// NewJobForm creates a new job form.
+// NewJobForm creates a new job form. job is nil for a new job, or an unsaved
+// draft (no ID) to create a job prefilled with it.
 func NewJobForm(app *App, job *SyncJob, onSave func(*SyncJob)) *JobForm {
 	jf := &JobForm{
 		app:    app,
 		job:    job,
-		isNew:  job == nil,
+		isNew:  job == nil || job.ID == 0, // Unsaved draft (imported job)
 		onSave: onSave,
 	}
 
-	if jf.isNew {
+	if job == nil {
 		jf.job = &SyncJob{
 			Mode:               syncpkg.SyncModeMirror,
 			ConflictResolution: "recent",
@@ -60,6 +98,7 @@ func NewJobForm(app *App, job *SyncJob, onSave func(*SyncJob)) *JobForm {
 	}
 
 	jf.smbConnections = app.GetSMBConnections()
+	jf.exclusionGroups = app.GetExclusionGroups()
 	jf.createFields()
 	return jf
 }
@@ -131,18 +170,40 @@ func (jf *JobForm) createFields() {
 	}, nil)
 	jf.conflictSelect.SetSelectedIndex(jf.conflictToIndex(jf.job.ConflictResolution))
 
+	// Realtime: wait for changes to settle (empty = config.yaml setting)
+	jf.debounceEntry = widget.NewEntry()
+	jf.debounceEntry.SetPlaceHolder("Default")
+	if jf.job.DebounceSeconds > 0 {
+		jf.debounceEntry.SetText(strconv.Itoa(jf.job.DebounceSeconds))
+	}
+
+	// Idle mode: minutes without input before syncing (empty = default)
+	jf.idleEntry = widget.NewEntry()
+	jf.idleEntry.SetPlaceHolder(strconv.Itoa(int(scheduler.DefaultIdleAfter.Minutes())))
+	if jf.job.IdleMinutes > 0 {
+		jf.idleEntry.SetText(strconv.Itoa(jf.job.IdleMinutes))
+	}
+
+	// Custom schedule: cron expression
+	jf.cronEntry = widget.NewEntry()
+	jf.cronEntry.SetPlaceHolder("e.g. 0 2 * * mon-fri")
+	jf.cronEntry.SetText(jf.job.TriggerCron)
+
 	// Trigger mode (simplified sync scheduling)
 	jf.triggerModeHelpLabel = widget.NewLabel("")
 	jf.triggerModeHelpLabel.Wrapping = fyne.TextWrapWord
 	jf.triggerModeHelpLabel.TextStyle = fyne.TextStyle{Italic: true}
 
 	jf.triggerModeSelect = widget.NewSelect([]string{
-		"Manual",
-		"Every 5 minutes",
-		"Every 15 minutes",
-		"Every 30 minutes",
-		"Every hour",
-		"Realtime",
+		SyncTriggerManual.String(),
+		SyncTrigger5Min.String(),
+		SyncTrigger15Min.String(),
+		SyncTrigger30Min.String(),
+		SyncTrigger1Hour.String(),
+		SyncTriggerRealtime.String(),
+		SyncTriggerNetwork.String(),
+		SyncTriggerCron.String(),
+		SyncTriggerIdle.String(),
 	}, func(selected string) {
 		jf.updateTriggerModeHelp()
 	})
@@ -157,6 +218,80 @@ func (jf *JobForm) createFields() {
 	jf.syncOnStartupCheck = widget.NewCheck("Sync immediately on application startup", nil)
 	jf.syncOnStartupCheck.SetChecked(jf.job.SyncOnStartup)
 
+	// Attribute sync
+	jf.syncAttributesCheck = widget.NewCheck("Sync read-only and archive attributes", nil)
+	jf.syncAttributesCheck.SetChecked(jf.job.SyncAttributes)
+
+	// Transfer verification
+	jf.verifyCheck = widget.NewCheck("Verify transferred files (reads each file again)", nil)
+	jf.verifyCheck.SetChecked(jf.job.VerifyTransfers)
+
+	// Permission sync
+	jf.syncACLsCheck = widget.NewCheck("Sync owner and NTFS permissions of transferred files", nil)
+	jf.syncACLsCheck.SetChecked(jf.job.SyncACLs)
+
+	// Alternate data stream sync
+	jf.syncStreamsCheck = widget.NewCheck("Sync alternate data streams (Zone.Identifier, tags...)", nil)
+	jf.syncStreamsCheck.SetChecked(jf.job.SyncStreams)
+
+	// Locked files read from a shadow copy
+	jf.lockedFilesCheck = widget.NewCheck("Upload locked files (Outlook PST...) from a shadow copy (administrator)", nil)
+	jf.lockedFilesCheck.SetChecked(jf.job.ReadLockedFiles)
+
+	// Separate log file
+	jf.jobLogCheck = widget.NewCheck("Write this job's logs to their own file (logs\\jobs)", nil)
+	jf.jobLogCheck.SetChecked(jf.job.JobLog)
+
+	// Safety cap on changed files per run
+	jf.maxChangesEntry = widget.NewEntry()
+	jf.maxChangesEntry.SetPlaceHolder("No limit")
+	if jf.job.MaxChangedFiles > 0 {
+		jf.maxChangesEntry.SetText(strconv.Itoa(jf.job.MaxChangedFiles))
+	}
+	jf.maxBytesEntry = widget.NewEntry()
+	jf.maxBytesEntry.SetPlaceHolder("No limit (e.g. 5 GB)")
+	if jf.job.MaxChangedBytes > 0 {
+		jf.maxBytesEntry.SetText(scanner.FormatSize(jf.job.MaxChangedBytes))
+	}
+
+	// Bandwidth limits (empty = global limits only)
+	jf.uploadLimitEntry = widget.NewEntry()
+	jf.uploadLimitEntry.SetPlaceHolder("No limit")
+	if jf.job.MaxUploadKBps > 0 {
+		jf.uploadLimitEntry.SetText(strconv.Itoa(jf.job.MaxUploadKBps))
+	}
+	jf.downloadLimitEntry = widget.NewEntry()
+	jf.downloadLimitEntry.SetPlaceHolder("No limit")
+	if jf.job.MaxDownloadKBps > 0 {
+		jf.downloadLimitEntry.SetText(strconv.Itoa(jf.job.MaxDownloadKBps))
+	}
+	jf.throttleMeteredCheck = widget.NewCheck("Slow down on metered connections (mobile data)", nil)
+	jf.throttleMeteredCheck.SetChecked(jf.job.ThrottleMetered)
+
+	// Network conditions of automatic syncs
+	jf.wifiSSIDsEntry = widget.NewEntry()
+	jf.wifiSSIDsEntry.SetPlaceHolder("Any network (e.g. Office, Home)")
+	jf.wifiSSIDsEntry.SetText(strings.Join(jf.job.WifiSSIDs, ", "))
+	jf.avoidMeteredCheck = widget.NewCheck("Don't sync automatically on metered connections", nil)
+	jf.avoidMeteredCheck.SetChecked(jf.job.AvoidMetered)
+	jf.vpnEntry = widget.NewEntry()
+	jf.vpnEntry.SetPlaceHolder("Not required (e.g. WireGuard)")
+	jf.vpnEntry.SetText(jf.job.VPNInterface)
+
+	// Maintenance windows
+	jf.maintenanceEntry = widget.NewEntry()
+	jf.maintenanceEntry.SetPlaceHolder("None (e.g. 02:00-03:00)")
+	jf.maintenanceEntry.SetText(FormatMaintenanceWindows(jf.job.MaintenanceWindows))
+	jf.maintenancePauseCheck = widget.NewCheck("Stop a running sync when a maintenance window opens", nil)
+	jf.maintenancePauseCheck.SetChecked(jf.job.MaintenancePolicy == MaintenancePause)
+
+	// Exclusion groups
+	jf.exclusionGroupChecks = make([]*widget.Check, len(jf.exclusionGroups))
+	for i, group := range jf.exclusionGroups {
+		jf.exclusionGroupChecks[i] = widget.NewCheck(group.Label, nil)
+		jf.exclusionGroupChecks[i].SetChecked(group.IsEnabled(jf.job.ExclusionGroups))
+	}
+
 	// Files On Demand (Cloud Files API) - Windows 10 1709+ only
 	jf.filesOnDemandHelpLabel = widget.NewLabel("Files appear in Explorer but are downloaded only when opened. Saves disk space. Requires Windows 10 1709+.")
 	jf.filesOnDemandHelpLabel.Wrapping = fyne.TextWrapWord
@@ -175,6 +310,19 @@ func (jf *JobForm) createFields() {
 		"After 90 days",
 	}, nil)
 	jf.autoDehydrateDaysSelect.SetSelectedIndex(jf.autoDehydrateDaysToIndex(jf.job.AutoDehydrateDays))
+
+	// Windows Storage Sense (frees space instead of AnemoneSync when opted in)
+	jf.storageSenseCheck = widget.NewCheck("Let Windows Storage Sense free up space instead", nil)
+	jf.storageSenseCheck.SetChecked(jf.job.StorageSense)
+	jf.storageSenseLabel = widget.NewLabel(jf.storageSenseStatus())
+	jf.storageSenseLabel.Wrapping = fyne.TextWrapWord
+	jf.storageSenseLabel.TextStyle = fyne.TextStyle{Italic: true}
+	jf.storageSenseCheck.OnChanged = func(bool) { jf.storageSenseLabel.SetText(jf.storageSenseStatus()) }
+	jf.autoDehydrateDaysSelect.OnChanged = func(string) { jf.storageSenseLabel.SetText(jf.storageSenseStatus()) }
+
+	// Machine-wide sync root (shared family PCs)
+	jf.sharedSyncRootCheck = widget.NewCheck("Show this folder to all accounts of this PC (administrator)", nil)
+	jf.sharedSyncRootCheck.SetChecked(jf.job.SharedSyncRoot)
 }
 
 // Show displays the form dialog.
@@ -241,15 +389,68 @@ func (jf *JobForm) Show(parent fyne.Window) {
 			),
 		),
 		jf.modeHelpLabel,
+		jf.syncAttributesCheck,
+		jf.verifyCheck,
+		jf.syncACLsCheck,
+		jf.syncStreamsCheck,
+		jf.lockedFilesCheck,
+		jf.jobLogCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Ask before changing more than (files)"),
+			jf.maxChangesEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Ask before changing more than (size)"),
+			jf.maxBytesEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Max upload speed (KB/s)"),
+			jf.uploadLimitEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Max download speed (KB/s)"),
+			jf.downloadLimitEntry,
+		),
+		jf.throttleMeteredCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Only sync automatically on Wi-Fi"),
+			jf.wifiSSIDsEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Only sync automatically with VPN adapter"),
+			jf.vpnEntry,
+		),
+		jf.avoidMeteredCheck,
+		widget.NewSeparator(),
+
+		widget.NewLabel("Skip Folders"),
+		jf.exclusionGroupsBox(),
 		widget.NewSeparator(),
 
 		widget.NewLabel("Sync Trigger"),
 		jf.triggerModeSelect,
 		jf.triggerModeHelpLabel,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Wait after last change (seconds)"),
+			jf.debounceEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Schedule (minute hour day month weekday)"),
+			jf.cronEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Idle time before syncing (minutes)"),
+			jf.idleEntry,
+		),
 		container.NewGridWithColumns(2,
 			jf.enabledCheck,
 			jf.syncOnStartupCheck,
 		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("No automatic sync during"),
+			jf.maintenanceEntry,
+		),
+		jf.maintenancePauseCheck,
 		widget.NewSeparator(),
 
 		widget.NewLabel("Files On Demand (Windows 10+)"),
@@ -263,6 +464,9 @@ func (jf *JobForm) Show(parent fyne.Window) {
 				jf.autoDehydrateDaysSelect,
 			),
 		),
+		jf.storageSenseCheck,
+		jf.storageSenseLabel,
+		jf.sharedSyncRootCheck,
 	)
 
 	scroll := container.NewVScroll(form)
@@ -308,6 +512,42 @@ func (jf *JobForm) validate(parent fyne.Window) bool {
 		dialog.ShowError(errFieldRequired("Share"), parent)
 		return false
 	}
+	if _, err := jf.remoteUNC(); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := jf.maxChangedFiles(); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := jf.maxChangedBytes(); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := jf.debounceSeconds(); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := jf.idleMinutes(); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := speedLimit(jf.uploadLimitEntry); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := speedLimit(jf.downloadLimitEntry); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := jf.cronExpression(); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
+	if _, err := ParseMaintenanceWindows(jf.maintenanceEntry.Text); err != nil {
+		dialog.ShowError(err, parent)
+		return false
+	}
 	return true
 }
 
@@ -326,16 +566,77 @@ func (jf *JobForm) saveJob(parent fyne.Window) {
 	jf.job.LocalPath = jf.localPathEntry.Text
 	jf.job.SMBConnectionID = smbConn.ID
 	jf.job.RemoteHost = smbConn.Host
+	jf.job.RemotePort = smbConn.Port
 	jf.job.RemoteShare = jf.remoteShareSelect.Selected
 	jf.job.Username = smbConn.Username
-	jf.job.RemotePath = jf.remotePathEntry.Text
+	jf.job.RemotePath = smbpath.CleanSharePath(jf.remotePathEntry.Text)
 	jf.job.Mode = jf.indexToMode(jf.modeSelect.SelectedIndex())
 	jf.job.ConflictResolution = jf.indexToConflict(jf.conflictSelect.SelectedIndex())
 	jf.job.TriggerMode = jf.indexToTriggerMode(jf.triggerModeSelect.SelectedIndex())
+	jf.job.DebounceSeconds, _ = jf.debounceSeconds()
+	jf.job.IdleMinutes, _ = jf.idleMinutes()
+	jf.job.TriggerCron, _ = jf.cronExpression()
 	jf.job.Enabled = jf.enabledCheck.Checked
 	jf.job.SyncOnStartup = jf.syncOnStartupCheck.Checked
+	jf.job.SyncAttributes = jf.syncAttributesCheck.Checked
+	jf.job.VerifyTransfers = jf.verifyCheck.Checked
+	jf.job.SyncACLs = jf.syncACLsCheck.Checked
+	jf.job.SyncStreams = jf.syncStreamsCheck.Checked
+	jf.job.ReadLockedFiles = jf.lockedFilesCheck.Checked
+	jf.job.JobLog = jf.jobLogCheck.Checked
+	jf.job.MaxChangedFiles, _ = jf.maxChangedFiles()
+	jf.job.MaxChangedBytes, _ = jf.maxChangedBytes()
+	jf.job.MaxUploadKBps, _ = speedLimit(jf.uploadLimitEntry)
+	jf.job.MaxDownloadKBps, _ = speedLimit(jf.downloadLimitEntry)
+	jf.job.ThrottleMetered = jf.throttleMeteredCheck.Checked
+	jf.job.WifiSSIDs = ParseSSIDs(jf.wifiSSIDsEntry.Text)
+	jf.job.AvoidMetered = jf.avoidMeteredCheck.Checked
+	jf.job.VPNInterface = strings.TrimSpace(jf.vpnEntry.Text)
+	jf.job.MaintenanceWindows, _ = ParseMaintenanceWindows(jf.maintenanceEntry.Text)
+	jf.job.MaintenancePolicy = ""
+	if jf.maintenancePauseCheck.Checked {
+		jf.job.MaintenancePolicy = MaintenancePause
+	}
+	jf.job.ExclusionGroups = jf.exclusionGroupOverrides()
+	wasFilesOnDemand := jf.job.FilesOnDemand && !jf.isNew
 	jf.job.FilesOnDemand = jf.filesOnDemandCheck.Checked
+	wasStorageSense, wasDehydrateDays := jf.job.StorageSense, jf.job.AutoDehydrateDays
 	jf.job.AutoDehydrateDays = jf.indexToAutoDehydrateDays(jf.autoDehydrateDaysSelect.SelectedIndex())
+	jf.job.StorageSense = jf.storageSenseCheck.Checked
+	wasShared := jf.job.SharedSyncRoot && !jf.isNew
+	jf.job.SharedSyncRoot = jf.sharedSyncRootCheck.Checked
+
+	// Files On Demand only works on local NTFS volumes: fall back to normal sync otherwise
+	fodWarning := ""
+	if jf.job.FilesOnDemand {
+		if info, err := cloudfiles.CheckVolume(jf.job.LocalPath); err != nil {
+			jf.app.Logger().Warn("Failed to check volume for Files On Demand", zap.Error(err))
+		} else if !info.Supported {
+			jf.job.FilesOnDemand = false
+			jf.filesOnDemandCheck.SetChecked(false)
+			fodWarning = info.Reason
+		}
+	}
+
+	// When Files On Demand is first enabled, check the rest of the environment
+	// now rather than failing deep inside sync root registration
+	fodNotice := ""
+	if jf.job.FilesOnDemand && !wasFilesOnDemand {
+		prereqs := cloudfiles.CheckPrerequisites(jf.job.LocalPath)
+		if !prereqs.OK() {
+			jf.app.Logger().Warn("Files On Demand prerequisites not met",
+				zap.String("job", jf.job.Name),
+				zap.String("failed", prereqs.Summary()),
+			)
+		}
+		if prereqs.Blocking() {
+			jf.job.FilesOnDemand = false
+			jf.filesOnDemandCheck.SetChecked(false)
+			fodWarning = prereqs.Summary()
+		} else if !prereqs.OK() {
+			fodNotice = prereqs.Summary()
+		}
+	}
 
 	// Save job first
 	var err error
@@ -350,6 +651,51 @@ func (jf *JobForm) saveJob(parent fyne.Window) {
 		return
 	}
 
+	if fodWarning != "" {
+		dialog.ShowInformation("Files On Demand Disabled",
+			fodWarning+"\n\nThis job will download files normally instead.", parent)
+	}
+	if fodNotice != "" {
+		dialog.ShowInformation("Files On Demand Needs Attention",
+			fodNotice+"\n\nFiles On Demand may fail until this is fixed.", parent)
+	}
+
+	// Sharing the sync root with other accounts changes machine-wide settings:
+	// apply it now to a registered sync root, at registration otherwise
+	if jf.job.FilesOnDemand && jf.job.SharedSyncRoot != wasShared {
+		var scopeErr error
+		if wasFilesOnDemand {
+			scopeErr = jf.app.ApplySyncRootScope(jf.job)
+		} else {
+			scopeErr = cloudfiles.CanShareSyncRoots()
+		}
+		if scopeErr != nil {
+			jf.app.Logger().Warn("Failed to change sync root scope",
+				zap.String("job", jf.job.Name),
+				zap.Bool("shared", jf.job.SharedSyncRoot),
+				zap.Error(scopeErr),
+			)
+			dialog.ShowInformation("Other Accounts Not Updated",
+				scopeErr.Error()+"\n\nRun AnemoneSync as administrator once to apply this setting.", parent)
+		}
+	}
+
+	// The Storage Sense policy replaces the one chosen in Windows Settings:
+	// only write it when the setting changes (at registration for a new sync root)
+	storageSenseChanged := jf.job.StorageSense != wasStorageSense ||
+		(jf.job.StorageSense && jf.job.AutoDehydrateDays != wasDehydrateDays)
+	if jf.job.FilesOnDemand && wasFilesOnDemand && storageSenseChanged {
+		if err := jf.app.ApplyStorageSense(jf.job); err != nil {
+			jf.app.Logger().Warn("Failed to apply Storage Sense policy",
+				zap.String("job", jf.job.Name),
+				zap.Bool("opted_in", jf.job.StorageSense),
+				zap.Error(err),
+			)
+			dialog.ShowInformation("Storage Sense Not Updated",
+				err.Error()+"\n\nYou can change this setting in Windows Settings > Storage.", parent)
+		}
+	}
+
 	// For new jobs in mirror mode, show the First Sync Wizard
 	if jf.isNew && jf.job.Mode == syncpkg.SyncModeMirror && !jf.job.FirstSyncDone {
 		jf.showFirstSyncWizard(parent)
@@ -2,8 +2,18 @@
 package app
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scheduler"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
 )
@@ -72,6 +82,12 @@ func (jf *JobForm) triggerModeToIndex(mode SyncTriggerMode) int {
 		return 4
 	case SyncTriggerRealtime:
 		return 5
+	case SyncTriggerNetwork:
+		return 6
+	case SyncTriggerCron:
+		return 7
+	case SyncTriggerIdle:
+		return 8
 	default:
 		return 0 // Manual
 	}
@@ -91,6 +107,12 @@ func (jf *JobForm) indexToTriggerMode(index int) SyncTriggerMode {
 		return SyncTrigger1Hour
 	case 5:
 		return SyncTriggerRealtime
+	case 6:
+		return SyncTriggerNetwork
+	case 7:
+		return SyncTriggerCron
+	case 8:
+		return SyncTriggerIdle
 	default:
 		return SyncTriggerManual
 	}
@@ -104,10 +126,33 @@ func (jf *JobForm) updateTriggerModeHelp() {
 	case 1, 2, 3, 4: // Scheduled
 		jf.triggerModeHelpLabel.SetText("Sync automatically at regular intervals. Checks both local and remote for changes.")
 	case 5: // Realtime
-		jf.triggerModeHelpLabel.SetText("Sync instantly when local files change. Also checks remote every 5 minutes.")
+		jf.triggerModeHelpLabel.SetText("Sync shortly after local files stop changing. Also checks remote every 5 minutes.")
+	case 6: // Network
+		jf.triggerModeHelpLabel.SetText("Sync when the PC connects to a network, e.g. when a laptop gets back to the office. At most once every 5 minutes.")
+	case 7: // Cron
+		jf.triggerModeHelpLabel.SetText("Sync at the times of a cron expression, e.g. '0 2 * * *' every night at 2:00 or '*/30 8-18 * * mon-fri' during office hours.")
+	case 8: // Idle
+		jf.triggerModeHelpLabel.SetText("Sync once you have been away from the keyboard and mouse for a while, so transfers don't slow down your work. A sync stops as soon as you are back and resumes the next time you are away.")
 	default:
 		jf.triggerModeHelpLabel.SetText("")
 	}
+
+	// The debounce delay only applies to realtime mode
+	if jf.triggerModeSelect.SelectedIndex() == 5 {
+		jf.debounceEntry.Enable()
+	} else {
+		jf.debounceEntry.Disable()
+	}
+	if jf.triggerModeSelect.SelectedIndex() == 7 {
+		jf.cronEntry.Enable()
+	} else {
+		jf.cronEntry.Disable()
+	}
+	if jf.triggerModeSelect.SelectedIndex() == 8 {
+		jf.idleEntry.Enable()
+	} else {
+		jf.idleEntry.Disable()
+	}
 }
 
 func (jf *JobForm) autoDehydrateDaysToIndex(days int) int {
@@ -148,6 +193,125 @@ func (jf *JobForm) indexToAutoDehydrateDays(index int) int {
 	}
 }
 
+var errInvalidMaxChanges = &formError{msg: "Change limit must be a number of files (empty for no limit)"}
+
+// maxChangedFiles parses the change cap entry (empty = no limit).
+func (jf *JobForm) maxChangedFiles() (int, error) {
+	text := strings.TrimSpace(jf.maxChangesEntry.Text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, errInvalidMaxChanges
+	}
+	return n, nil
+}
+
+var errInvalidMaxBytes = &formError{msg: "Size limit must be a size like 500 MB or 5 GB (empty for no limit)"}
+
+// maxChangedBytes parses the changed bytes cap entry (empty = no limit).
+func (jf *JobForm) maxChangedBytes() (int64, error) {
+	text := strings.TrimSpace(jf.maxBytesEntry.Text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := scanner.ParseSize(text)
+	if err != nil {
+		return 0, errInvalidMaxBytes
+	}
+	return n, nil
+}
+
+var errInvalidSpeedLimit = &formError{msg: "Speed limits must be a number of KB/s (empty for no limit)"}
+
+// speedLimit parses a bandwidth limit entry in KB/s (empty = no limit).
+func speedLimit(entry *widget.Entry) (int, error) {
+	text := strings.TrimSpace(entry.Text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, errInvalidSpeedLimit
+	}
+	return n, nil
+}
+
+var errInvalidDebounce = &formError{msg: "Wait after last change must be a number of seconds (empty for the default)"}
+
+// debounceSeconds parses the realtime debounce entry (empty = config.yaml setting).
+func (jf *JobForm) debounceSeconds() (int, error) {
+	text := strings.TrimSpace(jf.debounceEntry.Text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, errInvalidDebounce
+	}
+	return n, nil
+}
+
+var errInvalidIdle = &formError{msg: "Idle time must be a number of minutes (empty for the default)"}
+
+// idleMinutes parses the idle time entry (empty = scheduler.DefaultIdleAfter).
+func (jf *JobForm) idleMinutes() (int, error) {
+	text := strings.TrimSpace(jf.idleEntry.Text)
+	if text == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 1 {
+		return 0, errInvalidIdle
+	}
+	return n, nil
+}
+
+// remoteUNC validates the remote folder chosen in the form.
+func (jf *JobForm) remoteUNC() (smbpath.UNCPath, error) {
+	idx := jf.smbConnectionSelect.SelectedIndex()
+	if idx < 0 || idx >= len(jf.smbConnections) {
+		return smbpath.UNCPath{}, errFieldRequired("SMB Server")
+	}
+	return smbpath.JoinUNC(jf.smbConnections[idx].Host, jf.remoteShareSelect.Selected, jf.remotePathEntry.Text)
+}
+
+// cronExpression returns the schedule of a cron job ("" for other trigger modes).
+func (jf *JobForm) cronExpression() (string, error) {
+	if jf.indexToTriggerMode(jf.triggerModeSelect.SelectedIndex()) != SyncTriggerCron {
+		return "", nil
+	}
+	text := strings.Join(strings.Fields(jf.cronEntry.Text), " ")
+	if _, err := scheduler.ParseCron(text); err != nil {
+		return "", &formError{msg: "Invalid schedule: " + err.Error()}
+	}
+	return text, nil
+}
+
+// storageSenseStatus describes the current Storage Sense settings for the job
+// folder, and warns when the auto-free delay is longer than Storage Sense offers.
+func (jf *JobForm) storageSenseStatus() string {
+	status := "Storage Sense applies once Files On Demand is enabled. Uses the auto-free delay above (rounded to 1, 14, 30 or 60 days)."
+	if jf.job.LocalPath != "" && jf.job.FilesOnDemand {
+		settings, err := cloudfiles.GetStorageSenseSettings(jf.job.LocalPath)
+		if err != nil && !settings.Enabled {
+			status = "Storage Sense is turned off in Windows Settings."
+		} else {
+			status = settings.String() + "."
+		}
+	}
+
+	if jf.storageSenseCheck != nil && jf.storageSenseCheck.Checked {
+		days := jf.indexToAutoDehydrateDays(jf.autoDehydrateDaysSelect.SelectedIndex())
+		if capped := cloudfiles.StorageSenseDays(days); days > capped {
+			status += fmt.Sprintf("\n⚠️ Storage Sense offers %d days at most: files will be freed after %d days unused, not %d.",
+				capped, capped, days)
+		}
+	}
+	return status
+}
+
 // updateModeHelp updates the help text based on selected sync mode.
 func (jf *JobForm) updateModeHelp() {
 	switch jf.modeSelect.SelectedIndex() {
@@ -164,6 +328,32 @@ func (jf *JobForm) updateModeHelp() {
 
 // Browse and refresh methods
 
+// exclusionGroupsBox lays out the exclusion group checkboxes.
+func (jf *JobForm) exclusionGroupsBox() fyne.CanvasObject {
+	box := container.NewVBox()
+	for _, check := range jf.exclusionGroupChecks {
+		box.Add(check)
+	}
+	return box
+}
+
+// exclusionGroupOverrides returns the groups whose state differs from their default,
+// so later changes to the defaults still apply to untouched groups.
+func (jf *JobForm) exclusionGroupOverrides() map[string]bool {
+	var overrides map[string]bool
+	for i, group := range jf.exclusionGroups {
+		checked := jf.exclusionGroupChecks[i].Checked
+		if checked == group.DefaultEnabled {
+			continue
+		}
+		if overrides == nil {
+			overrides = make(map[string]bool)
+		}
+		overrides[group.Name] = checked
+	}
+	return overrides
+}
+
 // browseLocalFolder opens a folder browser dialog.
 func (jf *JobForm) browseLocalFolder(parent fyne.Window) {
 	dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
@@ -233,7 +423,7 @@ func (jf *JobForm) refreshShares(parent fyne.Window) {
 			progress.Hide()
 
 			if err != nil {
-				dialog.ShowError(err, parent)
+				jf.app.showFriendlyError(err, smbConn.Host, parent)
 				return
 			}
 
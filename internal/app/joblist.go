@@ -146,20 +146,27 @@ func (jl *JobsList) updateJobItem(id widget.ListItemID, obj fyne.CanvasObject) {
 	sizeLabel := rightContent.Objects[1].(*widget.Label)
 	lastSyncLabel := rightContent.Objects[2].(*widget.Label)
 
-	statusLabel.SetText("Status: " + job.LastStatus.String())
+	if job.LastStatus == JobStatusFailed && job.LastError != "" {
+		statusLabel.SetText("Status: " + job.LastStatus.String() + " — " + job.LastError)
+	} else {
+		statusLabel.SetText("Status: " + job.LastStatus.String())
+	}
 
 	// Display size information
-	if job.LocalSize > 0 {
+	if job.LocalSize > 0 && job.FODSavedBytes > 0 {
+		sizeLabel.SetText(fmt.Sprintf("Size: %s (%d files), %s saved by Files On Demand",
+			formatBytes(job.LocalSize), job.LocalFileCount, formatBytes(job.FODSavedBytes)))
+	} else if job.LocalSize > 0 {
 		sizeLabel.SetText(fmt.Sprintf("Size: %s (%d files)", formatBytes(job.LocalSize), job.LocalFileCount))
 	} else {
 		sizeLabel.SetText("Size: calculating...")
 	}
 
-	if job.LastSync.IsZero() {
-		lastSyncLabel.SetText("Last sync: Never")
-	} else {
-		lastSyncLabel.SetText("Last sync: " + job.LastSync.Format("2006-01-02 15:04"))
+	lastSync := "Never"
+	if !job.LastSync.IsZero() {
+		lastSync = job.LastSync.Format("2006-01-02 15:04")
 	}
+	lastSyncLabel.SetText("Last sync: " + lastSync + " — " + job.TriggerMode.String())
 }
 
 // getStatusColor returns the color for a job status.
@@ -169,7 +176,7 @@ func (jl *JobsList) getStatusColor(status JobStatus) color.Color {
 		return color.RGBA{R: 0, G: 200, B: 83, A: 255} // Green
 	case JobStatusSyncing:
 		return color.RGBA{R: 33, G: 150, B: 243, A: 255} // Blue
-	case JobStatusPartial:
+	case JobStatusPartial, JobStatusApproval:
 		return color.RGBA{R: 255, G: 152, B: 0, A: 255} // Orange
 	case JobStatusFailed:
 		return color.RGBA{R: 244, G: 67, B: 54, A: 255} // Red
@@ -0,0 +1,149 @@
+// Package app provides maintenance windows: daily times when a job must not
+// sync (e.g. while its server is backed up or under maintenance).
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MaintenanceWindow is a daily time range, in local time, during which the
+// scheduler and the file watcher do not start syncs of a job. A window whose
+// end is before its start crosses midnight (e.g. 23:00-01:00).
+type MaintenanceWindow struct {
+	Start string `json:"start"` // "15:04"
+	End   string `json:"end"`   // "15:04"
+}
+
+// What happens to a sync still running when a maintenance window opens.
+const (
+	MaintenanceFinish = "finish" // Let the run finish (default, also "")
+	MaintenancePause  = "pause"  // Stop the run, and sync again when the window closes
+)
+
+// ParseMaintenanceWindows parses a list of windows such as
+// "02:00-03:00, 12:00-12:30" ("" = none).
+func ParseMaintenanceWindows(s string) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(part, "-")
+		w := MaintenanceWindow{Start: strings.TrimSpace(start), End: strings.TrimSpace(end)}
+		if !ok || !validClock(w.Start) || !validClock(w.End) || w.Start == w.End {
+			return nil, fmt.Errorf("invalid maintenance window %q (use HH:MM-HH:MM, e.g. 02:00-03:00)", part)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// FormatMaintenanceWindows is the inverse of ParseMaintenanceWindows.
+func FormatMaintenanceWindows(windows []MaintenanceWindow) string {
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = w.Start + "-" + w.End
+	}
+	return strings.Join(parts, ", ")
+}
+
+func validClock(s string) bool {
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
+
+// at returns the occurrence of the window starting on the day of t.
+func (w MaintenanceWindow) at(t time.Time) (start, end time.Time) {
+	clock := func(s string) time.Time {
+		c, _ := time.Parse("15:04", s)
+		return time.Date(t.Year(), t.Month(), t.Day(), c.Hour(), c.Minute(), 0, 0, t.Location())
+	}
+	start, end = clock(w.Start), clock(w.End)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
+
+// maintenanceEnd returns the end of the window now is in, if any.
+func maintenanceEnd(windows []MaintenanceWindow, now time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, w := range windows {
+		// Windows crossing midnight may have started yesterday
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			start, end := w.at(day)
+			if !now.Before(start) && now.Before(end) && end.After(until) {
+				until = end
+			}
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// nextMaintenance returns the next window starting after now.
+func nextMaintenance(windows []MaintenanceWindow, now time.Time) (start, end time.Time, ok bool) {
+	for _, w := range windows {
+		for _, day := range []time.Time{now, now.AddDate(0, 0, 1)} {
+			s, e := w.at(day)
+			if s.After(now) && (!ok || s.Before(start)) {
+				start, end, ok = s, e, true
+			}
+		}
+	}
+	return start, end, ok
+}
+
+// deferForMaintenance reports whether an automatic sync of the job must wait
+// for a maintenance window to close; the scheduler then runs it at the end
+// of the window.
+func (a *App) deferForMaintenance(job *SyncJob) bool {
+	until, ok := maintenanceEnd(job.MaintenanceWindows, a.clock.Now())
+	if !ok {
+		return false
+	}
+
+	a.logger.Info("Sync deferred until maintenance window closes",
+		zap.String("name", job.Name),
+		zap.Time("until", until),
+	)
+	if a.scheduler != nil {
+		a.scheduler.DeferJob(job, until)
+	}
+	return true
+}
+
+// pauseAtMaintenance cancels the running sync of a job with the "pause"
+// policy when its next maintenance window opens. The returned function must
+// be called when the sync ends: it reports whether the run was stopped, and
+// the end of the window.
+func (m *SyncManager) pauseAtMaintenance(job *SyncJob, cancel context.CancelFunc) func() (time.Time, bool) {
+	if job.MaintenancePolicy != MaintenancePause {
+		return func() (time.Time, bool) { return time.Time{}, false }
+	}
+	now := m.app.clock.Now()
+	start, end, ok := nextMaintenance(job.MaintenanceWindows, now)
+	if !ok {
+		return func() (time.Time, bool) { return time.Time{}, false }
+	}
+
+	var paused atomic.Bool
+	timer := m.app.clock.AfterFunc(start.Sub(now), func() {
+		m.logger.Info("Maintenance window opened, pausing sync",
+			zap.String("name", job.Name),
+			zap.Time("until", end),
+		)
+		paused.Store(true)
+		cancel()
+	})
+	return func() (time.Time, bool) {
+		timer.Stop()
+		return end, paused.Load()
+	}
+}
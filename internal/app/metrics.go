@@ -0,0 +1,28 @@
+package app
+
+import (
+	"github.com/juste-un-gars/anemone_sync_windows/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// serveMetrics serves the Prometheus metrics on a local address
+// (logging.metrics in config.yaml) until the app quits.
+func (a *App) serveMetrics(listen string) {
+	metrics.QueueDepth.SetFunc(a.queuedActions)
+	if err := metrics.Serve(a.ctx, listen, a.logger.Named("metrics")); err != nil {
+		a.logger.Warn("Failed to start the metrics listener",
+			zap.String("listen", listen),
+			zap.Error(err))
+	}
+}
+
+// queuedActions counts the actions left to run in the syncs in progress.
+func (a *App) queuedActions() int64 {
+	var queued int64
+	for _, run := range a.RunningSyncs() {
+		if p := run.Progress; p.Phase == "executing" && p.FilesTotal > p.FilesProcessed {
+			queued += int64(p.FilesTotal - p.FilesProcessed)
+		}
+	}
+	return queued
+}
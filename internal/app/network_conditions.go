@@ -0,0 +1,159 @@
+// Package app provides per-job network conditions: automatic syncs of a job
+// can be restricted to some Wi-Fi networks, to unmetered connections or to
+// times a VPN is connected.
+package app
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+// networkRecheck is how long a sync deferred by the network conditions of
+// its job waits before checking them again.
+const networkRecheck = 15 * time.Minute
+
+// ParseSSIDs splits a comma-separated list of Wi-Fi network names.
+func ParseSSIDs(text string) []string {
+	var ssids []string
+	for _, ssid := range strings.Split(text, ",") {
+		if ssid = strings.TrimSpace(ssid); ssid != "" {
+			ssids = append(ssids, ssid)
+		}
+	}
+	return ssids
+}
+
+// unmetNetworkCondition returns why the current network doesn't meet the
+// network conditions of a job ("" = met, or the job has none).
+func unmetNetworkCondition(job *SyncJob) string {
+	if len(job.WifiSSIDs) > 0 {
+		current, err := connectedSSIDs()
+		if err != nil {
+			return fmt.Sprintf("Wi-Fi network unknown (%v)", err)
+		}
+		if !matchesSSID(current, job.WifiSSIDs) {
+			return fmt.Sprintf("not on Wi-Fi %s", strings.Join(job.WifiSSIDs, ", "))
+		}
+	}
+	if job.AvoidMetered {
+		// Unknown cost: the connection is not known to be metered
+		if metered, err := bandwidth.IsMetered(); err == nil && metered {
+			return "metered connection"
+		}
+	}
+	if job.VPNInterface != "" {
+		up, err := interfaceConnected(job.VPNInterface)
+		if err != nil {
+			return fmt.Sprintf("network adapters unknown (%v)", err)
+		}
+		if !up {
+			return fmt.Sprintf("%s not connected", job.VPNInterface)
+		}
+	}
+	return ""
+}
+
+// deferForNetwork reports whether an automatic sync of the job must wait for
+// its network conditions to be met; the scheduler then tries again after
+// networkRecheck.
+func (a *App) deferForNetwork(job *SyncJob) bool {
+	reason := unmetNetworkCondition(job)
+	if reason == "" {
+		return false
+	}
+
+	until := time.Now().Add(networkRecheck)
+	a.logger.Info("Sync deferred by network conditions",
+		zap.String("name", job.Name),
+		zap.String("reason", reason),
+		zap.Time("until", until),
+	)
+	if a.scheduler != nil {
+		a.scheduler.DeferJob(job, until)
+	}
+	return true
+}
+
+// matchesSSID reports whether one of the connected Wi-Fi networks is allowed
+// (names compared without case).
+func matchesSSID(connected, allowed []string) bool {
+	for _, c := range connected {
+		for _, a := range allowed {
+			if strings.EqualFold(c, a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// connectedSSIDs returns the names of the Wi-Fi networks the PC is connected
+// to (none without Wi-Fi). Recent Windows versions only report them to
+// applications allowed to access the location.
+func connectedSSIDs() ([]string, error) {
+	cmd := exec.Command("netsh", "wlan", "show", "interfaces")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	output, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(string(output), "wlansvc") {
+			return nil, nil // WLAN service not running: no Wi-Fi adapter
+		}
+		return nil, fmt.Errorf("netsh failed: %w", err)
+	}
+	return parseSSIDs(string(output)), nil
+}
+
+// parseSSIDs returns the SSID lines of "netsh wlan show interfaces" (the
+// labels are not translated).
+func parseSSIDs(output string) []string {
+	var ssids []string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "SSID" {
+			if ssid := strings.TrimSpace(value); ssid != "" {
+				ssids = append(ssids, ssid)
+			}
+		}
+	}
+	return ssids
+}
+
+// interfaceConnected reports whether a network adapter whose name or
+// description contains name (without case) is connected, e.g. "WireGuard" or
+// "TAP-Windows" for a VPN.
+func interfaceConnected(name string) (bool, error) {
+	size := uint32(15 << 10)
+	var buf []byte
+	for {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST|windows.GAA_FLAG_SKIP_DNS_SERVER,
+			0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_BUFFER_OVERFLOW {
+			return false, fmt.Errorf("GetAdaptersAddresses failed: %w", err)
+		}
+	}
+
+	name = strings.ToLower(name)
+	for aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); aa != nil; aa = aa.Next {
+		if aa.OperStatus != windows.IfOperStatusUp {
+			continue
+		}
+		friendly := strings.ToLower(windows.UTF16PtrToString(aa.FriendlyName))
+		description := strings.ToLower(windows.UTF16PtrToString(aa.Description))
+		if strings.Contains(friendly, name) || strings.Contains(description, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,150 @@
+// Package app provides network connection detection for scheduled syncs.
+package app
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+// networkSettleDelay lets interfaces finish getting their addresses (DHCP,
+// IPv6 autoconfiguration) before deciding that the PC connected to a network.
+const networkSettleDelay = 10 * time.Second
+
+// NetworkWatcher tells the scheduler when the PC connects to a network,
+// for jobs synced on network connect.
+type NetworkWatcher struct {
+	app    *App
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	addrs     string // Addresses of the connected interfaces at the last check
+	handle    windows.Handle
+	debouncer *debouncer
+}
+
+// activeNetworkWatcher receives the interface change notifications.
+var activeNetworkWatcher atomic.Pointer[NetworkWatcher]
+
+// networkChangeCallback is created once: Windows callbacks are never freed.
+var networkChangeCallback = sync.OnceValue(func() uintptr {
+	return windows.NewCallback(onNetworkChange)
+})
+
+// onNetworkChange is called by Windows on a thread pool thread for every IP
+// interface change; it must return quickly.
+func onNetworkChange(callerContext uintptr, row *windows.MibIpInterfaceRow, notificationType uint32) uintptr {
+	if nw := activeNetworkWatcher.Load(); nw != nil {
+		nw.debouncer.trigger()
+	}
+	return 0
+}
+
+// NewNetworkWatcher creates a new network watcher.
+func NewNetworkWatcher(app *App, logger *zap.Logger) *NetworkWatcher {
+	nw := &NetworkWatcher{
+		app:    app,
+		logger: logger,
+	}
+	nw.debouncer = newDebouncer(clock.System, networkSettleDelay, nw.check)
+	return nw
+}
+
+// Start begins watching network interface changes.
+func (nw *NetworkWatcher) Start() {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	if nw.handle != 0 {
+		return
+	}
+
+	// The connection present at startup is not a new one
+	nw.addrs = connectedAddresses()
+
+	activeNetworkWatcher.Store(nw)
+	err := windows.NotifyIpInterfaceChange(windows.AF_UNSPEC, networkChangeCallback(), nil, false, &nw.handle)
+	if err != nil {
+		activeNetworkWatcher.Store(nil)
+		nw.logger.Warn("Failed to watch network changes", zap.Error(err))
+		return
+	}
+
+	nw.logger.Info("Network watcher started", zap.String("addresses", nw.addrs))
+}
+
+// Stop stops watching network interface changes.
+func (nw *NetworkWatcher) Stop() {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	if nw.handle == 0 {
+		return
+	}
+
+	if err := windows.CancelMibChangeNotify2(nw.handle); err != nil {
+		nw.logger.Warn("Failed to stop watching network changes", zap.Error(err))
+	}
+	nw.handle = 0
+	activeNetworkWatcher.CompareAndSwap(nw, nil)
+	nw.debouncer.stop()
+
+	nw.logger.Info("Network watcher stopped")
+}
+
+// check runs the jobs synced on network connect when the PC got new
+// addresses (connected, or moved to another network).
+func (nw *NetworkWatcher) check() {
+	addrs := connectedAddresses()
+
+	nw.mu.Lock()
+	changed := addrs != nw.addrs
+	nw.addrs = addrs
+	nw.mu.Unlock()
+
+	if !changed || addrs == "" {
+		return // Same network, or disconnected
+	}
+
+	nw.logger.Info("Network connected", zap.String("addresses", addrs))
+	if nw.app.scheduler != nil {
+		nw.app.scheduler.NetworkConnected()
+	}
+	go nw.app.offlineQueue.Replay()
+}
+
+// connectedAddresses returns the sorted addresses of the interfaces that are
+// up, ignoring loopback and link-local ones (present without a network).
+func connectedAddresses() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	var addrs []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range ifaceAddrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			addrs = append(addrs, ipNet.IP.String())
+		}
+	}
+	slices.Sort(addrs)
+	return strings.Join(addrs, ",")
+}
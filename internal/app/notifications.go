@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"go.uber.org/zap"
@@ -21,6 +22,9 @@ const (
 type Notifier struct {
 	app     *App
 	enabled bool
+
+	mu   sync.Mutex
+	held []*fyne.Notification // Sent while the user presents (see hold)
 }
 
 // NewNotifier creates a new Notifier.
@@ -53,10 +57,12 @@ func (n *Notifier) Send(title, message string, notifyType NotificationType) {
 		zap.String("message", message),
 	)
 
-	// Send via Fyne
-	fyneApp := n.app.FyneApp()
+	// Send via Fyne, once the user stops presenting
 	notification := fyne.NewNotification(title, message)
-	fyneApp.SendNotification(notification)
+	if n.hold(notification) {
+		return
+	}
+	n.app.FyneApp().SendNotification(notification)
 }
 
 // SyncStarted sends a notification when sync starts.
@@ -77,11 +83,12 @@ func (n *Notifier) SyncCompleted(jobName string, filesCount int) {
 	)
 }
 
-// SyncFailed sends a notification when sync fails.
-func (n *Notifier) SyncFailed(jobName string, err error) {
+// SyncFailed sends a notification when sync fails, with the user message of
+// the error.
+func (n *Notifier) SyncFailed(jobName string, message string) {
 	n.Send(
 		"Sync Failed",
-		fmt.Sprintf("'%s' sync failed: %v", jobName, err),
+		fmt.Sprintf("'%s': %s", jobName, message),
 		NotifyError,
 	)
 }
@@ -104,6 +111,46 @@ func (n *Notifier) ConflictDetected(jobName string, count int) {
 	)
 }
 
+// ApprovalRequired sends a notification when a sync is paused by the change cap.
+func (n *Notifier) ApprovalRequired(jobName string, filesCount int) {
+	n.Send(
+		"Approval Required",
+		fmt.Sprintf("'%s' paused: %d files would change. Use Sync Now to review.", jobName, filesCount),
+		NotifyWarning,
+	)
+}
+
+// RansomwareSuspected sends a notification when a sync is paused because the
+// local changes look like ransomware.
+func (n *Notifier) RansomwareSuspected(jobName string) {
+	n.Send(
+		"Possible Ransomware",
+		fmt.Sprintf("'%s' paused: local files look encrypted. Nothing was sent to the server.", jobName),
+		NotifyError,
+	)
+}
+
+// SecurityDowngraded sends a notification when a server negotiates less
+// signing or encryption than before.
+func (n *Notifier) SecurityDowngraded(serverName, security, recorded string) {
+	n.Send(
+		"Server Security Changed",
+		fmt.Sprintf("'%s' is now %s, it used to be %s. Check the server settings.", serverName, security, recorded),
+		NotifyError,
+	)
+}
+
+// CorruptionSuspected sends a notification when too many transfers to a
+// server were read back corrupted.
+func (n *Notifier) CorruptionSuspected(serverName string, mismatches, attempts int64) {
+	n.Send(
+		"Transfers Corrupted",
+		fmt.Sprintf("%d of %d verified transfers with '%s' were corrupted. Check the network cable, network card and the memory of the NAS.",
+			mismatches, attempts, serverName),
+		NotifyError,
+	)
+}
+
 // ConnectionLost sends a notification when connection is lost.
 func (n *Notifier) ConnectionLost(serverName string) {
 	n.Send(
@@ -0,0 +1,272 @@
+// Package app provides the offline queue of jobs whose server is unreachable.
+package app
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// offlineProbeInterval is how often the servers of offline jobs are probed.
+const offlineProbeInterval = time.Minute
+
+// offlineProbeTimeout bounds the connection attempt of a probe.
+const offlineProbeTimeout = 5 * time.Second
+
+// OfflineQueue takes over the jobs whose last sync found their server
+// unreachable. The watcher records their local changes in the queue instead
+// of starting syncs bound to fail, and their servers are probed every minute
+// (and when the PC connects to a network). Once a server answers, its jobs
+// replay with a whole-job sync: change detection compares both sides with the
+// last synced state, so files also changed on the server meanwhile are
+// resolved as conflicts by the job's policy instead of being overwritten. The
+// queue of a job is emptied when a whole-job sync succeeds, and survives
+// restarts.
+type OfflineQueue struct {
+	app    *App
+	logger *zap.Logger
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	offline map[int64]bool // Jobs whose server was unreachable at their last sync
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewOfflineQueue creates the offline queue, nil when config.yaml disables it
+// (sync.network.enable_offline_queue).
+func NewOfflineQueue(app *App, cfg *config.Config, logger *zap.Logger) *OfflineQueue {
+	if !cfg.Sync.Network.EnableOfflineQueue {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OfflineQueue{
+		app:     app,
+		logger:  logger,
+		clock:   clock.System,
+		offline: make(map[int64]bool),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start takes over the jobs with changes queued before a restart and starts
+// probing the servers of offline jobs.
+func (q *OfflineQueue) Start() {
+	if q == nil || q.app.db == nil {
+		return
+	}
+
+	ids, err := q.app.db.GetOfflineJobs()
+	if err != nil {
+		q.logger.Warn("Failed to read the offline queue", zap.Error(err))
+	}
+	q.mu.Lock()
+	for _, id := range ids {
+		q.offline[id] = true
+	}
+	q.mu.Unlock()
+	if len(ids) > 0 {
+		q.logger.Info("Jobs with offline changes to replay", zap.Int64s("job_ids", ids))
+	}
+
+	q.wg.Add(1)
+	go q.probeLoop()
+}
+
+// Stop stops probing servers.
+func (q *OfflineQueue) Stop() {
+	if q == nil {
+		return
+	}
+	q.cancel()
+	q.wg.Wait()
+}
+
+// IsOffline reports whether the server of a job was unreachable at its last
+// sync.
+func (q *OfflineQueue) IsOffline(jobID int64) bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.offline[jobID]
+}
+
+// Record queues a local change of a job under localPath while its server is
+// unreachable. Returns false when the job is online: nothing is recorded and
+// the change syncs as usual.
+func (q *OfflineQueue) Record(jobID int64, localPath string, event fsnotify.Event) bool {
+	if !q.IsOffline(jobID) {
+		return false
+	}
+	if event.Op == fsnotify.Chmod {
+		return true // Attributes sync with the replay, nothing to queue
+	}
+
+	rel, err := filepath.Rel(localPath, event.Name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+	operation := database.OfflineUpload
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		operation = database.OfflineDelete
+	}
+
+	err = q.app.db.QueueOfflineChange(&database.OfflineQueueItem{
+		JobID:     jobID,
+		FilePath:  filepath.ToSlash(rel),
+		Operation: operation,
+	})
+	if err != nil {
+		q.logger.Warn("Failed to queue offline change",
+			zap.Int64("job_id", jobID),
+			zap.String("path", event.Name),
+			zap.Error(err),
+		)
+		return true
+	}
+	q.logger.Debug("Offline change queued",
+		zap.Int64("job_id", jobID),
+		zap.String("path", rel),
+		zap.String("operation", operation),
+	)
+	return true
+}
+
+// SyncFinished updates the offline state of a job after a sync of subtree
+// ("" = whole job) that returned err.
+func (q *OfflineQueue) SyncFinished(job *SyncJob, subtree string, err error) {
+	if q == nil || q.app.db == nil {
+		return
+	}
+
+	switch syncpkg.ErrorCodeOf(err) {
+	case syncpkg.ErrorCodeSyncInProgress, syncpkg.ErrorCodeCancelled:
+		return // Tells nothing about the server
+	case syncpkg.ErrorCodeServerUnreachable:
+		if q.setOffline(job.ID, true) {
+			if dbErr := q.app.db.MarkOfflineReplayFailed(job.ID, err.Error()); dbErr != nil {
+				q.logger.Warn("Failed to update the offline queue", zap.Error(dbErr))
+			}
+			return
+		}
+		q.logger.Warn("Server unreachable, recording local changes until it is back",
+			zap.String("name", job.Name),
+			zap.Error(err),
+		)
+		if q.app.notifier != nil {
+			q.app.notifier.ConnectionLost(q.serverName(job))
+		}
+		return
+	}
+
+	// The server answered: the queue stays until a whole-job sync succeeds
+	wasOffline := q.setOffline(job.ID, false)
+	if wasOffline && q.app.notifier != nil {
+		q.app.notifier.ConnectionRestored(q.serverName(job))
+	}
+	if err != nil || subtree != "" {
+		return
+	}
+	replayed, dbErr := q.app.db.ClearOfflineQueue(job.ID)
+	if dbErr != nil {
+		q.logger.Warn("Failed to clear the offline queue", zap.Error(dbErr))
+		return
+	}
+	if replayed > 0 {
+		q.logger.Info("Offline changes replayed",
+			zap.String("name", job.Name),
+			zap.Int64("changes", replayed),
+		)
+	}
+}
+
+// Replay syncs the offline jobs whose server answers again.
+func (q *OfflineQueue) Replay() {
+	if q == nil {
+		return
+	}
+
+	for _, job := range q.app.GetSyncJobs() {
+		if !job.Enabled || !q.IsOffline(job.ID) || !q.reachable(job) {
+			continue
+		}
+		if q.app.deferAutomaticSync(job) {
+			continue
+		}
+		q.logger.Info("Server reachable again, replaying offline changes",
+			zap.String("name", job.Name),
+		)
+		q.wg.Add(1)
+		go func(jobID int64) {
+			defer q.wg.Done()
+			q.app.ExecuteJobSync(jobID)
+		}(job.ID)
+	}
+}
+
+// probeLoop replays the offline jobs every offlineProbeInterval until Stop.
+func (q *OfflineQueue) probeLoop() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.clock.After(offlineProbeInterval):
+			q.Replay()
+		}
+	}
+}
+
+// setOffline records whether the server of a job is unreachable and returns
+// the previous state.
+func (q *OfflineQueue) setOffline(jobID int64, offline bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	was := q.offline[jobID]
+	if offline {
+		q.offline[jobID] = true
+	} else {
+		delete(q.offline, jobID)
+	}
+	return was
+}
+
+// reachable reports whether the server of a job accepts connections.
+func (q *OfflineQueue) reachable(job *SyncJob) bool {
+	if job.RemoteHost == "" {
+		return true // Left to the sync to find out
+	}
+	addr := smbpath.RemoteEndpoint{Host: job.RemoteHost, Port: job.RemotePort}.Addr()
+
+	dialer := net.Dialer{Timeout: offlineProbeTimeout}
+	c, err := dialer.DialContext(q.ctx, "tcp", addr)
+	if err != nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+// serverName returns the name of the server of a job for notifications.
+func (q *OfflineQueue) serverName(job *SyncJob) string {
+	if conn := q.app.GetSMBConnection(job.SMBConnectionID); conn != nil {
+		return conn.DisplayName()
+	}
+	return job.RemoteHost
+}
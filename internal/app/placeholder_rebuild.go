@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// RebuildResult summarizes a placeholder rebuild.
+type RebuildResult struct {
+	RemoteFiles int // Files in the remote listing
+	RemoteDirs  int // Directories in the remote listing
+	Recreated   int // Files and directories missing locally, recreated as placeholders
+}
+
+// RebuildPlaceholders recreates the placeholder tree of a Files On Demand job
+// from the current remote listing, e.g. after the local placeholders were
+// deleted by accident. No file content is transferred: placeholders are
+// hydrated when opened, as usual. Files and directories still on disk are
+// left as they are.
+func RebuildPlaceholders(ctx context.Context, dbJob *database.SyncJob, logger *zap.Logger) (*RebuildResult, error) {
+	job := convertDBJobToAppJob(dbJob)
+	if !job.FilesOnDemand {
+		return nil, fmt.Errorf("job %q does not have Files On Demand enabled", job.Name)
+	}
+	if !cloudfiles.IsAvailable() {
+		return nil, fmt.Errorf("Cloud Files API not available (requires Windows 10 1709+)")
+	}
+
+	localPath := filepath.FromSlash(job.LocalPath)
+	if err := os.MkdirAll(localPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local folder: %w", err)
+	}
+
+	dataSource, err := newSMBDataSource(job, logger)
+	if err != nil {
+		return nil, err
+	}
+	remoteFiles, err := dataSource.ListFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote files: %w", err)
+	}
+
+	result := &RebuildResult{}
+	for _, f := range remoteFiles {
+		if f.IsDirectory {
+			result.RemoteDirs++
+		} else {
+			result.RemoteFiles++
+		}
+		if _, err := os.Lstat(filepath.Join(localPath, filepath.FromSlash(f.Path))); os.IsNotExist(err) {
+			result.Recreated++
+		}
+	}
+
+	// Passive provider: the running application (if any) stays the one
+	// answering hydration requests for this sync root
+	provider, err := cloudfiles.NewCloudFilesProvider(cloudfiles.ProviderConfig{
+		LocalPath:     localPath,
+		RemotePath:    job.RemotePath,
+		ProviderName:  "AnemoneSync",
+		Logger:        logger.Named("cloudfiles"),
+		SyncRootScope: syncRootScope(job),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	if err := provider.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize provider: %w", err)
+	}
+	defer provider.Close()
+
+	if err := provider.SyncPlaceholders(ctx, remoteFiles); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Placeholders rebuilt from remote listing",
+		zap.String("job", job.Name),
+		zap.Int("remote_files", result.RemoteFiles),
+		zap.Int("recreated", result.Recreated),
+	)
+	return result, nil
+}
@@ -0,0 +1,129 @@
+// Package app provides presentation mode detection: automatic syncs and
+// notifications can wait while the user presents, shares the screen or runs
+// a full-screen application.
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	shell32 = windows.NewLazySystemDLL("shell32.dll")
+
+	procSHQueryUserNotificationState = shell32.NewProc("SHQueryUserNotificationState")
+)
+
+// QUERY_USER_NOTIFICATION_STATE values meaning the user must not be
+// interrupted (shellapi.h)
+const (
+	qunsBusy                 = 2 // Full-screen application
+	qunsRunningD3DFullScreen = 3 // Full-screen Direct3D application (game, video)
+	qunsPresentationMode     = 4 // Presentation settings or screen sharing
+)
+
+// presentationRecheck is how long a deferred sync waits before checking
+// again whether the user still presents.
+const presentationRecheck = 5 * time.Minute
+
+// presentationPoll is how often held notifications check whether the user
+// stopped presenting.
+const presentationPoll = 30 * time.Second
+
+// userPresenting reports whether Windows considers that the user must not be
+// interrupted: full-screen application, presentation mode or screen sharing.
+func userPresenting() bool {
+	if procSHQueryUserNotificationState.Find() != nil {
+		return false
+	}
+	var state uint32
+	hr, _, _ := procSHQueryUserNotificationState.Call(uintptr(unsafe.Pointer(&state)))
+	if hr != 0 {
+		return false
+	}
+	switch state {
+	case qunsBusy, qunsRunningD3DFullScreen, qunsPresentationMode:
+		return true
+	}
+	return false
+}
+
+// deferForPresentation reports whether an automatic sync of the job must
+// wait because the user presents; the scheduler then tries again after
+// presentationRecheck.
+func (a *App) deferForPresentation(job *SyncJob) bool {
+	if !a.GetPauseSyncWhenPresenting() || !userPresenting() {
+		return false
+	}
+
+	until := time.Now().Add(presentationRecheck)
+	a.logger.Info("Sync deferred while presenting",
+		zap.String("name", job.Name),
+		zap.Time("until", until),
+	)
+	if a.scheduler != nil {
+		a.scheduler.DeferJob(job, until)
+	}
+	return true
+}
+
+// deferAutomaticSync reports whether an automatic sync of the job must wait
+// for a maintenance window to close, for the user to stop presenting or for
+// its network conditions to be met.
+func (a *App) deferAutomaticSync(job *SyncJob) bool {
+	return a.deferForMaintenance(job) || a.deferForPresentation(job) || a.deferForNetwork(job)
+}
+
+// hold keeps a notification sent while the user presents, and shows the held
+// notifications once they stop. Returns false if the notification must be
+// shown now.
+func (n *Notifier) hold(notification *fyne.Notification) bool {
+	if !n.app.GetHoldNotificationsWhenPresenting() || !userPresenting() {
+		return false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.held = append(n.held, notification)
+	if len(n.held) == 1 {
+		go n.releaseHeld()
+	}
+	return true
+}
+
+// releaseHeld waits for the user to stop presenting, then shows the held
+// notifications: a single one as is, several as a summary.
+func (n *Notifier) releaseHeld() {
+	ticker := time.NewTicker(presentationPoll)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !userPresenting() {
+			break
+		}
+	}
+
+	n.mu.Lock()
+	held := n.held
+	n.held = nil
+	n.mu.Unlock()
+
+	n.app.Logger().Info("Showing notifications held while presenting", zap.Int("count", len(held)))
+	if len(held) == 1 {
+		n.app.FyneApp().SendNotification(held[0])
+		return
+	}
+	titles := make([]string, len(held))
+	for i, h := range held {
+		titles[i] = h.Title
+	}
+	n.app.FyneApp().SendNotification(fyne.NewNotification(
+		"While you were presenting",
+		fmt.Sprintf("%d notifications: %s", len(held), strings.Join(titles, ", ")),
+	))
+}
@@ -161,7 +161,7 @@ func (b *RemoteFolderBrowser) loadFolders(parent fyne.Window) {
 			if err != nil {
 				b.folders = []string{}
 				b.folderList.Refresh()
-				dialog.ShowError(err, parent)
+				b.app.showFriendlyError(err, b.smbConn.Host, parent)
 				return
 			}
 
@@ -0,0 +1,119 @@
+// Package app provides remote change detection for realtime syncs.
+package app
+
+import (
+	"context"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// remoteRewatchDelay is the wait before watching a server folder again after
+// the watch failed or ended (server unreachable, connection lost).
+const remoteRewatchDelay = time.Minute
+
+// watchRemote follows the changes of the server folder of a realtime job
+// (SMB2 CHANGE_NOTIFY), so that they sync as soon as local ones, scoped to
+// the folders that changed instead of the whole server folder. The watch is
+// opened again after it ends, until ctx is done.
+func (w *Watcher) watchRemote(ctx context.Context, jw *jobWatcher, remote smbpath.UNCPath) {
+	failures := 0
+	for {
+		rw, err := smb.WatchRemote(remote)
+		if err != nil {
+			// Logged once: the server may stay unreachable for long
+			if failures == 0 {
+				w.logger.Info("Cannot watch server folder, remote changes sync at the next whole-job sync",
+					zap.Int64("job_id", jw.jobID),
+					zap.String("remote", remote.String()),
+					zap.Error(err),
+				)
+			}
+			failures++
+		} else {
+			if failures > 0 {
+				w.logger.Info("Watching server folder again",
+					zap.Int64("job_id", jw.jobID),
+					zap.String("remote", remote.String()),
+				)
+			}
+			failures = 0
+			w.followRemote(ctx, jw, rw)
+			rw.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.clock.After(remoteRewatchDelay):
+		}
+	}
+}
+
+// followRemote turns the changes of a remote watch into debounced syncs,
+// until the watch ends or ctx is done.
+func (w *Watcher) followRemote(ctx context.Context, jw *jobWatcher, rw *smb.RemoteWatcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case changes, ok := <-rw.Changes:
+			if !ok {
+				return
+			}
+			w.handleRemoteChanges(jw, changes)
+
+		case err, ok := <-rw.Errors:
+			if !ok {
+				return
+			}
+			if !errors.Is(err, smb.ErrChangesLost) {
+				w.logger.Warn("Server folder watch ended",
+					zap.Int64("job_id", jw.jobID),
+					zap.Error(err),
+				)
+				return
+			}
+			// The server dropped changes: sync the whole job
+			w.logger.Debug("Remote changes lost", zap.Int64("job_id", jw.jobID))
+			jw.addFolderScope("")
+			jw.debouncer.trigger()
+		}
+	}
+}
+
+// handleRemoteChanges processes the changes of one server notification.
+func (w *Watcher) handleRemoteChanges(jw *jobWatcher, changes []smb.RemoteChange) {
+	// Changes made by the job's own sync come back from the server
+	if jw.syncActive || w.clock.Now().Before(jw.syncCooldown) {
+		return
+	}
+
+	triggered := false
+	for _, change := range changes {
+		if w.shouldIgnore(path.Base(change.Path)) {
+			continue
+		}
+		w.logger.Debug("Remote change",
+			zap.Int64("job_id", jw.jobID),
+			zap.String("path", change.Path),
+			zap.String("action", change.Action.String()),
+		)
+
+		folder, err := syncpkg.NormalizeSubtree(path.Dir(change.Path))
+		if err != nil {
+			folder = ""
+		}
+		jw.addFolderScope(folder)
+		triggered = true
+	}
+	if triggered {
+		jw.debouncer.trigger()
+	}
+}
@@ -7,30 +7,49 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scheduler"
 )
 
+// defaultScheduleJitter spreads jobs due at the same time unless config.yaml
+// sets sync.schedule_jitter_seconds.
+const defaultScheduleJitter = 30 * time.Second
+
 // Scheduler manages scheduled sync operations for all jobs.
+// Triggers (intervals, cron expressions, network connections), jitter and
+// next run persistence are handled by the scheduler package.
 type Scheduler struct {
 	app    *App
 	logger *zap.Logger
 
-	mu       sync.RWMutex
-	timers   map[int64]*time.Timer // Job ID -> Timer
-	running  bool
-	ctx      context.Context
-	cancel   context.CancelFunc
+	mu      sync.RWMutex
+	core    *scheduler.Scheduler
+	running bool
+	ctx     context.Context
+	cancel  context.CancelFunc
 }
 
 // NewScheduler creates a new scheduler instance.
-func NewScheduler(app *App, logger *zap.Logger) *Scheduler {
+func NewScheduler(app *App, cfg *config.Config, logger *zap.Logger) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Scheduler{
+	s := &Scheduler{
 		app:    app,
 		logger: logger,
-		timers: make(map[int64]*time.Timer),
 		ctx:    ctx,
 		cancel: cancel,
 	}
+
+	jitter := defaultScheduleJitter
+	if cfg.Sync.ScheduleJitterSeconds >= 0 {
+		jitter = time.Duration(cfg.Sync.ScheduleJitterSeconds) * time.Second
+	}
+	s.core = scheduler.New(s.executeSync, scheduler.Options{
+		Store:     s,
+		MaxJitter: jitter,
+		Logger:    logger.Named("core"),
+	})
+	return s
 }
 
 // Start begins the scheduler and schedules all enabled jobs.
@@ -45,7 +64,7 @@ func (s *Scheduler) Start() {
 
 	s.logger.Info("Scheduler starting")
 
-	// Schedule all enabled jobs (scheduled or realtime mode)
+	// Schedule all enabled jobs (scheduled, realtime, network or idle mode)
 	jobs := s.app.GetSyncJobs()
 	for _, job := range jobs {
 		if job.Enabled && s.shouldSchedule(job.TriggerMode) {
@@ -53,7 +72,7 @@ func (s *Scheduler) Start() {
 		}
 	}
 
-	s.logger.Info("Scheduler started", zap.Int("scheduled_jobs", len(s.timers)))
+	s.logger.Info("Scheduler started", zap.Int("scheduled_jobs", s.core.Len()))
 }
 
 // shouldSchedule returns true if the trigger mode requires scheduling.
@@ -64,7 +83,7 @@ func (s *Scheduler) shouldSchedule(mode SyncTriggerMode) bool {
 	case SyncTriggerRealtime:
 		return true // Realtime also schedules for remote checking
 	default:
-		return true // All interval modes
+		return true // Interval, cron and network modes
 	}
 }
 
@@ -83,81 +102,98 @@ func (s *Scheduler) Stop() {
 	s.cancel()
 
 	// Stop all timers
-	for id, timer := range s.timers {
-		timer.Stop()
-		delete(s.timers, id)
-	}
+	s.core.Stop()
 
 	s.running = false
 	s.logger.Info("Scheduler stopped")
 }
 
 // ScheduleJob schedules a specific job based on its trigger mode.
+// A next run saved before a restart is kept.
 func (s *Scheduler) ScheduleJob(job *SyncJob) {
 	if job.TriggerMode == SyncTriggerManual {
 		s.logger.Debug("Job is manual, not scheduling", zap.String("name", job.Name))
 		return
 	}
 
-	interval := s.getInterval(job.TriggerMode)
-	if interval == 0 {
-		s.logger.Warn("Invalid trigger mode", zap.String("mode", string(job.TriggerMode)))
+	schedJob := scheduler.Job{
+		ID:            job.ID,
+		TriggerMode:   convertTriggerModeForDB(job.TriggerMode),
+		TriggerParams: triggerParamsForDB(job),
+		NextRun:       job.NextSync,
+	}
+	if job.TriggerMode == SyncTriggerIdle {
+		// The idle time is a job option, not in trigger_params
+		schedJob.TriggerMode, schedJob.TriggerParams = scheduler.ModeIdle, job.idleAfter().String()
+	}
+	err := s.core.Schedule(schedJob)
+	if err != nil {
+		s.logger.Warn("Invalid trigger mode",
+			zap.String("name", job.Name),
+			zap.String("mode", string(job.TriggerMode)),
+			zap.Error(err),
+		)
 		return
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	next, _ := s.core.NextRun(job.ID)
+	s.logger.Info("Job scheduled",
+		zap.String("name", job.Name),
+		zap.String("mode", string(job.TriggerMode)),
+		zap.Time("next_run", next),
+	)
+}
 
-	// Cancel existing timer if any
-	if existing, ok := s.timers[job.ID]; ok {
-		existing.Stop()
-		delete(s.timers, job.ID)
+// DeferJob runs a job once at the given time instead of its next regular
+// run (e.g. at the end of a maintenance window), then schedules it as usual.
+func (s *Scheduler) DeferJob(job *SyncJob, at time.Time) {
+	s.mu.RLock()
+	running := s.running
+	s.mu.RUnlock()
+	if !running {
+		return
 	}
 
-	// Calculate next run time
-	nextRun := time.Now().Add(interval)
-
-	// Update job's next sync time
-	s.updateJobNextSync(job.ID, nextRun)
-
-	// Create new timer
-	timer := time.AfterFunc(interval, func() {
-		s.onJobTimer(job.ID)
-	})
-	s.timers[job.ID] = timer
+	// Manual jobs have no schedule to defer: plan this single run
+	if !s.shouldSchedule(job.TriggerMode) {
+		s.core.Schedule(scheduler.Job{ID: job.ID, TriggerMode: scheduler.ModeManual})
+	}
+	s.core.Defer(job.ID, at)
 
-	s.logger.Info("Job scheduled",
+	next, _ := s.core.NextRun(job.ID)
+	s.logger.Info("Job deferred",
 		zap.String("name", job.Name),
-		zap.String("mode", string(job.TriggerMode)),
-		zap.Duration("interval", interval),
-		zap.Time("next_run", nextRun),
+		zap.Time("next_run", next),
 	)
 }
 
 // RescheduleJob reschedules a job (e.g., after trigger mode change).
 func (s *Scheduler) RescheduleJob(job *SyncJob) {
-	s.mu.Lock()
-	if existing, ok := s.timers[job.ID]; ok {
-		existing.Stop()
-		delete(s.timers, job.ID)
-	}
-	s.mu.Unlock()
+	s.core.Unschedule(job.ID)
 
 	if job.Enabled && s.shouldSchedule(job.TriggerMode) {
 		s.ScheduleJob(job)
+	} else {
+		s.UpdateJobNextRun(job.ID, time.Time{})
 	}
 }
 
 // UnscheduleJob removes a job from the scheduler.
 func (s *Scheduler) UnscheduleJob(jobID int64) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if timer, ok := s.timers[jobID]; ok {
-		timer.Stop()
-		delete(s.timers, jobID)
+	if _, ok := s.core.NextRun(jobID); ok {
 		s.logger.Info("Job unscheduled", zap.Int64("job_id", jobID))
 	}
+	s.core.Unschedule(jobID)
+}
+
+// NetworkConnected starts the jobs synced when the PC connects to a network.
+func (s *Scheduler) NetworkConnected() {
+	s.core.NetworkConnected()
+}
+
+// UserIdle starts the jobs synced when the user has been idle this long.
+func (s *Scheduler) UserIdle(idle time.Duration) {
+	s.core.UserIdle(idle)
 }
 
 // TriggerNow triggers a sync immediately for a job.
@@ -166,33 +202,7 @@ func (s *Scheduler) TriggerNow(jobID int64) {
 	go s.executeSync(jobID)
 }
 
-// onJobTimer is called when a job's timer fires.
-func (s *Scheduler) onJobTimer(jobID int64) {
-	// Check if scheduler is still running
-	s.mu.RLock()
-	if !s.running {
-		s.mu.RUnlock()
-		return
-	}
-	s.mu.RUnlock()
-
-	// Execute sync
-	s.executeSync(jobID)
-
-	// Reschedule the job
-	s.mu.RLock()
-	jobs := s.app.GetSyncJobs()
-	s.mu.RUnlock()
-
-	for _, job := range jobs {
-		if job.ID == jobID && job.Enabled && s.shouldSchedule(job.TriggerMode) {
-			s.ScheduleJob(job)
-			break
-		}
-	}
-}
-
-// executeSync performs the actual sync for a job.
+// executeSync performs the actual sync for a job when its trigger fires.
 func (s *Scheduler) executeSync(jobID int64) {
 	// Check context
 	select {
@@ -221,31 +231,19 @@ func (s *Scheduler) executeSync(jobID int64) {
 		return
 	}
 
+	// Deferred jobs are planned again at the end of the window (or presentation)
+	if s.app.deferAutomaticSync(job) {
+		return
+	}
+
 	// Delegate to app's sync manager
 	s.logger.Info("Executing scheduled sync", zap.String("name", job.Name))
 	s.app.ExecuteJobSync(jobID)
 }
 
-// getInterval returns the sync interval for a trigger mode.
-func (s *Scheduler) getInterval(mode SyncTriggerMode) time.Duration {
-	switch mode {
-	case SyncTrigger5Min:
-		return 5 * time.Minute
-	case SyncTrigger15Min:
-		return 15 * time.Minute
-	case SyncTrigger30Min:
-		return 30 * time.Minute
-	case SyncTrigger1Hour:
-		return 1 * time.Hour
-	case SyncTriggerRealtime:
-		return 5 * time.Minute // Remote check interval for realtime mode
-	default:
-		return 0
-	}
-}
-
-// updateJobNextSync updates the job's NextSync field.
-func (s *Scheduler) updateJobNextSync(jobID int64, nextRun time.Time) {
+// UpdateJobNextRun implements scheduler.Store: it keeps the job's NextSync
+// field and its next_run column up to date.
+func (s *Scheduler) UpdateJobNextRun(jobID int64, nextRun time.Time) error {
 	jobs := s.app.GetSyncJobs()
 	for _, job := range jobs {
 		if job.ID == jobID {
@@ -253,20 +251,16 @@ func (s *Scheduler) updateJobNextSync(jobID int64, nextRun time.Time) {
 			break
 		}
 	}
+
+	if s.app.db == nil {
+		return nil
+	}
+	return s.app.db.UpdateJobNextRun(jobID, nextRun)
 }
 
 // GetNextRun returns the next scheduled run time for a job.
 func (s *Scheduler) GetNextRun(jobID int64) (time.Time, bool) {
-	jobs := s.app.GetSyncJobs()
-	for _, job := range jobs {
-		if job.ID == jobID {
-			if job.TriggerMode == SyncTriggerManual || job.NextSync.IsZero() {
-				return time.Time{}, false
-			}
-			return job.NextSync, true
-		}
-	}
-	return time.Time{}, false
+	return s.core.NextRun(jobID)
 }
 
 // IsRunning returns whether the scheduler is active.
@@ -278,7 +272,5 @@ func (s *Scheduler) IsRunning() bool {
 
 // ScheduledJobCount returns the number of currently scheduled jobs.
 func (s *Scheduler) ScheduledJobCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.timers)
+	return s.core.Len()
 }
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// --- Negotiated Security ---
+
+// installServerSecurity checks the signing or encryption negotiated by every
+// SMB connection of the process (see syncpkg.ServerSecurity), keeping the
+// levels shown by the server settings up to date and notifying downgrades.
+func (a *App) installServerSecurity() {
+	a.serverSecurity = syncpkg.NewServerSecurity(a.db, a.logger)
+	a.serverSecurity.OnRecorded = func(serverID int64, level smb.SecurityLevel) {
+		a.mu.Lock()
+		for _, c := range a.smbConnections {
+			if c.ID == serverID {
+				c.SecurityLevel = level
+			}
+		}
+		a.mu.Unlock()
+	}
+	a.serverSecurity.OnDowngrade = func(server string, level, recorded smb.SecurityLevel) {
+		if a.notifier != nil {
+			a.notifier.SecurityDowngraded(server, level.Label(), recorded.Label())
+		}
+	}
+	smb.SetSecurityCheck(a.serverSecurity.Check)
+}
+
+// serverConnection returns the connection of a server host (nil if not
+// configured). The caller holds a.mu.
+func (a *App) serverConnection(host string) *SMBConnection {
+	for _, c := range a.smbConnections {
+		if strings.EqualFold(c.Host, host) {
+			return c
+		}
+	}
+	return nil
+}
+
+// ForgetServerSecurity forgets the security recorded for a server, after a
+// deliberate change on the server: the next connection records it again.
+func (a *App) ForgetServerSecurity(connID int64) error {
+	if a.db != nil {
+		if err := a.db.SetSMBServerSecurityLevel(connID, ""); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	for _, c := range a.smbConnections {
+		if c.ID == connID {
+			c.SecurityLevel = ""
+		}
+	}
+	a.mu.Unlock()
+	if a.serverSecurity != nil {
+		a.serverSecurity.Forget(connID)
+	}
+
+	a.logger.Info("Forgot server security", zap.Int64("id", connID))
+	return nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -17,6 +18,7 @@ type SettingsWindow struct {
 	window   fyne.Window
 	jobsList *JobsList
 	smbList  *SMBList
+	health   *HealthBanner
 
 	// Dynamic buttons
 	syncNowBtn *widget.Button
@@ -52,7 +54,9 @@ func (sw *SettingsWindow) Show() {
 	)
 	tabs.SetTabLocation(container.TabLocationLeading)
 
-	sw.window.SetContent(tabs)
+	// Health banner above the tabs (hidden while everything is fine)
+	sw.health = NewHealthBanner(sw)
+	sw.window.SetContent(container.NewBorder(sw.health.Container(), nil, nil, nil, tabs))
 
 	// Handle window close
 	sw.window.SetOnClosed(func() {
@@ -60,6 +64,7 @@ func (sw *SettingsWindow) Show() {
 	})
 
 	sw.window.Show()
+	go sw.RefreshHealth()
 }
 
 // createSMBTab creates the SMB servers management tab.
@@ -72,6 +77,14 @@ func (sw *SettingsWindow) createSMBTab() fyne.CanvasObject {
 		sw.showSMBForm(nil)
 	})
 
+	// Jobs from robocopy scripts, Syncthing or OneDrive folders
+	importBtn := widget.NewButtonWithIcon("Import", theme.DownloadIcon(), func() {
+		sw.app.ShowImportJobsDialog(func() {
+			sw.jobsList.Refresh()
+			sw.RefreshHealth()
+		})
+	})
+
 	editBtn := widget.NewButtonWithIcon("Edit", theme.DocumentCreateIcon(), func() {
 		conn := sw.smbList.GetSelected()
 		if conn != nil {
@@ -88,6 +101,7 @@ func (sw *SettingsWindow) createSMBTab() fyne.CanvasObject {
 
 	toolbar := container.NewHBox(
 		addBtn,
+		importBtn,
 		editBtn,
 		deleteBtn,
 	)
@@ -112,10 +126,28 @@ func (sw *SettingsWindow) createSMBTab() fyne.CanvasObject {
 func (sw *SettingsWindow) showSMBForm(conn *SMBConnection) {
 	form := NewSMBForm(sw.app, conn, func(saved *SMBConnection) {
 		sw.smbList.Refresh()
+		sw.RefreshHealth()
 	})
 	form.Show(sw.window)
 }
 
+// confirmApproveChanges asks the user to approve the changes of a job paused
+// by its change cap before syncing it.
+func (sw *SettingsWindow) confirmApproveChanges(job *SyncJob) {
+	dialog.ShowConfirm(
+		"Approve Changes",
+		fmt.Sprintf("Sync of '%s' was stopped before changing anything:\n%s\n\n"+
+			"This can be a large reorganization, or files encrypted by ransomware.\n"+
+			"Check the local folder first. Sync anyway?", job.Name, job.PendingApproval),
+		func(confirmed bool) {
+			if confirmed {
+				sw.app.ApproveJobChanges(job.ID)
+			}
+		},
+		sw.window,
+	)
+}
+
 // confirmDeleteSMBConnection shows a confirmation dialog before deleting an SMB connection.
 func (sw *SettingsWindow) confirmDeleteSMBConnection(conn *SMBConnection) {
 	dialog.ShowConfirm(
@@ -160,7 +192,9 @@ func (sw *SettingsWindow) createJobsTab() fyne.CanvasObject {
 
 	sw.syncNowBtn = widget.NewButtonWithIcon("Sync Now", theme.ViewRefreshIcon(), func() {
 		job := sw.jobsList.GetSelected()
-		if job != nil {
+		if job != nil && job.PendingApproval != "" {
+			sw.confirmApproveChanges(job)
+		} else if job != nil {
 			sw.app.TriggerSyncJob(job.ID)
 		}
 	})
@@ -184,6 +218,43 @@ func (sw *SettingsWindow) createJobsTab() fyne.CanvasObject {
 		}
 	})
 
+	// Explains the last failure of the selected job
+	errorBtn := widget.NewButtonWithIcon("Last Error", theme.ErrorIcon(), func() {
+		job := sw.jobsList.GetSelected()
+		if job == nil {
+			return
+		}
+		if job.LastError == "" {
+			dialog.ShowInformation("Last Error", "The last sync of this job did not fail.", sw.window)
+			return
+		}
+		showErrorDetails(job.LastError, job.LastErrorDetails, sw.window)
+	})
+
+	// Older copies of a file from the server snapshots
+	versionsBtn := widget.NewButtonWithIcon("Previous Versions", theme.HistoryIcon(), func() {
+		job := sw.jobsList.GetSelected()
+		if job != nil {
+			sw.app.ShowVersionsDialog(job)
+		}
+	})
+
+	// State of a file, and a preview of files only on the server
+	inspectBtn := widget.NewButtonWithIcon("Inspect", theme.SearchIcon(), func() {
+		job := sw.jobsList.GetSelected()
+		if job != nil {
+			sw.app.ShowInspectDialog(job)
+		}
+	})
+
+	// What takes space on the server
+	usageBtn := widget.NewButtonWithIcon("Server Space", theme.StorageIcon(), func() {
+		job := sw.jobsList.GetSelected()
+		if job != nil {
+			sw.app.ShowUsageDialog(job)
+		}
+	})
+
 	// Update button states based on current sync status
 	sw.updateSyncButtons()
 
@@ -195,6 +266,10 @@ func (sw *SettingsWindow) createJobsTab() fyne.CanvasObject {
 		sw.syncNowBtn,
 		sw.stopBtn,
 		widget.NewSeparator(),
+		errorBtn,
+		versionsBtn,
+		inspectBtn,
+		usageBtn,
 		fixCloudBtn,
 	)
 
@@ -224,6 +299,16 @@ func (sw *SettingsWindow) createGeneralTab() fyne.CanvasObject {
 	})
 	notifyCheck.SetChecked(sw.app.GetNotificationsEnabled())
 
+	// Presentation mode, screen sharing and full-screen applications
+	holdNotifyCheck := widget.NewCheck("Hold notifications while presenting or in full screen", func(checked bool) {
+		sw.app.SetHoldNotificationsWhenPresenting(checked)
+	})
+	holdNotifyCheck.SetChecked(sw.app.GetHoldNotificationsWhenPresenting())
+	pauseSyncCheck := widget.NewCheck("Don't start automatic syncs while presenting or in full screen", func(checked bool) {
+		sw.app.SetPauseSyncWhenPresenting(checked)
+	})
+	pauseSyncCheck.SetChecked(sw.app.GetPauseSyncWhenPresenting())
+
 	// Log level
 	logLevelLabel := widget.NewLabel("Log Level:")
 	currentLogLevel := sw.app.GetLogLevel()
@@ -244,6 +329,48 @@ func (sw *SettingsWindow) createGeneralTab() fyne.CanvasObject {
 	})
 	intervalSelect.SetSelected(currentInterval)
 
+	// Webhooks: one URL per line (Slack, Teams or any JSON receiver)
+	webhookEntry := widget.NewMultiLineEntry()
+	webhookEntry.SetPlaceHolder("https://hooks.slack.com/services/...")
+	webhookEntry.SetMinRowsVisible(3)
+	webhookEntry.SetText(strings.Join(sw.app.GetWebhookURLs(), "\n"))
+
+	saveWebhooksBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		urls, err := ParseWebhookURLs(webhookEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, sw.window)
+			return
+		}
+		sw.app.SetWebhookURLs(urls)
+	})
+
+	testWebhooksBtn := widget.NewButtonWithIcon("Send Test", theme.MailSendIcon(), func() {
+		urls, err := ParseWebhookURLs(webhookEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, sw.window)
+			return
+		}
+		if len(urls) == 0 {
+			dialog.ShowInformation("Webhooks", "Enter a webhook URL first.", sw.window)
+			return
+		}
+		go func() {
+			var failures []string
+			for _, url := range urls {
+				if err := sw.app.TestWebhook(url); err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %v", redactWebhookURL(url), err))
+				}
+			}
+			fyne.Do(func() {
+				if len(failures) > 0 {
+					dialog.ShowError(fmt.Errorf("test notification failed:\n%s", strings.Join(failures, "\n")), sw.window)
+				} else {
+					dialog.ShowInformation("Webhooks", "Test notification sent.", sw.window)
+				}
+			})
+		}()
+	})
+
 	// Export/Import configuration
 	jsonFilter := storage.NewExtensionFileFilter([]string{".json"})
 
@@ -328,12 +455,17 @@ func (sw *SettingsWindow) createGeneralTab() fyne.CanvasObject {
 		widget.NewSeparator(),
 		widget.NewLabel("Notifications"),
 		notifyCheck,
+		holdNotifyCheck,
+		widget.NewLabel("Webhooks (JSON summary of every sync, one URL per line):"),
+		webhookEntry,
+		container.NewHBox(saveWebhooksBtn, testWebhooksBtn),
 		widget.NewSeparator(),
 		widget.NewLabel("Logging"),
 		container.NewHBox(logLevelLabel, logLevelSelect),
 		widget.NewSeparator(),
 		widget.NewLabel("Synchronization"),
 		container.NewHBox(intervalLabel, intervalSelect),
+		pauseSyncCheck,
 		widget.NewSeparator(),
 		widget.NewLabel("Backup / Restore"),
 		container.NewHBox(exportBtn, importBtn),
@@ -373,6 +505,7 @@ func (sw *SettingsWindow) createAboutTab() fyne.CanvasObject {
 func (sw *SettingsWindow) showJobForm(job *SyncJob) {
 	form := NewJobForm(sw.app, job, func(saved *SyncJob) {
 		sw.jobsList.Refresh()
+		sw.RefreshHealth()
 	})
 	form.Show(sw.window)
 }
@@ -409,6 +542,14 @@ func (sw *SettingsWindow) RefreshJobList() {
 		sw.jobsList.Refresh()
 	}
 	sw.updateSyncButtons()
+	sw.RefreshHealth()
+}
+
+// RefreshHealth runs the health checks again and updates the banner.
+func (sw *SettingsWindow) RefreshHealth() {
+	if sw.health != nil && sw.window != nil {
+		sw.health.Refresh()
+	}
 }
 
 // updateSyncButtons updates the sync/stop button states based on sync status.
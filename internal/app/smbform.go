@@ -1,12 +1,15 @@
 package app
 
 import (
+	"fmt"
 	"strconv"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 )
 
 // SMBForm is a form for creating/editing SMB connections.
@@ -23,6 +26,9 @@ type SMBForm struct {
 	usernameEntry *widget.Entry
 	passwordEntry *widget.Entry
 	domainEntry   *widget.Entry
+	mtimeSelect   *widget.Select
+	requireCheck  *widget.Check
+	encryptCheck  *widget.Check
 }
 
 // NewSMBForm creates a new SMB connection form.
@@ -53,6 +59,15 @@ func NewSMBForm(app *App, conn *SMBConnection, onSave func(*SMBConnection)) *SMB
 	f.domainEntry = widget.NewEntry()
 	f.domainEntry.SetPlaceHolder("WORKGROUP (optional)")
 
+	f.mtimeSelect = widget.NewSelect([]string{
+		"Last write time (default)",
+		"Change time",
+	}, nil)
+	f.mtimeSelect.SetSelectedIndex(0)
+
+	f.requireCheck = widget.NewCheck("Refuse less secure connections", nil)
+	f.encryptCheck = widget.NewCheck("Require SMB3 encryption", nil)
+
 	// Pre-fill if editing
 	if conn != nil {
 		f.nameEntry.SetText(conn.Name)
@@ -62,6 +77,11 @@ func NewSMBForm(app *App, conn *SMBConnection, onSave func(*SMBConnection)) *SMB
 		}
 		f.usernameEntry.SetText(conn.Username)
 		f.domainEntry.SetText(conn.Domain)
+		if conn.MTimeSource == smb.MTimeSourceChange {
+			f.mtimeSelect.SetSelectedIndex(1)
+		}
+		f.requireCheck.SetChecked(conn.RequireSecurity)
+		f.encryptCheck.SetChecked(conn.RequireEncryption)
 		// Password is not pre-filled for security
 	}
 
@@ -83,11 +103,19 @@ func (f *SMBForm) Show(parent fyne.Window) {
 			{Text: "Username", Widget: f.usernameEntry, HintText: "Authentication username"},
 			{Text: "Password", Widget: f.passwordEntry, HintText: "Password (stored securely)"},
 			{Text: "Domain", Widget: f.domainEntry, HintText: "Domain or workgroup (optional)"},
+			{Text: "Timestamp", Widget: f.mtimeSelect, HintText: "Remote time used to detect changes (use Change time if edits are missed)"},
+			{Text: "Security", Widget: f.requireCheck, HintText: f.securityHint()},
+			{Text: "Encryption", Widget: f.encryptCheck, HintText: "Fail rather than send files unencrypted"},
 		},
 		OnSubmit: func() {
 			f.save(parent)
 		},
 	}
+	if f.connection != nil {
+		integrity := widget.NewLabel(f.integritySummary())
+		integrity.Wrapping = fyne.TextWrapWord
+		form.Append("Integrity", integrity)
+	}
 
 	// Add test connection button
 	testBtn := widget.NewButton("Test Connection", func() {
@@ -99,10 +127,23 @@ func (f *SMBForm) Show(parent fyne.Window) {
 	infoLabel.Wrapping = fyne.TextWrapWord
 	infoLabel.TextStyle = fyne.TextStyle{Italic: true}
 
+	buttons := container.NewHBox(testBtn)
+	if f.connection != nil && f.connection.SecurityLevel != "" {
+		// After a deliberate change on the server, record its security again
+		buttons.Add(widget.NewButton("Forget Security Level", func() {
+			if err := f.app.ForgetServerSecurity(f.connection.ID); err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			dialog.ShowInformation("Security Level",
+				"The security of the next connection will be recorded as the new reference.", parent)
+		}))
+	}
+
 	content := container.NewVBox(
 		form,
 		widget.NewSeparator(),
-		container.NewHBox(testBtn),
+		buttons,
 		widget.NewSeparator(),
 		infoLabel,
 	)
@@ -139,6 +180,16 @@ func (f *SMBForm) save(parent fyne.Window) {
 		port = p
 	}
 
+	// The host may carry its own port ("nas:1445", "[fe80::1]:445")
+	host, hostPort, err := smbpath.ParseHostPort(f.hostEntry.Text)
+	if err != nil {
+		dialog.ShowError(err, parent)
+		return
+	}
+	if hostPort != 0 {
+		port = hostPort
+	}
+
 	// Create or update connection
 	conn := f.connection
 	if conn == nil {
@@ -147,12 +198,18 @@ func (f *SMBForm) save(parent fyne.Window) {
 
 	conn.Name = f.nameEntry.Text
 	if conn.Name == "" {
-		conn.Name = f.hostEntry.Text
+		conn.Name = host
 	}
-	conn.Host = f.hostEntry.Text
+	conn.Host = host
 	conn.Port = port
 	conn.Username = f.usernameEntry.Text
 	conn.Domain = f.domainEntry.Text
+	conn.MTimeSource = smb.MTimeSourceWrite
+	if f.mtimeSelect.SelectedIndex() == 1 {
+		conn.MTimeSource = smb.MTimeSourceChange
+	}
+	conn.RequireSecurity = f.requireCheck.Checked
+	conn.RequireEncryption = f.encryptCheck.Checked
 
 	// Save credentials to keyring (password only in keyring)
 	password := f.passwordEntry.Text
@@ -164,7 +221,6 @@ func (f *SMBForm) save(parent fyne.Window) {
 	}
 
 	// Save to database
-	var err error
 	if f.connection == nil {
 		err = f.app.AddSMBConnection(conn)
 	} else {
@@ -188,6 +244,35 @@ func (f *SMBForm) save(parent fyne.Window) {
 	dialog.ShowInformation("Success", "SMB server saved successfully", parent)
 }
 
+// securityHint describes the security recorded for the server.
+func (f *SMBForm) securityHint() string {
+	if f.connection == nil || f.connection.SecurityLevel == "" {
+		return "Refuse connections with less signing/encryption than the first one"
+	}
+	return "Connections so far: " + f.connection.SecurityLevel.Label() +
+		". Refuse connections with less (possible interception)"
+}
+
+// integritySummary describes the transfers read back with the server, for
+// jobs verifying their transfers.
+func (f *SMBForm) integritySummary() string {
+	recent, total, err := f.app.TransferIntegrity(f.connection.ID)
+	if err != nil {
+		return "Unavailable: " + err.Error()
+	}
+	if total.Verified+total.Mismatches == 0 {
+		return "No verified transfer yet (see \"Verify transferred files\" in the job settings)"
+	}
+
+	summary := fmt.Sprintf("Last %d days: %d verified, %d corrupted (%.2f%%). All time: %d verified, %d corrupted.",
+		int(integrityWindow.Hours()/24), recent.Verified, recent.Mismatches, recent.Rate()*100,
+		total.Verified, total.Mismatches)
+	if corruptionSuspected(recent) {
+		summary += " Check the network cable, network card, and the memory and disks of the server."
+	}
+	return summary
+}
+
 // testConnection tests the SMB connection.
 func (f *SMBForm) testConnection(parent fyne.Window) {
 	if f.hostEntry.Text == "" || f.usernameEntry.Text == "" {
@@ -216,26 +301,63 @@ func (f *SMBForm) testConnection(parent fyne.Window) {
 			port = p
 		}
 	}
+	host, hostPort, err := smbpath.ParseHostPort(f.hostEntry.Text)
+	if err != nil {
+		dialog.ShowError(err, parent)
+		return
+	}
+	if hostPort != 0 {
+		port = hostPort
+	}
 
 	// Show progress
 	progress := dialog.NewProgressInfinite("Testing Connection", "Connecting to SMB server...", parent)
 	progress.Show()
 
 	go func() {
-		err := f.app.TestSMBConnection(f.hostEntry.Text, f.usernameEntry.Text, password, f.domainEntry.Text, port)
+		result, err := f.app.TestSMBConnection(host, f.usernameEntry.Text, password, f.domainEntry.Text, port, f.encryptCheck.Checked)
 
 		fyne.Do(func() {
 			progress.Hide()
 
-			if err != nil {
-				dialog.ShowError(err, parent)
-			} else {
-				dialog.ShowInformation("Success", "Connection successful!", parent)
+			if result == nil {
+				f.app.showFriendlyError(err, host, parent)
+				return
 			}
+			f.showTestResult(result, err, host, parent)
 		})
 	}()
 }
 
+// showTestResult lists the stages of a connection test, with the user
+// message of the stage that failed.
+func (f *SMBForm) showTestResult(result *smb.ProbeResult, err error, host string, parent fyne.Window) {
+	stages := container.NewVBox()
+	for _, stage := range result.Stages {
+		icon, detail := "✓", stage.Detail
+		if stage.Err != nil {
+			icon, detail = "✗", stage.Err.Error()
+		}
+		label := widget.NewLabel(icon + " " + stage.Name + ": " + detail)
+		label.Wrapping = fyne.TextWrapWord
+		stages.Add(label)
+	}
+
+	title := "Connection Successful"
+	content := container.NewVBox(stages)
+	if err != nil {
+		title = "Connection Failed"
+		message := widget.NewLabel(f.app.friendlyError(err, host))
+		message.Wrapping = fyne.TextWrapWord
+		content.Add(widget.NewSeparator())
+		content.Add(message)
+	}
+
+	d := dialog.NewCustom(title, "OK", content, parent)
+	d.Resize(fyne.NewSize(480, 0))
+	d.Show()
+}
+
 // Error helpers
 func errFieldRequired(field string) error {
 	return &formError{msg: field + " is required"}
@@ -3,6 +3,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
 )
@@ -18,6 +20,7 @@ import (
 type SyncManager struct {
 	app    *App
 	engine *syncpkg.Engine
+	db     *database.DB
 	logger *zap.Logger
 
 	mu         sync.RWMutex
@@ -26,16 +29,31 @@ type SyncManager struct {
 	cancel     context.CancelFunc
 
 	// Cloud Files (Files On Demand) providers per job
-	providersMu sync.RWMutex
-	providers   map[int64]*cloudfiles.CloudFilesProvider
+	providersMu        sync.RWMutex
+	providers          map[int64]*cloudfiles.CloudFilesProvider
+	placeholderOptions cloudfiles.PlaceholderCreationOptions
+	readAheadDepth     int                      // Chunks prefetched during hydration (0 = default, negative = disabled)
+	previewRules       []cloudfiles.PreviewRule // Start of files kept on disk in placeholders
+	remoteOwners       bool                     // Owners of remote files are recorded (sync.shared_share)
+
+	// Consecutive authentication failures by server (lowercase host), for the event log
+	eventsMu     sync.Mutex
+	authFailures map[string]int
 }
 
-// NewSyncManager creates a new sync manager.
-func NewSyncManager(app *App, db *database.DB, logger *zap.Logger) (*SyncManager, error) {
+// NewSyncManager creates a new sync manager. The engine runs with the settings
+// of config.yaml (cfg), like the CLI.
+func NewSyncManager(app *App, db *database.DB, cfg *config.Config, logger *zap.Logger) (*SyncManager, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create config for engine
-	cfg := createDefaultConfig()
+	placeholderOptions := cloudfiles.DefaultPlaceholderCreationOptions()
+	placeholderOptions.BatchSize = cfg.Sync.Performance.PlaceholderBatchSize
+	placeholderOptions.MaxPerSecond = cfg.Sync.Performance.PlaceholderRateLimit
+	readAheadDepth := cfg.Sync.Performance.HydrationReadAhead
+	if readAheadDepth == 0 {
+		readAheadDepth = -1 // 0 disables read-ahead in config.yaml
+	}
+	previews := previewRules(cfg.Sync.FileTypes.Previews, logger)
 
 	// Create sync engine
 	engine, err := syncpkg.NewEngine(cfg, db, logger.Named("engine"))
@@ -47,37 +65,39 @@ func NewSyncManager(app *App, db *database.DB, logger *zap.Logger) (*SyncManager
 	return &SyncManager{
 		app:       app,
 		engine:    engine,
+		db:        db,
 		logger:    logger,
 		running:   make(map[int64]context.CancelFunc),
 		providers: make(map[int64]*cloudfiles.CloudFilesProvider),
 		ctx:       ctx,
 		cancel:    cancel,
+
+		placeholderOptions: placeholderOptions,
+		readAheadDepth:     readAheadDepth,
+		previewRules:       previews,
+		remoteOwners:       cfg.Sync.SharedShare,
 	}, nil
 }
 
-// createDefaultConfig creates a default config for the sync engine.
-func createDefaultConfig() *config.Config {
-	return &config.Config{
-		Sync: config.SyncConfig{
-			DefaultMode:               "mirror",
-			DefaultConflictResolution: "recent",
-			Performance: config.PerformanceConfig{
-				ParallelTransfers: 4,
-				BufferSizeMB:      8,
-				HashAlgorithm:     "sha256",
-			},
-		},
-		Logging: config.LoggingConfig{
-			Levels: config.LogLevelsConfig{
-				Console: "info",
-				File:    "debug",
-			},
-		},
+// remoteMTimeSource returns the remote timestamp source configured on the job's server.
+func (m *SyncManager) remoteMTimeSource(job *SyncJob) smb.MTimeSource {
+	if conn := m.app.GetSMBConnection(job.SMBConnectionID); conn != nil {
+		return conn.MTimeSource
 	}
+	return smb.MTimeSourceWrite
 }
 
 // ExecuteSync runs a sync for the given job.
 func (m *SyncManager) ExecuteSync(job *SyncJob) error {
+	return m.ExecuteScopedSync(job, "")
+}
+
+// ExecuteScopedSync runs a sync restricted to a folder of the job ("" = whole job).
+func (m *SyncManager) ExecuteScopedSync(job *SyncJob, subtree string) error {
+	if err := m.checkVolume(job); err != nil {
+		return err
+	}
+
 	// Check if already running
 	m.mu.Lock()
 	if _, running := m.running[job.ID]; running {
@@ -117,6 +137,7 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 		zap.String("name", job.Name),
 		zap.String("local", job.LocalPath),
 		zap.String("remote", job.FullRemotePath()),
+		zap.String("subtree", subtree),
 	)
 
 	// Create sync request
@@ -126,9 +147,24 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 		RemotePath:         job.FullRemotePath(), // Full UNC path: \\host\share\path
 		Mode:               job.Mode,
 		ConflictResolution: job.ConflictResolution,
-		DryRun:             false,
+		DryRun:             m.app.SafeMode(), // Recovered database not confirmed yet
 		ProgressCallback:   m.createProgressCallback(job),
 		FilesOnDemand:      job.FilesOnDemand,
+		RemoteMTimeSource:  m.remoteMTimeSource(job),
+		SyncAttributes:     job.SyncAttributes,
+		VerifyTransfers:    job.VerifyTransfers,
+		SyncACLs:           job.SyncACLs,
+		SyncStreams:        job.SyncStreams,
+		ReadLockedFiles:    job.ReadLockedFiles,
+		JobLog:             job.JobLog,
+		ExclusionGroups:    job.ExclusionGroups,
+		Subtree:            subtree,
+		MaxChangedFiles:    job.MaxChangedFiles,
+		MaxChangedBytes:    job.MaxChangedBytes,
+		ApproveChanges:     job.ChangesApproved,
+		MaxUploadKBps:      job.MaxUploadKBps,
+		MaxDownloadKBps:    job.MaxDownloadKBps,
+		ThrottleMetered:    job.ThrottleMetered,
 	}
 
 	// Set up Files On Demand if enabled
@@ -147,6 +183,11 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 		}
 	}
 
+	// Stop the run when a maintenance window opens, if the job asks to, or
+	// when the user is back for runs started while idle
+	maintenanceDone := m.pauseAtMaintenance(job, cancel)
+	idleDone := m.pauseOnUserReturn(job, cancel)
+
 	// Execute sync
 	startTime := time.Now()
 	result, err := m.engine.Sync(syncCtx, req)
@@ -155,6 +196,34 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 	// Update app state
 	m.app.SetSyncing(false)
 
+	userReturned := idleDone()
+	if until, paused := maintenanceDone(); paused {
+		m.logger.Info("Sync paused for maintenance window",
+			zap.String("name", job.Name),
+			zap.Duration("duration", duration),
+			zap.Time("until", until),
+		)
+		m.updateJobStatus(job, JobStatusMaintenance)
+		m.app.SetStatus("Paused for maintenance: " + job.Name)
+		if m.app.scheduler != nil {
+			m.app.scheduler.DeferJob(job, until)
+		}
+		return nil
+	}
+	if userReturned {
+		// Runs again the next time the user is idle
+		m.logger.Info("Sync paused, user is back",
+			zap.String("name", job.Name),
+			zap.Duration("duration", duration),
+		)
+		m.updateJobStatus(job, JobStatusUserBack)
+		m.app.SetStatus("Paused while you work: " + job.Name)
+		return nil
+	}
+	m.app.offlineQueue.SyncFinished(job, subtree, err)
+	if m.holdForApproval(job, err) {
+		return err
+	}
 	if err != nil {
 		m.logger.Error("Sync failed",
 			zap.String("name", job.Name),
@@ -162,11 +231,15 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 			zap.Duration("duration", duration),
 		)
 
+		m.setJobError(job, err)
 		m.updateJobStatus(job, JobStatusFailed)
 		m.app.SetStatus("Sync failed: " + job.Name)
 
 		if m.app.notifier != nil {
-			m.app.notifier.SyncFailed(job.Name, err)
+			m.app.notifier.SyncFailed(job.Name, job.LastError)
+		}
+		if m.app.webhooks != nil {
+			m.app.webhooks.SyncFinished(job, nil, err)
 		}
 
 		return err
@@ -183,8 +256,12 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 		finalStatus = JobStatusFailed
 	}
 
+	m.setJobError(job, nil)
 	m.updateJobStatus(job, finalStatus)
 	job.LastSync = time.Now()
+	job.PendingFiles = result.FilesError + result.ConflictsFound
+	m.clearApproval(job)
+	m.app.recordTransferIntegrity(job, result)
 
 	m.logger.Info("Sync completed",
 		zap.String("name", job.Name),
@@ -192,6 +269,7 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 		zap.Int("uploaded", result.FilesUploaded),
 		zap.Int("downloaded", result.FilesDownloaded),
 		zap.Int("errors", result.FilesError),
+		zap.Int("vetoed", result.FilesVetoed),
 		zap.Duration("duration", duration),
 	)
 
@@ -209,6 +287,10 @@ func (m *SyncManager) ExecuteSync(job *SyncJob) error {
 		}
 	}
 
+	if m.app.webhooks != nil {
+		m.app.webhooks.SyncFinished(job, result, nil)
+	}
+
 	// Update status message
 	if result.FilesUploaded+result.FilesDownloaded > 0 {
 		m.app.SetStatus(fmt.Sprintf("Synced %d files", result.FilesUploaded+result.FilesDownloaded))
@@ -233,7 +315,8 @@ func (m *SyncManager) CancelSync(jobID int64) bool {
 	return false
 }
 
-// CancelAllSyncs cancels all running syncs.
+// CancelAllSyncs cancels all running syncs, including those still preparing
+// (e.g. setting up Files On Demand) before the engine starts them.
 func (m *SyncManager) CancelAllSyncs() int {
 	m.mu.Lock()
 	count := len(m.running)
@@ -243,12 +326,34 @@ func (m *SyncManager) CancelAllSyncs() int {
 	}
 	m.mu.Unlock()
 
+	if m.engine != nil {
+		count = max(count, m.engine.CancelAll())
+	}
+
 	if count > 0 {
 		m.logger.Info("All syncs cancelled", zap.Int("count", count))
 	}
 	return count
 }
 
+// ListRunningSyncs returns the syncs the engine is running, with their phase
+// and progress, oldest first.
+func (m *SyncManager) ListRunningSyncs() []syncpkg.RunningSync {
+	if m.engine == nil {
+		return nil
+	}
+	return m.engine.ListRunning()
+}
+
+// RemoteUsage scans the remote folder of a job and returns the space used
+// by each top-level folder and its largest files.
+func (m *SyncManager) RemoteUsage(ctx context.Context, job *SyncJob) (*database.RemoteUsage, error) {
+	if m.engine == nil {
+		return nil, fmt.Errorf("sync engine not available")
+	}
+	return m.engine.RemoteUsage(ctx, job.ID)
+}
+
 // GetRunningSyncJobIDs returns the IDs of all currently running sync jobs.
 func (m *SyncManager) GetRunningSyncJobIDs() []int64 {
 	m.mu.RLock()
@@ -276,6 +381,12 @@ func (m *SyncManager) IsAnySyncing() bool {
 	return len(m.running) > 0
 }
 
+// RemoteOwners returns whether the owners of remote files are recorded, for
+// shares written by several users.
+func (m *SyncManager) RemoteOwners() bool {
+	return m.remoteOwners
+}
+
 // Close shuts down the sync manager.
 func (m *SyncManager) Close() error {
 	m.logger.Info("Sync manager shutting down")
@@ -316,6 +427,7 @@ func (m *SyncManager) createProgressCallback(job *SyncJob) syncpkg.ProgressCallb
 			status = fmt.Sprintf("Detecting changes in %s...", job.Name)
 		case "executing":
 			status = formatSyncProgress(job.Name, progress)
+			job.PendingFiles = progress.FilesTotal - progress.FilesProcessed
 		case "finalizing":
 			status = fmt.Sprintf("Finalizing %s...", job.Name)
 		default:
@@ -356,6 +468,35 @@ func formatSyncProgress(jobName string, p *syncpkg.SyncProgress) string {
 }
 
 
+// checkVolume refuses every sync of a job whose drive was remounted elsewhere
+// (scheduler, watchers, offline replay, shutdown) until the new path is confirmed.
+func (m *SyncManager) checkVolume(job *SyncJob) error {
+	err := checkVolumeMoved(job)
+	if err != nil {
+		m.logger.Warn("Job paused until new drive location is confirmed",
+			zap.String("name", job.Name),
+			zap.String("new_path", job.VolumeMoved),
+		)
+	}
+	return err
+}
+
+// setJobError records the failure of the last sync of a job for the UI and
+// the event log (nil clears it).
+func (m *SyncManager) setJobError(job *SyncJob, err error) {
+	if err == nil {
+		job.LastError = ""
+		job.LastErrorDetails = ""
+		job.LastErrorCode = ""
+		m.resetAuthFailures(job)
+		return
+	}
+	job.LastError = m.app.friendlyError(err, job.RemoteHost)
+	job.LastErrorDetails = err.Error()
+	job.LastErrorCode = syncpkg.ErrorCodeOf(err)
+	m.logJobFailure(job, err)
+}
+
 // updateJobStatus updates the job's status in memory.
 func (m *SyncManager) updateJobStatus(job *SyncJob, status JobStatus) {
 	job.LastStatus = status
@@ -365,10 +506,74 @@ func (m *SyncManager) updateJobStatus(job *SyncJob, status JobStatus) {
 	}
 }
 
+// holdForApproval pauses the job when its run stopped on the change cap or
+// on suspected ransomware: automatic syncs are skipped until the user
+// approves the changes. Returns false if err is another error.
+func (m *SyncManager) holdForApproval(job *SyncJob, err error) bool {
+	var capErr *syncpkg.ChangeCapError
+	var ransomErr *syncpkg.RansomwareError
+	switch {
+	case errors.As(err, &capErr):
+		m.logger.Warn("Sync paused, changes exceed the safety cap",
+			zap.String("name", job.Name),
+			zap.Int("files", capErr.Files),
+			zap.Int64("bytes", capErr.Bytes),
+		)
+	case errors.As(err, &ransomErr):
+		m.logger.Error("Sync paused, local changes look like ransomware",
+			zap.String("name", job.Name),
+			zap.Strings("reasons", ransomErr.Reasons),
+			zap.Strings("examples", ransomErr.Examples),
+		)
+		logRansomwareSuspected(job, ransomErr)
+	default:
+		return false
+	}
+
+	job.PendingApproval = err.Error()
+	job.ChangesApproved = false
+	m.saveJobOptions(job)
+	m.updateJobStatus(job, JobStatusApproval)
+	m.app.SetStatus("Approval required: " + job.Name)
+
+	if m.app.notifier != nil {
+		if ransomErr != nil {
+			m.app.notifier.RansomwareSuspected(job.Name)
+		} else {
+			m.app.notifier.ApprovalRequired(job.Name, capErr.Files)
+		}
+	}
+	return true
+}
+
+// clearApproval resumes a paused job after an approved run.
+func (m *SyncManager) clearApproval(job *SyncJob) {
+	if job.PendingApproval == "" && !job.ChangesApproved {
+		return
+	}
+	job.PendingApproval = ""
+	job.ChangesApproved = false
+	m.saveJobOptions(job)
+}
+
+// saveJobOptions persists the job without rescheduling it.
+func (m *SyncManager) saveJobOptions(job *SyncJob) {
+	if m.db == nil {
+		return
+	}
+	if err := m.db.UpdateSyncJob(convertAppJobToDBJob(job)); err != nil {
+		m.logger.Warn("Failed to save job", zap.String("name", job.Name), zap.Error(err))
+	}
+}
+
 // ExecuteSyncAndWait runs a sync for the given job and blocks until completion.
 // Unlike ExecuteSync, this method waits for the sync to finish and returns
 // only when the sync is complete or cancelled via context.
 func (m *SyncManager) ExecuteSyncAndWait(ctx context.Context, job *SyncJob) error {
+	if err := m.checkVolume(job); err != nil {
+		return err
+	}
+
 	// Check if already running
 	m.mu.Lock()
 	if _, running := m.running[job.ID]; running {
@@ -421,9 +626,23 @@ func (m *SyncManager) ExecuteSyncAndWait(ctx context.Context, job *SyncJob) erro
 		RemotePath:         job.FullRemotePath(),
 		Mode:               job.Mode,
 		ConflictResolution: job.ConflictResolution,
-		DryRun:             false,
+		DryRun:             m.app.SafeMode(), // Recovered database not confirmed yet
 		ProgressCallback:   m.createProgressCallback(job),
 		FilesOnDemand:      job.FilesOnDemand,
+		RemoteMTimeSource:  m.remoteMTimeSource(job),
+		SyncAttributes:     job.SyncAttributes,
+		VerifyTransfers:    job.VerifyTransfers,
+		SyncACLs:           job.SyncACLs,
+		SyncStreams:        job.SyncStreams,
+		ReadLockedFiles:    job.ReadLockedFiles,
+		JobLog:             job.JobLog,
+		ExclusionGroups:    job.ExclusionGroups,
+		MaxChangedFiles:    job.MaxChangedFiles,
+		MaxChangedBytes:    job.MaxChangedBytes,
+		ApproveChanges:     job.ChangesApproved,
+		MaxUploadKBps:      job.MaxUploadKBps,
+		MaxDownloadKBps:    job.MaxDownloadKBps,
+		ThrottleMetered:    job.ThrottleMetered,
 	}
 
 	// Set up Files On Demand if enabled
@@ -450,12 +669,17 @@ func (m *SyncManager) ExecuteSyncAndWait(ctx context.Context, job *SyncJob) erro
 	// Update app state
 	m.app.SetSyncing(false)
 
+	m.app.offlineQueue.SyncFinished(job, "", err)
+	if m.holdForApproval(job, err) {
+		return err
+	}
 	if err != nil {
 		m.logger.Error("Sync failed",
 			zap.String("name", job.Name),
 			zap.Error(err),
 			zap.Duration("duration", duration),
 		)
+		m.setJobError(job, err)
 		m.updateJobStatus(job, JobStatusFailed)
 		m.app.SetStatus("Sync failed: " + job.Name)
 		return err
@@ -472,8 +696,12 @@ func (m *SyncManager) ExecuteSyncAndWait(ctx context.Context, job *SyncJob) erro
 		finalStatus = JobStatusFailed
 	}
 
+	m.setJobError(job, nil)
 	m.updateJobStatus(job, finalStatus)
 	job.LastSync = time.Now()
+	job.PendingFiles = result.FilesError + result.ConflictsFound
+	m.clearApproval(job)
+	m.app.recordTransferIntegrity(job, result)
 
 	m.logger.Info("Sync completed",
 		zap.String("name", job.Name),
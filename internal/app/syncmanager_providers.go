@@ -3,6 +3,8 @@ package app
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
@@ -10,6 +12,9 @@ import (
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 	"go.uber.org/zap"
@@ -69,6 +74,32 @@ func (m *SyncManager) getOrCreateProvider(job *SyncJob) (*cloudfiles.CloudFilesP
 	// Normalize path to Windows format (backslashes)
 	localPathWin := filepath.FromSlash(job.LocalPath)
 
+	// Sync roots can only be registered on local NTFS volumes
+	if info, err := cloudfiles.CheckVolume(localPathWin); err != nil {
+		m.logger.Warn("Failed to check volume for Files On Demand",
+			zap.String("local_path", localPathWin),
+			zap.Error(err),
+		)
+	} else if !info.Supported {
+		return nil, fmt.Errorf("Files On Demand not supported on %s (%s): %s", info.Root, info.FileSystem, info.Reason)
+	}
+
+	// Placeholders fail with ERROR_GEN_FAILURE while the Cloud Files filter is
+	// detached (e.g. by a third-party cleanup tool): offer to reattach it
+	if status, err := cloudfiles.CheckCldFlt(localPathWin); err != nil {
+		m.logger.Warn("Failed to check Cloud Files filter",
+			zap.String("local_path", localPathWin),
+			zap.Error(err),
+		)
+	} else if status.Detached {
+		m.logger.Error("Cloud Files filter detached from sync root volume",
+			zap.String("job", job.Name),
+			zap.String("volume", status.Volume),
+		)
+		m.app.offerCldFltReattach(status.Volume, localPathWin)
+		return nil, fmt.Errorf("Cloud Files filter (CldFlt) is detached from %s", status.Volume)
+	}
+
 	m.logger.Info("Creating Cloud Files provider",
 		zap.String("job", job.Name),
 		zap.String("local_path", localPathWin),
@@ -81,6 +112,11 @@ func (m *SyncManager) getOrCreateProvider(job *SyncJob) (*cloudfiles.CloudFilesP
 		ProviderName: "AnemoneSync",
 		Logger:       m.logger.Named("cloudfiles"),
 		UseCGOBridge: true, // Enable CGO bridge for proper hydration callbacks
+
+		PlaceholderOptions: m.placeholderOptions,
+		ReadAheadDepth:     m.readAheadDepth,
+		SyncRootScope:      syncRootScope(job),
+		PreviewRules:       m.previewRules,
 	}
 
 	// Create provider
@@ -100,16 +136,28 @@ func (m *SyncManager) getOrCreateProvider(job *SyncJob) (*cloudfiles.CloudFilesP
 		provider.SetDataSource(dataSource)
 	}
 
+	// Never let Windows dehydrate a file whose local changes are not uploaded yet
+	provider.SetDehydrationGuard(m.dehydrationGuard(job, provider))
+
+	// Report bulk placeholder creation in the tray, one update per batch
+	provider.SetPlaceholderProgress(func(created, total int) {
+		m.app.SetStatus(fmt.Sprintf("Creating placeholders for %s: %d/%d", job.Name, created, total))
+	})
+
 	// Initialize the provider (register sync root + connect)
 	if err := provider.Initialize(m.ctx); err != nil {
 		return nil, fmt.Errorf("failed to initialize provider: %w", err)
 	}
 
-	// Configure auto-dehydration if enabled
-	if job.AutoDehydrateDays > 0 {
+	// Storage Sense and auto-dehydration must not both free space in the folder
+	m.checkStorageSensePolicy(job)
+
+	// Configure auto-dehydration if enabled (Storage Sense takes over when opted in)
+	if job.AutoDehydrateDays > 0 && !job.StorageSense {
 		policy := cloudfiles.DehydrationPolicy{
 			Enabled:    true,
 			MaxAgeDays: job.AutoDehydrateDays,
+			FileTypes:  m.engine.FileTypes(),
 		}
 		provider.SetDehydrationPolicy(policy)
 		if err := provider.StartAutoDehydration(m.ctx); err != nil {
@@ -129,6 +177,106 @@ func (m *SyncManager) getOrCreateProvider(job *SyncJob) (*cloudfiles.CloudFilesP
 	return provider, nil
 }
 
+// checkStorageSensePolicy reports how Windows Storage Sense treats the job's
+// sync root. The policy chosen in Windows Settings is kept: it is only written
+// for a job opted in whose sync root has no policy yet (just registered), and
+// when the setting of the job changes (ApplyStorageSense). When opted in,
+// Storage Sense uses the job's auto-dehydrate threshold and AnemoneSync's own
+// policy stays off.
+func (m *SyncManager) checkStorageSensePolicy(job *SyncJob) {
+	settings, err := cloudfiles.GetStorageSenseSettings(job.LocalPath)
+	if err != nil {
+		m.logger.Warn("Failed to read Storage Sense policy",
+			zap.Int64("job_id", job.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	if job.StorageSense && !settings.Configured {
+		if err := m.ApplyStorageSense(job); err != nil {
+			m.logger.Warn("Failed to apply Storage Sense policy",
+				zap.Int64("job_id", job.ID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+
+	m.logger.Info("Storage Sense policy",
+		zap.Int64("job_id", job.ID),
+		zap.Bool("opted_in", job.StorageSense),
+		zap.String("status", settings.String()),
+	)
+	if job.StorageSense && !settings.Active() {
+		m.logger.Warn("Storage Sense does not free files of this job, check its settings in Windows",
+			zap.Int64("job_id", job.ID),
+		)
+	}
+}
+
+// ApplyStorageSense opts the job's sync root in or out of Windows Storage
+// Sense cloud content dehydration, replacing the policy chosen in Windows
+// Settings. Called when the user changes the setting of the job.
+func (m *SyncManager) ApplyStorageSense(job *SyncJob) error {
+	if err := cloudfiles.SetStorageSenseDehydration(job.LocalPath, job.StorageSense, job.AutoDehydrateDays); err != nil {
+		return err
+	}
+
+	settings, err := cloudfiles.GetStorageSenseSettings(job.LocalPath)
+	if err != nil {
+		return nil
+	}
+	m.logger.Info("Storage Sense policy applied",
+		zap.Int64("job_id", job.ID),
+		zap.Bool("opted_in", job.StorageSense),
+		zap.String("status", settings.String()),
+	)
+	if job.StorageSense && !settings.Enabled {
+		m.logger.Warn("Storage Sense is turned off in Windows, files will not be freed automatically",
+			zap.Int64("job_id", job.ID),
+		)
+	}
+	return nil
+}
+
+// dehydrationGuard vetoes the dehydration of files modified since their last
+// sync: dehydrating them (e.g. Storage Sense on low disk space) would discard
+// changes not uploaded yet. File types kept hydrated by config.yaml (e.g.
+// databases) are vetoed too.
+func (m *SyncManager) dehydrationGuard(job *SyncJob, provider *cloudfiles.CloudFilesProvider) cloudfiles.DehydrationGuard {
+	return func(relativePath string) (bool, string) {
+		if ok, reason := m.engine.FileTypes().AllowsDehydration(relativePath); !ok {
+			return false, reason
+		}
+
+		state, err := provider.GetPlaceholderState(relativePath)
+		if err != nil {
+			return false, fmt.Sprintf("cannot check local state: %v", err)
+		}
+		if !state.Exists || state.IsDirectory {
+			return true, ""
+		}
+
+		synced, err := m.db.GetFileState(job.ID, relativePath)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && synced == nil) {
+			// Never synced by the engine: created from the remote listing
+			return true, ""
+		}
+		if err != nil {
+			m.logger.Warn("Failed to read sync state, dehydration refused",
+				zap.Int64("job_id", job.ID),
+				zap.String("path", relativePath),
+				zap.Error(err),
+			)
+			return false, fmt.Sprintf("cannot check sync state: %v", err)
+		}
+		if synced.Size != state.Size || synced.MTime != state.ModTime.Unix() {
+			return false, "local changes not uploaded yet"
+		}
+		return true, ""
+	}
+}
+
 // populatePlaceholdersAsync populates placeholders in the background.
 // This is called after provider initialization to make the folder browsable.
 // It tries the manifest first (instant), then falls back to full SMB scan.
@@ -144,7 +292,8 @@ func (m *SyncManager) populatePlaceholdersAsync(provider *cloudfiles.CloudFilesP
 		m.logger.Info("Manifest not available, falling back to SMB scan",
 			zap.String("reason", err.Error()),
 		)
-		// Fallback to full SMB scan
+		// Fallback to an SMB scan, which only lists the folders changed since
+		// the last scan (remote listing cache)
 		remoteFiles, err = m.populateFromSMBScan(job)
 		if err != nil {
 			m.logger.Error("Failed to list remote files for placeholder population",
@@ -154,21 +303,90 @@ func (m *SyncManager) populatePlaceholdersAsync(provider *cloudfiles.CloudFilesP
 		}
 	}
 
-	m.logger.Info("Creating placeholders from remote file list",
-		zap.Int("file_count", len(remoteFiles)),
-	)
+	// Only apply what changed since the last listing when a snapshot exists
+	previous, ok := m.loadRemoteSnapshot(job)
+	if ok {
+		diff := cloudfiles.DiffRemoteListing(previous, remoteFiles)
+		_, failed, err := provider.ApplyPlaceholderDiff(m.ctx, diff)
+		if err != nil {
+			m.logger.Error("Failed to apply placeholder changes",
+				zap.Error(err),
+			)
+			return
+		}
+		// Changes that failed stay in the next diff
+		remoteFiles = cloudfiles.RetainFailed(previous, remoteFiles, failed)
+	} else {
+		m.logger.Info("Creating placeholders from remote file list",
+			zap.Int("file_count", len(remoteFiles)),
+		)
 
-	// Create placeholders
-	if err := provider.SyncPlaceholders(m.ctx, remoteFiles); err != nil {
-		m.logger.Error("Failed to create placeholders",
-			zap.Error(err),
+		// Create placeholders
+		if err := provider.SyncPlaceholders(m.ctx, remoteFiles); err != nil {
+			m.logger.Error("Failed to create placeholders",
+				zap.Error(err),
+			)
+			return
+		}
+
+		m.logger.Info("Placeholders created successfully",
+			zap.Int("count", len(remoteFiles)),
 		)
+	}
+
+	m.saveRemoteSnapshot(job, remoteFiles)
+}
+
+// loadRemoteSnapshot returns the remote listing saved after the last placeholder sync.
+// Returns false if there is none, in which case all placeholders must be created.
+func (m *SyncManager) loadRemoteSnapshot(job *SyncJob) ([]cloudfiles.RemoteFileInfo, bool) {
+	if m.db == nil {
+		return nil, false
+	}
+
+	entries, err := m.db.GetRemoteSnapshot(job.ID)
+	if err != nil {
+		m.logger.Warn("Failed to load remote listing snapshot", zap.Error(err))
+		return nil, false
+	}
+	if len(entries) == 0 {
+		return nil, false
+	}
+
+	files := make([]cloudfiles.RemoteFileInfo, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, cloudfiles.RemoteFileInfo{
+			Path:    entry.Path,
+			Size:    entry.Size,
+			ModTime: time.Unix(entry.MTime, 0),
+			Hash:    entry.Hash,
+		})
+	}
+	return files, true
+}
+
+// saveRemoteSnapshot persists the remote listing for the next placeholder sync.
+func (m *SyncManager) saveRemoteSnapshot(job *SyncJob, remoteFiles []cloudfiles.RemoteFileInfo) {
+	if m.db == nil {
 		return
 	}
 
-	m.logger.Info("Placeholders created successfully",
-		zap.Int("count", len(remoteFiles)),
-	)
+	entries := make([]*database.RemoteSnapshotEntry, 0, len(remoteFiles))
+	for _, f := range remoteFiles {
+		if f.IsDirectory {
+			continue
+		}
+		entries = append(entries, &database.RemoteSnapshotEntry{
+			Path:  filepath.ToSlash(f.Path),
+			Size:  f.Size,
+			MTime: f.ModTime.Unix(),
+			Hash:  f.Hash,
+		})
+	}
+
+	if err := m.db.ReplaceRemoteSnapshot(job.ID, entries); err != nil {
+		m.logger.Warn("Failed to save remote listing snapshot", zap.Error(err))
+	}
 }
 
 // populateFromManifest reads the Anemone Server manifest and converts it to RemoteFileInfo.
@@ -224,25 +442,36 @@ func (m *SyncManager) populateFromManifest(job *SyncJob) ([]cloudfiles.RemoteFil
 	return remoteFiles, nil
 }
 
-// populateFromSMBScan does a full recursive SMB scan to list all remote files.
+// populateFromSMBScan lists the remote files like the remote scan of a sync,
+// reusing the folders unchanged since the last scan.
 func (m *SyncManager) populateFromSMBScan(job *SyncJob) ([]cloudfiles.RemoteFileInfo, error) {
-	dataSource, err := m.createSMBDataSource(job)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create SMB data source: %w", err)
-	}
-
-	remoteFiles, err := dataSource.ListFiles(m.ctx)
+	files, err := m.engine.ScanRemoteFiles(m.ctx, job.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list remote files: %w", err)
 	}
 
+	remoteFiles := make([]cloudfiles.RemoteFileInfo, 0, len(files))
+	for relPath, f := range files {
+		remoteFiles = append(remoteFiles, cloudfiles.RemoteFileInfo{
+			Path:    relPath,
+			Size:    f.Size,
+			ModTime: f.MTime,
+			Hash:    f.Hash,
+		})
+	}
 	return remoteFiles, nil
 }
 
 // createSMBDataSource creates a reconnectable SMB data source for hydration.
 func (m *SyncManager) createSMBDataSource(job *SyncJob) (cloudfiles.DataSource, error) {
+	return newSMBDataSource(job, m.logger)
+}
+
+// newSMBDataSource connects to the job's share and wraps the client in a
+// reconnectable data source for hydration.
+func newSMBDataSource(job *SyncJob, logger *zap.Logger) (cloudfiles.DataSource, error) {
 	// Create initial SMB client to verify connectivity
-	smbClient, err := smb.NewSMBClientFromKeyring(job.RemoteHost, job.RemoteShare, m.logger.Named("smb"))
+	smbClient, err := smb.NewSMBClientFromKeyring(job.RemoteHost, job.RemoteShare, logger.Named("smb"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SMB client: %w", err)
 	}
@@ -257,7 +486,7 @@ func (m *SyncManager) createSMBDataSource(job *SyncJob) (cloudfiles.DataSource,
 		share:      job.RemoteShare,
 		remotePath: job.RemotePath,
 		client:     smbClient,
-		logger:     m.logger.Named("smb_hydration"),
+		logger:     logger.Named("smb_hydration"),
 	}
 
 	return reconnectable, nil
@@ -367,6 +596,19 @@ func (m *SyncManager) CloseProvider(jobID int64) error {
 	return nil
 }
 
+// RepairSyncRoot closes the provider of a job and registers its sync root
+// again, e.g. after its registration was lost or its folder deleted.
+func (m *SyncManager) RepairSyncRoot(job *SyncJob) error {
+	if err := m.CloseProvider(job.ID); err != nil {
+		m.logger.Warn("Failed to close Cloud Files provider before repair",
+			zap.String("job", job.Name),
+			zap.Error(err),
+		)
+	}
+	_, err := m.getOrCreateProvider(job)
+	return err
+}
+
 // ReconnectProvider reconnects a Cloud Files provider for a job.
 // This is used at startup to reconnect providers for jobs that have FilesOnDemand enabled.
 func (m *SyncManager) ReconnectProvider(job *SyncJob) error {
@@ -411,9 +653,54 @@ func (m *SyncManager) UnregisterSyncRootByPath(localPath string) error {
 	// Normalize path to Windows format (backslashes)
 	localPathWin := filepath.FromSlash(localPath)
 	m.logger.Info("Unregistering sync root by path", zap.String("path", localPathWin))
+	if err := cloudfiles.UnshareSyncRoot(localPathWin); err != nil {
+		m.logger.Warn("Sync root still shown to other accounts",
+			zap.String("path", localPathWin),
+			zap.Error(err),
+		)
+	}
 	return cloudfiles.UnregisterSyncRoot(localPathWin)
 }
 
+// ApplySyncRootScope shares or unshares the registered sync root of a job
+// with the other accounts of the PC, after its option was changed.
+func (m *SyncManager) ApplySyncRootScope(job *SyncJob) error {
+	localPathWin := filepath.FromSlash(job.LocalPath)
+	if syncRootScope(job) == cloudfiles.SyncRootScopeMachine {
+		m.logger.Info("Sharing sync root with all accounts", zap.String("path", localPathWin))
+		return cloudfiles.ShareSyncRoot(localPathWin, "AnemoneSync - "+filepath.Base(localPathWin))
+	}
+	m.logger.Info("Unsharing sync root", zap.String("path", localPathWin))
+	return cloudfiles.UnshareSyncRoot(localPathWin)
+}
+
+// syncRootScope returns the accounts the sync root of a job is registered for.
+func syncRootScope(job *SyncJob) cloudfiles.SyncRootScope {
+	if job.SharedSyncRoot {
+		return cloudfiles.SyncRootScopeMachine
+	}
+	return cloudfiles.SyncRootScopeUser
+}
+
+// previewRules converts the placeholder previews of config.yaml, skipping
+// rules with an unknown file type.
+func previewRules(previews []config.PreviewConfig, logger *zap.Logger) []cloudfiles.PreviewRule {
+	var rules []cloudfiles.PreviewRule
+	for _, p := range previews {
+		class, ok := filetype.ParseClass(p.Type)
+		if !ok || p.KeepKB <= 0 {
+			logger.Warn("Ignoring invalid placeholder preview rule", zap.String("type", p.Type), zap.Int64("keep_kb", p.KeepKB))
+			continue
+		}
+		rules = append(rules, cloudfiles.PreviewRule{
+			Class:   class,
+			MinSize: p.MinSizeKB * 1024,
+			Bytes:   p.KeepKB * 1024,
+		})
+	}
+	return rules
+}
+
 // closeAllProviders closes all Cloud Files providers.
 func (m *SyncManager) closeAllProviders() {
 	m.providersMu.Lock()
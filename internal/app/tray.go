@@ -1,8 +1,10 @@
 package app
 
 import (
+	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/driver/desktop"
@@ -29,6 +31,7 @@ type Tray struct {
 	syncShutdownMenu    *fyne.MenuItem
 	cancelShutdownItem  *fyne.MenuItem
 	freeSpaceMenu       *fyne.MenuItem
+	jobStatsMenu        *fyne.MenuItem
 
 	// Dynamic icons for different states
 	icons     *trayIcons
@@ -58,13 +61,16 @@ func (t *Tray) Setup() {
 	statusItem := fyne.NewMenuItem("Status: Idle", nil)
 	statusItem.Disabled = true
 
+	// Jobs submenu (last run, result and pending files of each job)
+	t.jobStatsMenu = t.buildJobStatsMenu()
+
 	t.syncNowItem = fyne.NewMenuItem("Sync Now", func() {
 		t.app.Logger().Info("Sync Now clicked")
 		t.app.TriggerSync()
 	})
 
-	t.stopSyncItem = fyne.NewMenuItem("Stop Sync", func() {
-		t.app.Logger().Info("Stop Sync clicked")
+	t.stopSyncItem = fyne.NewMenuItem("Cancel All Syncs", func() {
+		t.app.Logger().Info("Cancel All Syncs clicked")
 		t.app.StopSync()
 	})
 	t.stopSyncItem.Disabled = true // Initially disabled (no sync running)
@@ -95,6 +101,7 @@ func (t *Tray) Setup() {
 	// Build menu
 	t.menu = fyne.NewMenu("AnemoneSync",
 		statusItem,
+		t.jobStatsMenu,
 		fyne.NewMenuItemSeparator(),
 		t.syncNowItem,
 		t.stopSyncItem,
@@ -126,7 +133,7 @@ func (t *Tray) Setup() {
 	t.desktopApp.SetSystemTrayMenu(t.menu)
 
 	// Set initial tooltip
-	systray.SetTooltip("AnemoneSync - Idle")
+	systray.SetTooltip(buildTrayTooltip("Idle", t.app.GetSyncJobs(), time.Now()))
 
 	t.ready = true
 	t.app.Logger().Debug("System tray ready")
@@ -144,19 +151,32 @@ func (t *Tray) UpdateStatus() {
 	status := t.app.GetStatus()
 	t.menu.Items[0].Label = "Status: " + status
 
-	// Update Sync Now / Stop Sync button states based on syncing status
+	// Update Sync Now / Cancel All Syncs button states based on syncing status
 	isSyncing := t.app.IsSyncing()
 	if t.syncNowItem != nil {
 		t.syncNowItem.Disabled = isSyncing
 	}
 	if t.stopSyncItem != nil {
 		t.stopSyncItem.Disabled = !isSyncing
+		t.stopSyncItem.Label = "Cancel All Syncs"
+		if n := len(t.app.RunningSyncs()); n > 1 {
+			t.stopSyncItem.Label = fmt.Sprintf("Cancel All Syncs (%d running)", n)
+		}
+	}
+
+	// Rebuild the Jobs submenu with the latest per-job state
+	for i, item := range t.menu.Items {
+		if item.Label == "Jobs" {
+			t.jobStatsMenu = t.buildJobStatsMenu()
+			t.menu.Items[i] = t.jobStatsMenu
+			break
+		}
 	}
 
 	t.menu.Refresh()
 
-	// Update tooltip with current status
-	systray.SetTooltip("AnemoneSync - " + status)
+	// Update tooltip with current status and per-job state
+	systray.SetTooltip(buildTrayTooltip(status, t.app.GetSyncJobs(), time.Now()))
 
 	// Update tray icon based on status
 	t.updateIconForStatus(status, isSyncing)
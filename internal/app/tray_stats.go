@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// maxTrayTooltipLen is the longest tooltip the Windows tray shows (szTip holds 128 UTF-16 chars)
+const maxTrayTooltipLen = 127
+
+// formatJobTrayLine formats the last run, result and pending files of a job
+// on one line, e.g. "Documents: Partial at 14:32, 3 pending".
+func formatJobTrayLine(job *SyncJob, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(job.Name)
+	b.WriteString(": ")
+
+	switch {
+	case !job.Enabled:
+		b.WriteString(JobStatusDisabled.String())
+	case job.LastStatus == JobStatusSyncing:
+		b.WriteString("Syncing")
+	case job.LastSync.IsZero():
+		b.WriteString("Never synced")
+	default:
+		status := job.LastStatus
+		if status == "" || status == JobStatusIdle {
+			status = JobStatusSuccess
+		}
+		b.WriteString(status.String())
+		if y, m, d := job.LastSync.Date(); now.Year() == y && now.Month() == m && now.Day() == d {
+			b.WriteString(" at " + job.LastSync.Format("15:04"))
+		} else {
+			b.WriteString(" on " + job.LastSync.Format("Jan 2"))
+		}
+	}
+
+	if job.Enabled && job.PendingFiles > 0 {
+		fmt.Fprintf(&b, ", %d pending", job.PendingFiles)
+	}
+	return b.String()
+}
+
+// buildTrayTooltip builds the tray tooltip: the app status then one line per
+// job, cut to what the tray can show.
+func buildTrayTooltip(status string, jobs []*SyncJob, now time.Time) string {
+	tooltip := "AnemoneSync - " + status
+	for i, job := range jobs {
+		line := "\n" + formatJobTrayLine(job, now)
+		if len(tooltip)+len(line) > maxTrayTooltipLen {
+			more := fmt.Sprintf("\n+%d more", len(jobs)-i)
+			if len(tooltip)+len(more) <= maxTrayTooltipLen {
+				tooltip += more
+			}
+			break
+		}
+		tooltip += line
+	}
+	return tooltip
+}
+
+// buildJobStatsMenu creates the "Jobs" submenu listing the state of each job.
+func (t *Tray) buildJobStatsMenu() *fyne.MenuItem {
+	jobs := t.app.GetSyncJobs()
+	now := time.Now()
+
+	menuItems := make([]*fyne.MenuItem, 0, len(jobs))
+	for _, job := range jobs {
+		item := fyne.NewMenuItem(formatJobTrayLine(job, now), nil)
+		item.Disabled = true
+		menuItems = append(menuItems, item)
+	}
+
+	jobsItem := fyne.NewMenuItem("Jobs", nil)
+	if len(menuItems) > 0 {
+		jobsItem.ChildMenu = fyne.NewMenu("", menuItems...)
+	} else {
+		jobsItem.Disabled = true
+	}
+	return jobsItem
+}
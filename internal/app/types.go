@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 )
 
@@ -13,9 +15,47 @@ type JobOptions struct {
 	// Files On Demand (Cloud Files API)
 	FilesOnDemand     bool `json:"files_on_demand,omitempty"`     // Enable placeholder files
 	AutoDehydrateDays int  `json:"auto_dehydrate_days,omitempty"` // Auto-dehydrate files not accessed for X days (0 = disabled)
+	StorageSense      bool `json:"storage_sense,omitempty"`       // Let Windows Storage Sense dehydrate instead of AnemoneSync
+	SharedSyncRoot    bool `json:"shared_sync_root,omitempty"`    // Show the sync root to every account of the PC
 	// Trust source for conflict resolution
 	TrustSource    string `json:"trust_source,omitempty"`    // "ask", "server", "local", "recent"
 	FirstSyncDone  bool   `json:"first_sync_done,omitempty"` // True after first sync wizard is completed
+	// Volume hosting LocalPath (to follow drive letter changes)
+	VolumeGUID string `json:"volume_guid,omitempty"` // Volume GUID path (\\?\Volume{...}\)
+	VolumeRoot string `json:"volume_root,omitempty"` // Mount root when the job was saved (e.g. "D:\")
+	// Propagate read-only/archive attribute changes of in-sync files
+	SyncAttributes bool `json:"sync_attributes,omitempty"`
+	// Read transferred files back and compare their checksum
+	VerifyTransfers bool `json:"verify_transfers,omitempty"`
+	// Copy the owner and NTFS permissions of transferred files
+	SyncACLs bool `json:"sync_acls,omitempty"`
+	// Copy the alternate data streams of transferred files
+	SyncStreams bool `json:"sync_streams,omitempty"`
+	// Upload files locked by other programs from a shadow copy of their volume
+	ReadLockedFiles bool `json:"read_locked_files,omitempty"`
+	// Also write the job's log lines to logs\jobs\<job id>.log
+	JobLog bool `json:"job_log,omitempty"`
+	// Exclusion group overrides (group name -> enabled), unlisted groups use their default
+	ExclusionGroups map[string]bool `json:"exclusion_groups,omitempty"`
+	// Safety cap on changes per run (0 = no limit)
+	MaxChangedFiles int    `json:"max_changed_files,omitempty"`
+	MaxChangedBytes int64  `json:"max_changed_bytes,omitempty"`
+	PendingApproval string `json:"pending_approval,omitempty"` // Why the job is paused until its changes are approved
+	// Daily times when automatic syncs wait, and what a running sync does then
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows,omitempty"`
+	MaintenancePolicy  string              `json:"maintenance_policy,omitempty"` // "finish" (default) or "pause"
+	// Realtime mode: seconds without new changes before syncing (0 = global setting)
+	DebounceSeconds int `json:"debounce_seconds,omitempty"`
+	// Idle mode: minutes without keyboard or mouse input before syncing (0 = default)
+	IdleMinutes int `json:"idle_minutes,omitempty"`
+	// Transfer rate limits in KB/s (0 = global limits only)
+	MaxUploadKBps   int  `json:"max_upload_kbps,omitempty"`
+	MaxDownloadKBps int  `json:"max_download_kbps,omitempty"`
+	ThrottleMetered bool `json:"throttle_metered,omitempty"` // Slow down on metered connections
+	// Network conditions of automatic syncs (empty = any network)
+	WifiSSIDs    []string `json:"wifi_ssids,omitempty"`    // Only on these Wi-Fi networks
+	AvoidMetered bool     `json:"avoid_metered,omitempty"` // Not on metered connections
+	VPNInterface string   `json:"vpn_interface,omitempty"` // Only while this adapter is connected
 }
 
 // ToJSON serializes JobOptions to JSON string.
@@ -47,8 +87,37 @@ const (
 	SyncTrigger30Min    SyncTriggerMode = "30m"      // Every 30 minutes
 	SyncTrigger1Hour    SyncTriggerMode = "1h"       // Every hour
 	SyncTriggerRealtime SyncTriggerMode = "realtime" // Realtime (local watcher + remote check every 5min)
+	SyncTriggerNetwork  SyncTriggerMode = "network"  // When the PC connects to a network
+	SyncTriggerCron     SyncTriggerMode = "cron"     // Cron expression in SyncJob.TriggerCron
+	SyncTriggerIdle     SyncTriggerMode = "idle"     // When the user is idle for SyncJob.IdleMinutes
 )
 
+// String returns the trigger as shown in the job form and the job list.
+func (m SyncTriggerMode) String() string {
+	switch m {
+	case SyncTriggerManual:
+		return "Manual"
+	case SyncTrigger5Min:
+		return "Every 5 minutes"
+	case SyncTrigger15Min:
+		return "Every 15 minutes"
+	case SyncTrigger30Min:
+		return "Every 30 minutes"
+	case SyncTrigger1Hour:
+		return "Every hour"
+	case SyncTriggerRealtime:
+		return "Realtime"
+	case SyncTriggerNetwork:
+		return "When connecting to a network"
+	case SyncTriggerCron:
+		return "Custom schedule (cron)"
+	case SyncTriggerIdle:
+		return "When I'm away (idle)"
+	default:
+		return string(m)
+	}
+}
+
 // SyncJob represents a configured sync job for the UI.
 type SyncJob struct {
 	ID                 int64
@@ -56,6 +125,7 @@ type SyncJob struct {
 	LocalPath          string
 	SMBConnectionID    int64  // Reference to SMBConnection
 	RemoteHost         string // SMB server address (from SMBConnection)
+	RemotePort         int    // SMB port (from SMBConnection, 0 = default)
 	RemoteShare        string // Share name (from SMBConnection)
 	RemotePath         string // Path within share (job-specific subfolder)
 	Username           string // Username (from SMBConnection)
@@ -65,18 +135,78 @@ type SyncJob struct {
 	LastSync           time.Time
 	LastStatus         JobStatus
 	NextSync           time.Time
-	// Sync trigger mode: "manual", "5m", "15m", "30m", "1h", "realtime"
+	PendingFiles       int // Files left to sync: remaining in the running sync, or failed/in conflict after the last one (not persisted)
+	// Sync trigger mode: "manual", "5m", "15m", "30m", "1h", "realtime", "network", "cron", "idle"
 	TriggerMode   SyncTriggerMode
+	TriggerCron   string // Cron expression (e.g. "0 2 * * *") when TriggerMode is "cron"
 	SyncOnStartup bool // Sync immediately when app starts via autostart
 	// Files On Demand (Cloud Files API)
 	FilesOnDemand     bool // Enable placeholder files (download on demand)
 	AutoDehydrateDays int  // Auto-dehydrate files not accessed for X days (0 = disabled)
+	StorageSense      bool // Let Windows Storage Sense dehydrate instead of AnemoneSync
+	SharedSyncRoot    bool // Show the sync root to every account of the PC (needs administrator rights)
 	// Trust source for conflict resolution
 	TrustSource   string // "ask", "server", "local", "recent"
 	FirstSyncDone bool   // True after first sync wizard is completed
+	// Volume hosting LocalPath (to follow drive letter changes)
+	VolumeGUID  string // Volume GUID path, stable across drive letter changes
+	VolumeRoot  string // Mount root when the job was saved (e.g. "D:\")
+	VolumeMoved string // New local path detected at startup, awaiting confirmation (not persisted)
+	// Propagate read-only/archive attribute changes of in-sync files
+	SyncAttributes bool
+	// Read each transferred file back and compare its checksum, retrying on mismatch
+	VerifyTransfers bool
+	// Copy the owner and NTFS permissions of each transferred file to its
+	// copy (SIDs translated by sync.acl_sid_map of config.yaml)
+	SyncACLs bool
+	// Copy the NTFS alternate data streams (Zone.Identifier, tags...) of each
+	// transferred file to its copy
+	SyncStreams bool
+	// Upload the files other programs keep locked (Outlook PST...) from a
+	// Volume Shadow Copy snapshot (needs administrator rights)
+	ReadLockedFiles bool
+	// Also write the log lines of the job's runs to their own rotated file,
+	// logs\jobs\<job id>.log
+	JobLog bool
+	// Exclusion group overrides (group name -> enabled), unlisted groups use their default
+	ExclusionGroups map[string]bool
+	// Safety cap on changes per run (0 = no limit): a run exceeding it changes
+	// nothing and pauses the job until the user approves
+	MaxChangedFiles int
+	MaxChangedBytes int64
+	PendingApproval string // Why the job is paused ("" = not paused)
+	ChangesApproved bool   // Next sync may exceed the cap (not persisted)
+	// Daily times when the scheduler and the file watcher don't start syncs;
+	// a running sync finishes, or stops until the window closes ("pause")
+	MaintenanceWindows []MaintenanceWindow
+	MaintenancePolicy  string // MaintenanceFinish or MaintenancePause
+	// Realtime mode: seconds without new local changes before syncing them
+	// (0 = sync.realtime.debounce_seconds of config.yaml)
+	DebounceSeconds int
+	// Idle mode: minutes without keyboard or mouse input before syncing
+	// (0 = scheduler.DefaultIdleAfter); the sync stops when the user is back
+	IdleMinutes int
+	// Transfer rate limits in KB/s on top of the global ones (0 = no job limit),
+	// and sync.performance.metered_kbps while the connection is metered
+	MaxUploadKBps   int
+	MaxDownloadKBps int
+	ThrottleMetered bool
+	// Network conditions of automatic syncs, checked before starting them
+	// (manual syncs always run): only on these Wi-Fi networks, not on
+	// metered connections, only while the network adapter whose name or
+	// description contains VPNInterface is connected
+	WifiSSIDs    []string
+	AvoidMetered bool
+	VPNInterface string
+	// Last failure (not persisted): user message, raw error and its category
+	LastError        string
+	LastErrorDetails string
+	LastErrorCode    syncpkg.ErrorCode
 	// Size information (calculated periodically, not persisted)
 	LocalSize      int64 // Total size of local folder in bytes
 	LocalFileCount int   // Number of files in local folder
+	AllocatedSize  int64 // Space used on disk (Files On Demand jobs only)
+	FODSavedBytes  int64 // Space saved by dehydrated placeholders (Files On Demand jobs only)
 	SizeUpdatedAt  time.Time
 }
 
@@ -84,12 +214,15 @@ type SyncJob struct {
 type JobStatus string
 
 const (
-	JobStatusIdle      JobStatus = "idle"
-	JobStatusSyncing   JobStatus = "syncing"
-	JobStatusSuccess   JobStatus = "success"
-	JobStatusPartial   JobStatus = "partial"
-	JobStatusFailed    JobStatus = "failed"
-	JobStatusDisabled  JobStatus = "disabled"
+	JobStatusIdle        JobStatus = "idle"
+	JobStatusSyncing     JobStatus = "syncing"
+	JobStatusSuccess     JobStatus = "success"
+	JobStatusPartial     JobStatus = "partial"
+	JobStatusFailed      JobStatus = "failed"
+	JobStatusDisabled    JobStatus = "disabled"
+	JobStatusApproval    JobStatus = "approval"    // Paused by the change cap
+	JobStatusMaintenance JobStatus = "maintenance" // Stopped by a maintenance window
+	JobStatusUserBack    JobStatus = "user_back"   // Idle job stopped when the user came back
 )
 
 // String returns the display string for JobStatus.
@@ -107,6 +240,12 @@ func (s JobStatus) String() string {
 		return "Failed"
 	case JobStatusDisabled:
 		return "Disabled"
+	case JobStatusApproval:
+		return "Awaiting approval"
+	case JobStatusMaintenance:
+		return "Maintenance window"
+	case JobStatusUserBack:
+		return "Paused while you work"
 	default:
 		return string(s)
 	}
@@ -121,23 +260,24 @@ func (s JobStatus) Icon() string {
 		return "~"
 	case JobStatusSuccess:
 		return "+"
-	case JobStatusPartial:
+	case JobStatusPartial, JobStatusApproval:
 		return "!"
 	case JobStatusFailed:
 		return "X"
-	case JobStatusDisabled:
+	case JobStatusDisabled, JobStatusMaintenance, JobStatusUserBack:
 		return "O"
 	default:
 		return "?"
 	}
 }
 
-// FullRemotePath returns the complete SMB path.
+// FullRemotePath returns the complete SMB path in canonical form (\\host\share\path).
 func (j *SyncJob) FullRemotePath() string {
-	if j.RemotePath == "" || j.RemotePath == "/" {
-		return "\\\\" + j.RemoteHost + "\\" + j.RemoteShare
-	}
-	return "\\\\" + j.RemoteHost + "\\" + j.RemoteShare + "\\" + j.RemotePath
+	return smbpath.UNCPath{
+		Host:  j.RemoteHost,
+		Share: j.RemoteShare,
+		Path:  smbpath.CleanSharePath(j.RemotePath),
+	}.String()
 }
 
 // SMBConnection represents a configured SMB server connection.
@@ -152,6 +292,14 @@ type SMBConnection struct {
 	Username     string // Username for authentication
 	CredentialID string // Reference to Windows Credential Manager
 	SMBVersion   string // "2.0", "2.1", "3.0", "3.1.1"
+	// Remote timestamp driving change detection: "write" (LastWriteTime) or "change" (ChangeTime)
+	MTimeSource smb.MTimeSource
+	// Best signing/encryption negotiated so far ("" = not connected yet)
+	SecurityLevel smb.SecurityLevel
+	// Refuse connections negotiating less than SecurityLevel
+	RequireSecurity bool
+	// Refuse connections not negotiating SMB3 encryption
+	RequireEncryption bool
 }
 
 // DisplayName returns a formatted display name for the connection.
@@ -168,6 +316,11 @@ type AppSettings struct {
 	NotificationsEnabled bool
 	LogLevel             string
 	SyncInterval         string
+	WebhookURLs          []string // Receive a JSON summary of every sync
+
+	// While the user presents or runs a full-screen application
+	PauseSyncWhenPresenting         bool // Defer automatic syncs
+	HoldNotificationsWhenPresenting bool // Show notifications afterwards
 }
 
 // DefaultAppSettings returns default settings.
@@ -177,5 +330,7 @@ func DefaultAppSettings() *AppSettings {
 		NotificationsEnabled: true,
 		LogLevel:             "Info",
 		SyncInterval:         "15 minutes",
+
+		HoldNotificationsWhenPresenting: true,
 	}
 }
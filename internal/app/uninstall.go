@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// UninstallOptions selects what the uninstall cleanup does besides
+// unregistering sync roots and removing the autostart entry.
+type UninstallOptions struct {
+	Hydrate           bool // Download every dehydrated file before unregistering (servers must be reachable)
+	DeleteCredentials bool // Delete saved SMB passwords from the Windows Credential Manager
+}
+
+// UninstallReport lists what the uninstall cleanup did.
+type UninstallReport struct {
+	Lines    []string
+	Failures int
+}
+
+func (r *UninstallReport) add(format string, args ...any) {
+	r.Lines = append(r.Lines, fmt.Sprintf(format, args...))
+}
+
+func (r *UninstallReport) fail(format string, args ...any) {
+	r.Failures++
+	r.Lines = append(r.Lines, "FAILED: "+fmt.Sprintf(format, args...))
+}
+
+// String returns the report, one line per step.
+func (r *UninstallReport) String() string {
+	return strings.Join(r.Lines, "\n") + "\n"
+}
+
+// WriteFile saves the report to path.
+func (r *UninstallReport) WriteFile(path string) error {
+	header := fmt.Sprintf("AnemoneSync uninstall cleanup - %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	return os.WriteFile(path, []byte(header+r.String()), 0644)
+}
+
+// UninstallCleanup undoes what AnemoneSync registered in Windows so that
+// uninstalling doesn't strand Cloud Files metadata: placeholders of Files On
+// Demand jobs become regular files (those without local content are removed,
+// their content stays on the server), sync roots are unregistered and the
+// autostart entry is removed. db may be nil if the database can't be opened.
+// Failures are recorded in the report and don't stop the cleanup.
+func UninstallCleanup(ctx context.Context, db *database.DB, opts UninstallOptions, logger *zap.Logger) *UninstallReport {
+	report := &UninstallReport{}
+
+	if db == nil {
+		report.fail("database not available: sync roots and credentials were not cleaned up")
+	} else {
+		dbJobs, err := db.GetAllSyncJobs()
+		if err != nil {
+			report.fail("list jobs: %v", err)
+		}
+		for _, dbJob := range dbJobs {
+			job := convertDBJobToAppJob(dbJob)
+			if job.FilesOnDemand {
+				cleanupJobSyncRoot(ctx, job, opts, report, logger)
+			}
+		}
+	}
+
+	// Autostart entry
+	if autoStart, err := NewAutoStart(); err != nil {
+		report.fail("autostart: %v", err)
+	} else if err := autoStart.Disable(); err != nil {
+		report.fail("remove autostart entry: %v", err)
+	} else {
+		report.add("Autostart entry removed")
+	}
+
+	// Saved passwords (kept unless asked, another install may still use them)
+	if opts.DeleteCredentials && db != nil {
+		servers, err := db.GetAllSMBServers()
+		if err != nil {
+			report.fail("list SMB servers: %v", err)
+		}
+		credMgr := smb.NewCredentialManager(logger)
+		for _, server := range servers {
+			if !credMgr.Exists(server.Host) {
+				continue
+			}
+			if err := credMgr.Delete(server.Host); err != nil {
+				report.fail("delete credentials of %s: %v", server.Host, err)
+			} else {
+				report.add("Credentials of %s deleted", server.Host)
+			}
+		}
+	}
+
+	return report
+}
+
+// cleanupJobSyncRoot hydrates (if asked), reverts the placeholders of a Files
+// On Demand job and unregisters its sync root.
+func cleanupJobSyncRoot(ctx context.Context, job *SyncJob, opts UninstallOptions, report *UninstallReport, logger *zap.Logger) {
+	localPath := filepath.FromSlash(job.LocalPath)
+	if _, err := os.Stat(localPath); err != nil {
+		report.fail("%s: folder %s not available: %v", job.Name, localPath, err)
+		return
+	}
+
+	if opts.Hydrate {
+		hydrated, failed, err := hydrateAllPlaceholders(ctx, job, localPath, logger)
+		if err != nil {
+			report.fail("%s: hydrate: %v", job.Name, err)
+		} else if failed > 0 {
+			report.fail("%s: %d files hydrated, %d could not be downloaded", job.Name, hydrated, failed)
+		} else {
+			report.add("%s: %d files hydrated", job.Name, hydrated)
+		}
+	}
+
+	reverted, removed, failed := revertPlaceholders(localPath, logger)
+	if failed > 0 {
+		report.fail("%s: %d placeholders converted to regular files, %d without local content removed, %d failed",
+			job.Name, reverted, removed, failed)
+	} else {
+		report.add("%s: %d placeholders converted to regular files, %d without local content removed (still on the server)",
+			job.Name, reverted, removed)
+	}
+
+	if err := cloudfiles.UnregisterSyncRoot(localPath); err != nil {
+		report.fail("%s: unregister sync root %s: %v", job.Name, localPath, err)
+	} else {
+		report.add("%s: sync root %s unregistered", job.Name, localPath)
+	}
+
+	// Sync roots shared with all accounts also have a machine-wide entry
+	if cloudfiles.IsSyncRootShared(localPath) {
+		if err := cloudfiles.UnshareSyncRoot(localPath); err != nil {
+			report.fail("%s: remove %s from the other accounts: %v", job.Name, localPath, err)
+		} else {
+			report.add("%s: %s removed from the other accounts", job.Name, localPath)
+		}
+	}
+}
+
+// hydrateAllPlaceholders connects a provider to the job's server and downloads
+// the content of every dehydrated placeholder.
+func hydrateAllPlaceholders(ctx context.Context, job *SyncJob, localPath string, logger *zap.Logger) (hydrated, failed int, err error) {
+	dataSource, err := newSMBDataSource(job, logger)
+	if err != nil {
+		return 0, 0, err
+	}
+	provider, err := connectHydrationProvider(ctx, job, localPath, dataSource, logger)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer provider.Close()
+
+	err = filepath.WalkDir(localPath, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		state, err := cloudfiles.GetFilePlaceholderState(path)
+		if err != nil || state&cloudfiles.CF_PLACEHOLDER_STATE_PARTIAL == 0 {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(localPath, path)
+		if err := provider.HydrateFile(ctx, relPath); err != nil {
+			logger.Warn("Failed to hydrate file", zap.String("path", path), zap.Error(err))
+			failed++
+			return nil
+		}
+		hydrated++
+		return nil
+	})
+	return hydrated, failed, err
+}
+
+// connectHydrationProvider connects a provider answering the hydration
+// requests of the job's sync root from dataSource. The caller closes it.
+func connectHydrationProvider(ctx context.Context, job *SyncJob, localPath string, dataSource cloudfiles.DataSource, logger *zap.Logger) (*cloudfiles.CloudFilesProvider, error) {
+	provider, err := cloudfiles.NewCloudFilesProvider(cloudfiles.ProviderConfig{
+		LocalPath:    localPath,
+		RemotePath:   job.RemotePath,
+		ProviderName: "AnemoneSync",
+		Logger:       logger.Named("cloudfiles"),
+		UseCGOBridge: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	provider.SetDataSource(dataSource)
+	if err := provider.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize provider: %w", err)
+	}
+	return provider, nil
+}
+
+// revertPlaceholders converts the hydrated placeholders under localPath to
+// regular files and removes those without local content.
+func revertPlaceholders(localPath string, logger *zap.Logger) (reverted, removed, failed int) {
+	filepath.WalkDir(localPath, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || path == localPath {
+			return nil
+		}
+		state, err := cloudfiles.GetFilePlaceholderState(path)
+		if err != nil || state&cloudfiles.CF_PLACEHOLDER_STATE_PLACEHOLDER == 0 {
+			return nil
+		}
+
+		if !d.IsDir() && state&cloudfiles.CF_PLACEHOLDER_STATE_PARTIAL != 0 {
+			if err := os.Remove(path); err != nil {
+				logger.Warn("Failed to remove dehydrated placeholder", zap.String("path", path), zap.Error(err))
+				failed++
+			} else {
+				removed++
+			}
+			return nil
+		}
+
+		if err := cloudfiles.RevertPlaceholder(path); err != nil {
+			logger.Warn("Failed to revert placeholder", zap.String("path", path), zap.Error(err))
+			failed++
+			return nil
+		}
+		if !d.IsDir() {
+			reverted++
+		}
+		return nil
+	})
+	return reverted, removed, failed
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// UsageDialog shows what takes space on the server for a job: the size of
+// each top-level folder and the largest files, as found by the last scan.
+type UsageDialog struct {
+	app    *App
+	job    *SyncJob
+	window fyne.Window
+
+	// UI elements
+	folderList  *widget.List
+	fileList    *widget.List
+	statusLabel *widget.Label
+	refreshBtn  *widget.Button
+
+	// Data
+	usage *database.RemoteUsage // nil = never scanned
+}
+
+// ShowUsageDialog displays the Server Space dialog for a job.
+func (a *App) ShowUsageDialog(job *SyncJob) {
+	if job == nil {
+		return
+	}
+
+	d := &UsageDialog{
+		app: a,
+		job: job,
+	}
+	if a.db != nil {
+		usage, err := a.db.GetRemoteUsage(job.ID)
+		if err != nil {
+			a.logger.Warn("failed to load remote usage", zap.Int64("job_id", job.ID), zap.Error(err))
+		}
+		d.usage = usage
+	}
+	d.show()
+}
+
+func (d *UsageDialog) show() {
+	d.window = d.app.fyneApp.NewWindow(fmt.Sprintf("Server Space - %s", d.job.Name))
+	d.window.Resize(fyne.NewSize(600, 500))
+
+	d.folderList = widget.NewList(
+		func() int { return len(d.folders()) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			f := d.folders()[id]
+			name := f.Path + `\`
+			if f.Path == "" {
+				name = "(files at the root)"
+			}
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  -  %s, %d files (%.0f%%)",
+				name, formatBytes(f.Bytes), f.Files, d.percent(f.Bytes)))
+		},
+	)
+	d.fileList = widget.NewList(
+		func() int { return len(d.largestFiles()) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			f := d.largestFiles()[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  -  %s", f.Path, formatBytes(f.Bytes)))
+		},
+	)
+
+	d.statusLabel = widget.NewLabel("")
+	d.statusLabel.Wrapping = fyne.TextWrapWord
+	d.showStatus()
+
+	// Buttons
+	d.refreshBtn = widget.NewButton("Scan Server", d.onRefresh)
+	d.refreshBtn.Importance = widget.HighImportance
+	closeBtn := widget.NewButton("Close", func() {
+		d.window.Close()
+	})
+
+	// Layout
+	lists := container.NewVSplit(
+		container.NewBorder(widget.NewLabel("Top-level folders:"), nil, nil, nil, d.folderList),
+		container.NewBorder(widget.NewLabel("Largest files:"), nil, nil, nil, d.fileList),
+	)
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("Server folder: %s", d.job.FullRemotePath())),
+			widget.NewSeparator(),
+		),
+		container.NewVBox(
+			widget.NewSeparator(),
+			d.statusLabel,
+			container.NewHBox(closeBtn, d.refreshBtn),
+		),
+		nil, nil,
+		lists,
+	)
+
+	d.window.SetContent(content)
+	d.window.Show()
+}
+
+// folders returns the top-level folders of the last scan.
+func (d *UsageDialog) folders() []database.UsageEntry {
+	if d.usage == nil {
+		return nil
+	}
+	return d.usage.Folders
+}
+
+// largestFiles returns the largest files of the last scan.
+func (d *UsageDialog) largestFiles() []database.UsageEntry {
+	if d.usage == nil {
+		return nil
+	}
+	return d.usage.LargestFiles
+}
+
+// percent returns bytes as a percentage of the space used by the job.
+func (d *UsageDialog) percent(bytes int64) float64 {
+	if d.usage == nil || d.usage.TotalBytes == 0 {
+		return 0
+	}
+	return float64(bytes) * 100 / float64(d.usage.TotalBytes)
+}
+
+// showStatus describes the last scan.
+func (d *UsageDialog) showStatus() {
+	if d.usage == nil {
+		d.statusLabel.SetText("The server was not scanned yet: the next sync of the job or a scan fills this report.")
+		return
+	}
+	d.statusLabel.SetText(fmt.Sprintf("%s in %d files, scanned %s.",
+		formatBytes(d.usage.TotalBytes), d.usage.TotalFiles, d.usage.ScannedAt.Format("2006-01-02 15:04")))
+}
+
+func (d *UsageDialog) onRefresh() {
+	if d.app.syncManager == nil {
+		return
+	}
+
+	d.refreshBtn.Disable()
+	d.statusLabel.SetText("Listing the files of the job on the server...")
+
+	go func() {
+		usage, err := d.app.syncManager.RemoteUsage(context.Background(), d.job)
+		fyne.Do(func() {
+			d.refreshBtn.Enable()
+			if err != nil {
+				d.showStatus()
+				d.app.showFriendlyError(err, d.job.RemoteHost, d.window)
+				return
+			}
+			d.usage = usage
+			d.folderList.Refresh()
+			d.fileList.Refresh()
+			d.showStatus()
+		})
+	}()
+}
@@ -0,0 +1,285 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// PreviousVersions lists the versions of a file of a job kept by the
+// snapshots of the server share, newest first. relPath is relative to the
+// job folder, or a full local path inside it.
+func (a *App) PreviousVersions(job *SyncJob, relPath string) ([]smb.PreviousVersion, error) {
+	remote, _, err := jobFilePaths(job, relPath)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := a.LoadSMBCredential(job.RemoteHost)
+	if err != nil {
+		return nil, err
+	}
+	return smb.ListPreviousVersions(remote, creds)
+}
+
+// RestorePreviousVersion copies a previous version of a file of a job next
+// to the local file, as "name (date).ext", and returns the path of the copy.
+// The current file is left as is: the user picks what to keep.
+func (a *App) RestorePreviousVersion(job *SyncJob, relPath string, v smb.PreviousVersion) (string, error) {
+	remote, localPath, err := jobFilePaths(job, relPath)
+	if err != nil {
+		return "", err
+	}
+	creds, err := a.LoadSMBCredential(job.RemoteHost)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(filepath.Dir(localPath), smb.VersionCopyName(filepath.Base(localPath), v))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("failed to create folder: %w", err)
+	}
+	if err := smb.RestorePreviousVersion(remote, creds, v, target); err != nil {
+		return "", err
+	}
+	a.logger.Info("previous version restored",
+		zap.String("job", job.Name),
+		zap.String("file", relPath),
+		zap.String("snapshot", v.Token),
+		zap.String("copy", target))
+	return target, nil
+}
+
+// jobFilePaths returns the remote and local paths of a file of a job.
+func jobFilePaths(job *SyncJob, relPath string) (smbpath.UNCPath, string, error) {
+	localRoot := filepath.FromSlash(job.LocalPath)
+	if filepath.IsAbs(relPath) {
+		rel, err := filepath.Rel(localRoot, relPath)
+		if err != nil {
+			return smbpath.UNCPath{}, "", fmt.Errorf("%s is not inside the job folder %s", relPath, localRoot)
+		}
+		relPath = rel
+	}
+	subtree, err := sync.NormalizeSubtree(relPath)
+	if err != nil || subtree == "" {
+		return smbpath.UNCPath{}, "", fmt.Errorf("%s is not a file of the job", relPath)
+	}
+
+	remote, err := smbpath.JoinUNC(job.RemoteHost, job.RemoteShare, job.RemotePath+`\`+subtree)
+	if err != nil {
+		return smbpath.UNCPath{}, "", err
+	}
+	return remote, filepath.Join(localRoot, filepath.FromSlash(subtree)), nil
+}
+
+// VersionsDialog shows the previous versions of a file of a job.
+type VersionsDialog struct {
+	app    *App
+	job    *SyncJob
+	window fyne.Window
+
+	// UI elements
+	fileEntry   *widget.Entry
+	versionList *widget.List
+	statusLabel *widget.Label
+	listBtn     *widget.Button
+	restoreBtn  *widget.Button
+
+	// Data
+	relPath  string // File the versions are of
+	versions []smb.PreviousVersion
+	selected int // -1 = none
+}
+
+// ShowVersionsDialog displays the Previous Versions dialog for a job.
+func (a *App) ShowVersionsDialog(job *SyncJob) {
+	if job == nil {
+		return
+	}
+
+	d := &VersionsDialog{
+		app:      a,
+		job:      job,
+		selected: -1,
+	}
+	d.show()
+}
+
+func (d *VersionsDialog) show() {
+	d.window = d.app.fyneApp.NewWindow(fmt.Sprintf("Previous Versions - %s", d.job.Name))
+	d.window.Resize(fyne.NewSize(600, 450))
+
+	// File of the job
+	d.fileEntry = widget.NewEntry()
+	d.fileEntry.SetPlaceHolder("File, relative to the job folder")
+	d.fileEntry.OnSubmitted = func(string) { d.refresh() }
+	browseBtn := widget.NewButton("Browse...", d.browse)
+	d.listBtn = widget.NewButton("List Versions", d.refresh)
+
+	fileContainer := container.NewBorder(
+		nil, nil,
+		widget.NewLabel("File:"),
+		container.NewHBox(browseBtn, d.listBtn),
+		d.fileEntry,
+	)
+
+	// Version list
+	d.versionList = widget.NewList(
+		func() int { return len(d.versions) },
+		func() fyne.CanvasObject {
+			return container.NewHBox(
+				widget.NewLabel("Snapshot 2025-01-31 10:00"),
+				widget.NewLabel("Modified 2025-01-31 10:00"),
+				widget.NewLabel("100 MB"),
+			)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(d.versions) {
+				return
+			}
+			v := d.versions[id]
+			hbox := obj.(*fyne.Container)
+			hbox.Objects[0].(*widget.Label).SetText("Snapshot " + v.Snapshot.Local().Format("2006-01-02 15:04"))
+			hbox.Objects[1].(*widget.Label).SetText("Modified " + v.ModTime.Local().Format("2006-01-02 15:04"))
+			hbox.Objects[2].(*widget.Label).SetText(formatBytes(v.Size))
+		},
+	)
+	d.versionList.OnSelected = func(id widget.ListItemID) {
+		d.selected = id
+		d.restoreBtn.Enable()
+	}
+	d.versionList.OnUnselected = func(widget.ListItemID) {
+		d.selected = -1
+		d.restoreBtn.Disable()
+	}
+
+	d.statusLabel = widget.NewLabel("Choose a file to list the versions kept by the server snapshots.")
+	d.statusLabel.Wrapping = fyne.TextWrapWord
+
+	// Buttons
+	d.restoreBtn = widget.NewButton("Restore Copy", d.onRestore)
+	d.restoreBtn.Importance = widget.HighImportance
+	d.restoreBtn.Disable()
+
+	closeBtn := widget.NewButton("Close", func() {
+		d.window.Close()
+	})
+
+	// Layout
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("Local folder: %s", d.job.LocalPath)),
+			widget.NewSeparator(),
+			fileContainer,
+		),
+		container.NewVBox(
+			widget.NewSeparator(),
+			d.statusLabel,
+			container.NewHBox(closeBtn, d.restoreBtn),
+		),
+		nil, nil,
+		d.versionList,
+	)
+
+	d.window.SetContent(content)
+	d.window.Show()
+}
+
+// browse picks the file in the job folder. A file deleted locally can't be
+// picked: its path is typed instead.
+func (d *VersionsDialog) browse() {
+	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, d.window)
+			return
+		}
+		if reader == nil {
+			return // cancelled
+		}
+		path := reader.URI().Path()
+		reader.Close()
+
+		rel, err := filepath.Rel(filepath.FromSlash(d.job.LocalPath), filepath.FromSlash(path))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			dialog.ShowError(fmt.Errorf("%s is not inside the job folder", path), d.window)
+			return
+		}
+		d.fileEntry.SetText(filepath.ToSlash(rel))
+		d.refresh()
+	}, d.window)
+	if lister, err := storage.ListerForURI(storage.NewFileURI(d.job.LocalPath)); err == nil {
+		fd.SetLocation(lister)
+	}
+	fd.Show()
+}
+
+// refresh lists the versions of the file in the entry.
+func (d *VersionsDialog) refresh() {
+	relPath := strings.TrimSpace(d.fileEntry.Text)
+	if relPath == "" {
+		return
+	}
+
+	d.listBtn.Disable()
+	d.restoreBtn.Disable()
+	d.statusLabel.SetText("Listing the snapshots of the server...")
+
+	go func() {
+		versions, err := d.app.PreviousVersions(d.job, relPath)
+		fyne.Do(func() {
+			d.listBtn.Enable()
+			d.relPath = relPath
+			d.versions = versions
+			d.selected = -1
+			d.versionList.UnselectAll()
+			d.versionList.Refresh()
+
+			switch {
+			case err != nil:
+				d.statusLabel.SetText("Could not list the versions.")
+				d.app.showFriendlyError(err, d.job.RemoteHost, d.window)
+			case len(versions) == 0:
+				d.statusLabel.SetText("No previous version: the server has no snapshot of this file, or it did not change between them.")
+			default:
+				d.statusLabel.SetText(fmt.Sprintf("%d previous versions. Restoring one copies it next to the file, which is left unchanged.", len(versions)))
+			}
+		})
+	}()
+}
+
+func (d *VersionsDialog) onRestore() {
+	if d.selected < 0 || d.selected >= len(d.versions) {
+		return
+	}
+	v := d.versions[d.selected]
+	relPath := d.relPath
+
+	d.restoreBtn.Disable()
+	d.statusLabel.SetText("Copying the version...")
+
+	go func() {
+		target, err := d.app.RestorePreviousVersion(d.job, relPath, v)
+		fyne.Do(func() {
+			d.restoreBtn.Enable()
+			if err != nil {
+				d.statusLabel.SetText("Could not restore the version.")
+				d.app.showFriendlyError(err, d.job.RemoteHost, d.window)
+				return
+			}
+			d.statusLabel.SetText("Restored as " + filepath.Base(target))
+			dialog.ShowInformation("Version Restored",
+				fmt.Sprintf("The version of %s was copied to:\n\n%s", v.ModTime.Local().Format("2006-01-02 15:04"), target),
+				d.window)
+		})
+	}()
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"go.uber.org/zap"
+)
+
+// WarmResult summarizes the hydration of the placeholders of a job.
+type WarmResult struct {
+	Matched  int   // Dehydrated placeholders matching the pattern
+	Hydrated int   // Placeholders whose content was downloaded
+	Failed   int   // Placeholders that could not be hydrated
+	Bytes    int64 // Size of the hydrated files
+}
+
+// WarmProgress is called after each placeholder is hydrated or failed.
+type WarmProgress func(done, total int, bytes int64, relPath string)
+
+// WarmPlaceholders downloads the content of the dehydrated placeholders of a
+// Files On Demand job matching pattern ("" = all), e.g. to fill a new machine
+// before going offline. Transfers wait on the bandwidth limiters of ctx.
+// Like the uninstall cleanup, hydration requests are answered by a provider
+// connected by the caller, so the server must be reachable.
+func WarmPlaceholders(ctx context.Context, dbJob *database.SyncJob, pattern string, progress WarmProgress, logger *zap.Logger) (*WarmResult, error) {
+	job := convertDBJobToAppJob(dbJob)
+	if !job.FilesOnDemand {
+		return nil, fmt.Errorf("job %q does not have Files On Demand enabled", job.Name)
+	}
+	if !cloudfiles.IsAvailable() {
+		return nil, fmt.Errorf("Cloud Files API not available (requires Windows 10 1709+)")
+	}
+
+	var match *scanner.Pattern
+	root := ""
+	if pattern != "" {
+		var err error
+		if match, err = scanner.CompileGlob(pattern); err != nil {
+			return nil, err
+		}
+		root = match.Root()
+	}
+
+	// Collect first, so progress has a total
+	localPath := filepath.FromSlash(job.LocalPath)
+	type placeholder struct {
+		relPath string
+		size    int64
+	}
+	var pending []placeholder
+	err := filepath.WalkDir(filepath.Join(localPath, filepath.FromSlash(root)), func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return nil
+		}
+		relPath, _ := filepath.Rel(localPath, path)
+		if match != nil && !match.MatchPath(relPath) {
+			return nil
+		}
+		state, err := cloudfiles.GetFilePlaceholderState(path)
+		if err != nil || state&cloudfiles.CF_PLACEHOLDER_STATE_PARTIAL == 0 {
+			return nil
+		}
+		var size int64
+		if info, err := d.Info(); err == nil {
+			size = info.Size()
+		}
+		pending = append(pending, placeholder{relPath: relPath, size: size})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list placeholders: %w", err)
+	}
+
+	result := &WarmResult{Matched: len(pending)}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	dataSource, err := newSMBDataSource(job, logger)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := connectHydrationProvider(ctx, job, localPath, &limitedDataSource{DataSource: dataSource, ctx: ctx}, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer provider.Close()
+
+	for i, p := range pending {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := provider.HydrateFile(ctx, p.relPath); err != nil {
+			logger.Warn("Failed to hydrate file", zap.String("path", p.relPath), zap.Error(err))
+			result.Failed++
+		} else {
+			result.Hydrated++
+			result.Bytes += p.size
+		}
+		if progress != nil {
+			progress(i+1, len(pending), result.Bytes, p.relPath)
+		}
+	}
+
+	logger.Info("Placeholders warmed",
+		zap.String("job", job.Name),
+		zap.String("pattern", pattern),
+		zap.Int("hydrated", result.Hydrated),
+		zap.Int("failed", result.Failed),
+		zap.Int64("bytes", result.Bytes),
+	)
+	return result, nil
+}
+
+// limitedDataSource reads remote files through the bandwidth limiters of ctx.
+// Hydration callbacks don't carry the context of the caller.
+type limitedDataSource struct {
+	cloudfiles.DataSource
+	ctx context.Context
+}
+
+func (s *limitedDataSource) GetFileReader(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error) {
+	reader, err := s.DataSource.GetFileReader(ctx, remotePath, offset)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{bandwidth.NewReader(s.ctx, reader, bandwidth.Download), reader}, nil
+}
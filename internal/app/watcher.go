@@ -10,6 +10,11 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"go.uber.org/zap"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
 )
 
 // Watcher monitors file system changes and triggers syncs.
@@ -22,6 +27,10 @@ type Watcher struct {
 	running   bool
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	debounceDelay time.Duration // Default wait for changes to settle (jobs may override it)
+	clock         clock.Clock   // Times cooldowns and debounce waits
+	remoteNotify  bool          // Also follow the changes of the server folders (see watchRemote)
 }
 
 // jobWatcher holds the watcher state for a single job.
@@ -33,30 +42,66 @@ type jobWatcher struct {
 	cancel       context.CancelFunc
 	syncActive   bool      // True while a sync is in progress
 	syncCooldown time.Time // Ignore events until this time
+
+	scopeMu      sync.Mutex
+	scope        string    // Common folder of pending changes ("" = whole job)
+	scopePending bool      // True once an event has set scope
+	lastFullSync time.Time // Last time the watcher ran a whole-job sync
+	scopedSyncs  int       // Scoped syncs since the last whole-job sync
 }
 
 // debouncer coalesces rapid file changes into single sync triggers.
 type debouncer struct {
 	mu       sync.Mutex
-	timer    *time.Timer
+	clock    clock.Clock
+	timer    clock.Timer
 	pending  bool
 	delay    time.Duration
 	callback func()
 }
 
-// Default debounce delay (wait for changes to settle).
+// Default debounce delay (wait for changes to settle), unless config.yaml sets one.
 const defaultDebounceDelay = 3 * time.Second
 
+// Scoped syncs only cover the folders that changed, so remote changes and
+// missed events elsewhere are picked up by a whole-job sync at least this often.
+const (
+	maxScopedSyncInterval = 30 * time.Minute
+	maxScopedSyncs        = 20
+)
+
 // NewWatcher creates a new file watcher instance.
-func NewWatcher(app *App, logger *zap.Logger) *Watcher {
+func NewWatcher(app *App, cfg *config.Config, logger *zap.Logger) *Watcher {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Watcher{
+	w := &Watcher{
 		app:      app,
 		logger:   logger,
 		watchers: make(map[int64]*jobWatcher),
 		ctx:      ctx,
 		cancel:   cancel,
+
+		debounceDelay: defaultDebounceDelay,
+		clock:         clock.System,
+		remoteNotify:  cfg.Sync.Realtime.RemoteNotify,
+	}
+	if cfg.Sync.Realtime.DebounceSeconds > 0 {
+		w.debounceDelay = time.Duration(cfg.Sync.Realtime.DebounceSeconds) * time.Second
 	}
+	return w
+}
+
+// SetClock replaces the clock of cooldowns and debounce waits, e.g. with a
+// fake one in tests. It must be called before Start.
+func (w *Watcher) SetClock(c clock.Clock) {
+	w.clock = clock.Or(c)
+}
+
+// debounceFor returns how long the changes of a job must settle before it syncs.
+func (w *Watcher) debounceFor(job *SyncJob) time.Duration {
+	if job.DebounceSeconds > 0 {
+		return time.Duration(job.DebounceSeconds) * time.Second
+	}
+	return w.debounceDelay
 }
 
 // Start begins watching all enabled jobs with realtime trigger mode.
@@ -147,18 +192,16 @@ func (w *Watcher) WatchJob(job *SyncJob) error {
 	// Create job watcher context
 	ctx, cancel := context.WithCancel(w.ctx)
 
-	// Create debouncer with default delay (3 seconds)
-	deb := newDebouncer(defaultDebounceDelay, func() {
-		w.onJobChange(job.ID)
-	})
-
+	// Create debouncer with the job's delay
 	jw := &jobWatcher{
 		jobID:     job.ID,
 		localPath: job.LocalPath,
 		watcher:   fsWatcher,
-		debouncer: deb,
 		cancel:    cancel,
 	}
+	jw.debouncer = newDebouncer(w.clock, w.debounceFor(job), func() {
+		w.onJobChange(job.ID, jw.takeScope(w.clock.Now()))
+	})
 
 	// Add directory and subdirectories
 	if err := w.addRecursive(fsWatcher, job.LocalPath); err != nil {
@@ -172,9 +215,17 @@ func (w *Watcher) WatchJob(job *SyncJob) error {
 	// Start event loop
 	go w.watchLoop(ctx, jw)
 
+	// Follow the server folder too, unless the job only uploads
+	if w.remoteNotify && job.Mode != syncpkg.SyncModeUpload {
+		if remote, err := smbpath.JoinUNC(job.RemoteHost, job.RemoteShare, job.RemotePath); err == nil {
+			go w.watchRemote(ctx, jw, remote)
+		}
+	}
+
 	w.logger.Info("Watching job",
 		zap.String("name", job.Name),
 		zap.String("path", job.LocalPath),
+		zap.Duration("debounce", jw.debouncer.delay),
 	)
 
 	return nil
@@ -273,7 +324,7 @@ func (w *Watcher) handleEvent(jw *jobWatcher, event fsnotify.Event) {
 		)
 		return
 	}
-	if time.Now().Before(jw.syncCooldown) {
+	if w.clock.Now().Before(jw.syncCooldown) {
 		w.logger.Debug("File event ignored (cooldown)",
 			zap.Int64("job_id", jw.jobID),
 			zap.String("path", event.Name),
@@ -294,10 +345,64 @@ func (w *Watcher) handleEvent(jw *jobWatcher, event fsnotify.Event) {
 		}
 	}
 
+	// Server unreachable: queue the change for the replay instead of syncing
+	if w.app.offlineQueue.Record(jw.jobID, jw.localPath, event) {
+		return
+	}
+
+	// Restrict the next sync to the folders that changed
+	jw.addScope(event.Name)
+
 	// Trigger debounced sync
 	jw.debouncer.trigger()
 }
 
+// addScope widens the pending sync scope to include the folder of a changed path.
+func (jw *jobWatcher) addScope(changedPath string) {
+	folder := ""
+	if rel, err := filepath.Rel(jw.localPath, filepath.Dir(changedPath)); err == nil {
+		if normalized, err := syncpkg.NormalizeSubtree(rel); err == nil {
+			folder = normalized
+		}
+	}
+	jw.addFolderScope(folder)
+}
+
+// addFolderScope widens the pending sync scope to include a folder of the
+// job ("" = whole job).
+func (jw *jobWatcher) addFolderScope(folder string) {
+	jw.scopeMu.Lock()
+	defer jw.scopeMu.Unlock()
+
+	if !jw.scopePending {
+		jw.scope = folder
+		jw.scopePending = true
+	} else {
+		jw.scope = syncpkg.CommonSubtree(jw.scope, folder)
+	}
+}
+
+// takeScope returns the folder to sync and resets the pending scope.
+// It returns "" (whole job) when a periodic whole-job sync is due at now.
+func (jw *jobWatcher) takeScope(now time.Time) string {
+	jw.scopeMu.Lock()
+	defer jw.scopeMu.Unlock()
+
+	scope := jw.scope
+	jw.scope = ""
+	jw.scopePending = false
+
+	if scope != "" && jw.scopedSyncs < maxScopedSyncs &&
+		now.Sub(jw.lastFullSync) < maxScopedSyncInterval {
+		jw.scopedSyncs++
+		return scope
+	}
+
+	jw.lastFullSync = now
+	jw.scopedSyncs = 0
+	return ""
+}
+
 // shouldIgnore returns true if the file should be ignored.
 func (w *Watcher) shouldIgnore(name string) bool {
 	// Ignore hidden files
@@ -331,7 +436,8 @@ func (w *Watcher) shouldIgnore(name string) bool {
 }
 
 // onJobChange is called when changes are detected for a job (after debounce).
-func (w *Watcher) onJobChange(jobID int64) {
+// subtree is the folder containing all changes ("" = whole job).
+func (w *Watcher) onJobChange(jobID int64, subtree string) {
 	// Find the job to verify it's still enabled
 	jobs := w.app.GetSyncJobs()
 	var job *SyncJob
@@ -345,13 +451,17 @@ func (w *Watcher) onJobChange(jobID int64) {
 	if job == nil || !job.Enabled {
 		return
 	}
+	if w.app.deferAutomaticSync(job) {
+		return
+	}
 
 	w.logger.Info("File changes detected, triggering sync",
 		zap.Int64("job_id", jobID),
+		zap.String("subtree", subtree),
 	)
 
 	// Delegate to app's sync execution
-	w.app.ExecuteJobSync(jobID)
+	w.app.ExecuteJobSyncScoped(jobID, subtree)
 }
 
 // IsWatching returns true if the watcher is actively monitoring the job.
@@ -377,7 +487,7 @@ func (w *Watcher) SetSyncActive(jobID int64, active bool) {
 	jw.syncActive = active
 	if !active {
 		// Set cooldown for 5 seconds after sync ends
-		jw.syncCooldown = time.Now().Add(5 * time.Second)
+		jw.syncCooldown = w.clock.Now().Add(5 * time.Second)
 		w.logger.Debug("Sync cooldown started",
 			zap.Int64("job_id", jobID),
 			zap.Time("cooldown_until", jw.syncCooldown),
@@ -394,9 +504,10 @@ func (w *Watcher) WatchedJobCount() int {
 
 // --- Debouncer ---
 
-// newDebouncer creates a new debouncer.
-func newDebouncer(delay time.Duration, callback func()) *debouncer {
+// newDebouncer creates a new debouncer timed by c.
+func newDebouncer(c clock.Clock, delay time.Duration, callback func()) *debouncer {
 	return &debouncer{
+		clock:    c,
 		delay:    delay,
 		callback: callback,
 	}
@@ -413,7 +524,7 @@ func (d *debouncer) trigger() {
 	}
 
 	d.pending = true
-	d.timer = time.AfterFunc(d.delay, func() {
+	d.timer = d.clock.AfterFunc(d.delay, func() {
 		d.mu.Lock()
 		d.pending = false
 		d.timer = nil
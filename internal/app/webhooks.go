@@ -0,0 +1,240 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+	"go.uber.org/zap"
+)
+
+// Webhook delivery: each URL gets a few attempts, waiting longer each time.
+const (
+	webhookAttempts  = 4
+	webhookBackoff   = 5 * time.Second // Doubled after each failed attempt
+	webhookTimeout   = 15 * time.Second
+	webhookMaxErrors = 10 // Error messages included in a payload
+)
+
+// WebhookPayload is the JSON summary of a sync posted to the webhooks. The
+// "text" field is what Slack and Teams incoming webhooks display; other
+// receivers read the structured fields.
+type WebhookPayload struct {
+	Text       string    `json:"text"`
+	Event      string    `json:"event"` // "sync" or "test"
+	Host       string    `json:"host"`
+	Job        string    `json:"job,omitempty"`
+	JobID      int64     `json:"job_id,omitempty"`
+	RunID      string    `json:"run_id,omitempty"`
+	Status     string    `json:"status,omitempty"` // success, partial or failed
+	StartTime  time.Time `json:"start_time,omitzero"`
+	DurationS  float64   `json:"duration_seconds,omitempty"`
+	Uploaded   int       `json:"files_uploaded"`
+	Downloaded int       `json:"files_downloaded"`
+	Deleted    int       `json:"files_deleted"`
+	Failed     int       `json:"files_failed"`
+	Conflicts  int       `json:"conflicts"`
+	Bytes      int64     `json:"bytes_transferred"`
+	Errors     []string  `json:"errors,omitempty"`
+}
+
+// Webhooks posts the result of every sync to the webhook URLs of the
+// settings.
+type Webhooks struct {
+	app    *App
+	client *http.Client
+}
+
+// NewWebhooks creates the webhook transport.
+func NewWebhooks(app *App) *Webhooks {
+	return &Webhooks{
+		app:    app,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// SyncFinished posts the result of a sync in the background (result is nil
+// when the sync failed before completing, err holds why).
+func (w *Webhooks) SyncFinished(job *SyncJob, result *syncpkg.SyncResult, err error) {
+	urls := w.app.GetWebhookURLs()
+	if len(urls) == 0 {
+		return
+	}
+	payload := syncPayload(job, result, err)
+	for _, target := range urls {
+		go func() {
+			if err := w.post(w.app.ctx, target, payload); err != nil {
+				w.app.Logger().Warn("Webhook delivery failed",
+					zap.String("url", redactWebhookURL(target)),
+					zap.String("job", job.Name),
+					zap.Error(err))
+			}
+		}()
+	}
+}
+
+// Test posts a test payload to a webhook URL, without retrying, so the
+// settings can show at once whether it works.
+func (w *Webhooks) Test(ctx context.Context, target string) error {
+	payload := &WebhookPayload{
+		Text:  fmt.Sprintf("AnemoneSync test notification from %s", hostName()),
+		Event: "test",
+		Host:  hostName(),
+	}
+	return w.send(ctx, target, payload)
+}
+
+// post sends a payload, retrying failed deliveries with backoff. Refused
+// payloads (4xx other than 429) are not retried.
+func (w *Webhooks) post(ctx context.Context, target string, payload *WebhookPayload) error {
+	backoff := webhookBackoff
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = w.send(ctx, target, payload)
+		if err == nil {
+			return nil
+		}
+		var refused *webhookRefused
+		if errors.As(err, &refused) && !refused.retryable() {
+			return err
+		}
+		if attempt == webhookAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("after %d attempts: %w", webhookAttempts, err)
+}
+
+// webhookRefused is the HTTP status of a refused delivery.
+type webhookRefused struct {
+	status int
+}
+
+func (e *webhookRefused) Error() string {
+	return fmt.Sprintf("webhook answered %d %s", e.status, http.StatusText(e.status))
+}
+
+// retryable reports whether the receiver may accept the payload later.
+func (e *webhookRefused) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// send posts a payload once.
+func (w *Webhooks) send(ctx context.Context, target string, payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AnemoneSync/"+AppVersion)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err // Without the URL: it holds the secret
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookRefused{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// syncPayload summarizes a sync.
+func syncPayload(job *SyncJob, result *syncpkg.SyncResult, err error) *WebhookPayload {
+	p := &WebhookPayload{
+		Event: "sync",
+		Host:  hostName(),
+		Job:   job.Name,
+		JobID: job.ID,
+	}
+	if result == nil {
+		p.Status = string(syncpkg.SyncStatusFailed)
+		if err != nil {
+			p.Errors = []string{err.Error()}
+		}
+		p.Text = fmt.Sprintf("AnemoneSync: sync of '%s' failed on %s", job.Name, p.Host)
+		if job.LastError != "" {
+			p.Text += ": " + job.LastError
+		}
+		return p
+	}
+
+	p.RunID = result.RunID
+	p.Status = string(result.Status)
+	p.StartTime = result.StartTime
+	p.DurationS = result.Duration.Seconds()
+	p.Uploaded = result.FilesUploaded
+	p.Downloaded = result.FilesDownloaded
+	p.Deleted = result.FilesDeleted
+	p.Failed = result.FilesError
+	p.Conflicts = result.ConflictsFound
+	p.Bytes = result.BytesTransferred
+	for _, syncErr := range result.Errors {
+		if len(p.Errors) == webhookMaxErrors {
+			break
+		}
+		p.Errors = append(p.Errors, fmt.Sprintf("%s (%s): %v", syncErr.FilePath, syncErr.Operation, syncErr.Error))
+	}
+	p.Text = fmt.Sprintf("AnemoneSync: sync of '%s' on %s %s - %d uploaded, %d downloaded, %d deleted, %d errors, %d conflicts",
+		job.Name, p.Host, p.Status, p.Uploaded, p.Downloaded, p.Deleted, p.Failed, p.Conflicts)
+	return p
+}
+
+// hostName returns the name of this computer in payloads.
+func hostName() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+// redactWebhookURL hides the path of a webhook URL in logs: it is the secret
+// of Slack and Teams webhooks.
+func redactWebhookURL(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
+
+// ParseWebhookURLs splits the webhook URLs typed in the settings, one per
+// line, and checks they are http(s) URLs.
+func ParseWebhookURLs(text string) ([]string, error) {
+	var urls []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q: expected http:// or https://", line)
+		}
+		urls = append(urls, line)
+	}
+	return urls, nil
+}
@@ -0,0 +1,185 @@
+// Package bandwidth limits the transfer rate of syncs with token buckets.
+// Limiters travel in the context of a sync, from the engine down to the SMB
+// client, so a transfer waits on every limiter that applies to it: the
+// global limit shared by all jobs, the limit of its job, and the limit of
+// metered connections.
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Direction is the direction of a transfer.
+type Direction int
+
+const (
+	Upload Direction = iota
+	Download
+)
+
+func (d Direction) String() string {
+	if d == Upload {
+		return "upload"
+	}
+	return "download"
+}
+
+// minBurst lets small reads through without waiting at low rates.
+const minBurst = 32 * 1024
+
+// chunkSize caps a single read, so waits stay short and the rate smooth.
+const chunkSize = 64 * 1024
+
+// Limiter is a token bucket allowing a given number of bytes per second.
+// A nil Limiter does not limit. Safe for concurrent use: transfers sharing a
+// limiter share its rate.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // Bytes per second
+	burst  float64 // Bucket size
+	tokens float64 // May go negative: the debt is paid by waiting
+	last   time.Time
+
+	now func() time.Time // Replaced by tests
+}
+
+// NewLimiter returns a limiter allowing bytesPerSec, or nil (no limit) if
+// bytesPerSec <= 0.
+func NewLimiter(bytesPerSec int64) *Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	l := &Limiter{now: time.Now}
+	l.setRate(bytesPerSec)
+	l.tokens = l.burst
+	l.last = l.now()
+	return l
+}
+
+// KBps returns a limiter allowing kbps kilobytes per second (nil if kbps <= 0).
+func KBps(kbps int) *Limiter {
+	return NewLimiter(int64(kbps) * 1024)
+}
+
+// SetRate changes the rate of the limiter. Transfers in progress follow the
+// new rate from their next read.
+func (l *Limiter) SetRate(bytesPerSec int64) {
+	if l == nil || bytesPerSec <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setRate(bytesPerSec)
+	l.tokens = min(l.tokens, l.burst)
+}
+
+func (l *Limiter) setRate(bytesPerSec int64) {
+	l.rate = float64(bytesPerSec)
+	l.burst = max(l.rate, minBurst)
+}
+
+// Rate returns the allowed bytes per second (0 = unlimited).
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int64(l.rate)
+}
+
+// WaitN waits until n bytes may be transferred, or ctx is done.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	wait := l.reserve(n)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve takes n bytes from the bucket and returns how long to wait before
+// transferring them.
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+type contextKey Direction
+
+// WithLimiter returns a context whose transfers in direction dir also wait
+// on l. A nil l returns ctx unchanged.
+func WithLimiter(ctx context.Context, dir Direction, l *Limiter) context.Context {
+	if l == nil {
+		return ctx
+	}
+	existing := Limiters(ctx, dir)
+	limiters := make([]*Limiter, 0, len(existing)+1)
+	limiters = append(append(limiters, existing...), l)
+	return context.WithValue(ctx, contextKey(dir), limiters)
+}
+
+// Limiters returns the limiters of ctx for direction dir.
+func Limiters(ctx context.Context, dir Direction) []*Limiter {
+	limiters, _ := ctx.Value(contextKey(dir)).([]*Limiter)
+	return limiters
+}
+
+// Wait waits until n bytes may be transferred in direction dir on every
+// limiter of ctx, for transfers that don't go through a reader (ReadAt/WriteAt).
+func Wait(ctx context.Context, dir Direction, n int) error {
+	for _, l := range Limiters(ctx, dir) {
+		if err := l.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewReader returns r limited by the limiters of ctx for direction dir
+// (r itself if there are none).
+func NewReader(ctx context.Context, r io.Reader, dir Direction) io.Reader {
+	limiters := Limiters(ctx, dir)
+	if len(limiters) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiters: limiters}
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		if werr := l.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,104 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Reserve(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	l := NewLimiter(100 * 1024) // 100 KB/s, 100 KB burst
+	l.now = func() time.Time { return now }
+	l.last = now
+
+	// The full bucket is available at once
+	if wait := l.reserve(100 * 1024); wait > 0 {
+		t.Errorf("first 100 KB wait %s, want none", wait)
+	}
+	// Then the rate applies
+	if wait := l.reserve(50 * 1024); wait != 500*time.Millisecond {
+		t.Errorf("next 50 KB wait %s, want 500ms", wait)
+	}
+	// Tokens refill with time
+	now = now.Add(2 * time.Second)
+	if wait := l.reserve(100 * 1024); wait > 0 {
+		t.Errorf("after refill wait %s, want none", wait)
+	}
+
+	// Slower rate: the bucket shrinks to the minimum burst
+	l.SetRate(1024)
+	if l.Rate() != 1024 {
+		t.Errorf("Rate() = %d, want 1024", l.Rate())
+	}
+	now = now.Add(time.Minute)
+	if wait := l.reserve(minBurst + 1024); wait != time.Second {
+		t.Errorf("wait at 1 KB/s = %s, want 1s", wait)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	if l := KBps(0); l != nil {
+		t.Fatal("KBps(0) should not limit")
+	}
+	var l *Limiter
+	if err := l.WaitN(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil limiter WaitN: %v", err)
+	}
+	if l.Rate() != 0 {
+		t.Errorf("nil limiter Rate() = %d", l.Rate())
+	}
+}
+
+func TestLimiter_WaitCancelled(t *testing.T) {
+	l := NewLimiter(1024)
+	l.reserve(minBurst) // Empty the bucket
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.WaitN(ctx, 10*1024); err != context.DeadlineExceeded {
+		t.Errorf("WaitN = %v, want deadline exceeded", err)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 300*1024)
+	ctx := context.Background()
+
+	// No limiter: the reader is returned as is
+	r := bytes.NewReader(data)
+	if NewReader(ctx, r, Upload) != io.Reader(r) {
+		t.Error("reader without limiter was wrapped")
+	}
+
+	// Frozen clocks: no refill while copying
+	global := NewLimiter(10 * 1024 * 1024)
+	job := NewLimiter(1024 * 1024)
+	for _, l := range []*Limiter{global, job} {
+		last := l.last
+		l.now = func() time.Time { return last }
+	}
+	ctx = WithLimiter(ctx, Upload, global)
+	ctx = WithLimiter(ctx, Upload, job)
+	ctx = WithLimiter(ctx, Download, nil)
+	if got := len(Limiters(ctx, Upload)); got != 2 {
+		t.Fatalf("%d upload limiters, want 2", got)
+	}
+	if got := len(Limiters(ctx, Download)); got != 0 {
+		t.Fatalf("%d download limiters, want 0", got)
+	}
+
+	// 300 KB at 1 MB/s fit in the burst of the job limiter
+	var out bytes.Buffer
+	n, err := io.Copy(&out, NewReader(ctx, bytes.NewReader(data), Upload))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("copy = %d, %v", n, err)
+	}
+	for _, l := range []*Limiter{global, job} {
+		if l.tokens != l.burst-float64(len(data)) {
+			t.Errorf("limiter at %d B/s: bytes not counted (tokens %.0f)", l.Rate(), l.tokens)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package bandwidth
+
+import "fmt"
+
+// IsMetered fails: connection costs are only reported by Windows.
+func IsMetered() (bool, error) {
+	return false, fmt.Errorf("metered connection detection is only available on Windows")
+}
@@ -0,0 +1,83 @@
+//go:build windows
+
+package bandwidth
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ole32 = windows.NewLazySystemDLL("ole32.dll")
+
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+)
+
+// Network List Manager (netlistmgr.h)
+var (
+	clsidNetworkListManager = windows.GUID{Data1: 0xDCB00C01, Data2: 0x570F, Data3: 0x4A9B, Data4: [8]byte{0x8D, 0x69, 0x19, 0x9F, 0xDB, 0xA5, 0x72, 0x3B}}
+	iidINetworkCostManager  = windows.GUID{Data1: 0xDCB00008, Data2: 0x570F, Data3: 0x4A9B, Data4: [8]byte{0x8D, 0x69, 0x19, 0x9F, 0xDB, 0xA5, 0x72, 0x3B}}
+)
+
+// NLM_CONNECTION_COST flags meaning the user pays for the data
+const (
+	nlmConnectionCostFixed         = 0x2
+	nlmConnectionCostVariable      = 0x4
+	nlmConnectionCostOverDataLimit = 0x10000
+	nlmConnectionCostRoaming       = 0x40000
+
+	nlmConnectionCostMetered = nlmConnectionCostFixed | nlmConnectionCostVariable |
+		nlmConnectionCostOverDataLimit | nlmConnectionCostRoaming
+)
+
+// INetworkCostManager vtable slots (after the 3 IUnknown methods)
+const (
+	vtblRelease = 2
+	vtblGetCost = 3
+)
+
+// networkCostManager is an INetworkCostManager COM object.
+type networkCostManager struct {
+	vtbl *[4]uintptr
+}
+
+// IsMetered reports whether Windows considers the current internet
+// connection metered (mobile data, data plan, roaming), as set by the user in
+// Settings > Network.
+func IsMetered() (bool, error) {
+	// COM state is per thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED); err != nil {
+		if err != syscall.Errno(windows.S_FALSE) {
+			return false, fmt.Errorf("CoInitializeEx failed: %w", err)
+		}
+	}
+	defer windows.CoUninitialize()
+
+	var manager *networkCostManager
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidNetworkListManager)),
+		0,
+		windows.CLSCTX_INPROC_SERVER,
+		uintptr(unsafe.Pointer(&iidINetworkCostManager)),
+		uintptr(unsafe.Pointer(&manager)),
+	)
+	if hr != 0 {
+		return false, fmt.Errorf("failed to create the network cost manager: HRESULT 0x%08X", uint32(hr))
+	}
+	defer syscall.SyscallN(manager.vtbl[vtblRelease], uintptr(unsafe.Pointer(manager)))
+
+	// No destination address: cost of the machine-wide internet connection
+	var cost uint32
+	hr, _, _ = syscall.SyscallN(manager.vtbl[vtblGetCost], uintptr(unsafe.Pointer(manager)), uintptr(unsafe.Pointer(&cost)), 0)
+	if hr != 0 {
+		return false, fmt.Errorf("failed to get the connection cost: HRESULT 0x%08X", uint32(hr))
+	}
+	return cost&nlmConnectionCostMetered != 0, nil
+}
@@ -0,0 +1,79 @@
+package cache
+
+// Windows file attribute bits tracked by attribute sync.
+// Values match FILE_ATTRIBUTE_* so they can be compared with both local and SMB attributes.
+const (
+	AttrReadOnly uint32 = 0x01
+	AttrArchive  uint32 = 0x20
+	AttrNormal   uint32 = 0x80 // Set when no tracked bit is set, so 0 keeps meaning "not tracked"
+
+	// TrackedAttributes are the bits propagated by attribute sync
+	TrackedAttributes = AttrReadOnly | AttrArchive
+
+	// RemoteSettableAttributes are the bits that can be written on the SMB side.
+	// The archive bit can only be propagated from remote to local.
+	RemoteSettableAttributes = AttrReadOnly
+)
+
+// NormalizeAttributes keeps only the tracked bits of raw Windows attributes.
+// Raw 0 means the attributes are unknown and stays 0 ("not tracked");
+// any other value gives a non-zero result.
+func NormalizeAttributes(raw uint32) uint32 {
+	if raw == 0 {
+		return 0
+	}
+	attrs := raw & TrackedAttributes
+	if attrs == 0 {
+		return AttrNormal
+	}
+	return attrs
+}
+
+// decideAttributes returns a metadata-only action when file content is in sync
+// but tracked attribute bits differ, or ActionNone.
+// Each bit is compared against the cached state to find which side changed:
+// read-only changes are propagated both ways (local wins without a baseline),
+// archive changes only from remote to local.
+func (cd *ChangeDetector) decideAttributes(local, remote, cached *FileInfo) (SyncAction, string) {
+	if local == nil || remote == nil || local.Attributes == 0 || remote.Attributes == 0 {
+		return ActionNone, ""
+	}
+
+	diff := (local.Attributes ^ remote.Attributes) & TrackedAttributes
+	if diff == 0 {
+		return ActionNone, ""
+	}
+
+	var cachedAttrs uint32
+	if cached != nil {
+		cachedAttrs = cached.Attributes
+	}
+
+	push, pull := false, false
+	for _, bit := range []uint32{AttrReadOnly, AttrArchive} {
+		if diff&bit == 0 {
+			continue
+		}
+
+		localChanged := cachedAttrs == 0 || (local.Attributes^cachedAttrs)&bit != 0
+		remoteChanged := cachedAttrs != 0 && (remote.Attributes^cachedAttrs)&bit != 0
+
+		switch {
+		case localChanged && bit&RemoteSettableAttributes != 0:
+			push = true
+		case remoteChanged:
+			pull = true
+		case cachedAttrs == 0:
+			// No baseline and the bit can't be pushed: take the remote value
+			pull = true
+		}
+	}
+
+	if push {
+		return ActionSetAttrRemote, "attributes changed locally"
+	}
+	if pull {
+		return ActionSetAttrLocal, "attributes changed remotely"
+	}
+	return ActionNone, ""
+}
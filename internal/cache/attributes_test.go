@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestNormalizeAttributes(t *testing.T) {
+	tests := []struct {
+		raw  uint32
+		want uint32
+	}{
+		{0, 0},                                  // unknown stays unknown
+		{0x80, AttrNormal},                      // FILE_ATTRIBUTE_NORMAL
+		{0x20 | 0x2000, AttrArchive},            // archive + not-content-indexed
+		{0x01 | 0x20 | 0x04, TrackedAttributes}, // read-only + archive + system
+		{0x10, AttrNormal},                      // no tracked bit
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAttributes(tt.raw); got != tt.want {
+			t.Errorf("NormalizeAttributes(%#x) = %#x, want %#x", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestChangeDetector_DecideAttributes(t *testing.T) {
+	cd := NewChangeDetector(nil, zap.NewNop())
+	now := time.Now().Truncate(time.Second)
+
+	file := func(attrs uint32) *FileInfo {
+		return &FileInfo{Size: 100, MTime: now, Attributes: attrs}
+	}
+
+	tests := []struct {
+		name   string
+		local  *FileInfo
+		remote *FileInfo
+		cached *FileInfo
+		want   SyncAction
+	}{
+		{"same attributes", file(AttrArchive), file(AttrArchive), file(AttrArchive), ActionNone},
+		{"not tracked", file(0), file(AttrReadOnly), file(0), ActionNone},
+		{"read-only set locally", file(AttrReadOnly), file(AttrNormal), file(AttrNormal), ActionSetAttrRemote},
+		{"read-only set remotely", file(AttrNormal), file(AttrReadOnly), file(AttrNormal), ActionSetAttrLocal},
+		{"read-only cleared remotely", file(AttrReadOnly), file(AttrArchive), file(AttrArchive | AttrReadOnly), ActionSetAttrLocal},
+		{"archive set remotely", file(AttrNormal), file(AttrArchive), file(AttrNormal), ActionSetAttrLocal},
+		{"archive cleared locally is not pushed", file(AttrNormal), file(AttrArchive), file(AttrArchive), ActionNone},
+		{"no baseline, read-only differs", file(AttrReadOnly), file(AttrNormal), nil, ActionSetAttrRemote},
+		{"no baseline, archive differs", file(AttrNormal), file(AttrArchive), nil, ActionSetAttrLocal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := cd.decideAttributes(tt.local, tt.remote, tt.cached)
+			if got != tt.want {
+				t.Errorf("decideAttributes() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeDetector_AttributeOnlyChange(t *testing.T) {
+	cd := NewChangeDetector(nil, zap.NewNop())
+	now := time.Now().Truncate(time.Second)
+
+	cached := &FileInfo{Size: 100, MTime: now, Hash: "abc", Attributes: AttrArchive}
+	local := &FileInfo{Size: 100, MTime: now, Hash: "abc", Attributes: AttrArchive | AttrReadOnly}
+	remote := &FileInfo{Size: 100, MTime: now, Attributes: AttrArchive}
+
+	// Content is unchanged: no upload, only a metadata operation
+	action, _ := cd.decide3Way(local, remote, cached)
+	if action != ActionSetAttrRemote {
+		t.Errorf("decide3Way() = %s, want %s", action, ActionSetAttrRemote)
+	}
+}
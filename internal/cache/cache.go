@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
 	"go.uber.org/zap"
 )
 
@@ -35,6 +36,41 @@ type FileInfo struct {
 	Size  int64     // File size in bytes
 	MTime time.Time // Modification time
 	Hash  string    // SHA256 hash (empty if not computed)
+
+	// Raw remote timestamps, recorded in files_state for debugging (zero for local files)
+	RemoteWriteTime  time.Time
+	RemoteChangeTime time.Time
+
+	// Tracked attribute bits, see NormalizeAttributes (0 = not tracked)
+	Attributes uint32
+
+	// Owner of the remote version, as DOMAIN\user ("" = not recorded)
+	RemoteOwner string
+
+	// When the destination was read back and matched the transferred content
+	// (zero = not verified)
+	VerifiedAt time.Time
+}
+
+// remoteTimes returns the remote timestamps as optional Unix timestamps.
+func (fi *FileInfo) remoteTimes() (writeTime, changeTime *int64) {
+	if !fi.RemoteWriteTime.IsZero() {
+		w := fi.RemoteWriteTime.Unix()
+		writeTime = &w
+	}
+	if !fi.RemoteChangeTime.IsZero() {
+		c := fi.RemoteChangeTime.Unix()
+		changeTime = &c
+	}
+	return writeTime, changeTime
+}
+
+// verifiedAt returns VerifiedAt as a Unix timestamp (0 = not verified).
+func (fi *FileInfo) verifiedAt() int64 {
+	if fi.VerifiedAt.IsZero() {
+		return 0
+	}
+	return fi.VerifiedAt.Unix()
 }
 
 // CacheManager handles intelligent caching and change detection
@@ -71,12 +107,29 @@ func (cm *CacheManager) GetCachedState(jobID int64, localPath string) (*FileInfo
 		return nil, nil
 	}
 
-	return &FileInfo{
-		Path:  state.LocalPath,
-		Size:  state.Size,
-		MTime: time.Unix(state.MTime, 0),
-		Hash:  state.Hash,
-	}, nil
+	return fileInfoFromState(state), nil
+}
+
+// fileInfoFromState returns the cached state of a file recorded in files_state.
+func fileInfoFromState(state *database.FileState) *FileInfo {
+	info := &FileInfo{
+		Path:        state.LocalPath,
+		Size:        state.Size,
+		MTime:       time.Unix(state.MTime, 0),
+		Hash:        state.Hash,
+		Attributes:  uint32(state.Attributes),
+		RemoteOwner: state.RemoteOwner,
+	}
+	if state.RemoteWriteTime != nil {
+		info.RemoteWriteTime = time.Unix(*state.RemoteWriteTime, 0)
+	}
+	if state.RemoteChangeTime != nil {
+		info.RemoteChangeTime = time.Unix(*state.RemoteChangeTime, 0)
+	}
+	if state.VerifiedAt != 0 {
+		info.VerifiedAt = time.Unix(state.VerifiedAt, 0)
+	}
+	return info
 }
 
 // UpdateCache updates the cache with current file state
@@ -97,7 +150,11 @@ func (cm *CacheManager) UpdateCache(jobID int64, localPath, remotePath string, i
 		SyncStatus: "idle",
 		CreatedAt:  now,
 		UpdatedAt:  now,
+		Attributes: int64(info.Attributes),
 	}
+	state.RemoteWriteTime, state.RemoteChangeTime = info.remoteTimes()
+	state.RemoteOwner = info.RemoteOwner
+	state.VerifiedAt = info.verifiedAt()
 
 	if err := cm.db.UpsertFileState(state); err != nil {
 		return fmt.Errorf("failed to update cache: %w", err)
@@ -137,7 +194,11 @@ func (cm *CacheManager) UpdateCacheBatch(jobID int64, updates map[string]*FileIn
 			SyncStatus: "idle",
 			CreatedAt:  now,
 			UpdatedAt:  now,
+			Attributes: int64(info.Attributes),
 		}
+		state.RemoteWriteTime, state.RemoteChangeTime = info.remoteTimes()
+		state.RemoteOwner = info.RemoteOwner
+		state.VerifiedAt = info.verifiedAt()
 		states = append(states, state)
 	}
 
@@ -220,8 +281,8 @@ func (cm *CacheManager) hasFileChanged(cached, current *FileInfo) bool {
 	}
 
 	// Modification time changed - likely modified
-	// Note: We use truncation to second precision as some filesystems don't support subsecond precision
-	if !cached.MTime.Truncate(time.Second).Equal(current.MTime.Truncate(time.Second)) {
+	// Note: FAT and some SMB servers store it to 2 seconds (see scanner.MTimeTolerance)
+	if !scanner.SameMTime(cached.MTime, current.MTime) {
 		return true
 	}
 
@@ -249,12 +310,7 @@ func (cm *CacheManager) GetAllCachedFiles(jobID int64) (map[string]*FileInfo, er
 		if state.LastSync == nil {
 			continue
 		}
-		result[state.LocalPath] = &FileInfo{
-			Path:  state.LocalPath,
-			Size:  state.Size,
-			MTime: time.Unix(state.MTime, 0),
-			Hash:  state.Hash,
-		}
+		result[state.LocalPath] = fileInfoFromState(state)
 	}
 
 	return result, nil
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +19,10 @@ const (
 	ActionConflict      SyncAction = "conflict"        // Conflict needs resolution
 	ActionDeleteLocal   SyncAction = "delete_local"    // Delete local file
 	ActionDeleteRemote  SyncAction = "delete_remote"   // Delete remote file
+	ActionSetAttrLocal  SyncAction = "attr_local"      // Apply remote attributes to local file
+	ActionSetAttrRemote SyncAction = "attr_remote"     // Apply local attributes to remote file
+	ActionRenameLocal   SyncAction = "rename_local"    // Move local file to the new remote path
+	ActionRenameRemote  SyncAction = "rename_remote"   // Move remote file to the new local path
 )
 
 // SyncDecision represents a sync decision for a file
@@ -30,6 +35,15 @@ type SyncDecision struct {
 	RemoteInfo  *FileInfo  // Current remote state
 	CachedInfo  *FileInfo  // Cached state
 	NeedsResolution bool   // True if requires user resolution
+
+	// Path the losing version of a conflict is kept at before being
+	// overwritten ("" = none)
+	ConflictCopy string
+
+	// Paths a renamed file is moved from (ActionRenameLocal/ActionRenameRemote),
+	// to LocalPath/RemotePath
+	OldLocalPath  string
+	OldRemotePath string
 }
 
 // ChangeDetector detects changes and determines sync actions
@@ -96,7 +110,7 @@ func (cd *ChangeDetector) decide3Way(local, remote, cached *FileInfo) (SyncActio
 		if localExists && remoteExists {
 			// Both created independently - conflict
 			if cd.filesAreSame(local, remote) {
-				return ActionNone, "file already in sync (identical content)"
+				return cd.inSync(local, remote, cached, "file already in sync (identical content)")
 			}
 			return ActionConflict, "file created on both sides with different content"
 		}
@@ -145,8 +159,8 @@ func (cd *ChangeDetector) decide3Way(local, remote, cached *FileInfo) (SyncActio
 		remoteChanged := !cd.filesAreSame(remote, cached)
 
 		if !localChanged && !remoteChanged {
-			// No changes anywhere
-			return ActionNone, "file unchanged"
+			// No content changes anywhere
+			return cd.inSync(local, remote, cached, "file unchanged")
 		}
 
 		if localChanged && !remoteChanged {
@@ -161,8 +175,8 @@ func (cd *ChangeDetector) decide3Way(local, remote, cached *FileInfo) (SyncActio
 
 		// Both changed - conflict
 		if cd.filesAreSame(local, remote) {
-			// Changed to same content - no action needed
-			return ActionNone, "file modified on both sides but content is identical"
+			// Changed to same content - no transfer needed
+			return cd.inSync(local, remote, cached, "file modified on both sides but content is identical")
 		}
 		return ActionConflict, "file modified on both sides with different content"
 	}
@@ -171,6 +185,15 @@ func (cd *ChangeDetector) decide3Way(local, remote, cached *FileInfo) (SyncActio
 	return ActionNone, "unexpected state"
 }
 
+// inSync is the decision for files whose content is in sync: ActionNone,
+// unless only attribute bits differ (see decideAttributes).
+func (cd *ChangeDetector) inSync(local, remote, cached *FileInfo, reason string) (SyncAction, string) {
+	if action, attrReason := cd.decideAttributes(local, remote, cached); action != ActionNone {
+		return action, attrReason
+	}
+	return ActionNone, reason
+}
+
 // filesAreSame checks if two files are the same
 // filesContentSame compares only content (size + hash if available), ignoring mtime.
 // This is used for deletion cases where mtime may differ but content is unchanged.
@@ -222,8 +245,8 @@ func (cd *ChangeDetector) filesAreSame(f1, f2 *FileInfo) bool {
 	}
 
 	// Neither has hash - compare size and mtime (fallback)
-	// Truncate to second precision for filesystem compatibility
-	return f1.MTime.Truncate(time.Second).Equal(f2.MTime.Truncate(time.Second))
+	// Within the FAT/SMB tolerance, or shifted by a time zone offset
+	return scanner.SameMTime(f1.MTime, f2.MTime) || scanner.ShiftedMTime(f1.MTime, f2.MTime)
 }
 
 // isFileRecreated checks if f1 appears to be a re-creation of f2.
@@ -292,6 +315,9 @@ func (cd *ChangeDetector) BatchDetermineSyncActions(jobID int64, files map[strin
 		}
 	}
 
+	// A file moved on one side is renamed on the other, not transferred again
+	decisions = detectRenames(decisions)
+
 	cd.logger.Info("batch sync decisions made",
 		zap.Int("total_paths", len(allPaths)),
 		zap.Int("actions_needed", len(decisions)))
@@ -319,7 +345,7 @@ func (cd *ChangeDetector) ResolveConflict(decision *SyncDecision, resolution str
 	case "recent":
 		// Keep most recent
 		if decision.LocalInfo != nil && decision.RemoteInfo != nil {
-			if decision.LocalInfo.MTime.After(decision.RemoteInfo.MTime) {
+			if scanner.NewerMTime(decision.LocalInfo.MTime, decision.RemoteInfo.MTime) {
 				decision.Action = ActionUpload
 				decision.Reason = "conflict resolved: local is more recent"
 			} else {
@@ -163,6 +163,33 @@ func TestChangeDetector_DetermineSyncAction(t *testing.T) {
 	}
 }
 
+func TestChangeDetector_FilesAreSameMTimeQuirks(t *testing.T) {
+	cd := NewChangeDetector(nil, zap.NewNop())
+	now := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		mtime  time.Time
+		expect bool
+	}{
+		{"rounded to 2 seconds", now.Add(1500 * time.Millisecond), true},
+		{"shifted by daylight saving", now.Add(-time.Hour), true},
+		{"shifted by a time zone offset", now.Add(2*time.Hour + time.Second), true},
+		{"modified later", now.Add(10 * time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without hashes, only the size and mtime tell
+			a := &FileInfo{Size: 100, MTime: now}
+			b := &FileInfo{Size: 100, MTime: tt.mtime}
+			if got := cd.filesAreSame(a, b); got != tt.expect {
+				t.Errorf("filesAreSame = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
 func TestChangeDetector_ResolveConflict(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -0,0 +1,118 @@
+package cache
+
+import "fmt"
+
+// detectRenames replaces the deletion of a file and the transfer of a new one
+// with the same content by a rename of the file on the other side, so a moved
+// or renamed file is not transferred again:
+//   - moved locally: the old path is deleted locally (ActionDeleteRemote) and
+//     a new local file has the same size and hash as the cached old one;
+//   - moved on the server: the old path is gone from the server
+//     (ActionDeleteLocal) and a new remote file has the same size and
+//     LastWriteTime (kept by SMB renames) as recorded for the old one. Remote
+//     files have no hash, so files whose remote LastWriteTime was never
+//     recorded (uploaded by this client) are still downloaded.
+//
+// Only a deletion and a new file that match each other and nothing else are
+// paired: copies and files with the same content stay separate actions.
+func detectRenames(decisions []*SyncDecision) []*SyncDecision {
+	removed := make(map[string][]int)
+	added := make(map[string][]int)
+	for i, d := range decisions {
+		if key, ok := renameSourceKey(d); ok {
+			removed[key] = append(removed[key], i)
+		} else if key, ok := renameTargetKey(d); ok {
+			added[key] = append(added[key], i)
+		}
+	}
+
+	renamed := make(map[int]*SyncDecision) // Index of the new file -> rename
+	dropped := make(map[int]bool)          // Index of the deletion
+	for key, sources := range removed {
+		targets := added[key]
+		if len(sources) != 1 || len(targets) != 1 {
+			continue
+		}
+		source, target := decisions[sources[0]], decisions[targets[0]]
+		renamed[targets[0]] = renameDecision(source, target)
+		dropped[sources[0]] = true
+	}
+	if len(renamed) == 0 {
+		return decisions
+	}
+
+	result := make([]*SyncDecision, 0, len(decisions)-len(dropped))
+	for i, d := range decisions {
+		if dropped[i] {
+			continue
+		}
+		if rename, ok := renamed[i]; ok {
+			d = rename
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// renameSourceKey returns the key matching a deleted file to its new path,
+// with the side it was moved on first ("local" or "remote").
+func renameSourceKey(d *SyncDecision) (string, bool) {
+	cached := d.CachedInfo
+	if cached == nil || cached.Size == 0 {
+		return "", false
+	}
+	switch d.Action {
+	case ActionDeleteRemote:
+		if cached.Hash != "" {
+			return fmt.Sprintf("local|%d|%s", cached.Size, cached.Hash), true
+		}
+	case ActionDeleteLocal:
+		if !cached.RemoteWriteTime.IsZero() {
+			return fmt.Sprintf("remote|%d|%d", cached.Size, cached.RemoteWriteTime.Unix()), true
+		}
+	}
+	return "", false
+}
+
+// renameTargetKey returns the key of a new file that may be a moved one (see
+// renameSourceKey).
+func renameTargetKey(d *SyncDecision) (string, bool) {
+	if d.CachedInfo != nil {
+		return "", false
+	}
+	switch d.Action {
+	case ActionUpload:
+		if local := d.LocalInfo; local != nil && d.RemoteInfo == nil && local.Size > 0 && local.Hash != "" {
+			return fmt.Sprintf("local|%d|%s", local.Size, local.Hash), true
+		}
+	case ActionDownload:
+		if remote := d.RemoteInfo; remote != nil && d.LocalInfo == nil && remote.Size > 0 && !remote.RemoteWriteTime.IsZero() {
+			return fmt.Sprintf("remote|%d|%d", remote.Size, remote.RemoteWriteTime.Unix()), true
+		}
+	}
+	return "", false
+}
+
+// renameDecision returns the rename of the file deleted by source to the path
+// of target, on the side it was not moved on yet.
+func renameDecision(source, target *SyncDecision) *SyncDecision {
+	d := &SyncDecision{
+		LocalPath:     target.LocalPath,
+		RemotePath:    target.RemotePath,
+		LocalInfo:     target.LocalInfo,
+		RemoteInfo:    target.RemoteInfo,
+		CachedInfo:    source.CachedInfo,
+		OldLocalPath:  source.LocalPath,
+		OldRemotePath: source.RemotePath,
+	}
+	if source.Action == ActionDeleteRemote {
+		d.Action = ActionRenameRemote
+		d.Reason = fmt.Sprintf("file moved locally from %s", source.LocalPath)
+		d.RemoteInfo = source.RemoteInfo
+	} else {
+		d.Action = ActionRenameLocal
+		d.Reason = fmt.Sprintf("file moved remotely from %s", source.RemotePath)
+		d.LocalInfo = source.LocalInfo
+	}
+	return d
+}
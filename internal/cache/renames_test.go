@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectRenames(t *testing.T) {
+	written := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cached := func(size int64, hash string) *FileInfo {
+		return &FileInfo{Size: size, Hash: hash, RemoteWriteTime: written}
+	}
+	local := func(size int64, hash string) *FileInfo {
+		return &FileInfo{Size: size, Hash: hash}
+	}
+	remote := func(size int64, writeTime time.Time) *FileInfo {
+		return &FileInfo{Size: size, RemoteWriteTime: writeTime}
+	}
+
+	decisions := []*SyncDecision{
+		// Moved locally
+		{LocalPath: "old/report.docx", RemotePath: "old/report.docx", Action: ActionDeleteRemote,
+			RemoteInfo: remote(100, written), CachedInfo: cached(100, "aaa")},
+		{LocalPath: "new/report.docx", RemotePath: "new/report.docx", Action: ActionUpload,
+			LocalInfo: local(100, "aaa")},
+		// Moved on the server
+		{LocalPath: "photo.jpg", RemotePath: "photo.jpg", Action: ActionDeleteLocal,
+			LocalInfo: local(200, "bbb"), CachedInfo: cached(200, "bbb")},
+		{LocalPath: "2025/photo.jpg", RemotePath: "2025/photo.jpg", Action: ActionDownload,
+			RemoteInfo: remote(200, written)},
+		// Two copies of the same content: not paired
+		{LocalPath: "a.txt", RemotePath: "a.txt", Action: ActionDeleteRemote, CachedInfo: cached(300, "ccc")},
+		{LocalPath: "b.txt", RemotePath: "b.txt", Action: ActionUpload, LocalInfo: local(300, "ccc")},
+		{LocalPath: "c.txt", RemotePath: "c.txt", Action: ActionUpload, LocalInfo: local(300, "ccc")},
+		// Same size, other content: not paired
+		{LocalPath: "d.txt", RemotePath: "d.txt", Action: ActionDeleteRemote, CachedInfo: cached(400, "ddd")},
+		{LocalPath: "e.txt", RemotePath: "e.txt", Action: ActionUpload, LocalInfo: local(400, "eee")},
+		// Same size on the server but another LastWriteTime: not paired
+		{LocalPath: "f.txt", RemotePath: "f.txt", Action: ActionDeleteLocal,
+			LocalInfo: local(500, "fff"), CachedInfo: cached(500, "fff")},
+		{LocalPath: "g.txt", RemotePath: "g.txt", Action: ActionDownload,
+			RemoteInfo: remote(500, written.Add(time.Hour))},
+	}
+
+	got := detectRenames(decisions)
+	actions := make(map[string]SyncAction)
+	for _, d := range got {
+		actions[d.LocalPath] = d.Action
+	}
+	want := map[string]SyncAction{
+		"new/report.docx": ActionRenameRemote,
+		"2025/photo.jpg":  ActionRenameLocal,
+		"a.txt":           ActionDeleteRemote,
+		"b.txt":           ActionUpload,
+		"c.txt":           ActionUpload,
+		"d.txt":           ActionDeleteRemote,
+		"e.txt":           ActionUpload,
+		"f.txt":           ActionDeleteLocal,
+		"g.txt":           ActionDownload,
+	}
+	if len(actions) != len(want) || len(got) != len(want) {
+		t.Fatalf("decisions = %v, want %v", actions, want)
+	}
+	for path, action := range want {
+		if actions[path] != action {
+			t.Errorf("%s: action = %s, want %s", path, actions[path], action)
+		}
+	}
+
+	for _, d := range got {
+		switch d.Action {
+		case ActionRenameRemote:
+			if d.OldRemotePath != "old/report.docx" || d.RemoteInfo == nil || d.CachedInfo.Hash != "aaa" {
+				t.Errorf("remote rename = %+v", d)
+			}
+		case ActionRenameLocal:
+			if d.OldLocalPath != "photo.jpg" || d.LocalInfo == nil || d.LocalInfo.Hash != "bbb" {
+				t.Errorf("local rename = %+v", d)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package clock abstracts the current time and timers. The sync engine, the
+// file watcher and the scheduler read the time through a Clock, so tests can
+// run them on a Fake clock: clock skew, DST transitions and intervals of days
+// are simulated deterministically, without waiting.
+package clock
+
+import "time"
+
+// Clock tells the time and runs timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel receiving the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc calls f in its own goroutine once d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer started by AfterFunc.
+type Timer interface {
+	// Stop prevents the timer from firing. It returns false if the timer
+	// already fired or was stopped.
+	Stop() bool
+	// Reset makes the timer fire once d has elapsed from now. It returns
+	// whether the timer was active.
+	Reset(d time.Duration) bool
+}
+
+// System is the clock of the computer.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Or returns c, or System when c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
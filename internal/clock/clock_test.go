@@ -0,0 +1,98 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_Advance(t *testing.T) {
+	start := time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	var fired []time.Time
+	c.AfterFunc(2*time.Hour, func() { fired = append(fired, c.Now()) })
+	c.AfterFunc(time.Hour, func() {
+		fired = append(fired, c.Now())
+		// Timers started by a timer fire within the same Advance
+		c.AfterFunc(30*time.Minute, func() { fired = append(fired, c.Now()) })
+	})
+	stopped := c.AfterFunc(90*time.Minute, func() { t.Error("stopped timer fired") })
+	if !stopped.Stop() {
+		t.Error("Stop of an active timer returned false")
+	}
+	if c.Pending() != 2 {
+		t.Errorf("Pending() = %d, want 2", c.Pending())
+	}
+
+	c.Advance(3 * time.Hour)
+
+	want := []time.Time{start.Add(time.Hour), start.Add(90 * time.Minute), start.Add(2 * time.Hour)}
+	if len(fired) != len(want) {
+		t.Fatalf("fired at %v, want %v", fired, want)
+	}
+	for i := range want {
+		if !fired[i].Equal(want[i]) {
+			t.Errorf("timer %d fired at %s, want %s", i, fired[i], want[i])
+		}
+	}
+	if got := c.Now(); !got.Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("Now() = %s, want %s", got, start.Add(3*time.Hour))
+	}
+	if c.Pending() != 0 {
+		t.Errorf("Pending() = %d after all timers fired", c.Pending())
+	}
+}
+
+func TestFake_SetDoesNotFireTimers(t *testing.T) {
+	start := time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	ch := c.After(time.Minute)
+
+	// The wall clock jumps an hour back (clock corrected): the timer still
+	// waits for a minute to elapse
+	c.Set(start.Add(-time.Hour))
+	select {
+	case <-ch:
+		t.Fatal("timer fired when the wall clock was set")
+	default:
+	}
+
+	c.Advance(time.Minute)
+	select {
+	case got := <-ch:
+		if want := start.Add(-time.Hour + time.Minute); !got.Equal(want) {
+			t.Errorf("After delivered %s, want %s", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+}
+
+func TestFake_ResetAndDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// 2025-03-30 02:00 CET: clocks jump to 03:00 CEST
+	c := NewFake(time.Date(2025, 3, 30, 1, 30, 0, 0, paris))
+
+	fired := false
+	timer := c.AfterFunc(time.Hour, func() { fired = true })
+	c.Advance(30 * time.Minute)
+	if !timer.Reset(time.Hour) {
+		t.Error("Reset of an active timer returned false")
+	}
+	c.Advance(59 * time.Minute)
+	if fired {
+		t.Fatal("timer fired before its reset deadline")
+	}
+	c.Advance(time.Minute)
+	if !fired {
+		t.Fatal("timer did not fire at its reset deadline")
+	}
+
+	// 1h30 elapsed across the transition: the wall clock reads 04:00
+	if got := c.Now(); got.Hour() != 4 || got.Minute() != 0 {
+		t.Errorf("Now() = %s, want 04:00 CEST", got)
+	}
+}
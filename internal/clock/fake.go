@@ -0,0 +1,143 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a clock that only moves when told to. Advancing it fires the
+// timers due on the way, earliest first, on the goroutine advancing it: once
+// Advance returns, their functions have run. It is safe for concurrent use.
+//
+// Like the system clock, it has a wall clock that can be set (Set) and
+// timers that measure elapsed time (Advance) and ignore such changes.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time     // Wall clock
+	elapsed time.Duration // Time advanced since the clock was created
+	timers  []*fakeTimer  // Active timers
+}
+
+// NewFake returns a fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time of the clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel receiving the time once the clock advanced d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	f.AfterFunc(d, func() { ch <- f.Now() })
+	return ch
+}
+
+// AfterFunc calls fn once the clock advanced d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// Advance lets d elapse, firing the timers due on the way. While the
+// function of a timer runs, the clock reads the time the timer was due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.elapsed + d
+	f.mu.Unlock()
+
+	for {
+		f.mu.Lock()
+		next := f.nextDueLocked(target)
+		if next == nil {
+			f.now = f.now.Add(target - f.elapsed)
+			f.elapsed = target
+			f.mu.Unlock()
+			return
+		}
+		f.removeLocked(next)
+		if next.at > f.elapsed {
+			f.now = f.now.Add(next.at - f.elapsed)
+			f.elapsed = next.at
+		}
+		f.mu.Unlock()
+
+		next.fn()
+	}
+}
+
+// Set sets the wall clock to t, like the user or a time synchronization
+// changing the time of the computer. Timers are not affected.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Pending returns the number of timers waiting to fire, so a test can wait
+// for the code under test to start its timer before advancing the clock.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// nextDueLocked returns the active timer due first, if due by elapsed time
+// at, or nil. The caller holds f.mu.
+func (f *Fake) nextDueLocked(at time.Duration) *fakeTimer {
+	var next *fakeTimer
+	for _, timer := range f.timers {
+		if timer.at <= at && (next == nil || timer.at < next.at) {
+			next = timer
+		}
+	}
+	return next
+}
+
+// removeLocked deactivates a timer and reports whether it was active. The
+// caller holds f.mu.
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, timer := range f.timers {
+		if timer == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer is a timer of a Fake clock.
+type fakeTimer struct {
+	clock *Fake
+	fn    func()
+	at    time.Duration // Elapsed time of the clock the timer is due at
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+// Reset rearms the timer. A timer already due (d <= 0) fires at once in its
+// own goroutine, as the caller may hold locks its function takes.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	wasActive := f.removeLocked(t)
+	t.at = f.elapsed + d
+	if d > 0 {
+		f.timers = append(f.timers, t)
+	}
+	f.mu.Unlock()
+
+	if d <= 0 {
+		go t.fn()
+	}
+	return wasActive
+}
@@ -13,6 +13,11 @@
 #define E_OUTOFMEMORY ((HRESULT)0x8007000EL)
 #endif
 
+// NTSTATUS returned to Windows to veto a dehydration
+#ifndef STATUS_CLOUD_FILE_DEHYDRATION_DISALLOWED
+#define STATUS_CLOUD_FILE_DEHYDRATION_DISALLOWED ((LONG)0xC000CF18L)
+#endif
+
 // --- Debug logging ---
 static int g_debugLogging = 0; // Disabled by default (set to 1 for troubleshooting)
 
@@ -141,12 +146,20 @@ typedef struct {
     LPCWSTR TargetPath;
 } CF_CALLBACK_PARAMETERS_RENAME;
 
+// Dehydrate and DehydrateCompletion share the same layout
+typedef struct {
+    DWORD Flags;
+    DWORD Reason;
+} CF_CALLBACK_PARAMETERS_DEHYDRATE;
+
 typedef struct {
     DWORD ParamSize;
     union {
         CF_CALLBACK_PARAMETERS_FETCHDATA FetchData;
         CF_CALLBACK_PARAMETERS_DELETE Delete;
         CF_CALLBACK_PARAMETERS_RENAME Rename;
+        CF_CALLBACK_PARAMETERS_DEHYDRATE Dehydrate;
+        CF_CALLBACK_PARAMETERS_DEHYDRATE DehydrateCompletion;
         BYTE Reserved[64];
     };
 } CF_CALLBACK_PARAMETERS;
@@ -193,11 +206,20 @@ typedef struct {
     LARGE_INTEGER Length;
 } CF_OPERATION_ACK_DATA_PARAMS;
 
+// Operation parameters for ack dehydrate
+typedef struct {
+    DWORD Flags;
+    LONG CompletionStatus;          // NTSTATUS - failure vetoes the dehydration
+    LPCVOID FileIdentity;
+    DWORD FileIdentityLength;
+} CF_OPERATION_ACK_DEHYDRATE_PARAMS;
+
 typedef struct {
     DWORD ParamSize;
     union {
         CF_OPERATION_TRANSFER_DATA_PARAMS TransferData;
         CF_OPERATION_ACK_DATA_PARAMS AckData;
+        CF_OPERATION_ACK_DEHYDRATE_PARAMS AckDehydrate;
         BYTE Reserved[128];
     };
 } CF_OPERATION_PARAMETERS;
@@ -537,24 +559,83 @@ static void CALLBACK OnNotifyFileCloseCompletionCallback(
     // Info only - no action needed
 }
 
-// NOTIFY_DEHYDRATE callback - file is being dehydrated
+// NOTIFY_DEHYDRATE callback - file is about to be dehydrated (user, Storage Sense, OS)
+// Windows waits for an ACK_DEHYDRATE: Go decides whether to allow it and
+// calls CfapiBridgeAckDehydrate, like TransferData for FETCH_DATA.
 static void CALLBACK OnNotifyDehydrateCallback(
     const CF_CALLBACK_INFO* callbackInfo,
     const CF_CALLBACK_PARAMETERS* callbackParameters
 ) {
-    (void)callbackParameters;
     PrintCallbackInfo("NOTIFY_DEHYDRATE", callbackInfo);
-    // Info only - no action needed
+
+    CfapiBridgeRequest req;
+    memset(&req, 0, sizeof(req));
+
+    req.type = CFAPI_CALLBACK_NOTIFY_DEHYDRATE;
+    req.connectionKey = (int64_t)callbackInfo->ConnectionKey;
+    req.transferKey = (int64_t)callbackInfo->TransferKey;
+    req.requestKey = (int64_t)callbackInfo->RequestKey;
+
+    if (callbackInfo->NormalizedPath) {
+        wcsncpy(req.filePath, callbackInfo->NormalizedPath, CFAPI_BRIDGE_MAX_PATH - 1);
+        req.filePath[CFAPI_BRIDGE_MAX_PATH - 1] = L'\0';
+    }
+
+    // The file identity must be passed back in the ACK
+    if (callbackInfo->FileIdentity && callbackInfo->FileIdentityLength <= sizeof(req.fileIdentity)) {
+        memcpy(req.fileIdentity, callbackInfo->FileIdentity, callbackInfo->FileIdentityLength);
+        req.fileIdentityLength = (int32_t)callbackInfo->FileIdentityLength;
+    }
+
+    if (callbackParameters && callbackParameters->ParamSize >= sizeof(DWORD) + sizeof(CF_CALLBACK_PARAMETERS_DEHYDRATE)) {
+        req.dehydrateFlags = (int32_t)callbackParameters->Dehydrate.Flags;
+        req.dehydrateReason = (int32_t)callbackParameters->Dehydrate.Reason;
+        DebugLog("  Reason: %d, Flags: 0x%x", req.dehydrateReason, req.dehydrateFlags);
+    }
+
+    // If Go can't be asked, keep the data: veto rather than risk losing local changes
+    if (!g_initialized || EnqueueRequest(&req) != CFAPI_BRIDGE_OK) {
+        DebugLog("ERROR: Failed to enqueue NOTIFY_DEHYDRATE, vetoing");
+        CfapiBridgeAckDehydrate(req.connectionKey, req.transferKey, req.requestKey,
+                                req.fileIdentityLength > 0 ? req.fileIdentity : NULL,
+                                req.fileIdentityLength, 0);
+        return;
+    }
+
+    DebugLog("NOTIFY_DEHYDRATE enqueued for Go to decide");
 }
 
-// NOTIFY_DEHYDRATE_COMPLETION callback - file dehydration completed
+// NOTIFY_DEHYDRATE_COMPLETION callback - file dehydration completed (or failed)
 static void CALLBACK OnNotifyDehydrateCompletionCallback(
     const CF_CALLBACK_INFO* callbackInfo,
     const CF_CALLBACK_PARAMETERS* callbackParameters
 ) {
-    (void)callbackParameters;
     PrintCallbackInfo("NOTIFY_DEHYDRATE_COMPLETION", callbackInfo);
-    // Info only - no action needed
+
+    if (!g_initialized) {
+        DebugLog("ERROR: Bridge not initialized!");
+        return;
+    }
+
+    CfapiBridgeRequest req;
+    memset(&req, 0, sizeof(req));
+
+    req.type = CFAPI_CALLBACK_NOTIFY_DEHYDRATE_COMPLETION;
+    req.connectionKey = (int64_t)callbackInfo->ConnectionKey;
+    req.transferKey = (int64_t)callbackInfo->TransferKey;
+
+    if (callbackInfo->NormalizedPath) {
+        wcsncpy(req.filePath, callbackInfo->NormalizedPath, CFAPI_BRIDGE_MAX_PATH - 1);
+        req.filePath[CFAPI_BRIDGE_MAX_PATH - 1] = L'\0';
+    }
+
+    if (callbackParameters && callbackParameters->ParamSize >= sizeof(DWORD) + sizeof(CF_CALLBACK_PARAMETERS_DEHYDRATE)) {
+        req.dehydrateFlags = (int32_t)callbackParameters->DehydrateCompletion.Flags;
+        req.dehydrateReason = (int32_t)callbackParameters->DehydrateCompletion.Reason;
+    }
+
+    EnqueueRequest(&req);
+    DebugLog("NOTIFY_DEHYDRATE_COMPLETION enqueued");
 }
 
 // NOTIFY_RENAME callback - file is being renamed
@@ -1082,6 +1163,47 @@ int32_t CfapiBridgeReportProgress(
     return CFAPI_BRIDGE_OK;
 }
 
+int32_t CfapiBridgeAckDehydrate(
+    int64_t connectionKey,
+    int64_t transferKey,
+    int64_t requestKey,
+    const void* fileIdentity,
+    int32_t fileIdentityLength,
+    int32_t allow
+) {
+    DebugLog("CfapiBridgeAckDehydrate: connKey=%lld, transKey=%lld, reqKey=%lld, allow=%d",
+             (long long)connectionKey, (long long)transferKey, (long long)requestKey, allow);
+
+    if (!g_pfnCfExecute) {
+        DebugLog("ERROR: CfExecute not loaded");
+        return CFAPI_BRIDGE_ERROR_NOT_INITIALIZED;
+    }
+
+    CF_OPERATION_INFO opInfo;
+    memset(&opInfo, 0, sizeof(opInfo));
+    opInfo.StructSize = sizeof(CF_OPERATION_INFO);
+    opInfo.Type = CF_OPERATION_TYPE_ACK_DEHYDRATE;
+    opInfo.ConnectionKey = (CF_CONNECTION_KEY)connectionKey;
+    opInfo.TransferKey = (CF_TRANSFER_KEY)transferKey;
+    opInfo.RequestKey = (LONGLONG)requestKey;
+
+    CF_OPERATION_PARAMETERS opParams;
+    memset(&opParams, 0, sizeof(opParams));
+    opParams.ParamSize = sizeof(CF_OPERATION_PARAMETERS);
+    opParams.AckDehydrate.Flags = 0;
+    opParams.AckDehydrate.CompletionStatus = allow ? 0 : STATUS_CLOUD_FILE_DEHYDRATION_DISALLOWED;
+    opParams.AckDehydrate.FileIdentity = fileIdentity;
+    opParams.AckDehydrate.FileIdentityLength = (DWORD)fileIdentityLength;
+
+    HRESULT hr = g_pfnCfExecute(&opInfo, &opParams);
+    if (FAILED(hr)) {
+        DebugLog("ERROR: CfExecute (AckDehydrate) FAILED: HRESULT=0x%08lX", hr);
+        return CFAPI_BRIDGE_ERROR_API_FAILED;
+    }
+
+    return CFAPI_BRIDGE_OK;
+}
+
 int32_t CfapiBridgeIsInitialized(void) {
     return g_initialized;
 }
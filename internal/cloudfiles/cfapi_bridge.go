@@ -21,6 +21,7 @@ import (
 	"syscall"
 	"unsafe"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
 	"go.uber.org/zap"
 )
 
@@ -36,6 +37,8 @@ var (
 	globalSyncRootPath       string
 	globalHydrationLogger    *zap.Logger
 	globalProgressCallback   func(path string, total, completed int64)
+	globalReadAheadDepth     = DefaultReadAheadDepth
+	globalHydrationStreams   = newHydrationStreamCache()
 )
 
 // SetGlobalDataProvider sets the global data provider for CGO callbacks.
@@ -45,6 +48,7 @@ func SetGlobalDataProvider(provider DataProvider, syncRootPath string, logger *z
 	defer globalDataProviderMu.Unlock()
 	globalDataProvider = provider
 	globalSyncRootPath = syncRootPath
+	globalHydrationStreams.closeAll()
 	if logger != nil {
 		globalHydrationLogger = logger
 	} else {
@@ -66,6 +70,18 @@ func ClearGlobalDataProvider() {
 	globalDataProvider = nil
 	globalSyncRootPath = ""
 	globalProgressCallback = nil
+	globalHydrationStreams.closeAll()
+}
+
+// SetReadAheadDepth sets how many chunks are prefetched from the server while
+// a file is hydrated (0 = no read-ahead).
+func SetReadAheadDepth(depth int) {
+	globalDataProviderMu.Lock()
+	defer globalDataProviderMu.Unlock()
+	if depth < 0 {
+		depth = 0
+	}
+	globalReadAheadDepth = depth
 }
 
 // ============================================================================
@@ -78,6 +94,10 @@ func ClearGlobalDataProvider() {
 // handleSharedFetchRequest processes a pending fetch request from C.
 // Called by processLoop when requestReadyEvent is signaled.
 func handleSharedFetchRequest(logger *zap.Logger) {
+	// Tag the logs of this request so they can be told apart (see HandleFetchData)
+	ctx := correlation.WithOpID(context.Background(), correlation.NewOpID())
+	logger = correlation.Logger(ctx, logger)
+
 	// Get pointer to shared request structure
 	sharedReq := C.CfapiBridgeGetPendingRequest()
 	if sharedReq == nil {
@@ -93,6 +113,7 @@ func handleSharedFetchRequest(logger *zap.Logger) {
 	globalDataProviderMu.RLock()
 	provider := globalDataProvider
 	syncRootPath := globalSyncRootPath
+	readAheadDepth := globalReadAheadDepth
 	globalDataProviderMu.RUnlock()
 
 	if provider == nil {
@@ -121,20 +142,23 @@ func handleSharedFetchRequest(logger *zap.Logger) {
 
 	// Debug logging removed - enable in C bridge (g_debugLogging=1) for troubleshooting
 
-	// Get reader from data provider
-	ctx := context.Background()
-	reader, err := provider.GetFileReader(ctx, relativePath, offset)
-	if err != nil {
-		logger.Error("handleSharedFetchRequest: failed to get file reader",
-			zap.String("path", relativePath),
-			zap.Error(err),
-		)
-		sharedReq.errorCode = -2
-		sharedReq.dataLength = 0
-		C.CfapiBridgeSignalDataReady()
-		return
+	// Reuse the stream of the previous chunk of this file (read-ahead keeps
+	// the next chunks coming while this one is delivered), or open a new one
+	reader := globalHydrationStreams.take(relativePath, offset)
+	if reader == nil {
+		source, err := provider.GetFileReader(ctx, relativePath, offset)
+		if err != nil {
+			logger.Error("handleSharedFetchRequest: failed to get file reader",
+				zap.String("path", relativePath),
+				zap.Error(err),
+			)
+			sharedReq.errorCode = -2
+			sharedReq.dataLength = 0
+			C.CfapiBridgeSignalDataReady()
+			return
+		}
+		reader = newReadAheadReader(source, C.CFAPI_BRIDGE_MAX_CHUNK_SIZE, readAheadDepth)
 	}
-	defer reader.Close()
 
 	// Read data into shared buffer
 	bufferPtr := (*[C.CFAPI_BRIDGE_MAX_CHUNK_SIZE]byte)(unsafe.Pointer(&sharedReq.data[0]))
@@ -145,6 +169,7 @@ func handleSharedFetchRequest(logger *zap.Logger) {
 
 	n, err := io.ReadFull(reader, bufferPtr[:toRead])
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		reader.Close()
 		logger.Error("handleSharedFetchRequest: failed to read data",
 			zap.String("path", relativePath),
 			zap.Error(err),
@@ -155,6 +180,13 @@ func handleSharedFetchRequest(logger *zap.Logger) {
 		return
 	}
 
+	// Keep the stream for the next chunk unless the file is fully read
+	if err == nil {
+		globalHydrationStreams.put(relativePath, reader, offset+int64(n))
+	} else {
+		reader.Close()
+	}
+
 	sharedReq.errorCode = 0
 	sharedReq.dataLength = C.int64_t(n)
 
@@ -237,6 +269,14 @@ type BridgeHandlers struct {
 	// OnNotifyRename is called when a file is being renamed.
 	// Return true to allow the rename, false to block it.
 	OnNotifyRename func(sourcePath, targetPath string, isDirectory bool) bool
+
+	// OnNotifyDehydrate is called before Windows dehydrates a placeholder
+	// (user action, Storage Sense, OS upgrade).
+	// Return true to allow the dehydration, false to veto it.
+	OnNotifyDehydrate func(filePath string, reason DehydrationReason) bool
+
+	// OnDehydrateComplete is called after a dehydration attempt.
+	OnDehydrateComplete func(filePath string, reason DehydrationReason, dehydrated bool)
 }
 
 // BridgeFetchDataRequest contains information about a hydration request.
@@ -534,6 +574,12 @@ func (b *BridgeManager) dispatchRequest(req *C.CfapiBridgeRequest) {
 	case C.CFAPI_CALLBACK_NOTIFY_RENAME:
 		b.handleNotifyRename(req, handlers.OnNotifyRename)
 
+	case C.CFAPI_CALLBACK_NOTIFY_DEHYDRATE:
+		b.handleNotifyDehydrate(req, handlers.OnNotifyDehydrate)
+
+	case C.CFAPI_CALLBACK_NOTIFY_DEHYDRATE_COMPLETION:
+		b.handleDehydrateComplete(req, handlers.OnDehydrateComplete)
+
 	default:
 		b.logger.Warn("unknown callback type", zap.Int32("type", int32(req._type)))
 	}
@@ -614,6 +660,48 @@ func (b *BridgeManager) handleNotifyRename(req *C.CfapiBridgeRequest, handler fu
 	}
 }
 
+// handleNotifyDehydrate handles a NOTIFY_DEHYDRATE callback.
+// Windows waits for the ACK_DEHYDRATE, which vetoes the dehydration if the
+// handler refuses it. Without a handler, dehydration is allowed.
+func (b *BridgeManager) handleNotifyDehydrate(req *C.CfapiBridgeRequest, handler func(string, DehydrationReason) bool) {
+	filePath := wcharToString((*uint16)(unsafe.Pointer(&req.filePath[0])))
+	reason := DehydrationReason(req.dehydrateReason)
+
+	allow := true
+	if handler != nil {
+		allow = handler(filePath, reason)
+	}
+
+	var identity unsafe.Pointer
+	if req.fileIdentityLength > 0 {
+		identity = unsafe.Pointer(&req.fileIdentity[0])
+	}
+
+	allowFlag := C.int32_t(0)
+	if allow {
+		allowFlag = 1
+	}
+
+	result := C.CfapiBridgeAckDehydrate(req.connectionKey, req.transferKey, req.requestKey,
+		identity, req.fileIdentityLength, allowFlag)
+	if result != C.CFAPI_BRIDGE_OK {
+		b.logger.Error("failed to acknowledge dehydration",
+			zap.String("path", filePath),
+			zap.Bool("allow", allow),
+			zap.Int("result", int(result)),
+		)
+	}
+}
+
+// handleDehydrateComplete handles a NOTIFY_DEHYDRATE_COMPLETION callback.
+func (b *BridgeManager) handleDehydrateComplete(req *C.CfapiBridgeRequest, handler func(string, DehydrationReason, bool)) {
+	filePath := wcharToString((*uint16)(unsafe.Pointer(&req.filePath[0])))
+	dehydrated := req.dehydrateFlags&C.int32_t(CF_CALLBACK_DEHYDRATE_COMPLETION_FLAG_DEHYDRATED) != 0
+	if handler != nil {
+		handler(filePath, DehydrationReason(req.dehydrateReason), dehydrated)
+	}
+}
+
 // wcharToString converts a null-terminated wide string to a Go string.
 func wcharToString(ptr *uint16) string {
 	if ptr == nil {
@@ -20,6 +20,8 @@
 typedef enum {
     CFAPI_CALLBACK_FETCH_DATA = 0,
     CFAPI_CALLBACK_CANCEL_FETCH_DATA = 2,
+    CFAPI_CALLBACK_NOTIFY_DEHYDRATE = 7,
+    CFAPI_CALLBACK_NOTIFY_DEHYDRATE_COMPLETION = 8,
     CFAPI_CALLBACK_NOTIFY_DELETE = 9,
     CFAPI_CALLBACK_NOTIFY_RENAME = 11,
 } CfapiBridgeCallbackType;
@@ -37,6 +39,10 @@ typedef struct {
     wchar_t targetPath[CFAPI_BRIDGE_MAX_PATH]; // Target path (for NOTIFY_RENAME)
     int32_t isDirectory;                    // Is this a directory operation
     void* completionEvent;                  // Event to signal when transfer is done (for sync callbacks)
+    int32_t dehydrateReason;                // CF_CALLBACK_DEHYDRATION_REASON (for NOTIFY_DEHYDRATE*)
+    int32_t dehydrateFlags;                 // CF_CALLBACK_DEHYDRATE[_COMPLETION]_FLAGS
+    int32_t fileIdentityLength;             // Length of fileIdentity (for NOTIFY_DEHYDRATE ack)
+    uint8_t fileIdentity[CFAPI_BRIDGE_MAX_PATH * 2]; // Placeholder file identity blob
 } CfapiBridgeRequest;
 
 // Response from Go for FETCH_DATA - contains a chunk of data
@@ -185,6 +191,23 @@ int32_t CfapiBridgeReportProgress(
     int64_t completed
 );
 
+// Acknowledge a NOTIFY_DEHYDRATE callback, allowing or vetoing the dehydration
+// connectionKey: the connection key
+// transferKey: the transfer key from the request
+// requestKey: the request key from the callback
+// fileIdentity: the placeholder file identity from the request (may be NULL)
+// fileIdentityLength: length of fileIdentity in bytes
+// allow: 1 to let Windows dehydrate the file, 0 to veto it
+// Returns CFAPI_BRIDGE_OK on success
+int32_t CfapiBridgeAckDehydrate(
+    int64_t connectionKey,
+    int64_t transferKey,
+    int64_t requestKey,
+    const void* fileIdentity,
+    int32_t fileIdentityLength,
+    int32_t allow
+);
+
 // Check if the bridge is initialized
 // Returns 1 if initialized, 0 otherwise
 int32_t CfapiBridgeIsInitialized(void);
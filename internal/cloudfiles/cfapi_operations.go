@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"unsafe"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
 	"golang.org/x/sys/windows"
 )
 
@@ -212,7 +213,7 @@ func OpenFileWithOplock(filePath string, flags CF_OPEN_FILE_FLAGS) (windows.Hand
 		return 0, fmt.Errorf("CfOpenFileWithOplock not available: %w", err)
 	}
 
-	pathPtr, err := windows.UTF16PtrFromString(filePath)
+	pathPtr, err := windows.UTF16PtrFromString(longpath.Fix(filePath))
 	if err != nil {
 		return 0, fmt.Errorf("invalid file path: %w", err)
 	}
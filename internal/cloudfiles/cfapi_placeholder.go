@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"unsafe"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
 	"golang.org/x/sys/windows"
 )
 
@@ -22,7 +23,7 @@ func CreatePlaceholders(basePath string, placeholders []CF_PLACEHOLDER_CREATE_IN
 		return nil
 	}
 
-	pathPtr, err := windows.UTF16PtrFromString(basePath)
+	pathPtr, err := windows.UTF16PtrFromString(longpath.Fix(basePath))
 	if err != nil {
 		return fmt.Errorf("invalid base path: %w", err)
 	}
@@ -103,6 +104,41 @@ func DehydratePlaceholder(fileHandle windows.Handle, startingOffset, length int6
 	return nil
 }
 
+// RevertPlaceholder turns a hydrated placeholder back into a regular file or
+// directory that no longer depends on the sync root. Fails for files whose
+// content is not fully on disk.
+func RevertPlaceholder(path string) error {
+	if err := procCfRevertPlaceholder.Find(); err != nil {
+		return fmt.Errorf("CfRevertPlaceholder not available: %w", err)
+	}
+
+	handle, err := windows.CreateFile(
+		windows.StringToUTF16Ptr(longpath.Fix(path)),
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT,
+		0,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer windows.CloseHandle(handle)
+
+	hr, _, _ := procCfRevertPlaceholder.Call(
+		uintptr(handle),
+		0, // CF_REVERT_FLAG_NONE
+		0, // Overlapped - NULL for synchronous
+	)
+
+	if hr != S_OK {
+		return fmt.Errorf("CfRevertPlaceholder failed: HRESULT 0x%08X (%s)", hr, decodeHRESULT(uint32(hr)))
+	}
+
+	return nil
+}
+
 // GetPlaceholderState returns the placeholder state of a file.
 func GetPlaceholderState(fileAttributes uint32, reparseTag uint32) CF_PLACEHOLDER_STATE {
 	if err := procCfGetPlaceholderStateFromAttributeTag.Find(); err != nil {
@@ -116,3 +152,28 @@ func GetPlaceholderState(fileAttributes uint32, reparseTag uint32) CF_PLACEHOLDE
 
 	return CF_PLACEHOLDER_STATE(state)
 }
+
+// GetFilePlaceholderState opens path without recalling its data and returns
+// its placeholder state.
+func GetFilePlaceholderState(path string) (CF_PLACEHOLDER_STATE, error) {
+	handle, err := windows.CreateFile(
+		windows.StringToUTF16Ptr(longpath.Fix(path)),
+		0, // Query only
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT,
+		0,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer windows.CloseHandle(handle)
+
+	var fileInfo windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &fileInfo); err != nil {
+		return 0, fmt.Errorf("failed to read file attributes: %w", err)
+	}
+
+	return GetPlaceholderState(fileInfo.FileAttributes, IO_REPARSE_TAG_CLOUD), nil
+}
@@ -0,0 +1,178 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Windows Cloud Files API bindings.
+// This file detects and repairs a Cloud Files minifilter detached from a volume.
+package cloudfiles
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// cldFltProbeEntries caps the directory entries read looking for a placeholder
+	cldFltProbeEntries = 500
+
+	// cldFltAttachTimeout is how long ReattachCldFlt waits for the elevated
+	// fltmc to attach the filter
+	cldFltAttachTimeout = 30 * time.Second
+)
+
+// ErrCldFltNotAttached is returned by ReattachCldFlt when the filter is still
+// detached after the attempt (e.g. the UAC prompt was declined).
+var ErrCldFltNotAttached = errors.New("Cloud Files filter is still not attached")
+
+// CldFltStatus tells whether the Cloud Files minifilter serves a sync root.
+type CldFltStatus struct {
+	Volume   string // Volume of the sync root (e.g. "C:")
+	Detached bool   // CldFlt is not attached: placeholders are inaccessible
+}
+
+// CheckCldFlt checks that the Cloud Files minifilter (CldFlt) is attached to
+// the volume hosting the sync root at path. Third-party cleanup tools may
+// detach it, after which every placeholder fails with ERROR_GEN_FAILURE.
+// With administrator rights fltmc is asked directly; otherwise a placeholder
+// of the sync root is opened, which fails that way when the filter is detached.
+func CheckCldFlt(path string) (*CldFltStatus, error) {
+	info, err := CheckVolume(path)
+	if err != nil {
+		return nil, err
+	}
+	status := &CldFltStatus{Volume: strings.TrimSuffix(info.Root, `\`)}
+
+	if windows.GetCurrentProcessToken().IsElevated() {
+		attached, err := cldFltAttached(status.Volume)
+		if err != nil {
+			return nil, err
+		}
+		status.Detached = !attached
+		return status, nil
+	}
+
+	placeholder := findPlaceholder(path)
+	if placeholder == "" {
+		// Nothing to open: no placeholder can be broken yet
+		return status, nil
+	}
+	status.Detached = errors.Is(openPlaceholder(placeholder), windows.ERROR_GEN_FAILURE)
+	return status, nil
+}
+
+// ReattachCldFlt attaches the Cloud Files minifilter to volume (e.g. "C:").
+// Without administrator rights, fltmc is started through a UAC prompt and
+// the placeholders of the sync root at path are probed until they work again.
+func ReattachCldFlt(volume, path string) error {
+	if windows.GetCurrentProcessToken().IsElevated() {
+		cmd := exec.Command("fltmc", "attach", "CldFlt", volume)
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("fltmc attach CldFlt %s failed: %w (%s)", volume, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	verb, _ := windows.UTF16PtrFromString("runas")
+	file, _ := windows.UTF16PtrFromString("fltmc.exe")
+	args, _ := windows.UTF16PtrFromString("attach CldFlt " + volume)
+	if err := windows.ShellExecute(0, verb, file, args, nil, windows.SW_HIDE); err != nil {
+		return fmt.Errorf("failed to start fltmc as administrator: %w", err)
+	}
+
+	// ShellExecute does not wait: probe until the placeholders open again
+	deadline := time.Now().Add(cldFltAttachTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		status, err := CheckCldFlt(path)
+		if err == nil && !status.Detached {
+			return nil
+		}
+	}
+	return ErrCldFltNotAttached
+}
+
+// cldFltAttached asks fltmc whether CldFlt has an instance on volume.
+// Requires administrator rights.
+func cldFltAttached(volume string) (bool, error) {
+	cmd := exec.Command("fltmc", "instances", "-f", "CldFlt")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("fltmc instances failed: %w", err)
+	}
+	return fltmcHasInstance(string(output), volume), nil
+}
+
+// findPlaceholder returns a placeholder file under root ("" if none found
+// within cldFltProbeEntries entries), checking the top level first.
+func findPlaceholder(root string) string {
+	seen := 0
+	dirs := []string{root}
+	for len(dirs) > 0 && seen < cldFltProbeEntries {
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			seen++
+			if seen > cldFltProbeEntries {
+				break
+			}
+			full := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				dirs = append(dirs, full)
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+			if ok && isPlaceholderAttributes(data.FileAttributes) {
+				return full
+			}
+		}
+	}
+	return ""
+}
+
+// isPlaceholderAttributes reports whether file attributes are those of a
+// Cloud Files placeholder.
+func isPlaceholderAttributes(attrs uint32) bool {
+	return attrs&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 &&
+		attrs&(windows.FILE_ATTRIBUTE_OFFLINE|windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS|windows.FILE_ATTRIBUTE_RECALL_ON_OPEN) != 0
+}
+
+// openPlaceholder opens a placeholder through its reparse point (attributes
+// only, no hydration), as any program would.
+func openPlaceholder(path string) error {
+	pathPtr, err := windows.UTF16PtrFromString(longpath.Fix(path))
+	if err != nil {
+		return err
+	}
+	handle, err := windows.CreateFile(
+		pathPtr,
+		windows.FILE_READ_ATTRIBUTES,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+	return windows.CloseHandle(handle)
+}
@@ -0,0 +1,37 @@
+//go:build windows
+// +build windows
+
+package cloudfiles
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestIsPlaceholderAttributes(t *testing.T) {
+	tests := []struct {
+		attrs uint32
+		want  bool
+	}{
+		{windows.FILE_ATTRIBUTE_REPARSE_POINT | windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS, true},
+		{windows.FILE_ATTRIBUTE_REPARSE_POINT | windows.FILE_ATTRIBUTE_OFFLINE, true},
+		{windows.FILE_ATTRIBUTE_REPARSE_POINT, false}, // Symlink or junction
+		{windows.FILE_ATTRIBUTE_ARCHIVE, false},
+	}
+	for _, tt := range tests {
+		if got := isPlaceholderAttributes(tt.attrs); got != tt.want {
+			t.Errorf("isPlaceholderAttributes(%#x) = %v, want %v", tt.attrs, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCldFlt_NoPlaceholder(t *testing.T) {
+	status, err := CheckCldFlt(t.TempDir())
+	if err != nil {
+		t.Skipf("CheckCldFlt: %v", err)
+	}
+	if status.Volume == "" {
+		t.Error("expected the volume of the temp dir")
+	}
+}
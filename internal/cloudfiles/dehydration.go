@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +21,7 @@ type DehydrationManager struct {
 
 	syncRoot    *SyncRootManager
 	policy      DehydrationPolicy
+	previews    []PreviewRule // Start of files kept on disk when dehydrating
 	logger      *zap.Logger
 
 	// Statistics
@@ -47,6 +49,10 @@ type DehydrationPolicy struct {
 	// ExcludePatterns are glob patterns for files to exclude from dehydration.
 	ExcludePatterns []string
 
+	// FileTypes excludes file types from dehydration (e.g. databases).
+	// nil dehydrates every type.
+	FileTypes *filetype.Policy
+
 	// MaxFilesToDehydrate limits the number of files processed per scan.
 	// Set to 0 for unlimited.
 	MaxFilesToDehydrate int
@@ -72,7 +78,8 @@ type DehydrationStats struct {
 	LastScanTime      time.Time
 	FilesScanned      int64
 	FilesDehydrated   int64
-	BytesFreed        int64
+	BytesFreed        int64 // Logical size of dehydrated files
+	AllocatedFreed    int64 // Disk space actually released (allocated size)
 	Errors            int64
 }
 
@@ -81,6 +88,7 @@ type HydratedFileInfo struct {
 	Path           string    // Relative path from sync root
 	FullPath       string    // Full filesystem path
 	Size           int64     // File size in bytes
+	AllocatedSize  int64     // Space used on disk (lower for compressed/sparse files)
 	LastAccessTime time.Time // Last access time
 	ModTime        time.Time // Modification time
 	DaysSinceAccess int      // Days since last access
@@ -241,6 +249,7 @@ func (dm *DehydrationManager) runScan(ctx context.Context) {
 		dm.mu.Lock()
 		dm.stats.FilesDehydrated++
 		dm.stats.BytesFreed += file.Size
+		dm.stats.AllocatedFreed += file.AllocatedSize
 		dm.mu.Unlock()
 
 		count++
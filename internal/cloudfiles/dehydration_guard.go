@@ -0,0 +1,53 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Go bindings for the Windows Cloud Files API.
+package cloudfiles
+
+import (
+	"go.uber.org/zap"
+)
+
+// DehydrationGuard decides whether Windows may dehydrate a placeholder.
+// It returns false and a reason to veto the dehydration, typically because
+// the file has local changes that are not uploaded yet.
+type DehydrationGuard func(relativePath string) (allow bool, reason string)
+
+// SetDehydrationGuard sets the guard consulted when Windows (user, Storage
+// Sense) dehydrates a placeholder. nil allows every dehydration.
+func (p *CloudFilesProvider) SetDehydrationGuard(guard DehydrationGuard) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dehydrationGuard = guard
+}
+
+// onNotifyDehydrate handles NOTIFY_DEHYDRATE: the guard can veto the dehydration.
+func (p *CloudFilesProvider) onNotifyDehydrate(filePath string, reason DehydrationReason) bool {
+	p.mu.RLock()
+	guard := p.dehydrationGuard
+	p.mu.RUnlock()
+
+	if guard == nil {
+		return true
+	}
+
+	relativePath := p.syncRoot.RelativePath(filePath)
+	allow, why := guard(relativePath)
+	if !allow {
+		p.logger.Warn("dehydration vetoed",
+			zap.String("path", relativePath),
+			zap.String("requested_by", reason.String()),
+			zap.String("reason", why),
+		)
+	}
+	return allow
+}
+
+// onDehydrateComplete handles NOTIFY_DEHYDRATE_COMPLETION.
+func (p *CloudFilesProvider) onDehydrateComplete(filePath string, reason DehydrationReason, dehydrated bool) {
+	p.logger.Debug("dehydration completed",
+		zap.String("path", p.syncRoot.RelativePath(filePath)),
+		zap.String("requested_by", reason.String()),
+		zap.Bool("dehydrated", dehydrated),
+	)
+}
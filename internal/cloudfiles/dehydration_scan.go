@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
 	"go.uber.org/zap"
 	"golang.org/x/sys/windows"
 )
@@ -56,6 +57,7 @@ func (dm *DehydrationManager) ScanHydratedFiles(ctx context.Context) ([]Hydrated
 			Path:            relPath,
 			FullPath:        path,
 			Size:            info.Size(),
+			AllocatedSize:   allocatedSizeOr(path, info.Size()),
 			LastAccessTime:  lastAccess,
 			ModTime:         info.ModTime(),
 			DaysSinceAccess: daysSinceAccess,
@@ -70,7 +72,7 @@ func (dm *DehydrationManager) ScanHydratedFiles(ctx context.Context) ([]Hydrated
 // getFileHydrationStatus checks if a file is hydrated and gets its last access time.
 func (dm *DehydrationManager) getFileHydrationStatus(path string) (bool, time.Time, error) {
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(path),
+		windows.StringToUTF16Ptr(longpath.Fix(path)),
 		0, // Query only
 		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
 		nil,
@@ -132,6 +134,11 @@ func (dm *DehydrationManager) filterEligibleFiles(files []HydratedFileInfo, poli
 			continue
 		}
 
+		// Check file type
+		if ok, _ := policy.FileTypes.AllowsDehydration(file.Path); !ok {
+			continue
+		}
+
 		eligible = append(eligible, file)
 	}
 
@@ -185,6 +192,19 @@ func (dm *DehydrationManager) DehydrateFile(ctx context.Context, relativePath st
 		return nil // Already dehydrated
 	}
 
+	// Keep the preview of the file on disk (partial placeholder): dehydrate the rest only
+	if preview := dm.previewLength(relativePath, fileSize); preview > 0 {
+		if err := DehydratePlaceholder(win32Handle, preview, fileSize-preview, 0); err != nil {
+			return fmt.Errorf("failed to dehydrate beyond preview: %w", err)
+		}
+		dm.logger.Info("file dehydrated, preview kept",
+			zap.String("path", relativePath),
+			zap.Int64("size", fileSize),
+			zap.Int64("preview", preview),
+		)
+		return nil
+	}
+
 	// Use CfUpdatePlaceholder with DEHYDRATE + MARK_IN_SYNC flags
 	// Using the protected handle from CfOpenFileWithOplock (exclusive access required)
 	if err := UpdatePlaceholder(protectedHandle, CF_UPDATE_FLAG_DEHYDRATE|CF_UPDATE_FLAG_MARK_IN_SYNC); err != nil {
@@ -200,6 +220,7 @@ func (dm *DehydrationManager) DehydrateFile(ctx context.Context, relativePath st
 }
 
 // DehydrateAll dehydrates all eligible files immediately.
+// Returns the number of files dehydrated and the disk space freed (allocated size).
 func (dm *DehydrationManager) DehydrateAll(ctx context.Context) (int, int64, error) {
 	dm.logger.Info("dehydrating all eligible files")
 
@@ -215,7 +236,7 @@ func (dm *DehydrationManager) DehydrateAll(ctx context.Context) (int, int64, err
 	eligible := dm.filterEligibleFiles(hydratedFiles, policy)
 
 	count := 0
-	var bytesFreed int64
+	var bytesFreed, logicalFreed int64
 
 	for _, file := range eligible {
 		if ctx.Err() != nil {
@@ -231,42 +252,36 @@ func (dm *DehydrationManager) DehydrateAll(ctx context.Context) (int, int64, err
 		}
 
 		count++
-		bytesFreed += file.Size
+		bytesFreed += file.AllocatedSize
+		logicalFreed += file.Size
 	}
 
 	dm.logger.Info("dehydration complete",
 		zap.Int("files", count),
 		zap.Int64("bytes_freed", bytesFreed),
+		zap.Int64("logical_bytes", logicalFreed),
 	)
 
 	return count, bytesFreed, nil
 }
 
-// GetSpaceUsage returns the current space usage by hydrated files.
+// GetSpaceUsage returns the current space usage of the sync root.
 func (dm *DehydrationManager) GetSpaceUsage(ctx context.Context) (SpaceUsage, error) {
-	hydratedFiles, err := dm.ScanHydratedFiles(ctx)
-	if err != nil {
-		return SpaceUsage{}, err
-	}
-
-	var usage SpaceUsage
-	for _, file := range hydratedFiles {
-		usage.HydratedFiles++
-		usage.HydratedBytes += file.Size
-	}
-
-	// TODO: Count placeholder files too
-	// For now, we just return hydrated file count
-
-	return usage, nil
+	return ScanSpaceUsage(ctx, dm.syncRoot.Path())
 }
 
 // SpaceUsage represents disk space usage.
+// Bytes are logical sizes unless named "Allocated".
 type SpaceUsage struct {
-	HydratedFiles    int64
-	HydratedBytes    int64
-	PlaceholderFiles int64
-	TotalFiles       int64
+	HydratedFiles          int64
+	HydratedBytes          int64
+	HydratedAllocatedBytes int64
+	PlaceholderFiles       int64 // Dehydrated placeholders (content not on disk)
+	PlaceholderBytes       int64
+	TotalFiles             int64
+	TotalBytes             int64
+	AllocatedBytes         int64 // Space used on disk by all files
+	SavedBytes             int64 // Space saved by Files On Demand (logical - allocated of placeholders)
 }
 
 // FormatBytes formats bytes as human-readable string.
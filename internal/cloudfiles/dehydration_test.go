@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
 )
 
 func TestDefaultDehydrationPolicy(t *testing.T) {
@@ -189,10 +191,15 @@ func TestFilterEligibleFiles(t *testing.T) {
 	}
 	syncRoot, _ := NewSyncRootManager(config)
 
+	fileTypes, err := filetype.NewPolicy([]string{"database"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
 	policy := DehydrationPolicy{
 		MaxAgeDays:      7,
 		MinFileSize:     1024,
 		ExcludePatterns: []string{"*.log"},
+		FileTypes:       fileTypes,
 	}
 	dm := NewDehydrationManager(syncRoot, policy, nil)
 
@@ -203,6 +210,7 @@ func TestFilterEligibleFiles(t *testing.T) {
 		{Path: "old.txt", Size: 2048, DaysSinceAccess: 10},        // Eligible
 		{Path: "excluded.log", Size: 2048, DaysSinceAccess: 10},   // Excluded pattern
 		{Path: "another.txt", Size: 4096, DaysSinceAccess: 30},    // Eligible
+		{Path: "mail.pst", Size: 4096, DaysSinceAccess: 30},       // Excluded file type
 	}
 
 	// Set last access times
@@ -237,6 +245,9 @@ func TestFilterEligibleFiles(t *testing.T) {
 	if eligiblePaths["excluded.log"] {
 		t.Error("excluded.log should not be eligible (excluded pattern)")
 	}
+	if eligiblePaths["mail.pst"] {
+		t.Error("mail.pst should not be eligible (database file type)")
+	}
 }
 
 func TestFormatBytes(t *testing.T) {
@@ -300,6 +311,50 @@ func TestSpaceUsage(t *testing.T) {
 	}
 }
 
+func TestAllocatedSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, make([]byte, 64*1024), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	size, err := AllocatedSize(path)
+	if err != nil {
+		t.Fatalf("AllocatedSize failed: %v", err)
+	}
+	// A regular uncompressed file occupies its logical size
+	if size != 64*1024 {
+		t.Errorf("Expected allocated size 65536, got %d", size)
+	}
+
+	if _, err := AllocatedSize(filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestScanSpaceUsage(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.txt", filepath.Join("sub", "b.txt")} {
+		path := filepath.Join(tempDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, make([]byte, 1000), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	usage, err := ScanSpaceUsage(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("ScanSpaceUsage failed: %v", err)
+	}
+
+	if usage.TotalFiles != 2 || usage.TotalBytes != 2000 {
+		t.Errorf("Expected 2 files / 2000 bytes, got %d / %d", usage.TotalFiles, usage.TotalBytes)
+	}
+	// Regular files are neither placeholders nor savings
+	if usage.PlaceholderFiles != 0 || usage.HydratedFiles != 0 || usage.SavedBytes != 0 {
+		t.Errorf("Unexpected placeholder accounting: %+v", usage)
+	}
+}
+
 func TestDehydrationManagerContextCancellation(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -375,3 +430,42 @@ func TestCloudFilesProviderDehydration(t *testing.T) {
 		t.Errorf("Expected 0 files scanned, got %d", stats.FilesScanned)
 	}
 }
+
+func TestStorageSenseDays(t *testing.T) {
+	tests := []struct {
+		days     int
+		expected int
+	}{
+		{0, 30},
+		{1, 1},
+		{7, 14},
+		{14, 14},
+		{30, 30},
+		{45, 60},
+		{60, 60},
+		{90, 60}, // Longest threshold offered
+	}
+
+	for _, tt := range tests {
+		if got := StorageSenseDays(tt.days); got != tt.expected {
+			t.Errorf("StorageSenseDays(%d) = %d, expected %d", tt.days, got, tt.expected)
+		}
+	}
+}
+
+func TestStorageSenseSettingsActive(t *testing.T) {
+	settings := StorageSenseSettings{Enabled: true, CloudDehydration: true, DehydrateDays: 30}
+	if !settings.Active() {
+		t.Error("Expected Storage Sense to be active")
+	}
+
+	settings.Enabled = false
+	if settings.Active() {
+		t.Error("Storage Sense should not be active when turned off")
+	}
+
+	settings = StorageSenseSettings{Enabled: true, CloudDehydration: true}
+	if settings.Active() {
+		t.Error("Storage Sense should not be active with a 'never' threshold")
+	}
+}
@@ -0,0 +1,108 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides disk usage accounting for placeholder files.
+package cloudfiles
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+var procGetCompressedFileSizeW = kernel32.NewProc("GetCompressedFileSizeW")
+
+// invalidFileSize is INVALID_FILE_SIZE, returned by GetCompressedFileSize on error
+// (or as a valid low DWORD, told apart by the last error).
+const invalidFileSize = 0xFFFFFFFF
+
+// AllocatedSize returns the space a file actually occupies on disk
+// (GetCompressedFileSize). It is lower than the logical size for compressed,
+// sparse and dehydrated files, and is the space freed by dehydrating the file.
+func AllocatedSize(path string) (int64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(longpath.Fix(path))
+	if err != nil {
+		return 0, err
+	}
+
+	var high uint32
+	low, _, callErr := procGetCompressedFileSizeW.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&high)),
+	)
+	if uint32(low) == invalidFileSize {
+		if errno, ok := callErr.(syscall.Errno); ok && errno != 0 {
+			return 0, errno
+		}
+	}
+
+	return int64(high)<<32 | int64(uint32(low)), nil
+}
+
+// allocatedSizeOr returns the allocated size of path, or fallback if it can't be read.
+func allocatedSizeOr(path string, fallback int64) int64 {
+	size, err := AllocatedSize(path)
+	if err != nil {
+		return fallback
+	}
+	return size
+}
+
+// ScanSpaceUsage walks a sync root and sums logical and allocated sizes,
+// split between hydrated files and dehydrated placeholders.
+func ScanSpaceUsage(ctx context.Context, rootPath string) (SpaceUsage, error) {
+	var usage SpaceUsage
+
+	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		size := info.Size()
+		allocated := allocatedSizeOr(path, size)
+
+		usage.TotalFiles++
+		usage.TotalBytes += size
+		usage.AllocatedBytes += allocated
+
+		var attrs uint32
+		if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+			attrs = data.FileAttributes
+		}
+		state := GetPlaceholderState(attrs, IO_REPARSE_TAG_CLOUD)
+		if state&CF_PLACEHOLDER_STATE_PLACEHOLDER == 0 {
+			return nil
+		}
+
+		if state&CF_PLACEHOLDER_STATE_PARTIAL != 0 {
+			usage.PlaceholderFiles++
+			usage.PlaceholderBytes += size
+		} else {
+			usage.HydratedFiles++
+			usage.HydratedBytes += size
+			usage.HydratedAllocatedBytes += allocated
+		}
+		if size > allocated {
+			usage.SavedBytes += size - allocated
+		}
+
+		return nil
+	})
+
+	return usage, err
+}
@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
-	"strings"
 	"sync"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/metrics"
 	"go.uber.org/zap"
 	"golang.org/x/sys/windows"
 )
@@ -21,6 +23,7 @@ type HydrationHandler struct {
 	syncRoot     *SyncRootManager
 	dataProvider DataProvider
 	chunkSize    int64
+	readAheadDepth int
 	logger       *zap.Logger
 
 	mu               sync.RWMutex
@@ -51,6 +54,7 @@ func NewHydrationHandler(syncRoot *SyncRootManager, provider DataProvider, logge
 		syncRoot:         syncRoot,
 		dataProvider:     provider,
 		chunkSize:        1024 * 1024, // 1MB chunks
+		readAheadDepth:   DefaultReadAheadDepth,
 		logger:           logger,
 		activeHydrations: make(map[CF_TRANSFER_KEY]*activeHydration),
 	}
@@ -63,48 +67,35 @@ func (h *HydrationHandler) SetChunkSize(size int64) {
 	}
 }
 
+// SetReadAheadDepth sets how many chunks are prefetched while the current one
+// is transferred (0 = no read-ahead).
+func (h *HydrationHandler) SetReadAheadDepth(depth int) {
+	if depth >= 0 {
+		h.readAheadDepth = depth
+	}
+}
+
 // handleFetchDataCallback is the callback function for SyncRootManager.
 // It converts FetchDataCallback signature to HandleFetchData call.
 func (h *HydrationHandler) handleFetchDataCallback(info *FetchDataInfo) error {
-	return h.HandleFetchData(context.Background(), info)
+	err := h.HandleFetchData(context.Background(), info)
+	if err != nil {
+		metrics.HydrationsFailed.Inc()
+	} else {
+		metrics.HydrationsServed.Inc()
+	}
+	return err
 }
 
 // HandleFetchData handles a fetch data callback from Windows.
 // This is called when a user opens a placeholder file.
 func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataInfo) error {
-	// Create cancellable context
-	ctx, cancel := context.WithCancel(ctx)
+	// Create cancellable context, tagged so the logs of this request can be told apart
+	ctx, cancel := context.WithCancel(correlation.WithOpID(ctx, correlation.NewOpID()))
+	log := correlation.Logger(ctx, h.logger)
 
 	// Get relative path from NormalizedPath
-	// NormalizedPath format: \<path_from_volume_root>\<relative_path>
-	// e.g., for sync root D:\Anemone\backup:
-	//   \Anemone\backup\subdir\file.txt -> subdir/file.txt
-	// e.g., for sync root D:\test_anemone:
-	//   \test_anemone\subdir\file.txt -> subdir/file.txt
-	relativePath := info.FilePath
-
-	// Strip leading backslash
-	relativePath = strings.TrimPrefix(relativePath, "\\")
-	relativePath = strings.TrimPrefix(relativePath, "/")
-
-	// Strip the sync root path (from volume root) from the beginning
-	// For D:\Anemone\backup, we need to strip "Anemone\backup\" (not just "backup\")
-	syncRootPath := h.syncRoot.Path()
-	volName := filepath.VolumeName(syncRootPath)
-	syncRootRelative := strings.TrimPrefix(syncRootPath, volName)
-	syncRootRelative = strings.TrimPrefix(syncRootRelative, "\\")
-	syncRootRelative = strings.TrimPrefix(syncRootRelative, "/")
-
-	if syncRootRelative != "" {
-		if strings.HasPrefix(relativePath, syncRootRelative+"\\") {
-			relativePath = relativePath[len(syncRootRelative)+1:]
-		} else if strings.HasPrefix(relativePath, syncRootRelative+"/") {
-			relativePath = relativePath[len(syncRootRelative)+1:]
-		}
-	}
-
-	// Normalize to forward slashes
-	relativePath = strings.ReplaceAll(relativePath, "\\", "/")
+	relativePath := h.syncRoot.RelativePath(info.FilePath)
 
 	// Track this hydration
 	hydration := &activeHydration{
@@ -124,7 +115,7 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 		cancel()
 	}()
 
-	h.logger.Info("starting hydration",
+	log.Info("starting hydration",
 		zap.String("file", relativePath),
 		zap.Int64("offset", info.RequiredOffset),
 		zap.Int64("size", info.FileSize),
@@ -133,12 +124,13 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 	// Get reader from data provider
 	reader, err := h.dataProvider.GetFileReader(ctx, relativePath, info.RequiredOffset)
 	if err != nil {
-		h.logger.Error("failed to get file reader",
+		log.Error("failed to get file reader",
 			zap.String("file", relativePath),
 			zap.Error(err),
 		)
 		return fmt.Errorf("failed to get file reader: %w", err)
 	}
+	reader = newReadAheadReader(reader, int(h.chunkSize), h.readAheadDepth)
 	defer reader.Close()
 
 	// Transfer data in chunks
@@ -154,7 +146,7 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 	for remaining > 0 {
 		select {
 		case <-ctx.Done():
-			h.logger.Info("hydration cancelled",
+			log.Info("hydration cancelled",
 				zap.String("file", relativePath),
 				zap.Int64("transferred", transferred),
 			)
@@ -171,7 +163,7 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 		// Read data
 		n, err := io.ReadFull(reader, buffer[:toRead])
 		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			h.logger.Error("failed to read data",
+			log.Error("failed to read data",
 				zap.String("file", relativePath),
 				zap.Error(err),
 			)
@@ -186,7 +178,7 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 
 		// Transfer to Windows (mark in-sync on last chunk)
 		if err := TransferData(info.ConnectionKey, info.TransferKey, info.RequestKey, buffer[:n], offset, isLastChunk); err != nil {
-			h.logger.Error("failed to transfer data",
+			log.Error("failed to transfer data",
 				zap.String("file", relativePath),
 				zap.Error(err),
 			)
@@ -208,7 +200,7 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 		h.reportProgress(info.ConnectionKey, info.TransferKey, info.FileSize, offset)
 	}
 
-	h.logger.Info("hydration complete",
+	log.Info("hydration complete",
 		zap.String("file", relativePath),
 		zap.Int64("bytes", transferred),
 	)
@@ -225,20 +217,20 @@ func (h *HydrationHandler) HandleFetchData(ctx context.Context, info *FetchDataI
 		// Use CfUpdatePlaceholder with MARK_IN_SYNC flag (recommended approach)
 		err := UpdatePlaceholder(win32Handle, CF_UPDATE_FLAG_MARK_IN_SYNC)
 		if err != nil {
-			h.logger.Warn("CfUpdatePlaceholder failed, trying CfSetInSyncState",
+			log.Warn("CfUpdatePlaceholder failed, trying CfSetInSyncState",
 				zap.String("file", relativePath),
 				zap.Error(err),
 			)
 			// Fallback to SetInSyncState
 			if err := SetInSyncState(protectedHandle, uint32(CF_IN_SYNC_STATE_IN_SYNC), nil); err != nil {
-				h.logger.Warn("failed to set in-sync state after hydration",
+				log.Warn("failed to set in-sync state after hydration",
 					zap.String("file", relativePath),
 					zap.Error(err),
 				)
 			}
 		}
 	} else {
-		h.logger.Warn("failed to open file for in-sync marking",
+		log.Warn("failed to open file for in-sync marking",
 			zap.String("file", relativePath),
 			zap.Error(err),
 		)
@@ -313,7 +305,7 @@ func (h *HydrationHandler) HydrateFile(ctx context.Context, relativePath string)
 
 	// Open the file
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(fullPath),
+		windows.StringToUTF16Ptr(longpath.Fix(fullPath)),
 		windows.GENERIC_WRITE,
 		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
 		nil,
@@ -344,7 +336,7 @@ func (h *HydrationHandler) DehydrateFile(ctx context.Context, relativePath strin
 
 	// Open the file
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(fullPath),
+		windows.StringToUTF16Ptr(longpath.Fix(fullPath)),
 		windows.GENERIC_WRITE,
 		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
 		nil,
@@ -375,7 +367,7 @@ func (h *HydrationHandler) SetPinned(relativePath string, pinned bool) error {
 
 	// Open the file
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(fullPath),
+		windows.StringToUTF16Ptr(longpath.Fix(fullPath)),
 		windows.GENERIC_WRITE,
 		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
 		nil,
@@ -6,9 +6,11 @@ package cloudfiles
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -242,3 +244,85 @@ func TestActiveHydrationTracking(t *testing.T) {
 		t.Errorf("Expected 0 active hydrations after cancel, got %d", len(active))
 	}
 }
+
+// closeTracker records whether the source reader was closed.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestReadAheadReader(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	src := &closeTracker{Reader: bytes.NewReader(content)}
+
+	reader := newReadAheadReader(src, 128, 4)
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Read %d bytes, content differs from source (%d bytes)", len(got), len(content))
+	}
+
+	reader.Close()
+	if !src.closed {
+		t.Error("Close should close the source")
+	}
+}
+
+func TestReadAheadReaderDisabled(t *testing.T) {
+	src := io.NopCloser(bytes.NewReader([]byte("data")))
+	if reader := newReadAheadReader(src, 128, 0); reader != src {
+		t.Error("Depth 0 should return the source unchanged")
+	}
+}
+
+func TestReadAheadReaderError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	src := io.NopCloser(io.MultiReader(bytes.NewReader([]byte("partial")), iotest.ErrReader(readErr)))
+
+	got, err := io.ReadAll(newReadAheadReader(src, 4, 2))
+	if !errors.Is(err, readErr) {
+		t.Errorf("Expected source error, got %v", err)
+	}
+	if string(got) != "partial" {
+		t.Errorf("Expected data read before the error, got %q", got)
+	}
+}
+
+func TestHydrationStreamCache(t *testing.T) {
+	cache := newHydrationStreamCache()
+	src := &closeTracker{Reader: bytes.NewReader(nil)}
+
+	if cache.take("a.txt", 0) != nil {
+		t.Fatal("Empty cache should return nil")
+	}
+
+	cache.put("a.txt", src, 1024)
+	if reader := cache.take("a.txt", 1024); reader != src {
+		t.Fatal("Stream at the requested offset should be reused")
+	}
+	if cache.take("a.txt", 1024) != nil {
+		t.Error("Taken stream should be removed from the cache")
+	}
+
+	cache.put("a.txt", src, 1024)
+	if cache.take("a.txt", 4096) != nil {
+		t.Error("Stream at another offset should not be reused")
+	}
+	if !src.closed {
+		t.Error("Stream at another offset should be closed")
+	}
+
+	other := &closeTracker{Reader: bytes.NewReader(nil)}
+	cache.put("b.txt", other, 0)
+	cache.closeAll()
+	if !other.closed {
+		t.Error("closeAll should close cached streams")
+	}
+}
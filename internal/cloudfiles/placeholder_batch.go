@@ -0,0 +1,130 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Go bindings for the Windows Cloud Files API.
+package cloudfiles
+
+import (
+	"context"
+	"time"
+)
+
+// Default placeholder creation settings.
+const (
+	DefaultPlaceholderBatchSize  = 1000
+	DefaultPlaceholderBatchPause = 10 * time.Millisecond
+)
+
+// PlaceholderProgressFunc is called after each batch of placeholders is created.
+type PlaceholderProgressFunc func(created, total int)
+
+// PlaceholderCreationOptions controls bulk placeholder creation, so that
+// populating a large sync root does not lock up Explorer.
+type PlaceholderCreationOptions struct {
+	BatchSize    int           // Max placeholders per CfCreatePlaceholders call (0 = default)
+	MaxPerSecond int           // Creation rate limit (0 = unlimited)
+	BatchPause   time.Duration // Yield between batches when not rate limited (0 = default)
+}
+
+// DefaultPlaceholderCreationOptions returns the default creation options.
+func DefaultPlaceholderCreationOptions() PlaceholderCreationOptions {
+	return PlaceholderCreationOptions{
+		BatchSize:  DefaultPlaceholderBatchSize,
+		BatchPause: DefaultPlaceholderBatchPause,
+	}
+}
+
+// withDefaults fills unset options with their default values.
+func (o PlaceholderCreationOptions) withDefaults() PlaceholderCreationOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultPlaceholderBatchSize
+	}
+	if o.BatchPause <= 0 {
+		o.BatchPause = DefaultPlaceholderBatchPause
+	}
+	if o.MaxPerSecond < 0 {
+		o.MaxPerSecond = 0
+	}
+	// Never create more than one second's worth of placeholders in a single call
+	if o.MaxPerSecond > 0 && o.BatchSize > o.MaxPerSecond {
+		o.BatchSize = o.MaxPerSecond
+	}
+	return o
+}
+
+// splitBatches splits files into chunks of at most size entries.
+func splitBatches(files []RemoteFileInfo, size int) [][]RemoteFileInfo {
+	if size <= 0 {
+		size = DefaultPlaceholderBatchSize
+	}
+	batches := make([][]RemoteFileInfo, 0, (len(files)+size-1)/size)
+	for len(files) > size {
+		batches = append(batches, files[:size])
+		files = files[size:]
+	}
+	if len(files) > 0 {
+		batches = append(batches, files)
+	}
+	return batches
+}
+
+// placeholderPacer spaces out placeholder batches and reports progress.
+type placeholderPacer struct {
+	opts     PlaceholderCreationOptions
+	progress PlaceholderProgressFunc
+	total    int
+	created  int
+	pending  int // Created since the last pause
+	start    time.Time
+}
+
+func newPlaceholderPacer(opts PlaceholderCreationOptions, total int, progress PlaceholderProgressFunc) *placeholderPacer {
+	return &placeholderPacer{
+		opts:     opts.withDefaults(),
+		progress: progress,
+		total:    total,
+		start:    time.Now(),
+	}
+}
+
+// batchDone records n created placeholders, reports progress and waits before
+// the next batch. Returns the context error if cancelled while waiting.
+func (p *placeholderPacer) batchDone(ctx context.Context, n int) error {
+	p.created += n
+	p.pending += n
+	if p.progress != nil {
+		p.progress(p.created, p.total)
+	}
+	if p.created >= p.total {
+		return nil
+	}
+
+	delay := p.delay(time.Since(p.start))
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// delay returns how long to wait before the next batch, given the time
+// elapsed since creation started. Without a rate limit, it pauses once a
+// full batch has been created (small directories are grouped).
+func (p *placeholderPacer) delay(elapsed time.Duration) time.Duration {
+	if p.opts.MaxPerSecond > 0 {
+		expected := time.Duration(p.created) * time.Second / time.Duration(p.opts.MaxPerSecond)
+		return expected - elapsed
+	}
+	if p.pending < p.opts.BatchSize {
+		return 0
+	}
+	p.pending = 0
+	return p.opts.BatchPause
+}
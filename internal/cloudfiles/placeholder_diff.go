@@ -0,0 +1,199 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Go bindings for the Windows Cloud Files API.
+package cloudfiles
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// PlaceholderUpdate is a remote file whose size or modification time changed.
+type PlaceholderUpdate struct {
+	Previous RemoteFileInfo // Metadata the placeholder was created from
+	Current  RemoteFileInfo // New remote metadata
+}
+
+// PlaceholderDiff lists the placeholder changes between two remote listings.
+type PlaceholderDiff struct {
+	Create []RemoteFileInfo    // Files new on the remote
+	Update []PlaceholderUpdate // Files changed on the remote
+	Delete []RemoteFileInfo    // Files gone from the remote (previous metadata)
+}
+
+// IsEmpty returns true if the listings are identical.
+func (d PlaceholderDiff) IsEmpty() bool {
+	return len(d.Create) == 0 && len(d.Update) == 0 && len(d.Delete) == 0
+}
+
+// DiffRemoteListing compares the current remote listing with the previous one.
+// Directories are ignored: they are real NTFS directories created on demand
+// for the files they contain.
+// Modification times are compared with second precision, like the snapshot stores them.
+func DiffRemoteListing(previous, current []RemoteFileInfo) PlaceholderDiff {
+	var diff PlaceholderDiff
+
+	previousByPath := make(map[string]RemoteFileInfo, len(previous))
+	for _, file := range previous {
+		if !file.IsDirectory {
+			previousByPath[normalizePath(file.Path)] = file
+		}
+	}
+	seen := make(map[string]bool, len(current))
+
+	for _, file := range current {
+		if file.IsDirectory {
+			continue
+		}
+		path := normalizePath(file.Path)
+		seen[path] = true
+
+		prev, ok := previousByPath[path]
+		if !ok {
+			diff.Create = append(diff.Create, file)
+			continue
+		}
+		if prev.Size != file.Size || prev.ModTime.Unix() != file.ModTime.Unix() {
+			diff.Update = append(diff.Update, PlaceholderUpdate{Previous: prev, Current: file})
+		}
+	}
+
+	for path, prev := range previousByPath {
+		if !seen[path] {
+			diff.Delete = append(diff.Delete, prev)
+		}
+	}
+	// Deterministic order for logs and deletions
+	sort.Slice(diff.Delete, func(i, j int) bool { return diff.Delete[i].Path < diff.Delete[j].Path })
+
+	return diff
+}
+
+// ApplyPlaceholderDiff creates, refreshes and removes placeholders according to diff.
+// Changed and deleted files are only touched while the local file still matches
+// the previous remote metadata: local edits are left to the sync engine.
+// Returns the number of placeholders created, updated or deleted, and the
+// paths of the updates and deletions that failed (see RetainFailed).
+func (p *CloudFilesProvider) ApplyPlaceholderDiff(ctx context.Context, diff PlaceholderDiff) (int, []string, error) {
+	p.mu.RLock()
+	if !p.initialized {
+		p.mu.RUnlock()
+		return 0, nil, fmt.Errorf("provider not initialized")
+	}
+	p.mu.RUnlock()
+
+	p.logger.Info("applying placeholder diff",
+		zap.Int("create", len(diff.Create)),
+		zap.Int("update", len(diff.Update)),
+		zap.Int("delete", len(diff.Delete)),
+	)
+
+	applied := 0
+	var failed []string
+	if err := p.createPlaceholders(ctx, diff.Create); err != nil {
+		return applied, failed, fmt.Errorf("failed to create placeholders: %w", err)
+	}
+	applied += len(diff.Create)
+
+	skipped := 0
+	for _, update := range diff.Update {
+		if err := ctx.Err(); err != nil {
+			return applied, failed, err
+		}
+
+		state, err := p.placeholders.GetPlaceholderState(update.Current.Path)
+		if err != nil || (state.Exists && !placeholderMatches(state, update.Previous)) {
+			skipped++
+			continue
+		}
+		if state.Exists {
+			if err := p.placeholders.DeletePlaceholder(update.Current.Path); err != nil {
+				p.logger.Warn("failed to remove outdated placeholder",
+					zap.String("path", update.Current.Path),
+					zap.Error(err),
+				)
+				failed = append(failed, update.Current.Path)
+				continue
+			}
+		}
+		if err := p.placeholders.CreateSinglePlaceholder(update.Current); err != nil {
+			p.logger.Warn("failed to recreate placeholder",
+				zap.String("path", update.Current.Path),
+				zap.Error(err),
+			)
+			failed = append(failed, update.Current.Path)
+			continue
+		}
+		applied++
+	}
+
+	for _, file := range diff.Delete {
+		if err := ctx.Err(); err != nil {
+			return applied, failed, err
+		}
+
+		state, err := p.placeholders.GetPlaceholderState(file.Path)
+		if err != nil || !state.Exists {
+			continue
+		}
+		if !placeholderMatches(state, file) {
+			skipped++
+			continue
+		}
+		if err := p.placeholders.DeletePlaceholder(file.Path); err != nil {
+			p.logger.Warn("failed to delete placeholder",
+				zap.String("path", file.Path),
+				zap.Error(err),
+			)
+			failed = append(failed, file.Path)
+			continue
+		}
+		applied++
+	}
+
+	p.logger.Info("placeholder diff applied",
+		zap.Int("applied", applied),
+		zap.Int("skipped_local_changes", skipped),
+		zap.Int("failed", len(failed)),
+	)
+
+	return applied, failed, nil
+}
+
+// RetainFailed returns the listing to save as the snapshot of the next diff:
+// the current listing, where the files whose placeholder could not be updated
+// or deleted keep their previous metadata, so the next diff tries them again.
+func RetainFailed(previous, current []RemoteFileInfo, failed []string) []RemoteFileInfo {
+	if len(failed) == 0 {
+		return current
+	}
+	failedPaths := make(map[string]bool, len(failed))
+	for _, path := range failed {
+		failedPaths[normalizePath(path)] = true
+	}
+
+	snapshot := make([]RemoteFileInfo, 0, len(current))
+	for _, file := range current {
+		if file.IsDirectory || !failedPaths[normalizePath(file.Path)] {
+			snapshot = append(snapshot, file)
+		}
+	}
+	for _, file := range previous {
+		if !file.IsDirectory && failedPaths[normalizePath(file.Path)] {
+			snapshot = append(snapshot, file)
+		}
+	}
+	return snapshot
+}
+
+// placeholderMatches reports whether a local file is still the placeholder
+// created from the given remote metadata.
+func placeholderMatches(state PlaceholderFileState, remote RemoteFileInfo) bool {
+	return state.IsPlaceholder && !state.IsDirectory &&
+		state.Size == remote.Size &&
+		state.ModTime.Unix() == remote.ModTime.Unix()
+}
@@ -5,6 +5,7 @@
 package cloudfiles
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,21 +13,29 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
 	"golang.org/x/sys/windows"
 )
 
 // PlaceholderManager manages placeholder files within a sync root.
 type PlaceholderManager struct {
 	syncRoot *SyncRootManager
+	options  PlaceholderCreationOptions
 }
 
 // NewPlaceholderManager creates a new placeholder manager for a sync root.
 func NewPlaceholderManager(syncRoot *SyncRootManager) *PlaceholderManager {
 	return &PlaceholderManager{
 		syncRoot: syncRoot,
+		options:  DefaultPlaceholderCreationOptions(),
 	}
 }
 
+// SetCreationOptions sets the batching and throttling of placeholder creation.
+func (pm *PlaceholderManager) SetCreationOptions(opts PlaceholderCreationOptions) {
+	pm.options = opts.withDefaults()
+}
+
 // RemoteFileInfo represents information about a remote file.
 type RemoteFileInfo struct {
 	Path         string    // Relative path from sync root
@@ -40,6 +49,14 @@ type RemoteFileInfo struct {
 // CreatePlaceholders creates placeholder files for the given remote files.
 // It creates any necessary parent directories as placeholder directories.
 func (pm *PlaceholderManager) CreatePlaceholders(files []RemoteFileInfo) error {
+	return pm.CreatePlaceholdersWithProgress(context.Background(), files, nil)
+}
+
+// CreatePlaceholdersWithProgress creates placeholders like CreatePlaceholders,
+// in batches paced by the creation options so Explorer stays responsive.
+// progress (optional) is called after each batch with the number of file
+// placeholders created so far.
+func (pm *PlaceholderManager) CreatePlaceholdersWithProgress(ctx context.Context, files []RemoteFileInfo, progress PlaceholderProgressFunc) error {
 	if len(files) == 0 {
 		return nil
 	}
@@ -47,6 +64,7 @@ func (pm *PlaceholderManager) CreatePlaceholders(files []RemoteFileInfo) error {
 	// Group files by parent directory for batch creation
 	dirFiles := make(map[string][]RemoteFileInfo)
 	directories := make(map[string]bool)
+	fileCount := 0
 
 	for _, f := range files {
 		// Normalize path
@@ -60,6 +78,7 @@ func (pm *PlaceholderManager) CreatePlaceholders(files []RemoteFileInfo) error {
 				parent = ""
 			}
 			dirFiles[parent] = append(dirFiles[parent], f)
+			fileCount++
 		}
 
 		// Collect all parent directories that need to be created
@@ -77,10 +96,16 @@ func (pm *PlaceholderManager) CreatePlaceholders(files []RemoteFileInfo) error {
 		}
 	}
 
-	// Create file placeholders by directory
+	// Create file placeholders by directory, one batch per call
+	pacer := newPlaceholderPacer(pm.options, fileCount, progress)
 	for parentDir, dirFileList := range dirFiles {
-		if err := pm.createFilePlaceholders(parentDir, dirFileList); err != nil {
-			return fmt.Errorf("failed to create placeholders in %s: %w", parentDir, err)
+		for _, batch := range splitBatches(dirFileList, pacer.opts.BatchSize) {
+			if err := pm.createFilePlaceholders(parentDir, batch); err != nil {
+				return fmt.Errorf("failed to create placeholders in %s: %w", parentDir, err)
+			}
+			if err := pacer.batchDone(ctx, len(batch)); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -98,7 +123,7 @@ func (pm *PlaceholderManager) UpdatePlaceholder(file RemoteFileInfo) error {
 
 	// Open the file
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(fullPath),
+		windows.StringToUTF16Ptr(longpath.Fix(fullPath)),
 		windows.GENERIC_WRITE,
 		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
 		nil,
@@ -160,7 +185,7 @@ func (pm *PlaceholderManager) GetPlaceholderState(relativePath string) (Placehol
 
 	// Try to get extended attributes
 	handle, err := windows.CreateFile(
-		windows.StringToUTF16Ptr(fullPath),
+		windows.StringToUTF16Ptr(longpath.Fix(fullPath)),
 		0, // Query only
 		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
 		nil,
@@ -4,6 +4,7 @@
 package cloudfiles
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -256,3 +257,159 @@ func TestRemoteFileInfo(t *testing.T) {
 		t.Errorf("FileIdentity length mismatch: got %d", len(info.FileIdentity))
 	}
 }
+
+func TestDiffRemoteListing(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	previous := []RemoteFileInfo{
+		{Path: "same.txt", Size: 10, ModTime: t0},
+		{Path: "docs/changed.txt", Size: 10, ModTime: t0},
+		{Path: "docs/touched.txt", Size: 10, ModTime: t0},
+		{Path: "gone.txt", Size: 5, ModTime: t0},
+	}
+	current := []RemoteFileInfo{
+		{Path: "same.txt", Size: 10, ModTime: t0.Add(300 * time.Millisecond)}, // sub-second difference ignored
+		{Path: "docs\\changed.txt", Size: 12, ModTime: t0},
+		{Path: "docs/touched.txt", Size: 10, ModTime: t0.Add(time.Hour)},
+		{Path: "new.txt", Size: 1, ModTime: t0},
+		{Path: "docs", IsDirectory: true},
+	}
+
+	diff := DiffRemoteListing(previous, current)
+
+	if len(diff.Create) != 1 || diff.Create[0].Path != "new.txt" {
+		t.Errorf("Create = %+v, want [new.txt]", diff.Create)
+	}
+	if len(diff.Update) != 2 {
+		t.Errorf("Update = %+v, want 2 entries", diff.Update)
+	}
+	for _, u := range diff.Update {
+		if u.Previous.Size != 10 {
+			t.Errorf("Update %s should carry previous metadata", u.Current.Path)
+		}
+	}
+	if len(diff.Delete) != 1 || diff.Delete[0].Path != "gone.txt" {
+		t.Errorf("Delete = %+v, want [gone.txt]", diff.Delete)
+	}
+
+	if !DiffRemoteListing(previous, previous).IsEmpty() {
+		t.Error("identical listings should give an empty diff")
+	}
+}
+
+func TestRetainFailed(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	previous := []RemoteFileInfo{
+		{Path: "kept.txt", Size: 10, ModTime: t0},
+		{Path: "docs/changed.txt", Size: 10, ModTime: t0},
+		{Path: "gone.txt", Size: 5, ModTime: t0},
+	}
+	current := []RemoteFileInfo{
+		{Path: "kept.txt", Size: 10, ModTime: t0},
+		{Path: "docs/changed.txt", Size: 12, ModTime: t0.Add(time.Hour)},
+		{Path: "docs", IsDirectory: true},
+	}
+
+	if got := RetainFailed(previous, current, nil); len(got) != len(current) {
+		t.Errorf("RetainFailed without failures = %+v, want the current listing", got)
+	}
+
+	// The update of changed.txt and the deletion of gone.txt failed
+	snapshot := RetainFailed(previous, current, []string{`docs\changed.txt`, "gone.txt"})
+	byPath := make(map[string]RemoteFileInfo)
+	for _, f := range snapshot {
+		byPath[f.Path] = f
+	}
+	if len(snapshot) != 4 {
+		t.Errorf("snapshot = %+v, want 4 entries", snapshot)
+	}
+	if f := byPath["docs/changed.txt"]; f.Size != 10 {
+		t.Errorf("failed update saved with size %d, want previous size 10", f.Size)
+	}
+	if _, ok := byPath["gone.txt"]; !ok {
+		t.Error("failed deletion should stay in the snapshot")
+	}
+	next := DiffRemoteListing(snapshot, current)
+	if len(next.Update) != 1 || len(next.Delete) != 1 {
+		t.Errorf("next diff = %+v, want the failed update and deletion", next)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	files := make([]RemoteFileInfo, 2500)
+
+	batches := splitBatches(files, 1000)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+	if len(batches[0]) != 1000 || len(batches[2]) != 500 {
+		t.Errorf("unexpected batch sizes: %d, %d", len(batches[0]), len(batches[2]))
+	}
+
+	if got := splitBatches(nil, 1000); len(got) != 0 {
+		t.Errorf("expected no batch for empty list, got %d", len(got))
+	}
+}
+
+func TestPlaceholderCreationOptionsDefaults(t *testing.T) {
+	opts := PlaceholderCreationOptions{}.withDefaults()
+	if opts.BatchSize != DefaultPlaceholderBatchSize || opts.BatchPause != DefaultPlaceholderBatchPause {
+		t.Errorf("expected defaults, got %+v", opts)
+	}
+
+	// A batch never exceeds one second's worth of the rate limit
+	opts = PlaceholderCreationOptions{BatchSize: 1000, MaxPerSecond: 200}.withDefaults()
+	if opts.BatchSize != 200 {
+		t.Errorf("expected batch size capped to 200, got %d", opts.BatchSize)
+	}
+}
+
+func TestPlaceholderPacer(t *testing.T) {
+	var reported []int
+	pacer := newPlaceholderPacer(PlaceholderCreationOptions{BatchSize: 100}, 250, func(created, total int) {
+		reported = append(reported, created)
+	})
+
+	// Small directories are grouped before pausing
+	pacer.created, pacer.pending = 40, 40
+	if d := pacer.delay(0); d != 0 {
+		t.Errorf("expected no pause before a full batch, got %v", d)
+	}
+	pacer.created, pacer.pending = 100, 100
+	if d := pacer.delay(0); d != DefaultPlaceholderBatchPause {
+		t.Errorf("expected batch pause, got %v", d)
+	}
+
+	// Rate limit: 200 created at 100/s should take 2s
+	limited := newPlaceholderPacer(PlaceholderCreationOptions{MaxPerSecond: 100}, 1000, nil)
+	limited.created = 200
+	if d := limited.delay(500 * time.Millisecond); d != 1500*time.Millisecond {
+		t.Errorf("expected 1.5s wait, got %v", d)
+	}
+	if d := limited.delay(3 * time.Second); d > 0 {
+		t.Errorf("expected no wait when behind schedule, got %v", d)
+	}
+
+	// Progress is reported after each batch, without waiting after the last one
+	pacer = newPlaceholderPacer(PlaceholderCreationOptions{BatchSize: 100}, 150, func(created, total int) {
+		reported = append(reported, created)
+	})
+	reported = nil
+	ctx := context.Background()
+	if err := pacer.batchDone(ctx, 100); err != nil {
+		t.Fatalf("batchDone failed: %v", err)
+	}
+	if err := pacer.batchDone(ctx, 50); err != nil {
+		t.Fatalf("batchDone failed: %v", err)
+	}
+	if len(reported) != 2 || reported[1] != 150 {
+		t.Errorf("expected progress 100 then 150, got %v", reported)
+	}
+
+	// Cancellation stops the wait
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	pacer = newPlaceholderPacer(PlaceholderCreationOptions{BatchSize: 10}, 100, nil)
+	if err := pacer.batchDone(cancelled, 10); err == nil {
+		t.Error("expected error from cancelled context")
+	}
+}
@@ -0,0 +1,217 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Windows Cloud Files API bindings.
+// This file contains the environment probes run before enabling Files On Demand.
+package cloudfiles
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// MinCloudFilesBuild is the first Windows build with the Cloud Files API
+// (Windows 10 1709).
+const MinCloudFilesBuild = 16299
+
+// cldFltServiceKeyPath is the registration of the Cloud Files minifilter driver.
+const cldFltServiceKeyPath = `SYSTEM\CurrentControlSet\Services\CldFlt`
+
+// PrerequisiteCheck is the outcome of one Files On Demand prerequisite probe.
+type PrerequisiteCheck struct {
+	Name       string // What was checked (e.g. "Windows version")
+	OK         bool   // True if the prerequisite is met
+	Detail     string // What was found
+	Fix        string // What to do when the prerequisite is not met
+	NeedsAdmin bool   // Fix (or the probe itself) requires administrator rights
+}
+
+// Prerequisites is the result of CheckPrerequisites.
+type Prerequisites struct {
+	Checks   []PrerequisiteCheck
+	Elevated bool // The process runs with administrator rights
+}
+
+// OK reports whether all prerequisites are met.
+func (p *Prerequisites) OK() bool {
+	return len(p.Failed()) == 0
+}
+
+// Failed returns the checks that did not pass.
+func (p *Prerequisites) Failed() []PrerequisiteCheck {
+	var failed []PrerequisiteCheck
+	for _, c := range p.Checks {
+		if !c.OK {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Blocking reports whether a failed check can't be fixed by running
+// AnemoneSync as administrator (old Windows, unsupported volume, missing
+// driver): Files On Demand must not be enabled.
+func (p *Prerequisites) Blocking() bool {
+	for _, c := range p.Failed() {
+		if !c.NeedsAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes the failed checks and their fixes, one per line.
+func (p *Prerequisites) Summary() string {
+	var lines []string
+	for _, c := range p.Failed() {
+		line := fmt.Sprintf("%s: %s", c.Name, c.Detail)
+		if c.Fix != "" {
+			line += " " + c.Fix
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckPrerequisites probes everything Files On Demand needs for a sync root
+// at path: Windows build, cldapi.dll, an NTFS volume and the CldFlt
+// minifilter attached to that volume. Checking the attachment requires
+// administrator rights; without them the check fails with NeedsAdmin.
+func CheckPrerequisites(path string) *Prerequisites {
+	p := &Prerequisites{Elevated: windows.GetCurrentProcessToken().IsElevated()}
+
+	p.Checks = append(p.Checks, checkWindowsBuild(), checkCldAPI())
+
+	volume := checkVolumePrerequisite(path)
+	p.Checks = append(p.Checks, volume.check, checkCldFltService())
+	if volume.root != "" {
+		p.Checks = append(p.Checks, checkCldFltAttached(volume.root, p.Elevated))
+	}
+
+	return p
+}
+
+func checkWindowsBuild() PrerequisiteCheck {
+	version := windows.RtlGetVersion()
+	check := PrerequisiteCheck{
+		Name:   "Windows version",
+		OK:     version.BuildNumber >= MinCloudFilesBuild,
+		Detail: fmt.Sprintf("build %d", version.BuildNumber),
+	}
+	if !check.OK {
+		check.Detail += fmt.Sprintf(" is older than %d.", MinCloudFilesBuild)
+		check.Fix = "Update Windows to Windows 10 version 1709 or later."
+	}
+	return check
+}
+
+func checkCldAPI() PrerequisiteCheck {
+	check := PrerequisiteCheck{Name: "Cloud Files API"}
+	if err := cldapi.Load(); err != nil {
+		check.Detail = fmt.Sprintf("cldapi.dll not available (%v).", err)
+		check.Fix = "Update Windows to Windows 10 version 1709 or later."
+		return check
+	}
+	if err := procCfRegisterSyncRoot.Find(); err != nil {
+		check.Detail = "cldapi.dll does not export CfRegisterSyncRoot."
+		check.Fix = "Install the latest Windows updates."
+		return check
+	}
+
+	check.OK = true
+	check.Detail = "cldapi.dll loaded"
+	if info, err := GetPlatformInfo(); err == nil {
+		check.Detail += fmt.Sprintf(" (platform build %d, revision %d)", info.BuildNumber, info.RevisionNumber)
+	}
+	return check
+}
+
+type volumePrerequisite struct {
+	check PrerequisiteCheck
+	root  string // Volume root, "" if it could not be determined
+}
+
+func checkVolumePrerequisite(path string) volumePrerequisite {
+	check := PrerequisiteCheck{Name: "Volume"}
+	info, err := CheckVolume(path)
+	if err != nil {
+		check.Detail = fmt.Sprintf("could not inspect the drive (%v).", err)
+		check.Fix = "Check that the drive is connected."
+		return volumePrerequisite{check: check}
+	}
+
+	check.OK = info.Supported
+	check.Detail = fmt.Sprintf("%s on %s", info.FileSystem, info.Root)
+	if !check.OK {
+		check.Detail += "."
+		check.Fix = info.Reason
+	}
+	return volumePrerequisite{check: check, root: info.Root}
+}
+
+func checkCldFltService() PrerequisiteCheck {
+	check := PrerequisiteCheck{Name: "Cloud Files filter driver"}
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, cldFltServiceKeyPath, registry.QUERY_VALUE)
+	if err != nil {
+		check.Detail = "CldFlt is not installed."
+		check.Fix = "Install the latest Windows updates, or repair Windows with \"sfc /scannow\"."
+		return check
+	}
+	defer key.Close()
+
+	// Service start type 4 = disabled
+	start, _, err := key.GetIntegerValue("Start")
+	if err == nil && start == 4 {
+		check.Detail = "CldFlt is disabled."
+		check.Fix = "As administrator, run \"sc config CldFlt start= auto\" then restart Windows."
+		check.NeedsAdmin = true
+		return check
+	}
+
+	check.OK = true
+	check.Detail = "CldFlt installed"
+	return check
+}
+
+func checkCldFltAttached(root string, elevated bool) PrerequisiteCheck {
+	volume := strings.TrimSuffix(root, `\`)
+	check := PrerequisiteCheck{Name: "Cloud Files filter on " + volume, NeedsAdmin: true}
+	if !elevated {
+		check.Detail = "could not be verified without administrator rights."
+		check.Fix = "Run AnemoneSync as administrator once to verify it."
+		return check
+	}
+
+	attached, err := cldFltAttached(volume)
+	if err != nil {
+		check.Detail = fmt.Sprintf("could not be verified (%v).", err)
+		check.Fix = fmt.Sprintf("As administrator, run \"fltmc attach CldFlt %s\".", volume)
+		return check
+	}
+
+	check.OK = attached
+	if check.OK {
+		check.Detail = "CldFlt attached"
+	} else {
+		check.Detail = "CldFlt is not attached to the volume."
+		check.Fix = fmt.Sprintf("As administrator, run \"fltmc attach CldFlt %s\".", volume)
+	}
+	return check
+}
+
+// fltmcHasInstance reports whether the output of "fltmc instances" lists an
+// instance on volume (e.g. "C:").
+func fltmcHasInstance(output, volume string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && strings.EqualFold(strings.TrimSuffix(fields[0], `\`), volume) {
+			return true
+		}
+	}
+	return false
+}
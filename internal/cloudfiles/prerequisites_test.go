@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package cloudfiles
+
+import "testing"
+
+func TestCheckPrerequisites(t *testing.T) {
+	p := CheckPrerequisites(t.TempDir())
+	if len(p.Checks) < 4 {
+		t.Fatalf("expected at least 4 checks, got %d", len(p.Checks))
+	}
+	for _, c := range p.Checks {
+		t.Logf("%s: ok=%v %s %s", c.Name, c.OK, c.Detail, c.Fix)
+	}
+}
+
+func TestFltmcHasInstance(t *testing.T) {
+	output := `
+Instances for CldFlt filter:
+
+Volume Name                              Altitude        Instance Name       Frame   VlStatus
+-------------------------------------  ------------  ----------------------  -----   --------
+C:                                        180451        CldFlt                 0
+\Device\Mup                               180451        CldFlt                 0
+`
+	if !fltmcHasInstance(output, "C:") || !fltmcHasInstance(output, "c:") {
+		t.Error("expected an instance on C:")
+	}
+	if fltmcHasInstance(output, "D:") {
+		t.Error("expected no instance on D:")
+	}
+}
@@ -0,0 +1,194 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Windows Cloud Files API bindings.
+// This file keeps the start of placeholders on disk (partial placeholders),
+// so media managers can read headers and tags without hydrating the files,
+// and fetches the start of a file for the file inspector.
+package cloudfiles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+// previewAlignment is the granularity of partial hydration: Cloud Files
+// transfers data in 4 KB units (except the end of the file).
+const previewAlignment = 4096
+
+// PreviewFetchSize is the start of a file fetched to preview it in the file
+// inspector: enough for the first pages of a document or the header of media.
+const PreviewFetchSize = 256 << 10
+
+// PreviewRule keeps the first bytes of the files of a type on disk when their
+// placeholder is created or dehydrated (e.g. 64 KB of audio files for their
+// ID3 tags, or the EXIF block of pictures).
+type PreviewRule struct {
+	Class   filetype.Class // File type the rule applies to
+	MinSize int64          // Smaller files stay fully dehydrated (0 = all sizes)
+	Bytes   int64          // Bytes kept from the start of the file (rounded up to 4 KB)
+}
+
+// previewLength returns the bytes to keep on disk for a file of size bytes at
+// path: 0 if no rule applies, or if the preview would hold the whole file.
+func previewLength(rules []PreviewRule, path string, size int64) int64 {
+	if len(rules) == 0 {
+		return 0
+	}
+	class := filetype.ClassOf(path)
+	for _, rule := range rules {
+		if rule.Class != class || rule.Bytes <= 0 || size < rule.MinSize {
+			continue
+		}
+		n := (rule.Bytes + previewAlignment - 1) / previewAlignment * previewAlignment
+		if n >= size {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
+
+// SetPreviewRules sets the previews kept on disk when dehydrating files.
+func (dm *DehydrationManager) SetPreviewRules(rules []PreviewRule) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.previews = rules
+}
+
+// previewLength returns the bytes of a file kept on disk when dehydrating it.
+func (dm *DehydrationManager) previewLength(relativePath string, size int64) int64 {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return previewLength(dm.previews, relativePath, size)
+}
+
+// fetchPreviews hydrates the start of the placeholders matching a preview
+// rule. Files whose preview is already on disk are not downloaded again.
+func (p *CloudFilesProvider) fetchPreviews(ctx context.Context, files []RemoteFileInfo) {
+	p.mu.RLock()
+	rules := p.previewRules
+	hasSource := p.hydration != nil
+	p.mu.RUnlock()
+	if len(rules) == 0 || !hasSource {
+		return
+	}
+
+	fetched, failed := 0, 0
+	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		if file.IsDirectory {
+			continue
+		}
+		n := previewLength(rules, file.Path, file.Size)
+		if n == 0 {
+			continue
+		}
+		if err := hydrateRange(filepath.Join(p.syncRoot.Path(), filepath.FromSlash(file.Path)), n); err != nil {
+			failed++
+			p.logger.Debug("failed to fetch preview",
+				zap.String("path", file.Path),
+				zap.Error(err),
+			)
+			continue
+		}
+		fetched++
+	}
+
+	if fetched > 0 || failed > 0 {
+		p.logger.Info("placeholder previews fetched",
+			zap.Int("files", fetched),
+			zap.Int("failed", failed),
+		)
+	}
+}
+
+// hydrateRange hydrates the first length bytes of a placeholder, leaving it
+// partially on disk.
+func hydrateRange(fullPath string, length int64) error {
+	state, err := GetFilePlaceholderState(fullPath)
+	if err != nil {
+		return err
+	}
+	if state&CF_PLACEHOLDER_STATE_PLACEHOLDER == 0 {
+		return fmt.Errorf("not a placeholder")
+	}
+	if state&CF_PLACEHOLDER_STATE_PARTIAL == 0 {
+		return nil // Whole content already on disk
+	}
+
+	handle, err := windows.CreateFile(
+		windows.StringToUTF16Ptr(longpath.Fix(fullPath)),
+		windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS,
+		0,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer windows.CloseHandle(handle)
+
+	return HydratePlaceholder(handle, 0, length, 0)
+}
+
+// FetchPreview copies the first PreviewFetchSize bytes of a file into a new
+// temporary file in dir, through the ranged reads of hydration: the
+// placeholder is left as is, however large the file. It returns the path of
+// the copy and the bytes copied.
+func (p *CloudFilesProvider) FetchPreview(ctx context.Context, relativePath, dir string) (string, int64, error) {
+	p.mu.RLock()
+	source := p.dataSource
+	p.mu.RUnlock()
+	if source == nil {
+		return "", 0, fmt.Errorf("no data source configured")
+	}
+	return fetchPreview(ctx, source, relativePath, dir)
+}
+
+// fetchPreview copies the start of a remote file into a temporary file in
+// dir named after it ("report.preview-123.pdf"), so it opens with the
+// application of its type.
+func fetchPreview(ctx context.Context, source DataSource, relativePath, dir string) (string, int64, error) {
+	reader, err := source.GetFileReader(ctx, relativePath, 0)
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create preview folder: %w", err)
+	}
+	name := filepath.Base(filepath.FromSlash(relativePath))
+	ext := filepath.Ext(name)
+	out, err := os.CreateTemp(dir, strings.TrimSuffix(name, ext)+".preview-*"+ext)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create preview file: %w", err)
+	}
+
+	n, err := io.Copy(out, io.LimitReader(reader, PreviewFetchSize))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", 0, fmt.Errorf("failed to fetch preview: %w", err)
+	}
+	return out.Name(), n, nil
+}
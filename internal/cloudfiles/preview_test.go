@@ -0,0 +1,74 @@
+//go:build windows
+// +build windows
+
+package cloudfiles
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
+)
+
+func TestPreviewLength(t *testing.T) {
+	rules := []PreviewRule{
+		{Class: filetype.ClassAudio, MinSize: 1 << 20, Bytes: 64 << 10},
+		{Class: filetype.ClassImage, Bytes: 5000},
+	}
+
+	tests := []struct {
+		path string
+		size int64
+		want int64
+	}{
+		{"Music/song.mp3", 8 << 20, 64 << 10},  // Audio above the minimum size
+		{"Music/jingle.mp3", 512 << 10, 0},     // Audio below the minimum size
+		{"Photos/IMG_0001.JPG", 3 << 20, 8192}, // Rounded up to 4 KB
+		{"Photos/icon.png", 6000, 0},           // Preview would hold the whole file
+		{"Docs/report.pdf", 8 << 20, 0},        // No rule for documents
+	}
+
+	for _, tt := range tests {
+		if got := previewLength(rules, tt.path, tt.size); got != tt.want {
+			t.Errorf("previewLength(%q, %d) = %d, want %d", tt.path, tt.size, got, tt.want)
+		}
+	}
+
+	if got := previewLength(nil, "Music/song.mp3", 8<<20); got != 0 {
+		t.Errorf("previewLength without rules = %d, want 0", got)
+	}
+}
+
+func TestFetchPreview(t *testing.T) {
+	source := newMockDataSource()
+	large := bytes.Repeat([]byte("0123456789"), PreviewFetchSize/5)
+	source.AddFile("Docs/report.pdf", large)
+	source.AddFile("Docs/notes.txt", []byte("short"))
+	dir := filepath.Join(t.TempDir(), "previews")
+
+	path, n, err := fetchPreview(context.Background(), source, "Docs/report.pdf", dir)
+	if err != nil {
+		t.Fatalf("fetchPreview: %v", err)
+	}
+	if n != PreviewFetchSize {
+		t.Errorf("copied %d bytes, want %d", n, PreviewFetchSize)
+	}
+	if base := filepath.Base(path); !strings.HasPrefix(base, "report.preview-") || filepath.Ext(base) != ".pdf" {
+		t.Errorf("preview name = %s, want report.preview-*.pdf", base)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, large[:PreviewFetchSize]) {
+		t.Errorf("preview content differs from the start of the file (err %v)", err)
+	}
+
+	if _, n, err := fetchPreview(context.Background(), source, "Docs/notes.txt", dir); err != nil || n != 5 {
+		t.Errorf("small file: copied %d bytes, err %v, want the whole file", n, err)
+	}
+	if _, _, err := fetchPreview(context.Background(), source, "Docs/missing.pdf", dir); err == nil {
+		t.Error("fetchPreview of a missing file succeeded")
+	}
+}
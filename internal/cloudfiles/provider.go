@@ -34,6 +34,18 @@ type CloudFilesProvider struct {
 	// Data source for hydration
 	dataSource DataSource
 
+	// Progress of bulk placeholder creation (optional)
+	placeholderProgress PlaceholderProgressFunc
+
+	// Vetoes dehydration of files with pending local changes (optional)
+	dehydrationGuard DehydrationGuard
+
+	// Chunks prefetched from the server during hydration
+	readAheadDepth int
+
+	// Start of files kept on disk in placeholders (partial placeholders)
+	previewRules []PreviewRule
+
 	// Context for bridge
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -57,6 +69,19 @@ type ProviderConfig struct {
 	ProviderName string // Provider name for Windows (default: "AnemoneSync")
 	Logger       *zap.Logger
 	UseCGOBridge bool // Use CGO bridge for callbacks (recommended for proper hydration)
+
+	// Placeholder creation batching and rate limit (zero values = defaults)
+	PlaceholderOptions PlaceholderCreationOptions
+
+	// Chunks prefetched during hydration (0 = DefaultReadAheadDepth, negative = disabled)
+	ReadAheadDepth int
+
+	// Accounts seeing the sync root (default: SyncRootScopeUser)
+	SyncRootScope SyncRootScope
+
+	// Start of files kept on disk by file type and size, for media managers
+	// reading headers and tags (none = placeholders hold no data)
+	PreviewRules []PreviewRule
 }
 
 // NewCloudFilesProvider creates a new CloudFilesProvider.
@@ -70,6 +95,11 @@ func NewCloudFilesProvider(config ProviderConfig) (*CloudFilesProvider, error) {
 	if config.Logger == nil {
 		config.Logger = zap.NewNop()
 	}
+	if config.ReadAheadDepth == 0 {
+		config.ReadAheadDepth = DefaultReadAheadDepth
+	} else if config.ReadAheadDepth < 0 {
+		config.ReadAheadDepth = 0
+	}
 
 	// Create sync root manager
 	syncRootConfig := SyncRootConfig{
@@ -77,6 +107,7 @@ func NewCloudFilesProvider(config ProviderConfig) (*CloudFilesProvider, error) {
 		ProviderName: config.ProviderName,
 		ProviderID:   DefaultProviderID(),
 		UseCGOBridge: config.UseCGOBridge,
+		Scope:        config.SyncRootScope,
 	}
 
 	syncRoot, err := NewSyncRootManager(syncRootConfig)
@@ -92,7 +123,15 @@ func NewCloudFilesProvider(config ProviderConfig) (*CloudFilesProvider, error) {
 		syncRoot:     syncRoot,
 		placeholders: NewPlaceholderManager(syncRoot),
 		logger:       config.Logger,
+
+		readAheadDepth: config.ReadAheadDepth,
+		previewRules:   config.PreviewRules,
 	}
+	provider.placeholders.SetCreationOptions(config.PlaceholderOptions)
+
+	// Let the app veto dehydrations (e.g. by Storage Sense) that would lose local changes
+	syncRoot.SetNotifyDehydrateCallback(provider.onNotifyDehydrate)
+	syncRoot.SetDehydrateCompleteCallback(provider.onDehydrateComplete)
 
 	return provider, nil
 }
@@ -107,10 +146,12 @@ func (p *CloudFilesProvider) SetDataSource(source DataSource) {
 	if source != nil {
 		adapter := &dataSourceAdapter{source: source, remotePath: p.remotePath}
 		p.hydration = NewHydrationHandler(p.syncRoot, adapter, p.logger)
+		p.hydration.SetReadAheadDepth(p.readAheadDepth)
 
 		// IMPORTANT: Set up global data provider for CGO callbacks
 		// The new architecture calls Go directly from C, so we need a global provider
 		SetGlobalDataProvider(adapter, p.localPath, p.logger)
+		SetReadAheadDepth(p.readAheadDepth)
 
 		// If already initialized with bridge, update the callback
 		// Note: With the new architecture, the bridge doesn't use this callback anymore,
@@ -124,6 +165,37 @@ func (p *CloudFilesProvider) SetDataSource(source DataSource) {
 	}
 }
 
+// SetPlaceholderProgress sets the callback reporting bulk placeholder creation progress.
+func (p *CloudFilesProvider) SetPlaceholderProgress(progress PlaceholderProgressFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.placeholderProgress = progress
+}
+
+// placeholderProgressLogInterval is the number of placeholders between progress logs.
+const placeholderProgressLogInterval = 10000
+
+// createPlaceholders creates placeholders in batches, logging progress of large creations.
+func (p *CloudFilesProvider) createPlaceholders(ctx context.Context, files []RemoteFileInfo) error {
+	p.mu.RLock()
+	progress := p.placeholderProgress
+	p.mu.RUnlock()
+
+	lastLogged := 0
+	return p.placeholders.CreatePlaceholdersWithProgress(ctx, files, func(created, total int) {
+		if created-lastLogged >= placeholderProgressLogInterval || (created == total && lastLogged > 0) {
+			lastLogged = created
+			p.logger.Info("creating placeholders",
+				zap.Int("created", created),
+				zap.Int("total", total),
+			)
+		}
+		if progress != nil {
+			progress(created, total)
+		}
+	})
+}
+
 // Initialize registers the sync root and connects to receive callbacks.
 func (p *CloudFilesProvider) Initialize(ctx context.Context) error {
 	p.mu.Lock()
@@ -144,6 +216,15 @@ func (p *CloudFilesProvider) Initialize(ctx context.Context) error {
 		return fmt.Errorf("failed to register sync root: %w", err)
 	}
 
+	// Share with (or stop sharing with) the other accounts of the PC; the
+	// sync root keeps working for the current account if that fails
+	if err := p.syncRoot.applyScope(); err != nil {
+		p.logger.Warn("failed to apply sync root scope",
+			zap.String("scope", string(p.syncRoot.Scope())),
+			zap.Error(err),
+		)
+	}
+
 	// Connect using CGO bridge if enabled
 	if p.useCGOBridge {
 		// Create context for bridge
@@ -190,10 +271,13 @@ func (p *CloudFilesProvider) SyncPlaceholders(ctx context.Context, remoteFiles [
 	)
 
 	// Create placeholders for all remote files
-	if err := p.placeholders.CreatePlaceholders(remoteFiles); err != nil {
+	if err := p.createPlaceholders(ctx, remoteFiles); err != nil {
 		return fmt.Errorf("failed to create placeholders: %w", err)
 	}
 
+	// Download the start of the files with a preview rule in the background
+	go p.fetchPreviews(ctx, remoteFiles)
+
 	// TODO: Remove placeholders that no longer exist remotely
 	// This requires scanning the local directory and comparing
 
@@ -325,12 +409,20 @@ func (p *CloudFilesProvider) SetDehydrationPolicy(policy DehydrationPolicy) {
 	defer p.mu.Unlock()
 
 	if p.dehydration == nil {
-		p.dehydration = NewDehydrationManager(p.syncRoot, policy, p.logger)
+		p.dehydration = p.newDehydrationManager(policy)
 	} else {
 		p.dehydration.SetPolicy(policy)
 	}
 }
 
+// newDehydrationManager creates the dehydration manager of the sync root,
+// keeping the previews of the provider on disk.
+func (p *CloudFilesProvider) newDehydrationManager(policy DehydrationPolicy) *DehydrationManager {
+	dm := NewDehydrationManager(p.syncRoot, policy, p.logger)
+	dm.SetPreviewRules(p.previewRules)
+	return dm
+}
+
 // GetDehydrationPolicy returns the current dehydration policy.
 func (p *CloudFilesProvider) GetDehydrationPolicy() DehydrationPolicy {
 	p.mu.RLock()
@@ -346,7 +438,7 @@ func (p *CloudFilesProvider) GetDehydrationPolicy() DehydrationPolicy {
 func (p *CloudFilesProvider) StartAutoDehydration(ctx context.Context) error {
 	p.mu.Lock()
 	if p.dehydration == nil {
-		p.dehydration = NewDehydrationManager(p.syncRoot, DefaultDehydrationPolicy(), p.logger)
+		p.dehydration = p.newDehydrationManager(DefaultDehydrationPolicy())
 	}
 	dehydration := p.dehydration
 	p.mu.Unlock()
@@ -369,7 +461,7 @@ func (p *CloudFilesProvider) StopAutoDehydration() {
 func (p *CloudFilesProvider) DehydrateFile(ctx context.Context, relativePath string) error {
 	p.mu.Lock()
 	if p.dehydration == nil {
-		p.dehydration = NewDehydrationManager(p.syncRoot, DefaultDehydrationPolicy(), p.logger)
+		p.dehydration = p.newDehydrationManager(DefaultDehydrationPolicy())
 	}
 	dehydration := p.dehydration
 	p.mu.Unlock()
@@ -381,7 +473,7 @@ func (p *CloudFilesProvider) DehydrateFile(ctx context.Context, relativePath str
 func (p *CloudFilesProvider) DehydrateAll(ctx context.Context) (int, int64, error) {
 	p.mu.Lock()
 	if p.dehydration == nil {
-		p.dehydration = NewDehydrationManager(p.syncRoot, DefaultDehydrationPolicy(), p.logger)
+		p.dehydration = p.newDehydrationManager(DefaultDehydrationPolicy())
 	}
 	dehydration := p.dehydration
 	p.mu.Unlock()
@@ -393,7 +485,7 @@ func (p *CloudFilesProvider) DehydrateAll(ctx context.Context) (int, int64, erro
 func (p *CloudFilesProvider) GetSpaceUsage(ctx context.Context) (SpaceUsage, error) {
 	p.mu.Lock()
 	if p.dehydration == nil {
-		p.dehydration = NewDehydrationManager(p.syncRoot, DefaultDehydrationPolicy(), p.logger)
+		p.dehydration = p.newDehydrationManager(DefaultDehydrationPolicy())
 	}
 	dehydration := p.dehydration
 	p.mu.Unlock()
@@ -419,7 +511,7 @@ func (p *CloudFilesProvider) GetDehydrationManager() *DehydrationManager {
 	defer p.mu.Unlock()
 
 	if p.dehydration == nil {
-		p.dehydration = NewDehydrationManager(p.syncRoot, DefaultDehydrationPolicy(), p.logger)
+		p.dehydration = p.newDehydrationManager(DefaultDehydrationPolicy())
 	}
 	return p.dehydration
 }
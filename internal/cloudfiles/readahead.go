@@ -0,0 +1,169 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Go bindings for the Windows Cloud Files API.
+package cloudfiles
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultReadAheadDepth is the number of chunks prefetched ahead of the one
+// being delivered to Windows during hydration.
+const DefaultReadAheadDepth = 4
+
+// hydrationStreamIdleTimeout closes cached hydration streams left unused
+// (file closed before being fully read, or read out of order).
+const hydrationStreamIdleTimeout = 30 * time.Second
+
+// readAheadReader reads its source in a background goroutine and keeps up to
+// depth chunks ready, so that the next SMB round trip overlaps with handing
+// the current chunk to CfExecute.
+type readAheadReader struct {
+	src     io.ReadCloser
+	chunks  chan []byte
+	stop    chan struct{}
+	done    chan struct{}
+	err     error  // Read error of the source, valid once chunks is closed
+	current []byte // Unread part of the current chunk
+
+	closeOnce sync.Once
+}
+
+// newReadAheadReader wraps src with read-ahead. A depth of 0 or less disables
+// read-ahead and returns src unchanged.
+func newReadAheadReader(src io.ReadCloser, chunkSize, depth int) io.ReadCloser {
+	if depth <= 0 || chunkSize <= 0 {
+		return src
+	}
+
+	r := &readAheadReader{
+		src:    src,
+		chunks: make(chan []byte, depth),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go r.prefetch(chunkSize)
+	return r
+}
+
+// prefetch reads chunks from the source until EOF, an error or Close.
+func (r *readAheadReader) prefetch(chunkSize int) {
+	defer close(r.done)
+	defer close(r.chunks)
+
+	for {
+		buf := make([]byte, chunkSize)
+		n, err := io.ReadFull(r.src, buf)
+		if n > 0 {
+			select {
+			case r.chunks <- buf[:n]:
+			case <-r.stop:
+				return
+			}
+		}
+		if err != nil {
+			if err != io.ErrUnexpectedEOF {
+				r.err = err
+			} else {
+				r.err = io.EOF
+			}
+			return
+		}
+	}
+}
+
+// Read implements io.Reader.
+func (r *readAheadReader) Read(p []byte) (int, error) {
+	if len(r.current) == 0 {
+		chunk, ok := <-r.chunks
+		if !ok {
+			return 0, r.err
+		}
+		r.current = chunk
+	}
+
+	n := copy(p, r.current)
+	r.current = r.current[n:]
+	return n, nil
+}
+
+// Close stops prefetching and closes the source.
+func (r *readAheadReader) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		err = r.src.Close() // Unblocks a pending read
+		<-r.done
+	})
+	return err
+}
+
+// hydrationStream is an open reader kept between fetch requests of a file,
+// positioned at offset.
+type hydrationStream struct {
+	reader   io.ReadCloser
+	offset   int64
+	lastUsed time.Time
+}
+
+// hydrationStreamCache keeps one read-ahead stream per file being hydrated,
+// so that consecutive chunk requests reuse it instead of reopening the file.
+type hydrationStreamCache struct {
+	mu      sync.Mutex
+	streams map[string]*hydrationStream
+}
+
+func newHydrationStreamCache() *hydrationStreamCache {
+	return &hydrationStreamCache{streams: make(map[string]*hydrationStream)}
+}
+
+// take removes and returns the stream of path if it is positioned at offset.
+// A stream at another offset is closed. Idle streams are closed too.
+func (c *hydrationStreamCache) take(path string, offset int64) io.ReadCloser {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for p, stream := range c.streams {
+		if p != path && now.Sub(stream.lastUsed) > hydrationStreamIdleTimeout {
+			stream.reader.Close()
+			delete(c.streams, p)
+		}
+	}
+
+	stream, ok := c.streams[path]
+	if !ok {
+		return nil
+	}
+	delete(c.streams, path)
+	if stream.offset != offset {
+		stream.reader.Close()
+		return nil
+	}
+	return stream.reader
+}
+
+// put stores the stream of path, positioned at offset, for the next request.
+func (c *hydrationStreamCache) put(path string, reader io.ReadCloser, offset int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.streams[path]; ok {
+		old.reader.Close()
+	}
+	c.streams[path] = &hydrationStream{reader: reader, offset: offset, lastUsed: time.Now()}
+}
+
+// closeAll closes every cached stream.
+func (c *hydrationStreamCache) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path, stream := range c.streams {
+		stream.reader.Close()
+		delete(c.streams, path)
+	}
+}
@@ -0,0 +1,183 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Go bindings for the Windows Cloud Files API.
+package cloudfiles
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Registry locations of Windows Storage Sense settings (per user) and of
+// registered sync roots (per machine).
+const (
+	storagePolicyKeyPath   = `Software\Microsoft\Windows\CurrentVersion\StorageSense\Parameters\StoragePolicy`
+	syncRootManagerKeyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Explorer\SyncRootManager`
+
+	storageSenseEnabledValue   = "01"   // Storage Sense turned on
+	storageSenseFrequencyValue = "2048" // Run every N days (0 = when disk space is low)
+	cloudDehydrateEnabledValue = "02"   // Per sync root: dehydrate unused cloud content
+	cloudDehydrateDaysValue    = "128"  // Per sync root: days unused before dehydration
+)
+
+// storageSenseDayChoices are the thresholds offered by Windows Settings.
+var storageSenseDayChoices = []int{1, 14, 30, 60}
+
+// StorageSenseSettings describes how Storage Sense treats a sync root.
+type StorageSenseSettings struct {
+	SyncRootID       string // Sync root registration ID (empty if not registered)
+	Enabled          bool   // Storage Sense is turned on
+	RunEveryDays     int    // How often Storage Sense runs (0 = when disk space is low)
+	CloudDehydration bool   // Storage Sense dehydrates unused content of this sync root
+	DehydrateDays    int    // Days unused before Storage Sense dehydrates a file
+	Configured       bool   // A policy is set for this sync root (by the user or AnemoneSync)
+}
+
+// Active reports whether Storage Sense will dehydrate files of the sync root.
+func (s StorageSenseSettings) Active() bool {
+	return s.Enabled && s.CloudDehydration && s.DehydrateDays > 0
+}
+
+// String describes the settings for display.
+func (s StorageSenseSettings) String() string {
+	switch {
+	case !s.Enabled:
+		return "Storage Sense is turned off in Windows Settings"
+	case s.Active():
+		return fmt.Sprintf("Storage Sense frees files unused for %d days", s.DehydrateDays)
+	default:
+		return "Storage Sense does not free files of this folder"
+	}
+}
+
+// FindSyncRootID returns the registration ID of the sync root at localPath.
+func FindSyncRootID(localPath string) (string, error) {
+	root, err := registry.OpenKey(registry.LOCAL_MACHINE, syncRootManagerKeyPath, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return "", fmt.Errorf("failed to open sync root registrations: %w", err)
+	}
+	defer root.Close()
+
+	ids, err := root.ReadSubKeyNames(-1)
+	if err != nil {
+		return "", fmt.Errorf("failed to list sync roots: %w", err)
+	}
+
+	want := filepath.Clean(localPath)
+	for _, id := range ids {
+		if syncRootHasPath(id, want) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no sync root registered for %s", localPath)
+}
+
+// syncRootHasPath reports whether one of the user paths of sync root id is path.
+func syncRootHasPath(id, path string) bool {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, syncRootManagerKeyPath+`\`+id+`\UserSyncRoots`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+
+	names, err := key.ReadValueNames(-1)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		value, _, err := key.GetStringValue(name)
+		if err == nil && strings.EqualFold(filepath.Clean(value), path) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStorageSenseSettings reads the Storage Sense settings of the sync root at localPath.
+func GetStorageSenseSettings(localPath string) (StorageSenseSettings, error) {
+	var settings StorageSenseSettings
+
+	key, err := registry.OpenKey(registry.CURRENT_USER, storagePolicyKeyPath, registry.QUERY_VALUE)
+	if err == nil {
+		settings.Enabled = readDWORD(key, storageSenseEnabledValue) == 1
+		settings.RunEveryDays = int(readDWORD(key, storageSenseFrequencyValue))
+		key.Close()
+	}
+
+	id, err := FindSyncRootID(localPath)
+	if err != nil {
+		return settings, err
+	}
+	settings.SyncRootID = id
+
+	key, err = registry.OpenKey(registry.CURRENT_USER, storagePolicyKeyPath+`\`+id, registry.QUERY_VALUE)
+	if err == nil {
+		_, _, flagErr := key.GetIntegerValue(cloudDehydrateEnabledValue)
+		settings.Configured = flagErr == nil
+		settings.CloudDehydration = readDWORD(key, cloudDehydrateEnabledValue) == 1
+		settings.DehydrateDays = int(readDWORD(key, cloudDehydrateDaysValue))
+		key.Close()
+	}
+
+	return settings, nil
+}
+
+// SetStorageSenseDehydration opts the sync root at localPath in or out of
+// Storage Sense cloud content dehydration. days is rounded to a threshold
+// offered by Windows Settings. Storage Sense itself must be turned on by the user.
+// It replaces the choice made in Windows Settings for the sync root: only call
+// it when the user changes the setting of the job.
+func SetStorageSenseDehydration(localPath string, enabled bool, days int) error {
+	id, err := FindSyncRootID(localPath)
+	if err != nil {
+		return err
+	}
+
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, storagePolicyKeyPath+`\`+id, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open Storage Sense policy: %w", err)
+	}
+	defer key.Close()
+
+	var flag uint32
+	if enabled {
+		flag = 1
+	}
+	if err := key.SetDWordValue(cloudDehydrateEnabledValue, flag); err != nil {
+		return fmt.Errorf("failed to set Storage Sense policy: %w", err)
+	}
+	if enabled {
+		if err := key.SetDWordValue(cloudDehydrateDaysValue, uint32(StorageSenseDays(days))); err != nil {
+			return fmt.Errorf("failed to set Storage Sense threshold: %w", err)
+		}
+	}
+	return nil
+}
+
+// StorageSenseDays rounds days up to the nearest threshold offered by
+// Storage Sense (1, 14, 30 or 60 days); longer delays get the longest one,
+// 60 days. 0 means the Windows default (30 days).
+func StorageSenseDays(days int) int {
+	if days <= 0 {
+		return 30
+	}
+	for _, choice := range storageSenseDayChoices {
+		if days <= choice {
+			return choice
+		}
+	}
+	return storageSenseDayChoices[len(storageSenseDayChoices)-1]
+}
+
+// readDWORD returns a DWORD registry value, or 0 if it is missing.
+func readDWORD(key registry.Key, name string) uint64 {
+	value, _, err := key.GetIntegerValue(name)
+	if err != nil {
+		return 0
+	}
+	return value
+}
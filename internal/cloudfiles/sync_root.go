@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"unsafe"
 
@@ -27,6 +28,7 @@ type SyncRootManager struct {
 	providerVersion string
 	providerID      GUID
 	useCGOBridge    bool
+	scope           SyncRootScope
 
 	// State
 	registered bool
@@ -40,6 +42,9 @@ type SyncRootManager struct {
 	cancelFetchCallback  CancelFetchCallback
 	notifyDeleteCallback NotifyDeleteCallback
 	notifyRenameCallback NotifyRenameCallback
+
+	notifyDehydrateCallback   NotifyDehydrateCallback
+	dehydrateCompleteCallback DehydrateCompleteCallback
 }
 
 // FetchDataCallback is called when a placeholder needs to be hydrated.
@@ -65,13 +70,21 @@ type NotifyDeleteCallback func(filePath string, isDirectory bool) bool
 // NotifyRenameCallback is called when a file is being renamed.
 type NotifyRenameCallback func(sourcePath, targetPath string, isDirectory bool) bool
 
+// NotifyDehydrateCallback is called before a placeholder is dehydrated.
+// Return false to veto the dehydration.
+type NotifyDehydrateCallback func(filePath string, reason DehydrationReason) bool
+
+// DehydrateCompleteCallback is called after a dehydration attempt.
+type DehydrateCompleteCallback func(filePath string, reason DehydrationReason, dehydrated bool)
+
 // SyncRootConfig contains configuration for creating a sync root.
 type SyncRootConfig struct {
-	Path            string // Local folder path
-	ProviderName    string // e.g., "AnemoneSync"
-	ProviderVersion string // e.g., "1.0.0"
-	ProviderID      GUID   // Unique identifier for the provider
-	UseCGOBridge    bool   // Use CGO bridge for callbacks (recommended)
+	Path            string        // Local folder path
+	ProviderName    string        // e.g., "AnemoneSync"
+	ProviderVersion string        // e.g., "1.0.0"
+	ProviderID      GUID          // Unique identifier for the provider
+	UseCGOBridge    bool          // Use CGO bridge for callbacks (recommended)
+	Scope           SyncRootScope // Accounts seeing the sync root (default: SyncRootScopeUser)
 }
 
 // DefaultProviderID returns a default GUID for AnemoneSync.
@@ -96,6 +109,9 @@ func NewSyncRootManager(config SyncRootConfig) (*SyncRootManager, error) {
 	if config.ProviderVersion == "" {
 		config.ProviderVersion = "1.0.0"
 	}
+	if config.Scope == "" {
+		config.Scope = SyncRootScopeUser
+	}
 
 	// Ensure path is absolute
 	absPath, err := filepath.Abs(config.Path)
@@ -109,9 +125,15 @@ func NewSyncRootManager(config SyncRootConfig) (*SyncRootManager, error) {
 		providerVersion: config.ProviderVersion,
 		providerID:      config.ProviderID,
 		useCGOBridge:    config.UseCGOBridge,
+		scope:           config.Scope,
 	}, nil
 }
 
+// Scope returns the accounts the sync root is registered for.
+func (m *SyncRootManager) Scope() SyncRootScope {
+	return m.scope
+}
+
 // Path returns the sync root path.
 func (m *SyncRootManager) Path() string {
 	return m.path
@@ -195,6 +217,13 @@ func (m *SyncRootManager) Unregister() error {
 		return fmt.Errorf("failed to unregister sync root: %w", err)
 	}
 
+	// A sync root shared with all accounts also has a machine-wide
+	// navigation pane entry, which needs administrator rights to remove
+	if err := UnshareSyncRoot(m.path); err != nil {
+		m.registered = false
+		return fmt.Errorf("sync root unregistered but still shown to other accounts: %w", err)
+	}
+
 	m.registered = false
 	return nil
 }
@@ -322,6 +351,25 @@ func (m *SyncRootManager) ConnectWithBridge(ctx context.Context, logger *zap.Log
 			}
 			return true
 		},
+		OnNotifyDehydrate: func(filePath string, reason DehydrationReason) bool {
+			m.mu.RLock()
+			cb := m.notifyDehydrateCallback
+			m.mu.RUnlock()
+
+			if cb != nil {
+				return cb(filePath, reason)
+			}
+			return true
+		},
+		OnDehydrateComplete: func(filePath string, reason DehydrationReason, dehydrated bool) {
+			m.mu.RLock()
+			cb := m.dehydrateCompleteCallback
+			m.mu.RUnlock()
+
+			if cb != nil {
+				cb(filePath, reason, dehydrated)
+			}
+		},
 	})
 
 	// Start the bridge
@@ -375,6 +423,51 @@ func (m *SyncRootManager) SetNotifyRenameCallback(cb NotifyRenameCallback) {
 	m.notifyRenameCallback = cb
 }
 
+// SetNotifyDehydrateCallback sets the callback deciding whether a placeholder may be dehydrated.
+// Only the CGO bridge registers NOTIFY_DEHYDRATE.
+func (m *SyncRootManager) SetNotifyDehydrateCallback(cb NotifyDehydrateCallback) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notifyDehydrateCallback = cb
+}
+
+// SetDehydrateCompleteCallback sets the callback for completed dehydrations.
+func (m *SyncRootManager) SetDehydrateCompleteCallback(cb DehydrateCompleteCallback) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dehydrateCompleteCallback = cb
+}
+
+// RelativePath converts a callback NormalizedPath (\<path_from_volume_root>\<relative_path>)
+// to a path relative to the sync root, with forward slashes.
+// e.g., for sync root D:\Anemone\backup: \Anemone\backup\subdir\file.txt -> subdir/file.txt
+func (m *SyncRootManager) RelativePath(normalizedPath string) string {
+	return relativeToSyncRoot(m.Path(), normalizedPath)
+}
+
+// relativeToSyncRoot strips the sync root (from volume root) from a normalized path.
+func relativeToSyncRoot(syncRootPath, normalizedPath string) string {
+	relativePath := strings.TrimPrefix(normalizedPath, "\\")
+	relativePath = strings.TrimPrefix(relativePath, "/")
+
+	// For D:\Anemone\backup, we need to strip "Anemone\backup\" (not just "backup\")
+	volName := filepath.VolumeName(syncRootPath)
+	syncRootRelative := strings.TrimPrefix(syncRootPath, volName)
+	syncRootRelative = strings.TrimPrefix(syncRootRelative, "\\")
+	syncRootRelative = strings.TrimPrefix(syncRootRelative, "/")
+
+	if syncRootRelative != "" {
+		if strings.HasPrefix(relativePath, syncRootRelative+"\\") {
+			relativePath = relativePath[len(syncRootRelative)+1:]
+		} else if strings.HasPrefix(relativePath, syncRootRelative+"/") {
+			relativePath = relativePath[len(syncRootRelative)+1:]
+		}
+	}
+
+	// Normalize to forward slashes
+	return strings.ReplaceAll(relativePath, "\\", "/")
+}
+
 // Close disconnects and unregisters the sync root.
 func (m *SyncRootManager) Close() error {
 	if err := m.Disconnect(); err != nil {
@@ -0,0 +1,298 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Windows Cloud Files API bindings.
+// This file shares a sync root with every account of the PC.
+package cloudfiles
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// SyncRootScope selects which Windows accounts see a sync root.
+type SyncRootScope string
+
+const (
+	// SyncRootScopeUser registers the sync root for the current account only
+	// (CfRegisterSyncRoot default).
+	SyncRootScopeUser SyncRootScope = "user"
+	// SyncRootScopeMachine also shows the sync root to every account of the
+	// PC: the folder is opened to the local Users group and pinned in the
+	// Explorer navigation pane of all accounts. Files are hydrated by the
+	// AnemoneSync instance of the registering account, which must be running.
+	SyncRootScopeMachine SyncRootScope = "machine"
+)
+
+// ErrMachineScopeNeedsAdmin is returned when sharing a sync root with all
+// accounts (or stopping to) without administrator rights.
+var ErrMachineScopeNeedsAdmin = errors.New("sharing a sync root with all accounts requires administrator rights")
+
+// Registry locations of the machine-wide Explorer namespace entries.
+const (
+	namespaceClassesKeyPath = `SOFTWARE\Classes\CLSID`
+	namespaceDesktopKeyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Explorer\Desktop\NameSpace`
+	namespaceHideKeyPath    = `SOFTWARE\Microsoft\Windows\CurrentVersion\Explorer\HideDesktopIcons\NewStartPanel`
+
+	// namespaceOwnerValue marks the CLSID keys created by AnemoneSync
+	namespaceOwnerValue = "AnemoneSyncRoot"
+
+	// Shell folder delegating to a file system folder (CLSID_FolderShortcut)
+	folderShortcutCLSID = "{0E5AAE11-A475-4c5b-AB00-C66DE400274E}"
+)
+
+// usersAccess is the access ShareSyncRoot gives the local Users group on the
+// folder, inherited by its files and subfolders.
+const (
+	usersAccessMask  = windows.FILE_GENERIC_READ | windows.FILE_GENERIC_WRITE | windows.FILE_GENERIC_EXECUTE | windows.DELETE
+	usersAccessFlags = windows.OBJECT_INHERIT_ACE | windows.CONTAINER_INHERIT_ACE
+)
+
+var procDeleteAce = windows.NewLazySystemDLL("advapi32.dll").NewProc("DeleteAce")
+
+// CanShareSyncRoots reports whether this process may register sync roots for
+// all accounts (or unshare them).
+func CanShareSyncRoots() error {
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		return ErrMachineScopeNeedsAdmin
+	}
+	return nil
+}
+
+// IsSyncRootShared reports whether the sync root at path is shared with all
+// accounts of the PC.
+func IsSyncRootShared(path string) bool {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, namespaceClassesKeyPath+`\`+namespaceCLSID(path), registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	key.Close()
+	return true
+}
+
+// ShareSyncRoot shows the sync root at path to every account of the PC:
+// the local Users group gets modify rights on the folder and the folder is
+// pinned in the Explorer navigation pane of all accounts under name.
+func ShareSyncRoot(path, name string) error {
+	if err := CanShareSyncRoots(); err != nil {
+		return err
+	}
+	path = filepath.Clean(path)
+
+	if err := grantUsersAccess(path); err != nil {
+		return fmt.Errorf("failed to open %s to all users: %w", path, err)
+	}
+	if err := addNamespaceEntry(path, name); err != nil {
+		return fmt.Errorf("failed to add %s to the navigation pane of all users: %w", path, err)
+	}
+	return nil
+}
+
+// UnshareSyncRoot removes the machine-wide navigation pane entry of the sync
+// root at path and the modify rights ShareSyncRoot gave the local Users group
+// (other permissions of the folder are kept). It is a no-op if the sync root
+// is not shared.
+func UnshareSyncRoot(path string) error {
+	path = filepath.Clean(path)
+	if !IsSyncRootShared(path) {
+		return nil
+	}
+	if err := CanShareSyncRoots(); err != nil {
+		return err
+	}
+
+	clsid := namespaceCLSID(path)
+	for _, keyPath := range []string{
+		namespaceClassesKeyPath + `\` + clsid + `\Instance\InitPropertyBag`,
+		namespaceClassesKeyPath + `\` + clsid + `\Instance`,
+		namespaceClassesKeyPath + `\` + clsid + `\InProcServer32`,
+		namespaceClassesKeyPath + `\` + clsid + `\ShellFolder`,
+		namespaceClassesKeyPath + `\` + clsid + `\DefaultIcon`,
+		namespaceClassesKeyPath + `\` + clsid,
+		namespaceDesktopKeyPath + `\` + clsid,
+	} {
+		if err := registry.DeleteKey(registry.LOCAL_MACHINE, keyPath); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return fmt.Errorf("failed to delete %s: %w", keyPath, err)
+		}
+	}
+
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, namespaceHideKeyPath, registry.SET_VALUE); err == nil {
+		key.DeleteValue(clsid)
+		key.Close()
+	}
+
+	if err := revokeUsersAccess(path); err != nil {
+		return fmt.Errorf("failed to close %s to other users: %w", path, err)
+	}
+	return nil
+}
+
+// applyScope shares or unshares the sync root according to its scope.
+func (m *SyncRootManager) applyScope() error {
+	if m.scope == SyncRootScopeMachine {
+		return ShareSyncRoot(m.path, m.providerName+" - "+filepath.Base(m.path))
+	}
+	return UnshareSyncRoot(m.path)
+}
+
+// grantUsersAccess gives the local Users group inherited modify rights on dir.
+func grantUsersAccess(dir string) error {
+	users, err := windows.CreateWellKnownSid(windows.WinBuiltinUsersSid)
+	if err != nil {
+		return err
+	}
+
+	sd, err := windows.GetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	current, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+
+	dacl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{{
+		AccessPermissions: usersAccessMask,
+		AccessMode:        windows.GRANT_ACCESS,
+		Inheritance:       windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_WELL_KNOWN_GROUP,
+			TrusteeValue: windows.TrusteeValueFromSID(users),
+		},
+	}}, current)
+	if err != nil {
+		return err
+	}
+
+	return windows.SetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
+
+// revokeUsersAccess removes the entry added by grantUsersAccess from the DACL
+// of dir. Entries of the Users group with other rights, and inherited ones,
+// are kept.
+func revokeUsersAccess(dir string) error {
+	users, err := windows.CreateWellKnownSid(windows.WinBuiltinUsersSid)
+	if err != nil {
+		return err
+	}
+
+	sd, err := windows.GetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil || dacl == nil {
+		return err
+	}
+
+	removed := false
+	for i := int(dacl.AceCount) - 1; i >= 0; i-- {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, uint32(i), &ace); err != nil {
+			return err
+		}
+		if !isUsersAccessACE(ace, users) {
+			continue
+		}
+		if r, _, err := procDeleteAce.Call(uintptr(unsafe.Pointer(dacl)), uintptr(i)); r == 0 {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return nil
+	}
+
+	return windows.SetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
+
+// isUsersAccessACE reports whether ace is the explicit entry added by
+// grantUsersAccess.
+func isUsersAccessACE(ace *windows.ACCESS_ALLOWED_ACE, users *windows.SID) bool {
+	if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE || ace.Header.AceFlags&windows.INHERITED_ACE != 0 {
+		return false
+	}
+	if ace.Mask != usersAccessMask || ace.Header.AceFlags&(windows.OBJECT_INHERIT_ACE|windows.CONTAINER_INHERIT_ACE) != usersAccessFlags {
+		return false
+	}
+	sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+	return sid.Equals(users)
+}
+
+// addNamespaceEntry pins dir in the Explorer navigation pane of all accounts,
+// as a folder shortcut shell extension registered under HKLM.
+func addNamespaceEntry(dir, name string) error {
+	clsid := namespaceCLSID(dir)
+	classPath := namespaceClassesKeyPath + `\` + clsid
+
+	values := []struct {
+		path  string
+		name  string
+		value any
+	}{
+		{classPath, "", name},
+		{classPath, namespaceOwnerValue, dir},
+		{classPath, "System.IsPinnedToNameSpaceTree", uint32(1)},
+		{classPath, "SortOrderIndex", uint32(0x42)},
+		{classPath + `\DefaultIcon`, "", `%SystemRoot%\system32\imageres.dll,-1043`},
+		{classPath + `\InProcServer32`, "", `%SystemRoot%\system32\shell32.dll`},
+		{classPath + `\Instance`, "CLSID", folderShortcutCLSID},
+		{classPath + `\Instance\InitPropertyBag`, "Attributes", uint32(windows.FILE_ATTRIBUTE_READONLY | windows.FILE_ATTRIBUTE_DIRECTORY)},
+		{classPath + `\Instance\InitPropertyBag`, "TargetFolderPath", dir},
+		{classPath + `\ShellFolder`, "FolderValueFlags", uint32(0x28)},
+		{classPath + `\ShellFolder`, "Attributes", uint32(0xF080004D)},
+		{namespaceDesktopKeyPath + `\` + clsid, "", name},
+		{namespaceHideKeyPath, clsid, uint32(1)},
+	}
+
+	for _, v := range values {
+		key, _, err := registry.CreateKey(registry.LOCAL_MACHINE, v.path, registry.SET_VALUE)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", v.path, err)
+		}
+		switch value := v.value.(type) {
+		case string:
+			if strings.Contains(value, "%") {
+				err = key.SetExpandStringValue(v.name, value)
+			} else {
+				err = key.SetStringValue(v.name, value)
+			}
+		case uint32:
+			err = key.SetDWordValue(v.name, value)
+		}
+		key.Close()
+		if err != nil {
+			return fmt.Errorf("failed to set %s\\%s: %w", v.path, v.name, err)
+		}
+	}
+	return nil
+}
+
+// namespaceCLSID returns the navigation pane CLSID of the sync root at path,
+// derived from the provider ID and the path so it can be found again to
+// unshare the sync root.
+func namespaceCLSID(path string) string {
+	id := DefaultProviderID()
+	h := sha1.New()
+	binary.Write(h, binary.LittleEndian, id)
+	h.Write([]byte(strings.ToLower(filepath.Clean(path))))
+	sum := h.Sum(nil)
+
+	guid := windows.GUID{
+		Data1: binary.BigEndian.Uint32(sum[0:4]),
+		Data2: binary.BigEndian.Uint16(sum[4:6]),
+		Data3: binary.BigEndian.Uint16(sum[6:8])&0x0FFF | 0x5000, // Name-based (version 5)
+	}
+	copy(guid.Data4[:], sum[8:16])
+	guid.Data4[0] = guid.Data4[0]&0x3F | 0x80 // RFC 4122 variant
+	return guid.String()
+}
@@ -0,0 +1,133 @@
+//go:build windows
+// +build windows
+
+package cloudfiles
+
+import (
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// explicitACE is an access allowed entry set on a folder itself.
+type explicitACE struct {
+	sid   string
+	mask  windows.ACCESS_MASK
+	flags uint8
+}
+
+// explicitACEs returns the access allowed entries of the DACL of dir that
+// are not inherited.
+func explicitACEs(t *testing.T, dir string) []explicitACE {
+	t.Helper()
+	sd, err := windows.GetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		t.Fatalf("GetNamedSecurityInfo failed: %v", err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		t.Fatalf("DACL failed: %v", err)
+	}
+
+	var aces []explicitACE
+	for i := 0; i < int(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, uint32(i), &ace); err != nil {
+			t.Fatalf("GetAce failed: %v", err)
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE || ace.Header.AceFlags&windows.INHERITED_ACE != 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		aces = append(aces, explicitACE{sid: sid.String(), mask: ace.Mask, flags: ace.Header.AceFlags})
+	}
+	return aces
+}
+
+// grantAccess adds an explicit entry for a well-known group to dir.
+func grantAccess(t *testing.T, dir string, group windows.WELL_KNOWN_SID_TYPE, mask windows.ACCESS_MASK, inheritance uint32) {
+	t.Helper()
+	sid, err := windows.CreateWellKnownSid(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := windows.GetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _, err := sd.DACL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dacl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{{
+		AccessPermissions: mask,
+		AccessMode:        windows.GRANT_ACCESS,
+		Inheritance:       inheritance,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_WELL_KNOWN_GROUP,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		},
+	}}, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := windows.SetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevokeUsersAccess(t *testing.T) {
+	dir := t.TempDir()
+	users, _ := windows.CreateWellKnownSid(windows.WinBuiltinUsersSid)
+	everyone, _ := windows.CreateWellKnownSid(windows.WinWorldSid)
+
+	// Entries set by someone else: read for Users on the folder only, read for Everyone
+	readOnly := windows.ACCESS_MASK(windows.FILE_GENERIC_READ)
+	grantAccess(t, dir, windows.WinBuiltinUsersSid, readOnly, windows.NO_INHERITANCE)
+	grantAccess(t, dir, windows.WinWorldSid, readOnly, windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT)
+	before := explicitACEs(t, dir)
+
+	if err := grantUsersAccess(dir); err != nil {
+		t.Fatalf("grantUsersAccess failed: %v", err)
+	}
+	shared := explicitACEs(t, dir)
+	want := explicitACE{sid: users.String(), mask: usersAccessMask, flags: usersAccessFlags}
+	if !containsACE(shared, want) || len(shared) != len(before)+1 {
+		t.Fatalf("expected the Users modify entry added to %+v, got %+v", before, shared)
+	}
+
+	if err := revokeUsersAccess(dir); err != nil {
+		t.Fatalf("revokeUsersAccess failed: %v", err)
+	}
+	after := explicitACEs(t, dir)
+	if containsACE(after, want) {
+		t.Errorf("expected the Users modify entry removed, got %+v", after)
+	}
+	if len(after) != len(before) {
+		t.Errorf("expected the other entries kept: before %+v, after %+v", before, after)
+	}
+	for _, kept := range []explicitACE{
+		{sid: users.String(), mask: readOnly, flags: 0},
+		{sid: everyone.String(), mask: readOnly, flags: usersAccessFlags},
+	} {
+		if !containsACE(after, kept) {
+			t.Errorf("expected %+v kept, got %+v", kept, after)
+		}
+	}
+
+	// Nothing left to remove
+	if err := revokeUsersAccess(dir); err != nil {
+		t.Errorf("second revokeUsersAccess failed: %v", err)
+	}
+}
+
+func containsACE(aces []explicitACE, want explicitACE) bool {
+	for _, ace := range aces {
+		if ace == want {
+			return true
+		}
+	}
+	return false
+}
@@ -128,6 +128,52 @@ func TestSyncRootManagerCallbacks(t *testing.T) {
 	}
 }
 
+func TestRelativeToSyncRoot(t *testing.T) {
+	tests := []struct {
+		syncRoot   string
+		normalized string
+		expected   string
+	}{
+		{`D:\Anemone\backup`, `\Anemone\backup\subdir\file.txt`, "subdir/file.txt"},
+		{`D:\test_anemone`, `\test_anemone\file.txt`, "file.txt"},
+		{`D:\Anemone\backup`, `\Other\file.txt`, "Other/file.txt"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeToSyncRoot(tt.syncRoot, tt.normalized); got != tt.expected {
+			t.Errorf("relativeToSyncRoot(%q, %q) = %q, want %q", tt.syncRoot, tt.normalized, got, tt.expected)
+		}
+	}
+}
+
+func TestProviderDehydrationGuard(t *testing.T) {
+	provider, err := NewCloudFilesProvider(ProviderConfig{LocalPath: `D:\Anemone\backup`})
+	if err != nil {
+		t.Fatalf("NewCloudFilesProvider failed: %v", err)
+	}
+
+	// Without a guard every dehydration is allowed
+	if !provider.onNotifyDehydrate(`\Anemone\backup\doc.txt`, CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_LOW_SPACE) {
+		t.Error("expected dehydration allowed without guard")
+	}
+
+	var checked string
+	provider.SetDehydrationGuard(func(relativePath string) (bool, string) {
+		checked = relativePath
+		return relativePath != "dirty.txt", "local changes not uploaded yet"
+	})
+
+	if provider.onNotifyDehydrate(`\Anemone\backup\dirty.txt`, CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_LOW_SPACE) {
+		t.Error("expected dehydration vetoed by guard")
+	}
+	if checked != "dirty.txt" {
+		t.Errorf("expected guard called with relative path, got %q", checked)
+	}
+	if !provider.onNotifyDehydrate(`\Anemone\backup\clean.txt`, CF_CALLBACK_DEHYDRATION_REASON_USER_MANUAL) {
+		t.Error("expected dehydration allowed by guard")
+	}
+}
+
 // Note: Tests that actually register/connect to sync roots require administrator privileges
 // and may interfere with the file system, so they're skipped by default.
 // Run them manually with: go test -run TestSyncRootRegister -v
@@ -170,3 +216,26 @@ func TestSyncRootRegister(t *testing.T) {
 		t.Error("should not be registered after Unregister()")
 	}
 }
+
+func TestNamespaceCLSID(t *testing.T) {
+	a := namespaceCLSID(`C:\Users\Public\AnemoneSync`)
+	if a != namespaceCLSID(`c:\users\public\anemonesync\`) {
+		t.Error("expected the same CLSID regardless of case and trailing separator")
+	}
+	if a == namespaceCLSID(`D:\AnemoneSync`) {
+		t.Error("expected different CLSIDs for different sync roots")
+	}
+	if len(a) != 38 || a[0] != '{' || a[15] != '5' {
+		t.Errorf("expected a version 5 GUID string, got %s", a)
+	}
+}
+
+func TestNewSyncRootManager_DefaultScope(t *testing.T) {
+	manager, err := NewSyncRootManager(SyncRootConfig{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manager.Scope() != SyncRootScopeUser {
+		t.Errorf("expected user scope by default, got %s", manager.Scope())
+	}
+}
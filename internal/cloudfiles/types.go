@@ -288,6 +288,41 @@ var CF_CALLBACK_REGISTRATION_END = CF_CALLBACK_REGISTRATION{
 	Callback: 0,
 }
 
+// --- Dehydration Callbacks ---
+
+// DehydrationReason tells why Windows dehydrates a placeholder (CF_CALLBACK_DEHYDRATION_REASON).
+type DehydrationReason uint32
+
+const (
+	CF_CALLBACK_DEHYDRATION_REASON_NONE              DehydrationReason = 0
+	CF_CALLBACK_DEHYDRATION_REASON_USER_MANUAL       DehydrationReason = 1
+	CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_LOW_SPACE  DehydrationReason = 2
+	CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_INACTIVITY DehydrationReason = 3
+	CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_OS_UPGRADE DehydrationReason = 4
+)
+
+// String returns a readable name for the reason.
+func (r DehydrationReason) String() string {
+	switch r {
+	case CF_CALLBACK_DEHYDRATION_REASON_USER_MANUAL:
+		return "user"
+	case CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_LOW_SPACE:
+		return "low disk space"
+	case CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_INACTIVITY:
+		return "inactivity"
+	case CF_CALLBACK_DEHYDRATION_REASON_SYSTEM_OS_UPGRADE:
+		return "OS upgrade"
+	default:
+		return "unknown"
+	}
+}
+
+// CF_CALLBACK_DEHYDRATE_COMPLETION_FLAGS are passed to NOTIFY_DEHYDRATE_COMPLETION.
+const (
+	CF_CALLBACK_DEHYDRATE_COMPLETION_FLAG_BACKGROUND = 0x00000001
+	CF_CALLBACK_DEHYDRATE_COMPLETION_FLAG_DEHYDRATED = 0x00000002
+)
+
 // --- Placeholder State ---
 
 // CF_PLACEHOLDER_STATE represents the state of a placeholder file.
@@ -0,0 +1,166 @@
+//go:build windows
+// +build windows
+
+// Package cloudfiles provides Windows Cloud Files API bindings.
+// This file contains volume detection used to validate sync root locations.
+package cloudfiles
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// VolumeInfo describes the volume hosting a local folder.
+type VolumeInfo struct {
+	Root       string // Volume root (e.g., "C:\")
+	FileSystem string // File system name (e.g., "NTFS", "exFAT")
+	DriveType  uint32 // windows.DRIVE_* constant
+	Flags      uint32 // File system flags from GetVolumeInformation
+	Supported  bool   // True if Files On Demand can be used on this volume
+	Reason     string // Guidance when the volume is not supported
+}
+
+// CheckVolume inspects the volume hosting path and reports whether a
+// Cloud Files sync root can be registered on it.
+// The path does not need to exist yet.
+func CheckVolume(path string) (*VolumeInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	pathPtr, err := windows.UTF16PtrFromString(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	rootBuf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &rootBuf[0], uint32(len(rootBuf))); err != nil {
+		return nil, fmt.Errorf("GetVolumePathName failed: %w", err)
+	}
+	root := windows.UTF16ToString(rootBuf)
+
+	rootPtr, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume root: %w", err)
+	}
+
+	info := &VolumeInfo{
+		Root:      root,
+		DriveType: windows.GetDriveType(rootPtr),
+	}
+
+	fsNameBuf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(rootPtr, nil, 0, nil, nil, &info.Flags, &fsNameBuf[0], uint32(len(fsNameBuf))); err != nil {
+		// Network drives may refuse the query; drive type alone is enough to reject them
+		if info.DriveType != windows.DRIVE_REMOTE {
+			return nil, fmt.Errorf("GetVolumeInformation failed for %s: %w", root, err)
+		}
+	}
+	info.FileSystem = windows.UTF16ToString(fsNameBuf)
+
+	info.Reason = volumeSupportReason(info.FileSystem, info.DriveType, info.Flags)
+	info.Supported = info.Reason == ""
+
+	return info, nil
+}
+
+// volumeSupportReason returns guidance explaining why Files On Demand cannot
+// be used on a volume, or "" if the volume is supported.
+// The Cloud Files filter (cldflt.sys) only attaches to local NTFS volumes.
+func volumeSupportReason(fileSystem string, driveType uint32, flags uint32) string {
+	switch driveType {
+	case windows.DRIVE_REMOTE:
+		return "The folder is on a network drive. Files On Demand requires a local disk; choose a folder on a local NTFS drive (e.g. C:\\Users\\you\\AnemoneSync)."
+	case windows.DRIVE_CDROM:
+		return "The folder is on an optical drive. Choose a folder on a local NTFS drive."
+	case windows.DRIVE_RAMDISK:
+		return "The folder is on a RAM disk. Choose a folder on a local NTFS drive."
+	case windows.DRIVE_NO_ROOT_DIR, windows.DRIVE_UNKNOWN:
+		return "The drive for this folder could not be found. Check that the drive is connected."
+	}
+
+	switch strings.ToUpper(fileSystem) {
+	case "NTFS":
+		if flags != 0 && flags&windows.FILE_SUPPORTS_REPARSE_POINTS == 0 {
+			return "This NTFS volume does not support reparse points, which Files On Demand relies on."
+		}
+		return ""
+	case "FAT", "FAT32", "EXFAT":
+		return fmt.Sprintf("The drive is formatted as %s, which does not support Files On Demand. Reformat it as NTFS or choose a folder on an NTFS drive.", fileSystem)
+	case "REFS":
+		return "The drive is formatted as ReFS, which does not support Files On Demand. Choose a folder on an NTFS drive."
+	case "":
+		return "The file system of this drive could not be determined. Choose a folder on a local NTFS drive."
+	default:
+		return fmt.Sprintf("The drive is formatted as %s. Files On Demand only works on NTFS drives.", fileSystem)
+	}
+}
+
+// VolumeGUIDPath returns the volume GUID path (e.g., "\\?\Volume{...}\") and the
+// current mount root (e.g., "D:\") of the volume hosting path.
+// Unlike drive letters, the GUID path stays the same when a drive is remounted.
+func VolumeGUIDPath(path string) (guidPath string, root string, err error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	pathPtr, err := windows.UTF16PtrFromString(absPath)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	rootBuf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &rootBuf[0], uint32(len(rootBuf))); err != nil {
+		return "", "", fmt.Errorf("GetVolumePathName failed: %w", err)
+	}
+	root = windows.UTF16ToString(rootBuf)
+
+	guidBuf := make([]uint16, 64)
+	if err := windows.GetVolumeNameForVolumeMountPoint(&rootBuf[0], &guidBuf[0], uint32(len(guidBuf))); err != nil {
+		return "", "", fmt.Errorf("GetVolumeNameForVolumeMountPoint failed for %s: %w", root, err)
+	}
+
+	return windows.UTF16ToString(guidBuf), root, nil
+}
+
+// VolumeMountPoints returns the paths where a volume is currently mounted
+// (drive letters and mounted folders). It returns an empty slice if the
+// volume is known but not mounted.
+func VolumeMountPoints(guidPath string) ([]string, error) {
+	guidPtr, err := windows.UTF16PtrFromString(guidPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume path: %w", err)
+	}
+
+	buf := make([]uint16, windows.MAX_PATH+1)
+	var needed uint32
+	err = windows.GetVolumePathNamesForVolumeName(guidPtr, &buf[0], uint32(len(buf)), &needed)
+	if err == windows.ERROR_MORE_DATA {
+		buf = make([]uint16, needed)
+		err = windows.GetVolumePathNamesForVolumeName(guidPtr, &buf[0], uint32(len(buf)), &needed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetVolumePathNamesForVolumeName failed for %s: %w", guidPath, err)
+	}
+
+	// The buffer is a list of NUL-terminated strings ending with an empty string
+	var mountPoints []string
+	start := 0
+	for i, c := range buf {
+		if c != 0 {
+			continue
+		}
+		if i == start {
+			break
+		}
+		mountPoints = append(mountPoints, windows.UTF16ToString(buf[start:i]))
+		start = i + 1
+	}
+
+	return mountPoints, nil
+}
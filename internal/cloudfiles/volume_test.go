@@ -0,0 +1,51 @@
+//go:build windows
+// +build windows
+
+package cloudfiles
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestCheckVolume(t *testing.T) {
+	info, err := CheckVolume(t.TempDir())
+	if err != nil {
+		t.Fatalf("CheckVolume failed: %v", err)
+	}
+
+	if info.Root == "" {
+		t.Error("Root should not be empty")
+	}
+
+	t.Logf("Volume %s: fs=%s driveType=%d supported=%v reason=%q",
+		info.Root, info.FileSystem, info.DriveType, info.Supported, info.Reason)
+}
+
+func TestVolumeSupportReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		fs        string
+		driveType uint32
+		flags     uint32
+		supported bool
+	}{
+		{"ntfs fixed", "NTFS", windows.DRIVE_FIXED, windows.FILE_SUPPORTS_REPARSE_POINTS, true},
+		{"ntfs removable", "NTFS", windows.DRIVE_REMOVABLE, 0, true},
+		{"exfat", "exFAT", windows.DRIVE_REMOVABLE, 0, false},
+		{"fat32", "FAT32", windows.DRIVE_FIXED, 0, false},
+		{"refs", "ReFS", windows.DRIVE_FIXED, windows.FILE_SUPPORTS_REPARSE_POINTS, false},
+		{"network", "NTFS", windows.DRIVE_REMOTE, windows.FILE_SUPPORTS_REPARSE_POINTS, false},
+		{"ntfs without reparse points", "NTFS", windows.DRIVE_FIXED, 0x1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := volumeSupportReason(tt.fs, tt.driveType, tt.flags)
+			if (reason == "") != tt.supported {
+				t.Errorf("supported = %v, want %v (reason: %q)", reason == "", tt.supported, reason)
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -29,7 +31,11 @@ type AppConfig struct {
 }
 
 type DatabaseConfig struct {
-	Path string `mapstructure:"path"`
+	Path       string `mapstructure:"path"`
+	AutoBackup bool   `mapstructure:"auto_backup"` // Sauvegarde chiffrée quotidienne de la base
+	BackupKeep int    `mapstructure:"backup_keep"` // Nombre de sauvegardes conservées
+	// Isole l'état des jobs dépassant ce nombre de fichiers dans leur propre base (0 = jamais)
+	IsolateJobsOver int `mapstructure:"isolate_jobs_over"`
 }
 
 type PathsConfig struct {
@@ -38,8 +44,18 @@ type PathsConfig struct {
 }
 
 type LoggingConfig struct {
-	Rotation LogRotationConfig `mapstructure:"rotation"`
-	Levels   LogLevelsConfig   `mapstructure:"levels"`
+	Rotation      LogRotationConfig `mapstructure:"rotation"`
+	Levels        LogLevelsConfig   `mapstructure:"levels"`
+	EventLog      bool              `mapstructure:"event_log"`       // Événements critiques copiés dans le journal Windows
+	AuditEventLog bool              `mapstructure:"audit_event_log"` // Entrées du journal d'audit aussi copiées dans le journal Windows
+	Metrics       MetricsConfig     `mapstructure:"metrics"`
+}
+
+// MetricsConfig sert les métriques Prometheus (synchros, octets, file
+// d'attente, hydratations, reconnexions SMB) sur une adresse locale
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Listen  string `mapstructure:"listen"` // Adresse d'écoute (127.0.0.1:9464 par défaut)
 }
 
 type LogRotationConfig struct {
@@ -57,20 +73,127 @@ type SyncConfig struct {
 	DefaultMode               string              `mapstructure:"default_mode"`
 	DefaultTrigger            string              `mapstructure:"default_trigger"`
 	DefaultConflictResolution string              `mapstructure:"default_conflict_resolution"`
+	// Nom de la copie serveur gardée par "keep_both" ({name}, {ext}, {host}, {date}, {time})
+	ConflictNamePattern       string              `mapstructure:"conflict_name_pattern"`
+	// Avec "recent", garde la version perdante d'un conflit à côté de la
+	// gagnante ("nom (conflict from PC 2025-01-31).ext") au lieu de l'écraser
+	ConflictCopies            bool                `mapstructure:"conflict_copies"`
+	// Partage écrit par plusieurs utilisateurs : relève le propriétaire des
+	// fichiers distants pour afficher qui les a modifiés
+	SharedShare               bool                `mapstructure:"shared_share"`
+	// Garde la date de modification des fichiers transférés (à désactiver pour
+	// les serveurs qui refusent de la modifier)
+	PreserveTimestamps        bool                `mapstructure:"preserve_timestamps"`
+	// Traduction des SID des permissions copiées vers le serveur quand le PC
+	// et le serveur sont dans des domaines différents (SID local -> SID distant)
+	ACLSIDMap                 map[string]string   `mapstructure:"acl_sid_map"`
+	// Transfère ensemble les fichiers d'une base SQLite, Access, Outlook ou
+	// OneNote, et seulement quand l'application ne les écrit plus
+	AppConsistent             bool                `mapstructure:"app_consistent"`
+	// Secondes sans modification avant qu'un de ces groupes soit transféré
+	AppQuietSeconds           int                 `mapstructure:"app_quiet_seconds"`
+	// Délai aléatoire maximum ajouté aux lancements planifiés, pour que les
+	// jobs prévus à la même heure ne démarrent pas tous ensemble
+	ScheduleJitterSeconds     int                 `mapstructure:"schedule_jitter_seconds"`
 	Realtime                  RealtimeConfig      `mapstructure:"realtime"`
 	Performance               PerformanceConfig   `mapstructure:"performance"`
 	Network                   NetworkConfig       `mapstructure:"network"`
+	FileTypes                 FileTypesConfig     `mapstructure:"file_types"`
+	Retry                     RetryConfig         `mapstructure:"retry"`
+}
+
+// RetryConfig règle les nouvelles tentatives d'un fichier dont l'action échoue
+// sur une erreur passagère (réseau, session SMB perdue, fichier occupé). Les
+// fichiers encore en échec sont retentés par la synchronisation suivante.
+type RetryConfig struct {
+	MaxAttempts         int `mapstructure:"max_attempts"`          // Tentatives par fichier, la première comprise (1 = aucune nouvelle tentative)
+	InitialDelaySeconds int `mapstructure:"initial_delay_seconds"` // Attente avant la deuxième tentative, doublée ensuite
+	MaxDelaySeconds     int `mapstructure:"max_delay_seconds"`     // Attente maximale entre deux tentatives
+}
+
+// FileTypesConfig applique des règles par type de fichier (text, document,
+// image, audio, video, archive, executable, database, other)
+type FileTypesConfig struct {
+	NeverDehydrate []string `mapstructure:"never_dehydrate"` // Types jamais libérés (Files On Demand)
+	Compress       []string `mapstructure:"compress"`        // Types compressés quand la compression est active (vide = tous)
+	ExcludeUpload  []string `mapstructure:"exclude_upload"`  // Types jamais envoyés au serveur
+
+	// Début des fichiers gardé sur le disque dans les placeholders (Files On Demand)
+	Previews []PreviewConfig `mapstructure:"previews"`
+}
+
+// PreviewConfig garde les premiers Ko des fichiers d'un type (en-têtes, tags ID3)
+// pour que les gestionnaires de médias les lisent sans tout télécharger
+type PreviewConfig struct {
+	Type      string `mapstructure:"type"`        // Type de fichier (audio, image, video...)
+	MinSizeKB int64  `mapstructure:"min_size_kb"` // Taille minimale des fichiers concernés
+	KeepKB    int64  `mapstructure:"keep_kb"`     // Ko gardés depuis le début du fichier
 }
 
 type RealtimeConfig struct {
-	DebounceSeconds      int `mapstructure:"debounce_seconds"`
-	BatchIntervalMinutes int `mapstructure:"batch_interval_minutes"`
+	DebounceSeconds      int  `mapstructure:"debounce_seconds"` // Attente après le dernier changement avant de synchroniser (surchargeable par job)
+	BatchIntervalMinutes int  `mapstructure:"batch_interval_minutes"`
+	RemoteNotify         bool `mapstructure:"remote_notify"` // Suivre aussi les changements du serveur (SMB2 CHANGE_NOTIFY)
 }
 
 type PerformanceConfig struct {
+	// Actions exécutées par parallel_transfers workers selon leurs
+	// dépendances (sinon une à la fois, dans l'ordre)
+	ParallelExecution bool   `mapstructure:"parallel_execution"`
 	ParallelTransfers int    `mapstructure:"parallel_transfers"`
 	BufferSizeMB      int    `mapstructure:"buffer_size_mb"`
 	HashAlgorithm     string `mapstructure:"hash_algorithm"`
+	QueueSize         int    `mapstructure:"queue_size"`       // Actions queued ahead of workers (0 = 2 per worker)
+	MaxInFlightMB     int    `mapstructure:"max_in_flight_mb"` // Memory ceiling for in-flight transfers (0 = unlimited)
+
+	// Création des placeholders Files On Demand (évite de bloquer l'Explorateur)
+	PlaceholderBatchSize int `mapstructure:"placeholder_batch_size"` // Placeholders par appel CfCreatePlaceholders
+	PlaceholderRateLimit int `mapstructure:"placeholder_rate_limit"` // Placeholders créés par seconde (0 = illimité)
+
+	// Lecture anticipée pendant l'hydratation (débit sur les liens à forte latence)
+	HydrationReadAhead int `mapstructure:"hydration_read_ahead"` // Blocs de 1 Mo préchargés (0 = désactivé)
+
+	// Ordre des transferts : default, smallest_first, newest_first, folder_priority
+	TransferOrder  string   `mapstructure:"transfer_order"`
+	FolderPriority []string `mapstructure:"folder_priority"` // Dossiers transférés en premier (folder_priority)
+
+	// Limites de débit partagées par tous les jobs, en Ko/s (0 = illimité)
+	MaxUploadKBps   int `mapstructure:"max_upload_kbps"`
+	MaxDownloadKBps int `mapstructure:"max_download_kbps"`
+	// Limite par sens sur une connexion facturée à l'usage (réglage Windows)
+	MeteredKBps     int  `mapstructure:"metered_kbps"`
+	ThrottleMetered bool `mapstructure:"throttle_metered"` // Pour tous les jobs (sinon selon le job)
+
+	// Gros fichiers transférés en plusieurs morceaux simultanés
+	ParallelChunkMinMB   int `mapstructure:"parallel_chunk_min_mb"`  // Taille à partir de laquelle un fichier est découpé (0 = désactivé)
+	ParallelChunkSizeMB  int `mapstructure:"parallel_chunk_size_mb"` // Taille des morceaux
+	ParallelChunkStreams int `mapstructure:"parallel_chunk_streams"` // Morceaux transférés en même temps
+
+	// Morceaux écrits à des multiples fixes (images disque, NAS avec
+	// déduplication), en Mo (0 = désactivé)
+	ChunkAlignmentMB int `mapstructure:"chunk_alignment_mb"`
+
+	// Nombre de transferts simultanés ajusté selon le débit mesuré (parallel_transfers = départ)
+	AdaptiveTransfers       bool `mapstructure:"adaptive_transfers"`
+	MinParallelTransfers    int  `mapstructure:"min_parallel_transfers"`
+	MaxParallelTransfers    int  `mapstructure:"max_parallel_transfers"`
+	AdaptiveIntervalSeconds int  `mapstructure:"adaptive_interval_seconds"` // Période de mesure du débit
+
+	// Rafales de petits fichiers envoyées par lots
+	SmallFileBatchKB      int `mapstructure:"small_file_batch_kb"`      // Taille maximale d'un fichier du lot (0 = désactivé)
+	SmallFileBatchMin     int `mapstructure:"small_file_batch_min"`     // Petits fichiers nécessaires pour former un lot
+	SmallFileBatchStreams int `mapstructure:"small_file_batch_streams"` // Fichiers envoyés en même temps
+
+	// Parcours local limité aux chemins modifiés depuis le dernier scan, lus
+	// dans le journal USN du volume NTFS (sinon dossier entièrement parcouru)
+	ChangeJournal bool `mapstructure:"change_journal"`
+
+	// Listing SMB du scan précédent réutilisé pour les dossiers distants dont
+	// la date de modification n'a pas changé (sans manifeste Anemone)
+	RemoteListingCache bool `mapstructure:"remote_listing_cache"`
+	// Âge maximal d'un listing réutilisé, en heures : un fichier réécrit sur
+	// place ne change pas la date de son dossier
+	RemoteListingMaxAgeHours int `mapstructure:"remote_listing_max_age_hours"`
 }
 
 type NetworkConfig struct {
@@ -100,9 +223,29 @@ type TrayIconConfig struct {
 }
 
 type SecurityConfig struct {
-	KeystoreServiceName  string `mapstructure:"keystore_service_name"`
-	ZeroMemoryAfterUse   bool   `mapstructure:"zero_memory_after_use"`
-	EnableSMB3Encryption bool   `mapstructure:"enable_smb3_encryption"`
+	KeystoreServiceName  string                    `mapstructure:"keystore_service_name"`
+	ZeroMemoryAfterUse   bool                      `mapstructure:"zero_memory_after_use"`
+	EnableSMB3Encryption bool                      `mapstructure:"enable_smb3_encryption"`
+	UploadScan           UploadScanConfig          `mapstructure:"upload_scan"`
+	RansomwareDetection  RansomwareDetectionConfig `mapstructure:"ransomware_detection"`
+}
+
+// UploadScanConfig configure l'analyse antivirus des fichiers avant upload
+type UploadScanConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	Type           string   `mapstructure:"type"`            // "command" ou "amsi"
+	Command        string   `mapstructure:"command"`         // Exécutable pour le type "command"
+	Args           []string `mapstructure:"args"`            // "{path}" est remplacé par le fichier (ajouté à la fin sinon)
+	VetoExitCodes  []int    `mapstructure:"veto_exit_codes"` // Codes de sortie = fichier infecté (vide = tout code non nul)
+	TimeoutSeconds int      `mapstructure:"timeout_seconds"`
+	MaxSizeMB      int      `mapstructure:"max_size_mb"` // AMSI : les fichiers plus gros ne sont pas analysés
+}
+
+// RansomwareDetectionConfig configure la détection heuristique de ransomware :
+// une synchro qui enverrait des fichiers chiffrés est suspendue jusqu'à validation
+type RansomwareDetectionConfig struct {
+	Enabled  bool `mapstructure:"enabled"`
+	MinFiles int  `mapstructure:"min_files"` // Fichiers renommés ou chiffrés à partir desquels on alerte
 }
 
 type AdvancedConfig struct {
@@ -150,14 +293,51 @@ func Load(configPath string) (*Config, error) {
 		}
 	}
 
+	// Configuration gérée : refuser un fichier modifié hors du circuit de signature
+	publicKey, err := managedPublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("erreur lecture stratégie: %w", err)
+	}
+	if publicKey != "" {
+		data, err := readManagedConfig(v.ConfigFileUsed(), publicKey)
+		if err != nil {
+			return nil, err
+		}
+		// Relire le contenu vérifié, pas le fichier qui a pu changer depuis
+		v.SetConfigType(strings.TrimPrefix(filepath.Ext(v.ConfigFileUsed()), "."))
+		if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("erreur lecture config: %w", err)
+		}
+	}
+
 	// Charger les valeurs par défaut depuis le fichier embarqué
 	setDefaults(v)
 
-	// Permettre les variables d'environnement
-	v.SetEnvPrefix("ANEMONE")
-	v.AutomaticEnv()
+	// Permettre les variables d'environnement (sauf pour une configuration
+	// gérée, qu'elles contourneraient)
+	if publicKey == "" {
+		v.SetEnvPrefix("ANEMONE")
+		v.AutomaticEnv()
+	}
+
+	return decode(v)
+}
 
-	// Decoder dans la structure
+// Default retourne la configuration par défaut, sans fichier ni variables
+// d'environnement : celle utilisée quand config.yaml ne peut pas être lu
+func Default() *Config {
+	v := viper.New()
+	setDefaults(v)
+	config, err := decode(v)
+	if err != nil {
+		// Les valeurs par défaut sont fixes : erreur de programmation
+		panic(err)
+	}
+	return config
+}
+
+// decode décode la configuration de v dans la structure
+func decode(v *viper.Viper) (*Config, error) {
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("erreur décodage config: %w", err)
@@ -171,8 +351,12 @@ func Load(configPath string) (*Config, error) {
 	return &config, nil
 }
 
-// getDefaultConfigDir retourne le répertoire de configuration par défaut selon l'OS
+// getDefaultConfigDir retourne le répertoire de configuration par défaut selon l'OS,
+// ou le répertoire des données quand il est imposé (voir DataDirEnv)
 func getDefaultConfigDir() string {
+	if dir := DataDirOverride(); dir != "" {
+		return dir
+	}
 	switch runtime.GOOS {
 	case "windows":
 		return filepath.Join(os.Getenv("APPDATA"), "AnemoneSync")
@@ -213,6 +397,9 @@ func setDefaults(v *viper.Viper) {
 
 	// Database
 	v.SetDefault("database.path", filepath.Join(getDefaultConfigDir(), "anemone_sync.db"))
+	v.SetDefault("database.auto_backup", true)
+	v.SetDefault("database.backup_keep", 7)
+	v.SetDefault("database.isolate_jobs_over", 0)
 
 	// Paths
 	v.SetDefault("paths.config_dir", getDefaultConfigDir())
@@ -224,19 +411,63 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("logging.rotation.compress", true)
 	v.SetDefault("logging.levels.console", "info")
 	v.SetDefault("logging.levels.file", "debug")
+	v.SetDefault("logging.event_log", true)
+	v.SetDefault("logging.audit_event_log", false)
+	v.SetDefault("logging.metrics.enabled", false)
+	v.SetDefault("logging.metrics.listen", "127.0.0.1:9464")
 
 	// Sync
 	v.SetDefault("sync.default_mode", "mirror")
 	v.SetDefault("sync.default_trigger", "realtime")
 	v.SetDefault("sync.default_conflict_resolution", "recent")
-	v.SetDefault("sync.realtime.debounce_seconds", 30)
+	v.SetDefault("sync.conflict_name_pattern", "{name}.server{ext}")
+	v.SetDefault("sync.conflict_copies", true)
+	v.SetDefault("sync.shared_share", false)
+	v.SetDefault("sync.preserve_timestamps", true)
+	v.SetDefault("sync.app_consistent", true)
+	v.SetDefault("sync.app_quiet_seconds", 30)
+	v.SetDefault("sync.schedule_jitter_seconds", 30)
+	v.SetDefault("sync.realtime.debounce_seconds", 3)
 	v.SetDefault("sync.realtime.batch_interval_minutes", 5)
+	v.SetDefault("sync.realtime.remote_notify", true)
+	v.SetDefault("sync.performance.parallel_execution", false)
 	v.SetDefault("sync.performance.parallel_transfers", 4)
 	v.SetDefault("sync.performance.buffer_size_mb", 4)
 	v.SetDefault("sync.performance.hash_algorithm", "sha256")
+	v.SetDefault("sync.performance.queue_size", 0)
+	v.SetDefault("sync.performance.max_in_flight_mb", 256)
+	v.SetDefault("sync.performance.placeholder_batch_size", 1000)
+	v.SetDefault("sync.performance.placeholder_rate_limit", 0)
+	v.SetDefault("sync.performance.hydration_read_ahead", 4)
+	v.SetDefault("sync.performance.transfer_order", "default")
+	v.SetDefault("sync.performance.folder_priority", []string{})
+	v.SetDefault("sync.performance.max_upload_kbps", 0)
+	v.SetDefault("sync.performance.max_download_kbps", 0)
+	v.SetDefault("sync.performance.metered_kbps", 256)
+	v.SetDefault("sync.performance.throttle_metered", false)
+	v.SetDefault("sync.performance.parallel_chunk_min_mb", 64)
+	v.SetDefault("sync.performance.parallel_chunk_size_mb", 4)
+	v.SetDefault("sync.performance.parallel_chunk_streams", 4)
+	v.SetDefault("sync.performance.chunk_alignment_mb", 0)
+	v.SetDefault("sync.performance.adaptive_transfers", true)
+	v.SetDefault("sync.performance.min_parallel_transfers", 1)
+	v.SetDefault("sync.performance.max_parallel_transfers", 16)
+	v.SetDefault("sync.performance.adaptive_interval_seconds", 5)
+	v.SetDefault("sync.performance.small_file_batch_kb", 64)
+	v.SetDefault("sync.performance.small_file_batch_min", 20)
+	v.SetDefault("sync.performance.small_file_batch_streams", 4)
+	v.SetDefault("sync.performance.change_journal", true)
+	v.SetDefault("sync.performance.remote_listing_cache", true)
+	v.SetDefault("sync.performance.remote_listing_max_age_hours", 24)
+	v.SetDefault("sync.file_types.never_dehydrate", []string{"database"})
+	v.SetDefault("sync.file_types.compress", []string{"text"})
+	v.SetDefault("sync.file_types.exclude_upload", []string{})
 	v.SetDefault("sync.network.require_wifi", false)
 	v.SetDefault("sync.network.require_data", false)
 	v.SetDefault("sync.network.enable_offline_queue", true)
+	v.SetDefault("sync.retry.max_attempts", 4)
+	v.SetDefault("sync.retry.initial_delay_seconds", 1)
+	v.SetDefault("sync.retry.max_delay_seconds", 30)
 
 	// UI
 	v.SetDefault("ui.start_minimized", false)
@@ -246,4 +477,10 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("security.keystore_service_name", "AnemoneSync")
 	v.SetDefault("security.zero_memory_after_use", true)
 	v.SetDefault("security.enable_smb3_encryption", true)
+	v.SetDefault("security.upload_scan.enabled", false)
+	v.SetDefault("security.upload_scan.type", "command")
+	v.SetDefault("security.upload_scan.timeout_seconds", 60)
+	v.SetDefault("security.upload_scan.max_size_mb", 100)
+	v.SetDefault("security.ransomware_detection.enabled", true)
+	v.SetDefault("security.ransomware_detection.min_files", 20)
 }
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	// Without config.yaml, Load returns the defaults
+	t.Chdir(t.TempDir())
+	t.Setenv(DataDirEnv, t.TempDir())
+	loaded, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if got := Default(); !reflect.DeepEqual(got, loaded) {
+		t.Errorf("Default() = %+v, want %+v", got, loaded)
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DataDirEnv est la variable d'environnement qui remplace le répertoire des
+// données (profils itinérants, tests isolés). L'option --data-dir la définit
+// pour le processus et ceux qu'il lance.
+const DataDirEnv = "ANEMONESYNC_DATA_DIR"
+
+// DataDir retourne le répertoire des données : base de données, sauvegardes
+// et journaux. Par défaut %LOCALAPPDATA%\AnemoneSync (le répertoire courant
+// si LOCALAPPDATA n'est pas défini).
+func DataDir() string {
+	if dir := DataDirOverride(); dir != "" {
+		return dir
+	}
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		localAppData = "."
+	}
+	return filepath.Join(localAppData, "AnemoneSync")
+}
+
+// DataDirOverride retourne le répertoire des données imposé par
+// ANEMONESYNC_DATA_DIR ou --data-dir ("" = emplacement par défaut)
+func DataDirOverride() string {
+	return os.Getenv(DataDirEnv)
+}
+
+// SetDataDir impose le répertoire des données (--data-dir), en chemin absolu
+// pour que les processus lancés depuis un autre répertoire le retrouvent
+func SetDataDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("répertoire des données invalide: %w", err)
+	}
+	return os.Setenv(DataDirEnv, abs)
+}
+
+// DatabasePath retourne le chemin de la base de données de l'application
+func DatabasePath() string {
+	return filepath.Join(DataDir(), "data", "anemonesync.db")
+}
+
+// LogDir retourne le répertoire des journaux de l'application
+func LogDir() string {
+	return filepath.Join(DataDir(), "logs")
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDataDir(t *testing.T) {
+	t.Setenv(DataDirEnv, "")
+	t.Setenv("LOCALAPPDATA", filepath.Join("profile", "Local"))
+	if got, want := DataDir(), filepath.Join("profile", "Local", "AnemoneSync"); got != want {
+		t.Errorf("DataDir() = %q, want %q", got, want)
+	}
+
+	dir := t.TempDir()
+	t.Setenv(DataDirEnv, dir)
+	if got := DataDir(); got != dir {
+		t.Errorf("DataDir() = %q, want %q", got, dir)
+	}
+	if got, want := DatabasePath(), filepath.Join(dir, "data", "anemonesync.db"); got != want {
+		t.Errorf("DatabasePath() = %q, want %q", got, want)
+	}
+	if got, want := LogDir(), filepath.Join(dir, "logs"); got != want {
+		t.Errorf("LogDir() = %q, want %q", got, want)
+	}
+	if got := getDefaultConfigDir(); got != dir {
+		t.Errorf("getDefaultConfigDir() = %q, want the data directory %q", got, dir)
+	}
+}
+
+func TestSetDataDir(t *testing.T) {
+	t.Setenv(DataDirEnv, "")
+	if err := SetDataDir("portable-data"); err != nil {
+		t.Fatalf("SetDataDir failed: %v", err)
+	}
+	got := DataDirOverride()
+	if !filepath.IsAbs(got) || filepath.Base(got) != "portable-data" {
+		t.Errorf("DataDirOverride() = %q, want an absolute path", got)
+	}
+}
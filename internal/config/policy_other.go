@@ -0,0 +1,9 @@
+//go:build !windows
+
+package config
+
+// managedPublicKey retourne "" : les configurations gérées sont déployées par
+// stratégie de groupe Windows
+func managedPublicKey() (string, error) {
+	return "", nil
+}
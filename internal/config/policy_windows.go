@@ -0,0 +1,36 @@
+//go:build windows
+
+package config
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Clé de stratégie machine, modifiable seulement par un administrateur
+const (
+	policyKeyPath        = `SOFTWARE\Policies\AnemoneSync`
+	policyConfigKeyValue = "ConfigPublicKey"
+)
+
+// managedPublicKey lit la clé publique des configurations gérées dans la stratégie machine
+func managedPublicKey() (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, policyKeyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer key.Close()
+
+	value, _, err := key.GetStringValue(policyConfigKeyValue)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return value, nil
+}
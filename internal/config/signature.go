@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
+)
+
+// ErrConfigTampered est renvoyée quand une configuration gérée ne correspond
+// pas à sa signature (modifiée, signature absente ou fichier supprimé)
+var ErrConfigTampered = errors.New("configuration gérée non conforme à sa signature")
+
+// SignatureFile retourne le fichier de signature d'un fichier de configuration
+func SignatureFile(configFile string) string {
+	return configFile + ".sig"
+}
+
+// GenerateSigningKey crée une paire de clés Ed25519 encodées en base64 :
+// la clé publique est déployée par stratégie, la clé privée reste chez l'administrateur
+func GenerateSigningKey() (publicKey, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("génération clé: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv), nil
+}
+
+// SignConfig signe le contenu d'un fichier de configuration
+func SignConfig(data []byte, privateKey string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("clé privée invalide")
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.PrivateKey(key), data)), nil
+}
+
+// VerifyConfig vérifie la signature du contenu d'un fichier de configuration
+func VerifyConfig(data []byte, signature, publicKey string) error {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil || !ed25519.Verify(key, data, sig) {
+		return ErrConfigTampered
+	}
+	return nil
+}
+
+// ParsePublicKey décode une clé publique Ed25519 en base64
+func ParsePublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("clé publique invalide")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ManagedPublicKey retourne la clé publique des configurations gérées,
+// déployée par stratégie de groupe ("" = configuration non gérée)
+func ManagedPublicKey() (string, error) {
+	return managedPublicKey()
+}
+
+// readManagedConfig lit et vérifie une configuration gérée. Le contenu
+// renvoyé est celui qui a été vérifié, pas une nouvelle lecture du fichier.
+func readManagedConfig(file, publicKey string) ([]byte, error) {
+	if file == "" {
+		return nil, tampered(file, "fichier de configuration introuvable")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, tampered(file, err.Error())
+	}
+	sig, err := os.ReadFile(SignatureFile(file))
+	if err != nil {
+		return nil, tampered(file, "signature absente")
+	}
+	if err := VerifyConfig(data, string(sig), publicKey); err != nil {
+		return nil, tampered(file, "contenu modifié depuis la signature")
+	}
+	return data, nil
+}
+
+// tampered signale une configuration gérée refusée dans le journal
+// d'événements Windows
+func tampered(file, reason string) error {
+	eventlog.Error(eventlog.EventConfigTampered, fmt.Sprintf(
+		"Managed configuration %s was refused (%s). AnemoneSync does not start or sync until the signed file is restored.",
+		file, reason))
+	return fmt.Errorf("%w (%s): %s", ErrConfigTampered, file, reason)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignConfig_Verify(t *testing.T) {
+	publicKey, privateKey, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+
+	data := []byte("sync:\n  default_mode: mirror\n")
+	sig, err := SignConfig(data, privateKey)
+	if err != nil {
+		t.Fatalf("SignConfig failed: %v", err)
+	}
+	if err := VerifyConfig(data, sig+"\r\n", publicKey); err != nil {
+		t.Errorf("VerifyConfig failed on signed content: %v", err)
+	}
+
+	edited := []byte("sync:\n  default_mode: upload\n")
+	if err := VerifyConfig(edited, sig, publicKey); !errors.Is(err, ErrConfigTampered) {
+		t.Errorf("VerifyConfig(edited) = %v, want ErrConfigTampered", err)
+	}
+
+	otherKey, _, _ := GenerateSigningKey()
+	if err := VerifyConfig(data, sig, otherKey); !errors.Is(err, ErrConfigTampered) {
+		t.Errorf("VerifyConfig(other key) = %v, want ErrConfigTampered", err)
+	}
+	if err := VerifyConfig(data, sig, "not a key"); err == nil {
+		t.Error("VerifyConfig should reject an invalid public key")
+	}
+}
+
+func TestReadManagedConfig(t *testing.T) {
+	publicKey, privateKey, _ := GenerateSigningKey()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("app:\n  language: en\n")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readManagedConfig(file, publicKey); !errors.Is(err, ErrConfigTampered) {
+		t.Errorf("missing signature: err = %v, want ErrConfigTampered", err)
+	}
+
+	sig, _ := SignConfig(data, privateKey)
+	if err := os.WriteFile(SignatureFile(file), []byte(sig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readManagedConfig(file, publicKey)
+	if err != nil || string(got) != string(data) {
+		t.Fatalf("readManagedConfig = %q, %v", got, err)
+	}
+
+	if err := os.WriteFile(file, []byte("app:\n  language: fr\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readManagedConfig(file, publicKey); !errors.Is(err, ErrConfigTampered) {
+		t.Errorf("edited file: err = %v, want ErrConfigTampered", err)
+	}
+	if _, err := readManagedConfig("", publicKey); !errors.Is(err, ErrConfigTampered) {
+		t.Errorf("no file: err = %v, want ErrConfigTampered", err)
+	}
+}
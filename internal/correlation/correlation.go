@@ -0,0 +1,85 @@
+// Package correlation carries the IDs tying log lines together across the
+// engine, executor, SMB and Cloud Files modules: a run ID per sync and an
+// operation ID per file operation. Grepping an ID gives the whole story of a
+// sync or of one file.
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// Log field names
+const (
+	RunIDField = "run_id"
+	OpIDField  = "op_id"
+)
+
+type contextKey int
+
+const (
+	runIDKey contextKey = iota
+	opIDKey
+)
+
+// NewRunID returns a new random sync run ID (12 hex characters).
+func NewRunID() string {
+	return randomID(6)
+}
+
+// NewOpID returns a new random file operation ID (8 hex characters).
+func NewOpID() string {
+	return randomID(4)
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRunID returns a context carrying a sync run ID.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey, runID)
+}
+
+// WithOpID returns a context carrying a file operation ID.
+func WithOpID(ctx context.Context, opID string) context.Context {
+	return context.WithValue(ctx, opIDKey, opID)
+}
+
+// RunID returns the sync run ID of ctx ("" if none).
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey).(string)
+	return id
+}
+
+// OpID returns the file operation ID of ctx ("" if none).
+func OpID(ctx context.Context) string {
+	id, _ := ctx.Value(opIDKey).(string)
+	return id
+}
+
+// Fields returns the IDs of ctx as log fields.
+func Fields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if id := RunID(ctx); id != "" {
+		fields = append(fields, zap.String(RunIDField, id))
+	}
+	if id := OpID(ctx); id != "" {
+		fields = append(fields, zap.String(OpIDField, id))
+	}
+	return fields
+}
+
+// Logger returns base with the IDs of ctx attached.
+func Logger(ctx context.Context, base *zap.Logger) *zap.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return base
+	}
+	return base.With(fields...)
+}
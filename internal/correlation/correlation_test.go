@@ -0,0 +1,31 @@
+package correlation
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	base := zap.New(core)
+
+	if Logger(context.Background(), base) != base {
+		t.Error("expected base logger without IDs")
+	}
+
+	ctx := WithRunID(context.Background(), NewRunID())
+	ctx = WithOpID(ctx, NewOpID())
+	Logger(ctx, base).Info("uploading")
+
+	fields := logs.All()[0].ContextMap()
+	if fields[RunIDField] != RunID(ctx) || fields[OpIDField] != OpID(ctx) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if len(RunID(ctx)) != 12 || len(OpID(ctx)) != 8 {
+		t.Errorf("unexpected ID lengths: %q, %q", RunID(ctx), OpID(ctx))
+	}
+}
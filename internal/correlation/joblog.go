@@ -0,0 +1,137 @@
+package correlation
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Job logs isolate the lines of the jobs that ask for it in their own file,
+// logs\jobs\<job id>.log: while a run of such a job is registered with
+// StartJobLog, every line tagged with its run ID is also written there.
+
+// jobLogs routes the tagged lines of registered runs to their job's file.
+var jobLogs = struct {
+	mu    sync.Mutex
+	runs  map[string]int64             // Run ID -> job ID
+	count map[int64]int                // Registered runs per job
+	files map[int64]*lumberjack.Logger // Open job log files
+}{
+	runs:  make(map[string]int64),
+	count: make(map[int64]int),
+	files: make(map[int64]*lumberjack.Logger),
+}
+
+// JobLogPath returns the log file of a job under the logs directory.
+func JobLogPath(logDir string, jobID int64) string {
+	return filepath.Join(logDir, "jobs", fmt.Sprintf("%d.log", jobID))
+}
+
+// StartJobLog copies the lines tagged with runID to the log file of jobID
+// until EndJobLog.
+func StartJobLog(runID string, jobID int64) {
+	jobLogs.mu.Lock()
+	defer jobLogs.mu.Unlock()
+	jobLogs.runs[runID] = jobID
+	jobLogs.count[jobID]++
+}
+
+// EndJobLog stops copying the lines of runID, closing the job's file when
+// none of its runs is left.
+func EndJobLog(runID string) {
+	jobLogs.mu.Lock()
+	defer jobLogs.mu.Unlock()
+	jobID, ok := jobLogs.runs[runID]
+	if !ok {
+		return
+	}
+	delete(jobLogs.runs, runID)
+	if jobLogs.count[jobID]--; jobLogs.count[jobID] > 0 {
+		return
+	}
+	delete(jobLogs.count, jobID)
+	if file := jobLogs.files[jobID]; file != nil {
+		file.Close()
+		delete(jobLogs.files, jobID)
+	}
+}
+
+// jobLogCore is the zap core writing the lines of registered runs to their
+// job's file. The run ID comes from the fields attached with Logger.
+type jobLogCore struct {
+	zapcore.LevelEnabler
+	logDir string
+	enc    zapcore.Encoder
+	runID  string
+}
+
+// NewJobLogCore returns a zap core to tee with the application's cores: it
+// writes the lines of the runs registered with StartJobLog to their job's
+// log file under logDir, rotated like the main log.
+func NewJobLogCore(logDir string, enc zapcore.Encoder, level zapcore.LevelEnabler) zapcore.Core {
+	return &jobLogCore{LevelEnabler: level, logDir: logDir, enc: enc}
+}
+
+func (c *jobLogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &jobLogCore{
+		LevelEnabler: c.LevelEnabler,
+		logDir:       c.logDir,
+		enc:          c.enc.Clone(),
+		runID:        c.runID,
+	}
+	for _, field := range fields {
+		field.AddTo(clone.enc)
+		if field.Key == RunIDField && field.Type == zapcore.StringType {
+			clone.runID = field.String
+		}
+	}
+	return clone
+}
+
+func (c *jobLogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.runID == "" || !c.Enabled(ent.Level) {
+		return ce
+	}
+	jobLogs.mu.Lock()
+	_, registered := jobLogs.runs[c.runID]
+	jobLogs.mu.Unlock()
+	if !registered {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+func (c *jobLogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	jobLogs.mu.Lock()
+	defer jobLogs.mu.Unlock()
+	jobID, ok := jobLogs.runs[c.runID]
+	if !ok {
+		return nil // Run ended since Check
+	}
+	file := jobLogs.files[jobID]
+	if file == nil {
+		file = &lumberjack.Logger{
+			Filename:   JobLogPath(c.logDir, jobID),
+			MaxSize:    10,   // 10 MB max before rotation
+			MaxBackups: 5,    // Keep 5 old files
+			MaxAge:     30,   // Delete files older than 30 days
+			Compress:   true, // Compress rotated files (.gz)
+		}
+		jobLogs.files[jobID] = file
+	}
+	_, err = file.Write(buf.Bytes())
+	return err
+}
+
+func (c *jobLogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package correlation
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestJobLogCore(t *testing.T) {
+	dir := t.TempDir()
+	enc := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	base := zap.New(NewJobLogCore(dir, enc, zapcore.InfoLevel))
+
+	tracked := WithRunID(context.Background(), NewRunID())
+	other := WithRunID(context.Background(), NewRunID())
+	StartJobLog(RunID(tracked), 7)
+
+	Logger(tracked, base).Info("uploading report.docx")
+	Logger(tracked, base).Debug("below the level")
+	Logger(other, base).Info("another job")
+	base.Info("untagged")
+	EndJobLog(RunID(tracked))
+	Logger(tracked, base).Info("after the run")
+
+	data, err := os.ReadFile(JobLogPath(dir, 7))
+	if err != nil {
+		t.Fatalf("job log not written: %v", err)
+	}
+	log := string(data)
+	if !strings.Contains(log, "uploading report.docx") || !strings.Contains(log, RunID(tracked)) {
+		t.Errorf("job log misses the run's line:\n%s", log)
+	}
+	for _, unwanted := range []string{"below the level", "another job", "untagged", "after the run"} {
+		if strings.Contains(log, unwanted) {
+			t.Errorf("job log contains %q:\n%s", unwanted, log)
+		}
+	}
+	if lines := strings.Count(log, "\n"); lines != 1 {
+		t.Errorf("job log has %d lines, want 1", lines)
+	}
+}
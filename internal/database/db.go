@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 
 	_ "github.com/mutecomm/go-sqlcipher/v4"
 )
@@ -18,6 +20,12 @@ var schemaSQL string
 type DB struct {
 	conn *sql.DB
 	path string
+	key  string // SQLCipher key, used to encrypt backups and job stores
+
+	// Jobs keeping their files_state in their own store (see db_job_store.go)
+	storesMu sync.Mutex
+	isolated map[int64]bool
+	stores   map[int64]*sql.DB
 }
 
 // Config contains database configuration.
@@ -57,6 +65,10 @@ func Open(cfg Config) (*DB, error) {
 	db := &DB{
 		conn: conn,
 		path: cfg.Path,
+		key:  cfg.EncryptionKey,
+
+		isolated: make(map[int64]bool),
+		stores:   make(map[int64]*sql.DB),
 	}
 
 	// Initialize schema if new database
@@ -73,6 +85,11 @@ func Open(cfg Config) (*DB, error) {
 		return nil, fmt.Errorf("schema version check failed: %w", err)
 	}
 
+	if err := db.loadJobStores(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Clean up corrupted cache entries (absolute paths from bug)
 	if err := db.cleanupCorruptedCacheEntries(); err != nil {
 		// Log but don't fail - this is a cleanup operation
@@ -84,12 +101,18 @@ func Open(cfg Config) (*DB, error) {
 
 // Close closes the database connection.
 func (db *DB) Close() error {
+	db.closeJobStores()
 	if db.conn != nil {
 		return db.conn.Close()
 	}
 	return nil
 }
 
+// Path returns the database file path.
+func (db *DB) Path() string {
+	return db.path
+}
+
 // Conn returns the underlying SQL connection.
 func (db *DB) Conn() *sql.DB {
 	return db.conn
@@ -103,7 +126,7 @@ func (db *DB) initSchema() error {
 	return nil
 }
 
-// checkSchemaVersion verifies the database schema version.
+// checkSchemaVersion verifies the database schema version and applies pending migrations.
 func (db *DB) checkSchemaVersion() error {
 	var version string
 	err := db.conn.QueryRow("SELECT value FROM db_metadata WHERE key = 'schema_version'").Scan(&version)
@@ -111,27 +134,45 @@ func (db *DB) checkSchemaVersion() error {
 		return fmt.Errorf("failed to read schema version: %w", err)
 	}
 
-	// TODO: Implement schema migration if needed
-	if version == "" {
-		return fmt.Errorf("invalid schema version")
+	current, err := strconv.Atoi(version)
+	if err != nil || current < 1 {
+		return fmt.Errorf("invalid schema version %q", version)
 	}
 
-	return nil
+	if current > CurrentSchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, CurrentSchemaVersion())
+	}
+
+	return db.migrate(current)
 }
 
-// cleanupCorruptedCacheEntries removes files_state entries with absolute Windows paths.
+// cleanupCorruptedCacheEntries removes files_state entries with absolute Windows paths,
+// in the shared table and in the job stores.
 // This fixes a bug where paths like "D:\data\file.txt" were stored instead of "data/file.txt".
 func (db *DB) cleanupCorruptedCacheEntries() error {
-	result, err := db.conn.Exec(`
-		DELETE FROM files_state
-		WHERE local_path LIKE '%:\%'
-		   OR local_path LIKE '%:/%'
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to cleanup corrupted cache entries: %w", err)
+	conns := []*sql.DB{db.conn}
+	for _, jobID := range db.isolatedJobIDs() {
+		store, err := db.stateConn(jobID)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, store)
+	}
+
+	var rowsAffected int64
+	for _, conn := range conns {
+		result, err := conn.Exec(`
+			DELETE FROM files_state
+			WHERE local_path LIKE '%:\%'
+			   OR local_path LIKE '%:/%'
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to cleanup corrupted cache entries: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		rowsAffected += n
 	}
 
-	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected > 0 {
 		fmt.Printf("Cleaned up %d corrupted cache entries with absolute paths\n", rowsAffected)
 	}
@@ -142,7 +183,7 @@ func (db *DB) cleanupCorruptedCacheEntries() error {
 // fileExists checks if a file exists.
 func fileExists(path string) bool {
 	info, err := os.Stat(path)
-	if os.IsNotExist(err) {
+	if err != nil {
 		return false
 	}
 	return !info.IsDir()
@@ -150,7 +191,12 @@ func fileExists(path string) bool {
 
 // Transaction executes a function within a transaction.
 func (db *DB) Transaction(fn func(*sql.Tx) error) error {
-	tx, err := db.conn.Begin()
+	return transaction(db.conn, fn)
+}
+
+// transaction executes a function within a transaction on conn.
+func transaction(conn *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := conn.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
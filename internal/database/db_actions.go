@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// --- Sync Actions ---
+
+// SyncActionRetention is how long executed actions are kept in sync_actions.
+const SyncActionRetention = 30 * 24 * time.Hour
+
+// InsertSyncActions records the actions executed by a sync run and drops
+// the records older than SyncActionRetention.
+func (db *DB) InsertSyncActions(records []*SyncActionRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO sync_actions (job_id, run_id, op_id, action, path, status, bytes, error, changed_by, timestamp)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, r := range records {
+			if r.Timestamp.IsZero() {
+				r.Timestamp = time.Now()
+			}
+			if _, err := stmt.Exec(r.JobID, r.RunID, r.OpID, r.Action, r.Path, r.Status,
+				r.Bytes, r.Error, r.ChangedBy, r.Timestamp.Unix()); err != nil {
+				return fmt.Errorf("insert sync action %s: %w", r.Path, err)
+			}
+		}
+
+		cutoff := time.Now().Add(-SyncActionRetention).Unix()
+		if _, err := tx.Exec(`DELETE FROM sync_actions WHERE timestamp < ?`, cutoff); err != nil {
+			return fmt.Errorf("prune sync actions: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetSyncActionsByRun retrieves the actions of a sync run, in execution order.
+func (db *DB) GetSyncActionsByRun(runID string) ([]*SyncActionRecord, error) {
+	return db.querySyncActions(`WHERE run_id = ? ORDER BY id`, runID)
+}
+
+// GetSyncActionsByPath retrieves the actions on a file of a job, most recent
+// first.
+func (db *DB) GetSyncActionsByPath(jobID int64, path string) ([]*SyncActionRecord, error) {
+	return db.querySyncActions(`WHERE job_id = ? AND path = ? ORDER BY id DESC`, jobID, path)
+}
+
+// GetSyncActionsSince retrieves the actions of a job executed since a time,
+// in execution order.
+func (db *DB) GetSyncActionsSince(jobID int64, since time.Time) ([]*SyncActionRecord, error) {
+	return db.querySyncActions(`WHERE job_id = ? AND timestamp >= ? ORDER BY id`, jobID, since.Unix())
+}
+
+func (db *DB) querySyncActions(where string, args ...interface{}) ([]*SyncActionRecord, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, job_id, run_id, op_id, action, path, status, bytes, error, changed_by, timestamp
+		FROM sync_actions
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query sync actions: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*SyncActionRecord
+	for rows.Next() {
+		var r SyncActionRecord
+		var timestamp int64
+		if err := rows.Scan(&r.ID, &r.JobID, &r.RunID, &r.OpID, &r.Action, &r.Path, &r.Status,
+			&r.Bytes, &r.Error, &r.ChangedBy, &timestamp); err != nil {
+			return nil, fmt.Errorf("scan sync action: %w", err)
+		}
+		r.Timestamp = time.Unix(timestamp, 0)
+		records = append(records, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sync actions: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncActions(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	records := []*SyncActionRecord{
+		{JobID: 1, RunID: "run1", OpID: "op1", Action: "upload", Path: "a.txt", Status: "success", Bytes: 10},
+		{JobID: 1, RunID: "run1", OpID: "op2", Action: "download", Path: "b.txt", Status: "failed", Error: "timeout"},
+		{JobID: 1, RunID: "run0", OpID: "op0", Action: "upload", Path: "a.txt", Status: "success",
+			Timestamp: time.Now().Add(-SyncActionRetention - time.Hour)},
+	}
+	if err := db.InsertSyncActions(records); err != nil {
+		t.Fatalf("InsertSyncActions failed: %v", err)
+	}
+
+	got, err := db.GetSyncActionsByRun("run1")
+	if err != nil {
+		t.Fatalf("GetSyncActionsByRun failed: %v", err)
+	}
+	if len(got) != 2 || got[0].OpID != "op1" || got[1].Error != "timeout" {
+		t.Errorf("unexpected run actions: %+v", got)
+	}
+
+	// The expired record was pruned
+	history, err := db.GetSyncActionsByPath(1, "a.txt")
+	if err != nil {
+		t.Fatalf("GetSyncActionsByPath failed: %v", err)
+	}
+	if len(history) != 1 || history[0].RunID != "run1" {
+		t.Errorf("unexpected file history: %+v", history)
+	}
+
+	recent, err := db.GetSyncActionsSince(1, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetSyncActionsSince failed: %v", err)
+	}
+	if len(recent) != 2 {
+		t.Errorf("expected the 2 actions of the last hour, got %d", len(recent))
+	}
+	if other, _ := db.GetSyncActionsSince(2, time.Time{}); len(other) != 0 {
+		t.Errorf("expected no actions for another job, got %d", len(other))
+	}
+}
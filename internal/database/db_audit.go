@@ -0,0 +1,136 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// --- Audit Log ---
+//
+// audit_log is append-only: triggers refuse updates and deletes, and each
+// entry carries the hash of the previous one, so an entry edited or removed
+// with another SQLite tool breaks the chain (see VerifyAuditLog). Removing
+// the last entries keeps a valid chain: compare the head with an earlier
+// export to detect it.
+
+// ErrAuditTampered is returned when the audit log chain is broken.
+var ErrAuditTampered = errors.New("audit log was modified")
+
+// AppendAudit appends entries to the audit log, chained to the last one.
+// Their ID, PrevHash and Hash are set.
+func (db *DB) AppendAudit(entries []*AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		var prev string
+		err := tx.QueryRow(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&prev)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("read audit log head: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT INTO audit_log (job_id, run_id, op_id, event, path, detail, actor, timestamp, prev_hash, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, e := range entries {
+			if e.Timestamp.IsZero() {
+				e.Timestamp = time.Now()
+			}
+			e.Timestamp = time.Unix(e.Timestamp.Unix(), 0) // Stored in seconds
+			e.PrevHash = prev
+			e.Hash = auditHash(prev, e)
+			res, err := stmt.Exec(e.JobID, e.RunID, e.OpID, e.Event, e.Path, e.Detail, e.Actor,
+				e.Timestamp.Unix(), e.PrevHash, e.Hash)
+			if err != nil {
+				return fmt.Errorf("insert audit entry %s: %w", e.Path, err)
+			}
+			if e.ID, err = res.LastInsertId(); err != nil {
+				return fmt.Errorf("get audit entry ID: %w", err)
+			}
+			prev = e.Hash
+		}
+		return nil
+	})
+}
+
+// GetAuditLog retrieves the audit entries of a job (0 = all entries) since
+// a time, oldest first.
+func (db *DB) GetAuditLog(jobID int64, since time.Time) ([]*AuditEntry, error) {
+	if jobID == 0 {
+		return db.queryAudit(`WHERE timestamp >= ? ORDER BY id`, since.Unix())
+	}
+	return db.queryAudit(`WHERE job_id = ? AND timestamp >= ? ORDER BY id`, jobID, since.Unix())
+}
+
+// VerifyAuditLog checks the hash chain of the whole audit log and returns
+// the number of entries checked. Returns ErrAuditTampered, with the first
+// entry not matching, if the chain is broken.
+func (db *DB) VerifyAuditLog() (int, error) {
+	entries, err := db.queryAudit(`ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+
+	prev := ""
+	for i, e := range entries {
+		if e.PrevHash != prev {
+			return i, fmt.Errorf("%w: entry %d does not follow the previous one", ErrAuditTampered, e.ID)
+		}
+		if e.Hash != auditHash(prev, e) {
+			return i, fmt.Errorf("%w: entry %d does not match its hash", ErrAuditTampered, e.ID)
+		}
+		prev = e.Hash
+	}
+	return len(entries), nil
+}
+
+// auditHash returns the hash chaining an entry to the previous one: SHA-256
+// of the previous hash and of the fields of the entry, as a JSON array.
+func auditHash(prev string, e *AuditEntry) string {
+	data, _ := json.Marshal([]interface{}{
+		prev, e.JobID, e.RunID, e.OpID, e.Event, e.Path, e.Detail, e.Actor, e.Timestamp.Unix(),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (db *DB) queryAudit(where string, args ...interface{}) ([]*AuditEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, job_id, run_id, op_id, event, path, detail, actor, timestamp, prev_hash, hash
+		FROM audit_log
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var timestamp int64
+		if err := rows.Scan(&e.ID, &e.JobID, &e.RunID, &e.OpID, &e.Event, &e.Path, &e.Detail,
+			&e.Actor, &timestamp, &e.PrevHash, &e.Hash); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		e.Timestamp = time.Unix(timestamp, 0)
+		entries = append(entries, &e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := db.AppendAudit([]*AuditEntry{
+		{JobID: 1, RunID: "run0", Event: AuditDeleteRemote, Path: "old.txt", Timestamp: old},
+	}); err != nil {
+		t.Fatalf("AppendAudit failed: %v", err)
+	}
+	entries := []*AuditEntry{
+		{JobID: 1, RunID: "run1", OpID: "op1", Event: AuditOverwriteLocal, Path: "a.txt", Actor: `CORP\me@PC`},
+		{JobID: 2, RunID: "run2", OpID: "op2", Event: AuditConflict, Path: "b.txt", Detail: "local newer"},
+	}
+	if err := db.AppendAudit(entries); err != nil {
+		t.Fatalf("AppendAudit failed: %v", err)
+	}
+	if entries[0].PrevHash == "" || entries[1].PrevHash != entries[0].Hash {
+		t.Errorf("entries not chained: %+v", entries)
+	}
+
+	recent, err := db.GetAuditLog(0, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetAuditLog failed: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Actor != `CORP\me@PC` || recent[1].Detail != "local newer" {
+		t.Errorf("unexpected recent entries: %+v", recent)
+	}
+	if job1, _ := db.GetAuditLog(1, time.Time{}); len(job1) != 2 {
+		t.Errorf("expected 2 entries for job 1, got %d", len(job1))
+	}
+
+	if n, err := db.VerifyAuditLog(); err != nil || n != 3 {
+		t.Fatalf("VerifyAuditLog() = %d, %v", n, err)
+	}
+
+	// The log refuses edits and deletions
+	if _, err := db.conn.Exec(`UPDATE audit_log SET path = 'x' WHERE id = ?`, entries[0].ID); err == nil {
+		t.Error("expected the update to be refused")
+	}
+	if _, err := db.conn.Exec(`DELETE FROM audit_log`); err == nil {
+		t.Error("expected the delete to be refused")
+	}
+
+	// An edit made without the triggers breaks the chain
+	if _, err := db.conn.Exec(`DROP TRIGGER audit_log_no_update`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`UPDATE audit_log SET path = 'x' WHERE id = ?`, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.VerifyAuditLog(); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("VerifyAuditLog() = %v, want ErrAuditTampered", err)
+	}
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Backup & Restore ---
+
+// backupFilePrefix and backupTimeFormat name automatic backups, so that
+// sorting names sorts them by date.
+const (
+	backupFilePrefix = "anemonesync-"
+	backupTimeFormat = "20060102-150405"
+)
+
+// BackupDir returns the folder holding the rotating backups of the database at dbPath.
+func BackupDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "backups")
+}
+
+// Backup writes an encrypted copy of the database to destPath (same key).
+// The copy is consistent even while syncs are writing. Job stores are
+// copied next to it (destPath + ".job-<id>").
+func (db *DB) Backup(destPath string) error {
+	if fileExists(destPath) {
+		return fmt.Errorf("backup file already exists: %s", destPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if err := exportDatabase(db.conn, destPath, db.key); err != nil {
+		return err
+	}
+
+	for _, jobID := range db.isolatedJobIDs() {
+		store, err := db.stateConn(jobID)
+		if err == nil {
+			err = exportDatabase(store, jobStoreBackupPath(destPath, jobID), db.key)
+		}
+		if err != nil {
+			removeBackup(destPath)
+			return fmt.Errorf("failed to back up job %d store: %w", jobID, err)
+		}
+	}
+
+	return nil
+}
+
+// exportDatabase writes an encrypted copy of conn's database to destPath.
+func exportDatabase(conn *sql.DB, destPath, key string) error {
+	// ATTACH is per connection: run the export on a single one
+	ctx := context.Background()
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer c.Close()
+
+	if _, err := c.ExecContext(ctx, "ATTACH DATABASE ? AS backup KEY ?", destPath, key); err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	_, exportErr := c.ExecContext(ctx, "SELECT sqlcipher_export('backup')")
+	if _, err := c.ExecContext(ctx, "DETACH DATABASE backup"); err != nil && exportErr == nil {
+		exportErr = err
+	}
+	if exportErr != nil {
+		os.Remove(destPath)
+		return fmt.Errorf("failed to export database: %w", exportErr)
+	}
+	return nil
+}
+
+// jobStoreBackupPath returns where the store of a job is copied in the backup at backupPath.
+func jobStoreBackupPath(backupPath string, jobID int64) string {
+	return fmt.Sprintf("%s.job-%d", backupPath, jobID)
+}
+
+// jobStoreBackups returns the job store copies of the backup at backupPath, by job ID.
+func jobStoreBackups(backupPath string) map[int64]string {
+	matches, _ := filepath.Glob(backupPath + ".job-*")
+	stores := make(map[int64]string, len(matches))
+	for _, match := range matches {
+		jobID, err := strconv.ParseInt(strings.TrimPrefix(match, backupPath+".job-"), 10, 64)
+		if err == nil {
+			stores[jobID] = match
+		}
+	}
+	return stores
+}
+
+// removeBackup deletes a backup and its job store copies.
+func removeBackup(backupPath string) error {
+	for _, store := range jobStoreBackups(backupPath) {
+		os.Remove(store)
+	}
+	return os.Remove(backupPath)
+}
+
+// BackupRotate writes a timestamped backup into dir and deletes the oldest
+// automatic backups so that at most keep remain. Returns the new backup path.
+func (db *DB) BackupRotate(dir string, keep int) (string, error) {
+	path := filepath.Join(dir, backupFilePrefix+time.Now().Format(backupTimeFormat)+".db")
+	if err := db.Backup(path); err != nil {
+		return "", err
+	}
+
+	backups, err := rotatingBackups(dir)
+	if err != nil {
+		return path, err
+	}
+	if keep < 1 {
+		keep = 1
+	}
+	for _, old := range backups[min(keep, len(backups)):] {
+		if err := removeBackup(old); err != nil {
+			return path, fmt.Errorf("failed to delete old backup: %w", err)
+		}
+	}
+
+	return path, nil
+}
+
+// ListBackups returns the backups in dir (automatic and manual), newest first.
+func ListBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	modTimes := make(map[string]time.Time)
+	var backups []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".db" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		modTimes[path] = info.ModTime()
+		backups = append(backups, path)
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return modTimes[backups[i]].After(modTimes[backups[j]])
+	})
+	return backups, nil
+}
+
+// rotatingBackups returns the automatic backups in dir, newest first.
+func rotatingBackups(dir string) ([]string, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotating []string
+	for _, path := range backups {
+		if strings.HasPrefix(filepath.Base(path), backupFilePrefix) {
+			rotating = append(rotating, path)
+		}
+	}
+	// Names embed the creation time
+	sort.Sort(sort.Reverse(sort.StringSlice(rotating)))
+	return rotating, nil
+}
+
+// LastBackupTime returns when the newest automatic backup in dir was written
+// (zero if there is none).
+func LastBackupTime(dir string) time.Time {
+	backups, err := rotatingBackups(dir)
+	if err != nil || len(backups) == 0 {
+		return time.Time{}
+	}
+	info, err := os.Stat(backups[0])
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// beforeRestoreSuffix names the files a restore replaced.
+const beforeRestoreSuffix = ".before-restore"
+
+// Restore replaces the database at cfg.Path with the backup at backupPath.
+// The database must not be open. The replaced database is kept next to it
+// (".before-restore") so a restore can be undone; if the restore fails, it is
+// put back in place.
+func Restore(cfg Config, backupPath string) (err error) {
+	if err := verifyBackup(backupPath, cfg.EncryptionKey); err != nil {
+		return err
+	}
+
+	stores := jobStoreBackups(backupPath)
+	targets := []string{cfg.Path}
+	for jobID := range stores {
+		targets = append(targets, JobStorePath(cfg.Path, jobID))
+	}
+
+	// Move the replaced files aside, journal files included: they belong to
+	// the replaced database
+	var replaced []string
+	defer func() {
+		if err != nil {
+			undoRestore(targets, replaced)
+		}
+	}()
+	for _, path := range targets {
+		for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+			file := path + suffix
+			if !fileExists(file) {
+				continue
+			}
+			os.Remove(file + beforeRestoreSuffix) // Only the last restore can be undone
+			if err := os.Rename(file, file+beforeRestoreSuffix); err != nil {
+				return fmt.Errorf("failed to move current database aside: %w", err)
+			}
+			replaced = append(replaced, file)
+		}
+	}
+
+	if err := copyFile(backupPath, cfg.Path); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	for jobID, store := range stores {
+		path := JobStorePath(cfg.Path, jobID)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create job store directory: %w", err)
+		}
+		if err := copyFile(store, path); err != nil {
+			return fmt.Errorf("failed to restore job %d store: %w", jobID, err)
+		}
+	}
+	return nil
+}
+
+// undoRestore deletes what a failed restore wrote to targets and moves the
+// replaced files back.
+func undoRestore(targets, replaced []string) {
+	for _, path := range targets {
+		removeDatabaseFiles(path)
+	}
+	for _, file := range replaced {
+		os.Rename(file+beforeRestoreSuffix, file)
+	}
+}
+
+// verifyBackup checks that path is a database readable with key.
+func verifyBackup(path, key string) error {
+	if !fileExists(path) {
+		return fmt.Errorf("backup file not found: %s", path)
+	}
+
+	connStr := fmt.Sprintf("file:%s?_pragma_key=%s&_pragma_cipher_page_size=4096&mode=ro", path, key)
+	conn, err := sql.Open("sqlite3", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer conn.Close()
+
+	var version string
+	if err := conn.QueryRow("SELECT value FROM db_metadata WHERE key = 'schema_version'").Scan(&version); err != nil {
+		return fmt.Errorf("not a valid AnemoneSync backup: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst, syncing dst to disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
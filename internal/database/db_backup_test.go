@@ -0,0 +1,146 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		Path:             filepath.Join(dir, "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := db.SetAppConfig("state", "before", "string"); err != nil {
+		t.Fatalf("SetAppConfig failed: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := db.Backup(backupPath); err == nil {
+		t.Error("expected error when the backup file exists")
+	}
+
+	if err := db.SetAppConfig("state", "after", "string"); err != nil {
+		t.Fatalf("SetAppConfig failed: %v", err)
+	}
+	db.Close()
+
+	// A backup with another key is rejected
+	if err := Restore(Config{Path: cfg.Path, EncryptionKey: "wrong-key"}, backupPath); err == nil {
+		t.Error("expected restore with wrong key to fail")
+	}
+
+	if err := Restore(cfg, backupPath); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if _, err := os.Stat(cfg.Path + ".before-restore"); err != nil {
+		t.Errorf("expected replaced database kept: %v", err)
+	}
+
+	db, err = Open(cfg)
+	if err != nil {
+		t.Fatalf("Open after restore failed: %v", err)
+	}
+	defer db.Close()
+	if value, err := db.GetAppConfig("state"); err != nil || value != "before" {
+		t.Errorf("expected restored state 'before', got %q (err=%v)", value, err)
+	}
+}
+
+func TestRestore_FailureKeepsCurrentDatabase(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		Path:             filepath.Join(dir, "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := db.SetAppConfig("state", "current", "string"); err != nil {
+		t.Fatalf("SetAppConfig failed: %v", err)
+	}
+	db.Close()
+
+	// A job store that can't be written: "jobs" is a file, not a directory
+	if err := os.WriteFile(backupPath+".job-7", []byte("store"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "jobs"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(cfg, backupPath); err == nil {
+		t.Fatal("expected restore to fail")
+	}
+
+	db, err = Open(cfg)
+	if err != nil {
+		t.Fatalf("Open after failed restore failed: %v", err)
+	}
+	defer db.Close()
+	if value, err := db.GetAppConfig("state"); err != nil || value != "current" {
+		t.Errorf("expected current state kept, got %q (err=%v)", value, err)
+	}
+}
+
+func TestBackupRotate(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(Config{
+		Path:             filepath.Join(dir, "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	backupDir := BackupDir(db.Path())
+	for _, name := range []string{"anemonesync-20240101-000000.db", "anemonesync-20240102-000000.db", "manual.db"} {
+		if err := os.MkdirAll(backupDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(backupDir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := db.BackupRotate(backupDir, 2)
+	if err != nil {
+		t.Fatalf("BackupRotate failed: %v", err)
+	}
+
+	backups, err := rotatingBackups(backupDir)
+	if err != nil {
+		t.Fatalf("rotatingBackups failed: %v", err)
+	}
+	if len(backups) != 2 || backups[0] != path || filepath.Base(backups[1]) != "anemonesync-20240102-000000.db" {
+		t.Errorf("expected newest 2 backups kept, got %v", backups)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, "manual.db")); err != nil {
+		t.Error("manual backups must not be rotated")
+	}
+
+	all, err := ListBackups(backupDir)
+	if err != nil || len(all) != 3 || all[0] != path {
+		t.Errorf("expected all 3 backups listed newest first, got %v (err=%v)", all, err)
+	}
+	if LastBackupTime(backupDir).IsZero() {
+		t.Error("expected last backup time")
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// --- Conflicts ---
+
+// InsertConflicts records the conflicts resolved by a sync run whose losing
+// version was kept.
+func (db *DB) InsertConflicts(records []*ConflictRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO conflicts (job_id, run_id, path, copy_path, winner, changed_by, timestamp)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, r := range records {
+			if r.Timestamp.IsZero() {
+				r.Timestamp = time.Now()
+			}
+			if _, err := stmt.Exec(r.JobID, r.RunID, r.Path, r.CopyPath, r.Winner, r.ChangedBy, r.Timestamp.Unix()); err != nil {
+				return fmt.Errorf("insert conflict %s: %w", r.Path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetConflicts retrieves the conflicts of a job, most recent first.
+func (db *DB) GetConflicts(jobID int64) ([]*ConflictRecord, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, job_id, run_id, path, copy_path, winner, changed_by, timestamp
+		FROM conflicts
+		WHERE job_id = ?
+		ORDER BY timestamp DESC, id DESC
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*ConflictRecord
+	for rows.Next() {
+		var r ConflictRecord
+		var timestamp int64
+		if err := rows.Scan(&r.ID, &r.JobID, &r.RunID, &r.Path, &r.CopyPath, &r.Winner, &r.ChangedBy, &timestamp); err != nil {
+			return nil, fmt.Errorf("scan conflict: %w", err)
+		}
+		r.Timestamp = time.Unix(timestamp, 0)
+		records = append(records, &r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate conflicts: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConflicts(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	records := []*ConflictRecord{
+		{JobID: 1, RunID: "run0", Path: "a.txt", CopyPath: "a (conflict from PC1 2025-01-30).txt",
+			Winner: "local", Timestamp: now.Add(-24 * time.Hour)},
+		{JobID: 1, RunID: "run1", Path: "b.txt", CopyPath: "b (conflict from PC1 2025-01-31).txt",
+			Winner: "remote", Timestamp: now},
+		{JobID: 2, RunID: "run2", Path: "c.txt", CopyPath: "c (conflict from PC1 2025-01-31).txt", Winner: "local"},
+	}
+	if err := db.InsertConflicts(records); err != nil {
+		t.Fatalf("InsertConflicts failed: %v", err)
+	}
+	if err := db.InsertConflicts([]*ConflictRecord{{JobID: 1, RunID: "run1", Path: "d.txt", CopyPath: "d2.txt", Winner: "both"}}); err == nil {
+		t.Error("expected an error for an invalid winner")
+	}
+
+	got, err := db.GetConflicts(1)
+	if err != nil {
+		t.Fatalf("GetConflicts failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Path != "b.txt" || got[0].Winner != "remote" || got[1].CopyPath != records[0].CopyPath {
+		t.Errorf("unexpected conflicts: %+v", got)
+	}
+	if other, _ := db.GetConflicts(3); len(other) != 0 {
+		t.Errorf("expected no conflicts for another job, got %d", len(other))
+	}
+}
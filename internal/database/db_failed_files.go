@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// --- Failed Files ---
+
+// RecordFailedFiles records the files of a job whose action failed after its
+// retries, and forgets the files synced since. A file failing again keeps its
+// first failure time and adds up its attempts and failed runs.
+func (db *DB) RecordFailedFiles(jobID int64, failed []*FailedFile, synced []string) error {
+	if len(failed) == 0 && len(synced) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		if len(synced) > 0 {
+			del, err := tx.Prepare(`DELETE FROM failed_files WHERE job_id = ? AND path = ?`)
+			if err != nil {
+				return fmt.Errorf("prepare statement: %w", err)
+			}
+			defer del.Close()
+			for _, path := range synced {
+				if _, err := del.Exec(jobID, path); err != nil {
+					return fmt.Errorf("delete failed file %s: %w", path, err)
+				}
+			}
+		}
+
+		if len(failed) == 0 {
+			return nil
+		}
+		stmt, err := tx.Prepare(`
+			INSERT INTO failed_files (job_id, path, action, error, attempts, runs, first_failed_at, last_failed_at)
+			VALUES (?, ?, ?, ?, ?, 1, ?, ?)
+			ON CONFLICT(job_id, path) DO UPDATE SET
+				action = excluded.action,
+				error = excluded.error,
+				attempts = attempts + excluded.attempts,
+				runs = runs + 1,
+				last_failed_at = excluded.last_failed_at
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, f := range failed {
+			if f.LastFailedAt.IsZero() {
+				f.LastFailedAt = time.Now()
+			}
+			if _, err := stmt.Exec(jobID, f.Path, f.Action, f.Error, f.Attempts,
+				f.LastFailedAt.Unix(), f.LastFailedAt.Unix()); err != nil {
+				return fmt.Errorf("save failed file %s: %w", f.Path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetFailedFiles retrieves the files of a job still failing, keyed by path.
+func (db *DB) GetFailedFiles(jobID int64) (map[string]*FailedFile, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, action, error, attempts, runs, first_failed_at, last_failed_at
+		FROM failed_files
+		WHERE job_id = ?
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]*FailedFile)
+	for rows.Next() {
+		f := FailedFile{JobID: jobID}
+		var firstFailed, lastFailed int64
+		if err := rows.Scan(&f.Path, &f.Action, &f.Error, &f.Attempts, &f.Runs, &firstFailed, &lastFailed); err != nil {
+			return nil, fmt.Errorf("scan failed file: %w", err)
+		}
+		f.FirstFailedAt = time.Unix(firstFailed, 0)
+		f.LastFailedAt = time.Unix(lastFailed, 0)
+		files[f.Path] = &f
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate failed files: %w", err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFailedFiles(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	first := time.Now().Add(-time.Hour)
+	if err := db.RecordFailedFiles(1, []*FailedFile{
+		{Path: "a.txt", Action: "upload", Error: "sharing violation", Attempts: 4, LastFailedAt: first},
+		{Path: "b.txt", Action: "download", Error: "access denied", Attempts: 1, LastFailedAt: first},
+	}, nil); err != nil {
+		t.Fatalf("RecordFailedFiles failed: %v", err)
+	}
+
+	// Next run: a.txt fails again, b.txt was synced
+	if err := db.RecordFailedFiles(1, []*FailedFile{
+		{Path: "a.txt", Action: "upload", Error: "connection reset", Attempts: 4},
+	}, []string{"b.txt"}); err != nil {
+		t.Fatalf("RecordFailedFiles failed: %v", err)
+	}
+
+	files, err := db.GetFailedFiles(1)
+	if err != nil {
+		t.Fatalf("GetFailedFiles failed: %v", err)
+	}
+	a := files["a.txt"]
+	if len(files) != 1 || a == nil {
+		t.Fatalf("unexpected failed files: %+v", files)
+	}
+	if a.Attempts != 8 || a.Runs != 2 || a.Error != "connection reset" {
+		t.Errorf("a.txt = %+v, want 8 attempts over 2 runs with the last error", a)
+	}
+	if a.FirstFailedAt.Unix() != first.Unix() || !a.LastFailedAt.After(first) {
+		t.Errorf("a.txt failed from %v to %v, want from %v", a.FirstFailedAt, a.LastFailedAt, first)
+	}
+
+	if other, _ := db.GetFailedFiles(2); len(other) != 0 {
+		t.Errorf("expected no failed files for job 2, got %d", len(other))
+	}
+}
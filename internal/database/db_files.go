@@ -12,11 +12,17 @@ import (
 func (db *DB) GetFileState(jobID int64, localPath string) (*FileState, error) {
 	var state FileState
 	var hash, errorMsg sql.NullString
-	var lastSync sql.NullInt64
+	var lastSync, remoteWrite, remoteChange sql.NullInt64
 
-	err := db.conn.QueryRow(`
+	conn, err := db.stateConn(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.QueryRow(`
 		SELECT id, job_id, local_path, remote_path, size, mtime, hash,
-		       last_sync, sync_status, error_message, created_at, updated_at
+		       last_sync, sync_status, error_message, created_at, updated_at,
+		       remote_write_time, remote_change_time, attributes, remote_owner, verified_at
 		FROM files_state
 		WHERE job_id = ? AND local_path = ?
 	`, jobID, localPath).Scan(
@@ -32,10 +38,15 @@ func (db *DB) GetFileState(jobID int64, localPath string) (*FileState, error) {
 		&errorMsg,
 		&state.CreatedAt,
 		&state.UpdatedAt,
+		&remoteWrite,
+		&remoteChange,
+		&state.Attributes,
+		&state.RemoteOwner,
+		&state.VerifiedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("file state not found for job %d path %s", jobID, localPath)
+		return nil, fmt.Errorf("file state not found for job %d path %s: %w", jobID, localPath, err)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query file state: %w", err)
@@ -49,6 +60,12 @@ func (db *DB) GetFileState(jobID int64, localPath string) (*FileState, error) {
 	if errorMsg.Valid {
 		state.ErrorMessage = &errorMsg.String
 	}
+	if remoteWrite.Valid {
+		state.RemoteWriteTime = &remoteWrite.Int64
+	}
+	if remoteChange.Valid {
+		state.RemoteChangeTime = &remoteChange.Int64
+	}
 
 	return &state, nil
 }
@@ -65,9 +82,14 @@ func (db *DB) UpsertFileState(state *FileState) error {
 		lastSync = nil
 	}
 
-	_, err := db.conn.Exec(`
-		INSERT INTO files_state (job_id, local_path, remote_path, size, mtime, hash, last_sync, sync_status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	conn, err := db.stateConn(state.JobID)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(`
+		INSERT INTO files_state (job_id, local_path, remote_path, size, mtime, hash, last_sync, sync_status, created_at, updated_at, remote_write_time, remote_change_time, attributes, remote_owner, verified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(job_id, local_path)
 		DO UPDATE SET
 			remote_path = excluded.remote_path,
@@ -76,8 +98,14 @@ func (db *DB) UpsertFileState(state *FileState) error {
 			hash = excluded.hash,
 			last_sync = excluded.last_sync,
 			sync_status = excluded.sync_status,
-			updated_at = excluded.updated_at
-	`, state.JobID, state.LocalPath, state.RemotePath, state.Size, state.MTime, state.Hash, lastSync, state.SyncStatus, now, now)
+			updated_at = excluded.updated_at,
+			remote_write_time = COALESCE(excluded.remote_write_time, files_state.remote_write_time),
+			remote_change_time = COALESCE(excluded.remote_change_time, files_state.remote_change_time),
+			attributes = COALESCE(NULLIF(excluded.attributes, 0), files_state.attributes),
+			remote_owner = COALESCE(NULLIF(excluded.remote_owner, ''), files_state.remote_owner),
+			verified_at = excluded.verified_at
+	`, state.JobID, state.LocalPath, state.RemotePath, state.Size, state.MTime, state.Hash, lastSync, state.SyncStatus, now, now,
+		nullableInt64(state.RemoteWriteTime), nullableInt64(state.RemoteChangeTime), state.Attributes, state.RemoteOwner, state.VerifiedAt)
 
 	if err != nil {
 		return fmt.Errorf("upsert file state: %w", err)
@@ -91,11 +119,35 @@ func (db *DB) BulkUpdateFileStates(states []*FileState) error {
 		return nil
 	}
 
-	return db.Transaction(func(tx *sql.Tx) error {
+	// Each job's states are written to the store holding them
+	byJob := make(map[int64][]*FileState)
+	var jobIDs []int64
+	for _, state := range states {
+		if _, ok := byJob[state.JobID]; !ok {
+			jobIDs = append(jobIDs, state.JobID)
+		}
+		byJob[state.JobID] = append(byJob[state.JobID], state)
+	}
+
+	for _, jobID := range jobIDs {
+		conn, err := db.stateConn(jobID)
+		if err != nil {
+			return err
+		}
+		if err := bulkUpsertFileStates(conn, byJob[jobID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bulkUpsertFileStates upserts states on conn in a single transaction
+func bulkUpsertFileStates(conn *sql.DB, states []*FileState) error {
+	return transaction(conn, func(tx *sql.Tx) error {
 		now := time.Now().Unix()
 		stmt, err := tx.Prepare(`
-			INSERT INTO files_state (job_id, local_path, remote_path, size, mtime, hash, last_sync, sync_status, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO files_state (job_id, local_path, remote_path, size, mtime, hash, last_sync, sync_status, created_at, updated_at, remote_write_time, remote_change_time, attributes, remote_owner, verified_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(job_id, local_path)
 			DO UPDATE SET
 				remote_path = excluded.remote_path,
@@ -104,7 +156,12 @@ func (db *DB) BulkUpdateFileStates(states []*FileState) error {
 				hash = excluded.hash,
 				last_sync = excluded.last_sync,
 				sync_status = excluded.sync_status,
-				updated_at = excluded.updated_at
+				updated_at = excluded.updated_at,
+				remote_write_time = COALESCE(excluded.remote_write_time, files_state.remote_write_time),
+				remote_change_time = COALESCE(excluded.remote_change_time, files_state.remote_change_time),
+				attributes = COALESCE(NULLIF(excluded.attributes, 0), files_state.attributes),
+			remote_owner = COALESCE(NULLIF(excluded.remote_owner, ''), files_state.remote_owner),
+				verified_at = excluded.verified_at
 		`)
 		if err != nil {
 			return fmt.Errorf("prepare statement: %w", err)
@@ -116,7 +173,8 @@ func (db *DB) BulkUpdateFileStates(states []*FileState) error {
 			if state.LastSync != nil {
 				lastSync = *state.LastSync
 			}
-			_, err := stmt.Exec(state.JobID, state.LocalPath, state.RemotePath, state.Size, state.MTime, state.Hash, lastSync, state.SyncStatus, now, now)
+			_, err := stmt.Exec(state.JobID, state.LocalPath, state.RemotePath, state.Size, state.MTime, state.Hash, lastSync, state.SyncStatus, now, now,
+				nullableInt64(state.RemoteWriteTime), nullableInt64(state.RemoteChangeTime), state.Attributes, state.RemoteOwner, state.VerifiedAt)
 			if err != nil {
 				return fmt.Errorf("execute statement for %s: %w", state.LocalPath, err)
 			}
@@ -128,9 +186,15 @@ func (db *DB) BulkUpdateFileStates(states []*FileState) error {
 
 // GetAllFileStates retrieves all file states for a job
 func (db *DB) GetAllFileStates(jobID int64) ([]*FileState, error) {
-	rows, err := db.conn.Query(`
+	conn, err := db.stateConn(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(`
 		SELECT id, job_id, local_path, remote_path, size, mtime, hash,
-		       last_sync, sync_status, error_message, created_at, updated_at
+		       last_sync, sync_status, error_message, created_at, updated_at,
+		       remote_write_time, remote_change_time, attributes, remote_owner, verified_at
 		FROM files_state
 		WHERE job_id = ?
 	`, jobID)
@@ -143,7 +207,7 @@ func (db *DB) GetAllFileStates(jobID int64) ([]*FileState, error) {
 	for rows.Next() {
 		var state FileState
 		var hash, errorMsg sql.NullString
-		var lastSync sql.NullInt64
+		var lastSync, remoteWrite, remoteChange sql.NullInt64
 
 		err := rows.Scan(
 			&state.ID,
@@ -158,6 +222,11 @@ func (db *DB) GetAllFileStates(jobID int64) ([]*FileState, error) {
 			&errorMsg,
 			&state.CreatedAt,
 			&state.UpdatedAt,
+			&remoteWrite,
+			&remoteChange,
+			&state.Attributes,
+			&state.RemoteOwner,
+			&state.VerifiedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan file state: %w", err)
@@ -171,6 +240,12 @@ func (db *DB) GetAllFileStates(jobID int64) ([]*FileState, error) {
 		if errorMsg.Valid {
 			state.ErrorMessage = &errorMsg.String
 		}
+		if remoteWrite.Valid {
+			state.RemoteWriteTime = &remoteWrite.Int64
+		}
+		if remoteChange.Valid {
+			state.RemoteChangeTime = &remoteChange.Int64
+		}
 
 		states = append(states, &state)
 	}
@@ -184,7 +259,12 @@ func (db *DB) GetAllFileStates(jobID int64) ([]*FileState, error) {
 
 // DeleteFileState deletes a file state (for deleted files)
 func (db *DB) DeleteFileState(jobID int64, localPath string) error {
-	_, err := db.conn.Exec(`
+	conn, err := db.stateConn(jobID)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(`
 		DELETE FROM files_state
 		WHERE job_id = ? AND local_path = ?
 	`, jobID, localPath)
@@ -195,10 +275,26 @@ func (db *DB) DeleteFileState(jobID int64, localPath string) error {
 }
 
 // ClearFilesState removes all file state entries for a job (used for testing)
+// An isolated job's store is recreated instead, which is instant.
 func (db *DB) ClearFilesState(jobID int64) error {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	if db.isolated[jobID] {
+		return db.resetJobStoreLocked(jobID)
+	}
+
 	_, err := db.conn.Exec(`DELETE FROM files_state WHERE job_id = ?`, jobID)
 	if err != nil {
 		return fmt.Errorf("clear files state: %w", err)
 	}
 	return nil
 }
+
+// nullableInt64 converts an optional int64 to a value suitable for SQL parameters.
+func nullableInt64(v *int64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
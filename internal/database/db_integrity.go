@@ -0,0 +1,46 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// --- Transfer Integrity ---
+
+// integrityDayFormat is the format of transfer_integrity.day (local date).
+const integrityDayFormat = "2006-01-02"
+
+// AddTransferIntegrity adds the verified transfers and the mismatches of a
+// sync to the statistics of its server for the day of at.
+func (db *DB) AddTransferIntegrity(serverID int64, at time.Time, verified, mismatches int) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO transfer_integrity (server_id, day, verified, mismatches)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(server_id, day) DO UPDATE SET
+			verified = verified + excluded.verified,
+			mismatches = mismatches + excluded.mismatches
+	`, serverID, at.Format(integrityDayFormat), verified, mismatches)
+	if err != nil {
+		return fmt.Errorf("add transfer integrity: %w", err)
+	}
+	return nil
+}
+
+// GetTransferIntegrity returns the statistics of a server since the day of
+// since (zero = all recorded days).
+func (db *DB) GetTransferIntegrity(serverID int64, since time.Time) (TransferIntegrity, error) {
+	var ti TransferIntegrity
+	day := ""
+	if !since.IsZero() {
+		day = since.Format(integrityDayFormat)
+	}
+	err := db.conn.QueryRow(`
+		SELECT COALESCE(SUM(verified), 0), COALESCE(SUM(mismatches), 0)
+		FROM transfer_integrity
+		WHERE server_id = ? AND day >= ?
+	`, serverID, day).Scan(&ti.Verified, &ti.Mismatches)
+	if err != nil {
+		return ti, fmt.Errorf("query transfer integrity: %w", err)
+	}
+	return ti, nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransferIntegrity(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	server := &SMBServer{Name: "nas", Host: "nas.local", Port: 445, Username: "user"}
+	if err := db.CreateSMBServer(server); err != nil {
+		t.Fatalf("CreateSMBServer failed: %v", err)
+	}
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+	for _, add := range []struct {
+		at                   time.Time
+		verified, mismatches int
+	}{
+		{old, 100, 5},
+		{now, 50, 0},
+		{now, 48, 2}, // Same day: added up
+	} {
+		if err := db.AddTransferIntegrity(server.ID, add.at, add.verified, add.mismatches); err != nil {
+			t.Fatalf("AddTransferIntegrity failed: %v", err)
+		}
+	}
+
+	recent, err := db.GetTransferIntegrity(server.ID, now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetTransferIntegrity failed: %v", err)
+	}
+	if recent.Verified != 98 || recent.Mismatches != 2 {
+		t.Errorf("last 30 days = %+v, want 98 verified, 2 mismatches", recent)
+	}
+	if rate := recent.Rate(); rate != 0.02 {
+		t.Errorf("Rate() = %v, want 0.02", rate)
+	}
+
+	all, err := db.GetTransferIntegrity(server.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("GetTransferIntegrity failed: %v", err)
+	}
+	if all.Verified != 198 || all.Mismatches != 7 {
+		t.Errorf("all time = %+v, want 198 verified, 7 mismatches", all)
+	}
+
+	if other, _ := db.GetTransferIntegrity(server.ID+1, time.Time{}); other.Rate() != 0 {
+		t.Errorf("expected no statistics for another server, got %+v", other)
+	}
+}
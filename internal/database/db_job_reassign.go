@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// --- Reassign Job History ---
+
+// reassignedTables hold per-job history and baselines moved by ReassignJobHistory
+// (files_state is handled separately since it may live in a job store).
+var reassignedTables = []string{"sync_history", "remote_snapshots", "upload_vetoes", "offline_queue", "resume_plans", "resume_plan_actions", "sync_actions", "failed_files", "sync_selection", "conflicts", "remote_usage"}
+
+// replacedTables are reassigned tables keyed by job: the rows of the target
+// job are replaced by those of the source, if it has any.
+var replacedTables = map[string]bool{"sync_selection": true, "remote_usage": true}
+
+// keptTables have a job_id column but are not moved by ReassignJobHistory.
+var keptTables = map[string]string{
+	"files_state":      "moved separately, it may live in a job store",
+	"job_state_stores": "moved with the job store",
+	"exclusions":       "rules of the job, not history",
+	"audit_log":        "append-only, its hash chain covers the job IDs",
+	"local_snapshots":  "scan cache of the job's local folder, rebuilt by its next scan",
+	"scan_checkpoints": "scan cache of the job's local folder, rebuilt by its next scan",
+	"remote_state":     "listing cache, rebuilt by the job's next scan",
+}
+
+// ReassignReport counts the rows moved by ReassignJobHistory, by table.
+type ReassignReport struct {
+	Rows map[string]int64
+}
+
+// ReassignJobHistory moves the sync history and baselines of job fromID to
+// job toID, e.g. when a job was recreated with a new ID. Baselines hold paths
+// relative to the job folders, so they stay valid as long as toID syncs the
+// same content. toID must not have a baseline yet, and no sync of either job
+// may run meanwhile.
+func (db *DB) ReassignJobHistory(fromID, toID int64) (*ReassignReport, error) {
+	if fromID == toID {
+		return nil, fmt.Errorf("source and target job are the same")
+	}
+
+	from, err := db.GetSyncJob(fromID)
+	if err != nil {
+		return nil, fmt.Errorf("get source job: %w", err)
+	}
+	to, err := db.GetSyncJob(toID)
+	if err != nil {
+		return nil, fmt.Errorf("get target job: %w", err)
+	}
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("job %d or %d not found", fromID, toID)
+	}
+
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	// Never merge two baselines: the target must start from nothing
+	targetConn := db.conn
+	if db.isolated[toID] {
+		if targetConn, err = db.jobStoreLocked(toID); err != nil {
+			return nil, err
+		}
+	}
+	var existing int64
+	if err := targetConn.QueryRow(`SELECT COUNT(*) FROM files_state WHERE job_id = ?`, toID).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("count target file states: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("job %d already has a baseline of %d files, reset it first", toID, existing)
+	}
+	if db.isolated[toID] {
+		if _, err := db.conn.Exec(`DELETE FROM job_state_stores WHERE job_id = ?`, toID); err != nil {
+			return nil, fmt.Errorf("unregister target job store: %w", err)
+		}
+		db.dropJobStoreLocked(toID)
+	}
+
+	report := &ReassignReport{Rows: make(map[string]int64)}
+
+	// An isolated source keeps its store, renamed after the target job
+	storeMoved := false
+	if db.isolated[fromID] {
+		moved, err := db.moveJobStoreLocked(fromID, toID)
+		if err != nil {
+			return nil, err
+		}
+		report.Rows["files_state"] = moved
+		storeMoved = true
+	}
+
+	err = db.Transaction(func(tx *sql.Tx) error {
+		tables := append([]string{}, reassignedTables...)
+		if storeMoved {
+			tables = append(tables, "job_state_stores")
+		} else {
+			tables = append(tables, "files_state")
+		}
+		for _, table := range tables {
+			if replacedTables[table] {
+				if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %[1]s WHERE job_id = ?
+					AND EXISTS (SELECT 1 FROM %[1]s WHERE job_id = ?)`, table), toID, fromID); err != nil {
+					return fmt.Errorf("replace %s: %w", table, err)
+				}
+			}
+			result, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET job_id = ? WHERE job_id = ?`, table), toID, fromID)
+			if err != nil {
+				return fmt.Errorf("reassign %s: %w", table, err)
+			}
+			if table == "job_state_stores" {
+				continue
+			}
+			report.Rows[table], _ = result.RowsAffected()
+		}
+
+		_, err := tx.Exec(`UPDATE sync_jobs SET last_run = (SELECT last_run FROM sync_jobs WHERE id = ?)
+			WHERE id = ? AND last_run IS NULL`, fromID, toID)
+		return err
+	})
+	if err != nil {
+		if storeMoved {
+			db.moveJobStoreLocked(toID, fromID)
+		}
+		return nil, err
+	}
+
+	if storeMoved {
+		delete(db.isolated, fromID)
+		db.isolated[toID] = true
+	}
+	return report, nil
+}
+
+// moveJobStoreLocked hands the store of job fromID over to job toID: its rows
+// are reassigned and its file renamed. Returns the number of rows moved.
+// storesMu must be held.
+func (db *DB) moveJobStoreLocked(fromID, toID int64) (int64, error) {
+	store, err := db.jobStoreLocked(fromID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := store.Exec(`UPDATE files_state SET job_id = ? WHERE job_id = ?`, toID, fromID)
+	if err != nil {
+		return 0, fmt.Errorf("reassign job store rows: %w", err)
+	}
+	moved, _ := result.RowsAffected()
+
+	store.Close()
+	delete(db.stores, fromID)
+
+	fromPath, toPath := JobStorePath(db.path, fromID), JobStorePath(db.path, toID)
+	removeDatabaseFiles(toPath)
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if !fileExists(fromPath + suffix) {
+			continue
+		}
+		if err := os.Rename(fromPath+suffix, toPath+suffix); err != nil {
+			return 0, fmt.Errorf("rename job store: %w", err)
+		}
+	}
+	return moved, nil
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReassignJobHistory(t *testing.T) {
+	for _, isolated := range []bool{false, true} {
+		name := "shared"
+		if isolated {
+			name = "isolated"
+		}
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{
+				Path:             filepath.Join(t.TempDir(), "test.db"),
+				EncryptionKey:    "test-key",
+				CreateIfNotExist: true,
+			}
+			db, err := Open(cfg)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer db.Close()
+
+			newJob := func(localPath string) *SyncJob {
+				job := &SyncJob{
+					Name:               "docs",
+					LocalPath:          localPath,
+					RemotePath:         `\\nas\share\docs`,
+					ServerCredentialID: "nas_user",
+					SyncMode:           "mirror",
+					TriggerMode:        "manual",
+					ConflictResolution: "recent",
+					Enabled:            true,
+				}
+				if err := db.CreateSyncJob(job); err != nil {
+					t.Fatalf("CreateSyncJob failed: %v", err)
+				}
+				return job
+			}
+			oldJob, newJobRecreated := newJob(`C:\docs`), newJob(`D:\docs`)
+
+			states := []*FileState{
+				{JobID: oldJob.ID, LocalPath: "a.txt", RemotePath: "a.txt", Size: 1, MTime: 1, SyncStatus: "idle"},
+				{JobID: oldJob.ID, LocalPath: "b.txt", RemotePath: "b.txt", Size: 2, MTime: 2, SyncStatus: "idle"},
+			}
+			if err := db.BulkUpdateFileStates(states); err != nil {
+				t.Fatalf("BulkUpdateFileStates failed: %v", err)
+			}
+			if isolated {
+				if err := db.IsolateJobState(oldJob.ID); err != nil {
+					t.Fatalf("IsolateJobState failed: %v", err)
+				}
+			}
+			if err := db.InsertSyncHistory(&SyncHistory{JobID: oldJob.ID, Timestamp: time.Now(), FilesSynced: 2, Status: "success"}); err != nil {
+				t.Fatalf("InsertSyncHistory failed: %v", err)
+			}
+
+			// Tables keyed by job: the rows of the new job are replaced
+			inserts := []struct {
+				jobID int64
+				stmt  string
+			}{
+				{oldJob.ID, `INSERT INTO remote_usage (job_id, scanned_at, total_files) VALUES (?, 1, 10)`},
+				{newJobRecreated.ID, `INSERT INTO remote_usage (job_id, scanned_at, total_files) VALUES (?, 2, 0)`},
+				{oldJob.ID, `INSERT INTO sync_selection (job_id, path, mode) VALUES (?, 'Photos', 'exclude')`},
+			}
+			for _, insert := range inserts {
+				if _, err := db.conn.Exec(insert.stmt, insert.jobID); err != nil {
+					t.Fatalf("insert failed: %v", err)
+				}
+			}
+
+			report, err := db.ReassignJobHistory(oldJob.ID, newJobRecreated.ID)
+			if err != nil {
+				t.Fatalf("ReassignJobHistory failed: %v", err)
+			}
+			if report.Rows["files_state"] != 2 || report.Rows["sync_history"] != 1 {
+				t.Errorf("unexpected report: %+v", report.Rows)
+			}
+
+			moved, err := db.GetAllFileStates(newJobRecreated.ID)
+			if err != nil || len(moved) != 2 {
+				t.Fatalf("expected 2 states on the new job, got %d (err=%v)", len(moved), err)
+			}
+			if left, _ := db.GetAllFileStates(oldJob.ID); len(left) != 0 {
+				t.Errorf("expected no state left on the old job, got %d", len(left))
+			}
+			if db.IsJobStateIsolated(newJobRecreated.ID) != isolated {
+				t.Errorf("expected new job isolated=%v", isolated)
+			}
+			if isolated {
+				if _, err := os.Stat(JobStorePath(cfg.Path, oldJob.ID)); !os.IsNotExist(err) {
+					t.Error("expected old job store renamed")
+				}
+			}
+
+			// Deleting the old job no longer loses anything
+			if err := db.DeleteSyncJob(oldJob.ID); err != nil {
+				t.Fatalf("DeleteSyncJob failed: %v", err)
+			}
+			if stats, err := db.GetJobStatistics(newJobRecreated.ID); err != nil || stats.TotalFiles != 2 {
+				t.Errorf("expected statistics of the new job, got %+v (err=%v)", stats, err)
+			}
+			var history int
+			db.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE job_id = ?`, newJobRecreated.ID).Scan(&history)
+			if history != 1 {
+				t.Errorf("expected history moved to the new job, got %d rows", history)
+			}
+			var usageFiles, selected int
+			db.conn.QueryRow(`SELECT total_files FROM remote_usage WHERE job_id = ?`, newJobRecreated.ID).Scan(&usageFiles)
+			db.conn.QueryRow(`SELECT COUNT(*) FROM sync_selection WHERE job_id = ?`, newJobRecreated.ID).Scan(&selected)
+			if usageFiles != 10 || selected != 1 {
+				t.Errorf("expected usage and selection moved to the new job, got %d files and %d paths", usageFiles, selected)
+			}
+		})
+	}
+}
+
+func TestReassignJobHistory_TargetHasBaseline(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	var ids []int64
+	for _, localPath := range []string{`C:\a`, `C:\b`} {
+		job := &SyncJob{
+			Name:               "job",
+			LocalPath:          localPath,
+			RemotePath:         `\\nas\share`,
+			ServerCredentialID: "nas_user",
+			SyncMode:           "mirror",
+			TriggerMode:        "manual",
+			ConflictResolution: "recent",
+			Enabled:            true,
+		}
+		if err := db.CreateSyncJob(job); err != nil {
+			t.Fatalf("CreateSyncJob failed: %v", err)
+		}
+		if err := db.UpsertFileState(&FileState{JobID: job.ID, LocalPath: "a.txt", RemotePath: "a.txt", SyncStatus: "idle"}); err != nil {
+			t.Fatalf("UpsertFileState failed: %v", err)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	if _, err := db.ReassignJobHistory(ids[0], ids[1]); err == nil {
+		t.Error("expected error when the target job already has a baseline")
+	}
+	if _, err := db.ReassignJobHistory(ids[0], ids[0]); err == nil {
+		t.Error("expected error when reassigning a job to itself")
+	}
+	if _, err := db.ReassignJobHistory(ids[0], 999); err == nil {
+		t.Error("expected error for a missing job")
+	}
+	if states, _ := db.GetAllFileStates(ids[0]); len(states) != 1 {
+		t.Errorf("failed reassign should not move states, got %d", len(states))
+	}
+}
+
+// Every table with a job_id column must be moved or deliberately kept.
+func TestReassignJobHistory_CoversJobTables(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.conn.Query(`SELECT m.name FROM sqlite_master m, pragma_table_info(m.name) c
+		WHERE m.type = 'table' AND c.name = 'job_id'`)
+	if err != nil {
+		t.Fatalf("list tables failed: %v", err)
+	}
+	defer rows.Close()
+
+	reassigned := make(map[string]bool)
+	for _, table := range reassignedTables {
+		reassigned[table] = true
+	}
+	found := 0
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatal(err)
+		}
+		found++
+		if !reassigned[table] && keptTables[table] == "" {
+			t.Errorf("table %s has a job_id column: add it to reassignedTables or keptTables", table)
+		}
+	}
+	if found == 0 {
+		t.Fatal("no table with a job_id column found")
+	}
+}
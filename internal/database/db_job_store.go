@@ -0,0 +1,323 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Per-Job State Stores ---
+//
+// A job can keep its files_state in its own encrypted database file (a job
+// store) instead of the shared table: queries of small jobs are not slowed
+// down by a huge one, and resetting or deleting the job drops a file
+// instead of deleting millions of rows.
+
+// jobStoreSchema creates files_state in a job store. Migrations that alter
+// files_state must alter it here too.
+const jobStoreSchema = `
+CREATE TABLE IF NOT EXISTS files_state (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_id INTEGER NOT NULL,
+    local_path TEXT NOT NULL,
+    remote_path TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    mtime INTEGER NOT NULL,
+    hash TEXT,
+    last_sync INTEGER,
+    sync_status TEXT NOT NULL CHECK(sync_status IN ('idle', 'syncing', 'error', 'queued')),
+    error_message TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    remote_write_time INTEGER,
+    remote_change_time INTEGER,
+    attributes INTEGER NOT NULL DEFAULT 0,
+    remote_owner TEXT NOT NULL DEFAULT '',
+    verified_at INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(job_id, local_path)
+);
+CREATE INDEX IF NOT EXISTS idx_files_state_status ON files_state(sync_status);
+CREATE INDEX IF NOT EXISTS idx_files_state_hash ON files_state(hash);
+`
+
+// fileStateColumns lists the files_state columns moved between stores.
+const fileStateColumns = `job_id, local_path, remote_path, size, mtime, hash, last_sync, sync_status,
+	error_message, created_at, updated_at, remote_write_time, remote_change_time, attributes, remote_owner, verified_at`
+
+// jobStoreUpgrades adds the files_state columns added since a store could
+// have been created (column -> definition).
+var jobStoreUpgrades = []struct{ column, definition string }{
+	{"remote_owner", `TEXT NOT NULL DEFAULT ''`},
+	{"verified_at", `INTEGER NOT NULL DEFAULT 0`},
+}
+
+// JobStorePath returns the path of the state store of a job, next to the database at dbPath.
+func JobStorePath(dbPath string, jobID int64) string {
+	return filepath.Join(filepath.Dir(dbPath), "jobs", fmt.Sprintf("job-%d.db", jobID))
+}
+
+// IsJobStateIsolated reports whether the job keeps its files_state in its own store.
+func (db *DB) IsJobStateIsolated(jobID int64) bool {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+	return db.isolated[jobID]
+}
+
+// IsolateJobState moves the files_state of a job into its own store.
+func (db *DB) IsolateJobState(jobID int64) error {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	if db.isolated[jobID] {
+		return nil
+	}
+
+	// A leftover store (e.g. from before a restore) is stale
+	path := JobStorePath(db.path, jobID)
+	removeDatabaseFiles(path)
+
+	store, err := db.openJobStore(jobID)
+	if err != nil {
+		return err
+	}
+
+	if err := copyFileStates(db.conn, store, jobID); err != nil {
+		store.Close()
+		removeDatabaseFiles(path)
+		return fmt.Errorf("failed to copy job state: %w", err)
+	}
+
+	err = db.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO job_state_stores (job_id) VALUES (?)`, jobID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM files_state WHERE job_id = ?`, jobID)
+		return err
+	})
+	if err != nil {
+		store.Close()
+		removeDatabaseFiles(path)
+		return fmt.Errorf("failed to register job store: %w", err)
+	}
+
+	db.isolated[jobID] = true
+	db.stores[jobID] = store
+	return nil
+}
+
+// ShareJobState moves the files_state of an isolated job back into the shared table.
+func (db *DB) ShareJobState(jobID int64) error {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	if !db.isolated[jobID] {
+		return nil
+	}
+
+	store, err := db.jobStoreLocked(jobID)
+	if err != nil {
+		return err
+	}
+
+	if err := copyFileStates(store, db.conn, jobID); err != nil {
+		return fmt.Errorf("failed to copy job state: %w", err)
+	}
+	if _, err := db.conn.Exec(`DELETE FROM job_state_stores WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("failed to unregister job store: %w", err)
+	}
+
+	db.dropJobStoreLocked(jobID)
+	return nil
+}
+
+// stateConn returns the connection holding the files_state of a job.
+func (db *DB) stateConn(jobID int64) (*sql.DB, error) {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	if !db.isolated[jobID] {
+		return db.conn, nil
+	}
+	return db.jobStoreLocked(jobID)
+}
+
+// jobStoreLocked returns the open store of an isolated job, opening it if needed.
+// storesMu must be held.
+func (db *DB) jobStoreLocked(jobID int64) (*sql.DB, error) {
+	if store, ok := db.stores[jobID]; ok {
+		return store, nil
+	}
+	store, err := db.openJobStore(jobID)
+	if err != nil {
+		return nil, err
+	}
+	db.stores[jobID] = store
+	return store, nil
+}
+
+// openJobStore opens (or creates) the store file of a job.
+func (db *DB) openJobStore(jobID int64) (*sql.DB, error) {
+	path := JobStorePath(db.path, jobID)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+
+	connStr := fmt.Sprintf("file:%s?_pragma_key=%s&_pragma_cipher_page_size=4096", path, db.key)
+	store, err := sql.Open("sqlite3", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store: %w", err)
+	}
+	if _, err := store.Exec(jobStoreSchema); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to initialize job store %s: %w", path, err)
+	}
+	if err := upgradeJobStore(store); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to upgrade job store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// upgradeJobStore adds the columns missing from a store created by an older
+// version.
+func upgradeJobStore(store *sql.DB) error {
+	rows, err := store.Query(`PRAGMA table_info(files_state)`)
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, upgrade := range jobStoreUpgrades {
+		if columns[upgrade.column] {
+			continue
+		}
+		if _, err := store.Exec(`ALTER TABLE files_state ADD COLUMN ` + upgrade.column + ` ` + upgrade.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetJobStoreLocked empties the store of an isolated job by recreating its file.
+// storesMu must be held.
+func (db *DB) resetJobStoreLocked(jobID int64) error {
+	if store, ok := db.stores[jobID]; ok {
+		store.Close()
+		delete(db.stores, jobID)
+	}
+	removeDatabaseFiles(JobStorePath(db.path, jobID))
+
+	store, err := db.openJobStore(jobID)
+	if err != nil {
+		return err
+	}
+	db.stores[jobID] = store
+	return nil
+}
+
+// dropJobStoreLocked closes and deletes the store of a job. storesMu must be held.
+func (db *DB) dropJobStoreLocked(jobID int64) {
+	if store, ok := db.stores[jobID]; ok {
+		store.Close()
+		delete(db.stores, jobID)
+	}
+	delete(db.isolated, jobID)
+	removeDatabaseFiles(JobStorePath(db.path, jobID))
+}
+
+// loadJobStores reads which jobs have their own store.
+func (db *DB) loadJobStores() error {
+	rows, err := db.conn.Query(`SELECT job_id FROM job_state_stores`)
+	if err != nil {
+		return fmt.Errorf("query job stores: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var jobID int64
+		if err := rows.Scan(&jobID); err != nil {
+			return fmt.Errorf("scan job store: %w", err)
+		}
+		db.isolated[jobID] = true
+	}
+	return rows.Err()
+}
+
+// closeJobStores closes all open job stores.
+func (db *DB) closeJobStores() {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	for jobID, store := range db.stores {
+		store.Close()
+		delete(db.stores, jobID)
+	}
+}
+
+// isolatedJobIDs returns the jobs that have their own store.
+func (db *DB) isolatedJobIDs() []int64 {
+	db.storesMu.Lock()
+	defer db.storesMu.Unlock()
+
+	ids := make([]int64, 0, len(db.isolated))
+	for jobID := range db.isolated {
+		ids = append(ids, jobID)
+	}
+	return ids
+}
+
+// copyFileStates copies the files_state rows of a job from src to dst.
+func copyFileStates(src, dst *sql.DB, jobID int64) error {
+	rows, err := src.Query(`SELECT `+fileStateColumns+` FROM files_state WHERE job_id = ?`, jobID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", strings.Count(fileStateColumns, ",")+1), ", ")
+	return transaction(dst, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT OR REPLACE INTO files_state (` + fileStateColumns + `) VALUES (` + placeholders + `)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		values := make([]interface{}, strings.Count(fileStateColumns, ",")+1)
+		pointers := make([]interface{}, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(pointers...); err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(values...); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// removeDatabaseFiles deletes a database file and its journal files.
+func removeDatabaseFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		os.Remove(path + suffix)
+	}
+}
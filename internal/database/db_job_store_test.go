@@ -0,0 +1,309 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJobStore_IsolateAndShare(t *testing.T) {
+	cfg := Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	newJob := func(name string) *SyncJob {
+		job := &SyncJob{
+			Name:               name,
+			LocalPath:          `C:\` + name,
+			RemotePath:         `\\nas\share\` + name,
+			ServerCredentialID: "nas_user",
+			SyncMode:           "mirror",
+			TriggerMode:        "manual",
+			ConflictResolution: "recent",
+			Enabled:            true,
+		}
+		if err := db.CreateSyncJob(job); err != nil {
+			t.Fatalf("CreateSyncJob failed: %v", err)
+		}
+		return job
+	}
+	big, small := newJob("big"), newJob("small")
+
+	state := func(jobID int64, path string) *FileState {
+		return &FileState{JobID: jobID, LocalPath: path, RemotePath: path, Size: 1, MTime: 1, SyncStatus: "idle"}
+	}
+	if err := db.BulkUpdateFileStates([]*FileState{state(big.ID, "a.txt"), state(big.ID, "b.txt"), state(small.ID, "c.txt")}); err != nil {
+		t.Fatalf("BulkUpdateFileStates failed: %v", err)
+	}
+
+	if err := db.IsolateJobState(big.ID); err != nil {
+		t.Fatalf("IsolateJobState failed: %v", err)
+	}
+	if _, err := os.Stat(JobStorePath(cfg.Path, big.ID)); err != nil {
+		t.Fatalf("expected job store file: %v", err)
+	}
+
+	// The shared table only holds the small job
+	var shared int
+	db.conn.QueryRow(`SELECT COUNT(*) FROM files_state`).Scan(&shared)
+	if shared != 1 {
+		t.Errorf("expected 1 row left in shared table, got %d", shared)
+	}
+
+	// Reads and writes go to the store, also after reopening
+	if err := db.UpsertFileState(state(big.ID, "d.txt")); err != nil {
+		t.Fatalf("UpsertFileState failed: %v", err)
+	}
+	db.Close()
+	db, err = Open(cfg)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer db.Close()
+
+	states, err := db.GetAllFileStates(big.ID)
+	if err != nil || len(states) != 3 {
+		t.Fatalf("expected 3 states in job store, got %d (err=%v)", len(states), err)
+	}
+	if _, err := db.GetFileState(big.ID, "a.txt"); err != nil {
+		t.Errorf("GetFileState failed: %v", err)
+	}
+	if stats, err := db.GetJobStatistics(big.ID); err != nil || stats.TotalFiles != 3 {
+		t.Errorf("expected statistics from job store, got %+v (err=%v)", stats, err)
+	}
+
+	// Resetting an isolated job recreates its store
+	if err := db.ClearFilesState(big.ID); err != nil {
+		t.Fatalf("ClearFilesState failed: %v", err)
+	}
+	if states, _ := db.GetAllFileStates(big.ID); len(states) != 0 {
+		t.Errorf("expected empty store after reset, got %d states", len(states))
+	}
+
+	if err := db.UpsertFileState(state(big.ID, "e.txt")); err != nil {
+		t.Fatalf("UpsertFileState failed: %v", err)
+	}
+	if err := db.ShareJobState(big.ID); err != nil {
+		t.Fatalf("ShareJobState failed: %v", err)
+	}
+	if db.IsJobStateIsolated(big.ID) {
+		t.Error("job should use the shared table again")
+	}
+	if _, err := os.Stat(JobStorePath(cfg.Path, big.ID)); !os.IsNotExist(err) {
+		t.Error("expected job store file removed")
+	}
+	if _, err := db.GetFileState(big.ID, "e.txt"); err != nil {
+		t.Errorf("expected state moved back to shared table: %v", err)
+	}
+}
+
+func TestJobStore_DeleteJobAndBackup(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		Path:             filepath.Join(dir, "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+	if err := db.IsolateJobState(job.ID); err != nil {
+		t.Fatalf("IsolateJobState failed: %v", err)
+	}
+	if err := db.UpsertFileState(&FileState{JobID: job.ID, LocalPath: "a.txt", RemotePath: "a.txt", SyncStatus: "idle"}); err != nil {
+		t.Fatalf("UpsertFileState failed: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := db.Backup(backupPath); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if _, err := os.Stat(jobStoreBackupPath(backupPath, job.ID)); err != nil {
+		t.Errorf("expected job store in backup: %v", err)
+	}
+
+	if err := db.DeleteSyncJob(job.ID); err != nil {
+		t.Fatalf("DeleteSyncJob failed: %v", err)
+	}
+	if _, err := os.Stat(JobStorePath(cfg.Path, job.ID)); !os.IsNotExist(err) {
+		t.Error("expected job store deleted with the job")
+	}
+	db.Close()
+
+	if err := Restore(cfg, backupPath); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	db, err = Open(cfg)
+	if err != nil {
+		t.Fatalf("Open after restore failed: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetFileState(job.ID, "a.txt"); err != nil {
+		t.Errorf("expected job store restored: %v", err)
+	}
+}
+
+func TestJobStore_CleanupCorruptedEntries(t *testing.T) {
+	cfg := Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "big",
+		LocalPath:          `C:\big`,
+		RemotePath:         `\\nas\share\big`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+	if err := db.IsolateJobState(job.ID); err != nil {
+		t.Fatalf("IsolateJobState failed: %v", err)
+	}
+	states := []*FileState{
+		{JobID: job.ID, LocalPath: "docs/a.txt", RemotePath: "docs/a.txt", Size: 1, MTime: 1, SyncStatus: "idle"},
+		{JobID: job.ID, LocalPath: `D:\big\b.txt`, RemotePath: "b.txt", Size: 1, MTime: 1, SyncStatus: "idle"},
+	}
+	if err := db.BulkUpdateFileStates(states); err != nil {
+		t.Fatalf("BulkUpdateFileStates failed: %v", err)
+	}
+
+	if err := db.cleanupCorruptedCacheEntries(); err != nil {
+		t.Fatalf("cleanupCorruptedCacheEntries failed: %v", err)
+	}
+	left, err := db.GetAllFileStates(job.ID)
+	if err != nil {
+		t.Fatalf("GetAllFileStates failed: %v", err)
+	}
+	if len(left) != 1 || left[0].LocalPath != "docs/a.txt" {
+		t.Errorf("expected only docs/a.txt left in the job store, got %d entries", len(left))
+	}
+}
+
+func TestJobStore_UpgradesOldStores(t *testing.T) {
+	store, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "job-1.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+
+	// A store created before remote_owner and verified_at
+	oldSchema := strings.Replace(jobStoreSchema, "    remote_owner TEXT NOT NULL DEFAULT '',\n", "", 1)
+	oldSchema = strings.Replace(oldSchema, "    verified_at INTEGER NOT NULL DEFAULT 0,\n", "", 1)
+	if _, err := store.Exec(oldSchema); err != nil {
+		t.Fatalf("create old store: %v", err)
+	}
+	for i := 0; i < 2; i++ { // Upgrading twice is harmless
+		if err := upgradeJobStore(store); err != nil {
+			t.Fatalf("upgradeJobStore failed: %v", err)
+		}
+	}
+	if _, err := store.Exec(`INSERT INTO files_state (` + fileStateColumns + `)
+		VALUES (1, 'a.txt', 'a.txt', 1, 1, '', 1, 'idle', '', 1, 1, NULL, NULL, 0, 'CORP\bob', 1)`); err != nil {
+		t.Errorf("expected the upgraded store to take every column: %v", err)
+	}
+}
+
+func TestFileState_RemoteOwner(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	state := &FileState{JobID: 1, LocalPath: "a.txt", RemotePath: "a.txt", SyncStatus: "idle", RemoteOwner: `CORP\bob`}
+	if err := db.UpsertFileState(state); err != nil {
+		t.Fatalf("UpsertFileState failed: %v", err)
+	}
+	// A sync not recording owners keeps the last one seen
+	state.RemoteOwner = ""
+	if err := db.BulkUpdateFileStates([]*FileState{state}); err != nil {
+		t.Fatalf("BulkUpdateFileStates failed: %v", err)
+	}
+
+	got, err := db.GetFileState(1, "a.txt")
+	if err != nil {
+		t.Fatalf("GetFileState failed: %v", err)
+	}
+	if got.RemoteOwner != `CORP\bob` {
+		t.Errorf("RemoteOwner = %q, want CORP\\bob", got.RemoteOwner)
+	}
+
+	// Files never synced are told apart from read errors
+	if _, err := db.GetFileState(1, "b.txt"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetFileState of an unknown file = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestFileState_VerifiedAt(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	state := &FileState{JobID: 1, LocalPath: "a.txt", RemotePath: "a.txt", SyncStatus: "idle", VerifiedAt: 1700000000}
+	if err := db.BulkUpdateFileStates([]*FileState{state}); err != nil {
+		t.Fatalf("BulkUpdateFileStates failed: %v", err)
+	}
+	got, err := db.GetFileState(1, "a.txt")
+	if err != nil {
+		t.Fatalf("GetFileState failed: %v", err)
+	}
+	if got.VerifiedAt != 1700000000 {
+		t.Errorf("VerifiedAt = %d, want 1700000000", got.VerifiedAt)
+	}
+
+	// A transfer without verification leaves the new content unverified
+	state.VerifiedAt = 0
+	if err := db.UpsertFileState(state); err != nil {
+		t.Fatalf("UpsertFileState failed: %v", err)
+	}
+	got, _ = db.GetFileState(1, "a.txt")
+	if got.VerifiedAt != 0 {
+		t.Errorf("VerifiedAt = %d after an unverified transfer, want 0", got.VerifiedAt)
+	}
+}
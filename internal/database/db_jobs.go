@@ -2,8 +2,11 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
 )
 
 // --- Sync Jobs CRUD ---
@@ -12,21 +15,21 @@ import (
 func (db *DB) GetSyncJob(jobID int64) (*SyncJob, error) {
 	var job SyncJob
 	var lastRun, nextRun sql.NullInt64
-	var triggerParams, conflictRes, networkCond sql.NullString
+	var triggerParams, conflictRes, networkCond, endpoint sql.NullString
 	var createdAt, updatedAt int64
 
 	err := db.conn.QueryRow(`
 		SELECT id, name, local_path, remote_path, server_credential_id,
 			   sync_mode, trigger_mode, trigger_params, conflict_resolution,
 			   network_conditions, enabled, last_run, next_run,
-			   created_at, updated_at
+			   created_at, updated_at, remote_endpoint
 		FROM sync_jobs
 		WHERE id = ?
 	`, jobID).Scan(
 		&job.ID, &job.Name, &job.LocalPath, &job.RemotePath, &job.ServerCredentialID,
 		&job.SyncMode, &job.TriggerMode, &triggerParams, &conflictRes,
 		&networkCond, &job.Enabled, &lastRun, &nextRun,
-		&createdAt, &updatedAt,
+		&createdAt, &updatedAt, &endpoint,
 	)
 
 	if err != nil {
@@ -55,6 +58,7 @@ func (db *DB) GetSyncJob(jobID int64) (*SyncJob, error) {
 	}
 	job.CreatedAt = time.Unix(createdAt, 0)
 	job.UpdatedAt = time.Unix(updatedAt, 0)
+	job.Endpoint = scanEndpoint(&job, endpoint)
 
 	return &job, nil
 }
@@ -65,7 +69,7 @@ func (db *DB) GetAllSyncJobs() ([]*SyncJob, error) {
 		SELECT id, name, local_path, remote_path, server_credential_id,
 			   sync_mode, trigger_mode, trigger_params, conflict_resolution,
 			   network_conditions, enabled, last_run, next_run,
-			   created_at, updated_at
+			   created_at, updated_at, remote_endpoint
 		FROM sync_jobs
 		ORDER BY name ASC
 	`)
@@ -78,14 +82,14 @@ func (db *DB) GetAllSyncJobs() ([]*SyncJob, error) {
 	for rows.Next() {
 		var job SyncJob
 		var lastRun, nextRun sql.NullInt64
-		var triggerParams, conflictRes, networkCond sql.NullString
+		var triggerParams, conflictRes, networkCond, endpoint sql.NullString
 		var createdAt, updatedAt int64
 
 		err := rows.Scan(
 			&job.ID, &job.Name, &job.LocalPath, &job.RemotePath, &job.ServerCredentialID,
 			&job.SyncMode, &job.TriggerMode, &triggerParams, &conflictRes,
 			&networkCond, &job.Enabled, &lastRun, &nextRun,
-			&createdAt, &updatedAt,
+			&createdAt, &updatedAt, &endpoint,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan sync job: %w", err)
@@ -110,6 +114,7 @@ func (db *DB) GetAllSyncJobs() ([]*SyncJob, error) {
 		}
 		job.CreatedAt = time.Unix(createdAt, 0)
 		job.UpdatedAt = time.Unix(updatedAt, 0)
+		job.Endpoint = scanEndpoint(&job, endpoint)
 
 		jobs = append(jobs, &job)
 	}
@@ -132,19 +137,24 @@ func (db *DB) CreateSyncJob(job *SyncJob) error {
 	if job.NextRun != nil {
 		nextRunUnix = sql.NullInt64{Int64: job.NextRun.Unix(), Valid: true}
 	}
+	job.Endpoint = job.RemoteEndpoint()
+	endpoint, err := json.Marshal(job.Endpoint)
+	if err != nil {
+		return fmt.Errorf("encode remote endpoint: %w", err)
+	}
 
 	result, err := db.conn.Exec(`
 		INSERT INTO sync_jobs (
 			name, local_path, remote_path, server_credential_id,
 			sync_mode, trigger_mode, trigger_params, conflict_resolution,
 			network_conditions, enabled, last_run, next_run,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			created_at, updated_at, remote_endpoint
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		job.Name, job.LocalPath, job.RemotePath, job.ServerCredentialID,
 		job.SyncMode, job.TriggerMode, job.TriggerParams, job.ConflictResolution,
 		job.NetworkConditions, job.Enabled, lastRunUnix, nextRunUnix,
-		now, now,
+		now, now, string(endpoint),
 	)
 	if err != nil {
 		return fmt.Errorf("insert sync job: %w", err)
@@ -173,19 +183,24 @@ func (db *DB) UpdateSyncJob(job *SyncJob) error {
 	if job.NextRun != nil {
 		nextRunUnix = sql.NullInt64{Int64: job.NextRun.Unix(), Valid: true}
 	}
+	job.Endpoint = job.RemoteEndpoint()
+	endpoint, err := json.Marshal(job.Endpoint)
+	if err != nil {
+		return fmt.Errorf("encode remote endpoint: %w", err)
+	}
 
 	result, err := db.conn.Exec(`
 		UPDATE sync_jobs SET
 			name = ?, local_path = ?, remote_path = ?, server_credential_id = ?,
 			sync_mode = ?, trigger_mode = ?, trigger_params = ?, conflict_resolution = ?,
 			network_conditions = ?, enabled = ?, last_run = ?, next_run = ?,
-			updated_at = ?
+			updated_at = ?, remote_endpoint = ?
 		WHERE id = ?
 	`,
 		job.Name, job.LocalPath, job.RemotePath, job.ServerCredentialID,
 		job.SyncMode, job.TriggerMode, job.TriggerParams, job.ConflictResolution,
 		job.NetworkConditions, job.Enabled, lastRunUnix, nextRunUnix,
-		now, job.ID,
+		now, string(endpoint), job.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update sync job: %w", err)
@@ -218,9 +233,32 @@ func (db *DB) DeleteSyncJob(jobID int64) error {
 		return fmt.Errorf("sync job not found: %d", jobID)
 	}
 
+	db.storesMu.Lock()
+	if db.isolated[jobID] {
+		db.conn.Exec(`DELETE FROM job_state_stores WHERE job_id = ?`, jobID)
+		db.dropJobStoreLocked(jobID)
+	}
+	db.storesMu.Unlock()
+
+	// Foreign keys are not enforced: drop the plan of an interrupted sync,
+	// the action log, the selective sync rules and the remote listing cache
+	db.DeleteResumePlan(jobID)
+	db.conn.Exec(`DELETE FROM sync_actions WHERE job_id = ?`, jobID)
+	db.conn.Exec(`DELETE FROM sync_selection WHERE job_id = ?`, jobID)
+	db.conn.Exec(`DELETE FROM remote_state WHERE job_id = ?`, jobID)
+
 	return nil
 }
 
+// scanEndpoint decodes the stored endpoint of a job. Rows whose endpoint is
+// missing or unreadable get the one of their remote path.
+func scanEndpoint(job *SyncJob, stored sql.NullString) smbpath.RemoteEndpoint {
+	if stored.Valid {
+		json.Unmarshal([]byte(stored.String), &job.Endpoint)
+	}
+	return job.RemoteEndpoint()
+}
+
 // UpdateJobStatus updates the status of a sync job
 // Note: Status is not a field in SyncJob model, we'll update it via sync_history
 // For now, we'll use this to mark jobs as syncing/idle/error via a metadata approach
@@ -247,34 +285,83 @@ func (db *DB) UpdateJobLastRun(jobID int64, lastRun time.Time) error {
 	return nil
 }
 
-// --- Sync History ---
+// UpdateJobNextRun updates the next planned run of a sync job (zero = none)
+func (db *DB) UpdateJobNextRun(jobID int64, nextRun time.Time) error {
+	var nextRunUnix sql.NullInt64
+	if !nextRun.IsZero() {
+		nextRunUnix = sql.NullInt64{Int64: nextRun.Unix(), Valid: true}
+	}
 
-// InsertSyncHistory inserts a sync history record
-func (db *DB) InsertSyncHistory(history *SyncHistory) error {
 	_, err := db.conn.Exec(`
-		INSERT INTO sync_history (
-			job_id, timestamp, files_synced, files_failed,
-			bytes_transferred, duration, status, error_summary, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`,
-		history.JobID,
-		history.Timestamp.Unix(),
-		history.FilesSynced,
-		history.FilesFailed,
-		history.BytesTransferred,
-		history.Duration,
-		history.Status,
-		history.ErrorSummary,
-		time.Now().Unix(),
-	)
+		UPDATE sync_jobs
+		SET next_run = ?, updated_at = ?
+		WHERE id = ?
+	`, nextRunUnix, time.Now().Unix(), jobID)
 
 	if err != nil {
-		return fmt.Errorf("insert sync history: %w", err)
+		return fmt.Errorf("update job next run: %w", err)
 	}
 
 	return nil
 }
 
+// --- Sync History ---
+
+// SyncHistoryRetention is how long runs are kept in sync_history, by status:
+// runs with errors are kept longer, to investigate them. The most recent
+// failed run of each job is kept whatever its age.
+var SyncHistoryRetention = map[string]time.Duration{
+	"success": 90 * 24 * time.Hour,
+	"partial": 180 * 24 * time.Hour,
+	"failed":  180 * 24 * time.Hour,
+}
+
+// InsertSyncHistory inserts a sync history record and drops the records
+// older than their SyncHistoryRetention.
+func (db *DB) InsertSyncHistory(history *SyncHistory) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO sync_history (
+				job_id, timestamp, files_synced, files_failed,
+				bytes_transferred, duration, status, error_summary, run_id, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			history.JobID,
+			history.Timestamp.Unix(),
+			history.FilesSynced,
+			history.FilesFailed,
+			history.BytesTransferred,
+			history.Duration,
+			history.Status,
+			history.ErrorSummary,
+			history.RunID,
+			time.Now().Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert sync history: %w", err)
+		}
+
+		return pruneSyncHistory(tx)
+	})
+}
+
+// pruneSyncHistory drops the runs older than the retention of their status,
+// except the most recent failed run of each job and its error summary, the
+// evidence of intermittent failures.
+func pruneSyncHistory(tx *sql.Tx) error {
+	for status, retention := range SyncHistoryRetention {
+		cutoff := time.Now().Add(-retention).Unix()
+		if _, err := tx.Exec(`
+			DELETE FROM sync_history
+			WHERE status = ? AND timestamp < ?
+			AND id NOT IN (SELECT MAX(id) FROM sync_history WHERE status = 'failed' GROUP BY job_id)
+		`, status, cutoff); err != nil {
+			return fmt.Errorf("prune sync history: %w", err)
+		}
+	}
+	return nil
+}
+
 // --- Job Statistics ---
 
 // GetJobStatistics retrieves statistics for a sync job
@@ -293,8 +380,13 @@ func (db *DB) GetJobStatistics(jobID int64) (*JobStatistics, error) {
 		Enabled: job.Enabled,
 	}
 
+	conn, err := db.stateConn(jobID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Count total files
-	err = db.conn.QueryRow(`
+	err = conn.QueryRow(`
 		SELECT COUNT(*), COALESCE(SUM(size), 0)
 		FROM files_state
 		WHERE job_id = ?
@@ -305,7 +397,7 @@ func (db *DB) GetJobStatistics(jobID int64) (*JobStatistics, error) {
 	}
 
 	// Count files with errors
-	err = db.conn.QueryRow(`
+	err = conn.QueryRow(`
 		SELECT COUNT(*)
 		FROM files_state
 		WHERE job_id = ? AND sync_status = 'error'
@@ -317,7 +409,7 @@ func (db *DB) GetJobStatistics(jobID int64) (*JobStatistics, error) {
 
 	// Get last sync time
 	var lastSyncUnix sql.NullInt64
-	err = db.conn.QueryRow(`
+	err = conn.QueryRow(`
 		SELECT MAX(last_sync)
 		FROM files_state
 		WHERE job_id = ?
@@ -332,16 +424,10 @@ func (db *DB) GetJobStatistics(jobID int64) (*JobStatistics, error) {
 		stats.LastSyncTime = &t
 	}
 
-	// Count queued operations (for future offline queue feature)
-	err = db.conn.QueryRow(`
-		SELECT COUNT(*)
-		FROM offline_queue_items
-		WHERE job_id = ?
-	`, jobID).Scan(&stats.QueuedOperations)
-
-	if err != nil && err != sql.ErrNoRows {
-		// Offline queue table may not exist yet
-		stats.QueuedOperations = 0
+	// Count the changes waiting for the server (offline queue)
+	stats.QueuedOperations, err = db.CountOfflineQueue(jobID)
+	if err != nil {
+		return nil, err
 	}
 
 	return stats, nil
@@ -445,6 +531,47 @@ func (db *DB) CreateExclusion(excl *Exclusion) error {
 	return nil
 }
 
+// GetExclusionGroups retrieves the curated exclusion groups with their patterns.
+func (db *DB) GetExclusionGroups() ([]*ExclusionGroup, error) {
+	rows, err := db.conn.Query(`
+		SELECT g.name, g.label, g.default_enabled, p.pattern
+		FROM exclusion_groups g
+		LEFT JOIN exclusion_group_patterns p ON p.group_name = g.name
+		ORDER BY g.sort_order ASC, g.name ASC, p.id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query exclusion groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*ExclusionGroup
+	byName := make(map[string]*ExclusionGroup)
+	for rows.Next() {
+		var name, label string
+		var defaultEnabled bool
+		var pattern sql.NullString
+		if err := rows.Scan(&name, &label, &defaultEnabled, &pattern); err != nil {
+			return nil, fmt.Errorf("scan exclusion group: %w", err)
+		}
+
+		group, ok := byName[name]
+		if !ok {
+			group = &ExclusionGroup{Name: name, Label: label, DefaultEnabled: defaultEnabled}
+			byName[name] = group
+			groups = append(groups, group)
+		}
+		if pattern.Valid {
+			group.Patterns = append(group.Patterns, pattern.String)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate exclusion groups: %w", err)
+	}
+
+	return groups, nil
+}
+
 // GetIndividualExclusions retrieves individual path exclusions for a job
 func (db *DB) GetIndividualExclusions(jobID int64) (map[string]bool, error) {
 	rows, err := db.conn.Query(`
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncHistoryPruning(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	old := time.Now().Add(-SyncHistoryRetention["failed"] - time.Hour)
+	runs := []*SyncHistory{
+		{JobID: 1, RunID: "old-failed-1", Timestamp: old.Add(-time.Hour), Status: "failed", ErrorSummary: "share not found"},
+		{JobID: 1, RunID: "old-failed-2", Timestamp: old, Status: "failed", ErrorSummary: "access denied"},
+		{JobID: 1, RunID: "old-success", Timestamp: old, Status: "success"},
+		{JobID: 1, RunID: "old-partial", Timestamp: old, Status: "partial", ErrorSummary: "1 file locked"},
+		{JobID: 1, RunID: "recent-success", Timestamp: time.Now(), Status: "success"},
+		{JobID: 2, RunID: "old-success-2", Timestamp: time.Now().Add(-SyncHistoryRetention["success"] - time.Hour), Status: "success"},
+		{JobID: 2, RunID: "recent-failed-2", Timestamp: time.Now(), Status: "failed"},
+	}
+	for _, run := range runs {
+		if err := db.InsertSyncHistory(run); err != nil {
+			t.Fatalf("InsertSyncHistory failed: %v", err)
+		}
+	}
+
+	rows, err := db.conn.Query(`SELECT run_id, error_summary FROM sync_history ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query sync history: %v", err)
+	}
+	defer rows.Close()
+	kept := make(map[string]string)
+	for rows.Next() {
+		var runID, summary string
+		if err := rows.Scan(&runID, &summary); err != nil {
+			t.Fatalf("scan sync history: %v", err)
+		}
+		kept[runID] = summary
+	}
+
+	// Expired runs are dropped, except the last failure of each job
+	if len(kept) != 3 || kept["old-failed-2"] != "access denied" || !hasKey(kept, "recent-success") || !hasKey(kept, "recent-failed-2") {
+		t.Errorf("unexpected runs kept: %v", kept)
+	}
+}
+
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
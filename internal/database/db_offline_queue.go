@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// --- Offline Queue ---
+//
+// The local changes of a job made while its server is unreachable, one item
+// per file: a later change of the same file replaces its item. Items are kept
+// until a sync of the whole job succeeds.
+
+// QueueOfflineChange records a local change of a job made while its server
+// is unreachable, replacing the previous change of the same file.
+func (db *DB) QueueOfflineChange(item *OfflineQueueItem) error {
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now()
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM offline_queue WHERE job_id = ? AND file_path = ?`,
+			item.JobID, item.FilePath); err != nil {
+			return fmt.Errorf("delete queued change: %w", err)
+		}
+		res, err := tx.Exec(`
+			INSERT INTO offline_queue (job_id, file_path, operation, priority, retry_count, last_error, created_at)
+			VALUES (?, ?, ?, ?, 0, NULL, ?)
+		`, item.JobID, item.FilePath, item.Operation, item.Priority, item.CreatedAt.Unix())
+		if err != nil {
+			return fmt.Errorf("queue offline change: %w", err)
+		}
+		item.ID, err = res.LastInsertId()
+		return err
+	})
+}
+
+// GetOfflineQueue retrieves the queued changes of a job, by priority then
+// oldest first.
+func (db *DB) GetOfflineQueue(jobID int64) ([]*OfflineQueueItem, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, file_path, operation, priority, retry_count, last_error, created_at
+		FROM offline_queue
+		WHERE job_id = ?
+		ORDER BY priority DESC, created_at, id
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query offline queue: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*OfflineQueueItem
+	for rows.Next() {
+		item := OfflineQueueItem{JobID: jobID}
+		var lastError sql.NullString
+		var createdAt int64
+		if err := rows.Scan(&item.ID, &item.FilePath, &item.Operation, &item.Priority,
+			&item.RetryCount, &lastError, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan queued change: %w", err)
+		}
+		item.LastError = lastError.String
+		item.CreatedAt = time.Unix(createdAt, 0)
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate offline queue: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetOfflineJobs returns the IDs of the jobs with queued changes.
+func (db *DB) GetOfflineJobs() ([]int64, error) {
+	rows, err := db.conn.Query(`SELECT DISTINCT job_id FROM offline_queue ORDER BY job_id`)
+	if err != nil {
+		return nil, fmt.Errorf("query offline jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan offline job: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountOfflineQueue returns the number of queued changes of a job.
+func (db *DB) CountOfflineQueue(jobID int64) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM offline_queue WHERE job_id = ?`, jobID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count offline queue: %w", err)
+	}
+	return count, nil
+}
+
+// MarkOfflineReplayFailed records a failed replay of the queued changes of a
+// job (server still unreachable).
+func (db *DB) MarkOfflineReplayFailed(jobID int64, lastError string) error {
+	_, err := db.conn.Exec(`
+		UPDATE offline_queue SET retry_count = retry_count + 1, last_error = ?
+		WHERE job_id = ?
+	`, lastError, jobID)
+	if err != nil {
+		return fmt.Errorf("update offline queue: %w", err)
+	}
+	return nil
+}
+
+// ClearOfflineQueue removes the queued changes of a job once replayed and
+// returns how many there were.
+func (db *DB) ClearOfflineQueue(jobID int64) (int64, error) {
+	res, err := db.conn.Exec(`DELETE FROM offline_queue WHERE job_id = ?`, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("clear offline queue: %w", err)
+	}
+	return res.RowsAffected()
+}
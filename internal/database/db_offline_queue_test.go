@@ -0,0 +1,65 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	for _, item := range []*OfflineQueueItem{
+		{JobID: 1, FilePath: "docs/a.txt", Operation: OfflineUpload, CreatedAt: now.Add(-time.Minute)},
+		{JobID: 1, FilePath: "b.txt", Operation: OfflineDelete, CreatedAt: now},
+		{JobID: 2, FilePath: "c.txt", Operation: OfflineUpload},
+		// Deleted after being edited: only the last change is kept
+		{JobID: 1, FilePath: "docs/a.txt", Operation: OfflineDelete, CreatedAt: now.Add(time.Minute)},
+	} {
+		if err := db.QueueOfflineChange(item); err != nil {
+			t.Fatalf("QueueOfflineChange failed: %v", err)
+		}
+	}
+	if err := db.QueueOfflineChange(&OfflineQueueItem{JobID: 1, FilePath: "d.txt", Operation: "rename"}); err == nil {
+		t.Error("expected an error for an unknown operation")
+	}
+
+	items, err := db.GetOfflineQueue(1)
+	if err != nil {
+		t.Fatalf("GetOfflineQueue failed: %v", err)
+	}
+	if len(items) != 2 || items[0].FilePath != "b.txt" || items[1].FilePath != "docs/a.txt" || items[1].Operation != OfflineDelete {
+		t.Fatalf("unexpected queue: %+v", items)
+	}
+
+	if jobs, err := db.GetOfflineJobs(); err != nil || len(jobs) != 2 || jobs[0] != 1 || jobs[1] != 2 {
+		t.Errorf("GetOfflineJobs() = %v, %v", jobs, err)
+	}
+
+	if err := db.MarkOfflineReplayFailed(1, "server unreachable"); err != nil {
+		t.Fatalf("MarkOfflineReplayFailed failed: %v", err)
+	}
+	items, _ = db.GetOfflineQueue(1)
+	if items[0].RetryCount != 1 || items[0].LastError != "server unreachable" {
+		t.Errorf("replay failure not recorded: %+v", items[0])
+	}
+
+	if n, err := db.ClearOfflineQueue(1); err != nil || n != 2 {
+		t.Errorf("ClearOfflineQueue() = %d, %v", n, err)
+	}
+	if n, _ := db.CountOfflineQueue(1); n != 0 {
+		t.Errorf("expected an empty queue for job 1, got %d", n)
+	}
+	if n, _ := db.CountOfflineQueue(2); n != 1 {
+		t.Errorf("expected 1 queued change for job 2, got %d", n)
+	}
+}
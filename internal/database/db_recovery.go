@@ -0,0 +1,316 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// --- Corrupt Database Recovery ---
+
+// MetaSafeMode is the db_metadata key set after a recovery: syncs run in
+// dry-run until the user confirms the recovered data.
+const MetaSafeMode = "safe_mode"
+
+// recoveryTableOrder lists tables copied first, so that rows referencing them
+// find their parent.
+var recoveryTableOrder = []string{"smb_servers", "sync_jobs", "exclusion_groups"}
+
+// baselineTables hold the per-job sync baseline, rebuilt when not fully recovered.
+var baselineTables = []string{"files_state", "remote_snapshots", "job_state_stores"}
+
+// RecoveryReport describes the outcome of Recover.
+type RecoveryReport struct {
+	BackupPath    string            // Where the corrupt database was moved
+	RecoveredRows map[string]int    // Rows salvaged per table
+	FailedTables  map[string]string // Tables that could not be (fully) read, with the error
+	BaselineReset bool              // files_state was lost: baselines are rebuilt on next sync
+}
+
+// IsCorrupt reports whether err means the database file is corrupt or not a
+// database (which is also what a wrong SQLCipher key looks like).
+func IsCorrupt(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrNotADB || sqliteErr.Code == sqlite3.ErrCorrupt
+	}
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "file is not a database") ||
+		strings.Contains(msg, "database disk image is malformed")
+}
+
+// Recover moves the corrupt database at cfg.Path aside, creates a new one and
+// salvages every readable row of the old one into it. Baselines that could
+// not be salvaged are cleared so they are rebuilt by the next sync.
+// The new database is left in safe mode (see MetaSafeMode).
+func Recover(cfg Config) (*DB, *RecoveryReport, error) {
+	report := &RecoveryReport{
+		BackupPath:    fmt.Sprintf("%s.corrupt-%s", cfg.Path, time.Now().Format("20060102-150405")),
+		RecoveredRows: make(map[string]int),
+		FailedTables:  make(map[string]string),
+	}
+
+	if err := os.Rename(cfg.Path, report.BackupPath); err != nil {
+		return nil, nil, fmt.Errorf("failed to back up corrupt database: %w", err)
+	}
+	// Keep the journal files with the backup: they may hold the latest writes
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if fileExists(cfg.Path + suffix) {
+			os.Rename(cfg.Path+suffix, report.BackupPath+suffix)
+		}
+	}
+
+	db, err := Open(Config{
+		Path:             cfg.Path,
+		EncryptionKey:    cfg.EncryptionKey,
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create new database: %w", err)
+	}
+
+	if err := db.salvage(report.BackupPath, cfg.EncryptionKey, report); err != nil {
+		report.FailedTables["*"] = err.Error()
+	}
+
+	// A local snapshot is only replayed with the change journal when it is
+	// complete: the next scans walk the job folders again
+	if _, err := db.conn.Exec(`DELETE FROM scan_checkpoints`); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to reset scan checkpoints: %w", err)
+	}
+	// A partly salvaged listing cache would hide remote files: list again
+	if _, err := db.conn.Exec(`DELETE FROM remote_state`); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to reset remote listing cache: %w", err)
+	}
+	if err := db.resetLostBaselines(report); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	// Jobs whose state store was salvaged keep using it
+	if err := db.loadJobStores(); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	if err := db.SetSafeMode(true); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, report, nil
+}
+
+// SafeMode reports whether the database was recovered and not confirmed yet.
+func (db *DB) SafeMode() bool {
+	value, err := db.GetMetadata(MetaSafeMode)
+	return err == nil && value == "1"
+}
+
+// SetSafeMode enters or leaves safe mode.
+func (db *DB) SetSafeMode(enabled bool) error {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return db.SetMetadata(MetaSafeMode, value)
+}
+
+// salvage copies the readable rows of the database at path into db.
+func (db *DB) salvage(path, key string, report *RecoveryReport) error {
+	connStr := fmt.Sprintf("file:%s?_pragma_key=%s&_pragma_cipher_page_size=4096&mode=ro", path, key)
+	old, err := sql.Open("sqlite3", connStr)
+	if err != nil {
+		return fmt.Errorf("failed to open corrupt database: %w", err)
+	}
+	defer old.Close()
+
+	tables, err := listTables(old)
+	if err != nil {
+		return fmt.Errorf("failed to read corrupt database schema: %w", err)
+	}
+
+	for _, table := range tables {
+		if table == "db_metadata" {
+			continue // Keep the new schema version
+		}
+		copied, err := db.copyTable(old, table)
+		if copied > 0 {
+			report.RecoveredRows[table] = copied
+		}
+		if err != nil {
+			report.FailedTables[table] = err.Error()
+		}
+	}
+
+	return nil
+}
+
+// copyTable copies the rows of table from old into db, for the columns both
+// schemas share. Rows read before a corrupt page are kept.
+func (db *DB) copyTable(old *sql.DB, table string) (int, error) {
+	columns, err := sharedColumns(old, db.conn, table)
+	if err != nil || len(columns) == 0 {
+		return 0, err
+	}
+
+	columnList := strings.Join(columns, ", ")
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+
+	rows, err := old.Query(fmt.Sprintf("SELECT %s FROM %s", columnList, table))
+	if err != nil {
+		return 0, fmt.Errorf("query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	copied := 0
+	err = db.Transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)", table, columnList, placeholders))
+		if err != nil {
+			return fmt.Errorf("prepare insert into %s: %w", table, err)
+		}
+		defer stmt.Close()
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(pointers...); err != nil {
+				return nil // Keep what was read so far
+			}
+			if _, err := stmt.Exec(values...); err != nil {
+				continue // Skip rows the new schema rejects
+			}
+			copied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return copied, fmt.Errorf("read %s: %w", table, err)
+	}
+	return copied, nil
+}
+
+// resetLostBaselines clears the baselines when files_state was not fully recovered:
+// a partial baseline would make missing entries look like deletions.
+func (db *DB) resetLostBaselines(report *RecoveryReport) error {
+	_, filesFailed := report.FailedTables["files_state"]
+	_, allFailed := report.FailedTables["*"]
+	if !filesFailed && !allFailed {
+		return nil
+	}
+
+	for _, table := range baselineTables {
+		if _, err := db.conn.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to reset %s: %w", table, err)
+		}
+		delete(report.RecoveredRows, table)
+	}
+	report.BaselineReset = true
+	return nil
+}
+
+// listTables returns the user tables of conn, parent tables first.
+func listTables(conn *sql.DB) ([]string, error) {
+	rows, err := conn.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rank := func(name string) int {
+		for i, first := range recoveryTableOrder {
+			if name == first {
+				return i
+			}
+		}
+		return len(recoveryTableOrder)
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		if rank(tables[i]) != rank(tables[j]) {
+			return rank(tables[i]) < rank(tables[j])
+		}
+		return tables[i] < tables[j]
+	})
+
+	return tables, nil
+}
+
+// sharedColumns returns the columns of table present in both databases.
+func sharedColumns(old, current *sql.DB, table string) ([]string, error) {
+	oldColumns, err := tableColumns(old, table)
+	if err != nil {
+		return nil, fmt.Errorf("read %s columns: %w", table, err)
+	}
+	currentColumns, err := tableColumns(current, table)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]bool, len(currentColumns))
+	for _, column := range currentColumns {
+		present[column] = true
+	}
+
+	var shared []string
+	for _, column := range oldColumns {
+		if present[column] {
+			shared = append(shared, column)
+		}
+	}
+	return shared, nil
+}
+
+// tableColumns returns the column names of table (none if it does not exist).
+func tableColumns(conn *sql.DB, table string) ([]string, error) {
+	rows, err := conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			cid        int
+			name, kind string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultVal, &primaryKey); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen_CorruptFileIsDetected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, []byte("this is definitely not an encrypted sqlite database, just garbage bytes"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Open(Config{Path: path, EncryptionKey: "test-key", CreateIfNotExist: true})
+	if err == nil {
+		t.Fatal("expected Open to fail on a corrupt file")
+	}
+	if !IsCorrupt(err) {
+		t.Errorf("expected corrupt database error, got %v", err)
+	}
+}
+
+func TestRecover_SalvagesReadableData(t *testing.T) {
+	cfg := Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+	if err := db.UpsertFileState(&FileState{JobID: job.ID, LocalPath: "a.txt", RemotePath: "a.txt", Size: 1, MTime: 1, Hash: "h", SyncStatus: "idle"}); err != nil {
+		t.Fatalf("UpsertFileState failed: %v", err)
+	}
+	db.Close()
+
+	recovered, report, err := Recover(cfg)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	defer recovered.Close()
+
+	if _, err := os.Stat(report.BackupPath); err != nil {
+		t.Errorf("expected backup at %s: %v", report.BackupPath, err)
+	}
+	if report.RecoveredRows["sync_jobs"] != 1 || report.RecoveredRows["files_state"] != 1 {
+		t.Errorf("expected job and file state recovered, got %v (failed %v)", report.RecoveredRows, report.FailedTables)
+	}
+	if report.BaselineReset {
+		t.Error("baseline should be kept when files_state is recovered")
+	}
+	if got, err := recovered.GetSyncJob(job.ID); err != nil || got == nil || got.Name != "job" {
+		t.Errorf("expected recovered job, got %v (err=%v)", got, err)
+	}
+	if !recovered.SafeMode() {
+		t.Error("expected safe mode after recovery")
+	}
+
+	if err := recovered.SetSafeMode(false); err != nil || recovered.SafeMode() {
+		t.Errorf("expected safe mode cleared (err=%v)", err)
+	}
+}
+
+func TestRecover_UnreadableDatabase(t *testing.T) {
+	cfg := Config{
+		Path:          filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey: "test-key",
+	}
+	if err := os.WriteFile(cfg.Path, []byte("garbage garbage garbage garbage garbage garbage garbage garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	db, report, err := Recover(cfg)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	defer db.Close()
+
+	if !report.BaselineReset || len(report.FailedTables) == 0 {
+		t.Errorf("expected failed salvage with baseline reset, got %+v", report)
+	}
+	if !db.SafeMode() {
+		t.Error("expected safe mode after recovery")
+	}
+	if err := db.HealthCheck(); err != nil {
+		t.Errorf("expected usable new database: %v", err)
+	}
+}
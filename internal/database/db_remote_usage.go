@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// --- Remote Usage ---
+
+// SaveRemoteUsage replaces the remote space usage of a job.
+func (db *DB) SaveRemoteUsage(usage *RemoteUsage) error {
+	folders, err := json.Marshal(usage.Folders)
+	if err != nil {
+		return err
+	}
+	largest, err := json.Marshal(usage.LargestFiles)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(`
+		INSERT OR REPLACE INTO remote_usage (job_id, scanned_at, total_files, total_bytes, folders, largest_files)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, usage.JobID, usage.ScannedAt.Unix(), usage.TotalFiles, usage.TotalBytes, string(folders), string(largest))
+	if err != nil {
+		return fmt.Errorf("save remote usage: %w", err)
+	}
+	return nil
+}
+
+// GetRemoteUsage retrieves the remote space usage of a job saved by its last
+// scan. Returns nil if the job was never scanned.
+func (db *DB) GetRemoteUsage(jobID int64) (*RemoteUsage, error) {
+	usage := &RemoteUsage{JobID: jobID}
+	var scannedAt int64
+	var folders, largest string
+	err := db.conn.QueryRow(`
+		SELECT scanned_at, total_files, total_bytes, folders, largest_files
+		FROM remote_usage
+		WHERE job_id = ?
+	`, jobID).Scan(&scannedAt, &usage.TotalFiles, &usage.TotalBytes, &folders, &largest)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query remote usage: %w", err)
+	}
+
+	usage.ScannedAt = time.Unix(scannedAt, 0)
+	if err := json.Unmarshal([]byte(folders), &usage.Folders); err != nil {
+		return nil, fmt.Errorf("decode remote usage folders: %w", err)
+	}
+	if err := json.Unmarshal([]byte(largest), &usage.LargestFiles); err != nil {
+		return nil, fmt.Errorf("decode remote usage files: %w", err)
+	}
+	return usage, nil
+}
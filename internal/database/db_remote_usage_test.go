@@ -0,0 +1,54 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRemoteUsage(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	usage, err := db.GetRemoteUsage(1)
+	if err != nil || usage != nil {
+		t.Fatalf("GetRemoteUsage before any scan = %v, %v, want nil", usage, err)
+	}
+
+	scannedAt := time.Now().Truncate(time.Second)
+	if err := db.SaveRemoteUsage(&RemoteUsage{
+		JobID:        1,
+		ScannedAt:    scannedAt,
+		TotalFiles:   3,
+		TotalBytes:   700,
+		Folders:      []UsageEntry{{Path: "Videos", Files: 1, Bytes: 500}, {Path: "", Files: 2, Bytes: 200}},
+		LargestFiles: []UsageEntry{{Path: "Videos/trip.mp4", Files: 1, Bytes: 500}},
+	}); err != nil {
+		t.Fatalf("SaveRemoteUsage failed: %v", err)
+	}
+	if err := db.SaveRemoteUsage(&RemoteUsage{JobID: 1, ScannedAt: scannedAt, TotalFiles: 3, TotalBytes: 700,
+		Folders: []UsageEntry{{Path: "Videos", Files: 3, Bytes: 700}}}); err != nil {
+		t.Fatalf("SaveRemoteUsage failed: %v", err)
+	}
+
+	usage, err = db.GetRemoteUsage(1)
+	if err != nil {
+		t.Fatalf("GetRemoteUsage failed: %v", err)
+	}
+	if !usage.ScannedAt.Equal(scannedAt) || usage.TotalFiles != 3 || usage.TotalBytes != 700 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if len(usage.Folders) != 1 || usage.Folders[0] != (UsageEntry{Path: "Videos", Files: 3, Bytes: 700}) {
+		t.Errorf("folders = %+v, want the last scan only", usage.Folders)
+	}
+	if len(usage.LargestFiles) != 0 {
+		t.Errorf("largest files = %+v, want none", usage.LargestFiles)
+	}
+}
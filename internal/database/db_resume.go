@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// --- Resume Plans ---
+
+// SaveResumePlan replaces the resume plan of a job with the given decisions
+// (JSON-encoded, in execution order), none of them done yet.
+func (db *DB) SaveResumePlan(plan *ResumePlan, decisions []string) error {
+	if plan.CreatedAt.IsZero() {
+		plan.CreatedAt = time.Now()
+	}
+	plan.Total = len(decisions)
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		if err := deleteResumePlanTx(tx, plan.JobID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO resume_plans (job_id, mode, subtree, total, created_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, plan.JobID, plan.Mode, plan.Subtree, plan.Total, plan.CreatedAt.Unix()); err != nil {
+			return fmt.Errorf("insert resume plan: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT INTO resume_plan_actions (job_id, seq, decision)
+			VALUES (?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for seq, decision := range decisions {
+			if _, err := stmt.Exec(plan.JobID, seq, decision); err != nil {
+				return fmt.Errorf("insert resume action %d: %w", seq, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetResumePlan retrieves the resume plan of a job with its pending actions.
+// Returns nil if the job has no plan.
+func (db *DB) GetResumePlan(jobID int64) (*ResumePlan, error) {
+	plan := &ResumePlan{JobID: jobID}
+	var createdAt int64
+	err := db.conn.QueryRow(`
+		SELECT mode, subtree, total, created_at
+		FROM resume_plans
+		WHERE job_id = ?
+	`, jobID).Scan(&plan.Mode, &plan.Subtree, &plan.Total, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query resume plan: %w", err)
+	}
+	plan.CreatedAt = time.Unix(createdAt, 0)
+
+	rows, err := db.conn.Query(`
+		SELECT seq, decision
+		FROM resume_plan_actions
+		WHERE job_id = ? AND done = 0
+		ORDER BY seq
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query resume actions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var action ResumeAction
+		if err := rows.Scan(&action.Seq, &action.Decision); err != nil {
+			return nil, fmt.Errorf("scan resume action: %w", err)
+		}
+		plan.Pending = append(plan.Pending, &action)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate resume actions: %w", err)
+	}
+
+	return plan, nil
+}
+
+// MarkResumeActionsDone records that the actions at the given positions of a
+// job's resume plan are done.
+func (db *DB) MarkResumeActionsDone(jobID int64, seqs []int) error {
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`UPDATE resume_plan_actions SET done = 1 WHERE job_id = ? AND seq = ?`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, seq := range seqs {
+			if _, err := stmt.Exec(jobID, seq); err != nil {
+				return fmt.Errorf("mark resume action %d done: %w", seq, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteResumePlan removes the resume plan of a job, if any.
+func (db *DB) DeleteResumePlan(jobID int64) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		return deleteResumePlanTx(tx, jobID)
+	})
+}
+
+func deleteResumePlanTx(tx *sql.Tx, jobID int64) error {
+	if _, err := tx.Exec(`DELETE FROM resume_plan_actions WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("delete resume actions: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM resume_plans WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("delete resume plan: %w", err)
+	}
+	return nil
+}
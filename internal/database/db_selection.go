@@ -0,0 +1,68 @@
+package database
+
+import (
+	"fmt"
+)
+
+// --- Selective Sync ---
+
+// GetSyncSelection retrieves the selective sync rules of a job, sorted by folder.
+// No rules means the whole job is synced.
+func (db *DB) GetSyncSelection(jobID int64) ([]*SelectionRule, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, mode
+		FROM sync_selection
+		WHERE job_id = ?
+		ORDER BY path
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query sync selection: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*SelectionRule
+	for rows.Next() {
+		rule := SelectionRule{JobID: jobID}
+		if err := rows.Scan(&rule.Path, &rule.Mode); err != nil {
+			return nil, fmt.Errorf("scan selection rule: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sync selection: %w", err)
+	}
+
+	return rules, nil
+}
+
+// SaveSelectionRule includes or excludes a folder of a job, replacing the
+// previous rule of the folder.
+func (db *DB) SaveSelectionRule(rule *SelectionRule) error {
+	if rule.Mode != SelectionInclude && rule.Mode != SelectionExclude {
+		return fmt.Errorf("invalid selection mode %q", rule.Mode)
+	}
+
+	_, err := db.conn.Exec(`
+		INSERT OR REPLACE INTO sync_selection (job_id, path, mode)
+		VALUES (?, ?, ?)
+	`, rule.JobID, rule.Path, rule.Mode)
+	if err != nil {
+		return fmt.Errorf("save selection rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteSelectionRule removes the rule of a folder: it follows its parent
+// folders again. Returns whether a rule was removed.
+func (db *DB) DeleteSelectionRule(jobID int64, path string) (bool, error) {
+	res, err := db.conn.Exec(`DELETE FROM sync_selection WHERE job_id = ? AND path = ?`, jobID, path)
+	if err != nil {
+		return false, fmt.Errorf("delete selection rule: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete selection rule: %w", err)
+	}
+	return n > 0, nil
+}
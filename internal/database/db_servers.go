@@ -13,7 +13,7 @@ func (db *DB) GetAllSMBServers() ([]*SMBServer, error) {
 	rows, err := db.conn.Query(`
 		SELECT id, name, host, port, username, domain, credential_id,
 			   smb_version, last_connection_test, last_connection_status,
-			   created_at, updated_at
+			   mtime_source, security_level, require_security, require_encryption, created_at, updated_at
 		FROM smb_servers
 		ORDER BY name ASC
 	`)
@@ -32,7 +32,7 @@ func (db *DB) GetAllSMBServers() ([]*SMBServer, error) {
 		err := rows.Scan(
 			&s.ID, &s.Name, &s.Host, &s.Port, &s.Username,
 			&domain, &s.CredentialID, &smbVersion, &lastConnTest, &connStatus,
-			&createdAt, &updatedAt,
+			&s.MTimeSource, &s.SecurityLevel, &s.RequireSecurity, &s.RequireEncryption, &createdAt, &updatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan smb server: %w", err)
@@ -74,13 +74,13 @@ func (db *DB) GetSMBServer(id int64) (*SMBServer, error) {
 	err := db.conn.QueryRow(`
 		SELECT id, name, host, port, username, domain, credential_id,
 			   smb_version, last_connection_test, last_connection_status,
-			   created_at, updated_at
+			   mtime_source, security_level, require_security, require_encryption, created_at, updated_at
 		FROM smb_servers
 		WHERE id = ?
 	`, id).Scan(
 		&s.ID, &s.Name, &s.Host, &s.Port, &s.Username,
 		&domain, &s.CredentialID, &smbVersion, &lastConnTest, &connStatus,
-		&createdAt, &updatedAt,
+		&s.MTimeSource, &s.SecurityLevel, &s.RequireSecurity, &s.RequireEncryption, &createdAt, &updatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -109,6 +109,19 @@ func (db *DB) GetSMBServer(id int64) (*SMBServer, error) {
 	return &s, nil
 }
 
+// GetSMBServerByHost retrieves a single SMB server by host (nil if not found)
+func (db *DB) GetSMBServerByHost(host string) (*SMBServer, error) {
+	var id int64
+	err := db.conn.QueryRow(`SELECT id FROM smb_servers WHERE host = ? COLLATE NOCASE`, host).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get smb server by host: %w", err)
+	}
+	return db.GetSMBServer(id)
+}
+
 // CreateSMBServer creates a new SMB server configuration
 func (db *DB) CreateSMBServer(server *SMBServer) error {
 	now := time.Now().Unix()
@@ -119,11 +132,11 @@ func (db *DB) CreateSMBServer(server *SMBServer) error {
 	result, err := db.conn.Exec(`
 		INSERT INTO smb_servers (
 			name, host, port, username, domain, credential_id,
-			smb_version, created_at, updated_at
-		) VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?)
+			smb_version, mtime_source, require_security, require_encryption, created_at, updated_at
+		) VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), COALESCE(NULLIF(?, ''), 'write'), ?, ?, ?, ?)
 	`,
 		server.Name, server.Host, server.Port, server.Username,
-		server.Domain, server.CredentialID, server.SMBVersion, now, now,
+		server.Domain, server.CredentialID, server.SMBVersion, server.MTimeSource, server.RequireSecurity, server.RequireEncryption, now, now,
 	)
 	if err != nil {
 		return fmt.Errorf("insert smb server: %w", err)
@@ -151,11 +164,12 @@ func (db *DB) UpdateSMBServer(server *SMBServer) error {
 	result, err := db.conn.Exec(`
 		UPDATE smb_servers SET
 			name = ?, host = ?, port = ?, username = ?,
-			domain = NULLIF(?, ''), credential_id = ?, smb_version = NULLIF(?, ''), updated_at = ?
+			domain = NULLIF(?, ''), credential_id = ?, smb_version = NULLIF(?, ''),
+			mtime_source = COALESCE(NULLIF(?, ''), 'write'), require_security = ?, require_encryption = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		server.Name, server.Host, server.Port, server.Username,
-		server.Domain, server.CredentialID, server.SMBVersion, now, server.ID,
+		server.Domain, server.CredentialID, server.SMBVersion, server.MTimeSource, server.RequireSecurity, server.RequireEncryption, now, server.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update smb server: %w", err)
@@ -204,3 +218,13 @@ func (db *DB) UpdateSMBServerConnectionStatus(id int64, status string) error {
 	}
 	return nil
 }
+
+// SetSMBServerSecurityLevel records the best security negotiated with a
+// server so far ("" forgets it, e.g. after a deliberate change on the server)
+func (db *DB) SetSMBServerSecurityLevel(id int64, level string) error {
+	_, err := db.conn.Exec(`UPDATE smb_servers SET security_level = ? WHERE id = ?`, level, id)
+	if err != nil {
+		return fmt.Errorf("update security level: %w", err)
+	}
+	return nil
+}
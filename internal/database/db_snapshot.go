@@ -0,0 +1,242 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// --- Remote Listing Snapshots ---
+
+// GetRemoteSnapshot retrieves the last remote listing saved for a job, keyed by path.
+// Returns an empty map if no snapshot was saved yet.
+func (db *DB) GetRemoteSnapshot(jobID int64) (map[string]*RemoteSnapshotEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, size, mtime, hash
+		FROM remote_snapshots
+		WHERE job_id = ?
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query remote snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string]*RemoteSnapshotEntry)
+	for rows.Next() {
+		var entry RemoteSnapshotEntry
+		var hash sql.NullString
+		if err := rows.Scan(&entry.Path, &entry.Size, &entry.MTime, &hash); err != nil {
+			return nil, fmt.Errorf("scan remote snapshot entry: %w", err)
+		}
+		entry.Hash = hash.String
+		entries[entry.Path] = &entry
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate remote snapshot: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReplaceRemoteSnapshot replaces the remote listing snapshot of a job.
+func (db *DB) ReplaceRemoteSnapshot(jobID int64, entries []*RemoteSnapshotEntry) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM remote_snapshots WHERE job_id = ?`, jobID); err != nil {
+			return fmt.Errorf("clear remote snapshot: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT OR REPLACE INTO remote_snapshots (job_id, path, size, mtime, hash)
+			VALUES (?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, entry := range entries {
+			if _, err := stmt.Exec(jobID, entry.Path, entry.Size, entry.MTime, entry.Hash); err != nil {
+				return fmt.Errorf("execute statement for %s: %w", entry.Path, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// --- Remote Listing Cache ---
+
+// GetRemoteState retrieves the files and folders found by the last SMB scan
+// of a job, keyed by path. Returns an empty map if no scan was saved yet.
+func (db *DB) GetRemoteState(jobID int64) (map[string]*RemoteStateEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, is_dir, size, mtime, write_time, change_time, attributes, listed_at
+		FROM remote_state
+		WHERE job_id = ?
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query remote state: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string]*RemoteStateEntry)
+	for rows.Next() {
+		var entry RemoteStateEntry
+		if err := rows.Scan(&entry.Path, &entry.IsDir, &entry.Size, &entry.MTime, &entry.WriteTime,
+			&entry.ChangeTime, &entry.Attributes, &entry.ListedAt); err != nil {
+			return nil, fmt.Errorf("scan remote state entry: %w", err)
+		}
+		entries[entry.Path] = &entry
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate remote state: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReplaceRemoteState replaces the files and folders of the last SMB scan of a
+// job. A nil list clears it: the next scan lists every folder.
+func (db *DB) ReplaceRemoteState(jobID int64, entries []*RemoteStateEntry) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM remote_state WHERE job_id = ?`, jobID); err != nil {
+			return fmt.Errorf("clear remote state: %w", err)
+		}
+
+		stmt, err := tx.Prepare(`
+			INSERT OR REPLACE INTO remote_state (job_id, path, is_dir, size, mtime, write_time, change_time, attributes, listed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, entry := range entries {
+			if _, err := stmt.Exec(jobID, entry.Path, entry.IsDir, entry.Size, entry.MTime, entry.WriteTime,
+				entry.ChangeTime, entry.Attributes, entry.ListedAt); err != nil {
+				return fmt.Errorf("execute statement for %s: %w", entry.Path, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// --- Local Listing Snapshots ---
+
+// GetScanCheckpoint retrieves the change journal position of the last local
+// snapshot of a job. Returns nil if the job has none.
+func (db *DB) GetScanCheckpoint(jobID int64) (*ScanCheckpoint, error) {
+	var cp ScanCheckpoint
+	err := db.conn.QueryRow(`
+		SELECT job_id, root, volume, journal_id, usn, rules, scanned_at
+		FROM scan_checkpoints
+		WHERE job_id = ?
+	`, jobID).Scan(&cp.JobID, &cp.Root, &cp.Volume, &cp.JournalID, &cp.USN, &cp.Rules, &cp.ScannedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query scan checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// GetLocalSnapshot retrieves the files found by the last local scan of a job,
+// keyed by path. Returns an empty map if no snapshot was saved yet.
+func (db *DB) GetLocalSnapshot(jobID int64) (map[string]*LocalSnapshotEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, size, mtime, hash, attributes, excluded
+		FROM local_snapshots
+		WHERE job_id = ?
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query local snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string]*LocalSnapshotEntry)
+	for rows.Next() {
+		var entry LocalSnapshotEntry
+		var hash sql.NullString
+		if err := rows.Scan(&entry.Path, &entry.Size, &entry.MTime, &hash, &entry.Attributes, &entry.Excluded); err != nil {
+			return nil, fmt.Errorf("scan local snapshot entry: %w", err)
+		}
+		entry.Hash = hash.String
+		entries[entry.Path] = &entry
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate local snapshot: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReplaceLocalSnapshot replaces the local snapshot of a job and its checkpoint,
+// after a scan that walked the whole job folder.
+func (db *DB) ReplaceLocalSnapshot(cp *ScanCheckpoint, entries []*LocalSnapshotEntry) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM local_snapshots WHERE job_id = ?`, cp.JobID); err != nil {
+			return fmt.Errorf("clear local snapshot: %w", err)
+		}
+		if err := upsertLocalSnapshot(tx, cp.JobID, entries); err != nil {
+			return err
+		}
+		return saveScanCheckpoint(tx, cp)
+	})
+}
+
+// UpdateLocalSnapshot applies the changes found by a change journal scan to
+// the local snapshot of a job and moves its checkpoint.
+func (db *DB) UpdateLocalSnapshot(cp *ScanCheckpoint, changed []*LocalSnapshotEntry, removed []string) error {
+	return db.Transaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`DELETE FROM local_snapshots WHERE job_id = ? AND path = ?`)
+		if err != nil {
+			return fmt.Errorf("prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, path := range removed {
+			if _, err := stmt.Exec(cp.JobID, path); err != nil {
+				return fmt.Errorf("execute statement for %s: %w", path, err)
+			}
+		}
+		if err := upsertLocalSnapshot(tx, cp.JobID, changed); err != nil {
+			return err
+		}
+		return saveScanCheckpoint(tx, cp)
+	})
+}
+
+// upsertLocalSnapshot inserts or replaces local snapshot entries of a job.
+func upsertLocalSnapshot(tx *sql.Tx, jobID int64, entries []*LocalSnapshotEntry) error {
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO local_snapshots (job_id, path, size, mtime, hash, attributes, excluded)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		if _, err := stmt.Exec(jobID, entry.Path, entry.Size, entry.MTime, entry.Hash, entry.Attributes, entry.Excluded); err != nil {
+			return fmt.Errorf("execute statement for %s: %w", entry.Path, err)
+		}
+	}
+	return nil
+}
+
+// saveScanCheckpoint inserts or replaces the checkpoint of a job.
+func saveScanCheckpoint(tx *sql.Tx, cp *ScanCheckpoint) error {
+	if _, err := tx.Exec(`
+		INSERT OR REPLACE INTO scan_checkpoints (job_id, root, volume, journal_id, usn, rules, scanned_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, cp.JobID, cp.Root, cp.Volume, cp.JournalID, cp.USN, cp.Rules, cp.ScannedAt); err != nil {
+		return fmt.Errorf("save scan checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// --- Upload Vetoes ---
+
+// GetUploadVetoes retrieves the files of a job vetoed by the pre-upload scan, keyed by path.
+func (db *DB) GetUploadVetoes(jobID int64) (map[string]*UploadVeto, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, hash, reason, vetoed_at
+		FROM upload_vetoes
+		WHERE job_id = ?
+	`, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("query upload vetoes: %w", err)
+	}
+	defer rows.Close()
+
+	vetoes := make(map[string]*UploadVeto)
+	for rows.Next() {
+		veto := UploadVeto{JobID: jobID}
+		var reason sql.NullString
+		var vetoedAt int64
+		if err := rows.Scan(&veto.Path, &veto.Hash, &reason, &vetoedAt); err != nil {
+			return nil, fmt.Errorf("scan upload veto: %w", err)
+		}
+		veto.Reason = reason.String
+		veto.VetoedAt = time.Unix(vetoedAt, 0)
+		vetoes[veto.Path] = &veto
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate upload vetoes: %w", err)
+	}
+
+	return vetoes, nil
+}
+
+// SaveUploadVeto records (or replaces) the veto of a file.
+func (db *DB) SaveUploadVeto(veto *UploadVeto) error {
+	if veto.VetoedAt.IsZero() {
+		veto.VetoedAt = time.Now()
+	}
+
+	_, err := db.conn.Exec(`
+		INSERT OR REPLACE INTO upload_vetoes (job_id, path, hash, reason, vetoed_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, veto.JobID, veto.Path, veto.Hash, veto.Reason, veto.VetoedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("save upload veto: %w", err)
+	}
+	return nil
+}
+
+// DeleteUploadVeto removes the veto of a file.
+func (db *DB) DeleteUploadVeto(jobID int64, path string) error {
+	_, err := db.conn.Exec(`DELETE FROM upload_vetoes WHERE job_id = ? AND path = ?`, jobID, path)
+	if err != nil {
+		return fmt.Errorf("delete upload veto: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,534 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+// migration upgrades the schema to a given version.
+// schema.sql always creates a version 1 database; migrations bring it up to date.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+
+	// backfill converts existing rows after the statements, in the same transaction
+	backfill func(tx *sql.Tx) error
+}
+
+// migrations lists all schema migrations in ascending version order.
+// Never edit a released migration: add a new one instead.
+var migrations = []migration{
+	{
+		version:     2,
+		description: "remote timestamp source per server",
+		statements: []string{
+			`ALTER TABLE smb_servers ADD COLUMN mtime_source TEXT NOT NULL DEFAULT 'write'`,
+			`ALTER TABLE files_state ADD COLUMN remote_write_time INTEGER`,
+			`ALTER TABLE files_state ADD COLUMN remote_change_time INTEGER`,
+		},
+	},
+	{
+		version:     3,
+		description: "file attribute bits",
+		statements: []string{
+			`ALTER TABLE files_state ADD COLUMN attributes INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     4,
+		description: "curated exclusion groups",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS exclusion_groups (
+				name TEXT PRIMARY KEY,
+				label TEXT NOT NULL,
+				default_enabled INTEGER NOT NULL DEFAULT 1,
+				sort_order INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE IF NOT EXISTS exclusion_group_patterns (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				group_name TEXT NOT NULL,
+				pattern TEXT NOT NULL,
+				FOREIGN KEY (group_name) REFERENCES exclusion_groups(name) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_exclusion_group_patterns_group ON exclusion_group_patterns(group_name)`,
+			`INSERT INTO exclusion_groups (name, label, default_enabled, sort_order) VALUES
+				('system', 'Recycle bins and system folders', 1, 1),
+				('vcs', 'Version control folders (.git, .svn, ...)', 1, 2),
+				('dependencies', 'Dependency folders (node_modules, Python venvs)', 1, 3)`,
+			`INSERT INTO exclusion_group_patterns (group_name, pattern) VALUES
+				('system', '$RECYCLE.BIN/'),
+				('system', 'RECYCLER/'),
+				('system', 'System Volume Information/'),
+				('system', '.Trashes/'),
+				('system', '.Spotlight-V100/'),
+				('system', '.fseventsd/'),
+				('vcs', '.git/'),
+				('vcs', '.svn/'),
+				('vcs', '.hg/'),
+				('vcs', '.bzr/'),
+				('dependencies', 'node_modules/'),
+				('dependencies', '__pycache__/'),
+				('dependencies', '.venv/'),
+				('dependencies', 'venv/')`,
+		},
+	},
+	{
+		version:     5,
+		description: "remote listing snapshot for placeholder sync",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS remote_snapshots (
+				job_id INTEGER NOT NULL,
+				path TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				mtime INTEGER NOT NULL,
+				hash TEXT,
+				PRIMARY KEY (job_id, path),
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     6,
+		description: "files vetoed by the pre-upload scan",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS upload_vetoes (
+				job_id INTEGER NOT NULL,
+				path TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				reason TEXT,
+				vetoed_at INTEGER NOT NULL,
+				PRIMARY KEY (job_id, path),
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     7,
+		description: "per-job files_state stores",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS job_state_stores (
+				job_id INTEGER PRIMARY KEY,
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     8,
+		description: "action plans of interrupted syncs",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS resume_plans (
+				job_id INTEGER PRIMARY KEY,
+				mode TEXT NOT NULL,
+				subtree TEXT NOT NULL DEFAULT '',
+				total INTEGER NOT NULL,
+				created_at INTEGER NOT NULL,
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS resume_plan_actions (
+				job_id INTEGER NOT NULL,
+				seq INTEGER NOT NULL,
+				decision TEXT NOT NULL,
+				done INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (job_id, seq),
+				FOREIGN KEY (job_id) REFERENCES resume_plans(job_id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     9,
+		description: "sync action log with correlation IDs",
+		statements: []string{
+			`ALTER TABLE sync_history ADD COLUMN run_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE IF NOT EXISTS sync_actions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				job_id INTEGER NOT NULL,
+				run_id TEXT NOT NULL,
+				op_id TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL,
+				path TEXT NOT NULL,
+				status TEXT NOT NULL,
+				bytes INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				timestamp INTEGER NOT NULL,
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sync_actions_run_id ON sync_actions(run_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_sync_actions_job_path ON sync_actions(job_id, path)`,
+		},
+	},
+	{
+		version:     10,
+		description: "structured remote endpoint of sync jobs",
+		statements: []string{
+			`ALTER TABLE sync_jobs ADD COLUMN remote_endpoint TEXT`,
+		},
+		backfill: backfillRemoteEndpoints,
+	},
+	{
+		version:     11,
+		description: "selective sync folders",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS sync_selection (
+				job_id INTEGER NOT NULL,
+				path TEXT NOT NULL,
+				mode TEXT NOT NULL CHECK (mode IN ('include', 'exclude')),
+				PRIMARY KEY (job_id, path),
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     12,
+		description: "conflict copies",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS conflicts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				job_id INTEGER NOT NULL,
+				run_id TEXT NOT NULL,
+				path TEXT NOT NULL,
+				copy_path TEXT NOT NULL,
+				winner TEXT NOT NULL CHECK (winner IN ('local', 'remote')),
+				timestamp INTEGER NOT NULL,
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_conflicts_job ON conflicts(job_id, timestamp)`,
+		},
+	},
+	{
+		version:     13,
+		description: "remote owners",
+		statements: []string{
+			`ALTER TABLE files_state ADD COLUMN remote_owner TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE sync_actions ADD COLUMN changed_by TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE conflicts ADD COLUMN changed_by TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		version:     14,
+		description: "negotiated security per server",
+		statements: []string{
+			`ALTER TABLE smb_servers ADD COLUMN security_level TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE smb_servers ADD COLUMN require_security INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     15,
+		description: "require encryption per server",
+		statements: []string{
+			`ALTER TABLE smb_servers ADD COLUMN require_encryption INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     16,
+		description: "transfer verification",
+		statements: []string{
+			`ALTER TABLE files_state ADD COLUMN verified_at INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     17,
+		description: "local listing snapshot for change journal scans",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS local_snapshots (
+				job_id INTEGER NOT NULL,
+				path TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				mtime INTEGER NOT NULL,
+				hash TEXT,
+				attributes INTEGER NOT NULL DEFAULT 0,
+				excluded INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (job_id, path),
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS scan_checkpoints (
+				job_id INTEGER PRIMARY KEY,
+				root TEXT NOT NULL,
+				volume TEXT NOT NULL,
+				journal_id INTEGER NOT NULL,
+				usn INTEGER NOT NULL,
+				rules TEXT NOT NULL,
+				scanned_at INTEGER NOT NULL,
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     18,
+		description: "remote listing cache for SMB scans",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS remote_state (
+				job_id INTEGER NOT NULL,
+				path TEXT NOT NULL,
+				is_dir INTEGER NOT NULL DEFAULT 0,
+				size INTEGER NOT NULL,
+				mtime INTEGER NOT NULL,
+				write_time INTEGER NOT NULL DEFAULT 0,
+				change_time INTEGER NOT NULL DEFAULT 0,
+				attributes INTEGER NOT NULL DEFAULT 0,
+				listed_at INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (job_id, path),
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     19,
+		description: "sync history retention by status",
+		statements: []string{
+			// Pruned by InsertSyncHistory, which keeps the last failed run of each job
+			`DROP TRIGGER IF EXISTS cleanup_old_sync_history`,
+		},
+	},
+	{
+		version:     20,
+		description: "transfer integrity statistics per server",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS transfer_integrity (
+				server_id INTEGER NOT NULL,
+				day TEXT NOT NULL,
+				verified INTEGER NOT NULL DEFAULT 0,
+				mismatches INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (server_id, day),
+				FOREIGN KEY (server_id) REFERENCES smb_servers(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     21,
+		description: "append-only audit log of destructive actions",
+		statements: []string{
+			// No foreign key: entries outlive their job
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				job_id INTEGER NOT NULL DEFAULT 0,
+				run_id TEXT NOT NULL DEFAULT '',
+				op_id TEXT NOT NULL DEFAULT '',
+				event TEXT NOT NULL,
+				path TEXT NOT NULL DEFAULT '',
+				detail TEXT NOT NULL DEFAULT '',
+				actor TEXT NOT NULL DEFAULT '',
+				timestamp INTEGER NOT NULL,
+				prev_hash TEXT NOT NULL,
+				hash TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_job ON audit_log(job_id, timestamp)`,
+			`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+			BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`,
+			`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+			BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`,
+		},
+	},
+	{
+		version:     22,
+		description: "files still failing after their retries",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS failed_files (
+				job_id INTEGER NOT NULL,
+				path TEXT NOT NULL,
+				action TEXT NOT NULL,
+				error TEXT NOT NULL DEFAULT '',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				runs INTEGER NOT NULL DEFAULT 0,
+				first_failed_at INTEGER NOT NULL,
+				last_failed_at INTEGER NOT NULL,
+				PRIMARY KEY (job_id, path),
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     23,
+		description: "remote space usage by folder",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS remote_usage (
+				job_id INTEGER PRIMARY KEY,
+				scanned_at INTEGER NOT NULL,
+				total_files INTEGER NOT NULL DEFAULT 0,
+				total_bytes INTEGER NOT NULL DEFAULT 0,
+				folders TEXT NOT NULL DEFAULT '[]',
+				largest_files TEXT NOT NULL DEFAULT '[]',
+				FOREIGN KEY (job_id) REFERENCES sync_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version:     24,
+		description: "drop job statistics view",
+		statements: []string{
+			// The view only sees the shared files_state, not the job stores:
+			// GetJobStatistics reads the store of each job
+			`DROP VIEW IF EXISTS job_statistics`,
+		},
+	},
+	{
+		version:     25,
+		description: "network and idle trigger modes",
+		backfill:    widenTriggerModes,
+	},
+}
+
+// CurrentSchemaVersion returns the schema version after all migrations.
+func CurrentSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 1
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// migrate applies all migrations newer than the given version.
+// Each migration runs in its own transaction together with the version bump.
+func (db *DB) migrate(version int) error {
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		err := db.Transaction(func(tx *sql.Tx) error {
+			for _, stmt := range m.statements {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("exec %q: %w", stmt, err)
+				}
+			}
+			if m.backfill != nil {
+				if err := m.backfill(tx); err != nil {
+					return fmt.Errorf("backfill: %w", err)
+				}
+			}
+			_, err := tx.Exec(`
+				INSERT INTO db_metadata (key, value) VALUES ('schema_version', ?)
+				ON CONFLICT(key) DO UPDATE SET value = excluded.value
+			`, strconv.Itoa(m.version))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+	}
+	return nil
+}
+
+// trigger_mode constraint of databases created before network and idle jobs,
+// and the one accepting them
+const (
+	legacyTriggerModeCheck = "CHECK(trigger_mode IN ('realtime', 'interval', 'scheduled', 'manual'))"
+	triggerModeCheck       = "CHECK(trigger_mode IN ('realtime', 'interval', 'scheduled', 'manual', 'network', 'idle'))"
+)
+
+// widenTriggerModes rebuilds sync_jobs with the wider trigger_mode constraint
+// (SQLite can't alter a constraint), then moves the jobs stored as "manual"
+// with trigger_params "network" or "idle" to their real mode.
+func widenTriggerModes(tx *sql.Tx) error {
+	var create string
+	err := tx.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'sync_jobs'`).Scan(&create)
+	if err != nil {
+		return fmt.Errorf("read sync_jobs schema: %w", err)
+	}
+
+	// Databases created with the current schema.sql already accept them
+	if strings.Contains(create, legacyTriggerModeCheck) {
+		// Same columns under a new name (the stored name may be quoted)
+		columns := strings.Index(create, "(")
+		if columns < 0 {
+			return fmt.Errorf("unexpected sync_jobs schema: %s", create)
+		}
+		create = "CREATE TABLE sync_jobs_new " + strings.Replace(create[columns:], legacyTriggerModeCheck, triggerModeCheck, 1)
+
+		statements := []string{
+			// The view would make the rename fail while sync_jobs is missing
+			`DROP VIEW IF EXISTS recent_sync_history`,
+			create,
+			`INSERT INTO sync_jobs_new SELECT * FROM sync_jobs`,
+			`DROP TABLE sync_jobs`,
+			`ALTER TABLE sync_jobs_new RENAME TO sync_jobs`,
+			// Dropped with the old table (see schema.sql)
+			`CREATE INDEX IF NOT EXISTS idx_sync_jobs_enabled ON sync_jobs(enabled)`,
+			`CREATE INDEX IF NOT EXISTS idx_sync_jobs_next_run ON sync_jobs(next_run) WHERE enabled = 1`,
+			`CREATE TRIGGER IF NOT EXISTS update_sync_jobs_timestamp
+			AFTER UPDATE ON sync_jobs
+			BEGIN
+				UPDATE sync_jobs SET updated_at = strftime('%s', 'now') WHERE id = NEW.id;
+			END`,
+			`CREATE VIEW IF NOT EXISTS recent_sync_history AS
+			SELECT
+				sh.*,
+				sj.name as job_name
+			FROM sync_history sh
+			JOIN sync_jobs sj ON sj.id = sh.job_id
+			WHERE sh.timestamp >= strftime('%s', 'now', '-30 days')
+			ORDER BY sh.timestamp DESC`,
+		}
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("rebuild sync_jobs: %w", err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE sync_jobs SET trigger_mode = trigger_params
+		WHERE trigger_mode = 'manual' AND trigger_params IN ('network', 'idle')`)
+	if err != nil {
+		return fmt.Errorf("update trigger modes: %w", err)
+	}
+	return nil
+}
+
+// backfillRemoteEndpoints fills the remote endpoint of the jobs created before
+// it was stored, from their remote path and the port of their server.
+func backfillRemoteEndpoints(tx *sql.Tx) error {
+	ports := make(map[string]int)
+	rows, err := tx.Query(`SELECT host, COALESCE(port, 0) FROM smb_servers`)
+	if err != nil {
+		return fmt.Errorf("query servers: %w", err)
+	}
+	for rows.Next() {
+		var host string
+		var port int
+		if err := rows.Scan(&host, &port); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan server: %w", err)
+		}
+		ports[strings.ToLower(host)] = port
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`SELECT id, remote_path FROM sync_jobs WHERE remote_endpoint IS NULL`)
+	if err != nil {
+		return fmt.Errorf("query sync jobs: %w", err)
+	}
+	endpoints := make(map[int64]smbpath.RemoteEndpoint)
+	for rows.Next() {
+		var id int64
+		var remotePath string
+		if err := rows.Scan(&id, &remotePath); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan sync job: %w", err)
+		}
+		endpoint := smbpath.EndpointFromUNC(remotePath, 0)
+		if port := ports[strings.ToLower(endpoint.Host)]; port != smbpath.DefaultPort {
+			endpoint.Port = port
+		}
+		endpoints[id] = endpoint
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("iterate sync jobs: %w", err)
+	}
+	rows.Close()
+
+	for id, endpoint := range endpoints {
+		data, err := json.Marshal(endpoint)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE sync_jobs SET remote_endpoint = ? WHERE id = ?`, string(data), id); err != nil {
+			return fmt.Errorf("update sync job %d: %w", id, err)
+		}
+	}
+	return nil
+}
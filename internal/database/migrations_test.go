@@ -0,0 +1,709 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+func TestOpen_AppliesMigrations(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	version, err := db.GetMetadata("schema_version")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if version != strconv.Itoa(CurrentSchemaVersion()) {
+		t.Errorf("schema_version = %s, want %d", version, CurrentSchemaVersion())
+	}
+
+	// Migrating again must be a no-op
+	if err := db.checkSchemaVersion(); err != nil {
+		t.Errorf("second migration pass failed: %v", err)
+	}
+}
+
+func TestOpen_JobStatisticsViewStaysDropped(t *testing.T) {
+	cfg := Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	}
+
+	// The schema runs again on each start, it must not bring the view back
+	for i := 0; i < 2; i++ {
+		db, err := Open(cfg)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		var count int
+		err = db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'view' AND name = 'job_statistics'`).Scan(&count)
+		db.Close()
+		if err != nil {
+			t.Fatalf("query sqlite_master: %v", err)
+		}
+		if count != 0 {
+			t.Fatalf("job_statistics view exists after open %d", i+1)
+		}
+	}
+}
+
+func TestSMBServer_MTimeSource(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	server := &SMBServer{Name: "nas", Host: "nas.local", Port: 445, Username: "user"}
+	if err := db.CreateSMBServer(server); err != nil {
+		t.Fatalf("CreateSMBServer failed: %v", err)
+	}
+
+	got, err := db.GetSMBServerByHost("NAS.local")
+	if err != nil || got == nil {
+		t.Fatalf("GetSMBServerByHost failed: %v", err)
+	}
+	if got.MTimeSource != "write" {
+		t.Errorf("default MTimeSource = %q, want write", got.MTimeSource)
+	}
+
+	got.MTimeSource = "change"
+	if err := db.UpdateSMBServer(got); err != nil {
+		t.Fatalf("UpdateSMBServer failed: %v", err)
+	}
+	got, _ = db.GetSMBServer(server.ID)
+	if got.MTimeSource != "change" {
+		t.Errorf("MTimeSource = %q, want change", got.MTimeSource)
+	}
+}
+
+func TestSMBServer_SecurityLevel(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	server := &SMBServer{Name: "nas", Host: "nas.local", Port: 445, Username: "user", RequireSecurity: true, RequireEncryption: true}
+	if err := db.CreateSMBServer(server); err != nil {
+		t.Fatalf("CreateSMBServer failed: %v", err)
+	}
+	if err := db.SetSMBServerSecurityLevel(server.ID, "encrypted"); err != nil {
+		t.Fatalf("SetSMBServerSecurityLevel failed: %v", err)
+	}
+
+	// Editing the server keeps the recorded level
+	got, _ := db.GetSMBServer(server.ID)
+	got.Name = "renamed"
+	if err := db.UpdateSMBServer(got); err != nil {
+		t.Fatalf("UpdateSMBServer failed: %v", err)
+	}
+	got, _ = db.GetSMBServer(server.ID)
+	if got.SecurityLevel != "encrypted" || !got.RequireSecurity || !got.RequireEncryption {
+		t.Errorf("got level %q, require %v, encryption %v, want encrypted and true", got.SecurityLevel, got.RequireSecurity, got.RequireEncryption)
+	}
+}
+
+func TestGetExclusionGroups_Seeded(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	groups, err := db.GetExclusionGroups()
+	if err != nil {
+		t.Fatalf("GetExclusionGroups failed: %v", err)
+	}
+
+	byName := make(map[string]*ExclusionGroup)
+	for _, g := range groups {
+		byName[g.Name] = g
+	}
+	for _, name := range []string{"system", "vcs", "dependencies"} {
+		g, ok := byName[name]
+		if !ok {
+			t.Fatalf("group %q not seeded", name)
+		}
+		if len(g.Patterns) == 0 {
+			t.Errorf("group %q has no patterns", name)
+		}
+		if !g.IsEnabled(nil) {
+			t.Errorf("group %q should be enabled by default", name)
+		}
+	}
+
+	if byName["vcs"].IsEnabled(map[string]bool{"vcs": false}) {
+		t.Error("vcs group should be disabled by job override")
+	}
+	if !byName["system"].IsEnabled(map[string]bool{"vcs": false}) {
+		t.Error("override of another group should not disable system group")
+	}
+}
+
+func TestExclusionGroupsMigration_KeepsDefaults(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	// A job created before the exclusion groups, upgraded to them
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share\docs`,
+		ServerCredentialID: "host_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		NetworkConditions:  `{"max_upload_kbps":100}`,
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+	if _, err := db.conn.Exec(`DROP TABLE exclusion_group_patterns; DROP TABLE exclusion_groups`); err != nil {
+		t.Fatalf("drop exclusion groups: %v", err)
+	}
+	for _, m := range migrations {
+		if m.version != 4 {
+			continue
+		}
+		err := db.Transaction(func(tx *sql.Tx) error {
+			for _, stmt := range m.statements {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("migration 4 failed: %v", err)
+		}
+	}
+
+	// The job options are left alone, so the groups keep applying the
+	// default exclusions (.git/, node_modules/, ...) to the job
+	var stored string
+	if err := db.conn.QueryRow(`SELECT network_conditions FROM sync_jobs WHERE id = ?`, job.ID).Scan(&stored); err != nil {
+		t.Fatalf("read options: %v", err)
+	}
+	var opts struct {
+		ExclusionGroups map[string]bool `json:"exclusion_groups"`
+	}
+	if err := json.Unmarshal([]byte(stored), &opts); err != nil {
+		t.Fatalf("parse options %s: %v", stored, err)
+	}
+	if opts.ExclusionGroups != nil {
+		t.Errorf("exclusion groups set on upgrade: %v", opts.ExclusionGroups)
+	}
+
+	groups, err := db.GetExclusionGroups()
+	if err != nil {
+		t.Fatalf("GetExclusionGroups failed: %v", err)
+	}
+	excluded := make(map[string]bool)
+	for _, g := range groups {
+		if g.IsEnabled(opts.ExclusionGroups) {
+			for _, p := range g.Patterns {
+				excluded[p] = true
+			}
+		}
+	}
+	for _, pattern := range []string{".git/", "node_modules/"} {
+		if !excluded[pattern] {
+			t.Errorf("%s no longer excluded after upgrade", pattern)
+		}
+	}
+}
+
+func TestWidenTriggerModes(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	// A network job stored the old way, with its sync history
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share\docs`,
+		ServerCredentialID: "host_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		TriggerParams:      "network",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+	if _, err := db.conn.Exec(`INSERT INTO sync_history (job_id, timestamp, duration, status, created_at)
+		VALUES (?, 1, 1, 'success', 1)`, job.ID); err != nil {
+		t.Fatalf("insert history: %v", err)
+	}
+
+	// Back to the constraint of databases created before network and idle modes
+	var create string
+	if err := db.conn.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'sync_jobs'`).Scan(&create); err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	legacy := strings.Replace(create, triggerModeCheck, legacyTriggerModeCheck, 1)
+	legacy = strings.Replace(legacy, "CREATE TABLE sync_jobs", "CREATE TABLE sync_jobs_old", 1)
+	for _, stmt := range []string{
+		`DROP VIEW recent_sync_history`,
+		legacy,
+		`INSERT INTO sync_jobs_old SELECT * FROM sync_jobs`,
+		`DROP TABLE sync_jobs`,
+		`ALTER TABLE sync_jobs_old RENAME TO sync_jobs`,
+	} {
+		if _, err := db.conn.Exec(stmt); err != nil {
+			t.Fatalf("restore legacy schema: %v", err)
+		}
+	}
+	if _, err := db.conn.Exec(`UPDATE sync_jobs SET trigger_mode = 'network' WHERE id = ?`, job.ID); err == nil {
+		t.Fatal("legacy constraint should refuse trigger_mode 'network'")
+	}
+
+	if err := db.Transaction(widenTriggerModes); err != nil {
+		t.Fatalf("widenTriggerModes failed: %v", err)
+	}
+
+	got, err := db.GetSyncJob(job.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetSyncJob failed: %v", err)
+	}
+	if got.TriggerMode != "network" {
+		t.Errorf("TriggerMode = %q, want network", got.TriggerMode)
+	}
+	if _, err := db.conn.Exec(`UPDATE sync_jobs SET trigger_mode = 'idle' WHERE id = ?`, job.ID); err != nil {
+		t.Errorf("trigger_mode 'idle' refused after migration: %v", err)
+	}
+
+	var history int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM sync_history WHERE job_id = ?`, job.ID).Scan(&history); err != nil || history != 1 {
+		t.Errorf("sync history after rebuild = %d, %v, want 1", history, err)
+	}
+	for _, name := range []string{"idx_sync_jobs_enabled", "update_sync_jobs_timestamp", "recent_sync_history"} {
+		var n int
+		if err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ?`, name).Scan(&n); err != nil || n != 1 {
+			t.Errorf("%s missing after rebuild", name)
+		}
+	}
+}
+
+func TestRemoteSnapshot_ReplaceAndGet(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	entries, err := db.GetRemoteSnapshot(job.ID)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected empty snapshot, got %d entries (err=%v)", len(entries), err)
+	}
+
+	err = db.ReplaceRemoteSnapshot(job.ID, []*RemoteSnapshotEntry{
+		{Path: "a.txt", Size: 10, MTime: 1000, Hash: "abc"},
+		{Path: "dir/b.txt", Size: 20, MTime: 2000},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceRemoteSnapshot failed: %v", err)
+	}
+
+	entries, _ = db.GetRemoteSnapshot(job.ID)
+	if len(entries) != 2 || entries["a.txt"].Hash != "abc" || entries["dir/b.txt"].Size != 20 {
+		t.Errorf("unexpected snapshot: %+v", entries)
+	}
+
+	// Replacing drops entries missing from the new listing
+	if err := db.ReplaceRemoteSnapshot(job.ID, []*RemoteSnapshotEntry{{Path: "a.txt", Size: 11, MTime: 1001}}); err != nil {
+		t.Fatalf("ReplaceRemoteSnapshot failed: %v", err)
+	}
+	entries, _ = db.GetRemoteSnapshot(job.ID)
+	if len(entries) != 1 || entries["a.txt"].Size != 11 {
+		t.Errorf("unexpected snapshot after replace: %+v", entries)
+	}
+}
+
+func TestLocalSnapshot_ReplaceAndUpdate(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	if cp, err := db.GetScanCheckpoint(job.ID); err != nil || cp != nil {
+		t.Fatalf("expected no checkpoint, got %+v (err=%v)", cp, err)
+	}
+
+	cp := &ScanCheckpoint{JobID: job.ID, Root: `C:\data`, Volume: "C:", JournalID: 7, USN: 100, Rules: "r1", ScannedAt: 1000}
+	err = db.ReplaceLocalSnapshot(cp, []*LocalSnapshotEntry{
+		{Path: "a.txt", Size: 10, MTime: 1000, Hash: "abc", Attributes: 0x20},
+		{Path: "dir/b.txt", Size: 20, MTime: 2000},
+		{Path: "big.iso", Excluded: true},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceLocalSnapshot failed: %v", err)
+	}
+
+	// Updating replaces changed entries, drops removed ones and moves the checkpoint
+	cp.USN = 150
+	err = db.UpdateLocalSnapshot(cp, []*LocalSnapshotEntry{{Path: "a.txt", Size: 11, MTime: 1001, Hash: "abd"}}, []string{"dir/b.txt"})
+	if err != nil {
+		t.Fatalf("UpdateLocalSnapshot failed: %v", err)
+	}
+
+	entries, err := db.GetLocalSnapshot(job.ID)
+	if err != nil {
+		t.Fatalf("GetLocalSnapshot failed: %v", err)
+	}
+	if len(entries) != 2 || entries["a.txt"].Hash != "abd" || entries["a.txt"].Attributes != 0 || !entries["big.iso"].Excluded {
+		t.Errorf("unexpected snapshot: %+v", entries)
+	}
+	got, err := db.GetScanCheckpoint(job.ID)
+	if err != nil || got == nil || got.USN != 150 || got.JournalID != 7 || got.Rules != "r1" {
+		t.Errorf("unexpected checkpoint: %+v (err=%v)", got, err)
+	}
+}
+
+func TestRemoteState_ReplaceAndGet(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	err = db.ReplaceRemoteState(job.ID, []*RemoteStateEntry{
+		{Path: "", IsDir: true, MTime: 500, ListedAt: 1000},
+		{Path: "dir", IsDir: true, MTime: 600},
+		{Path: "a.txt", Size: 10, MTime: 1000, WriteTime: 1000, ChangeTime: 1100, Attributes: 0x20},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceRemoteState failed: %v", err)
+	}
+
+	entries, err := db.GetRemoteState(job.ID)
+	if err != nil {
+		t.Fatalf("GetRemoteState failed: %v", err)
+	}
+	if len(entries) != 3 || !entries[""].IsDir || entries[""].ListedAt != 1000 || entries["dir"].ListedAt != 0 ||
+		entries["a.txt"].IsDir || entries["a.txt"].ChangeTime != 1100 || entries["a.txt"].Attributes != 0x20 {
+		t.Errorf("unexpected remote state: %+v", entries)
+	}
+
+	// Deleting the job drops its cache
+	if err := db.DeleteSyncJob(job.ID); err != nil {
+		t.Fatalf("DeleteSyncJob failed: %v", err)
+	}
+	if entries, err := db.GetRemoteState(job.ID); err != nil || len(entries) != 0 {
+		t.Errorf("expected empty remote state after delete, got %d entries (err=%v)", len(entries), err)
+	}
+}
+
+func TestUploadVetoes(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	if err := db.SaveUploadVeto(&UploadVeto{JobID: job.ID, Path: "dir/evil.exe", Hash: "abc", Reason: "Trojan:Win32/Test"}); err != nil {
+		t.Fatalf("SaveUploadVeto failed: %v", err)
+	}
+
+	vetoes, err := db.GetUploadVetoes(job.ID)
+	if err != nil {
+		t.Fatalf("GetUploadVetoes failed: %v", err)
+	}
+	veto := vetoes["dir/evil.exe"]
+	if len(vetoes) != 1 || veto == nil || veto.Hash != "abc" || veto.Reason != "Trojan:Win32/Test" || veto.VetoedAt.IsZero() {
+		t.Fatalf("unexpected vetoes: %+v", vetoes)
+	}
+
+	if err := db.DeleteUploadVeto(job.ID, "dir/evil.exe"); err != nil {
+		t.Fatalf("DeleteUploadVeto failed: %v", err)
+	}
+	vetoes, _ = db.GetUploadVetoes(job.ID)
+	if len(vetoes) != 0 {
+		t.Errorf("expected no vetoes after delete, got %d", len(vetoes))
+	}
+}
+
+func TestSyncJob_RemoteEndpoint(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\fe80::1\share\docs`,
+		ServerCredentialID: "fe80::1_user",
+		Endpoint:           smbpath.RemoteEndpoint{Host: "fe80::1", Port: 1445},
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	got, err := db.GetSyncJob(job.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetSyncJob failed: %v", err)
+	}
+	want := smbpath.RemoteEndpoint{Host: "fe80::1", Port: 1445, Share: "share", Path: "docs", CredentialID: "fe80::1"}
+	if got.Endpoint != want {
+		t.Errorf("Endpoint = %+v, want %+v", got.Endpoint, want)
+	}
+	if got.Endpoint.Addr() != "[fe80::1]:1445" {
+		t.Errorf("Addr() = %q", got.Endpoint.Addr())
+	}
+
+	// Moving the job to another server drops the port of the old one
+	got.RemotePath = `\\nas\share`
+	if err := db.UpdateSyncJob(got); err != nil {
+		t.Fatalf("UpdateSyncJob failed: %v", err)
+	}
+	got, _ = db.GetSyncJob(job.ID)
+	want = smbpath.RemoteEndpoint{Host: "nas", Share: "share", CredentialID: "nas"}
+	if got.Endpoint != want {
+		t.Errorf("Endpoint after move = %+v, want %+v", got.Endpoint, want)
+	}
+}
+
+func TestBackfillRemoteEndpoints(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.CreateSMBServer(&SMBServer{Name: "nas", Host: "NAS", Port: 1445, Username: "user"}); err != nil {
+		t.Fatalf("CreateSMBServer failed: %v", err)
+	}
+	for i, remote := range []string{`\\nas\share\docs`, `\\10.0.0.2\backup`} {
+		job := &SyncJob{
+			Name:               "job",
+			LocalPath:          `C:\data` + strconv.Itoa(i),
+			RemotePath:         remote,
+			ServerCredentialID: "host_user",
+			SyncMode:           "mirror",
+			TriggerMode:        "manual",
+			ConflictResolution: "recent",
+			Enabled:            true,
+		}
+		if err := db.CreateSyncJob(job); err != nil {
+			t.Fatalf("CreateSyncJob failed: %v", err)
+		}
+	}
+
+	// Rows written before the endpoint was stored
+	if _, err := db.conn.Exec(`UPDATE sync_jobs SET remote_endpoint = NULL`); err != nil {
+		t.Fatalf("reset endpoints: %v", err)
+	}
+	if err := db.Transaction(backfillRemoteEndpoints); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+
+	var stored string
+	if err := db.conn.QueryRow(`SELECT remote_endpoint FROM sync_jobs WHERE remote_path = ?`, `\\nas\share\docs`).Scan(&stored); err != nil {
+		t.Fatalf("read endpoint: %v", err)
+	}
+	if stored != `{"host":"nas","port":1445,"share":"share","path":"docs","credential_id":"nas"}` {
+		t.Errorf("backfilled endpoint = %s", stored)
+	}
+
+	jobs, _ := db.GetAllSyncJobs()
+	for _, job := range jobs {
+		if job.RemotePath == `\\10.0.0.2\backup` && job.Endpoint.Addr() != "10.0.0.2:445" {
+			t.Errorf("Addr() = %q, want 10.0.0.2:445", job.Endpoint.Addr())
+		}
+	}
+}
+
+func TestSyncSelection(t *testing.T) {
+	db, err := Open(Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	job := &SyncJob{
+		Name:               "job",
+		LocalPath:          `C:\data`,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	for _, rule := range []*SelectionRule{
+		{JobID: job.ID, Path: "Photos", Mode: SelectionInclude},
+		{JobID: job.ID, Path: "Photos/Raw", Mode: SelectionInclude},
+		{JobID: job.ID, Path: "Photos/Raw", Mode: SelectionExclude}, // Replaces the include
+	} {
+		if err := db.SaveSelectionRule(rule); err != nil {
+			t.Fatalf("SaveSelectionRule failed: %v", err)
+		}
+	}
+	if err := db.SaveSelectionRule(&SelectionRule{JobID: job.ID, Path: "Docs", Mode: "maybe"}); err == nil {
+		t.Error("expected an error for an invalid mode")
+	}
+
+	rules, err := db.GetSyncSelection(job.ID)
+	if err != nil {
+		t.Fatalf("GetSyncSelection failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Path != "Photos" || rules[0].Mode != SelectionInclude ||
+		rules[1].Path != "Photos/Raw" || rules[1].Mode != SelectionExclude {
+		t.Fatalf("unexpected rules: %+v %+v", rules[0], rules[1])
+	}
+
+	removed, err := db.DeleteSelectionRule(job.ID, "Photos/Raw")
+	if err != nil || !removed {
+		t.Fatalf("DeleteSelectionRule = %v, %v", removed, err)
+	}
+	if removed, _ := db.DeleteSelectionRule(job.ID, "Photos/Raw"); removed {
+		t.Error("expected nothing to remove the second time")
+	}
+
+	// Rules go away with their job
+	if err := db.DeleteSyncJob(job.ID); err != nil {
+		t.Fatalf("DeleteSyncJob failed: %v", err)
+	}
+	rules, _ = db.GetSyncSelection(job.ID)
+	if len(rules) != 0 {
+		t.Errorf("expected no rules after deleting the job, got %d", len(rules))
+	}
+}
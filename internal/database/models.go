@@ -1,6 +1,11 @@
 package database
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
 
 // SyncJob représente un job de synchronisation
 type SyncJob struct {
@@ -9,8 +14,9 @@ type SyncJob struct {
 	LocalPath            string    `json:"local_path"`
 	RemotePath           string    `json:"remote_path"`
 	ServerCredentialID   string    `json:"server_credential_id"`
+	Endpoint             smbpath.RemoteEndpoint `json:"endpoint"` // Serveur, port, partage et dossier de RemotePath
 	SyncMode             string    `json:"sync_mode"` // mirror, upload, download, mirror_priority
-	TriggerMode          string    `json:"trigger_mode"` // realtime, interval, scheduled, manual
+	TriggerMode          string    `json:"trigger_mode"` // realtime, interval, scheduled, manual, network, idle
 	TriggerParams        string    `json:"trigger_params,omitempty"` // JSON
 	ConflictResolution   string    `json:"conflict_resolution,omitempty"` // recent, local, remote, both, ask
 	NetworkConditions    string    `json:"network_conditions,omitempty"` // JSON
@@ -21,6 +27,19 @@ type SyncJob struct {
 	UpdatedAt            time.Time `json:"updated_at"`
 }
 
+// RemoteEndpoint returns the endpoint of the job, kept in line with RemotePath:
+// the port and credentials of Endpoint are kept while its host is unchanged.
+func (j *SyncJob) RemoteEndpoint() smbpath.RemoteEndpoint {
+	endpoint := smbpath.EndpointFromUNC(j.RemotePath, 0)
+	if strings.EqualFold(j.Endpoint.Host, endpoint.Host) {
+		endpoint.Port = j.Endpoint.Port
+		if j.Endpoint.CredentialID != "" {
+			endpoint.CredentialID = j.Endpoint.CredentialID
+		}
+	}
+	return endpoint
+}
+
 // FileState représente l'état d'un fichier synchronisé
 type FileState struct {
 	ID           int64   `json:"id"`
@@ -35,6 +54,151 @@ type FileState struct {
 	ErrorMessage *string `json:"error_message,omitempty"`
 	CreatedAt    int64   `json:"created_at"` // Unix timestamp
 	UpdatedAt    int64   `json:"updated_at"` // Unix timestamp
+	// Horodatages distants bruts (débogage de la détection)
+	RemoteWriteTime  *int64 `json:"remote_write_time,omitempty"`  // LastWriteTime côté serveur
+	RemoteChangeTime *int64 `json:"remote_change_time,omitempty"` // ChangeTime côté serveur
+	// Attributs lecture seule / archive au dernier sync (0 = non suivis)
+	Attributes int64 `json:"attributes,omitempty"`
+	// Propriétaire du fichier distant au dernier sync (DOMAINE\utilisateur,
+	// vide si non relevé)
+	RemoteOwner string `json:"remote_owner,omitempty"`
+	// Contenu relu et comparé après le dernier transfert (Unix, 0 = non vérifié)
+	VerifiedAt int64 `json:"verified_at,omitempty"`
+}
+
+// RemoteSnapshotEntry représente un fichier du dernier listing distant d'un job
+// (utilisé pour calculer les placeholders à créer/mettre à jour/supprimer)
+type RemoteSnapshotEntry struct {
+	Path  string `json:"path"`  // Chemin relatif (séparateurs /)
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime"` // Unix timestamp
+	Hash  string `json:"hash,omitempty"`
+}
+
+// LocalSnapshotEntry représente un fichier du dernier parcours local d'un job
+// (rejoué avec le journal de modifications du volume au lieu de reparcourir
+// le dossier)
+type LocalSnapshotEntry struct {
+	Path       string `json:"path"` // Chemin relatif (séparateurs /)
+	Size       int64  `json:"size"`
+	MTime      int64  `json:"mtime"` // Unix, en nanosecondes
+	Hash       string `json:"hash,omitempty"`
+	Attributes int64  `json:"attributes,omitempty"` // Attributs Windows bruts
+	Excluded   bool   `json:"excluded,omitempty"`   // Exclu par une règle du job (ex. taille)
+}
+
+// RemoteStateEntry représente un fichier ou un dossier du dernier listing SMB
+// d'un job (un dossier dont la date de modification n'a pas changé n'est pas
+// relisté)
+type RemoteStateEntry struct {
+	Path       string `json:"path"` // Chemin relatif au dossier parcouru (séparateurs /, "" = ce dossier)
+	IsDir      bool   `json:"is_dir"`
+	Size       int64  `json:"size"`
+	MTime      int64  `json:"mtime"`                 // Unix, en nanosecondes
+	WriteTime  int64  `json:"write_time,omitempty"`  // Unix, en nanosecondes (0 = non fourni)
+	ChangeTime int64  `json:"change_time,omitempty"` // Unix, en nanosecondes (0 = non fourni)
+	Attributes int64  `json:"attributes,omitempty"`  // Attributs Windows bruts
+	ListedAt   int64  `json:"listed_at,omitempty"`   // Dossiers : dernier listing (Unix, 0 = contenu inconnu)
+}
+
+// ScanCheckpoint représente la position du journal de modifications (USN)
+// du volume d'un job lors du parcours qui a produit son instantané local
+type ScanCheckpoint struct {
+	JobID     int64  `json:"job_id"`
+	Root      string `json:"root"`       // Dossier local parcouru
+	Volume    string `json:"volume"`     // Volume du dossier (ex. C:)
+	JournalID int64  `json:"journal_id"` // Identifiant du journal (change s'il est recréé)
+	USN       int64  `json:"usn"`        // Position du journal avant le parcours
+	Rules     string `json:"rules"`      // Empreinte des exclusions et de la sélection appliquées
+	ScannedAt int64  `json:"scanned_at"` // Unix timestamp
+}
+
+// UploadVeto représente un fichier refusé par l'analyse avant upload
+// (exclu des uploads tant que son hash ne change pas)
+type UploadVeto struct {
+	JobID    int64     `json:"job_id"`
+	Path     string    `json:"path"` // Chemin relatif (séparateurs /)
+	Hash     string    `json:"hash"`
+	Reason   string    `json:"reason,omitempty"`
+	VetoedAt time.Time `json:"vetoed_at"`
+}
+
+// FailedFile représente un fichier dont l'action échoue encore après ses
+// nouvelles tentatives (retenté par les synchronisations suivantes)
+type FailedFile struct {
+	JobID         int64     `json:"job_id"`
+	Path          string    `json:"path"`     // Chemin relatif (séparateurs /)
+	Action        string    `json:"action"`   // upload, download, delete_local...
+	Error         string    `json:"error"`    // Dernière erreur
+	Attempts      int       `json:"attempts"` // Tentatives cumulées de toutes les synchronisations
+	Runs          int       `json:"runs"`     // Synchronisations consécutives en échec
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
+
+// RemoteUsage représente l'espace occupé par un job sur le serveur, par
+// dossier de premier niveau, au dernier parcours du partage
+type RemoteUsage struct {
+	JobID        int64        `json:"job_id"`
+	ScannedAt    time.Time    `json:"scanned_at"`
+	TotalFiles   int          `json:"total_files"`
+	TotalBytes   int64        `json:"total_bytes"`
+	Folders      []UsageEntry `json:"folders"`       // Dossiers de premier niveau, du plus gros au plus petit
+	LargestFiles []UsageEntry `json:"largest_files"` // Plus gros fichiers, du plus gros au plus petit
+}
+
+// UsageEntry représente un dossier ou un fichier de RemoteUsage
+type UsageEntry struct {
+	Path  string `json:"path"`  // Chemin relatif (séparateurs /, "" = fichiers à la racine du job)
+	Files int    `json:"files"` // Fichiers contenus (1 pour un fichier)
+	Bytes int64  `json:"bytes"`
+}
+
+// TransferIntegrity compte les transferts relus après coup sur un serveur
+// (vérification des transferts) et ceux relus différents de leur source
+type TransferIntegrity struct {
+	Verified   int64 `json:"verified"`   // Transferts relus identiques à leur source
+	Mismatches int64 `json:"mismatches"` // Tentatives relues différentes (corruption)
+}
+
+// Rate retourne la part des tentatives corrompues (0 si aucune vérification)
+func (ti TransferIntegrity) Rate() float64 {
+	total := ti.Verified + ti.Mismatches
+	if total == 0 {
+		return 0
+	}
+	return float64(ti.Mismatches) / float64(total)
+}
+
+// Modes d'une règle de synchronisation sélective
+const (
+	SelectionInclude = "include"
+	SelectionExclude = "exclude"
+)
+
+// SelectionRule représente un dossier inclus ou exclu de la synchronisation
+// sélective d'un job (la règle du dossier le plus profond l'emporte)
+type SelectionRule struct {
+	JobID int64  `json:"job_id"`
+	Path  string `json:"path"` // Dossier relatif au job (séparateurs /)
+	Mode  string `json:"mode"` // SelectionInclude ou SelectionExclude
+}
+
+// ResumePlan représente les actions d'un sync interrompu, rejouées au
+// prochain lancement sans rescanner
+type ResumePlan struct {
+	JobID     int64           `json:"job_id"`
+	Mode      string          `json:"mode"`
+	Subtree   string          `json:"subtree,omitempty"`
+	Total     int             `json:"total"`   // Nombre d'actions du plan
+	CreatedAt time.Time       `json:"created_at"`
+	Pending   []*ResumeAction `json:"pending"` // Actions pas encore terminées, dans l'ordre du plan
+}
+
+// ResumeAction représente une action d'un plan de reprise
+type ResumeAction struct {
+	Seq      int    `json:"seq"`      // Position dans le plan
+	Decision string `json:"decision"` // Décision encodée en JSON
 }
 
 // Exclusion représente une règle d'exclusion
@@ -48,6 +212,23 @@ type Exclusion struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// ExclusionGroup représente une catégorie d'exclusions prédéfinie (corbeille, .git, node_modules...)
+// activable ou désactivable par job
+type ExclusionGroup struct {
+	Name           string   `json:"name"`
+	Label          string   `json:"label"`
+	DefaultEnabled bool     `json:"default_enabled"`
+	Patterns       []string `json:"patterns"`
+}
+
+// IsEnabled indique si le groupe est actif compte tenu des surcharges du job (nom -> actif)
+func (g *ExclusionGroup) IsEnabled(overrides map[string]bool) bool {
+	if enabled, ok := overrides[g.Name]; ok {
+		return enabled
+	}
+	return g.DefaultEnabled
+}
+
 // SyncHistory représente une entrée d'historique de synchronisation
 type SyncHistory struct {
 	ID               int64     `json:"id"`
@@ -59,9 +240,64 @@ type SyncHistory struct {
 	Duration         int       `json:"duration"` // En secondes
 	Status           string    `json:"status"` // success, partial, failed
 	ErrorSummary     string    `json:"error_summary,omitempty"`
+	RunID            string    `json:"run_id,omitempty"` // Identifiant du run dans les logs
 	CreatedAt        time.Time `json:"created_at"`
 }
 
+// SyncActionRecord représente une action exécutée pendant un sync, avec les
+// identifiants de corrélation des logs (run_id, op_id)
+type SyncActionRecord struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"`
+	RunID     string    `json:"run_id"`
+	OpID      string    `json:"op_id,omitempty"` // Vide pour les actions refusées avant exécution
+	Action    string    `json:"action"`
+	Path      string    `json:"path"`
+	Status    string    `json:"status"` // success, failed, skipped, vetoed
+	Bytes     int64     `json:"bytes"`
+	Error     string    `json:"error,omitempty"`
+	ChangedBy string    `json:"changed_by,omitempty"` // Propriétaire de la version distante (DOMAINE\utilisateur)
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ConflictRecord représente un conflit résolu dont la version perdante a été
+// gardée à côté de la gagnante, pour que l'utilisateur puisse la revoir
+type ConflictRecord struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"`
+	RunID     string    `json:"run_id"`
+	Path      string    `json:"path"`      // Fichier en conflit (relatif au job)
+	CopyPath  string    `json:"copy_path"` // Copie de la version perdante (relative au job)
+	Winner    string    `json:"winner"`    // local, remote
+	ChangedBy string    `json:"changed_by,omitempty"` // Propriétaire de la version distante (DOMAINE\utilisateur)
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Événements du journal d'audit (actions destructrices)
+const (
+	AuditDeleteLocal     = "delete_local"     // Fichier local supprimé
+	AuditDeleteRemote    = "delete_remote"    // Fichier distant supprimé
+	AuditOverwriteLocal  = "overwrite_local"  // Fichier local remplacé par la version distante
+	AuditOverwriteRemote = "overwrite_remote" // Fichier distant remplacé par la version locale
+	AuditConflict        = "conflict"         // Conflit résolu automatiquement
+)
+
+// AuditEntry représente une entrée du journal d'audit, chaînée à la
+// précédente par son hash : modifier ou retirer une entrée casse la chaîne
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	JobID     int64     `json:"job_id"` // 0 = hors job
+	RunID     string    `json:"run_id,omitempty"`
+	OpID      string    `json:"op_id,omitempty"`
+	Event     string    `json:"event"`
+	Path      string    `json:"path,omitempty"` // Relatif au job
+	Detail    string    `json:"detail,omitempty"`
+	Actor     string    `json:"actor,omitempty"` // Compte et poste ayant exécuté l'action (DOMAINE\utilisateur@poste)
+	Timestamp time.Time `json:"timestamp"`
+	PrevHash  string    `json:"prev_hash"` // Hash de l'entrée précédente ("" pour la première)
+	Hash      string    `json:"hash"`      // SHA-256 de PrevHash et du contenu de l'entrée
+}
+
 // SMBServer représente un serveur SMB configuré (sans share - choisi au niveau job)
 type SMBServer struct {
 	ID                     int64      `json:"id"`
@@ -74,6 +310,10 @@ type SMBServer struct {
 	SMBVersion             string     `json:"smb_version,omitempty"`
 	LastConnectionTest     *time.Time `json:"last_connection_test,omitempty"`
 	LastConnectionStatus   string     `json:"last_connection_status,omitempty"`
+	MTimeSource            string     `json:"mtime_source"` // Horodatage distant utilisé: write, change
+	SecurityLevel          string     `json:"security_level,omitempty"` // Meilleure sécurité négociée jusqu'ici: none, signed, encrypted
+	RequireSecurity        bool       `json:"require_security"` // Refuse les connexions moins sécurisées que SecurityLevel
+	RequireEncryption      bool       `json:"require_encryption"` // Refuse les connexions non chiffrées (SMB3)
 	CreatedAt              time.Time  `json:"created_at"`
 	UpdatedAt              time.Time  `json:"updated_at"`
 }
@@ -82,14 +322,21 @@ type SMBServer struct {
 type OfflineQueueItem struct {
 	ID         int64     `json:"id"`
 	JobID      int64     `json:"job_id"`
-	FilePath   string    `json:"file_path"`
+	FilePath   string    `json:"file_path"` // Chemin relatif (séparateurs /)
 	Operation  string    `json:"operation"` // upload, download, delete
 	Priority   int       `json:"priority"`
-	RetryCount int       `json:"retry_count"`
+	RetryCount int       `json:"retry_count"` // Rejeux échoués (serveur toujours injoignable)
 	LastError  string    `json:"last_error,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// Opérations de la file d'attente hors-ligne
+const (
+	OfflineUpload   = "upload"   // Fichier créé ou modifié localement
+	OfflineDownload = "download" // Fichier à récupérer du serveur
+	OfflineDelete   = "delete"   // Fichier supprimé ou déplacé localement
+)
+
 // JobStatistics représente les statistiques d'un job
 type JobStatistics struct {
 	ID               int64      `json:"id"`
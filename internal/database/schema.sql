@@ -10,7 +10,7 @@ CREATE TABLE IF NOT EXISTS sync_jobs (
     remote_path TEXT NOT NULL,
     server_credential_id TEXT NOT NULL, -- Référence au keystore système
     sync_mode TEXT NOT NULL CHECK(sync_mode IN ('mirror', 'upload', 'download', 'mirror_priority')),
-    trigger_mode TEXT NOT NULL CHECK(trigger_mode IN ('realtime', 'interval', 'scheduled', 'manual')),
+    trigger_mode TEXT NOT NULL CHECK(trigger_mode IN ('realtime', 'interval', 'scheduled', 'manual', 'network', 'idle')),
     trigger_params TEXT, -- JSON: délai, intervalle, horaires, etc.
     conflict_resolution TEXT CHECK(conflict_resolution IN ('recent', 'local', 'remote', 'both', 'ask')),
     network_conditions TEXT, -- JSON: wifi, data, specific_networks
@@ -141,21 +141,6 @@ INSERT OR IGNORE INTO db_metadata (key, value) VALUES ('schema_version', '1');
 INSERT OR IGNORE INTO db_metadata (key, value) VALUES ('created_at', strftime('%s', 'now'));
 INSERT OR IGNORE INTO db_metadata (key, value) VALUES ('app_version', '0.1.0-dev');
 
--- Vue pour statistiques rapides par job
-CREATE VIEW IF NOT EXISTS job_statistics AS
-SELECT
-    sj.id,
-    sj.name,
-    sj.enabled,
-    COUNT(DISTINCT fs.id) as total_files,
-    SUM(fs.size) as total_size,
-    MAX(fs.last_sync) as last_sync_time,
-    (SELECT COUNT(*) FROM files_state WHERE job_id = sj.id AND sync_status = 'error') as files_with_errors,
-    (SELECT COUNT(*) FROM offline_queue WHERE job_id = sj.id) as queued_operations
-FROM sync_jobs sj
-LEFT JOIN files_state fs ON fs.job_id = sj.id
-GROUP BY sj.id;
-
 -- Vue pour historique récent (30 derniers jours)
 CREATE VIEW IF NOT EXISTS recent_sync_history AS
 SELECT
@@ -184,11 +169,3 @@ AFTER UPDATE ON smb_servers
 BEGIN
     UPDATE smb_servers SET updated_at = strftime('%s', 'now') WHERE id = NEW.id;
 END;
-
--- Trigger pour nettoyer les anciennes entrées de l'historique (conservation: 90 jours)
-CREATE TRIGGER IF NOT EXISTS cleanup_old_sync_history
-AFTER INSERT ON sync_history
-BEGIN
-    DELETE FROM sync_history
-    WHERE timestamp < strftime('%s', 'now', '-90 days');
-END;
@@ -0,0 +1,101 @@
+// Package errmsg turns engine and SMB errors into short, localized messages
+// telling the user what to do. The raw error stays available for logs and
+// for the "Details" part of the GUI dialogs.
+package errmsg
+
+import (
+	"strings"
+
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+// DefaultLanguage is used for languages without translations
+const DefaultLanguage = "en"
+
+// messages holds the messages by language then error code. {server} is
+// replaced by the server name.
+var messages = map[string]map[syncpkg.ErrorCode]string{
+	"en": {
+		syncpkg.ErrorCodeAuthFailed:         "The server {server} refused the password — update the credentials.",
+		syncpkg.ErrorCodePasswordExpired:    "The password for {server} has expired — change it, then update the credentials.",
+		syncpkg.ErrorCodeAccountLocked:      "The account is locked on {server} — contact the server administrator.",
+		syncpkg.ErrorCodeCredentialsMissing: "No saved credentials for {server} — edit the server and enter the password.",
+		syncpkg.ErrorCodeServerUnreachable:  "Can't reach {server} — check that it is on and that this PC is on the network.",
+		syncpkg.ErrorCodeShareNotFound:      "The share was not found on {server} — check the share name in the job.",
+		syncpkg.ErrorCodeAccessDenied:       "Access denied — check the permissions of the account on the folder.",
+		syncpkg.ErrorCodeDiskFull:           "Not enough disk space — free some space, then sync again.",
+		syncpkg.ErrorCodeFileLocked:         "A file is open in another program — close it, then sync again.",
+		syncpkg.ErrorCodeFileNotFound:       "A file or folder no longer exists — sync again to refresh the list.",
+		syncpkg.ErrorCodePathTooLong:        "A path is too long for Windows — shorten the folder or file names.",
+		syncpkg.ErrorCodeUploadVetoed:       "A file was blocked by the security scan and was not sent.",
+		syncpkg.ErrorCodeChangeCapExceeded:  "Too many changes at once — review them, then approve the sync.",
+		syncpkg.ErrorCodeRansomware:         "Local files look encrypted — nothing was sent. Check the folder before approving.",
+		syncpkg.ErrorCodeSyncInProgress:     "This job is already syncing — wait for it to finish.",
+		syncpkg.ErrorCodeCancelled:          "The sync was stopped.",
+		syncpkg.ErrorCodeEncryptionRequired: "{server} did not encrypt the connection, which its settings require — nothing was sent. Enable SMB3 encryption on the server.",
+		syncpkg.ErrorCodeVerifyFailed:       "A copied file did not match the original, even after retrying — check the disk and the network, then sync again.",
+		syncpkg.ErrorCodeUnknown:            "The sync failed — see the details.",
+	},
+	"fr": {
+		syncpkg.ErrorCodeAuthFailed:         "Le serveur {server} a refusé le mot de passe — mettez à jour les identifiants.",
+		syncpkg.ErrorCodePasswordExpired:    "Le mot de passe pour {server} a expiré — changez-le, puis mettez à jour les identifiants.",
+		syncpkg.ErrorCodeAccountLocked:      "Le compte est verrouillé sur {server} — contactez l'administrateur du serveur.",
+		syncpkg.ErrorCodeCredentialsMissing: "Aucun identifiant enregistré pour {server} — modifiez le serveur et saisissez le mot de passe.",
+		syncpkg.ErrorCodeServerUnreachable:  "Impossible de joindre {server} — vérifiez qu'il est allumé et que ce PC est sur le réseau.",
+		syncpkg.ErrorCodeShareNotFound:      "Le partage est introuvable sur {server} — vérifiez le nom du partage dans la tâche.",
+		syncpkg.ErrorCodeAccessDenied:       "Accès refusé — vérifiez les droits du compte sur le dossier.",
+		syncpkg.ErrorCodeDiskFull:           "Espace disque insuffisant — libérez de la place, puis relancez la synchronisation.",
+		syncpkg.ErrorCodeFileLocked:         "Un fichier est ouvert dans un autre programme — fermez-le, puis relancez la synchronisation.",
+		syncpkg.ErrorCodeFileNotFound:       "Un fichier ou dossier n'existe plus — relancez la synchronisation pour rafraîchir la liste.",
+		syncpkg.ErrorCodePathTooLong:        "Un chemin est trop long pour Windows — raccourcissez les noms de dossiers ou de fichiers.",
+		syncpkg.ErrorCodeUploadVetoed:       "Un fichier a été bloqué par l'analyse de sécurité et n'a pas été envoyé.",
+		syncpkg.ErrorCodeChangeCapExceeded:  "Trop de modifications d'un coup — vérifiez-les, puis approuvez la synchronisation.",
+		syncpkg.ErrorCodeRansomware:         "Des fichiers locaux semblent chiffrés — rien n'a été envoyé. Vérifiez le dossier avant d'approuver.",
+		syncpkg.ErrorCodeSyncInProgress:     "Cette tâche est déjà en cours de synchronisation — attendez la fin.",
+		syncpkg.ErrorCodeCancelled:          "La synchronisation a été arrêtée.",
+		syncpkg.ErrorCodeEncryptionRequired: "{server} n'a pas chiffré la connexion, ce que ses paramètres exigent — rien n'a été envoyé. Activez le chiffrement SMB3 sur le serveur.",
+		syncpkg.ErrorCodeVerifyFailed:       "Un fichier copié ne correspondait pas à l'original, même après plusieurs essais — vérifiez le disque et le réseau, puis relancez la synchronisation.",
+		syncpkg.ErrorCodeUnknown:            "La synchronisation a échoué — voir les détails.",
+	},
+}
+
+// unnamedServer replaces {server} when the server is not known
+var unnamedServer = map[string]string{
+	"en": "the server",
+	"fr": "le serveur",
+}
+
+// Message returns the user message for err in lang (e.g. "fr", "fr-FR";
+// languages without translations use English). server names the SMB server
+// involved, "" if unknown.
+func Message(err error, lang, server string) string {
+	if err == nil {
+		return ""
+	}
+	return MessageForCode(syncpkg.ErrorCodeOf(err), lang, server)
+}
+
+// MessageForCode returns the user message for an error code (see Message).
+func MessageForCode(code syncpkg.ErrorCode, lang, server string) string {
+	lang = Language(lang)
+	msg, ok := messages[lang][code]
+	if !ok {
+		msg = messages[lang][syncpkg.ErrorCodeUnknown]
+	}
+	if server == "" {
+		server = unnamedServer[lang]
+	}
+	return strings.ReplaceAll(msg, "{server}", server)
+}
+
+// Language returns the supported language matching lang, DefaultLanguage if none.
+func Language(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := messages[lang]; ok {
+		return lang
+	}
+	return DefaultLanguage
+}
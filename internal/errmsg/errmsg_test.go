@@ -0,0 +1,46 @@
+package errmsg
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hirochachacha/go-smb2"
+	syncpkg "github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+func TestMessage(t *testing.T) {
+	logonFailure := fmt.Errorf("failed to create SMB session: %w", &smb2.ResponseError{Code: 0xC000006D})
+
+	tests := []struct {
+		err    error
+		lang   string
+		server string
+		want   string
+	}{
+		{logonFailure, "en", "NAS01", "The server NAS01 refused the password — update the credentials."},
+		{logonFailure, "fr-FR", "NAS01", "Le serveur NAS01 a refusé le mot de passe — mettez à jour les identifiants."},
+		{fmt.Errorf("failed to mount share docs: %w", &smb2.ResponseError{Code: 0xC00000CC}), "de", "",
+			"The share was not found on the server — check the share name in the job."},
+		{fmt.Errorf("run paused: %w", syncpkg.ErrChangeCapExceeded), "fr", "",
+			"Trop de modifications d'un coup — vérifiez-les, puis approuvez la synchronisation."},
+		{fmt.Errorf("failed to connect to nas:445: dial tcp: i/o timeout"), "en", "nas",
+			"Can't reach nas — check that it is on and that this PC is on the network."},
+		{fmt.Errorf("something odd"), "en", "", "The sync failed — see the details."},
+	}
+
+	for _, tt := range tests {
+		if got := Message(tt.err, tt.lang, tt.server); got != tt.want {
+			t.Errorf("Message(%v, %q) = %q, want %q", tt.err, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestMessagesComplete(t *testing.T) {
+	for lang, byCode := range messages {
+		for code := range messages[DefaultLanguage] {
+			if byCode[code] == "" {
+				t.Errorf("missing %s message for %s", lang, code)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package eventlog copies critical events to the Windows Event Log
+// (Application log, source "AnemoneSync"), where enterprise monitoring tools
+// collect them without parsing the text logs.
+package eventlog
+
+import "sync/atomic"
+
+// Source is the event source of AnemoneSync.
+const Source = "AnemoneSync"
+
+// Event IDs, stable: monitoring rules match on them. They stay within 1-1000,
+// the range of the messages of EventCreate.exe used by the registered source.
+const (
+	EventServiceStarted uint32 = 100 // AnemoneSync started
+	EventServiceStopped uint32 = 101 // AnemoneSync stopped
+
+	EventJobFailed           uint32 = 200 // A sync run failed
+	EventAuthFailures        uint32 = 201 // A server refused the credentials several times in a row
+	EventRansomwareSuspected uint32 = 202 // A sync was paused because local files look encrypted
+	EventSecurityDowngrade   uint32 = 203 // A server negotiated less signing/encryption than before
+	EventCorruptionSuspected uint32 = 204 // Too many transfers to a server were read back corrupted
+
+	EventConfigTampered uint32 = 300 // Managed config does not match its signature
+
+	EventAuditEntry uint32 = 400 // A file deleted or overwritten, or a conflict resolved (logging.audit_event_log)
+)
+
+type level int
+
+const (
+	levelInfo level = iota
+	levelWarning
+	levelError
+)
+
+// disabled turns all events off (logging.event_log in config.yaml)
+var disabled atomic.Bool
+
+// SetEnabled turns event logging on or off (on by default).
+func SetEnabled(enabled bool) {
+	disabled.Store(!enabled)
+}
+
+// Info writes an information event.
+func Info(id uint32, msg string) { write(levelInfo, id, msg) }
+
+// Warning writes a warning event.
+func Warning(id uint32, msg string) { write(levelWarning, id, msg) }
+
+// Error writes an error event. Like the other levels, failures are ignored:
+// the event is a copy of what is already in the application log.
+func Error(id uint32, msg string) { write(levelError, id, msg) }
+
+func write(lvl level, id uint32, msg string) {
+	if disabled.Load() {
+		return
+	}
+	report(lvl, id, msg)
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package eventlog
+
+import "fmt"
+
+// Install fails: the event log only exists on Windows.
+func Install() error {
+	return fmt.Errorf("the event log is only available on Windows")
+}
+
+// Uninstall fails: the event log only exists on Windows.
+func Uninstall() error {
+	return fmt.Errorf("the event log is only available on Windows")
+}
+
+// report does nothing: the event log only exists on Windows.
+func report(lvl level, id uint32, msg string) {}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package eventlog
+
+import (
+	"strings"
+
+	winlog "golang.org/x/sys/windows/svc/eventlog"
+)
+
+// Install registers the event source, so the Event Viewer shows the messages
+// without a note about a missing description. Needs administrator rights
+// (run by the installer); registering it again is not an error.
+func Install() error {
+	err := winlog.InstallAsEventCreate(Source, winlog.Error|winlog.Warning|winlog.Info)
+	if err != nil && strings.Contains(err.Error(), "already exists") {
+		return nil
+	}
+	return err
+}
+
+// Uninstall removes the event source. Needs administrator rights.
+func Uninstall() error {
+	return winlog.Remove(Source)
+}
+
+func report(lvl level, id uint32, msg string) {
+	// Without the registered source, Windows still stores the message, with
+	// a note that its description is missing
+	l, err := winlog.Open(Source)
+	if err != nil {
+		return
+	}
+	defer l.Close()
+
+	switch lvl {
+	case levelError:
+		l.Error(id, msg)
+	case levelWarning:
+		l.Warning(id, msg)
+	default:
+		l.Info(id, msg)
+	}
+}
@@ -0,0 +1,141 @@
+// Package filetype classifies files by extension and MIME type, so policies
+// can target kinds of files ("never dehydrate databases", "don't upload
+// executables", "compress only text") instead of extension lists. The same
+// classification is shared by the upload exclusion of the sync engine, the
+// dehydration of Files On Demand folders and compression.
+package filetype
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Class is a kind of file.
+type Class string
+
+const (
+	ClassText       Class = "text"       // Plain text, source code, markup, configuration
+	ClassDocument   Class = "document"   // Office documents and PDF
+	ClassImage      Class = "image"      // Pictures, including camera raw files
+	ClassAudio      Class = "audio"      // Music and sound
+	ClassVideo      Class = "video"      // Movies and clips
+	ClassArchive    Class = "archive"    // Compressed archives and disk images
+	ClassExecutable Class = "executable" // Programs, libraries, installers and scripts
+	ClassDatabase   Class = "database"   // Database files, often kept open and written in place
+	ClassOther      Class = "other"      // Anything else
+)
+
+// Classes lists the valid classes, for settings and validation.
+var Classes = []Class{
+	ClassText, ClassDocument, ClassImage, ClassAudio, ClassVideo,
+	ClassArchive, ClassExecutable, ClassDatabase, ClassOther,
+}
+
+// extensionClasses maps lowercase extensions to their class. Extensions not
+// listed are classified by their MIME type.
+var extensionClasses = map[string]Class{
+	// Text
+	".txt": ClassText, ".md": ClassText, ".csv": ClassText, ".tsv": ClassText, ".log": ClassText,
+	".json": ClassText, ".xml": ClassText, ".html": ClassText, ".htm": ClassText, ".css": ClassText,
+	".js": ClassText, ".ts": ClassText, ".go": ClassText, ".c": ClassText, ".h": ClassText,
+	".cpp": ClassText, ".cs": ClassText, ".py": ClassText, ".java": ClassText, ".rb": ClassText,
+	".php": ClassText, ".sql": ClassText, ".ini": ClassText, ".cfg": ClassText, ".conf": ClassText,
+	".yaml": ClassText, ".yml": ClassText, ".toml": ClassText, ".tex": ClassText, ".rtf": ClassText,
+	".svg": ClassText, ".srt": ClassText,
+	// Documents
+	".pdf": ClassDocument, ".doc": ClassDocument, ".docx": ClassDocument, ".xls": ClassDocument,
+	".xlsx": ClassDocument, ".ppt": ClassDocument, ".pptx": ClassDocument, ".odt": ClassDocument,
+	".ods": ClassDocument, ".odp": ClassDocument, ".epub": ClassDocument, ".one": ClassDocument,
+	// Images
+	".jpg": ClassImage, ".jpeg": ClassImage, ".png": ClassImage, ".gif": ClassImage, ".bmp": ClassImage,
+	".tif": ClassImage, ".tiff": ClassImage, ".webp": ClassImage, ".heic": ClassImage, ".raw": ClassImage,
+	".cr2": ClassImage, ".nef": ClassImage, ".arw": ClassImage, ".dng": ClassImage, ".psd": ClassImage,
+	".ico": ClassImage,
+	// Audio
+	".mp3": ClassAudio, ".wav": ClassAudio, ".flac": ClassAudio, ".aac": ClassAudio, ".ogg": ClassAudio,
+	".m4a": ClassAudio, ".wma": ClassAudio, ".opus": ClassAudio,
+	// Video
+	".mp4": ClassVideo, ".mkv": ClassVideo, ".avi": ClassVideo, ".mov": ClassVideo, ".wmv": ClassVideo,
+	".webm": ClassVideo, ".m4v": ClassVideo, ".mpg": ClassVideo, ".mpeg": ClassVideo,
+	// Archives
+	".zip": ClassArchive, ".7z": ClassArchive, ".rar": ClassArchive, ".tar": ClassArchive,
+	".gz": ClassArchive, ".tgz": ClassArchive, ".bz2": ClassArchive, ".xz": ClassArchive,
+	".zst": ClassArchive, ".cab": ClassArchive, ".iso": ClassArchive, ".vhd": ClassArchive,
+	".vhdx": ClassArchive,
+	// Executables and scripts
+	".exe": ClassExecutable, ".dll": ClassExecutable, ".msi": ClassExecutable, ".msix": ClassExecutable,
+	".sys": ClassExecutable, ".com": ClassExecutable, ".scr": ClassExecutable, ".bat": ClassExecutable,
+	".cmd": ClassExecutable, ".ps1": ClassExecutable, ".vbs": ClassExecutable,
+	".jar": ClassExecutable, ".apk": ClassExecutable, ".lnk": ClassExecutable,
+	// Databases
+	".db": ClassDatabase, ".db3": ClassDatabase, ".sqlite": ClassDatabase, ".sqlite3": ClassDatabase,
+	".mdb": ClassDatabase, ".accdb": ClassDatabase, ".pst": ClassDatabase, ".ost": ClassDatabase,
+	".dbf": ClassDatabase, ".kdbx": ClassDatabase, ".fdb": ClassDatabase, ".mdf": ClassDatabase,
+	".ldf": ClassDatabase, ".ndf": ClassDatabase, ".ibd": ClassDatabase,
+}
+
+// Info is the classification of a file.
+type Info struct {
+	Class Class
+	MIME  string // MIME type ("application/octet-stream" if unknown)
+}
+
+// classified caches the classification per extension: every file is
+// classified once per extension, whichever module asks first.
+var classified sync.Map // extension -> Info
+
+// Classify returns the classification of the file at path (only its
+// extension is considered; the file is not opened).
+func Classify(path string) Info {
+	ext := strings.ToLower(filepath.Ext(path))
+	if info, ok := classified.Load(ext); ok {
+		return info.(Info)
+	}
+	info := classify(ext)
+	classified.Store(ext, info)
+	return info
+}
+
+// ClassOf returns the class of the file at path.
+func ClassOf(path string) Class {
+	return Classify(path).Class
+}
+
+// classify classifies an extension, from the table then its MIME type.
+func classify(ext string) Info {
+	info := Info{Class: ClassOther, MIME: "application/octet-stream"}
+	if ext == "" {
+		return info
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		info.MIME, _, _ = strings.Cut(t, ";")
+	}
+	if class, ok := extensionClasses[ext]; ok {
+		info.Class = class
+		return info
+	}
+
+	switch kind, _, _ := strings.Cut(info.MIME, "/"); kind {
+	case "text":
+		info.Class = ClassText
+	case "image":
+		info.Class = ClassImage
+	case "audio":
+		info.Class = ClassAudio
+	case "video":
+		info.Class = ClassVideo
+	}
+	return info
+}
+
+// ParseClass returns the class named s (case-insensitive).
+func ParseClass(s string) (Class, bool) {
+	for _, c := range Classes {
+		if strings.EqualFold(s, string(c)) {
+			return c, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,79 @@
+package filetype
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		path string
+		want Class
+	}{
+		{"notes.txt", ClassText},
+		{`C:\Users\me\Docs\Report.PDF`, ClassDocument},
+		{"photos/IMG_0001.jpeg", ClassImage},
+		{"setup.exe", ClassExecutable},
+		{"deploy.ps1", ClassExecutable},
+		{"Outlook.pst", ClassDatabase},
+		{"app.sqlite3", ClassDatabase},
+		{"backup.tar.gz", ClassArchive},
+		{"movie.MKV", ClassVideo},
+		{"Makefile", ClassOther},
+		{"data.unknownext", ClassOther},
+	}
+
+	for _, tt := range tests {
+		if got := ClassOf(tt.path); got != tt.want {
+			t.Errorf("ClassOf(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+		// Cached result must match
+		if got := ClassOf(tt.path); got != tt.want {
+			t.Errorf("ClassOf(%q) second call = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	if info := Classify("page.html"); info.MIME != "text/html" {
+		t.Errorf("Classify(page.html).MIME = %q, want text/html", info.MIME)
+	}
+	if info := Classify("blob"); info.MIME != "application/octet-stream" {
+		t.Errorf("Classify(blob).MIME = %q, want application/octet-stream", info.MIME)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	if _, err := NewPolicy([]string{"databases"}, nil, nil); err == nil {
+		t.Error("NewPolicy with an unknown class: expected error")
+	}
+
+	p, err := NewPolicy([]string{"Database"}, []string{"text"}, []string{" executable "})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	if ok, _ := p.AllowsDehydration("mail.pst"); ok {
+		t.Error("database file should not be dehydrated")
+	}
+	if ok, _ := p.AllowsDehydration("video.mp4"); !ok {
+		t.Error("video file should be dehydrated")
+	}
+	if ok, reason := p.AllowsUpload("tool.exe"); ok || reason == "" {
+		t.Errorf("executable should not be uploaded (reason %q)", reason)
+	}
+	if ok, _ := p.AllowsUpload("notes.txt"); !ok {
+		t.Error("text file should be uploaded")
+	}
+	if !p.Compresses("notes.txt") || p.Compresses("photo.jpg") {
+		t.Error("only text files should be compressed")
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var p *Policy
+	if ok, _ := p.AllowsDehydration("mail.pst"); !ok {
+		t.Error("nil policy should allow dehydration")
+	}
+	if ok, _ := p.AllowsUpload("tool.exe"); !ok {
+		t.Error("nil policy should allow upload")
+	}
+	if !p.Compresses("photo.jpg") {
+		t.Error("nil policy should compress everything")
+	}
+}
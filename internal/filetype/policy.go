@@ -0,0 +1,103 @@
+package filetype
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy holds the per-class rules of the modules handling files.
+// A nil *Policy applies no rule.
+type Policy struct {
+	neverDehydrate map[Class]bool // Classes kept hydrated in Files On Demand folders
+	compress       map[Class]bool // Classes worth compressing (empty = all)
+	excludeUpload  map[Class]bool // Classes never uploaded to the server
+}
+
+// NewPolicy creates a policy from class names (see Classes).
+func NewPolicy(neverDehydrate, compress, excludeUpload []string) (*Policy, error) {
+	p := &Policy{}
+	var err error
+	if p.neverDehydrate, err = parseClasses(neverDehydrate); err != nil {
+		return nil, fmt.Errorf("never_dehydrate: %w", err)
+	}
+	if p.compress, err = parseClasses(compress); err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	if p.excludeUpload, err = parseClasses(excludeUpload); err != nil {
+		return nil, fmt.Errorf("exclude_upload: %w", err)
+	}
+	return p, nil
+}
+
+func parseClasses(names []string) (map[Class]bool, error) {
+	classes := make(map[Class]bool, len(names))
+	for _, name := range names {
+		c, ok := ParseClass(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown file type %q (valid: %s)", name, classNames())
+		}
+		classes[c] = true
+	}
+	return classes, nil
+}
+
+func classNames() string {
+	names := make([]string, len(Classes))
+	for i, c := range Classes {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
+
+// AllowsDehydration reports whether the file at path may be dehydrated,
+// with the reason when it may not.
+func (p *Policy) AllowsDehydration(path string) (bool, string) {
+	if p == nil {
+		return true, ""
+	}
+	if class := ClassOf(path); p.neverDehydrate[class] {
+		return false, fmt.Sprintf("%s files are never dehydrated", class)
+	}
+	return true, ""
+}
+
+// AllowsUpload reports whether the file at path may be uploaded, with the
+// reason when it may not.
+func (p *Policy) AllowsUpload(path string) (bool, string) {
+	if p == nil {
+		return true, ""
+	}
+	if class := ClassOf(path); p.excludeUpload[class] {
+		return false, fmt.Sprintf("%s files are not uploaded", class)
+	}
+	return true, ""
+}
+
+// Compresses reports whether the file at path should be compressed when
+// compression is enabled. It is the hook for advanced.compression, which
+// transfers do not apply yet.
+func (p *Policy) Compresses(path string) bool {
+	if p == nil || len(p.compress) == 0 {
+		return true
+	}
+	return p.compress[ClassOf(path)]
+}
+
+// String describes the rules, for logs.
+func (p *Policy) String() string {
+	if p == nil {
+		return "none"
+	}
+	return fmt.Sprintf("never_dehydrate=%v compress=%v exclude_upload=%v",
+		classList(p.neverDehydrate), classList(p.compress), classList(p.excludeUpload))
+}
+
+func classList(classes map[Class]bool) []Class {
+	var list []Class
+	for _, c := range Classes {
+		if classes[c] {
+			list = append(list, c)
+		}
+	}
+	return list
+}
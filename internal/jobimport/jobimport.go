@@ -0,0 +1,169 @@
+// Package jobimport turns the setups of other sync tools into proposed
+// AnemoneSync jobs: robocopy scripts, Syncthing folders, OneDrive folders and
+// plain folder pairs. A proposal is a draft: paths, exclusion rules and mode
+// are carried over, and what has no equivalent is reported in its notes for
+// the user to review before the job is created.
+package jobimport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+// Proposal is a job proposed from an existing setup.
+type Proposal struct {
+	Source    string          // Where it comes from ("backup.cmd line 3", "Syncthing folder photos")
+	Name      string          // Proposed job name
+	LocalPath string          // Local folder
+	Remote    smbpath.UNCPath // Server folder (zero = to be chosen)
+	Mode      sync.SyncMode   // Sync direction
+	Excludes  []string        // Exclusion rules, in .anemoneignore syntax
+	Notes     []string        // What was not carried over, or needs a decision
+}
+
+// HasRemote reports whether the server folder is known.
+func (p *Proposal) HasRemote() bool {
+	return p.Remote.Host != "" && p.Remote.Share != ""
+}
+
+func (p *Proposal) note(format string, args ...any) {
+	p.Notes = append(p.Notes, fmt.Sprintf(format, args...))
+}
+
+// Result is what an import found.
+type Result struct {
+	Proposals []Proposal
+	Skipped   []string // What could not become a job, and why
+}
+
+func (r *Result) skip(format string, args ...any) {
+	r.Skipped = append(r.Skipped, fmt.Sprintf(format, args...))
+}
+
+// FolderPair proposes a mirror job for a local folder and a server folder
+// kept in sync by hand or by another tool. Exclusions of a Syncthing folder
+// (.stignore) are carried over.
+func FolderPair(local, remote string) (Proposal, error) {
+	local = filepath.Clean(strings.TrimSpace(local))
+	info, err := os.Stat(local)
+	if err != nil {
+		return Proposal{}, fmt.Errorf("local folder: %w", err)
+	}
+	if !info.IsDir() {
+		return Proposal{}, fmt.Errorf("%s is not a folder", local)
+	}
+	unc, err := smbpath.ParseUNC(remote)
+	if err != nil {
+		return Proposal{}, fmt.Errorf("server folder: %w", err)
+	}
+
+	p := Proposal{
+		Source:    "folder pair",
+		Name:      folderName(local),
+		LocalPath: local,
+		Remote:    unc,
+		Mode:      sync.SyncModeMirror,
+	}
+	if _, err := os.Stat(filepath.Join(local, ".stfolder")); err == nil {
+		p.note("Syncthing also syncs this folder: remove it from Syncthing before enabling the job")
+	}
+	readStignore(&p)
+	return p, nil
+}
+
+// OneDriveFolders proposes a job for each OneDrive folder of the user. The
+// server folder is to be chosen: these files come from the cloud.
+func OneDriveFolders() []Proposal {
+	return oneDriveFolders(os.Getenv)
+}
+
+// oneDriveVars are the variables OneDrive sets to its folders.
+var oneDriveVars = []string{"OneDrive", "OneDriveConsumer", "OneDriveCommercial"}
+
+func oneDriveFolders(getenv func(string) string) []Proposal {
+	var proposals []Proposal
+	seen := make(map[string]bool)
+	for _, name := range oneDriveVars {
+		dir := getenv(name)
+		if dir == "" {
+			continue
+		}
+		dir = cleanLocalPath(dir)
+		if seen[strings.ToLower(dir)] {
+			continue
+		}
+		seen[strings.ToLower(dir)] = true
+
+		p := Proposal{
+			Source:    "OneDrive (" + name + ")",
+			Name:      folderName(dir),
+			LocalPath: dir,
+			Mode:      sync.SyncModeMirror,
+		}
+		p.note("choose the server folder to sync it with")
+		p.note("stop OneDrive from syncing this folder first: two sync tools on one folder undo each other's changes")
+		proposals = append(proposals, p)
+	}
+	return proposals
+}
+
+// cleanLocalPath cleans a local path. Windows paths are cleaned the same
+// whatever the OS the import runs on, keeping the "\" of a drive root.
+func cleanLocalPath(path string) string {
+	path = strings.TrimSpace(path)
+	if !isWindowsAbs(path) {
+		if path == "" {
+			return ""
+		}
+		return filepath.Clean(path)
+	}
+	path = strings.ReplaceAll(path, "/", `\`)
+	for len(path) > 3 && strings.HasSuffix(path, `\`) {
+		path = path[:len(path)-1]
+	}
+	return path
+}
+
+// isWindowsAbs reports whether a path is a local Windows path ("C:\..."),
+// whatever the OS the import runs on.
+func isWindowsAbs(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') &&
+		(path[0] >= 'a' && path[0] <= 'z' || path[0] >= 'A' && path[0] <= 'Z')
+}
+
+// isUNC reports whether a path is a network path (\\server\share).
+func isUNC(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
+
+// folderName returns the last element of a Windows path, used as job name.
+func folderName(path string) string {
+	trimmed := strings.TrimRight(path, `\/`)
+	if i := strings.LastIndexAny(trimmed, `\/`); i >= 0 {
+		trimmed = trimmed[i+1:]
+	}
+	if trimmed == "" || strings.HasSuffix(trimmed, ":") {
+		return "Drive " + strings.TrimSuffix(trimmed, ":")
+	}
+	return trimmed
+}
+
+// relativeRule turns an excluded path into a rule anchored at the job root
+// when it lies inside root, or returns "" otherwise.
+func relativeRule(root, path string, dirOnly bool) string {
+	root = strings.TrimRight(strings.ReplaceAll(root, "/", `\`), `\`) + `\`
+	norm := strings.TrimRight(strings.ReplaceAll(path, "/", `\`), `\`)
+	if len(norm) <= len(root) || !strings.EqualFold(norm[:len(root)], root) {
+		return ""
+	}
+	rule := "/" + strings.ReplaceAll(norm[len(root):], `\`, "/")
+	if dirOnly {
+		rule += "/"
+	}
+	return rule
+}
@@ -0,0 +1,177 @@
+package jobimport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+func TestParseRobocopy(t *testing.T) {
+	script := `@echo off
+rem Nightly backup
+:: robocopy C:\Old \\nas\old /MIR
+robocopy "C:\Users\me\Documents" \\nas\backup\docs /MIR /XD node_modules "C:\Users\me\Documents\Temp Files" ^
+  /XF *.tmp ~$* /MAX:1000000 /R:2 /W:5 /NP /LOG:C:\logs\docs.log
+C:\Windows\System32\Robocopy.exe \\nas\media\photos D:\Photos *.jpg /E /MOV /XA:H
+robocopy C:\Data D:\Data /MIR
+robocopy %SOURCE% \\nas\backup /MIR
+`
+	result, err := ParseRobocopy(strings.NewReader(script), "backup.cmd")
+	if err != nil {
+		t.Fatalf("ParseRobocopy: %v", err)
+	}
+	if len(result.Proposals) != 2 {
+		t.Fatalf("got %d proposals, want 2: %+v", len(result.Proposals), result.Proposals)
+	}
+
+	docs := result.Proposals[0]
+	if docs.Source != "backup.cmd line 4" || docs.Name != "Documents" || docs.LocalPath != `C:\Users\me\Documents` {
+		t.Errorf("docs = %q %q %q", docs.Source, docs.Name, docs.LocalPath)
+	}
+	if docs.Mode != sync.SyncModeUpload {
+		t.Errorf("docs mode = %s, want upload", docs.Mode)
+	}
+	if want := (smbpath.UNCPath{Host: "nas", Share: "backup", Path: "docs"}); docs.Remote != want {
+		t.Errorf("docs remote = %+v, want %+v", docs.Remote, want)
+	}
+	wantRules := []string{"node_modules/", "/Temp Files/", "*.tmp", "~$*", "size>1000000"}
+	if !reflect.DeepEqual(docs.Excludes, wantRules) {
+		t.Errorf("docs excludes = %q, want %q", docs.Excludes, wantRules)
+	}
+	if len(docs.Notes) != 0 {
+		t.Errorf("docs notes = %q, want none", docs.Notes)
+	}
+
+	photos := result.Proposals[1]
+	if photos.Mode != sync.SyncModeDownload || photos.LocalPath != `D:\Photos` || photos.Remote.Share != "media" {
+		t.Errorf("photos = %s %q %+v", photos.Mode, photos.LocalPath, photos.Remote)
+	}
+	notes := strings.Join(photos.Notes, "\n")
+	for _, want := range []string{"*.jpg", "/MOV", "/PURGE", "/XA:H"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("photos notes don't mention %s:\n%s", want, notes)
+		}
+	}
+
+	if len(result.Skipped) != 2 {
+		t.Errorf("skipped = %q, want the local copy and the variable", result.Skipped)
+	}
+}
+
+func TestRobocopyExclusion(t *testing.T) {
+	tests := []struct {
+		arg  string
+		dir  bool
+		want string
+	}{
+		{"bin", true, "bin/"},
+		{"*.bak", false, "*.bak"},
+		{`C:\Src\build\out`, true, "/build/out/"},
+		{`c:\src\notes.txt`, false, "/notes.txt"},
+		{`D:\Elsewhere`, true, ""},
+		{`C:\Src`, true, ""},
+		{`cache\tmp`, true, "**/cache/tmp/"},
+	}
+	for _, tt := range tests {
+		if got := robocopyExclusion(`C:\Src`, tt.arg, tt.dir); got != tt.want {
+			t.Errorf("robocopyExclusion(%q, %v) = %q, want %q", tt.arg, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	got := splitCommandLine(`robocopy  "C:\My Docs" \\nas\x	/XF "a b.txt" ""`)
+	want := []string{"robocopy", `C:\My Docs`, `\\nas\x`, "/XF", "a b.txt", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitCommandLine = %q, want %q", got, want)
+	}
+}
+
+func TestParseSyncthingConfig(t *testing.T) {
+	photos := t.TempDir()
+	stignore := "// Syncthing ignores\n(?d).DS_Store\n!(?i)keep.txt\n#include more.stignore\n*.part\n"
+	if err := os.WriteFile(filepath.Join(photos, ".stignore"), []byte(stignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := `<configuration version="37">
+  <folder id="abcd-1234" label="Photos" path="` + photos + `" type="sendonly">
+    <device id="DEVICE1"></device>
+    <paused>true</paused>
+  </folder>
+  <folder id="music" label="" path="C:\Music\" type="sendreceive"></folder>
+  <folder id="vault" label="Vault" path="C:\Vault" type="receiveencrypted"></folder>
+  <device id="DEVICE1" name="laptop"></device>
+</configuration>`
+	result, err := ParseSyncthingConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("ParseSyncthingConfig: %v", err)
+	}
+	if len(result.Proposals) != 2 || len(result.Skipped) != 1 {
+		t.Fatalf("got %d proposals and skipped %q, want 2 and the encrypted folder", len(result.Proposals), result.Skipped)
+	}
+
+	p := result.Proposals[0]
+	if p.Name != "Photos" || p.Mode != sync.SyncModeUpload || p.HasRemote() {
+		t.Errorf("photos = %q %s remote %+v", p.Name, p.Mode, p.Remote)
+	}
+	if want := []string{".DS_Store", "!keep.txt", "*.part"}; !reflect.DeepEqual(p.Excludes, want) {
+		t.Errorf("photos excludes = %q, want %q", p.Excludes, want)
+	}
+	notes := strings.Join(p.Notes, "\n")
+	for _, want := range []string{"paused", "more.stignore", "(?d)"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("photos notes don't mention %s:\n%s", want, notes)
+		}
+	}
+
+	music := result.Proposals[1]
+	if music.Name != "music" || music.Mode != sync.SyncModeMirror || music.LocalPath != `C:\Music` {
+		t.Errorf("music = %q %s %q", music.Name, music.Mode, music.LocalPath)
+	}
+}
+
+func TestFolderPair(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".stignore"), []byte("*.tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := FolderPair(dir, `\\nas\share\work`)
+	if err != nil {
+		t.Fatalf("FolderPair: %v", err)
+	}
+	if p.Mode != sync.SyncModeMirror || !p.HasRemote() || p.Remote.Path != "work" {
+		t.Errorf("pair = %s %+v", p.Mode, p.Remote)
+	}
+	if want := []string{"*.tmp"}; !reflect.DeepEqual(p.Excludes, want) {
+		t.Errorf("excludes = %q, want %q", p.Excludes, want)
+	}
+
+	if _, err := FolderPair(filepath.Join(dir, "missing"), `\\nas\share`); err == nil {
+		t.Error("FolderPair accepted a missing local folder")
+	}
+	if _, err := FolderPair(dir, `\\nas`); err == nil {
+		t.Error("FolderPair accepted a server folder without share")
+	}
+}
+
+func TestOneDriveFolders(t *testing.T) {
+	env := map[string]string{
+		"OneDrive":           `C:\Users\me\OneDrive - Contoso`,
+		"OneDriveCommercial": `C:\Users\me\OneDrive - Contoso\`,
+		"OneDriveConsumer":   `C:\Users\me\OneDrive`,
+	}
+	got := oneDriveFolders(func(name string) string { return env[name] })
+	if len(got) != 2 {
+		t.Fatalf("got %d folders, want 2: %+v", len(got), got)
+	}
+	if got[0].Name != "OneDrive - Contoso" || got[1].LocalPath != `C:\Users\me\OneDrive` || got[0].HasRemote() {
+		t.Errorf("folders = %+v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package jobimport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+// robocopyQuiet are robocopy options that only change how files are copied
+// or logged: the engine has its own equivalent, nothing to report.
+var robocopyQuiet = map[string]bool{
+	"/e": true, "/s": true, "/z": true, "/b": true, "/zb": true, "/j": true,
+	"/mt": true, "/r": true, "/w": true, "/reg": true, "/tbd": true,
+	"/copy": true, "/copyall": true, "/dcopy": true, "/sec": true, "/nocopy": true,
+	"/fft": true, "/dst": true, "/xj": true, "/xjd": true, "/xjf": true,
+	"/log": true, "/log+": true, "/unilog": true, "/unilog+": true, "/tee": true,
+	"/np": true, "/nfl": true, "/ndl": true, "/njh": true, "/njs": true, "/nc": true,
+	"/ns": true, "/bytes": true, "/ts": true, "/fp": true, "/eta": true, "/v": true,
+	"/x": true, "/unicode": true, "/ipg": true, "/compress": true,
+}
+
+// ParseRobocopy proposes a job for each robocopy command of a script
+// (.cmd, .bat or .ps1). source names the script in the proposals. A copy to
+// a server folder becomes an upload job, a copy from one a download job;
+// /XD and /XF exclusions and /MAX and /MIN sizes become exclusion rules.
+// Mapped drive letters are taken for local drives: the server side must be
+// a UNC path.
+func ParseRobocopy(r io.Reader, source string) (Result, error) {
+	var result Result
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var pending string // Line continued with "^"
+	lineNo, startLine := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if pending == "" {
+			startLine = lineNo
+		}
+		if strings.HasSuffix(line, "^") {
+			pending += strings.TrimSuffix(line, "^") + " "
+			continue
+		}
+		line, pending = pending+line, ""
+
+		args := splitCommandLine(line)
+		at := robocopyIndex(args)
+		if at < 0 {
+			continue
+		}
+		where := fmt.Sprintf("%s line %d", source, startLine)
+		if p, ok := parseRobocopyArgs(args[at+1:], where, &result); ok {
+			result.Proposals = append(result.Proposals, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("read %s: %w", source, err)
+	}
+	return result, nil
+}
+
+// robocopyIndex returns the index of the robocopy program in a command, or
+// -1 when the line is a comment or runs something else.
+func robocopyIndex(args []string) int {
+	if len(args) == 0 {
+		return -1
+	}
+	first := strings.ToLower(args[0])
+	if first == "rem" || strings.HasPrefix(first, "::") || strings.HasPrefix(first, "#") {
+		return -1
+	}
+	for i, arg := range args {
+		name := strings.ToLower(path.Base(strings.ReplaceAll(arg, `\`, "/")))
+		if name == "robocopy" || name == "robocopy.exe" {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseRobocopyArgs turns the arguments of a robocopy command into a
+// proposal. Commands that can't become a job are recorded in result.
+func parseRobocopyArgs(args []string, where string, result *Result) (Proposal, bool) {
+	var positional []string
+	i := 0
+	for ; i < len(args) && !strings.HasPrefix(args[i], "/"); i++ {
+		positional = append(positional, args[i])
+	}
+	if len(positional) < 2 {
+		result.skip("%s: robocopy without source and destination folders", where)
+		return Proposal{}, false
+	}
+	src, dst := positional[0], positional[1]
+	if strings.Contains(src+dst, "%") {
+		result.skip("%s: the folders use script variables (%s, %s)", where, src, dst)
+		return Proposal{}, false
+	}
+
+	p := Proposal{Source: where}
+	var local string
+	switch {
+	case isWindowsAbs(src) && isUNC(dst):
+		local, p.Mode = src, sync.SyncModeUpload
+		p.Remote = smbpath.SplitUNC(dst)
+	case isUNC(src) && isWindowsAbs(dst):
+		local, p.Mode = dst, sync.SyncModeDownload
+		p.Remote = smbpath.SplitUNC(src)
+	default:
+		result.skip("%s: %s to %s is not a copy between a local folder and a server folder (\\\\server\\share)", where, src, dst)
+		return Proposal{}, false
+	}
+	if err := p.Remote.Validate(); err != nil {
+		result.skip("%s: %v", where, err)
+		return Proposal{}, false
+	}
+	p.LocalPath = cleanLocalPath(local)
+	p.Name = folderName(p.LocalPath)
+
+	for _, spec := range positional[2:] {
+		if spec != "*" && spec != "*.*" {
+			p.note("robocopy only copied %s: the job syncs every file", spec)
+		}
+	}
+
+	recursive, mirror := false, false
+	var ignored []string
+	for ; i < len(args); i++ {
+		name, value, _ := strings.Cut(strings.ToLower(args[i]), ":")
+		switch name {
+		case "/e", "/s":
+			recursive = true
+		case "/mir":
+			recursive, mirror = true, true
+		case "/purge":
+			mirror = true
+		case "/xd", "/xf":
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "/") {
+				i++
+				if rule := robocopyExclusion(local, args[i], name == "/xd"); rule != "" {
+					p.Excludes = append(p.Excludes, rule)
+				}
+			}
+		case "/max", "/min":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				ignored = append(ignored, args[i])
+				continue
+			}
+			op := ">"
+			if name == "/min" {
+				op = "<"
+			}
+			p.Excludes = append(p.Excludes, fmt.Sprintf("size%s%d", op, size))
+		case "/mov", "/move":
+			p.note("robocopy moved the files (%s): the job keeps them on both sides", args[i])
+		case "/l":
+			p.note("the command only listed the files (/L): check the folders are the right ones")
+		default:
+			if !robocopyQuiet[name] {
+				ignored = append(ignored, args[i])
+			}
+		}
+	}
+
+	if !recursive {
+		p.note("robocopy only copied the top folder (no /E or /S): the job syncs subfolders too")
+	}
+	if !mirror {
+		p.note("robocopy kept files deleted from the source (no /MIR or /PURGE): the job deletes them on the other side too")
+	}
+	if len(ignored) > 0 {
+		p.note("options with no equivalent, not carried over: %s", strings.Join(ignored, " "))
+	}
+	return p, true
+}
+
+// robocopyExclusion turns a /XD or /XF argument into an exclusion rule: a
+// full path inside the source is anchored at the job root, a name or
+// wildcard matches at any depth.
+func robocopyExclusion(root, arg string, dir bool) string {
+	if isWindowsAbs(arg) || isUNC(arg) {
+		return relativeRule(root, arg, dir)
+	}
+	rule := strings.Trim(strings.ReplaceAll(arg, `\`, "/"), "/")
+	if rule == "" {
+		return ""
+	}
+	if strings.Contains(rule, "/") {
+		rule = "**/" + rule // robocopy matches names, at any depth
+	}
+	if dir {
+		rule += "/"
+	}
+	return rule
+}
+
+// splitCommandLine splits a command line into arguments the way cmd.exe
+// passes them to a program: spaces separate arguments, double quotes group
+// them and are removed.
+func splitCommandLine(line string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,158 @@
+package jobimport
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/sync"
+)
+
+// syncthingConfig is the part of a Syncthing config.xml the import reads.
+type syncthingConfig struct {
+	Folders []struct {
+		ID     string `xml:"id,attr"`
+		Label  string `xml:"label,attr"`
+		Path   string `xml:"path,attr"`
+		Type   string `xml:"type,attr"`
+		Paused bool   `xml:"paused"`
+	} `xml:"folder"`
+}
+
+// DefaultSyncthingConfig returns the config.xml of the Syncthing installed
+// for the user, or "" when there is none.
+func DefaultSyncthingConfig() string {
+	dir := os.Getenv("LOCALAPPDATA")
+	if dir == "" {
+		return ""
+	}
+	path := filepath.Join(dir, "Syncthing", "config.xml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// ParseSyncthingConfig proposes a job for each folder of a Syncthing
+// config.xml, with the rules of its .stignore. Syncthing syncs with other
+// devices, not with a share: the server folder is left to be chosen.
+func ParseSyncthingConfig(r io.Reader) (Result, error) {
+	var result Result
+	var config syncthingConfig
+	if err := xml.NewDecoder(r).Decode(&config); err != nil {
+		return result, fmt.Errorf("read Syncthing configuration: %w", err)
+	}
+
+	for _, folder := range config.Folders {
+		name := folder.Label
+		if name == "" {
+			name = folder.ID
+		}
+		where := "Syncthing folder " + name
+
+		p := Proposal{
+			Source:    where,
+			Name:      name,
+			LocalPath: cleanLocalPath(expandHome(folder.Path)),
+		}
+		switch folder.Type {
+		case "", "sendreceive":
+			p.Mode = sync.SyncModeMirror
+		case "sendonly":
+			p.Mode = sync.SyncModeUpload
+		case "receiveonly":
+			p.Mode = sync.SyncModeDownload
+		default:
+			result.skip("%s: %s folders have no equivalent", where, folder.Type)
+			continue
+		}
+		if p.LocalPath == "" {
+			result.skip("%s: no folder path", where)
+			continue
+		}
+
+		p.note("choose the server folder to sync it with")
+		p.note("remove the folder from Syncthing before enabling the job: two sync tools on one folder undo each other's changes")
+		if folder.Paused {
+			p.note("the folder was paused in Syncthing")
+		}
+		readStignore(&p)
+		result.Proposals = append(result.Proposals, p)
+	}
+	return result, nil
+}
+
+// expandHome replaces the "~" Syncthing allows at the start of a path.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, `~\`) && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
+// readStignore adds the rules of the .stignore of the local folder, if any.
+func readStignore(p *Proposal) {
+	f, err := os.Open(filepath.Join(p.LocalPath, ".stignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	rules, notes := convertStignore(f)
+	p.Excludes = append(p.Excludes, rules...)
+	p.Notes = append(p.Notes, notes...)
+}
+
+// convertStignore converts .stignore patterns into exclusion rules. Both
+// follow the gitignore syntax; Syncthing prefixes and includes are not
+// carried over.
+func convertStignore(r io.Reader) (rules, notes []string) {
+	droppedDeletable := false
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(lines.Text(), "\ufeff"))
+		switch {
+		case line == "", strings.HasPrefix(line, "//"):
+			continue
+		case strings.HasPrefix(line, "#include"):
+			notes = append(notes, fmt.Sprintf(".stignore includes %s: add its rules to the job by hand",
+				strings.TrimSpace(strings.TrimPrefix(line, "#include"))))
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		negate := strings.HasPrefix(line, "!")
+		pattern := strings.TrimPrefix(line, "!")
+		for {
+			if rest, ok := strings.CutPrefix(pattern, "(?i)"); ok {
+				pattern = rest // Rules are case-insensitive
+			} else if rest, ok := strings.CutPrefix(pattern, "(?d)"); ok {
+				pattern, droppedDeletable = rest, true
+			} else {
+				break
+			}
+		}
+		if negate {
+			pattern = "!" + pattern
+		}
+		if _, err := scanner.ParseRule(pattern); err != nil {
+			notes = append(notes, fmt.Sprintf(".stignore pattern %s not carried over: %v", line, err))
+			continue
+		}
+		rules = append(rules, pattern)
+	}
+	if droppedDeletable {
+		notes = append(notes, "(?d) in .stignore has no equivalent: an excluded file keeps its folder from being deleted")
+	}
+	return rules, notes
+}
@@ -0,0 +1,91 @@
+// Package longpath turns Windows paths longer than MAX_PATH into their
+// extended-length form (\\?\C:\... or \\?\UNC\server\share\...), which the
+// Win32 API accepts up to 32,767 characters. The os package already does it
+// for its own calls; paths passed to syscalls (CreateFile, the Cloud Files
+// API, security descriptors, streams) must go through Fix, otherwise deeply
+// nested folders fail with ERROR_PATH_NOT_FOUND.
+//
+// Paths are handled as strings so the package behaves the same on every OS.
+package longpath
+
+import "strings"
+
+// MaxShortPath is the length from which paths are extended. It is below
+// MAX_PATH (260) as creating a folder leaves room for an 8.3 file name.
+const MaxShortPath = 248
+
+const (
+	prefix    = `\\?\`
+	uncPrefix = `\\?\UNC\`
+)
+
+// Fix returns the extended-length form of an absolute path of MaxShortPath
+// characters or more, and other paths unchanged. The extended form is not
+// normalized by Windows, so / separators, repeated separators, "." and ".."
+// are resolved here.
+func Fix(path string) string {
+	if len(path) < MaxShortPath || IsExtended(path) {
+		return path
+	}
+	path = strings.ReplaceAll(path, "/", `\`)
+
+	switch {
+	case len(path) >= 3 && isLetter(path[0]) && path[1] == ':' && path[2] == '\\':
+		// C:\dir -> \\?\C:\dir
+		return prefix + path[:2] + clean(path[3:])
+	case strings.HasPrefix(path, `\\`) && !strings.HasPrefix(path, `\\.\`):
+		// \\server\share\dir -> \\?\UNC\server\share\dir
+		parts := strings.SplitN(strings.TrimLeft(path, `\`), `\`, 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return path
+		}
+		rest := ""
+		if len(parts) == 3 {
+			rest = parts[2]
+		}
+		return uncPrefix + parts[0] + `\` + parts[1] + clean(rest)
+	}
+	return path // Relative, drive-relative or device path
+}
+
+// Strip returns a path without its extended-length prefix, for display and
+// to compare it with paths given by the user.
+func Strip(path string) string {
+	switch {
+	case strings.HasPrefix(path, uncPrefix):
+		return `\\` + path[len(uncPrefix):]
+	case strings.HasPrefix(path, prefix):
+		return path[len(prefix):]
+	}
+	return path
+}
+
+// IsExtended reports whether a path already is in extended-length form.
+func IsExtended(path string) bool {
+	return strings.HasPrefix(path, prefix)
+}
+
+// clean resolves the elements of a path below its root, returned with a
+// leading separator. ".." stops at the root.
+func clean(rest string) string {
+	var elems []string
+	for _, elem := range strings.Split(rest, `\`) {
+		switch elem {
+		case "", ".":
+		case "..":
+			if len(elems) > 0 {
+				elems = elems[:len(elems)-1]
+			}
+		default:
+			elems = append(elems, elem)
+		}
+	}
+	if len(elems) == 0 {
+		return `\`
+	}
+	return `\` + strings.Join(elems, `\`)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,48 @@
+package longpath
+
+import (
+	"strings"
+	"testing"
+)
+
+// deepPath returns a path of about 400 characters under root.
+func deepPath(root string) string {
+	return root + strings.Repeat(`\nested folder 0123456789`, 16) + `\file.txt`
+}
+
+func TestFix(t *testing.T) {
+	local := deepPath(`C:\Users\me`)
+	unc := deepPath(`\\nas\share`)
+	if len(local) < 400 || len(unc) < 400 {
+		t.Fatalf("test paths too short: %d, %d", len(local), len(unc))
+	}
+
+	tests := []struct {
+		name, path, want string
+	}{
+		{"short", `C:\Users\me\file.txt`, `C:\Users\me\file.txt`},
+		{"local", local, `\\?\` + local},
+		{"unc", unc, `\\?\UNC\nas\share` + unc[len(`\\nas\share`):]},
+		{"slashes", strings.ReplaceAll(local, `\`, "/"), `\\?\` + local},
+		{"dots", `C:\Users\me\.\skipped\..` + local[len(`C:\Users\me`):], `\\?\` + local},
+		{"repeated separators", strings.Replace(local, `\`, `\\\`, 1), `\\?\` + local},
+		{"already extended", `\\?\` + local, `\\?\` + local},
+		{"relative", local[3:], local[3:]},
+		{"device", `\\.\` + local[3:], `\\.\` + local[3:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fix(tt.path); got != tt.want {
+				t.Errorf("Fix() = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrip(t *testing.T) {
+	for _, path := range []string{deepPath(`C:\Users\me`), deepPath(`\\nas\share`), `D:\short`} {
+		if got := Strip(Fix(path)); got != path {
+			t.Errorf("Strip(Fix(%q)) = %q", path, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"sort"
+	"strings"
+)
+
+// DiffKind is how a file differs between two manifests.
+type DiffKind string
+
+const (
+	OnlyInA      DiffKind = "only_in_a"     // Listed in the first manifest only
+	OnlyInB      DiffKind = "only_in_b"     // Listed in the second manifest only
+	SizeDiffers  DiffKind = "size_differs"  // Same path, different sizes
+	HashDiffers  DiffKind = "hash_differs"  // Same size, different known hashes
+	MTimeDiffers DiffKind = "mtime_differs" // Same content as far as known, different times
+)
+
+// mtimeTolerance absorbs the 2-second resolution of FAT volumes and the
+// rounding of some NAS file systems.
+const mtimeTolerance = 2
+
+// Diff is a file differing between two manifests.
+type Diff struct {
+	Path string
+	Kind DiffKind
+	A, B *Entry // nil when the file is not in that manifest
+}
+
+// Compare returns the files differing between two manifests, sorted by path.
+// Paths are compared without case, like Windows does. Hashes are only
+// compared when both manifests know them.
+func Compare(a, b *Manifest) []Diff {
+	inB := make(map[string]*Entry, len(b.Files))
+	for i := range b.Files {
+		inB[strings.ToLower(b.Files[i].Path)] = &b.Files[i]
+	}
+
+	var diffs []Diff
+	for i := range a.Files {
+		ea := &a.Files[i]
+		key := strings.ToLower(ea.Path)
+		eb, ok := inB[key]
+		if !ok {
+			diffs = append(diffs, Diff{Path: ea.Path, Kind: OnlyInA, A: ea})
+			continue
+		}
+		delete(inB, key)
+		if kind, differs := compareEntries(ea, eb); differs {
+			diffs = append(diffs, Diff{Path: ea.Path, Kind: kind, A: ea, B: eb})
+		}
+	}
+	for _, eb := range inB {
+		diffs = append(diffs, Diff{Path: eb.Path, Kind: OnlyInB, B: eb})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// compareEntries returns how two entries of the same path differ.
+func compareEntries(a, b *Entry) (DiffKind, bool) {
+	switch {
+	case a.Size != b.Size:
+		return SizeDiffers, true
+	case a.Hash != "" && b.Hash != "" && !strings.EqualFold(a.Hash, b.Hash):
+		return HashDiffers, true
+	case a.Hash != "" && strings.EqualFold(a.Hash, b.Hash):
+		return "", false // Same content, times don't matter
+	case a.MTime-b.MTime > mtimeTolerance || b.MTime-a.MTime > mtimeTolerance:
+		return MTimeDiffers, true
+	}
+	return "", false
+}
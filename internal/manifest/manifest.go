@@ -0,0 +1,211 @@
+// Package manifest exports the file listing of one side of a job (paths,
+// sizes, modification times and hashes where known) to a JSON file, and
+// compares two of them: audits and support can check what a PC or a server
+// holds without shipping the files. A manifest carries a checksum of its
+// content, and optionally an Ed25519 signature made with a key of
+// "config keygen", so edits after export are detected.
+//
+// Not to be confused with the manifest of Anemone Server (sync.Manifest),
+// which the server writes to the share to speed up scans.
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the format version of written manifests.
+const Version = 1
+
+// Sides of a job a manifest lists.
+const (
+	SideLocal  = "local"
+	SideRemote = "remote"
+)
+
+// ErrTampered is returned when a manifest doesn't match its checksum or its
+// signature.
+var ErrTampered = errors.New("manifest was modified after export")
+
+// ErrUntrusted is returned when a manifest is not signed with the key it is
+// checked against.
+var ErrUntrusted = errors.New("manifest is not signed with the trusted key")
+
+// Entry is a file of a manifest.
+type Entry struct {
+	Path  string `json:"path"`           // Relative to the job folder, / separators
+	Size  int64  `json:"size"`           // Bytes
+	MTime int64  `json:"mtime"`          // Unix timestamp
+	Hash  string `json:"hash,omitempty"` // SHA-256, "" when not known
+}
+
+// Manifest is the file listing of one side of a job.
+type Manifest struct {
+	Version     int       `json:"version"`
+	JobID       int64     `json:"job_id"`
+	JobName     string    `json:"job_name"`
+	Side        string    `json:"side"` // SideLocal or SideRemote
+	Root        string    `json:"root"` // Local folder or UNC path listed
+	GeneratedAt time.Time `json:"generated_at"`
+	FileCount   int       `json:"file_count"`
+	TotalSize   int64     `json:"total_size"`
+	Files       []Entry   `json:"files"`
+
+	// Seal (see Seal)
+	Checksum  string `json:"checksum"`             // SHA-256 of the content without the seal
+	PublicKey string `json:"public_key,omitempty"` // Base64 Ed25519 key of the signature
+	Signature string `json:"signature,omitempty"`  // Base64 Ed25519 signature of the content
+}
+
+// New creates the manifest of files, sorted by path.
+func New(jobID int64, jobName, side, root string, files []Entry) *Manifest {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	m := &Manifest{
+		Version:     Version,
+		JobID:       jobID,
+		JobName:     jobName,
+		Side:        side,
+		Root:        root,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		FileCount:   len(files),
+		Files:       files,
+	}
+	for _, f := range files {
+		m.TotalSize += f.Size
+	}
+	return m
+}
+
+// content returns the JSON covered by the checksum and the signature.
+func (m *Manifest) content() ([]byte, error) {
+	unsealed := *m
+	unsealed.Checksum, unsealed.PublicKey, unsealed.Signature = "", "", ""
+	return json.Marshal(&unsealed)
+}
+
+// Seal computes the checksum of the manifest and, with privateKey (base64,
+// from "config keygen"), signs it. An empty key only sets the checksum.
+func (m *Manifest) Seal(privateKey string) error {
+	data, err := m.content()
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	m.Checksum = "sha256:" + hex.EncodeToString(sum[:])
+	m.PublicKey, m.Signature = "", ""
+
+	if privateKey == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key")
+	}
+	priv := ed25519.PrivateKey(key)
+	m.PublicKey = base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+	m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data))
+	return nil
+}
+
+// Verify checks the checksum of the manifest and its signature if it is
+// signed. Returns ErrTampered if either doesn't match. The key embedded in
+// the manifest only shows that it wasn't edited after signing, not who
+// signed it: with trustedKey (base64 public key of "config keygen"), the
+// manifest must be signed with that key, otherwise ErrUntrusted is returned.
+func (m *Manifest) Verify(trustedKey string) error {
+	data, err := m.content()
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if m.Checksum != "sha256:"+hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("%w (checksum mismatch)", ErrTampered)
+	}
+
+	var trusted []byte
+	if trustedKey != "" {
+		trusted, err = base64.StdEncoding.DecodeString(strings.TrimSpace(trustedKey))
+		if err != nil || len(trusted) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid trusted public key")
+		}
+	}
+
+	if m.Signature == "" && m.PublicKey == "" {
+		if trusted != nil {
+			return fmt.Errorf("%w (not signed)", ErrUntrusted)
+		}
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(m.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w (invalid public key)", ErrTampered)
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return fmt.Errorf("%w (signature mismatch)", ErrTampered)
+	}
+	if trusted != nil && !bytes.Equal(key, trusted) {
+		return fmt.Errorf("%w (signed with key %s)", ErrUntrusted, m.KeyFingerprint())
+	}
+	return nil
+}
+
+// Signed reports whether the manifest carries a signature.
+func (m *Manifest) Signed() bool {
+	return m.Signature != ""
+}
+
+// KeyFingerprint returns a short form of the public key of the signature, to
+// check it against the key of the signer ("" if not signed).
+func (m *Manifest) KeyFingerprint() string {
+	if m.PublicKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(m.PublicKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Write writes the manifest to a file. It must be sealed.
+func (m *Manifest) Write(path string) error {
+	if m.Checksum == "" {
+		return fmt.Errorf("manifest is not sealed")
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Read reads a manifest file and verifies its seal, against trustedKey if
+// set (see Verify).
+func Read(path, trustedKey string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if m.Version > Version {
+		return nil, fmt.Errorf("manifest %s has version %d, this version reads up to %d", path, m.Version, Version)
+	}
+	if err := m.Verify(trustedKey); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
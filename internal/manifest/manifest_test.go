@@ -0,0 +1,169 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+)
+
+func testManifest() *Manifest {
+	return New(1, "Documents", SideRemote, `\\nas\docs`, []Entry{
+		{Path: "b.txt", Size: 20, MTime: 1700000000},
+		{Path: "a.txt", Size: 10, MTime: 1700000000, Hash: "aa"},
+	})
+}
+
+func TestNew_SortsAndTotals(t *testing.T) {
+	m := testManifest()
+	if m.Files[0].Path != "a.txt" || m.FileCount != 2 || m.TotalSize != 30 {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+}
+
+func TestWriteRead_Checksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m := testManifest()
+	if err := m.Seal(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(path, "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.Signed() || len(got.Files) != 2 || got.Files[1].Size != 20 {
+		t.Errorf("unexpected manifest read: %+v", got)
+	}
+
+	// An edited size is detected
+	data, _ := os.ReadFile(path)
+	edited := strings.Replace(string(data), `"size": 20`, `"size": 21`, 1)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path, ""); !errors.Is(err, ErrTampered) {
+		t.Errorf("Read() of edited manifest = %v, want ErrTampered", err)
+	}
+}
+
+func TestSeal_Signature(t *testing.T) {
+	_, privateKey, err := config.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := testManifest()
+	if err := m.Seal(privateKey); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Signed() || m.KeyFingerprint() == "" {
+		t.Fatal("manifest not signed")
+	}
+	if err := m.Verify(""); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// Recomputing the checksum after an edit doesn't make up for the signature
+	m.Files[0].Hash = "bb"
+	signature, publicKey := m.Signature, m.PublicKey
+	if err := m.Seal(""); err != nil {
+		t.Fatal(err)
+	}
+	m.Signature, m.PublicKey = signature, publicKey
+	if err := m.Verify(""); !errors.Is(err, ErrTampered) {
+		t.Errorf("Verify() = %v, want ErrTampered", err)
+	}
+
+	if err := m.Seal("not a key"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestVerify_TrustedKey(t *testing.T) {
+	publicKey, privateKey, err := config.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := testManifest()
+	if err := m.Seal(privateKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(publicKey); err != nil {
+		t.Fatalf("Verify(trusted key) error = %v", err)
+	}
+
+	// Re-signed with another key: consistent on its own, but not trusted
+	_, otherKey, err := config.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Files[0].Hash = "bb"
+	if err := m.Seal(otherKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(""); err != nil {
+		t.Fatalf("Verify() without trusted key error = %v", err)
+	}
+	if err := m.Verify(publicKey); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Verify(trusted key) of re-signed manifest = %v, want ErrUntrusted", err)
+	}
+
+	if err := m.Seal(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(publicKey); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Verify(trusted key) of unsigned manifest = %v, want ErrUntrusted", err)
+	}
+	if err := m.Verify("not a key"); err == nil {
+		t.Error("expected an error for an invalid trusted key")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := New(1, "Documents", SideLocal, `C:\Docs`, []Entry{
+		{Path: "same.txt", Size: 1, MTime: 100, Hash: "11"},
+		{Path: "Case.txt", Size: 1, MTime: 100},
+		{Path: "local-only.txt", Size: 1, MTime: 100},
+		{Path: "size.txt", Size: 1, MTime: 100},
+		{Path: "hash.txt", Size: 1, MTime: 100, Hash: "11"},
+		{Path: "touched.txt", Size: 1, MTime: 100},
+		{Path: "fat.txt", Size: 1, MTime: 100},
+		{Path: "rehashed.txt", Size: 1, MTime: 100, Hash: "11"},
+	})
+	b := New(1, "Documents", SideRemote, `\\nas\docs`, []Entry{
+		{Path: "same.txt", Size: 1, MTime: 100, Hash: "11"},
+		{Path: "case.txt", Size: 1, MTime: 100},
+		{Path: "remote-only.txt", Size: 1, MTime: 100},
+		{Path: "size.txt", Size: 2, MTime: 100},
+		{Path: "hash.txt", Size: 1, MTime: 100, Hash: "22"},
+		{Path: "touched.txt", Size: 1, MTime: 500},
+		{Path: "fat.txt", Size: 1, MTime: 101},
+		{Path: "rehashed.txt", Size: 1, MTime: 500, Hash: "11"},
+	})
+
+	got := make(map[string]DiffKind)
+	for _, d := range Compare(a, b) {
+		got[d.Path] = d.Kind
+	}
+	want := map[string]DiffKind{
+		"local-only.txt":  OnlyInA,
+		"remote-only.txt": OnlyInB,
+		"size.txt":        SizeDiffers,
+		"hash.txt":        HashDiffers,
+		"touched.txt":     MTimeDiffers,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Compare() = %v, want %v", got, want)
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("%s: got %q, want %q", path, got[path], kind)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package metrics counts what sync health dashboards graph (syncs, bytes
+// transferred, queued actions, hydrations, SMB reconnections) and serves
+// them in the Prometheus text format on an optional local listener.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Process-wide metrics, updated by the components whatever the listener.
+var (
+	SyncsStarted   = newCounter("anemonesync_syncs_started_total", "Syncs started.", "")
+	SyncsSucceeded = newCounter("anemonesync_syncs_finished_total", "Syncs finished, by status.", `status="success"`)
+	SyncsPartial   = newCounter("anemonesync_syncs_finished_total", "Syncs finished, by status.", `status="partial"`)
+	SyncsFailed    = newCounter("anemonesync_syncs_finished_total", "Syncs finished, by status.", `status="failed"`)
+
+	BytesUploaded   = newCounter("anemonesync_bytes_transferred_total", "Bytes transferred by syncs, by direction.", `direction="upload"`)
+	BytesDownloaded = newCounter("anemonesync_bytes_transferred_total", "Bytes transferred by syncs, by direction.", `direction="download"`)
+
+	QueueDepth = newGauge("anemonesync_queue_depth", "Sync actions left to run in the syncs in progress.")
+
+	HydrationsServed = newCounter("anemonesync_hydration_requests_total", "Placeholder hydration requests from Windows, by result.", `result="served"`)
+	HydrationsFailed = newCounter("anemonesync_hydration_requests_total", "Placeholder hydration requests from Windows, by result.", `result="failed"`)
+
+	SMBReconnects = newCounter("anemonesync_smb_reconnects_total", "SMB sessions re-established after being lost mid-run.", "")
+)
+
+// metric is a sample of the exposition, in registration order.
+type metric struct {
+	name   string
+	help   string
+	kind   string // counter or gauge
+	labels string // `key="value"` ("" = none)
+	value  func() int64
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*metric
+)
+
+func register(m *metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// Counter only goes up.
+type Counter struct {
+	v atomic.Int64
+}
+
+func newCounter(name, help, labels string) *Counter {
+	c := &Counter{}
+	register(&metric{name: name, help: help, kind: "counter", labels: labels, value: c.v.Load})
+	return c
+}
+
+// Inc adds one.
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add adds n (ignored if negative).
+func (c *Counter) Add(n int64) {
+	if n > 0 {
+		c.v.Add(n)
+	}
+}
+
+// Value returns the count.
+func (c *Counter) Value() int64 { return c.v.Load() }
+
+// Gauge is a value read when scraped.
+type Gauge struct {
+	mu sync.RWMutex
+	fn func() int64
+}
+
+func newGauge(name, help string) *Gauge {
+	g := &Gauge{}
+	register(&metric{name: name, help: help, kind: "gauge", value: g.Value})
+	return g
+}
+
+// SetFunc sets the function computing the value (nil = 0).
+func (g *Gauge) SetFunc(fn func() int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.fn = fn
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() int64 {
+	g.mu.RLock()
+	fn := g.fn
+	g.mu.RUnlock()
+	if fn == nil {
+		return 0
+	}
+	return fn()
+}
+
+// Write writes every metric in the Prometheus text format.
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	metrics := append([]*metric(nil), registry...)
+	registryMu.Unlock()
+
+	written := make(map[string]bool)
+	for _, m := range metrics {
+		if !written[m.name] {
+			written[m.name] = true
+			if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+				return err
+			}
+		}
+		sample := m.name
+		if m.labels != "" {
+			sample += "{" + m.labels + "}"
+		}
+		if _, err := fmt.Fprintf(w, "%s %d\n", sample, m.value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
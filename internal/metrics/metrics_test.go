@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	BytesUploaded.Add(1024)
+	BytesUploaded.Add(-5) // Ignored: counters only go up
+	QueueDepth.SetFunc(func() int64 { return 7 })
+	defer QueueDepth.SetFunc(nil)
+
+	var buf bytes.Buffer
+	if err := Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE anemonesync_bytes_transferred_total counter\n",
+		`anemonesync_bytes_transferred_total{direction="upload"} 1024` + "\n",
+		`anemonesync_bytes_transferred_total{direction="download"} 0` + "\n",
+		"# TYPE anemonesync_queue_depth gauge\nanemonesync_queue_depth 7\n",
+		"anemonesync_smb_reconnects_total 0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output misses %q:\n%s", want, out)
+		}
+	}
+	// Labelled samples share one HELP/TYPE header
+	if n := strings.Count(out, "# TYPE anemonesync_syncs_finished_total"); n != 1 {
+		t.Errorf("anemonesync_syncs_finished_total has %d TYPE lines, want 1", n)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	SyncsStarted.Inc()
+
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+	if !strings.Contains(rec.Body.String(), "anemonesync_syncs_started_total ") {
+		t.Errorf("body misses the syncs started:\n%s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handle(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
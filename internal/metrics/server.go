@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultListen is the default address of the listener: local only, the
+// metrics are for a Prometheus running on this computer.
+const DefaultListen = "127.0.0.1:9464"
+
+// contentType is the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Serve listens on addr and serves the metrics on /metrics until ctx is
+// done. It returns once listening, with the error if the address can't be
+// used (e.g. already in use).
+func Serve(ctx context.Context, addr string, logger *zap.Logger) error {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if addr == "" {
+		addr = DefaultListen
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", handle)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("metrics listener stopped", zap.Error(err))
+		}
+	}()
+
+	logger.Info("serving Prometheus metrics", zap.String("address", listener.Addr().String()))
+	return nil
+}
+
+// handle serves the metrics.
+func handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	Write(w)
+}
@@ -0,0 +1,27 @@
+// Package partialfile names the files a download is written to until it
+// completes: the partial file of a large download, the sidecar with the
+// hashes of its completed chunks and the temporary copy of a restored
+// previous version. The package has no dependencies so that the scanner can
+// leave these files out without importing the SMB client.
+package partialfile
+
+import "strings"
+
+const (
+	// Suffix is the suffix of the partial file of a large download
+	Suffix = ".anemone-downloading"
+
+	// ChunksSuffix is appended to the partial file name for its chunk hashes
+	ChunksSuffix = ".chunks"
+
+	// VersionPrefix starts the name of a previous version being restored,
+	// before it is renamed to its final name in the job folder
+	VersionPrefix = ".anemone-version-"
+)
+
+// Is reports whether name is a partial download file, its chunk hashes or a
+// previous version being restored.
+func Is(name string) bool {
+	return strings.HasSuffix(name, Suffix) || strings.HasSuffix(name, Suffix+ChunksSuffix) ||
+		strings.HasPrefix(name, VersionPrefix)
+}
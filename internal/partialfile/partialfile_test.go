@@ -0,0 +1,19 @@
+package partialfile
+
+import "testing"
+
+func TestIs(t *testing.T) {
+	tests := map[string]bool{
+		"video.mkv":                            false,
+		"video.mkv" + Suffix:                   true,
+		"video.mkv.anemone-downloading.chunks": true,
+		"notes.chunks":                         false,
+		VersionPrefix + "123456":               true,
+		"report.anemone-version-1.docx":        false,
+	}
+	for name, want := range tests {
+		if got := Is(name); got != want {
+			t.Errorf("Is(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package scanner
+
+import "os"
+
+const (
+	fileAttributeReadOnly = 0x01
+	fileAttributeNormal   = 0x80
+)
+
+// fileAttributes emulates Windows attributes on other platforms:
+// only the read-only bit is derived from the owner write permission.
+func fileAttributes(info os.FileInfo) uint32 {
+	if info.Mode().Perm()&0200 == 0 {
+		return fileAttributeReadOnly
+	}
+	return fileAttributeNormal
+}
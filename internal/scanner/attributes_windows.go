@@ -0,0 +1,17 @@
+//go:build windows
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileAttributes returns the raw Windows attributes (FILE_ATTRIBUTE_*) of a file.
+func fileAttributes(info os.FileInfo) uint32 {
+	sys, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return 0
+	}
+	return sys.FileAttributes
+}
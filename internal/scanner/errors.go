@@ -44,6 +44,10 @@ var (
 	// Scanner errors
 	ErrTooManyErrors = errors.New("too many errors, scan aborted")
 	ErrScanAborted   = errors.New("scan aborted")
+
+	// Change journal errors (the scan walks the folder instead)
+	ErrJournalUnavailable = errors.New("change journal unavailable")
+	ErrJournalExpired     = errors.New("change journal no longer covers the last scan")
 )
 
 // ScanError represents an error during scanning with context
@@ -2,10 +2,14 @@ package scanner
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"go.uber.org/zap"
 )
@@ -16,6 +20,7 @@ type ExclusionLevel int
 const (
 	LevelIndividual ExclusionLevel = iota // Highest priority - specific file/dir exclusions
 	LevelJob                              // Job-specific pattern exclusions
+	LevelGroup                            // Curated exclusion groups enabled for the job
 	LevelGlobal                           // Global pattern exclusions (lowest priority)
 )
 
@@ -26,6 +31,8 @@ func (l ExclusionLevel) String() string {
 		return "individual"
 	case LevelJob:
 		return "job"
+	case LevelGroup:
+		return "group"
 	case LevelGlobal:
 		return "global"
 	default:
@@ -50,9 +57,13 @@ type ExclusionResult struct {
 
 // Excluder handles 3-level exclusion system
 type Excluder struct {
+	mu              sync.RWMutex              // Guards job rules, reloaded by every scan
 	globalPatterns  []*Pattern                // Global exclusion patterns
-	jobPatterns     map[int64][]*Pattern      // Job-specific patterns (jobID -> patterns)
+	jobPatterns     map[int64][]*Rule         // Job-specific rules (jobID -> rules, last match wins)
+	jobRoots        map[int64]string          // Job folder, root of the paths matched by job rules
 	individualPaths map[int64]map[string]bool // Individual path exclusions (jobID -> path -> excluded)
+	groupPatterns   map[int64][]*Pattern      // Patterns of enabled exclusion groups (jobID -> patterns)
+	disabledGlobals map[int64]map[string]bool // Global patterns disabled by a group toggle (jobID -> raw pattern)
 	logger          *zap.Logger               // Logger
 }
 
@@ -72,8 +83,11 @@ func NewExcluder(logger *zap.Logger) *Excluder {
 
 	return &Excluder{
 		globalPatterns:  make([]*Pattern, 0),
-		jobPatterns:     make(map[int64][]*Pattern),
+		jobPatterns:     make(map[int64][]*Rule),
+		jobRoots:        make(map[int64]string),
 		individualPaths: make(map[int64]map[string]bool),
+		groupPatterns:   make(map[int64][]*Pattern),
+		disabledGlobals: make(map[int64]map[string]bool),
 		logger:          logger.With(zap.String("component", "excluder")),
 	}
 }
@@ -121,17 +135,16 @@ func (e *Excluder) LoadDefaultExclusions(configPath string) error {
 	return nil
 }
 
-// AddJobPattern adds a job-specific exclusion pattern
+// AddJobPattern adds a job-specific exclusion rule (see Rule for the syntax)
 func (e *Excluder) AddJobPattern(jobID int64, patternStr string) error {
-	pattern, err := compilePattern(patternStr)
+	rule, err := ParseRule(patternStr)
 	if err != nil {
-		return WrapError(ErrInvalidPattern, "compile job pattern %s", patternStr)
+		return err
 	}
 
-	if e.jobPatterns[jobID] == nil {
-		e.jobPatterns[jobID] = make([]*Pattern, 0)
-	}
-	e.jobPatterns[jobID] = append(e.jobPatterns[jobID], pattern)
+	e.mu.Lock()
+	e.jobPatterns[jobID] = append(e.jobPatterns[jobID], rule)
+	e.mu.Unlock()
 
 	e.logger.Debug("added job pattern",
 		zap.Int64("job_id", jobID),
@@ -140,6 +153,57 @@ func (e *Excluder) AddJobPattern(jobID int64, patternStr string) error {
 	return nil
 }
 
+// SetJobRules replaces the exclusion rules of a job. Paths under root are
+// matched relative to it; other paths (and every path if root is empty) as
+// given.
+func (e *Excluder) SetJobRules(jobID int64, root string, rules []*Rule) {
+	e.mu.Lock()
+	e.jobPatterns[jobID] = rules
+	e.jobRoots[jobID] = root
+	e.mu.Unlock()
+
+	e.logger.Debug("set job rules",
+		zap.Int64("job_id", jobID),
+		zap.String("root", root),
+		zap.Int("rule_count", len(rules)))
+}
+
+// JobExclusions returns a matcher of the rules of a job, for paths the walk
+// does not see (nil if the job has no rules).
+func (e *Excluder) JobExclusions(jobID int64) *JobExclusions {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return NewJobExclusions(e.jobPatterns[jobID])
+}
+
+// writeRules writes every rule that applies to a job to w, in a stable order,
+// so that two scans can tell whether they left out the same paths.
+func (e *Excluder) writeRules(w io.Writer, jobID int64) {
+	e.mu.RLock()
+	fmt.Fprintf(w, "root %s\n", e.jobRoots[jobID])
+	for _, rule := range e.jobPatterns[jobID] {
+		fmt.Fprintf(w, "job %s\n", rule.Raw)
+	}
+	e.mu.RUnlock()
+
+	for _, pattern := range e.groupPatterns[jobID] {
+		fmt.Fprintf(w, "group %s\n", pattern.Raw)
+	}
+	for _, pattern := range e.globalPatterns {
+		if !e.disabledGlobals[jobID][pattern.Raw] {
+			fmt.Fprintf(w, "global %s\n", pattern.Raw)
+		}
+	}
+	paths := make([]string, 0, len(e.individualPaths[jobID]))
+	for path := range e.individualPaths[jobID] {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(w, "individual %s\n", path)
+	}
+}
+
 // AddIndividualPath adds an individual file/directory path exclusion
 func (e *Excluder) AddIndividualPath(jobID int64, path string) {
 	if e.individualPaths[jobID] == nil {
@@ -153,12 +217,53 @@ func (e *Excluder) AddIndividualPath(jobID int64, path string) {
 		zap.String("path", cleanPath))
 }
 
-// ShouldExclude checks if a file/directory should be excluded
-// Priority: Individual > Job > Global
+// SetJobGroups replaces the exclusion group patterns of a job.
+// Patterns of enabled groups are matched at group level. Patterns of disabled
+// groups are also skipped at global level, so a job can opt back in to folders
+// like .git/ or node_modules/ that the default exclusions skip.
+func (e *Excluder) SetJobGroups(jobID int64, enabled, disabled []string) {
+	patterns := make([]*Pattern, 0, len(enabled))
+	for _, patternStr := range enabled {
+		pattern, err := compilePattern(patternStr)
+		if err != nil {
+			e.logger.Warn("failed to compile group pattern",
+				zap.String("pattern", patternStr),
+				zap.Error(err))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	e.groupPatterns[jobID] = patterns
+
+	disabledSet := make(map[string]bool, len(disabled))
+	for _, patternStr := range disabled {
+		disabledSet[patternStr] = true
+	}
+	e.disabledGlobals[jobID] = disabledSet
+
+	e.logger.Debug("set job exclusion groups",
+		zap.Int64("job_id", jobID),
+		zap.Int("enabled_patterns", len(patterns)),
+		zap.Int("disabled_patterns", len(disabledSet)))
+}
+
+// ShouldExclude checks if a file/directory should be excluded, size rules
+// aside (see ShouldExcludeFile)
 func (e *Excluder) ShouldExclude(jobID int64, path string, isDir bool) *ExclusionResult {
+	return e.ShouldExcludeFile(jobID, path, isDir, -1)
+}
+
+// ShouldExcludeFile checks if a file/directory of the given size should be excluded
+// Priority: Individual > Job > Group > Global. A "!" job rule re-includes
+// what group and global patterns exclude.
+func (e *Excluder) ShouldExcludeFile(jobID int64, path string, isDir bool, size int64) *ExclusionResult {
 	cleanPath := filepath.Clean(path)
 	baseName := filepath.Base(cleanPath)
 
+	e.mu.RLock()
+	rules, root := e.jobPatterns[jobID], e.jobRoots[jobID]
+	e.mu.RUnlock()
+
 	// Level 1: Check individual path exclusions (highest priority)
 	if paths, exists := e.individualPaths[jobID]; exists {
 		if paths[cleanPath] {
@@ -171,22 +276,42 @@ func (e *Excluder) ShouldExclude(jobID int64, path string, isDir bool) *Exclusio
 		}
 	}
 
-	// Level 2: Check job-specific patterns
-	if patterns, exists := e.jobPatterns[jobID]; exists {
-		for _, pattern := range patterns {
-			if matchPattern(pattern, baseName, cleanPath, isDir) {
-				return &ExclusionResult{
-					Excluded: true,
-					Level:    LevelJob,
-					Pattern:  pattern.Raw,
-					Reason:   "matched job-specific pattern",
-				}
+	// Level 2: Check job-specific rules
+	if rule := lastMatch(rules, jobRelativePath(root, cleanPath), isDir, size); rule != nil {
+		if rule.Negate {
+			return &ExclusionResult{
+				Excluded: false,
+				Level:    LevelJob,
+				Pattern:  rule.Raw,
+				Reason:   "re-included by job-specific rule",
 			}
 		}
+		return &ExclusionResult{
+			Excluded: true,
+			Level:    LevelJob,
+			Pattern:  rule.Raw,
+			Reason:   "matched job-specific pattern",
+		}
 	}
 
-	// Level 3: Check global patterns (lowest priority)
+	// Level 3: Check patterns of enabled exclusion groups
+	for _, pattern := range e.groupPatterns[jobID] {
+		if matchPattern(pattern, baseName, cleanPath, isDir) {
+			return &ExclusionResult{
+				Excluded: true,
+				Level:    LevelGroup,
+				Pattern:  pattern.Raw,
+				Reason:   "matched exclusion group pattern",
+			}
+		}
+	}
+
+	// Level 4: Check global patterns (lowest priority)
+	disabled := e.disabledGlobals[jobID]
 	for _, pattern := range e.globalPatterns {
+		if disabled[pattern.Raw] {
+			continue
+		}
 		if matchPattern(pattern, baseName, cleanPath, isDir) {
 			return &ExclusionResult{
 				Excluded: true,
@@ -228,6 +353,35 @@ func compilePattern(patternStr string) (*Pattern, error) {
 	return pattern, nil
 }
 
+// CompileGlob compiles a glob matched against whole job-relative paths
+// (e.g. "Projects/Active/**"), ignoring case like Windows paths.
+func CompileGlob(glob string) (*Pattern, error) {
+	glob = strings.Trim(strings.ReplaceAll(glob, "\\", "/"), "/")
+	regex, err := regexp.Compile("(?i)" + globToRegex(glob))
+	if err != nil {
+		return nil, WrapError(err, "compile glob %s", glob)
+	}
+	return &Pattern{Raw: glob, Regex: regex}, nil
+}
+
+// MatchPath reports whether a job-relative path matches a pattern compiled
+// by CompileGlob.
+func (p *Pattern) MatchPath(relPath string) bool {
+	return p.Regex.MatchString(filepath.ToSlash(relPath))
+}
+
+// Root returns the deepest folder holding every path matching a pattern
+// compiled by CompileGlob: its leading segments without wildcards
+// ("Projects/Active/**" -> "Projects/Active", "" = anywhere in the job).
+func (p *Pattern) Root() string {
+	parts := strings.Split(p.Raw, "/")
+	n := 0
+	for n < len(parts)-1 && !strings.ContainsAny(parts[n], "*?") {
+		n++
+	}
+	return strings.Join(parts[:n], "/")
+}
+
 // globToRegex converts a glob pattern to a regex pattern
 func globToRegex(glob string) string {
 	// Escape special regex characters except * and ?
@@ -283,17 +437,31 @@ func matchPattern(pattern *Pattern, baseName, fullPath string, isDir bool) bool
 	return false
 }
 
+// jobRelativePath returns path relative to the job root, "/"-separated, or
+// path itself if it is not under root.
+func jobRelativePath(root, path string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
 // GetStatistics returns statistics about loaded exclusion rules
 func (e *Excluder) GetStatistics() map[string]interface{} {
+	e.mu.RLock()
 	jobCount := len(e.jobPatterns)
+	e.mu.RUnlock()
 	individualCount := 0
 	for _, paths := range e.individualPaths {
 		individualCount += len(paths)
 	}
 
 	return map[string]interface{}{
-		"global_patterns":  len(e.globalPatterns),
-		"job_pattern_sets": jobCount,
-		"individual_paths": individualCount,
+		"global_patterns":    len(e.globalPatterns),
+		"job_pattern_sets":   jobCount,
+		"group_pattern_sets": len(e.groupPatterns),
+		"individual_paths":   individualCount,
 	}
 }
@@ -383,3 +383,143 @@ func BenchmarkExcluder_LargeBatchCheck(b *testing.B) {
 		}
 	}
 }
+
+func TestExcluder_JobGroups(t *testing.T) {
+	h := NewTestHelpers(t)
+	excluder := NewExcluder(h.GetTestLogger(false))
+
+	configPath := filepath.Join("..", "..", "configs", "default_exclusions.json")
+	h.AssertNoError(excluder.LoadDefaultExclusions(configPath), "load default exclusions")
+
+	// Job 1 keeps the system group, opts back in to .git folders
+	excluder.SetJobGroups(1, []string{"$RECYCLE.BIN/", "System Volume Information/"}, []string{".git/"})
+
+	result := excluder.ShouldExclude(1, "$RECYCLE.BIN", true)
+	if !result.Excluded || result.Level != LevelGroup {
+		t.Errorf("$RECYCLE.BIN should be excluded at group level, got excluded=%v level=%s",
+			result.Excluded, result.Level.String())
+	}
+	if !excluder.ShouldExclude(1, "System Volume Information", true).Excluded {
+		t.Error("System Volume Information should be excluded")
+	}
+	if excluder.ShouldExclude(1, "$RECYCLE.BIN", false).Excluded {
+		t.Error("directory pattern should not match a file")
+	}
+
+	// Disabled group pattern overrides the default global exclusion
+	if excluder.ShouldExclude(1, ".git", true).Excluded {
+		t.Error(".git should be synced when the group is disabled for the job")
+	}
+	if !excluder.ShouldExclude(1, "node_modules", true).Excluded {
+		t.Error("other global patterns should still apply")
+	}
+
+	// Other jobs are unaffected
+	if !excluder.ShouldExclude(2, ".git", true).Excluded {
+		t.Error(".git should still be excluded for other jobs")
+	}
+	if excluder.ShouldExclude(2, "$RECYCLE.BIN", true).Excluded {
+		t.Error("group patterns should not apply to other jobs")
+	}
+
+	// Setting groups again replaces the previous state
+	excluder.SetJobGroups(1, nil, nil)
+	if excluder.ShouldExclude(1, "$RECYCLE.BIN", true).Excluded {
+		t.Error("group patterns should be replaced")
+	}
+	if !excluder.ShouldExclude(1, ".git", true).Excluded {
+		t.Error(".git should be excluded again once the override is removed")
+	}
+}
+
+func TestExcluder_JobRules(t *testing.T) {
+	h := NewTestHelpers(t)
+	excluder := NewExcluder(h.GetTestLogger(false))
+
+	configPath := filepath.Join("..", "..", "configs", "default_exclusions.json")
+	err := excluder.LoadDefaultExclusions(configPath)
+	h.AssertNoError(err, "load default exclusions")
+
+	jobID := int64(1)
+	root := filepath.Join(h.CreateTempDir(), "job")
+	rules, errs := ParseRules("/docs/drafts/\n*.tmp\n!keep.tmp\nsize>1KB\n")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	excluder.SetJobRules(jobID, root, rules)
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		size     int64
+		excluded bool
+		level    ExclusionLevel
+	}{
+		{filepath.Join(root, "docs", "drafts"), true, -1, true, LevelJob},
+		{filepath.Join(root, "other", "docs", "drafts"), true, -1, false, LevelIndividual},
+		{filepath.Join(root, "a.tmp"), false, 10, true, LevelJob},
+		{filepath.Join(root, "keep.tmp"), false, 10, false, LevelJob}, // Re-included over the global *.tmp
+		{filepath.Join(root, "big.bin"), false, 4096, true, LevelJob},
+		{filepath.Join(root, "small.bin"), false, 10, false, LevelIndividual},
+	}
+	for _, tt := range tests {
+		result := excluder.ShouldExcludeFile(jobID, tt.path, tt.isDir, tt.size)
+		if result.Excluded != tt.excluded {
+			t.Errorf("%s: expected excluded=%v, got %v", tt.path, tt.excluded, result.Excluded)
+		}
+		if result.Pattern != "" && result.Level != tt.level {
+			t.Errorf("%s: expected level %s, got %s", tt.path, tt.level, result.Level)
+		}
+	}
+
+	// Rules are replaced, not appended
+	excluder.SetJobRules(jobID, root, nil)
+	if excluder.ShouldExcludeFile(jobID, filepath.Join(root, "big.bin"), false, 4096).Excluded {
+		t.Error("rules should be replaced")
+	}
+	if excluder.JobExclusions(jobID) != nil {
+		t.Error("a job without rules has no exclusions")
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{"Projects/Active/**", "Projects/Active/plan.docx", true},
+		{"Projects/Active/**", "projects/active/2024/q1/report.xlsx", true},
+		{"Projects/Active/**", "Projects/Archive/plan.docx", false},
+		{"Projects/*/Docs/**", "Projects/A/Docs/spec.pdf", true},
+		{"Projects/*/Docs/**", "Projects/A/B/Docs/spec.pdf", false},
+		{"Photos/*.jpg", "Photos/IMG_001.JPG", true},
+		{"Photos/*.jpg", "Photos/2024/IMG_001.jpg", false},
+		{"\\Reports\\**", "Reports/2024.pdf", true},
+	}
+
+	for _, tt := range tests {
+		pattern, err := CompileGlob(tt.glob)
+		if err != nil {
+			t.Fatalf("CompileGlob(%q): %v", tt.glob, err)
+		}
+		if got := pattern.MatchPath(tt.path); got != tt.matches {
+			t.Errorf("%q matching %q = %v, want %v", tt.glob, tt.path, got, tt.matches)
+		}
+	}
+
+	roots := map[string]string{
+		"Projects/Active/**": "Projects/Active",
+		"Projects/*/Docs/**": "Projects",
+		"Photos/*.jpg":       "Photos",
+		"Reports/2024.pdf":   "Reports",
+		"**/*.psd":           "",
+		"*.txt":              "",
+	}
+	for glob, want := range roots {
+		pattern, _ := CompileGlob(glob)
+		if got := pattern.Root(); got != want {
+			t.Errorf("root of %q = %q, want %q", glob, got, want)
+		}
+	}
+}
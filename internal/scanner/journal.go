@@ -0,0 +1,286 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
+	"go.uber.org/zap"
+)
+
+// journalMaxChanges is the number of changed paths above which walking the
+// job folder is cheaper than replaying the change journal.
+const journalMaxChanges = 100000
+
+// JournalPosition is a position in the change journal of a volume.
+type JournalPosition struct {
+	Volume    string // Volume of the folder, e.g. "C:"
+	JournalID int64  // Identifies the journal: it changes when the journal is recreated
+	USN       int64  // Update sequence number of the next change
+}
+
+// changeJournal lists what changed under a folder from the change journal of
+// its volume (the NTFS USN journal on Windows).
+type changeJournal interface {
+	// Position returns the current position of the journal of the volume of root.
+	Position(root string) (JournalPosition, error)
+
+	// Changes returns the files and folders under root, relative to it with
+	// forward slashes, created, modified, renamed or deleted from since up
+	// to until. It returns ErrJournalExpired when the journal was recreated
+	// or no longer holds the changes since since.
+	Changes(root string, since, until JournalPosition) ([]string, error)
+}
+
+// journalCheckpoint returns the checkpoint of a scan of the whole job folder:
+// the current position of the change journal of its volume and the rules the
+// scan applies. It must be taken before the folder is read. Returns nil when
+// the journal is disabled or not available for the folder.
+func (s *Scanner) journalCheckpoint(req ScanRequest) *database.ScanCheckpoint {
+	if s.journal == nil || !s.config.Sync.Performance.ChangeJournal || req.Subtree != "" {
+		return nil
+	}
+	pos, err := s.journal.Position(req.BasePath)
+	if err != nil {
+		s.logger.Debug("change journal not available, walking the job folder",
+			zap.Int64("job_id", req.JobID),
+			zap.Error(err))
+		return nil
+	}
+	return &database.ScanCheckpoint{
+		JobID:     req.JobID,
+		Root:      filepath.Clean(req.BasePath),
+		Volume:    pos.Volume,
+		JournalID: pos.JournalID,
+		USN:       pos.USN,
+		Rules:     s.rulesFingerprint(req),
+		ScannedAt: time.Now().Unix(),
+	}
+}
+
+// rulesFingerprint returns a digest of the exclusions and selection a scan of
+// req applies: a snapshot made with other rules lacks the files they left out.
+func (s *Scanner) rulesFingerprint(req ScanRequest) string {
+	h := sha256.New()
+	s.excluder.writeRules(h, req.JobID)
+	for _, dir := range req.Selection.Include {
+		fmt.Fprintf(h, "include %s\n", dir)
+	}
+	for _, dir := range req.Selection.Exclude {
+		fmt.Fprintf(h, "exclude %s\n", dir)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// journalReplay is what a change journal replay found, to update the local
+// snapshot with.
+type journalReplay struct {
+	changed int      // Paths read from the journal
+	removed []string // Snapshot entries under changed paths
+}
+
+// replayJournal rebuilds the listing of the job folder from the local
+// snapshot of the previous scan and the change journal: only the paths
+// changed since are visited again (with visit, or recorded in excludedFiles),
+// every other file is taken from the snapshot into result and foundFiles.
+// When the snapshot can't be replayed, it returns an error wrapping
+// ErrJournalUnavailable: the caller walks the folder from scratch.
+func (s *Scanner) replayJournal(ctx context.Context, req ScanRequest, next *database.ScanCheckpoint,
+	result *ScanResult, foundFiles, excludedFiles map[string]bool, visit WalkFunc) (*journalReplay, error) {
+
+	prev, err := s.db.GetScanCheckpoint(req.JobID)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrJournalUnavailable, err)
+	case prev == nil:
+		return nil, fmt.Errorf("%w: no snapshot of a previous scan", ErrJournalUnavailable)
+	case !strings.EqualFold(prev.Root, next.Root):
+		return nil, fmt.Errorf("%w: the job folder changed", ErrJournalUnavailable)
+	case prev.Rules != next.Rules:
+		return nil, fmt.Errorf("%w: the exclusions or the selection changed", ErrJournalUnavailable)
+	}
+
+	changed, err := s.journal.Changes(req.BasePath,
+		JournalPosition{Volume: prev.Volume, JournalID: prev.JournalID, USN: prev.USN},
+		JournalPosition{Volume: next.Volume, JournalID: next.JournalID, USN: next.USN})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJournalUnavailable, err)
+	}
+	if len(changed) > journalMaxChanges {
+		return nil, fmt.Errorf("%w: %d paths changed", ErrJournalUnavailable, len(changed))
+	}
+	snapshot, err := s.db.GetLocalSnapshot(req.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJournalUnavailable, err)
+	}
+	states, err := s.db.GetAllFileStates(req.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJournalUnavailable, err)
+	}
+
+	// NTFS names are case-insensitive
+	dirty := make(map[string]bool, len(changed))
+	for _, rel := range changed {
+		dirty[strings.ToLower(rel)] = true
+	}
+	underDirty := func(rel string) bool {
+		for p := strings.ToLower(rel); ; p = path.Dir(p) {
+			if dirty[p] {
+				return true
+			}
+			if !strings.Contains(p, "/") {
+				return false
+			}
+		}
+	}
+
+	// Visit the changed paths again, outermost first: a changed folder (new,
+	// renamed or moved in) is walked whole
+	sort.Strings(changed)
+	for _, rel := range changed {
+		if parent := path.Dir(rel); parent != "." && underDirty(parent) {
+			continue
+		}
+		if err := s.revisit(ctx, req, rel, excludedFiles, visit); err != nil {
+			return nil, err
+		}
+	}
+
+	// Files of the snapshot under no changed path are as the previous scan left them
+	replay := &journalReplay{changed: len(changed)}
+	byPath := make(map[string]*database.FileState, len(states))
+	for _, state := range states {
+		byPath[state.LocalPath] = state
+	}
+	for rel, entry := range snapshot {
+		if underDirty(rel) {
+			replay.removed = append(replay.removed, rel)
+			continue
+		}
+		if entry.Excluded {
+			excludedFiles[rel] = true
+			continue
+		}
+		foundFiles[rel] = true
+		result.TotalFiles++
+		result.ProcessedFiles++
+		fileInfo := s.snapshotFileInfo(req, entry, byPath[rel])
+		switch fileInfo.Status {
+		case StatusNew:
+			result.NewFiles = append(result.NewFiles, fileInfo)
+		case StatusModified:
+			result.ModifiedFiles = append(result.ModifiedFiles, fileInfo)
+		default:
+			result.UnchangedFiles = append(result.UnchangedFiles, fileInfo)
+			result.SkippedFiles++
+		}
+	}
+	return replay, nil
+}
+
+// revisit visits a changed path of the job folder the way the walk of the
+// whole folder would: folders are walked, excluded files recorded in
+// excludedFiles, paths that no longer exist skipped.
+func (s *Scanner) revisit(ctx context.Context, req ScanRequest, rel string, excludedFiles map[string]bool, visit WalkFunc) error {
+	if s.leftOut(req, rel) {
+		return nil
+	}
+	absPath := filepath.Join(req.BasePath, filepath.FromSlash(rel))
+	info, err := os.Lstat(absPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		// Its files would look deleted
+		return fmt.Errorf("%w: %v", ErrJournalUnavailable, err)
+	}
+
+	metadata := ExtractMetadataWithStat(absPath, info)
+	switch {
+	case metadata.IsSymlink:
+		return nil
+	case metadata.IsDir:
+		if req.Selection.SkipDir(rel) {
+			return nil
+		}
+		return s.walkTree(req.JobID, absPath, true, func(dir string) bool {
+			return req.Selection.SkipDir(relativeTo(req.BasePath, dir))
+		}, visit)
+	}
+
+	select {
+	case <-ctx.Done():
+		return WrapError(ErrScanAborted, "context canceled")
+	default:
+	}
+	if partialfile.Is(info.Name()) {
+		return nil
+	}
+	if excl := s.excluder.ShouldExcludeFile(req.JobID, absPath, false, metadata.Size); excl.Excluded {
+		if excl.Level == LevelJob {
+			excludedFiles[rel] = true
+		}
+		return nil
+	}
+	return visit(absPath, metadata)
+}
+
+// leftOut reports whether the walk of the whole job folder never reaches rel:
+// a folder above it is excluded or left out of the selection.
+func (s *Scanner) leftOut(req ScanRequest, rel string) bool {
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		absDir := filepath.Join(req.BasePath, filepath.FromSlash(dir))
+		if s.excluder.ShouldExclude(req.JobID, absDir, true).Excluded || req.Selection.SkipDir(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotFileInfo returns the scan result of a file unchanged since the
+// previous scan, compared with its sync state like processFile does.
+func (s *Scanner) snapshotFileInfo(req ScanRequest, entry *database.LocalSnapshotEntry, state *database.FileState) *FileInfo {
+	fileInfo := &FileInfo{
+		LocalPath:  entry.Path,
+		RemotePath: s.mapToRemotePath(req.BasePath, req.RemoteBase, filepath.Join(req.BasePath, filepath.FromSlash(entry.Path))),
+		Size:       entry.Size,
+		MTime:      time.Unix(0, entry.MTime),
+		Hash:       entry.Hash,
+		Attributes: uint32(entry.Attributes),
+	}
+	switch {
+	case entry.Hash == placeholderHash:
+		fileInfo.Status = StatusUnchanged
+	case state == nil:
+		fileInfo.Status = StatusNew
+	case SameMetadata(&FileMetadata{Size: entry.Size, MTime: fileInfo.MTime},
+		&FileMetadata{Size: state.Size, MTime: time.Unix(state.MTime, 0)}):
+		fileInfo.Status = StatusUnchanged
+		fileInfo.Hash = state.Hash
+	case entry.Hash == state.Hash:
+		fileInfo.Status = StatusUnchanged
+	default:
+		fileInfo.Status = StatusModified
+	}
+	return fileInfo
+}
+
+// snapshotEntry returns the local snapshot entry of a scanned file.
+func snapshotEntry(fileInfo *FileInfo) *database.LocalSnapshotEntry {
+	return &database.LocalSnapshotEntry{
+		Path:       fileInfo.LocalPath,
+		Size:       fileInfo.Size,
+		MTime:      fileInfo.MTime.UnixNano(),
+		Hash:       fileInfo.Hash,
+		Attributes: int64(fileInfo.Attributes),
+	}
+}
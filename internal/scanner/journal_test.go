@@ -0,0 +1,157 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+)
+
+// fakeJournal reports the paths set in changes, whatever the positions.
+type fakeJournal struct {
+	usn     int64
+	changes []string
+	err     error
+}
+
+func (j *fakeJournal) Position(root string) (JournalPosition, error) {
+	j.usn++
+	return JournalPosition{Volume: "C:", JournalID: 1, USN: j.usn}, nil
+}
+
+func (j *fakeJournal) Changes(root string, since, until JournalPosition) ([]string, error) {
+	return j.changes, j.err
+}
+
+func TestScanner_JournalReplay(t *testing.T) {
+	h := NewTestHelpers(t)
+	tempDir := h.CreateTempDir()
+	db := h.SetupTestDB()
+
+	h.CreateTestFile(filepath.Join(tempDir, "a.txt"), []byte("a"))
+	h.CreateTestFile(filepath.Join(tempDir, "dir", "b.txt"), []byte("b"))
+	h.CreateTestFile(filepath.Join(tempDir, "dir", "c.txt"), []byte("c"))
+	h.CreateTestFile(filepath.Join(tempDir, "keep", "d.txt"), []byte("d"))
+	jobID := h.CreateTestJob(db, tempDir, "\\\\server\\share")
+
+	cfg := &config.Config{
+		Paths: config.PathsConfig{ConfigDir: tempDir},
+		Sync: config.SyncConfig{
+			Performance: config.PerformanceConfig{
+				HashAlgorithm: "sha256",
+				BufferSizeMB:  4,
+				ChangeJournal: true,
+			},
+		},
+	}
+	scanner, err := NewScanner(cfg, db, h.GetTestLogger(false))
+	h.AssertNoError(err, "create scanner")
+	defer scanner.Close()
+	journal := &fakeJournal{}
+	scanner.journal = journal
+
+	req := ScanRequest{JobID: jobID, BasePath: tempDir, RemoteBase: "\\\\server\\share"}
+	sizes := func(result *ScanResult) map[string]int64 {
+		got := make(map[string]int64)
+		for _, files := range [][]*FileInfo{result.NewFiles, result.ModifiedFiles, result.UnchangedFiles} {
+			for _, f := range files {
+				got[f.LocalPath] = f.Size
+			}
+		}
+		return got
+	}
+
+	// No snapshot yet: the folder is walked
+	result, err := scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "first scan")
+	h.AssertEqual(0, result.JournalChanges, "journal changes of first scan")
+	h.AssertEqual(4, result.TotalFiles, "total files of first scan")
+	checkpoint, err := db.GetScanCheckpoint(jobID)
+	if err != nil || checkpoint == nil {
+		t.Fatalf("no checkpoint saved after the first scan (err=%v)", err)
+	}
+
+	// Only the paths reported changed are visited again: keep/d.txt keeps the
+	// size the snapshot recorded
+	h.CreateTestFile(filepath.Join(tempDir, "dir", "b.txt"), []byte("bigger"))
+	os.Remove(filepath.Join(tempDir, "dir", "c.txt"))
+	os.Remove(filepath.Join(tempDir, "a.txt"))
+	h.CreateTestFile(filepath.Join(tempDir, "new", "e.txt"), []byte("e"))
+	h.CreateTestFile(filepath.Join(tempDir, "keep", "d.txt"), []byte("unseen"))
+	journal.changes = []string{"a.txt", "dir/b.txt", "dir/c.txt", "new"}
+
+	result, err = scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "journal scan")
+	h.AssertEqual(4, result.JournalChanges, "journal changes")
+	got := sizes(result)
+	want := map[string]int64{"dir/b.txt": 6, "keep/d.txt": 1, "new/e.txt": 1}
+	if len(got) != len(want) {
+		t.Fatalf("journal scan found %v, want %v", got, want)
+	}
+	for path, size := range want {
+		if got[path] != size {
+			t.Errorf("%s: size %d, want %d", path, got[path], size)
+		}
+	}
+
+	// The snapshot was updated: replaying nothing gives the same listing
+	journal.changes = nil
+	result, err = scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "empty journal scan")
+	h.AssertEqual(3, result.TotalFiles, "total files of empty journal scan")
+	h.AssertEqual(int64(6), sizes(result)["dir/b.txt"], "size of dir/b.txt")
+
+	// Other rules than the snapshot's: the folder is walked again
+	req.Selection = Selection{Exclude: []string{"new"}}
+	result, err = scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "scan with a new selection")
+	h.AssertEqual(0, result.JournalChanges, "journal changes with a new selection")
+	h.AssertEqual(int64(6), sizes(result)["keep/d.txt"], "size of keep/d.txt after a walk")
+
+	// An expired journal falls back to a walk too
+	journal.changes, journal.err = []string{"keep/d.txt"}, ErrJournalExpired
+	result, err = scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "scan with an expired journal")
+	h.AssertEqual(0, result.JournalChanges, "journal changes with an expired journal")
+	h.AssertEqual(2, result.TotalFiles, "total files with an expired journal")
+}
+
+func TestScanner_JournalSkipsExcludedFolders(t *testing.T) {
+	h := NewTestHelpers(t)
+	tempDir := h.CreateTempDir()
+	db := h.SetupTestDB()
+
+	h.CreateTestFile(filepath.Join(tempDir, "a.txt"), []byte("a"))
+	h.CreateTestFile(filepath.Join(tempDir, ".anemoneignore"), []byte("build/\n"))
+	jobID := h.CreateTestJob(db, tempDir, "\\\\server\\share")
+
+	cfg := &config.Config{
+		Paths: config.PathsConfig{ConfigDir: tempDir},
+		Sync: config.SyncConfig{
+			Performance: config.PerformanceConfig{
+				HashAlgorithm: "sha256",
+				BufferSizeMB:  4,
+				ChangeJournal: true,
+			},
+		},
+	}
+	scanner, err := NewScanner(cfg, db, h.GetTestLogger(false))
+	h.AssertNoError(err, "create scanner")
+	defer scanner.Close()
+	journal := &fakeJournal{}
+	scanner.journal = journal
+
+	req := ScanRequest{JobID: jobID, BasePath: tempDir, RemoteBase: "\\\\server\\share"}
+	first, err := scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "first scan")
+
+	// A file changed inside an excluded folder is not listed
+	h.CreateTestFile(filepath.Join(tempDir, "build", "out", "app.exe"), []byte("exe"))
+	journal.changes = []string{"build/out/app.exe", "build/out"}
+	result, err := scanner.Scan(context.Background(), req)
+	h.AssertNoError(err, "journal scan")
+	h.AssertEqual(2, result.JournalChanges, "journal changes")
+	h.AssertEqual(first.TotalFiles, result.TotalFiles, "total files")
+}
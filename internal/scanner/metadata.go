@@ -16,6 +16,7 @@ type FileMetadata struct {
 	IsSymlink     bool        // Whether it's a symlink
 	IsPlaceholder bool        // Whether it's a Cloud Files placeholder (would trigger hydration)
 	Mode          os.FileMode // File mode/permissions
+	Attributes    uint32      // Raw Windows attributes (FILE_ATTRIBUTE_*)
 }
 
 // ExtractMetadata extracts metadata from a file path using os.Stat
@@ -38,12 +39,13 @@ func ExtractMetadata(path string) (*FileMetadata, error) {
 
 	// Extract metadata
 	metadata := &FileMetadata{
-		Path:      cleanPath,
-		Size:      info.Size(),
-		MTime:     info.ModTime(),
-		IsDir:     info.IsDir(),
-		IsSymlink: info.Mode()&os.ModeSymlink != 0,
-		Mode:      info.Mode(),
+		Path:       cleanPath,
+		Size:       info.Size(),
+		MTime:      info.ModTime(),
+		IsDir:      info.IsDir(),
+		IsSymlink:  info.Mode()&os.ModeSymlink != 0,
+		Mode:       info.Mode(),
+		Attributes: fileAttributes(info),
 	}
 
 	return metadata, nil
@@ -60,6 +62,7 @@ func ExtractMetadataWithStat(path string, info os.FileInfo) *FileMetadata {
 		IsSymlink:     info.Mode()&os.ModeSymlink != 0,
 		IsPlaceholder: isPlaceholderInfo(info),
 		Mode:          info.Mode(),
+		Attributes:    fileAttributes(info),
 	}
 }
 
@@ -81,14 +84,50 @@ func (m *FileMetadata) String() string {
 }
 
 // SameMetadata compares two FileMetadata for equality (size + mtime)
-// Used for quick change detection without hashing
+// Used for quick change detection without hashing. A whole-hour shift
+// (see ShiftedMTime) is not ignored here: the hash tells.
 func SameMetadata(a, b *FileMetadata) bool {
 	if a == nil || b == nil {
 		return false
 	}
-	// Compare size and mtime (truncate to seconds for SMB compatibility)
-	return a.Size == b.Size &&
-		a.MTime.Truncate(time.Second).Equal(b.MTime.Truncate(time.Second))
+	return a.Size == b.Size && SameMTime(a.MTime, b.MTime)
+}
+
+// MTimeTolerance is how far apart two modification times of the same file
+// may be: FAT stores them to 2 seconds, and SMB servers round them their own way.
+const MTimeTolerance = 2 * time.Second
+
+// maxMTimeShift is the widest whole-hour shift ShiftedMTime ignores, the
+// widest offset from UTC.
+const maxMTimeShift = 14 * time.Hour
+
+// SameMTime reports whether two modification times are the same within
+// MTimeTolerance.
+func SameMTime(a, b time.Time) bool {
+	diff := a.Sub(b)
+	return diff < MTimeTolerance && diff > -MTimeTolerance
+}
+
+// ShiftedMTime reports whether two modification times differ by whole hours,
+// within MTimeTolerance: the same time read with another UTC offset, as FAT
+// drives report it after a daylight saving change, or Samba servers keeping
+// local time. A file saved again exactly hours later looks the same, so it
+// only counts when nothing else tells two files apart.
+func ShiftedMTime(a, b time.Time) bool {
+	diff := a.Sub(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff <= time.Hour-MTimeTolerance || diff >= maxMTimeShift+MTimeTolerance {
+		return false
+	}
+	off := diff % time.Hour
+	return off < MTimeTolerance || off > time.Hour-MTimeTolerance
+}
+
+// NewerMTime reports whether a is more recent than b, beyond MTimeTolerance.
+func NewerMTime(a, b time.Time) bool {
+	return a.After(b) && !SameMTime(a, b)
 }
 
 // MTimeDiffSeconds returns the absolute difference in modification time in seconds
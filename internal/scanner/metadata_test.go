@@ -209,6 +209,41 @@ func TestMTimeDiffSeconds(t *testing.T) {
 	}
 }
 
+func TestSameMTime(t *testing.T) {
+	base := time.Date(2025, 10, 26, 0, 30, 1, 500000000, time.UTC)
+
+	tests := []struct {
+		name    string
+		b       time.Time
+		same    bool
+		shifted bool
+	}{
+		{"identical", base, true, false},
+		{"rounded to 2 seconds by FAT", base.Add(500 * time.Millisecond), true, false},
+		{"truncated by SMB", base.Truncate(time.Second), true, false},
+		{"2 seconds apart", base.Add(-2 * time.Second), false, false},
+		{"daylight saving shift", base.Add(time.Hour), false, true},
+		{"daylight saving shift on FAT", base.Add(-time.Hour + 500*time.Millisecond), false, true},
+		{"time zone offset", base.Add(5 * time.Hour), false, true},
+		{"an hour and a minute", base.Add(time.Hour + time.Minute), false, false},
+		{"a day", base.Add(24 * time.Hour), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameMTime(base, tt.b); got != tt.same {
+				t.Errorf("SameMTime = %v, want %v", got, tt.same)
+			}
+			if got := ShiftedMTime(base, tt.b); got != tt.shifted {
+				t.Errorf("ShiftedMTime = %v, want %v", got, tt.shifted)
+			}
+			if got := NewerMTime(tt.b, base); got != (!tt.same && tt.b.After(base)) {
+				t.Errorf("NewerMTime = %v", got)
+			}
+		})
+	}
+}
+
 func TestFileMetadata_String(t *testing.T) {
 	now := time.Now()
 	metadata := &FileMetadata{
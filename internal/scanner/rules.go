@@ -0,0 +1,379 @@
+package scanner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// IgnoreFileName is the file of exclusion rules at the root of a job folder,
+// one rule per line like a .gitignore. It is synced like any other file, so
+// every machine of the job shares its rules.
+const IgnoreFileName = ".anemoneignore"
+
+// Rule prefixes
+const (
+	regexRulePrefix = "re:"
+	sizeRulePrefix  = "size"
+	extRulePrefix   = "ext:"
+)
+
+type ruleKind int
+
+const (
+	ruleGlob ruleKind = iota
+	ruleRegex
+	ruleSize
+	ruleExtension
+)
+
+// Rule is a job exclusion rule, from the exclusions table or a line of
+// .anemoneignore:
+//
+//	*.log, temp/        glob on the name, at any depth ("/" at the end: folders only)
+//	/build, docs/*.pdf  glob on the path from the job root (a "/" other than at the end)
+//	**/cache, logs/**   "**" spans folders
+//	re:^Archive/\d{4}/  regular expression on the path from the job root
+//	size>100MB          files larger (>, >=) or smaller (<, <=) than a size in B, KB, MB or GB
+//	ext:iso,vhdx        file extensions
+//	!keep.log           re-includes what the rules before it excluded
+//
+// Matching ignores case, like Windows paths.
+type Rule struct {
+	Raw     string // Rule as written
+	Negate  bool   // "!" rule: the path is included
+	DirOnly bool   // Only matches folders
+
+	kind     ruleKind
+	regex    *regexp.Regexp  // Glob (compiled) and regex rules
+	anchored bool            // Glob matched against the path rather than the name
+	sizeOp   string          // Size rules: >, >=, < or <=
+	size     int64           // Size rules: bytes
+	exts     map[string]bool // Extension rules: lower case, without dot
+}
+
+// ParseRule parses a job exclusion rule.
+func ParseRule(line string) (*Rule, error) {
+	raw := strings.TrimSpace(line)
+	rule := &Rule{Raw: raw}
+
+	text := raw
+	if strings.HasPrefix(text, "!") {
+		rule.Negate = true
+		text = strings.TrimSpace(text[1:])
+	}
+	if text == "" {
+		return nil, WrapError(ErrInvalidPattern, "empty rule %q", raw)
+	}
+
+	switch {
+	case strings.HasPrefix(text, regexRulePrefix):
+		regex, err := regexp.Compile("(?i)" + strings.TrimPrefix(text, regexRulePrefix))
+		if err != nil {
+			return nil, WrapError(ErrInvalidPattern, "rule %q: %v", raw, err)
+		}
+		rule.kind = ruleRegex
+		rule.regex = regex
+
+	case strings.HasPrefix(text, extRulePrefix):
+		rule.kind = ruleExtension
+		rule.exts = make(map[string]bool)
+		for _, ext := range strings.Split(strings.TrimPrefix(text, extRulePrefix), ",") {
+			if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+				rule.exts[ext] = true
+			}
+		}
+		if len(rule.exts) == 0 {
+			return nil, WrapError(ErrInvalidPattern, "rule %q: no extension", raw)
+		}
+
+	case isSizeRule(text):
+		op, size, err := parseSizeRule(text)
+		if err != nil {
+			return nil, WrapError(ErrInvalidPattern, "rule %q: %v", raw, err)
+		}
+		rule.kind = ruleSize
+		rule.sizeOp = op
+		rule.size = size
+
+	default:
+		glob := strings.ReplaceAll(text, "\\", "/")
+		if strings.HasSuffix(glob, "/") {
+			rule.DirOnly = true
+			glob = strings.TrimRight(glob, "/")
+		}
+		rule.anchored = strings.Contains(glob, "/")
+		glob = strings.TrimPrefix(glob, "/")
+		if glob == "" {
+			return nil, WrapError(ErrInvalidPattern, "rule %q matches the job root", raw)
+		}
+		regex, err := regexp.Compile("(?i)^" + gitGlobToRegex(glob) + "$")
+		if err != nil {
+			return nil, WrapError(ErrInvalidPattern, "rule %q: %v", raw, err)
+		}
+		rule.kind = ruleGlob
+		rule.regex = regex
+	}
+
+	return rule, nil
+}
+
+// ParseRules parses the rules of an ignore file, skipping blank lines and
+// comments (#). Invalid lines are returned as errors, the others still apply.
+func ParseRules(text string) ([]*Rule, []error) {
+	var rules []*Rule
+	var errs []error
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", n, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, errs
+}
+
+// LoadIgnoreFile reads the .anemoneignore file at the root of a job folder.
+// A missing file has no rules.
+func LoadIgnoreFile(root string) ([]*Rule, []error, error) {
+	data, err := os.ReadFile(filepath.Join(root, IgnoreFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, WrapError(err, "read %s", IgnoreFileName)
+	}
+	rules, errs := ParseRules(strings.TrimPrefix(string(data), "\ufeff")) // UTF-8 BOM (Notepad)
+	return rules, errs, nil
+}
+
+// Match reports whether a path relative to the job root ("/"-separated)
+// matches the rule. size is the size of a file (-1 = unknown: size rules
+// don't match).
+func (r *Rule) Match(relPath string, isDir bool, size int64) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+
+	switch r.kind {
+	case ruleRegex:
+		return r.regex.MatchString(relPath)
+
+	case ruleExtension:
+		ext := strings.TrimPrefix(path.Ext(relPath), ".")
+		return !isDir && r.exts[strings.ToLower(ext)]
+
+	case ruleSize:
+		if isDir || size < 0 {
+			return false
+		}
+		switch r.sizeOp {
+		case ">":
+			return size > r.size
+		case ">=":
+			return size >= r.size
+		case "<":
+			return size < r.size
+		default:
+			return size <= r.size
+		}
+
+	default:
+		if r.anchored {
+			return r.regex.MatchString(relPath)
+		}
+		return r.regex.MatchString(path.Base(relPath))
+	}
+}
+
+// isSizeRule reports whether a rule compares sizes ("size>10MB").
+func isSizeRule(text string) bool {
+	rest, ok := strings.CutPrefix(text, sizeRulePrefix)
+	return ok && (strings.HasPrefix(rest, ">") || strings.HasPrefix(rest, "<"))
+}
+
+// sizeUnits are the units of size rules, longest first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// parseSizeRule parses "size>10MB" into its operator and size in bytes.
+func parseSizeRule(text string) (op string, size int64, err error) {
+	rest := strings.TrimPrefix(text, sizeRulePrefix)
+	for _, candidate := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(rest, candidate) {
+			op = candidate
+			rest = strings.TrimSpace(rest[len(candidate):])
+			break
+		}
+	}
+
+	size, err = ParseSize(rest)
+	if err != nil {
+		return "", 0, err
+	}
+	return op, size, nil
+}
+
+// ParseSize parses a size with an optional unit, as in size rules: "512",
+// "10MB", "1.5 GB". Units are binary (1 KB = 1024 bytes).
+func ParseSize(text string) (int64, error) {
+	rest := strings.TrimSpace(text)
+	unit := int64(1)
+	upper := strings.ToUpper(rest)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			unit = u.bytes
+			rest = strings.TrimSpace(rest[:len(rest)-len(u.suffix)])
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(rest, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", rest)
+	}
+	return int64(value * float64(unit)), nil
+}
+
+// FormatSize formats a size in the largest unit of size rules that keeps it
+// exact, so that ParseSize reads it back unchanged: "500 MB", "1536 KB".
+func FormatSize(size int64) string {
+	for _, u := range sizeUnits {
+		if u.bytes > 1 && size >= u.bytes && size%u.bytes == 0 {
+			return fmt.Sprintf("%d %s", size/u.bytes, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// gitGlobToRegex converts a gitignore-like glob to a regex (without anchors):
+// "*" and "?" stay within a folder, "[...]" is a character class and "**"
+// spans folders ("**/x" at any depth, "x/**" everything inside).
+func gitGlobToRegex(glob string) string {
+	var result strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			result.WriteString("(?:.*/)?") // Zero or more folders
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			result.WriteString(".*")
+			i++
+		case ch == '*':
+			result.WriteString("[^/]*")
+		case ch == '?':
+			result.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				result.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			result.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			result.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	return result.String()
+}
+
+// lastMatch returns the last rule matching a job-relative path (nil if none):
+// like .gitignore, later rules override earlier ones.
+func lastMatch(rules []*Rule, relPath string, isDir bool, size int64) *Rule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].Match(relPath, isDir, size) {
+			return rules[i]
+		}
+	}
+	return nil
+}
+
+// JobExclusions matches job-relative paths against the rules of a job,
+// including the rules of their parent folders, for the files the local walk
+// never sees: remote files and cached state. Not safe for concurrent use.
+type JobExclusions struct {
+	rules []*Rule
+	dirs  map[string]bool // Folder -> excluded
+	files map[string]bool // Files excluded by the local walk
+}
+
+// NewJobExclusions returns a matcher of rules (nil if there are none).
+func NewJobExclusions(rules []*Rule) *JobExclusions {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &JobExclusions{rules: rules, dirs: make(map[string]bool)}
+}
+
+// Excludes reports whether a file (job-relative path, "/"-separated) or one
+// of its folders is excluded. A nil JobExclusions excludes nothing.
+func (j *JobExclusions) Excludes(relPath string, size int64) bool {
+	if j == nil {
+		return false
+	}
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if j.files[relPath] {
+		return true
+	}
+	if dir := path.Dir(relPath); dir != "." && j.excludesDir(dir) {
+		return true
+	}
+	rule := lastMatch(j.rules, relPath, false, size)
+	return rule != nil && !rule.Negate
+}
+
+// addFiles marks files excluded by the local walk (job-relative paths).
+func (j *JobExclusions) addFiles(relPaths map[string]bool) {
+	if j == nil || len(relPaths) == 0 {
+		return
+	}
+	if j.files == nil {
+		j.files = make(map[string]bool, len(relPaths))
+	}
+	for relPath := range relPaths {
+		j.files[relPath] = true
+	}
+}
+
+// excludesDir reports whether a folder or one of its parents is excluded.
+func (j *JobExclusions) excludesDir(dir string) bool {
+	if excluded, ok := j.dirs[dir]; ok {
+		return excluded
+	}
+	excluded := false
+	if parent := path.Dir(dir); parent != "." && j.excludesDir(parent) {
+		excluded = true
+	} else if rule := lastMatch(j.rules, dir, true, -1); rule != nil && !rule.Negate {
+		excluded = true
+	}
+	j.dirs[dir] = excluded
+	return excluded
+}
@@ -0,0 +1,158 @@
+package scanner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		relPath string
+		isDir   bool
+		size    int64
+		match   bool
+	}{
+		// Globs without "/" match the name at any depth
+		{"*.log", "app.log", false, -1, true},
+		{"*.log", "logs/2024/APP.LOG", false, -1, true},
+		{"*.log", "app.log.txt", false, -1, false},
+		{"temp?", "a/temp1", false, -1, true},
+		{"[ab]*.txt", "dir/b1.txt", false, -1, true},
+		{"[!ab]*.txt", "dir/b1.txt", false, -1, false},
+
+		// Trailing "/": folders only
+		{"cache/", "src/cache", true, -1, true},
+		{"cache/", "src/cache", false, -1, false},
+
+		// Leading or middle "/": anchored to the job root
+		{"/build", "build", true, -1, true},
+		{"/build", "src/build", true, -1, false},
+		{"docs/*.pdf", "docs/a.pdf", false, -1, true},
+		{"docs/*.pdf", "docs/sub/a.pdf", false, -1, false},
+		{"docs/*.pdf", "other/docs/a.pdf", false, -1, false},
+		{`docs\*.pdf`, "docs/a.pdf", false, -1, true},
+
+		// "**" spans folders
+		{"**/bin", "bin", true, -1, true},
+		{"**/bin", "a/b/bin", true, -1, true},
+		{"logs/**", "logs/a/b.txt", false, -1, true},
+		{"a/**/z.txt", "a/z.txt", false, -1, true},
+		{"a/**/z.txt", "a/b/c/z.txt", false, -1, true},
+
+		// Regex on the job-relative path
+		{`re:^archive/\d{4}/`, "Archive/2023/x.doc", false, -1, true},
+		{`re:^archive/\d{4}/`, "Archive/old/x.doc", false, -1, false},
+
+		// Size rules only match files of known size
+		{"size>100MB", "video.mkv", false, 200 * 1024 * 1024, true},
+		{"size>100MB", "video.mkv", false, 100 * 1024 * 1024, false},
+		{"size>=100MB", "video.mkv", false, 100 * 1024 * 1024, true},
+		{"size<1kb", "empty.txt", false, 0, true},
+		{"size<=512", "small.txt", false, 513, false},
+		{"size>1.5GB", "disk.img", false, 2 * 1024 * 1024 * 1024, true},
+		{"size>0", "folder", true, 10, false},
+		{"size>0", "unknown.txt", false, -1, false},
+
+		// Extension rules
+		{"ext:iso, .VHDX", "vm/disk.vhdx", false, -1, true},
+		{"ext:iso,vhdx", "image.ISO", false, -1, true},
+		{"ext:iso,vhdx", "image.iso.txt", false, -1, false},
+		{"ext:iso", "folder.iso", true, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule+"/"+tt.relPath, func(t *testing.T) {
+			rule, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.rule, err)
+			}
+			if got := rule.Match(tt.relPath, tt.isDir, tt.size); got != tt.match {
+				t.Errorf("Match(%q, dir=%v, size=%d) = %v, want %v", tt.relPath, tt.isDir, tt.size, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestParseRule_Invalid(t *testing.T) {
+	for _, line := range []string{"", "!", "/", "re:(", "ext:", "ext: , ", "size>", "size>abc", "size<-1KB"} {
+		if _, err := ParseRule(line); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("ParseRule(%q) = %v, want ErrInvalidPattern", line, err)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"10MB":   10 << 20,
+		"1.5 gb": 3 << 29,
+		" 2 KB ": 2048,
+	}
+	for text, want := range tests {
+		if got, err := ParseSize(text); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"", "MB", "-1KB", "ten"} {
+		if _, err := ParseSize(text); err == nil {
+			t.Errorf("ParseSize(%q) should fail", text)
+		}
+	}
+
+	// FormatSize is read back unchanged
+	for _, size := range []int64{500, 1536, 500 << 20, 5 << 30} {
+		if got, err := ParseSize(FormatSize(size)); err != nil || got != size {
+			t.Errorf("ParseSize(FormatSize(%d)) = %d, %v", size, got, err)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, errs := ParseRules("# comment\n\n*.tmp\nre:[\n  !keep.tmp  \n")
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if !rules[1].Negate || rules[1].Raw != "!keep.tmp" {
+		t.Errorf("unexpected second rule %+v", rules[1])
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+}
+
+func TestJobExclusions(t *testing.T) {
+	rules, errs := ParseRules("node_modules/\n*.log\n!important.log\nsize>1MB\n")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	exclusions := NewJobExclusions(rules)
+
+	tests := []struct {
+		relPath  string
+		size     int64
+		excluded bool
+	}{
+		{"readme.md", 10, false},
+		{"app.log", 10, true},
+		{"important.log", 10, false},
+		{"web/node_modules/react/index.js", 10, true}, // Parent folder excluded
+		{"big.zip", 2 * 1024 * 1024, true},
+		{"web/src/index.js", 10, false},
+	}
+	for _, tt := range tests {
+		if got := exclusions.Excludes(tt.relPath, tt.size); got != tt.excluded {
+			t.Errorf("Excludes(%q, %d) = %v, want %v", tt.relPath, tt.size, got, tt.excluded)
+		}
+	}
+
+	exclusions.addFiles(map[string]bool{"web/src/index.js": true})
+	if !exclusions.Excludes("web/src/index.js", 10) {
+		t.Error("files excluded by the local walk should be excluded")
+	}
+
+	var none *JobExclusions
+	if NewJobExclusions(nil) != nil || none.Excludes("app.log", 10) {
+		t.Error("no rules should exclude nothing")
+	}
+}
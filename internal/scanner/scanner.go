@@ -2,8 +2,11 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,6 +15,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// placeholderHash stands for the hash of a Cloud Files placeholder, never read.
+const placeholderHash = "placeholder"
+
 // Scanner orchestrates file scanning, hashing, and change detection
 type Scanner struct {
 	db       *database.DB
@@ -20,6 +26,7 @@ type Scanner struct {
 	excluder *Excluder
 	hasher   *Hasher
 	walker   *Walker
+	journal  changeJournal // Changes of the job folders since a scan (nil = always walk)
 
 	mu           sync.Mutex
 	scanningJobs map[int64]bool // Track which jobs are currently scanning
@@ -32,6 +39,18 @@ type ScanRequest struct {
 	JobID      int64  // Job ID from sync_jobs table
 	BasePath   string // Local base path to scan
 	RemoteBase string // Remote base path for mapping
+
+	// ExclusionGroups overrides the default state of curated exclusion groups
+	// for this job (group name -> enabled). Groups not listed use their default.
+	ExclusionGroups map[string]bool
+
+	// Subtree restricts the scan to a folder relative to BasePath (forward slashes,
+	// "" = whole tree). Paths in the result stay relative to BasePath.
+	Subtree string
+
+	// Selection is the selective sync of the job: folders outside it are not
+	// walked and their files are neither found nor reported deleted.
+	Selection Selection
 }
 
 // ScanResult contains the result of a scan operation
@@ -48,6 +67,11 @@ type ScanResult struct {
 	Errors         []*ScanError
 	Duration       time.Duration
 	WalkStats      *WalkStatistics
+	JournalChanges int // Paths the change journal reported changed (0 = the folder was walked)
+
+	// Exclusions matches the job rules (exclusions table and .anemoneignore),
+	// so remote and cached files they exclude are left alone too (nil = none).
+	Exclusions *JobExclusions
 }
 
 // FileInfo contains information about a scanned file
@@ -58,6 +82,7 @@ type FileInfo struct {
 	MTime      time.Time
 	Hash       string
 	Status     FileStatus
+	Attributes uint32 // Raw Windows attributes (FILE_ATTRIBUTE_*)
 }
 
 // FileStatus indicates the change detection result
@@ -148,6 +173,7 @@ func NewScanner(cfg *config.Config, db *database.DB, logger *zap.Logger) (*Scann
 		excluder:     excluder,
 		hasher:       hasher,
 		walker:       walker,
+		journal:      systemJournal(),
 		scanningJobs: make(map[int64]bool),
 		batchSize:    100,             // Batch 100 files for DB updates
 		batchDelay:   5 * time.Second, // Or 5 seconds, whichever comes first
@@ -176,23 +202,22 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 	s.logger.Info("starting file scan",
 		zap.Int64("job_id", req.JobID),
 		zap.String("base_path", req.BasePath),
-		zap.String("remote_base", req.RemoteBase))
+		zap.String("remote_base", req.RemoteBase),
+		zap.String("subtree", req.Subtree))
 
-	result := &ScanResult{
-		JobID:          req.JobID,
-		NewFiles:       make([]*FileInfo, 0),
-		ModifiedFiles:  make([]*FileInfo, 0),
-		UnchangedFiles: make([]*FileInfo, 0),
-		DeletedFiles:   make([]*FileInfo, 0),
-		Errors:         make([]*ScanError, 0),
-	}
+	result := newScanResult(req.JobID)
 
 	// Load exclusions from database for this job
-	if err := s.loadJobExclusions(req.JobID); err != nil {
+	if err := s.loadJobExclusions(req.JobID, req.BasePath); err != nil {
 		s.logger.Warn("failed to load job exclusions",
 			zap.Int64("job_id", req.JobID),
 			zap.Error(err))
 	}
+	if err := s.loadExclusionGroups(req.JobID, req.ExclusionGroups); err != nil {
+		s.logger.Warn("failed to load exclusion groups",
+			zap.Int64("job_id", req.JobID),
+			zap.Error(err))
+	}
 
 	// Track files found during scan
 	foundFiles := make(map[string]bool)
@@ -204,8 +229,36 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 	// - local = current local state (from scanner)
 	// - remote = current remote state (from manifest/SMB)
 
-	// Walk the directory tree
-	err := s.walker.Walk(req.JobID, req.BasePath, func(path string, metadata *FileMetadata) error {
+	// Walk the directory tree (or only the requested subtree)
+	walkRoot := req.BasePath
+	if req.Subtree != "" {
+		walkRoot = filepath.Join(req.BasePath, filepath.FromSlash(req.Subtree))
+	}
+
+	var skipDir func(path string) bool
+	if !req.Selection.IsEmpty() {
+		skipDir = func(path string) bool {
+			return req.Selection.SkipDir(relativeTo(req.BasePath, path))
+		}
+	}
+
+	// Files excluded by job rules, so the other sides of the sync leave them
+	// alone even when a size rule only matches the local copy
+	excludedFiles := make(map[string]bool)
+	s.walker.SetExcludedFunc(func(path string, isDir bool, excl *ExclusionResult) {
+		if !isDir && excl.Level == LevelJob {
+			excludedFiles[relativeTo(req.BasePath, path)] = true
+		}
+	})
+	defer s.walker.SetExcludedFunc(nil)
+
+	// Scans of the whole folder keep what they found as the local snapshot,
+	// so the next one only visits the paths the change journal reports
+	// changed since (nil = no snapshot kept)
+	checkpoint := s.journalCheckpoint(req)
+	var listed []*database.LocalSnapshotEntry
+
+	visit := func(path string, metadata *FileMetadata) error {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
@@ -213,18 +266,22 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 		default:
 		}
 
-		result.TotalFiles++
-
 		// Calculate relative path for foundFiles tracking (must match DB storage format)
 		relPath, relErr := filepath.Rel(req.BasePath, path)
 		if relErr == nil {
 			relPath = filepath.ToSlash(relPath) // Normalize to forward slashes
+			// Files of listed folders may still be outside the selection
+			if !req.Selection.Contains(relPath) {
+				return nil
+			}
 			foundFiles[relPath] = true
 		} else {
 			// Fallback to absolute path if relative fails (should not happen)
 			foundFiles[path] = true
 		}
 
+		result.TotalFiles++
+
 		// Process file with 3-step algorithm
 		fileInfo, err := s.processFile(ctx, req, path, metadata)
 		if err != nil {
@@ -246,6 +303,9 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 		case StatusError:
 			result.ErrorFiles++
 		}
+		if checkpoint != nil {
+			listed = append(listed, snapshotEntry(fileInfo))
+		}
 
 		result.ProcessedFiles++
 
@@ -260,7 +320,23 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 		}
 
 		return nil
-	})
+	}
+
+	var replay *journalReplay
+	var err error
+	if checkpoint != nil {
+		replay, err = s.replayJournal(ctx, req, checkpoint, result, foundFiles, excludedFiles, visit)
+		if errors.Is(err, ErrJournalUnavailable) {
+			s.logger.Info("walking the job folder", zap.Int64("job_id", req.JobID), zap.Error(err))
+			replay, listed, err = nil, nil, nil
+			result = newScanResult(req.JobID)
+			clear(foundFiles)
+			clear(excludedFiles)
+		}
+	}
+	if replay == nil && err == nil {
+		err = s.walkTree(req.JobID, walkRoot, req.Subtree != "", skipDir, visit)
+	}
 
 	if err != nil {
 		s.logger.Error("walk failed", zap.Error(err))
@@ -268,7 +344,9 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 	}
 
 	// Detect deleted files (in DB but not found during walk)
-	deletedFiles, err := s.detectDeletedFiles(req.JobID, foundFiles)
+	result.Exclusions = s.excluder.JobExclusions(req.JobID)
+	result.Exclusions.addFiles(excludedFiles)
+	deletedFiles, err := s.detectDeletedFiles(req.JobID, foundFiles, req.Subtree, req.Selection, result.Exclusions)
 	if err != nil {
 		s.logger.Warn("failed to detect deleted files", zap.Error(err))
 	} else {
@@ -278,6 +356,14 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 	// Get walk statistics
 	result.WalkStats = s.walker.GetStatistics()
 	result.Duration = time.Since(start)
+	if replay != nil {
+		result.JournalChanges = replay.changed
+	}
+
+	// A file that couldn't be read would look deleted to the next replay
+	if checkpoint != nil && result.ErrorFiles == 0 {
+		s.saveSnapshot(checkpoint, replay, listed, excludedFiles)
+	}
 
 	s.logger.Info("scan completed",
 		zap.Int64("job_id", req.JobID),
@@ -287,11 +373,46 @@ func (s *Scanner) Scan(ctx context.Context, req ScanRequest) (*ScanResult, error
 		zap.Int("unchanged_files", len(result.UnchangedFiles)),
 		zap.Int("deleted_files", len(result.DeletedFiles)),
 		zap.Int("errors", len(result.Errors)),
+		zap.Bool("change_journal", replay != nil),
 		zap.Duration("duration", result.Duration))
 
 	return result, nil
 }
 
+// saveSnapshot saves what a scan of the whole job folder found as its local
+// snapshot: replaced after a walk, updated with the paths visited again after
+// a change journal replay.
+func (s *Scanner) saveSnapshot(checkpoint *database.ScanCheckpoint, replay *journalReplay,
+	listed []*database.LocalSnapshotEntry, excludedFiles map[string]bool) {
+	for rel := range excludedFiles {
+		listed = append(listed, &database.LocalSnapshotEntry{Path: rel, Excluded: true})
+	}
+
+	var err error
+	if replay != nil {
+		err = s.db.UpdateLocalSnapshot(checkpoint, listed, replay.removed)
+	} else {
+		err = s.db.ReplaceLocalSnapshot(checkpoint, listed)
+	}
+	if err != nil {
+		s.logger.Warn("failed to save local snapshot",
+			zap.Int64("job_id", checkpoint.JobID),
+			zap.Error(err))
+	}
+}
+
+// newScanResult returns an empty scan result.
+func newScanResult(jobID int64) *ScanResult {
+	return &ScanResult{
+		JobID:          jobID,
+		NewFiles:       make([]*FileInfo, 0),
+		ModifiedFiles:  make([]*FileInfo, 0),
+		UnchangedFiles: make([]*FileInfo, 0),
+		DeletedFiles:   make([]*FileInfo, 0),
+		Errors:         make([]*ScanError, 0),
+	}
+}
+
 // processFile implements the 3-step change detection algorithm
 func (s *Scanner) processFile(ctx context.Context, req ScanRequest, path string, metadata *FileMetadata) (*FileInfo, error) {
 	// Calculate relative path for storage (not absolute path)
@@ -308,6 +429,7 @@ func (s *Scanner) processFile(ctx context.Context, req ScanRequest, path string,
 		RemotePath: remotePath,
 		Size:       metadata.Size,
 		MTime:      metadata.MTime,
+		Attributes: metadata.Attributes,
 	}
 
 	// Cloud Files placeholder: skip hashing entirely.
@@ -315,7 +437,7 @@ func (s *Scanner) processFile(ctx context.Context, req ScanRequest, path string,
 	// which defeats the purpose of Files On Demand.
 	if metadata.IsPlaceholder {
 		fileInfo.Status = StatusUnchanged
-		fileInfo.Hash = "placeholder"
+		fileInfo.Hash = placeholderHash
 		return fileInfo, nil
 	}
 
@@ -382,25 +504,47 @@ func (s *Scanner) mapToRemotePath(localBase, remoteBase, localPath string) strin
 	return filepath.Join(remoteBase, relPath)
 }
 
-// loadJobExclusions loads job-specific and individual exclusions from database
-func (s *Scanner) loadJobExclusions(jobID int64) error {
+// loadJobExclusions loads job-specific and individual exclusions from database,
+// then the .anemoneignore file of the job folder (its rules come last, so they
+// override the database ones)
+func (s *Scanner) loadJobExclusions(jobID int64, basePath string) error {
 	// Load job-specific and global exclusions
 	exclusions, err := s.db.GetExclusions(jobID)
 	if err != nil {
 		return WrapError(err, "get exclusions for job %d", jobID)
 	}
 
+	rules := make([]*Rule, 0, len(exclusions))
 	for _, excl := range exclusions {
 		if excl.Type == "job" {
-			if err := s.excluder.AddJobPattern(jobID, excl.PatternOrPath); err != nil {
+			rule, err := ParseRule(excl.PatternOrPath)
+			if err != nil {
 				s.logger.Warn("failed to add job pattern",
 					zap.String("pattern", excl.PatternOrPath),
 					zap.Error(err))
+				continue
 			}
+			rules = append(rules, rule)
 		}
 		// Global patterns are already loaded from default_exclusions.json
 	}
 
+	ignoreRules, ruleErrs, err := LoadIgnoreFile(basePath)
+	if err != nil {
+		s.logger.Warn("failed to read ignore file",
+			zap.String("path", filepath.Join(basePath, IgnoreFileName)),
+			zap.Error(err))
+	}
+	for _, ruleErr := range ruleErrs {
+		s.logger.Warn("invalid rule in ignore file",
+			zap.String("path", filepath.Join(basePath, IgnoreFileName)),
+			zap.Error(ruleErr))
+	}
+	rules = append(rules, ignoreRules...)
+
+	// Replaced on every scan, so edits to the rules apply to the next sync
+	s.excluder.SetJobRules(jobID, basePath, rules)
+
 	// Load individual path exclusions
 	individualPaths, err := s.db.GetIndividualExclusions(jobID)
 	if err != nil {
@@ -414,6 +558,7 @@ func (s *Scanner) loadJobExclusions(jobID int64) error {
 	s.logger.Info("loaded job exclusions",
 		zap.Int64("job_id", jobID),
 		zap.Int("pattern_count", len(exclusions)),
+		zap.Int("ignore_file_rules", len(ignoreRules)),
 		zap.Int("individual_count", len(individualPaths)))
 
 	return nil
@@ -430,7 +575,8 @@ func (s *Scanner) getFileState(jobID int64, localPath string) (*database.FileSta
 }
 
 // detectDeletedFiles detects files that are in DB but were not found during scan
-func (s *Scanner) detectDeletedFiles(jobID int64, foundFiles map[string]bool) ([]*FileInfo, error) {
+// Only files inside subtree are considered ("" = whole job).
+func (s *Scanner) detectDeletedFiles(jobID int64, foundFiles map[string]bool, subtree string, selection Selection, exclusions *JobExclusions) ([]*FileInfo, error) {
 	// Get all files from database for this job
 	dbStates, err := s.db.GetAllFileStates(jobID)
 	if err != nil {
@@ -440,6 +586,13 @@ func (s *Scanner) detectDeletedFiles(jobID int64, foundFiles map[string]bool) ([
 	deletedFiles := make([]*FileInfo, 0)
 
 	for _, state := range dbStates {
+		if !InSubtree(state.LocalPath, subtree) || !selection.Contains(state.LocalPath) {
+			continue
+		}
+		// Excluded since the last sync: not walked, but not deleted either
+		if exclusions.Excludes(state.LocalPath, state.Size) {
+			continue
+		}
 		if !foundFiles[state.LocalPath] {
 			// File is in DB but not found on disk = deleted
 			deletedFiles = append(deletedFiles, &FileInfo{
@@ -467,6 +620,37 @@ func (s *Scanner) detectDeletedFiles(jobID int64, foundFiles map[string]bool) ([
 	return deletedFiles, nil
 }
 
+// walkTree walks root, treating a missing root as empty when allowMissing is set
+// (a scoped scan of a folder deleted since the last sync)
+func (s *Scanner) walkTree(jobID int64, root string, allowMissing bool, skipDir func(path string) bool, walkFn WalkFunc) error {
+	if allowMissing {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			s.logger.Info("scan subtree does not exist, treating as empty",
+				zap.String("path", root))
+			return nil
+		}
+	}
+	return s.walker.WalkSelected(jobID, root, skipDir, walkFn)
+}
+
+// relativeTo returns path relative to base with forward slashes ("" for base itself).
+func relativeTo(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// InSubtree reports whether a job-relative path (forward slashes) is inside
+// subtree. An empty subtree contains every path.
+func InSubtree(relPath, subtree string) bool {
+	if subtree == "" {
+		return true
+	}
+	return relPath == subtree || strings.HasPrefix(relPath, subtree+"/")
+}
+
 // Close closes the scanner and releases resources
 func (s *Scanner) Close() error {
 	s.logger.Info("closing scanner")
@@ -486,3 +670,29 @@ func (s *Scanner) IsScanningJob(jobID int64) bool {
 	defer s.mu.Unlock()
 	return s.scanningJobs[jobID]
 }
+
+// loadExclusionGroups resolves which curated exclusion groups apply to the job
+func (s *Scanner) loadExclusionGroups(jobID int64, overrides map[string]bool) error {
+	groups, err := s.db.GetExclusionGroups()
+	if err != nil {
+		return WrapError(err, "get exclusion groups")
+	}
+
+	var enabled, disabled []string
+	var enabledGroups []string
+	for _, group := range groups {
+		if group.IsEnabled(overrides) {
+			enabled = append(enabled, group.Patterns...)
+			enabledGroups = append(enabledGroups, group.Name)
+		} else {
+			disabled = append(disabled, group.Patterns...)
+		}
+	}
+	s.excluder.SetJobGroups(jobID, enabled, disabled)
+
+	s.logger.Debug("loaded exclusion groups",
+		zap.Int64("job_id", jobID),
+		zap.Strings("enabled_groups", enabledGroups))
+
+	return nil
+}
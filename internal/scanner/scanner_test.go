@@ -222,6 +222,195 @@ func TestScanner_DeletedFiles(t *testing.T) {
 	h.AssertEqual(8, result.TotalFiles, "remaining files")
 }
 
+func TestScanner_Subtree(t *testing.T) {
+	h := NewTestHelpers(t)
+	tempDir := h.CreateTempDir()
+	db := h.SetupTestDB()
+
+	rootFiles := h.CreateTestFiles(tempDir, 3, 1024)
+	projectFiles := h.CreateTestFiles(filepath.Join(tempDir, "ProjectA"), 4, 1024)
+	h.CreateTestFiles(filepath.Join(tempDir, "ProjectB"), 2, 1024)
+
+	jobID := h.CreateTestJob(db, tempDir, "\\\\server\\share")
+
+	cfg := &config.Config{
+		Paths: config.PathsConfig{ConfigDir: tempDir},
+		Sync: config.SyncConfig{
+			Performance: config.PerformanceConfig{
+				HashAlgorithm: "sha256",
+				BufferSizeMB:  4,
+			},
+		},
+	}
+
+	scanner, err := NewScanner(cfg, db, h.GetTestLogger(false))
+	h.AssertNoError(err, "create scanner")
+	defer scanner.Close()
+
+	firstResult, err := scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+	})
+	h.AssertNoError(err, "first scan")
+	h.SimulateSyncComplete(db, jobID, firstResult.NewFiles)
+
+	// Deletions outside the subtree must not be reported by a scoped scan
+	os.Remove(rootFiles[0])
+	os.Remove(projectFiles[0])
+
+	result, err := scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+		Subtree:    "ProjectA",
+	})
+	h.AssertNoError(err, "subtree scan")
+
+	h.AssertEqual(3, result.TotalFiles, "files in subtree")
+	h.AssertEqual(1, len(result.DeletedFiles), "deleted files in subtree")
+	h.AssertEqual("ProjectA/file_0000.txt", result.DeletedFiles[0].LocalPath, "deleted file path")
+
+	// A subtree deleted since the last sync scans as empty
+	os.RemoveAll(filepath.Join(tempDir, "ProjectB"))
+	result, err = scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+		Subtree:    "ProjectB",
+	})
+	h.AssertNoError(err, "missing subtree scan")
+	h.AssertEqual(0, result.TotalFiles, "files in missing subtree")
+	h.AssertEqual(2, len(result.DeletedFiles), "deleted files in missing subtree")
+}
+
+func TestScanner_Selection(t *testing.T) {
+	h := NewTestHelpers(t)
+	tempDir := h.CreateTempDir()
+	db := h.SetupTestDB()
+
+	h.CreateTestFiles(tempDir, 2, 1024)
+	photoFiles := h.CreateTestFiles(filepath.Join(tempDir, "Photos"), 3, 1024)
+	rawFiles := h.CreateTestFiles(filepath.Join(tempDir, "Photos", "Raw"), 4, 1024)
+	h.CreateTestFiles(filepath.Join(tempDir, "Photos", "Raw", "Best"), 1, 1024)
+	h.CreateTestFiles(filepath.Join(tempDir, "Videos"), 5, 1024)
+
+	jobID := h.CreateTestJob(db, tempDir, "\\\\server\\share")
+
+	cfg := &config.Config{
+		Paths: config.PathsConfig{ConfigDir: tempDir},
+		Sync: config.SyncConfig{
+			Performance: config.PerformanceConfig{
+				HashAlgorithm: "sha256",
+				BufferSizeMB:  4,
+			},
+		},
+	}
+
+	scanner, err := NewScanner(cfg, db, h.GetTestLogger(false))
+	h.AssertNoError(err, "create scanner")
+	defer scanner.Close()
+
+	firstResult, err := scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+	})
+	h.AssertNoError(err, "first scan")
+	h.SimulateSyncComplete(db, jobID, firstResult.NewFiles)
+
+	// Deletions outside the selection must not be reported
+	os.Remove(photoFiles[0])
+	os.Remove(rawFiles[0])
+
+	result, err := scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+		Selection: Selection{
+			Include: []string{"Photos", "Photos/Raw/Best"},
+			Exclude: []string{"Photos/Raw"},
+		},
+	})
+	h.AssertNoError(err, "selective scan")
+
+	h.AssertEqual(3, result.TotalFiles, "files in selection")
+	h.AssertEqual(1, len(result.DeletedFiles), "deleted files in selection")
+	h.AssertEqual("Photos/file_0000.txt", result.DeletedFiles[0].LocalPath, "deleted file path")
+	h.AssertEqual(1, result.WalkStats.ExcludedDirs, "unselected folders skipped") // Videos; Photos/Raw is listed to reach Best
+}
+
+func TestSelection(t *testing.T) {
+	sel := Selection{
+		Include: []string{"Photos", "Photos/Raw/Best"},
+		Exclude: []string{"Photos/Raw"},
+	}
+
+	contains := []struct {
+		path string
+		want bool
+	}{
+		{"readme.txt", false},
+		{"Photos/a.jpg", true},
+		{"photos/a.jpg", true},
+		{"Photos/Raw/a.cr2", false},
+		{"Photos/Raw/Best/a.cr2", true},
+		{"Photos/RawEdits/a.jpg", true},
+		{"Videos/a.mp4", false},
+	}
+	for _, tt := range contains {
+		if got := sel.Contains(tt.path); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	skip := []struct {
+		dir  string
+		want bool
+	}{
+		{"", false},
+		{"Photos", false},
+		{"Photos/Raw", false}, // Best is included below
+		{"Photos/Raw/Other", true},
+		{"Videos", true},
+	}
+	for _, tt := range skip {
+		if got := sel.SkipDir(tt.dir); got != tt.want {
+			t.Errorf("SkipDir(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+
+	// Exclusions only: everything else is synced
+	sel = Selection{Exclude: []string{"Cache"}}
+	if !sel.Contains("Docs/a.txt") || sel.Contains("Cache/a.tmp") || !sel.SkipDir("Cache") {
+		t.Error("unexpected result for an exclusion-only selection")
+	}
+	if !(Selection{}).Contains("any/path") {
+		t.Error("an empty selection should contain everything")
+	}
+}
+
+func TestInSubtree(t *testing.T) {
+	tests := []struct {
+		path    string
+		subtree string
+		want    bool
+	}{
+		{"a/b.txt", "", true},
+		{"ProjectA/b.txt", "ProjectA", true},
+		{"ProjectA/sub/b.txt", "ProjectA", true},
+		{"ProjectA", "ProjectA", true},
+		{"ProjectAB/b.txt", "ProjectA", false},
+		{"b.txt", "ProjectA", false},
+	}
+
+	for _, tt := range tests {
+		if got := InSubtree(tt.path, tt.subtree); got != tt.want {
+			t.Errorf("InSubtree(%q, %q) = %v, want %v", tt.path, tt.subtree, got, tt.want)
+		}
+	}
+}
+
 func TestScanner_WithExclusions(t *testing.T) {
 	h := NewTestHelpers(t)
 	tempDir := h.CreateTempDir()
@@ -272,6 +461,79 @@ func TestScanner_WithExclusions(t *testing.T) {
 	}
 }
 
+func TestScanner_IgnoreFile(t *testing.T) {
+	h := NewTestHelpers(t)
+	tempDir := h.CreateTempDir()
+	db := h.SetupTestDB()
+
+	h.CreateTestFile(filepath.Join(tempDir, IgnoreFileName), []byte("# build output\n/build/\nsize>1KB\n*.log\n!keep.log\n[invalid\nre:(\n"))
+	h.CreateTestFile(filepath.Join(tempDir, "notes.txt"), []byte("notes"))
+	h.CreateTestFile(filepath.Join(tempDir, "big.bin"), make([]byte, 4096))
+	h.CreateTestFile(filepath.Join(tempDir, "app.log"), []byte("log"))
+	h.CreateTestFile(filepath.Join(tempDir, "keep.log"), []byte("log"))
+	h.CreateTestFile(filepath.Join(tempDir, "build", "out.o"), []byte("obj"))
+	h.CreateTestFile(filepath.Join(tempDir, "src", "build", "gen.go"), []byte("package gen"))
+
+	jobID := h.CreateTestJob(db, tempDir, "\\\\server\\share")
+
+	cfg := &config.Config{
+		Paths: config.PathsConfig{ConfigDir: tempDir},
+		Sync: config.SyncConfig{
+			Performance: config.PerformanceConfig{
+				HashAlgorithm: "sha256",
+				BufferSizeMB:  4,
+			},
+		},
+	}
+
+	scanner, err := NewScanner(cfg, db, h.GetTestLogger(false))
+	h.AssertNoError(err, "create scanner")
+	defer scanner.Close()
+
+	result, err := scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+	})
+	h.AssertNoError(err, "scan with ignore file")
+
+	// .anemoneignore, notes.txt, keep.log and src/build/gen.go ("/build/" is anchored)
+	found := make(map[string]bool)
+	for _, f := range result.NewFiles {
+		found[filepath.ToSlash(f.LocalPath)] = true
+	}
+	h.AssertEqual(4, result.TotalFiles, "total files")
+	for _, relPath := range []string{IgnoreFileName, "notes.txt", "keep.log", "src/build/gen.go"} {
+		if !found[relPath] {
+			t.Errorf("%s should be scanned, found %v", relPath, found)
+		}
+	}
+
+	// Remote and cached files the rules exclude are left alone too
+	if result.Exclusions == nil {
+		t.Fatal("scan result should carry the job exclusions")
+	}
+	if !result.Exclusions.Excludes("big.bin", 10) {
+		t.Error("files excluded by the local walk should stay excluded whatever their remote size")
+	}
+	if !result.Exclusions.Excludes("build/remote-only.o", 1) {
+		t.Error("files under an excluded folder should be excluded")
+	}
+	if result.Exclusions.Excludes("keep.log", 3) {
+		t.Error("keep.log is re-included")
+	}
+
+	// Reloading the rules replaces them
+	h.CreateTestFile(filepath.Join(tempDir, IgnoreFileName), []byte("*.txt\n"))
+	result, err = scanner.Scan(context.Background(), ScanRequest{
+		JobID:      jobID,
+		BasePath:   tempDir,
+		RemoteBase: "\\\\server\\share",
+	})
+	h.AssertNoError(err, "rescan")
+	h.AssertEqual(6, result.TotalFiles, "total files after editing the ignore file")
+}
+
 func TestScanner_ContextCancellation(t *testing.T) {
 	h := NewTestHelpers(t)
 	tempDir := h.CreateTempDir()
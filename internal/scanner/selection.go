@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+)
+
+// Selection is the selective sync of a job: folders synced (Include) and
+// folders left out (Exclude), relative to the job with forward slashes.
+// The deepest rule containing a path decides, so a folder can be excluded
+// inside an included one and included back deeper. Paths under no rule are
+// synced unless the selection has Include rules. The zero Selection syncs
+// everything.
+type Selection struct {
+	Include []string
+	Exclude []string
+}
+
+// SelectionFromRules builds the selection of a job from its stored rules.
+func SelectionFromRules(rules []*database.SelectionRule) Selection {
+	var sel Selection
+	for _, rule := range rules {
+		switch rule.Mode {
+		case database.SelectionInclude:
+			sel.Include = append(sel.Include, rule.Path)
+		case database.SelectionExclude:
+			sel.Exclude = append(sel.Exclude, rule.Path)
+		}
+	}
+	return sel
+}
+
+// IsEmpty reports whether the selection syncs everything.
+func (s Selection) IsEmpty() bool {
+	return len(s.Include) == 0 && len(s.Exclude) == 0
+}
+
+// Contains reports whether a job-relative path is synced.
+func (s Selection) Contains(relPath string) bool {
+	selected := len(s.Include) == 0
+	deepest := -1
+	for _, dir := range s.Include {
+		if len(dir) > deepest && inFolder(relPath, dir) {
+			selected, deepest = true, len(dir)
+		}
+	}
+	for _, dir := range s.Exclude {
+		if len(dir) >= deepest && inFolder(relPath, dir) {
+			selected, deepest = false, len(dir)
+		}
+	}
+	return selected
+}
+
+// SkipDir reports whether nothing under a job-relative folder is synced, so
+// the folder need not be listed at all ("" = job root).
+func (s Selection) SkipDir(relDir string) bool {
+	if s.Contains(relDir) {
+		return false
+	}
+	// Listed anyway to reach the included folders below it
+	for _, dir := range s.Include {
+		if relDir == "" || inFolder(dir, relDir) {
+			return false
+		}
+	}
+	return true
+}
+
+// inFolder reports whether relPath is dir or inside it. Windows paths are
+// compared case-insensitively.
+func inFolder(relPath, dir string) bool {
+	if len(relPath) < len(dir) || !strings.EqualFold(relPath[:len(dir)], dir) {
+		return false
+	}
+	return len(relPath) == len(dir) || relPath[len(dir)] == '/'
+}
@@ -0,0 +1,25 @@
+package scanner
+
+import "strings"
+
+// Stream is an NTFS alternate data stream of a file.
+type Stream struct {
+	Name string // Without the leading ':' and the ":$DATA" type, e.g. "Zone.Identifier"
+	Size int64
+}
+
+// Path returns the path opening the stream of the file at path.
+func (s Stream) Path(path string) string {
+	return path + ":" + s.Name
+}
+
+// streamName returns the name of a data stream as reported by
+// FindFirstStreamW (":Zone.Identifier:$DATA"), or "" for the main stream
+// ("::$DATA") and streams which are not data streams.
+func streamName(raw string) string {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(raw, ":"), ":$DATA")
+	if !ok {
+		return ""
+	}
+	return name
+}
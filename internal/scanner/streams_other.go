@@ -0,0 +1,9 @@
+//go:build !windows
+
+package scanner
+
+// AlternateStreams always returns no stream on non-Windows platforms, whose
+// file systems have no alternate data streams.
+func AlternateStreams(path string) ([]Stream, error) {
+	return nil, nil
+}
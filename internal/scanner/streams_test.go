@@ -0,0 +1,28 @@
+package scanner
+
+import "testing"
+
+func TestStreamName(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"::$DATA", ""},
+		{":Zone.Identifier:$DATA", "Zone.Identifier"},
+		{":com.dropbox.attrs:$DATA", "com.dropbox.attrs"},
+		{":$I30:$INDEX_ALLOCATION", ""},
+	}
+
+	for _, tt := range tests {
+		if got := streamName(tt.raw); got != tt.want {
+			t.Errorf("streamName(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestStreamPath(t *testing.T) {
+	s := Stream{Name: "Zone.Identifier"}
+	if got := s.Path(`C:\Sync\setup.exe`); got != `C:\Sync\setup.exe:Zone.Identifier` {
+		t.Errorf("Path() = %q", got)
+	}
+}
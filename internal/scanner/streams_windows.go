@@ -0,0 +1,64 @@
+//go:build windows
+
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procFindFirstStreamW = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = kernel32.NewProc("FindNextStreamW")
+)
+
+// findStreamInfoStandard is FindStreamInfoStandard (STREAM_INFO_LEVELS).
+const findStreamInfoStandard = 0
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [windows.MAX_PATH + 36]uint16
+}
+
+// AlternateStreams returns the alternate data streams of a file (local or
+// UNC path), without its main stream.
+func AlternateStreams(path string) ([]Stream, error) {
+	p, err := windows.UTF16PtrFromString(longpath.Fix(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var data win32FindStreamData
+	h, _, err := procFindFirstStreamW.Call(
+		uintptr(unsafe.Pointer(p)),
+		findStreamInfoStandard,
+		uintptr(unsafe.Pointer(&data)),
+		0,
+	)
+	if windows.Handle(h) == windows.InvalidHandle {
+		// No stream at all (directories, some file systems)
+		if errors.Is(err, windows.ERROR_HANDLE_EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list streams of %s: %w", path, err)
+	}
+	defer windows.FindClose(windows.Handle(h))
+
+	var streams []Stream
+	for {
+		if name := streamName(windows.UTF16ToString(data.StreamName[:])); name != "" {
+			streams = append(streams, Stream{Name: name, Size: data.StreamSize})
+		}
+		if ok, _, err := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data))); ok == 0 {
+			if errors.Is(err, windows.ERROR_HANDLE_EOF) {
+				return streams, nil
+			}
+			return nil, fmt.Errorf("failed to list streams of %s: %w", path, err)
+		}
+	}
+}
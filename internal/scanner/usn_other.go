@@ -0,0 +1,8 @@
+//go:build !windows
+
+package scanner
+
+// systemJournal returns nil on non-Windows platforms: scans walk the folders.
+func systemJournal() changeJournal {
+	return nil
+}
@@ -0,0 +1,319 @@
+//go:build windows
+
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procOpenFileById = kernel32.NewProc("OpenFileById")
+)
+
+// Change journal control codes (winioctl.h)
+const (
+	fsctlQueryUSNJournal            = 0x000900F4
+	fsctlReadUSNJournal             = 0x000900BB
+	fsctlReadUnprivilegedUSNJournal = 0x000903AB // Windows 10 1709+, without administrator rights
+)
+
+// usnBufferSize is the size of the buffer journal records are read into.
+const usnBufferSize = 64 << 10
+
+// usnRecordV2Size is the size of a USN_RECORD_V2 before its file name.
+const usnRecordV2Size = 60
+
+// usnJournalData is USN_JOURNAL_DATA_V0.
+type usnJournalData struct {
+	UsnJournalID    uint64
+	FirstUsn        int64
+	NextUsn         int64
+	LowestValidUsn  int64
+	MaxUsn          int64
+	MaximumSize     uint64
+	AllocationDelta uint64
+}
+
+// readUSNJournalData is READ_USN_JOURNAL_DATA_V0.
+type readUSNJournalData struct {
+	StartUsn          int64
+	ReasonMask        uint32
+	ReturnOnlyOnClose uint32
+	Timeout           uint64
+	BytesToWaitFor    uint64
+	UsnJournalID      uint64
+}
+
+// fileIDDescriptor is FILE_ID_DESCRIPTOR with a 64-bit file ID (FileIdType).
+type fileIDDescriptor struct {
+	Size   uint32
+	Type   uint32
+	FileID uint64
+	_      [8]byte // Rest of the union (ObjectId, ExtendedFileId)
+}
+
+// usnJournal reads the NTFS USN journal.
+type usnJournal struct{}
+
+// systemJournal returns the NTFS USN journal reader.
+func systemJournal() changeJournal {
+	return usnJournal{}
+}
+
+// Position returns the current position of the USN journal of the volume of root.
+func (usnJournal) Position(root string) (JournalPosition, error) {
+	volume, err := journalVolume(root)
+	if err != nil {
+		return JournalPosition{}, err
+	}
+	vol, err := openVolume(volume)
+	if err != nil {
+		return JournalPosition{}, err
+	}
+	defer windows.CloseHandle(vol)
+
+	data, err := queryJournal(vol)
+	if err != nil {
+		return JournalPosition{}, err
+	}
+	return JournalPosition{Volume: volume, JournalID: int64(data.UsnJournalID), USN: data.NextUsn}, nil
+}
+
+// Changes returns the paths under root changed from since up to until. Each
+// record names a file in its parent folder, looked up by file ID: a folder
+// deleted or moved since is not found, but its own record covers its old path.
+func (usnJournal) Changes(root string, since, until JournalPosition) ([]string, error) {
+	if since.Volume != until.Volume || since.JournalID != until.JournalID {
+		return nil, ErrJournalExpired
+	}
+	vol, err := openVolume(until.Volume)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(vol)
+
+	data, err := queryJournal(vol)
+	if err != nil {
+		return nil, err
+	}
+	if int64(data.UsnJournalID) != since.JournalID || since.USN < data.FirstUsn {
+		return nil, ErrJournalExpired
+	}
+
+	// The folder handle is also the volume hint of OpenFileById
+	dir, err := openFolder(root)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(dir)
+	rootPath, err := finalPath(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJournalUnavailable, err)
+	}
+
+	folders := make(map[uint64]string) // Parent file ID -> path ("" = not found)
+	seen := make(map[string]bool)
+	var changed []string
+	err = readJournal(vol, since, until, func(parentID uint64, name string) bool {
+		parent, ok := folders[parentID]
+		if !ok {
+			parent = folderByID(dir, parentID)
+			folders[parentID] = parent
+		}
+		if parent == "" {
+			return true
+		}
+		rel, ok := journalRelPath(rootPath, parent+`\`+name)
+		if !ok || seen[rel] {
+			return true
+		}
+		seen[rel] = true
+		changed = append(changed, rel)
+		return len(changed) <= journalMaxChanges
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// journalVolume returns the drive of root, e.g. "C:".
+func journalVolume(root string) (string, error) {
+	volume := filepath.VolumeName(filepath.Clean(root))
+	if len(volume) != 2 || volume[1] != ':' {
+		return "", fmt.Errorf("%w: %s is not on a local drive", ErrJournalUnavailable, root)
+	}
+	return strings.ToUpper(volume), nil
+}
+
+// openVolume opens a drive for journal queries, without administrator rights
+// if need be.
+func openVolume(volume string) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	share := uint32(windows.FILE_SHARE_READ | windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE)
+	h, err := windows.CreateFile(name, windows.GENERIC_READ, share, nil, windows.OPEN_EXISTING, 0, 0)
+	if err == windows.ERROR_ACCESS_DENIED {
+		h, err = windows.CreateFile(name, 0, share, nil, windows.OPEN_EXISTING, 0, 0)
+	}
+	if err != nil {
+		return windows.InvalidHandle, fmt.Errorf("%w: open %s: %v", ErrJournalUnavailable, volume, err)
+	}
+	return h, nil
+}
+
+// openFolder opens a folder to read its path, without access to its content.
+func openFolder(path string) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(longpath.Fix(path))
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	h, err := windows.CreateFile(name, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return windows.InvalidHandle, fmt.Errorf("%w: open %s: %v", ErrJournalUnavailable, path, err)
+	}
+	return h, nil
+}
+
+// queryJournal returns the state of the USN journal of a volume.
+func queryJournal(vol windows.Handle) (*usnJournalData, error) {
+	var data usnJournalData
+	var n uint32
+	err := windows.DeviceIoControl(vol, fsctlQueryUSNJournal, nil, 0,
+		(*byte)(unsafe.Pointer(&data)), uint32(unsafe.Sizeof(data)), &n, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: query USN journal: %v", ErrJournalUnavailable, err)
+	}
+	return &data, nil
+}
+
+// readJournal calls fn with the parent folder ID and the name of each record
+// from since up to until, until fn returns false.
+func readJournal(vol windows.Handle, since, until JournalPosition, fn func(parentID uint64, name string) bool) error {
+	req := readUSNJournalData{
+		StartUsn:     since.USN,
+		ReasonMask:   0xFFFFFFFF,
+		UsnJournalID: uint64(since.JournalID),
+	}
+	code := uint32(fsctlReadUSNJournal)
+	buf := make([]byte, usnBufferSize)
+	for req.StartUsn < until.USN {
+		var n uint32
+		err := windows.DeviceIoControl(vol, code, (*byte)(unsafe.Pointer(&req)), uint32(unsafe.Sizeof(req)),
+			&buf[0], uint32(len(buf)), &n, nil)
+		switch {
+		case err == windows.ERROR_ACCESS_DENIED && code == fsctlReadUSNJournal:
+			code = fsctlReadUnprivilegedUSNJournal
+			continue
+		case errors.Is(err, windows.ERROR_JOURNAL_ENTRY_DELETED),
+			errors.Is(err, windows.ERROR_JOURNAL_NOT_ACTIVE),
+			errors.Is(err, windows.ERROR_JOURNAL_DELETE_IN_PROGRESS):
+			return ErrJournalExpired
+		case err != nil:
+			return fmt.Errorf("%w: read USN journal: %v", ErrJournalUnavailable, err)
+		}
+		if n <= 8 {
+			return nil
+		}
+
+		next := int64(binary.LittleEndian.Uint64(buf))
+		records := buf[8:n]
+		for len(records) >= usnRecordV2Size {
+			length := binary.LittleEndian.Uint32(records)
+			if length < usnRecordV2Size || int(length) > len(records) {
+				return fmt.Errorf("%w: malformed USN record", ErrJournalUnavailable)
+			}
+			if major := binary.LittleEndian.Uint16(records[4:]); major != 2 {
+				return fmt.Errorf("%w: USN records version %d", ErrJournalUnavailable, major)
+			}
+			record := records[:length]
+			records = records[length:]
+
+			if int64(binary.LittleEndian.Uint64(record[24:])) >= until.USN {
+				return nil
+			}
+			nameLen := int(binary.LittleEndian.Uint16(record[56:]))
+			nameOff := int(binary.LittleEndian.Uint16(record[58:]))
+			if nameOff+nameLen > len(record) {
+				return fmt.Errorf("%w: malformed USN record", ErrJournalUnavailable)
+			}
+			name := make([]uint16, nameLen/2)
+			for i := range name {
+				name[i] = binary.LittleEndian.Uint16(record[nameOff+2*i:])
+			}
+			if !fn(binary.LittleEndian.Uint64(record[16:]), windows.UTF16ToString(name)) {
+				return nil
+			}
+		}
+		if next <= req.StartUsn {
+			return nil
+		}
+		req.StartUsn = next
+	}
+	return nil
+}
+
+// folderByID returns the current path of a folder of the volume of hint,
+// or "" if it no longer exists.
+func folderByID(hint windows.Handle, id uint64) string {
+	desc := fileIDDescriptor{Type: 0, FileID: id} // FileIdType
+	desc.Size = uint32(unsafe.Sizeof(desc))
+	r, _, _ := procOpenFileById.Call(
+		uintptr(hint),
+		uintptr(unsafe.Pointer(&desc)),
+		0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		0,
+		windows.FILE_FLAG_BACKUP_SEMANTICS,
+	)
+	h := windows.Handle(r)
+	if h == windows.InvalidHandle {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+
+	path, err := finalPath(h)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// finalPath returns the path of an open file or folder, e.g. \\?\C:\Users,
+// without trailing separator.
+func finalPath(h windows.Handle) (string, error) {
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		n, err := windows.GetFinalPathNameByHandle(h, &buf[0], uint32(len(buf)), 0)
+		if err != nil {
+			return "", err
+		}
+		if int(n) < len(buf) {
+			return strings.TrimSuffix(windows.UTF16ToString(buf[:n]), `\`), nil
+		}
+		buf = make([]uint16, n+1)
+	}
+}
+
+// journalRelPath returns path relative to root with forward slashes, or false
+// when it is not under root.
+func journalRelPath(root, path string) (string, bool) {
+	if len(path) <= len(root)+1 || path[len(root)] != '\\' || !strings.EqualFold(path[:len(root)], root) {
+		return "", false
+	}
+	return filepath.ToSlash(path[len(root)+1:]), true
+}
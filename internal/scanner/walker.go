@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +21,7 @@ type Walker struct {
 	followSymlinks bool            // Whether to follow symlinks (default: false)
 	visited        map[string]bool // Track visited paths to prevent cycles
 	stats          *WalkStatistics
+	onExcluded     func(path string, isDir bool, result *ExclusionResult) // Optional: called for excluded paths
 }
 
 // WalkStatistics contains statistics about a walk operation
@@ -55,9 +57,21 @@ func (w *Walker) SetFollowSymlinks(follow bool) {
 	w.followSymlinks = follow
 }
 
+// SetExcludedFunc sets a function called for each excluded file/directory
+// (nil = none)
+func (w *Walker) SetExcludedFunc(fn func(path string, isDir bool, result *ExclusionResult)) {
+	w.onExcluded = fn
+}
+
 // Walk recursively traverses a directory tree starting at basePath
 // Applies exclusion rules and calls walkFn for each non-excluded file
 func (w *Walker) Walk(jobID int64, basePath string, walkFn WalkFunc) error {
+	return w.WalkSelected(jobID, basePath, nil, walkFn)
+}
+
+// WalkSelected is Walk without entering the directories for which skipDir
+// returns true (nil enters them all), e.g. folders left out of a selective sync.
+func (w *Walker) WalkSelected(jobID int64, basePath string, skipDir func(path string) bool, walkFn WalkFunc) error {
 	// Clean the base path
 	basePath = filepath.Clean(basePath)
 
@@ -130,9 +144,18 @@ func (w *Walker) Walk(jobID int64, basePath string, walkFn WalkFunc) error {
 			w.visited[realPath] = true
 		}
 
+		// Skip partial files of interrupted downloads (resumed by the next sync)
+		if !metadata.IsDir && partialfile.Is(info.Name()) {
+			w.stats.ExcludedFiles++
+			return nil
+		}
+
 		// Check exclusions
-		result := w.excluder.ShouldExclude(jobID, path, metadata.IsDir)
+		result := w.excluder.ShouldExcludeFile(jobID, path, metadata.IsDir, metadata.Size)
 		if result.Excluded {
+			if w.onExcluded != nil {
+				w.onExcluded(path, metadata.IsDir, result)
+			}
 			if metadata.IsDir {
 				w.stats.ExcludedDirs++
 				w.logger.Debug("excluding directory",
@@ -150,6 +173,13 @@ func (w *Walker) Walk(jobID int64, basePath string, walkFn WalkFunc) error {
 			}
 		}
 
+		// Folders outside the selection are never listed
+		if metadata.IsDir && skipDir != nil && path != basePath && skipDir(path) {
+			w.stats.ExcludedDirs++
+			w.logger.Debug("skipping unselected directory", zap.String("path", path))
+			return filepath.SkipDir
+		}
+
 		// Update statistics
 		if metadata.IsDir {
 			w.stats.TotalDirs++
@@ -0,0 +1,259 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the five standard fields:
+// minute, hour, day of month, month and day of week. Fields accept
+// "*", numbers, ranges ("1-5"), lists ("1,15"), steps ("*/15", "8-18/2")
+// and, for months and days of week, English names ("jan", "mon").
+// When both the day of month and the day of week are restricted, a day
+// matching either runs, as in crontab.
+type Cron struct {
+	expr string
+
+	minute, hour, dom, month, dow uint64 // Bit sets of the allowed values
+	domAny, dowAny                bool   // Day field left to "*"
+}
+
+// cronShortcuts are the predefined schedules of crontab.
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronHorizon bounds the search of Next: an expression that never matches
+// (e.g. "0 0 30 2 *") has no next run.
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+// ParseCron parses a cron expression such as "30 2 * * 1-5" or "@daily".
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[strings.ToLower(spec)]; ok {
+		spec = shortcut
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day month weekday)", expr)
+	}
+
+	c := &Cron{expr: strings.TrimSpace(expr)}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("cron day of week: %w", err)
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of items of one field.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(a, names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(b, names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if hasStep {
+				hi = max // "5/15" = from 5 to the end, every 15
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t matching the expression, in the
+// location of t, or the zero time if there is none.
+//
+// Times are matched against the wall clock. When clocks go forward, an
+// expression matching a time they skip runs once, when they do; when clocks
+// go back, it runs at the first of the two times the wall clock shows its
+// time. Expressions running every hour follow the clock instead: they skip
+// the times that don't exist and run through the repeated hour twice.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// Wall clock times are searched in UTC, where every day has 24 hours.
+	// The search starts early enough for the repeated hour ahead of t.
+	_, offset := t.Zone()
+	if _, end := t.ZoneBounds(); !end.IsZero() {
+		if _, after := end.Zone(); after < offset {
+			offset = after
+		}
+	}
+	wall := t.UTC().Add(time.Duration(offset) * time.Second).Truncate(time.Minute).Add(time.Minute)
+	limit := wall.Add(cronHorizon)
+
+	var best, settle time.Time // settle: wall clock time past which best is final
+	for wall.Before(limit) {
+		if !best.IsZero() && wall.After(settle) {
+			return best
+		}
+		switch {
+		case c.month&(1<<uint(wall.Month())) == 0:
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(wall):
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(wall.Hour())) == 0:
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour()+1, 0, 0, 0, time.UTC)
+		case c.minute&(1<<uint(wall.Minute())) == 0:
+			wall = wall.Add(time.Minute)
+		default:
+			// A second occurrence may come after the first one of a later time
+			if next, repeat, ok := c.place(wall, loc, t); ok && (best.IsZero() || next.Before(best)) {
+				best, settle = next, wall.Add(repeat)
+			}
+			wall = wall.Add(time.Minute)
+		}
+	}
+	return best
+}
+
+// everyHour is the hour field of an expression running every hour.
+const everyHour = 1<<24 - 1
+
+// place returns the first time after t the wall clock of loc shows wall (a
+// UTC time), and how much later the wall clock shows it again if that is a
+// second occurrence. ok is false when there is none.
+func (c *Cron) place(wall time.Time, loc *time.Location, t time.Time) (next time.Time, repeat time.Duration, ok bool) {
+	// The times wall may be are found with the offsets around its zone
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+	start, end := guess.ZoneBounds()
+	probes := []time.Time{guess}
+	if !start.IsZero() {
+		probes = append(probes, start.Add(-time.Second))
+	}
+	if !end.IsZero() {
+		probes = append(probes, end)
+	}
+	var first, second time.Time
+	for _, probe := range probes {
+		_, offset := probe.Zone()
+		at := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if !wallClock(at).Equal(wall) {
+			continue
+		}
+		switch {
+		case first.IsZero() || at.Equal(first):
+			first = at
+		case at.Before(first):
+			first, second = at, first
+		default:
+			second = at
+		}
+	}
+
+	switch {
+	case first.IsZero():
+		// Skipped when clocks went forward: run when they did
+		if c.hour == everyHour {
+			return time.Time{}, 0, false
+		}
+		change := start
+		if wallClock(guess).Before(wall) {
+			change = end
+		}
+		return change, 0, change.After(t)
+	case first.After(t):
+		return first, 0, true
+	case !second.IsZero() && c.hour == everyHour && second.After(t):
+		return second, second.Sub(first), true
+	}
+	return time.Time{}, 0, false
+}
+
+// wallClock returns the wall clock time of t as a UTC time.
+func wallClock(t time.Time) time.Time {
+	_, offset := t.Zone()
+	return t.UTC().Add(time.Duration(offset) * time.Second)
+}
+
+// dayMatches applies the crontab rule for the two day fields.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// String returns the expression as written.
+func (c *Cron) String() string {
+	return c.expr
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q): expected error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Wednesday 15 January 2025, 10:07
+	base := time.Date(2025, 1, 15, 10, 7, 30, 0, time.Local)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", at(1, 15, 10, 8)},
+		{"*/15 * * * *", at(1, 15, 10, 15)},
+		{"5/20 * * * *", at(1, 15, 10, 25)},
+		{"0 2 * * *", at(1, 16, 2, 0)},
+		{"@daily", at(1, 16, 0, 0)},
+		{"@hourly", at(1, 15, 11, 0)},
+		{"30 9 * * mon-fri", at(1, 16, 9, 30)},
+		{"0 8 * * sat,sun", at(1, 18, 8, 0)},
+		{"0 0 * * 7", at(1, 19, 0, 0)},
+		{"0 0 1 * *", at(2, 1, 0, 0)},
+		{"0 12 * feb *", at(2, 1, 12, 0)},
+		{"0 9-17/4 * * *", at(1, 15, 13, 0)},
+		// Day of month and day of week both restricted: either matches
+		{"0 0 20 * fri", at(1, 17, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %s, want %s", tt.expr, got.Format(time.DateTime), tt.want.Format(time.DateTime))
+		}
+	}
+}
+
+func TestCronNext_LeapDayAndNever(t *testing.T) {
+	c, err := ParseCron("0 0 29 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Next(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(29 Feb) = %s, want %s", got, want)
+	}
+
+	c, err = ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next(30 Feb) = %s, want zero time", got)
+	}
+}
+
+func TestCronNext_DST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// Clocks go from 02:00 to 03:00 on 2025-03-30 in Paris and on 2025-03-09
+	// in New York, and from 03:00 back to 02:00 on 2025-10-26 in Paris
+	spring := time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC) // 03:00 CEST
+	fallFirst := time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC)
+	fallSecond := time.Date(2025, 10, 26, 1, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{"skipped time runs when clocks go forward", "30 2 * * *",
+			time.Date(2025, 3, 29, 12, 0, 0, 0, paris),
+			[]time.Time{spring, time.Date(2025, 3, 31, 2, 30, 0, 0, paris)}},
+		{"skipped times run once", "*/20 2 * * *",
+			time.Date(2025, 3, 29, 23, 0, 0, 0, paris),
+			[]time.Time{spring, time.Date(2025, 3, 31, 2, 0, 0, 0, paris)}},
+		{"hourly skips the missing hour", "30 * * * *",
+			time.Date(2025, 3, 30, 1, 0, 0, 0, paris),
+			[]time.Time{time.Date(2025, 3, 30, 1, 30, 0, 0, paris), time.Date(2025, 3, 30, 3, 30, 0, 0, paris)}},
+		{"repeated time runs once", "30 2 * * *",
+			time.Date(2025, 10, 25, 12, 0, 0, 0, paris),
+			[]time.Time{fallFirst, time.Date(2025, 10, 27, 2, 30, 0, 0, paris)}},
+		{"hourly runs the repeated hour twice", "30 * * * *",
+			time.Date(2025, 10, 26, 1, 45, 0, 0, paris),
+			[]time.Time{fallFirst, fallSecond, time.Date(2025, 10, 26, 3, 30, 0, 0, paris)}},
+		{"clock going forward west of UTC", "30 2 * * *",
+			time.Date(2025, 3, 8, 12, 0, 0, 0, newYork),
+			[]time.Time{time.Date(2025, 3, 9, 7, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 2, 30, 0, 0, newYork)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from := tt.from
+			for i, want := range tt.want {
+				got := c.Next(from)
+				if !got.Equal(want) {
+					t.Fatalf("run %d of %q = %s, want %s", i, tt.expr, got, want.In(from.Location()))
+				}
+				from = got
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Package scheduler runs sync jobs on their triggers: cron expressions,
+// fixed intervals, network connections or user inactivity, as stored in the
+// trigger_mode and trigger_params columns of sync_jobs. The next run of every job is saved
+// (next_run), so schedules survive restarts and runs missed while the
+// application was closed are caught up. A random jitter spreads jobs due at
+// the same time, so they don't all hit the server at once.
+package scheduler
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// Job is a job as seen by the scheduler.
+type Job struct {
+	ID            int64
+	TriggerMode   string    // sync_jobs.trigger_mode
+	TriggerParams string    // sync_jobs.trigger_params
+	NextRun       time.Time // Saved next run (zero = none)
+}
+
+// JobFromDB returns the scheduling fields of a sync_jobs row.
+func JobFromDB(j *database.SyncJob) Job {
+	job := Job{ID: j.ID, TriggerMode: j.TriggerMode, TriggerParams: j.TriggerParams}
+	if j.NextRun != nil {
+		job.NextRun = *j.NextRun
+	}
+	return job
+}
+
+// Store saves the next run of jobs (zero = no next run).
+// *database.DB implements it.
+type Store interface {
+	UpdateJobNextRun(jobID int64, nextRun time.Time) error
+}
+
+// RunFunc runs a job. The scheduler waits for it to return before
+// computing the next run of the job.
+type RunFunc func(jobID int64)
+
+// Options configures a Scheduler.
+type Options struct {
+	Store     Store         // Saves next runs (nil = not saved)
+	MaxJitter time.Duration // Runs are delayed by a random duration up to this (0 = none)
+	Clock     clock.Clock   // Time and timers (nil = system clock)
+	Logger    *zap.Logger
+}
+
+// Scheduler runs jobs when their trigger fires.
+type Scheduler struct {
+	run       RunFunc
+	store     Store
+	maxJitter time.Duration
+	clock     clock.Clock
+	logger    *zap.Logger
+
+	// Replaced by tests
+	jitter func(max time.Duration) time.Duration
+
+	mu      sync.Mutex
+	entries map[int64]*entry
+	stopped bool
+}
+
+// entry is the schedule of one job.
+type entry struct {
+	id      int64
+	trigger Trigger
+	timer   clock.Timer
+	next    time.Time // Zero when no run is planned
+	gen     uint64    // Bumped on every (re)scheduling, to ignore stale timers
+	lastRun time.Time
+}
+
+// New creates a scheduler calling run for due jobs.
+func New(run RunFunc, opts Options) *Scheduler {
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Scheduler{
+		run:       run,
+		store:     opts.Store,
+		maxJitter: opts.MaxJitter,
+		clock:     clock.Or(opts.Clock),
+		logger:    logger,
+		jitter:    randomJitter,
+		entries:   make(map[int64]*entry),
+	}
+}
+
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
+
+// Schedule plans the runs of a job, replacing its previous schedule.
+// A saved next run in the future is kept; one in the past (missed while the
+// application was closed) runs shortly.
+func (s *Scheduler) Schedule(job Job) error {
+	trigger, err := ParseTrigger(job.TriggerMode, job.TriggerParams)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	e := s.entries[job.ID]
+	if e == nil {
+		e = &entry{id: job.ID}
+		s.entries[job.ID] = e
+	}
+	e.trigger = trigger
+
+	now := s.clock.Now()
+	next := trigger.Next(now)
+	switch {
+	case next.IsZero():
+	case job.NextRun.IsZero():
+		next = next.Add(s.jitter(s.maxJitter))
+	case job.NextRun.Before(now):
+		s.logger.Info("catching up missed run",
+			zap.Int64("job_id", job.ID),
+			zap.Time("missed", job.NextRun),
+		)
+		next = now.Add(s.jitter(s.maxJitter))
+	case job.NextRun.Before(next):
+		next = job.NextRun // Already jittered when it was saved
+	default:
+		next = next.Add(s.jitter(s.maxJitter))
+	}
+	s.arm(e, next)
+	s.mu.Unlock()
+
+	s.logger.Info("job scheduled",
+		zap.Int64("job_id", job.ID),
+		zap.Stringer("trigger", trigger),
+		zap.Time("next_run", next),
+	)
+	s.save(job.ID, next)
+	return nil
+}
+
+// Defer runs a job once at the given time (plus jitter) instead of its next
+// planned run, then resumes its schedule.
+func (s *Scheduler) Defer(jobID int64, at time.Time) {
+	s.mu.Lock()
+	e := s.entries[jobID]
+	if e == nil || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	at = at.Add(s.jitter(s.maxJitter))
+	s.arm(e, at)
+	s.mu.Unlock()
+
+	s.save(jobID, at)
+}
+
+// Unschedule stops planning runs of a job.
+func (s *Scheduler) Unschedule(jobID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[jobID]; ok {
+		s.disarm(e)
+		delete(s.entries, jobID)
+	}
+}
+
+// NetworkConnected runs the jobs triggered by network connections, unless
+// they ran less than their minimum gap ago.
+func (s *Scheduler) NetworkConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	now := s.clock.Now()
+	for _, e := range s.entries {
+		trigger, ok := e.trigger.(NetworkConnect)
+		if !ok || !e.next.IsZero() {
+			continue // Not a network job, or already planned
+		}
+		if !e.lastRun.IsZero() && now.Sub(e.lastRun) < trigger.MinGap {
+			continue
+		}
+		s.arm(e, now.Add(s.jitter(s.maxJitter)))
+		s.logger.Info("network connected, job planned",
+			zap.Int64("job_id", e.id),
+			zap.Time("next_run", e.next),
+		)
+	}
+}
+
+// UserIdle runs the jobs triggered by user inactivity once the user has been
+// idle for their idle time, once per idle period: a job that ran since the
+// user became idle waits for the next one.
+func (s *Scheduler) UserIdle(idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+	now := s.clock.Now()
+	idleSince := now.Add(-idle)
+	for _, e := range s.entries {
+		trigger, ok := e.trigger.(Idle)
+		if !ok || !e.next.IsZero() || idle < trigger.After {
+			continue // Not an idle job, already planned, or not idle long enough
+		}
+		if e.lastRun.After(idleSince) {
+			continue
+		}
+		s.arm(e, now.Add(s.jitter(s.maxJitter)))
+		s.logger.Info("user idle, job planned",
+			zap.Int64("job_id", e.id),
+			zap.Duration("idle", idle),
+			zap.Time("next_run", e.next),
+		)
+	}
+}
+
+// NextRun returns the next planned run of a job.
+func (s *Scheduler) NextRun(jobID int64) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[jobID]; ok && !e.next.IsZero() {
+		return e.next, true
+	}
+	return time.Time{}, false
+}
+
+// Len returns the number of scheduled jobs.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Stop cancels every planned run. Runs in progress are not interrupted.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, e := range s.entries {
+		s.disarm(e)
+		delete(s.entries, id)
+	}
+	s.stopped = true
+}
+
+// arm plans the next run of e at next (zero = none). Must hold s.mu.
+func (s *Scheduler) arm(e *entry, next time.Time) {
+	s.disarm(e)
+	e.next = next
+	if next.IsZero() {
+		return
+	}
+	gen := e.gen
+	e.timer = s.clock.AfterFunc(next.Sub(s.clock.Now()), func() {
+		s.fire(e.id, gen)
+	})
+}
+
+// disarm cancels the planned run of e. Must hold s.mu.
+func (s *Scheduler) disarm(e *entry) {
+	e.gen++
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.next = time.Time{}
+}
+
+// fire runs a due job, then plans its next run unless it was rescheduled
+// meanwhile (e.g. deferred by the run itself).
+func (s *Scheduler) fire(jobID int64, gen uint64) {
+	s.mu.Lock()
+	e := s.entries[jobID]
+	if e == nil || e.gen != gen || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	e.timer = nil
+	e.next = time.Time{}
+	e.lastRun = s.clock.Now()
+	s.mu.Unlock()
+
+	s.run(jobID)
+
+	s.mu.Lock()
+	if s.entries[jobID] != e || e.gen != gen || s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	next := e.trigger.Next(s.clock.Now())
+	if !next.IsZero() {
+		next = next.Add(s.jitter(s.maxJitter))
+	}
+	s.arm(e, next)
+	s.mu.Unlock()
+
+	s.save(jobID, next)
+}
+
+// save records the next run of a job in the store.
+func (s *Scheduler) save(jobID int64, next time.Time) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.UpdateJobNextRun(jobID, next); err != nil {
+		s.logger.Warn("failed to save next run",
+			zap.Int64("job_id", jobID),
+			zap.Error(err),
+		)
+	}
+}
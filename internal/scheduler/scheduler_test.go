@@ -0,0 +1,288 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+)
+
+func TestParseTrigger(t *testing.T) {
+	tests := []struct {
+		mode, params string
+		want         string
+	}{
+		{"manual", "manual", "manual"},
+		{"interval", "15m", "every 15m0s"},
+		{"scheduled", "1h", "every 1h0m0s"},
+		{"scheduled", "0 2 * * *", "0 2 * * *"},
+		{"realtime", "realtime", "every 5m0s"},
+		{"network", "", "on network connect (at most every 5m0s)"},
+		{"network", "1h", "on network connect (at most every 1h0m0s)"},
+		{"manual", "network", "on network connect (at most every 5m0s)"},
+		{"idle", "20m", "when idle for 20m0s"},
+		{"manual", "idle", "when idle for 10m0s"},
+	}
+	for _, tt := range tests {
+		trigger, err := ParseTrigger(tt.mode, tt.params)
+		if err != nil {
+			t.Fatalf("ParseTrigger(%q, %q): %v", tt.mode, tt.params, err)
+		}
+		if got := trigger.String(); got != tt.want {
+			t.Errorf("ParseTrigger(%q, %q) = %q, want %q", tt.mode, tt.params, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"interval", "10s"}, {"interval", "0 2 * * *"}, {"scheduled", "tomorrow"}, {"idle", "30s"}, {"weekly", ""}} {
+		if _, err := ParseTrigger(bad[0], bad[1]); err == nil {
+			t.Errorf("ParseTrigger(%q, %q): expected error", bad[0], bad[1])
+		}
+	}
+}
+
+type fakeStore struct {
+	mu   sync.Mutex
+	next map[int64]time.Time
+}
+
+func (s *fakeStore) UpdateJobNextRun(jobID int64, nextRun time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[jobID] = nextRun
+	return nil
+}
+
+func (s *fakeStore) get(jobID int64) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next[jobID]
+}
+
+func newTestScheduler(run RunFunc, now time.Time) (*Scheduler, *fakeStore) {
+	store := &fakeStore{next: make(map[int64]time.Time)}
+	s := New(run, Options{Store: store, MaxJitter: time.Minute, Clock: clock.NewFake(now)})
+	s.jitter = func(max time.Duration) time.Duration { return max / 2 }
+	return s, store
+}
+
+func TestSchedule_NextRun(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.Local)
+	s, store := newTestScheduler(func(int64) {}, now)
+	defer s.Stop()
+
+	// New job: trigger + jitter
+	if err := s.Schedule(Job{ID: 1, TriggerMode: "interval", TriggerParams: "15m"}); err != nil {
+		t.Fatal(err)
+	}
+	want := now.Add(15*time.Minute + 30*time.Second)
+	if got, _ := s.NextRun(1); !got.Equal(want) {
+		t.Errorf("new job next run = %s, want %s", got, want)
+	}
+	if got := store.get(1); !got.Equal(want) {
+		t.Errorf("saved next run = %s, want %s", got, want)
+	}
+
+	// Saved run still ahead: kept across restarts
+	saved := now.Add(5 * time.Minute)
+	s.Schedule(Job{ID: 2, TriggerMode: "interval", TriggerParams: "15m", NextRun: saved})
+	if got, _ := s.NextRun(2); !got.Equal(saved) {
+		t.Errorf("saved next run = %s, want %s kept", got, saved)
+	}
+
+	// Run missed while closed: caught up shortly
+	s.Schedule(Job{ID: 3, TriggerMode: "scheduled", TriggerParams: "0 2 * * *", NextRun: now.Add(-8 * time.Hour)})
+	if got, _ := s.NextRun(3); !got.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("missed run = %s, want %s", got, now.Add(30*time.Second))
+	}
+
+	// Manual and network jobs have no planned run
+	s.Schedule(Job{ID: 4, TriggerMode: "manual"})
+	s.Schedule(Job{ID: 5, TriggerMode: "network"})
+	for _, id := range []int64{4, 5} {
+		if _, ok := s.NextRun(id); ok {
+			t.Errorf("job %d has a planned run", id)
+		}
+	}
+	if s.Len() != 5 {
+		t.Errorf("Len() = %d, want 5", s.Len())
+	}
+
+	if err := s.Schedule(Job{ID: 6, TriggerMode: "interval", TriggerParams: "soon"}); err == nil {
+		t.Error("Schedule with invalid params: expected error")
+	}
+}
+
+func TestNetworkConnected(t *testing.T) {
+	now := time.Now()
+	ran := make(chan int64, 4)
+	s, store := newTestScheduler(func(id int64) { ran <- id }, now)
+	s.jitter = func(time.Duration) time.Duration { return 0 }
+	defer s.Stop()
+
+	s.Schedule(Job{ID: 1, TriggerMode: "network", TriggerParams: "1h"})
+	s.Schedule(Job{ID: 2, TriggerMode: "interval", TriggerParams: "1h"})
+
+	s.NetworkConnected()
+	select {
+	case id := <-ran:
+		if id != 1 {
+			t.Fatalf("job %d ran on network connect, want 1", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("network job did not run")
+	}
+
+	// Wait for the run to be recorded, then connect again within the gap
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		recorded := !s.entries[1].lastRun.IsZero() && s.entries[1].timer == nil
+		s.mu.Unlock()
+		if recorded || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.NetworkConnected()
+	if _, ok := s.NextRun(1); ok {
+		t.Error("network job planned again within its minimum gap")
+	}
+	if got := store.get(1); !got.IsZero() {
+		t.Errorf("saved next run of network job = %s, want none", got)
+	}
+}
+
+func TestUserIdle(t *testing.T) {
+	now := time.Now()
+	var runs []int64
+	s, _ := newTestScheduler(func(id int64) { runs = append(runs, id) }, now)
+	s.jitter = func(time.Duration) time.Duration { return time.Second }
+	fake := s.clock.(*clock.Fake)
+	defer s.Stop()
+
+	s.Schedule(Job{ID: 1, TriggerMode: "idle", TriggerParams: "10m"})
+	s.Schedule(Job{ID: 2, TriggerMode: "idle", TriggerParams: "30m"})
+	s.Schedule(Job{ID: 3, TriggerMode: "network"})
+
+	s.UserIdle(15 * time.Minute)
+	if got, ok := s.NextRun(1); !ok || !got.Equal(now.Add(time.Second)) {
+		t.Errorf("idle job next run = %s, %v; want %s", got, ok, now.Add(time.Second))
+	}
+	for _, id := range []int64{2, 3} {
+		if _, ok := s.NextRun(id); ok {
+			t.Errorf("job %d planned after 15 minutes of inactivity", id)
+		}
+	}
+	fake.Advance(time.Second)
+	if len(runs) != 1 || runs[0] != 1 {
+		t.Fatalf("runs = %v, want [1]", runs)
+	}
+
+	// Once per idle period
+	fake.Advance(time.Minute)
+	s.UserIdle(16 * time.Minute)
+	if _, ok := s.NextRun(1); ok {
+		t.Error("idle job planned again in the same idle period")
+	}
+
+	// The user came back, then left again
+	fake.Advance(time.Hour)
+	s.UserIdle(5 * time.Minute)
+	if _, ok := s.NextRun(1); ok {
+		t.Error("idle job planned before its idle time")
+	}
+	s.UserIdle(12 * time.Minute)
+	if _, ok := s.NextRun(1); !ok {
+		t.Error("idle job not planned in a new idle period")
+	}
+}
+
+func TestDefer(t *testing.T) {
+	now := time.Now()
+	s, store := newTestScheduler(func(int64) {}, now)
+	defer s.Stop()
+
+	s.Schedule(Job{ID: 1, TriggerMode: "interval", TriggerParams: "15m"})
+	at := now.Add(time.Hour)
+	s.Defer(1, at)
+
+	want := at.Add(30 * time.Second)
+	if got, _ := s.NextRun(1); !got.Equal(want) {
+		t.Errorf("deferred run = %s, want %s", got, want)
+	}
+	if got := store.get(1); !got.Equal(want) {
+		t.Errorf("saved deferred run = %s, want %s", got, want)
+	}
+
+	s.Unschedule(1)
+	if _, ok := s.NextRun(1); ok {
+		t.Error("unscheduled job still has a planned run")
+	}
+}
+
+func TestScheduler_FakeClockAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// Clocks jump from 02:00 to 03:00 on 2025-03-30
+	fake := clock.NewFake(time.Date(2025, 3, 29, 12, 0, 0, 0, paris))
+
+	var runs []time.Time
+	s := New(func(int64) { runs = append(runs, fake.Now()) }, Options{Clock: fake})
+	s.jitter = func(time.Duration) time.Duration { return 0 }
+	defer s.Stop()
+
+	if err := s.Schedule(Job{ID: 1, TriggerMode: "scheduled", TriggerParams: "0 3 * * *"}); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	fake.Advance(14 * time.Hour) // 03:00 CEST, an hour shorter than the wall clock says
+	fake.Advance(24 * time.Hour)
+
+	if len(runs) != 2 {
+		t.Fatalf("ran %d times, want 2: %v", len(runs), runs)
+	}
+	for i, run := range runs {
+		if run.Hour() != 3 || run.Minute() != 0 {
+			t.Errorf("run %d at %s, want 03:00", i, run)
+		}
+	}
+	if next, _ := s.NextRun(1); !next.Equal(time.Date(2025, 4, 1, 3, 0, 0, 0, paris)) {
+		t.Errorf("next run = %s, want 2025-04-01 03:00", next)
+	}
+}
+
+func TestScheduler_FakeClockAcrossFallBack(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// Clocks go back from 03:00 to 02:00 on 2025-10-26: 02:30 shows twice
+	fake := clock.NewFake(time.Date(2025, 10, 25, 12, 0, 0, 0, paris))
+
+	var runs []time.Time
+	s := New(func(int64) { runs = append(runs, fake.Now()) }, Options{Clock: fake})
+	s.jitter = func(time.Duration) time.Duration { return 0 }
+	defer s.Stop()
+
+	if err := s.Schedule(Job{ID: 1, TriggerMode: "scheduled", TriggerParams: "30 2 * * *"}); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	for i := 0; i < 48; i++ {
+		fake.Advance(time.Hour)
+	}
+
+	want := []time.Time{
+		time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC), // 02:30 CEST
+		time.Date(2025, 10, 27, 2, 30, 0, 0, paris),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("ran %d times, want %d: %v", len(runs), len(want), runs)
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("run %d at %s, want %s", i, runs[i], want[i].In(paris))
+		}
+	}
+}
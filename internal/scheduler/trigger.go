@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trigger decides when a job runs.
+type Trigger interface {
+	// Next returns the first run after t, or the zero time if the trigger
+	// does not run on a clock (manual jobs, jobs run on network connect).
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// Trigger modes stored in sync_jobs.trigger_mode.
+const (
+	ModeManual    = "manual"    // Only run on demand
+	ModeInterval  = "interval"  // Every trigger_params (a duration, e.g. "15m")
+	ModeScheduled = "scheduled" // trigger_params is a cron expression or a duration
+	ModeRealtime  = "realtime"  // Local changes are watched; the remote is checked every RealtimeRemoteCheck
+	ModeNetwork   = "network"   // When the PC connects to a network (trigger_params: minimum gap, e.g. "1h")
+	ModeIdle      = "idle"      // When the user has been idle for trigger_params (e.g. "10m")
+)
+
+// Before schema version 25, the trigger_mode CHECK constraint of sync_jobs
+// didn't accept network and idle: these jobs were stored as trigger_mode
+// "manual" with trigger_params "network" or "idle". The migration moves them
+// to their real mode; ParseTrigger still reads the old form, which job
+// exports made back then may hold.
+
+// RealtimeRemoteCheck is how often realtime jobs look for remote changes,
+// which the local file watcher cannot see.
+const RealtimeRemoteCheck = 5 * time.Minute
+
+// minInterval keeps a mistyped interval ("5s") from hammering the server.
+const minInterval = time.Minute
+
+// defaultNetworkGap is the minimum time between two runs of a job on
+// network connect, as connections often flap while a laptop wakes up.
+const defaultNetworkGap = 5 * time.Minute
+
+// DefaultIdleAfter is how long the user must be idle before an idle job runs.
+const DefaultIdleAfter = 10 * time.Minute
+
+// Interval runs a job at a fixed period.
+type Interval time.Duration
+
+// Next implements Trigger.
+func (i Interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+func (i Interval) String() string {
+	return "every " + time.Duration(i).String()
+}
+
+// Manual never runs a job by itself.
+type Manual struct{}
+
+// Next implements Trigger.
+func (Manual) Next(time.Time) time.Time { return time.Time{} }
+
+func (Manual) String() string { return ModeManual }
+
+// NetworkConnect runs a job when the PC connects to a network, at most once
+// per MinGap.
+type NetworkConnect struct {
+	MinGap time.Duration
+}
+
+// Next implements Trigger: network triggers are event-driven.
+func (NetworkConnect) Next(time.Time) time.Time { return time.Time{} }
+
+func (n NetworkConnect) String() string {
+	return "on network connect (at most every " + n.MinGap.String() + ")"
+}
+
+// Idle runs a job once the user has been idle (no keyboard or mouse input)
+// for After, once per idle period.
+type Idle struct {
+	After time.Duration
+}
+
+// Next implements Trigger: idle triggers are event-driven.
+func (Idle) Next(time.Time) time.Time { return time.Time{} }
+
+func (i Idle) String() string {
+	return "when idle for " + i.After.String()
+}
+
+// ParseTrigger returns the trigger of a job from the trigger_mode and
+// trigger_params columns of sync_jobs. Params may also hold the exact mode
+// chosen in the application ("5m", "1h", "realtime", "manual").
+func ParseTrigger(mode, params string) (Trigger, error) {
+	params = strings.TrimSpace(params)
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case ModeManual, "":
+		switch params {
+		case ModeNetwork:
+			return NetworkConnect{MinGap: defaultNetworkGap}, nil
+		case ModeIdle:
+			return Idle{After: DefaultIdleAfter}, nil
+		}
+		return Manual{}, nil
+
+	case ModeRealtime:
+		return Interval(RealtimeRemoteCheck), nil
+
+	case ModeInterval:
+		return parseInterval(params)
+
+	case ModeScheduled:
+		if _, err := time.ParseDuration(params); err == nil {
+			return parseInterval(params)
+		}
+		return ParseCron(params)
+
+	case ModeNetwork:
+		gap := defaultNetworkGap
+		if params != "" && params != ModeNetwork {
+			d, err := time.ParseDuration(params)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid minimum gap %q for network trigger", params)
+			}
+			gap = d
+		}
+		return NetworkConnect{MinGap: gap}, nil
+
+	case ModeIdle:
+		after := DefaultIdleAfter
+		if params != "" && params != ModeIdle {
+			d, err := time.ParseDuration(params)
+			if err != nil || d < minInterval {
+				return nil, fmt.Errorf("invalid idle time %q (at least %s, e.g. 10m)", params, minInterval)
+			}
+			after = d
+		}
+		return Idle{After: after}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown trigger mode %q (use manual, interval, scheduled, realtime, network or idle)", mode)
+	}
+}
+
+func parseInterval(params string) (Trigger, error) {
+	d, err := time.ParseDuration(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval %q (e.g. 15m, 1h)", params)
+	}
+	if d < minInterval {
+		return nil, fmt.Errorf("interval %s is shorter than %s", d, minInterval)
+	}
+	return Interval(d), nil
+}
@@ -0,0 +1,61 @@
+package smb
+
+import (
+	"errors"
+	"io"
+)
+
+// --- Chunk Alignment ---
+//
+// Block-level tools and deduplicating NAS file systems (ZFS, Btrfs) see the
+// same blocks in two copies of a file only when they were written at the
+// same boundaries. With an alignment set, transfers write fixed-size chunks
+// at multiples of it: streamed copies write whole blocks, and the chunks of
+// resumable and parallel transfers are sized in multiples of it, so a VM
+// disk or a backup image always lands on the same boundaries.
+
+// SetChunkAlignment sets the boundary transfers write at, in bytes (0 = any).
+func (c *SMBClient) SetChunkAlignment(alignment int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alignment = max(alignment, 0)
+}
+
+// AlignChunkSize rounds a chunk size up to a multiple of alignment
+// (unchanged if alignment is 0).
+func AlignChunkSize(size, alignment int64) int64 {
+	if alignment <= 0 {
+		return size
+	}
+	if size <= alignment {
+		return alignment
+	}
+	return (size + alignment - 1) / alignment * alignment
+}
+
+// copyAligned copies src to dst in writes of block bytes, the last one
+// holding the rest. A block of 0 copies like io.Copy.
+func copyAligned(dst io.Writer, src io.Reader, block int64) (int64, error) {
+	if block <= 0 {
+		return io.Copy(dst, src)
+	}
+
+	buf := make([]byte, block)
+	var written int64
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		if n > 0 {
+			w, err := dst.Write(buf[:n])
+			written += int64(w)
+			if err != nil {
+				return written, err
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
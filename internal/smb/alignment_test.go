@@ -0,0 +1,59 @@
+package smb
+
+import (
+	"bytes"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAlignChunkSize(t *testing.T) {
+	const mib = 1 << 20
+	tests := []struct {
+		size, alignment, want int64
+	}{
+		{4 * mib, 0, 4 * mib},
+		{4 * mib, mib, 4 * mib},
+		{4*mib + 1, mib, 5 * mib},
+		{64 << 10, mib, mib},
+		{4 * mib, 3 * mib, 6 * mib},
+	}
+	for _, tt := range tests {
+		if got := AlignChunkSize(tt.size, tt.alignment); got != tt.want {
+			t.Errorf("AlignChunkSize(%d, %d) = %d, want %d", tt.size, tt.alignment, got, tt.want)
+		}
+	}
+}
+
+// recordingWriter records the size of each write.
+type recordingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestCopyAligned(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25) // 250 bytes
+
+	// Short reads from the source still give whole blocks
+	var dst recordingWriter
+	n, err := copyAligned(&dst, iotest.OneByteReader(bytes.NewReader(data)), 100)
+	if err != nil {
+		t.Fatalf("copyAligned() error = %v", err)
+	}
+	if n != 250 || !bytes.Equal(dst.Bytes(), data) {
+		t.Fatalf("copied %d bytes, content equal = %v", n, bytes.Equal(dst.Bytes(), data))
+	}
+	if len(dst.writes) != 3 || dst.writes[0] != 100 || dst.writes[1] != 100 || dst.writes[2] != 50 {
+		t.Errorf("writes = %v, want [100 100 50]", dst.writes)
+	}
+
+	// Empty source: nothing written
+	dst = recordingWriter{}
+	if n, err := copyAligned(&dst, bytes.NewReader(nil), 100); n != 0 || err != nil || len(dst.writes) != 0 {
+		t.Errorf("empty copy: n = %d, err = %v, writes = %v", n, err, dst.writes)
+	}
+}
@@ -0,0 +1,185 @@
+package smb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
+	"go.uber.org/zap"
+)
+
+// --- Small File Upload Batches ---
+//
+// Uploading a small file costs more round trips than bytes: the remote
+// folder is checked, the temp file created, written and closed, the
+// destination removed, then the temp file renamed. A batch checks each
+// remote folder once, reads every file whole and writes it with a single
+// request sequence, only removes destinations known to exist, and keeps
+// several files in flight over the SMB session.
+
+// MaxBatchFileSize caps the size of the files of an upload batch, which are
+// read whole in memory.
+const MaxBatchFileSize = 1024 * 1024
+
+// BatchUpload is a file of an upload batch.
+type BatchUpload struct {
+	LocalPath  string
+	RemotePath string // Relative to the share root
+	Replace    bool   // A remote file exists at RemotePath
+}
+
+// BatchResult is the outcome of the upload of a file of a batch.
+type BatchResult struct {
+	Hash string // Hex-encoded SHA-256 of the content
+	Size int64
+	Err  error
+
+	timesErr error // The server rejected setting the file times
+}
+
+// batchFS is the part of the share used by upload batches (replaced by tests).
+type batchFS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Remove(name string) error
+	Rename(oldpath, newpath string) error
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// UploadBatch uploads small files (up to MaxBatchFileSize), streams at a
+// time, each atomically like Upload. Results are in the order of files; a
+// failed file does not stop the others.
+func (c *SMBClient) UploadBatch(ctx context.Context, files []BatchUpload, streams int) []BatchResult {
+	c.mu.RLock()
+	connected, fs, preserveTimes := c.connected, c.fs, c.preserveTimes
+	c.mu.RUnlock()
+
+	if !connected {
+		results := make([]BatchResult, len(files))
+		for i := range results {
+			results[i].Err = fmt.Errorf("not connected to SMB server")
+		}
+		return results
+	}
+	log := correlation.Logger(ctx, c.logger)
+	results := uploadBatch(ctx, fs, files, streams, preserveTimes, log)
+	for i, r := range results {
+		if r.timesErr != nil {
+			c.timesRejected(files[i].RemotePath, r.timesErr, log)
+			break
+		}
+	}
+	return results
+}
+
+// uploadBatch uploads files to fs, streams at a time, keeping their
+// modification time if preserveTimes.
+func uploadBatch(ctx context.Context, fs batchFS, files []BatchUpload, streams int, preserveTimes bool, log *zap.Logger) []BatchResult {
+	results := make([]BatchResult, len(files))
+	if streams < 1 {
+		streams = 1
+	}
+
+	// Each remote folder once, parents before children (ignore errors like Upload)
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		if dir := filepath.Dir(f.RemotePath); dir != "." && dir != "/" {
+			dirs[dir] = true
+		}
+	}
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+	for _, dir := range sorted {
+		_ = fs.MkdirAll(dir, 0755)
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(streams, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = uploadSmallFile(ctx, fs, files[i], preserveTimes)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	log.Info("upload batch completed",
+		zap.Int("files", len(files)),
+		zap.Int("folders", len(sorted)),
+		zap.Int("failed", failed),
+		zap.Int("streams", streams))
+
+	return results
+}
+
+// uploadSmallFile writes a file of a batch to a temp file, then renames it over the destination.
+func uploadSmallFile(ctx context.Context, fs batchFS, f BatchUpload, preserveTimes bool) BatchResult {
+	if err := ctx.Err(); err != nil {
+		return BatchResult{Err: err}
+	}
+
+	data, err := os.ReadFile(f.LocalPath)
+	if err != nil {
+		return BatchResult{Err: fmt.Errorf("failed to read local file %s: %w", f.LocalPath, err)}
+	}
+	if len(data) > MaxBatchFileSize {
+		return BatchResult{Err: fmt.Errorf("file %s too large for an upload batch (%d bytes)", f.LocalPath, len(data))}
+	}
+	sum := sha256.Sum256(data)
+
+	if err := bandwidth.Wait(ctx, bandwidth.Upload, len(data)); err != nil {
+		return BatchResult{Err: err}
+	}
+
+	tempPath := f.RemotePath + UploadTempSuffix
+	if err := fs.WriteFile(tempPath, data, 0644); err != nil {
+		fs.Remove(tempPath)
+		return BatchResult{Err: fmt.Errorf("failed to write remote file %s: %w", tempPath, err)}
+	}
+
+	// Set on the temp file, the rename keeps it
+	var timesErr error
+	if preserveTimes {
+		if info, err := os.Stat(f.LocalPath); err == nil {
+			timesErr = fs.Chtimes(tempPath, info.ModTime(), info.ModTime())
+		}
+	}
+
+	// Rename won't overwrite on SMB
+	if f.Replace {
+		fs.Remove(f.RemotePath)
+	}
+	if err := fs.Rename(tempPath, f.RemotePath); err != nil {
+		// Created on the server since the scan
+		if f.Replace || fs.Remove(f.RemotePath) != nil || fs.Rename(tempPath, f.RemotePath) != nil {
+			fs.Remove(tempPath)
+			return BatchResult{Err: fmt.Errorf("failed to rename temp file to %s: %w", f.RemotePath, err)}
+		}
+	}
+
+	return BatchResult{Hash: hex.EncodeToString(sum[:]), Size: int64(len(data)), timesErr: timesErr}
+}
@@ -0,0 +1,181 @@
+package smb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// dirFS is a batchFS on a local folder that counts its requests.
+type dirFS struct {
+	root string
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newDirFS(root string) *dirFS {
+	return &dirFS{root: root, calls: make(map[string]int)}
+}
+
+func (d *dirFS) count(op string) {
+	d.mu.Lock()
+	d.calls[op]++
+	d.mu.Unlock()
+}
+
+func (d *dirFS) MkdirAll(path string, perm os.FileMode) error {
+	d.count("mkdir")
+	return os.MkdirAll(filepath.Join(d.root, path), perm)
+}
+
+func (d *dirFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	d.count("write")
+	return os.WriteFile(filepath.Join(d.root, name), data, perm)
+}
+
+func (d *dirFS) Remove(name string) error {
+	d.count("remove")
+	return os.Remove(filepath.Join(d.root, name))
+}
+
+// Rename fails when the destination exists, like SMB
+func (d *dirFS) Rename(oldpath, newpath string) error {
+	d.count("rename")
+	if _, err := os.Stat(filepath.Join(d.root, newpath)); err == nil {
+		return fmt.Errorf("rename %s: file exists", newpath)
+	}
+	return os.Rename(filepath.Join(d.root, oldpath), filepath.Join(d.root, newpath))
+}
+
+func (d *dirFS) Chtimes(name string, atime, mtime time.Time) error {
+	d.count("chtimes")
+	return os.Chtimes(filepath.Join(d.root, name), atime, mtime)
+}
+
+func TestUploadBatch(t *testing.T) {
+	local := t.TempDir()
+	remote := newDirFS(t.TempDir())
+
+	var files []BatchUpload
+	for i := range 40 {
+		rel := filepath.Join(fmt.Sprintf("dir%d", i%4), "sub", fmt.Sprintf("file%02d.txt", i))
+		path := filepath.Join(local, fmt.Sprintf("file%02d.txt", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("content %d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, BatchUpload{LocalPath: path, RemotePath: rel})
+	}
+
+	// One file replaces a remote copy, another appeared on the server since the scan
+	os.MkdirAll(filepath.Join(remote.root, "dir0", "sub"), 0755)
+	os.WriteFile(filepath.Join(remote.root, files[0].RemotePath), []byte("old"), 0644)
+	files[0].Replace = true
+	os.WriteFile(filepath.Join(remote.root, files[4].RemotePath), []byte("new on server"), 0644)
+
+	results := uploadBatch(context.Background(), remote, files, 4, false, zap.NewNop())
+
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("file %d failed: %v", i, r.Err)
+		}
+		want := fmt.Sprintf("content %d", i)
+		got, err := os.ReadFile(filepath.Join(remote.root, files[i].RemotePath))
+		if err != nil || string(got) != want {
+			t.Fatalf("remote file %d = %q, %v; want %q", i, got, err, want)
+		}
+		sum := sha256.Sum256([]byte(want))
+		if r.Hash != hex.EncodeToString(sum[:]) || r.Size != int64(len(want)) {
+			t.Errorf("file %d: unexpected hash or size %+v", i, r)
+		}
+	}
+
+	// Each folder once, and no removal for new files
+	if remote.calls["mkdir"] != 4 {
+		t.Errorf("expected 4 folder checks, got %d", remote.calls["mkdir"])
+	}
+	if remote.calls["write"] != 40 {
+		t.Errorf("expected 40 writes, got %d", remote.calls["write"])
+	}
+	if remote.calls["remove"] != 2 {
+		t.Errorf("expected 2 removals (replaced file, file created since the scan), got %d", remote.calls["remove"])
+	}
+
+	// No temp file left behind
+	filepath.Walk(remote.root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, UploadTempSuffix) {
+			t.Errorf("temp file left: %s", path)
+		}
+		return nil
+	})
+}
+
+func TestUploadBatch_FailuresAreIsolated(t *testing.T) {
+	local := t.TempDir()
+	remote := newDirFS(t.TempDir())
+
+	ok := filepath.Join(local, "ok.txt")
+	os.WriteFile(ok, []byte("ok"), 0644)
+	files := []BatchUpload{
+		{LocalPath: filepath.Join(local, "missing.txt"), RemotePath: "missing.txt"},
+		{LocalPath: ok, RemotePath: "ok.txt"},
+	}
+
+	results := uploadBatch(context.Background(), remote, files, 2, false, zap.NewNop())
+	if results[0].Err == nil {
+		t.Error("expected an error for the missing file")
+	}
+	if results[1].Err != nil {
+		t.Errorf("expected the other file to upload, got %v", results[1].Err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = uploadBatch(ctx, remote, files[1:], 2, false, zap.NewNop())
+	if results[0].Err == nil {
+		t.Error("expected an error after cancellation")
+	}
+}
+
+func TestUploadBatch_PreservesTimes(t *testing.T) {
+	local := t.TempDir()
+	remote := newDirFS(t.TempDir())
+
+	path := filepath.Join(local, "report.txt")
+	os.WriteFile(path, []byte("report"), 0644)
+	mtime := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	files := []BatchUpload{{LocalPath: path, RemotePath: "report.txt"}}
+
+	results := uploadBatch(context.Background(), remote, files, 1, true, zap.NewNop())
+	if results[0].Err != nil || results[0].timesErr != nil {
+		t.Fatalf("upload failed: %+v", results[0])
+	}
+	info, err := os.Stat(filepath.Join(remote.root, "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("remote modification time = %v, want %v", info.ModTime(), mtime)
+	}
+
+	// Not set when disabled
+	results = uploadBatch(context.Background(), remote, files, 1, false, zap.NewNop())
+	if results[0].Err != nil {
+		t.Fatalf("upload failed: %v", results[0].Err)
+	}
+	if remote.calls["chtimes"] != 1 {
+		t.Errorf("expected 1 chtimes request, got %d", remote.calls["chtimes"])
+	}
+}
@@ -0,0 +1,295 @@
+package smb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
+)
+
+// --- Parallel Chunked Transfers ---
+//
+// A large file is split into chunks copied by several concurrent requests
+// over the SMB session (ReadAt/WriteAt at the offset of each chunk), which
+// hides the round trip a serial copy waits for on every request. The SHA-256
+// of every chunk is kept, and the reassembled file is checked against them
+// before it replaces the destination.
+
+// errChunkMismatch is returned when the reassembled file differs from the chunks transferred.
+var errChunkMismatch = errors.New("reassembled file differs from the transferred chunks")
+
+// ParallelTransfer splits large files into chunks transferred concurrently.
+// The zero value disables it.
+type ParallelTransfer struct {
+	MinSize   int64 // Size from which a file is split (0 = never)
+	ChunkSize int64 // Size of the chunks
+	Streams   int   // Chunks transferred at the same time
+}
+
+// Applies reports whether a file of the given size is transferred in parallel chunks.
+func (p ParallelTransfer) Applies(size int64) bool {
+	return p.MinSize > 0 && p.ChunkSize > 0 && p.Streams > 1 && size >= p.MinSize
+}
+
+// count returns the number of chunks of a file of the given size.
+func (p ParallelTransfer) count(size int64) int {
+	return int((size + p.ChunkSize - 1) / p.ChunkSize)
+}
+
+// span returns the offset and length of chunk i of a file of the given size.
+func (p ParallelTransfer) span(i int, size int64) (int64, int) {
+	off := int64(i) * p.ChunkSize
+	return off, int(min(p.ChunkSize, size-off))
+}
+
+// SetParallelTransfer enables parallel chunked transfers of large files.
+func (c *SMBClient) SetParallelTransfer(p ParallelTransfer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parallel = p
+}
+
+// forEachChunk runs fn for chunks 0 to count-1, streams at a time. The first
+// error cancels the chunks not started yet and is returned.
+func forEachChunk(ctx context.Context, count, streams int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	next := make(chan int)
+	for w := 0; w < min(streams, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < count; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// readChunk reads n bytes of src at off, waiting on the limiters of ctx for dir.
+func readChunk(ctx context.Context, src io.ReaderAt, off int64, n int, dir bandwidth.Direction) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	read, err := src.ReadAt(buf, off)
+	if read < n {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if err := bandwidth.Wait(ctx, dir, n); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// copyChunk copies n bytes at off from src to dst and returns their hex SHA-256.
+func copyChunk(ctx context.Context, dst io.WriterAt, src io.ReaderAt, off int64, n int, dir bandwidth.Direction) (string, error) {
+	buf, err := readChunk(ctx, src, off, n, dir)
+	if err != nil {
+		return "", err
+	}
+	if _, err := dst.WriteAt(buf, off); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashChunks reads r sequentially and returns the hex SHA-256 of its content
+// and the chunks whose hash differs from want.
+func hashChunks(r io.ReaderAt, size, chunkSize int64, want []string) (string, []int, error) {
+	hasher := sha256.New()
+	section := io.NewSectionReader(r, 0, size)
+	buf := make([]byte, chunkSize)
+	var bad []int
+	for i := range want {
+		n, err := io.ReadFull(section, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", nil, fmt.Errorf("failed to read reassembled file: %w", err)
+		}
+		hasher.Write(buf[:n])
+		sum := sha256.Sum256(buf[:n])
+		if hex.EncodeToString(sum[:]) != want[i] {
+			bad = append(bad, i)
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), bad, nil
+}
+
+// remoteFile is the part of an open SMB file used by chunked uploads.
+type remoteFile interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// uploadParallel copies local (size bytes) to remote in parallel chunks,
+// reads every chunk back from the server to check it, and returns the hex
+// SHA-256 of the content.
+func uploadParallel(ctx context.Context, remote remoteFile, local io.ReaderAt, size int64, p ParallelTransfer) (string, error) {
+	sums := make([]string, p.count(size))
+	err := forEachChunk(ctx, len(sums), p.Streams, func(ctx context.Context, i int) error {
+		off, n := p.span(i, size)
+		sum, err := copyChunk(ctx, remote, local, off, n, bandwidth.Upload)
+		sums[i] = sum
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The reassembled remote file must hold the chunks as sent
+	err = forEachChunk(ctx, len(sums), p.Streams, func(ctx context.Context, i int) error {
+		off, n := p.span(i, size)
+		buf, err := readChunk(ctx, remote, off, n, bandwidth.Download)
+		if err != nil {
+			return fmt.Errorf("failed to read back chunk %d: %w", i, err)
+		}
+		if sum := sha256.Sum256(buf); hex.EncodeToString(sum[:]) != sums[i] {
+			return fmt.Errorf("%w: chunk %d at offset %d", errChunkMismatch, i, off)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Hash of the whole file, from a sequential read of the local copy
+	hash, bad, err := hashChunks(local, size, p.ChunkSize, sums)
+	if err != nil {
+		return "", err
+	}
+	if len(bad) > 0 {
+		return "", fmt.Errorf("local file changed during upload (%d chunk(s) differ)", len(bad))
+	}
+	return hash, nil
+}
+
+// downloadParallel copies remote (size bytes, modified at modTime) to
+// localPath in parallel chunks, through a partial file like
+// downloadResumable: an interrupted download keeps its completed chunks,
+// whatever their order. Returns the hex SHA-256 of the content and the
+// bytes kept from a previous attempt.
+func downloadParallel(ctx context.Context, remote io.ReaderAt, size int64, modTime time.Time, localPath string, p ParallelTransfer) (string, int64, error) {
+	partialPath := localPath + partialfile.Suffix
+	chunksPath := partialPath + partialfile.ChunksSuffix
+
+	chunks := loadDownloadChunks(chunksPath)
+	if chunks == nil || !chunks.matches(size, modTime, p.ChunkSize) {
+		chunks = &downloadChunks{Size: size, ModTime: modTime.UnixNano(), ChunkSize: p.ChunkSize}
+	}
+	// Chunks complete out of order: "" marks a missing one
+	hashes := make([]string, p.count(size))
+	copy(hashes, chunks.Hashes)
+	chunks.Hashes = hashes
+
+	localFile, err := os.OpenFile(partialPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create local file %s: %w", partialPath, err)
+	}
+	defer localFile.Close()
+	if err := localFile.Truncate(size); err != nil {
+		return "", 0, fmt.Errorf("failed to size partial file: %w", err)
+	}
+
+	// Keep the chunks of a previous attempt that are still intact
+	var resumed int64
+	for i, want := range chunks.Hashes {
+		if want == "" {
+			continue
+		}
+		off, n := p.span(i, size)
+		buf := make([]byte, n)
+		if _, err := localFile.ReadAt(buf, off); err == nil {
+			if sum := sha256.Sum256(buf); hex.EncodeToString(sum[:]) == want {
+				resumed += int64(n)
+				continue
+			}
+		}
+		chunks.Hashes[i] = ""
+	}
+	if err := chunks.save(chunksPath); err != nil {
+		return "", 0, fmt.Errorf("failed to save chunk hashes: %w", err)
+	}
+
+	var mu sync.Mutex
+	err = forEachChunk(ctx, len(chunks.Hashes), p.Streams, func(ctx context.Context, i int) error {
+		if chunks.Hashes[i] != "" {
+			return nil
+		}
+		off, n := p.span(i, size)
+		sum, err := copyChunk(ctx, localFile, remote, off, n, bandwidth.Download)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		chunks.Hashes[i] = sum
+		return chunks.saveThrottled(chunksPath)
+	})
+	if err != nil {
+		// Keep the partial file: the next attempt downloads the missing chunks only
+		chunks.save(chunksPath)
+		return "", resumed, fmt.Errorf("failed to copy data: %w", err)
+	}
+
+	// The reassembled file must hold the chunks as received
+	hash, bad, err := hashChunks(localFile, size, p.ChunkSize, chunks.Hashes)
+	if err != nil {
+		return "", resumed, err
+	}
+	if len(bad) > 0 {
+		for _, i := range bad {
+			chunks.Hashes[i] = ""
+		}
+		chunks.save(chunksPath)
+		return "", resumed, fmt.Errorf("%w: %d chunk(s)", errChunkMismatch, len(bad))
+	}
+
+	if err := localFile.Close(); err != nil {
+		return "", resumed, fmt.Errorf("failed to close partial file: %w", err)
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return "", resumed, fmt.Errorf("failed to rename partial file to %s: %w", localPath, err)
+	}
+	os.Remove(chunksPath)
+
+	return hash, resumed, nil
+}
@@ -0,0 +1,153 @@
+package smb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
+)
+
+// memFile is an in-memory remote file safe for concurrent ReadAt/WriteAt.
+type memFile struct {
+	mu   sync.Mutex
+	data []byte
+
+	corruptAt int64 // Offset of a byte flipped on write (-1 = none)
+	failAt    int64 // Reads from this offset fail (-1 = never)
+}
+
+func newMemFile(data []byte) *memFile {
+	return &memFile{data: data, corruptAt: -1, failAt: -1}
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failAt >= 0 && off+int64(len(p)) > f.failAt {
+		return 0, errors.New("connection reset")
+	}
+	return bytes.NewReader(f.data).ReadAt(p, off)
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[off:], p)
+	if f.corruptAt >= off && f.corruptAt < off+int64(len(p)) {
+		f.data[f.corruptAt] ^= 0xFF
+	}
+	return len(p), nil
+}
+
+var testParallel = ParallelTransfer{MinSize: 1, ChunkSize: 1000, Streams: 3}
+
+func TestUploadParallel(t *testing.T) {
+	content := bytes.Repeat([]byte("anemone-"), 1000) // 8000 bytes, 8 chunks
+	remote := newMemFile(nil)
+
+	hash, err := uploadParallel(context.Background(), remote, bytes.NewReader(content), int64(len(content)), testParallel)
+	if err != nil {
+		t.Fatalf("uploadParallel failed: %v", err)
+	}
+	if hash != sha256Hex(content) {
+		t.Error("Hash should cover the whole content")
+	}
+	if !bytes.Equal(remote.data, content) {
+		t.Error("Reassembled remote file differs from local")
+	}
+}
+
+func TestUploadParallel_CorruptChunk(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 450) // 4500 bytes, last chunk partial
+	remote := newMemFile(nil)
+	remote.corruptAt = 2500
+
+	_, err := uploadParallel(context.Background(), remote, bytes.NewReader(content), int64(len(content)), testParallel)
+	if !errors.Is(err, errChunkMismatch) {
+		t.Fatalf("Expected errChunkMismatch, got %v", err)
+	}
+}
+
+func TestDownloadParallel(t *testing.T) {
+	content := bytes.Repeat([]byte("anemone-"), 1000)
+	modTime := time.Now()
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+
+	// Interrupted: the chunks before the failing one are kept
+	remote := newMemFile(content)
+	remote.failAt = 5500
+	p := ParallelTransfer{MinSize: 1, ChunkSize: 1000, Streams: 1}
+	if _, _, err := downloadParallel(context.Background(), remote, int64(len(content)), modTime, localPath, p); err == nil {
+		t.Fatal("Expected interrupted download to fail")
+	}
+
+	hash, resumed, err := downloadParallel(context.Background(), newMemFile(content), int64(len(content)), modTime, localPath, testParallel)
+	if err != nil {
+		t.Fatalf("Resumed download failed: %v", err)
+	}
+	if resumed != 5000 {
+		t.Errorf("Expected 5000 bytes kept, got %d", resumed)
+	}
+	if hash != sha256Hex(content) {
+		t.Error("Hash should cover the whole content")
+	}
+
+	got, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("Downloaded content differs from remote")
+	}
+	if _, err := os.Stat(localPath + partialfile.Suffix + partialfile.ChunksSuffix); !os.IsNotExist(err) {
+		t.Error("Chunk hashes should be removed after completion")
+	}
+}
+
+func TestDownloadParallel_ResumesSerialPartial(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 500) // 8000 bytes
+	modTime := time.Now()
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+
+	// A serial download with the same chunk size left 3 chunks
+	remote := &failingReadSeeker{Reader: bytes.NewReader(content), failAt: 3500}
+	if _, _, err := downloadResumable(remote, int64(len(content)), modTime, localPath, 1000); err == nil {
+		t.Fatal("Expected interrupted download to fail")
+	}
+
+	hash, resumed, err := downloadParallel(context.Background(), newMemFile(content), int64(len(content)), modTime, localPath, testParallel)
+	if err != nil {
+		t.Fatalf("Parallel download failed: %v", err)
+	}
+	if resumed != 3000 {
+		t.Errorf("Expected 3000 bytes kept, got %d", resumed)
+	}
+	if hash != sha256Hex(content) {
+		t.Error("Hash should cover the whole content")
+	}
+}
+
+func TestParallelTransfer_Applies(t *testing.T) {
+	p := ParallelTransfer{MinSize: 100, ChunkSize: 10, Streams: 4}
+	if p.Applies(99) || !p.Applies(100) {
+		t.Error("Applies should start at MinSize")
+	}
+	if (ParallelTransfer{MinSize: 100, ChunkSize: 10, Streams: 1}).Applies(1000) {
+		t.Error("a single stream is a serial transfer")
+	}
+	if (ParallelTransfer{}).Applies(1 << 40) {
+		t.Error("zero value should be disabled")
+	}
+	if off, n := p.span(10, 105); off != 100 || n != 5 {
+		t.Errorf("span(10) = %d, %d, want 100, 5", off, n)
+	}
+}
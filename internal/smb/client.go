@@ -1,8 +1,10 @@
 package smb
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -28,10 +30,30 @@ type SMBClient struct {
 	session *smb2.Session
 	fs      *smb2.Share
 
+	// Remote timestamp used as ModTime
+	mtimeSource MTimeSource
+
+	// Large files transferred in parallel chunks (zero = disabled)
+	parallel ParallelTransfer
+
+	// Transfers keep the modification time of the source file (see times.go)
+	preserveTimes bool
+
+	// Boundary transfers write at, in bytes (zero = any, see alignment.go)
+	alignment int64
+
+	// Signing or encryption negotiated by the last connection
+	security SecurityLevel
+
 	// State
 	mu        sync.RWMutex
 	connected bool
 
+	// Session recovery (see recover.go)
+	recoverMu  sync.Mutex
+	generation uint64 // Incremented on each connection
+	recoverErr error  // Why the session of generation could not be recovered
+
 	// Logger
 	logger *zap.Logger
 }
@@ -44,6 +66,9 @@ type ClientConfig struct {
 	Username string
 	Password string
 	Domain   string // Optional domain
+
+	// MTimeSource selects the remote timestamp reported as ModTime ("" = write time)
+	MTimeSource MTimeSource
 }
 
 // NewSMBClient creates a new SMB client instance
@@ -70,13 +95,14 @@ func NewSMBClient(cfg *ClientConfig, logger *zap.Logger) (*SMBClient, error) {
 	}
 
 	return &SMBClient{
-		server:   cfg.Server,
-		share:    cfg.Share,
-		port:     port,
-		username: cfg.Username,
-		password: cfg.Password,
-		domain:   cfg.Domain,
-		logger:   logger.With(zap.String("component", "smb")),
+		server:      cfg.Server,
+		share:       cfg.Share,
+		port:        port,
+		username:    cfg.Username,
+		password:    cfg.Password,
+		domain:      cfg.Domain,
+		mtimeSource: cfg.MTimeSource.normalize(),
+		logger:      logger.With(zap.String("component", "smb")),
 	}, nil
 }
 
@@ -95,12 +121,13 @@ func (c *SMBClient) Connect() error {
 		zap.Int("port", c.port))
 
 	// Connect to server
-	addr := fmt.Sprintf("%s:%d", c.server, c.port)
-	conn, err := net.Dial("tcp", addr)
+	addr := net.JoinHostPort(c.server, strconv.Itoa(c.port))
+	conn, err := dialServer(context.Background(), c.server, c.port, c.logger)
 	if err != nil {
 		return fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
-	c.conn = conn
+	observer := newSecurityObserver(conn)
+	c.conn = observer
 
 	// Create SMB2 dialer
 	c.dialer = &smb2.Dialer{
@@ -112,7 +139,7 @@ func (c *SMBClient) Connect() error {
 	}
 
 	// Start SMB2 session
-	session, err := c.dialer.Dial(conn)
+	session, err := c.dialer.Dial(observer)
 	if err != nil {
 		c.conn.Close()
 		return fmt.Errorf("failed to create SMB session: %w", err)
@@ -126,13 +153,25 @@ func (c *SMBClient) Connect() error {
 		c.conn.Close()
 		return fmt.Errorf("failed to mount share %s: %w", c.share, err)
 	}
+
+	// Check the negotiated security before transferring anything
+	c.security = observer.level()
+	if err := checkSecurity(c.server, c.security); err != nil {
+		fs.Umount()
+		c.session.Logoff()
+		c.conn.Close()
+		return fmt.Errorf("connection to %s refused: %w", c.server, err)
+	}
 	c.fs = fs
 
 	c.connected = true
+	c.generation++
+	c.recoverErr = nil
 
 	c.logger.Info("successfully connected to SMB server",
 		zap.String("server", c.server),
-		zap.String("share", c.share))
+		zap.String("share", c.share),
+		zap.String("security", string(c.security)))
 
 	return nil
 }
@@ -197,11 +236,42 @@ func (c *SMBClient) GetServer() string {
 	return c.server
 }
 
+// SetMTimeSource selects which remote timestamp is reported as ModTime.
+func (c *SMBClient) SetMTimeSource(source MTimeSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mtimeSource = source.normalize()
+}
+
 // GetShare returns the share name
 func (c *SMBClient) GetShare() string {
 	return c.share
 }
 
+// RedirectorPath returns the UNC path of a remote file (\\server\share\path),
+// to reach it through the Windows SMB redirector with the session of the
+// signed-in Windows user rather than the credentials of the client.
+func (c *SMBClient) RedirectorPath(remotePath string) string {
+	return `\\` + c.server + `\` + c.share + `\` + strings.ReplaceAll(strings.TrimLeft(remotePath, `\/`), "/", `\`)
+}
+
+// GetAccount returns the account the client signs in with, as DOMAIN\user
+// (user alone without a domain): the owner of the files it writes.
+func (c *SMBClient) GetAccount() string {
+	if c.domain == "" {
+		return c.username
+	}
+	return c.domain + `\` + c.username
+}
+
+// Security returns the signing or encryption negotiated by the last
+// connection ("" before connecting).
+func (c *SMBClient) Security() SecurityLevel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.security
+}
+
 // NewSMBClientFromKeyring creates a new SMB client using credentials from the system keyring
 // server is used to identify the credentials in the keyring
 func NewSMBClientFromKeyring(server, share string, logger *zap.Logger) (*SMBClient, error) {
@@ -301,8 +371,8 @@ func ListSharesOnServer(server string, port int, username, password, domain stri
 		zap.Int("port", port))
 
 	// Connect to server
-	addr := fmt.Sprintf("%s:%d", server, port)
-	conn, err := net.Dial("tcp", addr)
+	addr := net.JoinHostPort(server, strconv.Itoa(port))
+	conn, err := dialServer(context.Background(), server, port, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
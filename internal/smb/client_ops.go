@@ -2,12 +2,18 @@
 package smb
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/hirochachacha/go-smb2"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
 	"go.uber.org/zap"
 )
 
@@ -16,67 +22,171 @@ type RemoteFileInfo struct {
 	Name    string    // File or directory name
 	Path    string    // Full path relative to share root
 	Size    int64     // Size in bytes (0 for directories)
-	ModTime time.Time // Last modification time
+	ModTime time.Time // Modification time used for change detection (see MTimeSource)
 	IsDir   bool      // True if this is a directory
+
+	// Raw server timestamps (zero if the server did not report them)
+	WriteTime  time.Time // LastWriteTime
+	ChangeTime time.Time // ChangeTime (also updated by metadata-only operations)
+
+	// Raw FILE_ATTRIBUTE_* bits (0 if the server did not report them)
+	Attributes uint32
+}
+
+// MTimeSource selects which remote timestamp drives change detection.
+// Some servers update ChangeTime but not LastWriteTime on metadata operations
+// (or the reverse), so the choice is made per server.
+type MTimeSource string
+
+const (
+	// MTimeSourceWrite uses LastWriteTime (default)
+	MTimeSourceWrite MTimeSource = "write"
+	// MTimeSourceChange uses ChangeTime
+	MTimeSourceChange MTimeSource = "change"
+)
+
+// normalize returns the source, defaulting unknown values to MTimeSourceWrite.
+func (s MTimeSource) normalize() MTimeSource {
+	if s == MTimeSourceChange {
+		return MTimeSourceChange
+	}
+	return MTimeSourceWrite
+}
+
+// newRemoteFileInfo builds a RemoteFileInfo from a go-smb2 file info,
+// selecting ModTime according to source.
+func newRemoteFileInfo(info os.FileInfo, path string, source MTimeSource) RemoteFileInfo {
+	result := RemoteFileInfo{
+		Name:      info.Name(),
+		Path:      path,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		IsDir:     info.IsDir(),
+		WriteTime: info.ModTime(),
+	}
+
+	if stat, ok := info.Sys().(*smb2.FileStat); ok {
+		result.WriteTime = stat.LastWriteTime
+		result.ChangeTime = stat.ChangeTime
+		result.Attributes = stat.FileAttributes
+		if source == MTimeSourceChange && !stat.ChangeTime.IsZero() {
+			result.ModTime = stat.ChangeTime
+		}
+	}
+
+	return result
 }
 
 // Download downloads a file from the SMB share to local filesystem
 // remotePath is relative to the share root (e.g., "folder/file.txt")
 // localPath is the absolute local path where the file will be saved
 func (c *SMBClient) Download(remotePath, localPath string) error {
+	_, err := c.DownloadWithHash(remotePath, localPath)
+	return err
+}
+
+// DownloadWithHash downloads a file like Download and returns the hex-encoded
+// SHA-256 of the content, computed while copying (no second read of the file).
+func (c *SMBClient) DownloadWithHash(remotePath, localPath string) (string, error) {
+	return c.DownloadWithHashContext(context.Background(), remotePath, localPath)
+}
+
+// DownloadWithHashContext is DownloadWithHash with the correlation IDs of ctx
+// in its logs, limited by the download bandwidth limiters of ctx.
+func (c *SMBClient) DownloadWithHashContext(ctx context.Context, remotePath, localPath string) (string, error) {
+	log := correlation.Logger(ctx, c.logger)
+
 	c.mu.RLock()
 	if !c.connected {
 		c.mu.RUnlock()
-		return fmt.Errorf("not connected to SMB server")
+		return "", fmt.Errorf("not connected to SMB server")
 	}
 	fs := c.fs
+	parallel := c.parallel
+	preserveTimes := c.preserveTimes
+	alignment := c.alignment
 	c.mu.RUnlock()
+	parallel.ChunkSize = AlignChunkSize(parallel.ChunkSize, alignment)
 
-	c.logger.Debug("downloading file",
+	log.Debug("downloading file",
 		zap.String("remote", remotePath),
 		zap.String("local", localPath))
 
 	// Open remote file for reading
 	remoteFile, err := fs.Open(remotePath)
 	if err != nil {
-		return fmt.Errorf("failed to open remote file %s: %w", remotePath, err)
+		return "", fmt.Errorf("failed to open remote file %s: %w", remotePath, err)
 	}
 	defer remoteFile.Close()
 
 	// Get remote file info for logging
 	remoteInfo, err := remoteFile.Stat()
 	if err != nil {
-		c.logger.Warn("failed to get remote file info", zap.Error(err))
+		log.Warn("failed to get remote file info", zap.Error(err))
 	}
 
 	// Create local directory if needed
 	localDir := filepath.Dir(localPath)
 	if err := os.MkdirAll(localDir, 0755); err != nil {
-		return fmt.Errorf("failed to create local directory %s: %w", localDir, err)
+		return "", fmt.Errorf("failed to create local directory %s: %w", localDir, err)
+	}
+
+	// Large files go through a partial file so an interrupted download can
+	// resume, the largest ones in parallel chunks
+	if remoteInfo != nil && (remoteInfo.Size() >= ResumableDownloadMinSize || parallel.Applies(remoteInfo.Size())) {
+		var hash string
+		var resumedFrom int64
+		if parallel.Applies(remoteInfo.Size()) {
+			hash, resumedFrom, err = downloadParallel(ctx, remoteFile, remoteInfo.Size(), remoteInfo.ModTime(), localPath, parallel)
+		} else {
+			remote := struct {
+				io.Reader
+				io.Seeker
+			}{bandwidth.NewReader(ctx, remoteFile, bandwidth.Download), remoteFile}
+			hash, resumedFrom, err = downloadResumable(remote, remoteInfo.Size(), remoteInfo.ModTime(), localPath, AlignChunkSize(DownloadChunkSize, alignment))
+		}
+		if err != nil {
+			return "", err
+		}
+		if preserveTimes {
+			setLocalTime(localPath, remoteInfo.ModTime(), log)
+		}
+		log.Info("file downloaded successfully",
+			zap.String("remote", remotePath),
+			zap.String("local", localPath),
+			zap.Int64("size", remoteInfo.Size()),
+			zap.Int64("resumed_from", resumedFrom),
+			zap.Bool("parallel", parallel.Applies(remoteInfo.Size())))
+		return hash, nil
 	}
 
 	// Create local file
 	localFile, err := os.Create(localPath)
 	if err != nil {
-		return fmt.Errorf("failed to create local file %s: %w", localPath, err)
+		return "", fmt.Errorf("failed to create local file %s: %w", localPath, err)
 	}
 	defer localFile.Close()
 
-	// Copy data from remote to local
-	written, err := io.Copy(localFile, remoteFile)
+	// Copy data from remote to local, hashing on the fly
+	hasher := sha256.New()
+	written, err := copyAligned(localFile, io.TeeReader(bandwidth.NewReader(ctx, remoteFile, bandwidth.Download), hasher), alignment)
 	if err != nil {
 		// Try to clean up incomplete file
 		os.Remove(localPath)
-		return fmt.Errorf("failed to copy data: %w", err)
+		return "", fmt.Errorf("failed to copy data: %w", err)
+	}
+	if preserveTimes && remoteInfo != nil {
+		localFile.Close() // Close before setting the times
+		setLocalTime(localPath, remoteInfo.ModTime(), log)
 	}
 
-	c.logger.Info("file downloaded successfully",
+	log.Info("file downloaded successfully",
 		zap.String("remote", remotePath),
 		zap.String("local", localPath),
 		zap.Int64("bytes", written),
 		zap.Int64("size", remoteInfo.Size()))
 
-	return nil
+	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
 // ReadFile reads a file from the SMB share and returns its content.
@@ -138,6 +248,35 @@ func (c *SMBClient) OpenFile(remotePath string) (io.ReadCloser, error) {
 	return remoteFile, nil
 }
 
+// HashFileContext reads a remote file back and returns the hex-encoded
+// SHA-256 of its content, e.g. to check an upload against the hash computed
+// while sending it. Reads are limited by the download bandwidth limiters of
+// ctx.
+func (c *SMBClient) HashFileContext(ctx context.Context, remotePath string) (string, error) {
+	c.mu.RLock()
+	if !c.connected {
+		c.mu.RUnlock()
+		return "", fmt.Errorf("not connected to SMB server")
+	}
+	fs := c.fs
+	c.mu.RUnlock()
+
+	correlation.Logger(ctx, c.logger).Debug("reading remote file back",
+		zap.String("remote", remotePath))
+
+	remoteFile, err := fs.Open(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open remote file %s: %w", remotePath, err)
+	}
+	defer remoteFile.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, bandwidth.NewReader(ctx, remoteFile, bandwidth.Download)); err != nil {
+		return "", fmt.Errorf("failed to read remote file %s: %w", remotePath, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 // UploadTempSuffix is the suffix used for temporary upload files (atomic upload)
 const UploadTempSuffix = ".anemone-uploading"
 
@@ -146,35 +285,54 @@ const UploadTempSuffix = ".anemone-uploading"
 // remotePath is relative to the share root (e.g., "folder/file.txt")
 // Uses atomic upload: writes to .anemone-uploading file first, then renames
 func (c *SMBClient) Upload(localPath, remotePath string) error {
+	_, err := c.UploadWithHash(localPath, remotePath)
+	return err
+}
+
+// UploadWithHash uploads a file like Upload and returns the hex-encoded
+// SHA-256 of the content, computed while copying (no second read of the file).
+func (c *SMBClient) UploadWithHash(localPath, remotePath string) (string, error) {
+	return c.UploadWithHashContext(context.Background(), localPath, remotePath)
+}
+
+// UploadWithHashContext is UploadWithHash with the correlation IDs of ctx in
+// its logs, limited by the upload bandwidth limiters of ctx.
+func (c *SMBClient) UploadWithHashContext(ctx context.Context, localPath, remotePath string) (string, error) {
+	log := correlation.Logger(ctx, c.logger)
+
 	c.mu.RLock()
 	if !c.connected {
 		c.mu.RUnlock()
-		return fmt.Errorf("not connected to SMB server")
+		return "", fmt.Errorf("not connected to SMB server")
 	}
 	fs := c.fs
+	parallel := c.parallel
+	preserveTimes := c.preserveTimes
+	alignment := c.alignment
 	c.mu.RUnlock()
+	parallel.ChunkSize = AlignChunkSize(parallel.ChunkSize, alignment)
 
-	c.logger.Debug("uploading file",
+	log.Debug("uploading file",
 		zap.String("local", localPath),
 		zap.String("remote", remotePath))
 
 	// Open local file for reading
 	localFile, err := os.Open(localPath)
 	if err != nil {
-		return fmt.Errorf("failed to open local file %s: %w", localPath, err)
+		return "", fmt.Errorf("failed to open local file %s: %w", localPath, err)
 	}
 	defer localFile.Close()
 
 	// Get local file info
 	localInfo, err := localFile.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to get local file info: %w", err)
+		return "", fmt.Errorf("failed to get local file info: %w", err)
 	}
 
 	// Check if it's a directory (we can't upload directories this way)
 	// Note: Cloud Files placeholders are reparse points but can be read normally
 	if localInfo.IsDir() {
-		return fmt.Errorf("cannot upload directory: %s", localPath)
+		return "", fmt.Errorf("cannot upload directory: %s", localPath)
 	}
 
 	// Create remote directory if needed
@@ -190,17 +348,34 @@ func (c *SMBClient) Upload(localPath, remotePath string) error {
 	// Create temp remote file
 	remoteFile, err := fs.Create(tempPath)
 	if err != nil {
-		return fmt.Errorf("failed to create remote file %s: %w", tempPath, err)
+		return "", fmt.Errorf("failed to create remote file %s: %w", tempPath, err)
 	}
 
-	// Copy data from local to remote
-	written, err := io.Copy(remoteFile, localFile)
+	// Copy data from local to remote, hashing on the fly (large files in
+	// parallel chunks, checked once reassembled)
+	var hash string
+	var written int64
+	if parallel.Applies(localInfo.Size()) {
+		hash, err = uploadParallel(ctx, remoteFile, localFile, localInfo.Size(), parallel)
+		if err == nil {
+			written = localInfo.Size()
+		}
+	} else {
+		hasher := sha256.New()
+		written, err = copyAligned(remoteFile, io.TeeReader(bandwidth.NewReader(ctx, localFile, bandwidth.Upload), hasher), alignment)
+		hash = hex.EncodeToString(hasher.Sum(nil))
+	}
 	remoteFile.Close() // Close before rename
 
 	if err != nil {
 		// Try to clean up incomplete temp file (may fail if connection lost)
 		fs.Remove(tempPath)
-		return fmt.Errorf("failed to copy data: %w", err)
+		return "", fmt.Errorf("failed to copy data: %w", err)
+	}
+
+	// Set on the temp file, the rename keeps it
+	if preserveTimes {
+		c.setRemoteTime(fs, tempPath, localInfo.ModTime(), log)
 	}
 
 	// Remove existing file if present (rename won't overwrite on SMB)
@@ -210,16 +385,17 @@ func (c *SMBClient) Upload(localPath, remotePath string) error {
 	if err := fs.Rename(tempPath, remotePath); err != nil {
 		// Try to clean up temp file
 		fs.Remove(tempPath)
-		return fmt.Errorf("failed to rename temp file to %s: %w", remotePath, err)
+		return "", fmt.Errorf("failed to rename temp file to %s: %w", remotePath, err)
 	}
 
-	c.logger.Info("file uploaded successfully",
+	log.Info("file uploaded successfully",
 		zap.String("local", localPath),
 		zap.String("remote", remotePath),
 		zap.Int64("bytes", written),
-		zap.Int64("size", localInfo.Size()))
+		zap.Int64("size", localInfo.Size()),
+		zap.Bool("parallel", parallel.Applies(localInfo.Size())))
 
-	return nil
+	return hash, nil
 }
 
 // ListRemote lists files and directories in the specified remote path
@@ -232,6 +408,7 @@ func (c *SMBClient) ListRemote(remotePath string) ([]RemoteFileInfo, error) {
 		return nil, fmt.Errorf("not connected to SMB server")
 	}
 	fs := c.fs
+	mtimeSource := c.mtimeSource
 	c.mu.RUnlock()
 
 	c.logger.Debug("listing remote directory",
@@ -259,13 +436,7 @@ func (c *SMBClient) ListRemote(remotePath string) ([]RemoteFileInfo, error) {
 			fullPath = filepath.Join(remotePath, info.Name())
 		}
 
-		result = append(result, RemoteFileInfo{
-			Name:    info.Name(),
-			Path:    fullPath,
-			Size:    info.Size(),
-			ModTime: info.ModTime(),
-			IsDir:   info.IsDir(),
-		})
+		result = append(result, newRemoteFileInfo(info, fullPath, mtimeSource))
 	}
 
 	c.logger.Info("remote directory listed successfully",
@@ -285,6 +456,7 @@ func (c *SMBClient) GetMetadata(remotePath string) (*RemoteFileInfo, error) {
 		return nil, fmt.Errorf("not connected to SMB server")
 	}
 	fs := c.fs
+	mtimeSource := c.mtimeSource
 	c.mu.RUnlock()
 
 	c.logger.Debug("getting remote file metadata",
@@ -296,13 +468,8 @@ func (c *SMBClient) GetMetadata(remotePath string) (*RemoteFileInfo, error) {
 		return nil, fmt.Errorf("failed to get metadata for %s: %w", remotePath, err)
 	}
 
-	result := &RemoteFileInfo{
-		Name:    info.Name(),
-		Path:    remotePath,
-		Size:    info.Size(),
-		ModTime: info.ModTime(),
-		IsDir:   info.IsDir(),
-	}
+	fileInfo := newRemoteFileInfo(info, remotePath, mtimeSource)
+	result := &fileInfo
 
 	c.logger.Debug("metadata retrieved successfully",
 		zap.String("remote", remotePath),
@@ -316,6 +483,13 @@ func (c *SMBClient) GetMetadata(remotePath string) (*RemoteFileInfo, error) {
 // remotePath is relative to the share root (e.g., "folder/file.txt")
 // Note: This only removes files, not directories (use RemoveAll for directories)
 func (c *SMBClient) Delete(remotePath string) error {
+	return c.DeleteContext(context.Background(), remotePath)
+}
+
+// DeleteContext is Delete with the correlation IDs of ctx in its logs.
+func (c *SMBClient) DeleteContext(ctx context.Context, remotePath string) error {
+	log := correlation.Logger(ctx, c.logger)
+
 	c.mu.RLock()
 	if !c.connected {
 		c.mu.RUnlock()
@@ -324,7 +498,7 @@ func (c *SMBClient) Delete(remotePath string) error {
 	fs := c.fs
 	c.mu.RUnlock()
 
-	c.logger.Debug("deleting remote file",
+	log.Debug("deleting remote file",
 		zap.String("remote", remotePath))
 
 	// Remove the file
@@ -332,8 +506,85 @@ func (c *SMBClient) Delete(remotePath string) error {
 		return fmt.Errorf("failed to delete %s: %w", remotePath, err)
 	}
 
-	c.logger.Info("remote file deleted successfully",
+	log.Info("remote file deleted successfully",
 		zap.String("remote", remotePath))
 
 	return nil
 }
+
+// RenameContext renames a remote file. Fails if newPath exists.
+func (c *SMBClient) RenameContext(ctx context.Context, oldPath, newPath string) error {
+	log := correlation.Logger(ctx, c.logger)
+
+	c.mu.RLock()
+	if !c.connected {
+		c.mu.RUnlock()
+		return fmt.Errorf("not connected to SMB server")
+	}
+	fs := c.fs
+	c.mu.RUnlock()
+
+	if err := fs.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", oldPath, newPath, err)
+	}
+
+	log.Info("remote file renamed",
+		zap.String("from", oldPath),
+		zap.String("to", newPath))
+
+	return nil
+}
+
+// MoveContext moves a remote file to another path of the share, creating its
+// folder if needed. Fails if newPath exists.
+func (c *SMBClient) MoveContext(ctx context.Context, oldPath, newPath string) error {
+	c.mu.RLock()
+	if !c.connected {
+		c.mu.RUnlock()
+		return fmt.Errorf("not connected to SMB server")
+	}
+	fs := c.fs
+	c.mu.RUnlock()
+
+	if newDir := filepath.Dir(newPath); newDir != "." && newDir != "/" {
+		// Ignore the error if the folder already exists
+		_ = fs.MkdirAll(newDir, 0755)
+	}
+
+	return c.RenameContext(ctx, oldPath, newPath)
+}
+
+// SetReadOnly sets or clears the read-only attribute of a remote file.
+// Other attribute bits are preserved.
+func (c *SMBClient) SetReadOnly(remotePath string, readOnly bool) error {
+	return c.SetReadOnlyContext(context.Background(), remotePath, readOnly)
+}
+
+// SetReadOnlyContext is SetReadOnly with the correlation IDs of ctx in its logs.
+func (c *SMBClient) SetReadOnlyContext(ctx context.Context, remotePath string, readOnly bool) error {
+	log := correlation.Logger(ctx, c.logger)
+
+	c.mu.RLock()
+	if !c.connected {
+		c.mu.RUnlock()
+		return fmt.Errorf("not connected to SMB server")
+	}
+	fs := c.fs
+	c.mu.RUnlock()
+
+	// go-smb2 maps the owner write bit to FILE_ATTRIBUTE_READONLY
+	mode := os.FileMode(0644)
+	if readOnly {
+		mode = 0444
+	}
+
+	if err := fs.Chmod(remotePath, mode); err != nil {
+		return fmt.Errorf("failed to set attributes on %s: %w", remotePath, err)
+	}
+
+	log.Debug("remote read-only attribute updated",
+		zap.String("remote", remotePath),
+		zap.Bool("read_only", readOnly))
+
+	return nil
+}
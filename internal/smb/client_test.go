@@ -2,7 +2,9 @@ package smb
 
 import (
 	"testing"
+	"time"
 
+	"github.com/hirochachacha/go-smb2"
 	"go.uber.org/zap"
 )
 
@@ -170,6 +172,13 @@ func TestSMBClient_BasicState(t *testing.T) {
 	if client.GetShare() != "test-share" {
 		t.Errorf("GetShare: expected test-share, got %s", client.GetShare())
 	}
+	if client.GetAccount() != "user" {
+		t.Errorf("GetAccount: expected user, got %s", client.GetAccount())
+	}
+	client.domain = "CORP"
+	if client.GetAccount() != `CORP\user` {
+		t.Errorf("GetAccount: expected CORP\\user, got %s", client.GetAccount())
+	}
 
 	// Test disconnect on non-connected client (should not error)
 	if err := client.Disconnect(); err != nil {
@@ -177,6 +186,34 @@ func TestSMBClient_BasicState(t *testing.T) {
 	}
 }
 
+func TestNewRemoteFileInfo_MTimeSource(t *testing.T) {
+	writeTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	changeTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	stat := &smb2.FileStat{
+		FileName:      "file.txt",
+		EndOfFile:     42,
+		LastWriteTime: writeTime,
+		ChangeTime:    changeTime,
+	}
+
+	info := newRemoteFileInfo(stat, "dir/file.txt", MTimeSourceWrite)
+	if !info.ModTime.Equal(writeTime) {
+		t.Errorf("write source: ModTime = %v, want %v", info.ModTime, writeTime)
+	}
+	if !info.WriteTime.Equal(writeTime) || !info.ChangeTime.Equal(changeTime) {
+		t.Errorf("raw timestamps not recorded: write=%v change=%v", info.WriteTime, info.ChangeTime)
+	}
+
+	info = newRemoteFileInfo(stat, "dir/file.txt", MTimeSourceChange)
+	if !info.ModTime.Equal(changeTime) {
+		t.Errorf("change source: ModTime = %v, want %v", info.ModTime, changeTime)
+	}
+
+	if MTimeSource("bogus").normalize() != MTimeSourceWrite {
+		t.Error("unknown source should default to write time")
+	}
+}
+
 func TestSMBClient_DownloadNotConnected(t *testing.T) {
 	config := &ClientConfig{
 		Server:   "test-server",
@@ -0,0 +1,145 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// --- Dual-Stack Connect ---
+//
+// A NAS often has both an IPv6 (AAAA) and an IPv4 (A) address, and one of the
+// two paths may be broken (IPv6 routed on the LAN but filtered by the NAS, a
+// stale AAAA record...). Trying the addresses one after the other then hangs
+// for the full timeout of the broken one. Instead, connections race (Happy
+// Eyeballs, RFC 8305): the addresses are tried alternating families, each
+// attempt starting when the previous one fails or after connectAttemptDelay,
+// and the first connection established wins. The others are abandoned.
+
+const (
+	// connectAttemptDelay is the head start of each attempt over the next one
+	connectAttemptDelay = 250 * time.Millisecond
+	// connectTimeout bounds the whole connect, resolution included
+	connectTimeout = 30 * time.Second
+)
+
+// Resolution and dialing, replaced in tests
+var (
+	lookupIPAddr = net.DefaultResolver.LookupIPAddr
+	dialTCP      = (&net.Dialer{}).DialContext
+)
+
+// dialServer connects to an SMB server, racing its IPv6 and IPv4 addresses.
+func dialServer(ctx context.Context, host string, port int, logger *zap.Logger) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	var addrs []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IP{ip}
+	} else {
+		resolved, err := lookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		for _, a := range resolved {
+			addrs = append(addrs, a.IP)
+		}
+		addrs = interleaveFamilies(addrs)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+
+	type attempt struct {
+		addr string
+		conn net.Conn
+		err  error
+	}
+	results := make(chan attempt, len(addrs))
+	started, received := 0, 0
+	start := func() {
+		addr := net.JoinHostPort(addrs[started].String(), strconv.Itoa(port))
+		started++
+		go func() {
+			conn, err := dialTCP(ctx, "tcp", addr)
+			results <- attempt{addr: addr, conn: conn, err: err}
+		}()
+	}
+
+	// Connections established after the winner are closed
+	defer func() {
+		go func() {
+			for ; received < started; received++ {
+				if r := <-results; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}()
+	}()
+
+	var errs []error
+	start()
+	timer := time.NewTimer(connectAttemptDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-results:
+			received++
+			if r.err == nil {
+				if len(addrs) > 1 {
+					logger.Debug("connection race won",
+						zap.String("host", host),
+						zap.String("addr", r.addr),
+						zap.Int("attempts", started))
+				}
+				return r.conn, nil
+			}
+			errs = append(errs, r.err)
+			if started < len(addrs) {
+				start()
+				timer.Reset(connectAttemptDelay)
+			} else if received == started {
+				return nil, errors.Join(errs...)
+			}
+		case <-timer.C:
+			if started < len(addrs) {
+				start()
+				timer.Reset(connectAttemptDelay)
+			}
+		}
+	}
+}
+
+// interleaveFamilies alternates the address families, starting with the
+// family of the first address (the resolver sorts the preferred one first).
+func interleaveFamilies(addrs []net.IP) []net.IP {
+	if len(addrs) == 0 {
+		return addrs
+	}
+	var first, second []net.IP
+	firstIsV4 := addrs[0].To4() != nil
+	for _, ip := range addrs {
+		if (ip.To4() != nil) == firstIsV4 {
+			first = append(first, ip)
+		} else {
+			second = append(second, ip)
+		}
+	}
+
+	interleaved := make([]net.IP, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			interleaved = append(interleaved, first[i])
+		}
+		if i < len(second) {
+			interleaved = append(interleaved, second[i])
+		}
+	}
+	return interleaved
+}
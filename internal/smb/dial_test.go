@@ -0,0 +1,91 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestInterleaveFamilies(t *testing.T) {
+	addrs := []net.IP{
+		net.ParseIP("fd00::1"),
+		net.ParseIP("fd00::2"),
+		net.ParseIP("192.168.1.10"),
+	}
+	got := interleaveFamilies(addrs)
+	want := []net.IP{addrs[0], addrs[2], addrs[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("interleaveFamilies = %v, want %v", got, want)
+	}
+}
+
+// fakeNetwork replaces resolution and dialing: addresses in hang never
+// connect, those in fail are refused, the others connect.
+func fakeNetwork(t *testing.T, resolved []string, hang, fail map[string]bool) {
+	origLookup, origDial := lookupIPAddr, dialTCP
+	t.Cleanup(func() { lookupIPAddr, dialTCP = origLookup, origDial })
+
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		var addrs []net.IPAddr
+		for _, a := range resolved {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		return addrs, nil
+	}
+	dialTCP = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		switch {
+		case hang[host]:
+			<-ctx.Done()
+			return nil, ctx.Err()
+		case fail[host]:
+			return nil, errors.New("connection refused")
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+}
+
+func TestDialServer_BrokenFamily(t *testing.T) {
+	// IPv6 preferred but filtered: IPv4 wins without waiting for a timeout
+	fakeNetwork(t, []string{"fd00::1", "192.168.1.10"}, map[string]bool{"fd00::1": true}, nil)
+
+	startedAt := time.Now()
+	conn, err := dialServer(context.Background(), "nas.local", 445, zap.NewNop())
+	if err != nil {
+		t.Fatalf("dialServer: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(startedAt); elapsed > 5*connectAttemptDelay {
+		t.Errorf("connect took %v, expected the IPv4 attempt to win early", elapsed)
+	}
+}
+
+func TestDialServer_FailureStartsNextAttempt(t *testing.T) {
+	fakeNetwork(t, []string{"fd00::1", "192.168.1.10"}, nil, map[string]bool{"fd00::1": true})
+
+	startedAt := time.Now()
+	conn, err := dialServer(context.Background(), "nas.local", 445, zap.NewNop())
+	if err != nil {
+		t.Fatalf("dialServer: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(startedAt); elapsed >= connectAttemptDelay {
+		t.Errorf("connect took %v, a refused attempt should start the next one at once", elapsed)
+	}
+}
+
+func TestDialServer_AllFail(t *testing.T) {
+	fakeNetwork(t, []string{"fd00::1", "192.168.1.10"}, nil,
+		map[string]bool{"fd00::1": true, "192.168.1.10": true})
+
+	if _, err := dialServer(context.Background(), "nas.local", 445, zap.NewNop()); err == nil {
+		t.Fatal("expected an error when every address is refused")
+	}
+}
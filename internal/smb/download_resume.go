@@ -0,0 +1,183 @@
+package smb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
+)
+
+// --- Resumable Downloads ---
+//
+// Large downloads are written to a partial file next to the destination,
+// with the SHA-256 of every completed chunk kept in a sidecar file. When a
+// download is interrupted, the next attempt re-hashes the partial file,
+// keeps the chunks that still match and continues from there instead of
+// restarting from the first byte.
+
+const (
+	// ResumableDownloadMinSize is the size from which downloads can be resumed
+	ResumableDownloadMinSize = 16 * 1024 * 1024
+
+	// DownloadChunkSize is the size of the chunks hashed for resume
+	DownloadChunkSize = 4 * 1024 * 1024
+
+	// downloadChunksSaveInterval is the time between two writes of the chunk
+	// hashes: the whole sidecar is rewritten each time, so saving after every
+	// chunk is quadratic in the file size
+	downloadChunksSaveInterval = 2 * time.Second
+)
+
+// downloadChunks is the sidecar file of a partial download.
+type downloadChunks struct {
+	Size      int64    `json:"size"`       // Remote size when the download started
+	ModTime   int64    `json:"mtime"`      // Remote modification time (Unix nanoseconds)
+	ChunkSize int64    `json:"chunk_size"` // Size of the hashed chunks
+	Hashes    []string `json:"hashes"`     // SHA-256 of each completed chunk
+
+	savedAt time.Time // Last write of the sidecar file
+}
+
+// matches reports whether the partial download is of the same remote version.
+func (d *downloadChunks) matches(size int64, modTime time.Time, chunkSize int64) bool {
+	return d.Size == size && d.ModTime == modTime.UnixNano() && d.ChunkSize == chunkSize
+}
+
+// loadDownloadChunks reads the chunk hashes of a partial download (nil if missing or unreadable).
+func loadDownloadChunks(path string) *downloadChunks {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var chunks downloadChunks
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil
+	}
+	return &chunks
+}
+
+// save writes the chunk hashes of a partial download.
+func (d *downloadChunks) save(path string) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	d.savedAt = time.Now()
+	return nil
+}
+
+// saveThrottled writes the chunk hashes unless they were written less than
+// downloadChunksSaveInterval ago. After a crash, the chunks completed since
+// the last write are downloaded again.
+func (d *downloadChunks) saveThrottled(path string) error {
+	if time.Since(d.savedAt) < downloadChunksSaveInterval {
+		return nil
+	}
+	return d.save(path)
+}
+
+// downloadResumable copies remote (size bytes, modified at modTime) to
+// localPath through a partial file, resuming a previous partial download of
+// the same remote version. Returns the hex-encoded SHA-256 of the content
+// and the offset the download resumed from.
+func downloadResumable(remote io.ReadSeeker, size int64, modTime time.Time, localPath string, chunkSize int64) (string, int64, error) {
+	partialPath := localPath + partialfile.Suffix
+	chunksPath := partialPath + partialfile.ChunksSuffix
+
+	hasher := sha256.New()
+	chunks := loadDownloadChunks(chunksPath)
+	if chunks == nil || !chunks.matches(size, modTime, chunkSize) {
+		chunks = &downloadChunks{Size: size, ModTime: modTime.UnixNano(), ChunkSize: chunkSize}
+	} else {
+		chunks.Hashes = verifyPartialChunks(partialPath, chunks, hasher)
+	}
+	offset := int64(len(chunks.Hashes)) * chunkSize
+
+	localFile, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create local file %s: %w", partialPath, err)
+	}
+	defer localFile.Close()
+
+	// Drop bytes past the last verified chunk
+	if err := localFile.Truncate(offset); err != nil {
+		return "", 0, fmt.Errorf("failed to truncate partial file: %w", err)
+	}
+	if _, err := localFile.Seek(offset, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("failed to seek partial file: %w", err)
+	}
+	if _, err := remote.Seek(offset, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("failed to seek remote file: %w", err)
+	}
+	if err := chunks.save(chunksPath); err != nil {
+		return "", 0, fmt.Errorf("failed to save chunk hashes: %w", err)
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(remote, buf)
+		if n > 0 {
+			if _, err := localFile.Write(buf[:n]); err != nil {
+				return "", offset, fmt.Errorf("failed to write data: %w", err)
+			}
+			hasher.Write(buf[:n])
+			chunkHash := sha256.Sum256(buf[:n])
+			chunks.Hashes = append(chunks.Hashes, hex.EncodeToString(chunkHash[:]))
+			if err := chunks.saveThrottled(chunksPath); err != nil {
+				return "", offset, fmt.Errorf("failed to save chunk hashes: %w", err)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			// Keep the partial file: the next attempt resumes from the completed chunks
+			chunks.save(chunksPath)
+			return "", offset, fmt.Errorf("failed to copy data: %w", readErr)
+		}
+	}
+
+	if err := localFile.Close(); err != nil {
+		return "", offset, fmt.Errorf("failed to close partial file: %w", err)
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return "", offset, fmt.Errorf("failed to rename partial file to %s: %w", localPath, err)
+	}
+	os.Remove(chunksPath)
+
+	return hex.EncodeToString(hasher.Sum(nil)), offset, nil
+}
+
+// verifyPartialChunks re-hashes the chunks of a partial download and returns
+// the hashes of the leading chunks that still match, feeding their bytes to hasher.
+func verifyPartialChunks(partialPath string, chunks *downloadChunks, hasher hash.Hash) []string {
+	f, err := os.Open(partialPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	buf := make([]byte, chunks.ChunkSize)
+	for i, expected := range chunks.Hashes {
+		if _, err := io.ReadFull(f, buf); err != nil {
+			return chunks.Hashes[:i]
+		}
+		sum := sha256.Sum256(buf)
+		want, err := hex.DecodeString(expected)
+		if err != nil || !bytes.Equal(sum[:], want) {
+			return chunks.Hashes[:i]
+		}
+		hasher.Write(buf)
+	}
+	return chunks.Hashes
+}
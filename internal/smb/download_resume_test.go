@@ -0,0 +1,152 @@
+package smb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
+)
+
+// failingReadSeeker fails reads once failAt bytes have been read.
+type failingReadSeeker struct {
+	*bytes.Reader
+	failAt int64
+}
+
+func (f *failingReadSeeker) Read(p []byte) (int, error) {
+	pos := f.Size() - int64(f.Len())
+	if pos >= f.failAt {
+		return 0, errors.New("connection reset")
+	}
+	if remaining := f.failAt - pos; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	return f.Reader.Read(p)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadResumable(t *testing.T) {
+	content := bytes.Repeat([]byte("anemone-"), 1000) // 8000 bytes
+	modTime := time.Now()
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	const chunkSize = 1024
+
+	// Interrupted after 3.5 chunks: 3 chunks are kept
+	remote := &failingReadSeeker{Reader: bytes.NewReader(content), failAt: 3*chunkSize + 512}
+	if _, _, err := downloadResumable(remote, int64(len(content)), modTime, localPath, chunkSize); err == nil {
+		t.Fatal("Expected interrupted download to fail")
+	}
+	if _, err := os.Stat(localPath + partialfile.Suffix); err != nil {
+		t.Fatalf("Partial file should be kept: %v", err)
+	}
+
+	hash, resumedFrom, err := downloadResumable(bytes.NewReader(content), int64(len(content)), modTime, localPath, chunkSize)
+	if err != nil {
+		t.Fatalf("Resumed download failed: %v", err)
+	}
+	if resumedFrom != 3*chunkSize {
+		t.Errorf("Expected resume from %d, got %d", 3*chunkSize, resumedFrom)
+	}
+	if hash != sha256Hex(content) {
+		t.Error("Hash should cover the whole content")
+	}
+
+	got, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("Downloaded content differs from remote")
+	}
+	for _, leftover := range []string{localPath + partialfile.Suffix, localPath + partialfile.Suffix + partialfile.ChunksSuffix} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after completion", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestDownloadResumable_CorruptChunk(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 256) // 4096 bytes
+	modTime := time.Now()
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	const chunkSize = 1024
+
+	remote := &failingReadSeeker{Reader: bytes.NewReader(content), failAt: 3 * chunkSize}
+	downloadResumable(remote, int64(len(content)), modTime, localPath, chunkSize)
+
+	// Damage the second chunk: only the first one can be kept
+	f, err := os.OpenFile(localPath+partialfile.Suffix, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Failed to open partial file: %v", err)
+	}
+	f.WriteAt([]byte("XXXX"), chunkSize+10)
+	f.Close()
+
+	_, resumedFrom, err := downloadResumable(bytes.NewReader(content), int64(len(content)), modTime, localPath, chunkSize)
+	if err != nil {
+		t.Fatalf("Resumed download failed: %v", err)
+	}
+	if resumedFrom != chunkSize {
+		t.Errorf("Expected resume from %d, got %d", chunkSize, resumedFrom)
+	}
+	if got, _ := os.ReadFile(localPath); !bytes.Equal(got, content) {
+		t.Error("Damaged chunk should be downloaded again")
+	}
+}
+
+func TestDownloadResumable_RemoteChanged(t *testing.T) {
+	content := bytes.Repeat([]byte("v1"), 2048)
+	localPath := filepath.Join(t.TempDir(), "big.bin")
+	const chunkSize = 1024
+
+	remote := &failingReadSeeker{Reader: bytes.NewReader(content), failAt: 2 * chunkSize}
+	downloadResumable(remote, int64(len(content)), time.Unix(1000, 0), localPath, chunkSize)
+
+	// A new remote version restarts from the beginning
+	changed := bytes.Repeat([]byte("v2"), 2048)
+	_, resumedFrom, err := downloadResumable(bytes.NewReader(changed), int64(len(changed)), time.Unix(2000, 0), localPath, chunkSize)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if resumedFrom != 0 {
+		t.Errorf("Expected restart from 0 for a changed remote file, got %d", resumedFrom)
+	}
+	if got, _ := os.ReadFile(localPath); !bytes.Equal(got, changed) {
+		t.Error("Downloaded content should be the new version")
+	}
+}
+
+func TestDownloadChunks_SaveThrottled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin"+partialfile.Suffix+partialfile.ChunksSuffix)
+	chunks := &downloadChunks{Size: 2048, ChunkSize: 1024, Hashes: []string{"a"}}
+	if err := chunks.saveThrottled(path); err != nil {
+		t.Fatalf("First save failed: %v", err)
+	}
+
+	// Written a moment ago: the next chunk is not saved yet
+	chunks.Hashes = append(chunks.Hashes, "b")
+	if err := chunks.saveThrottled(path); err != nil {
+		t.Fatalf("Throttled save failed: %v", err)
+	}
+	if got := loadDownloadChunks(path); got == nil || len(got.Hashes) != 1 {
+		t.Errorf("Expected the sidecar to keep 1 hash until the interval elapses, got %+v", got)
+	}
+
+	chunks.savedAt = time.Now().Add(-downloadChunksSaveInterval)
+	if err := chunks.saveThrottled(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got := loadDownloadChunks(path); got == nil || len(got.Hashes) != 2 {
+		t.Errorf("Expected 2 hashes once the interval elapsed, got %+v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package smb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// --- Change Notifications ---
+//
+// A client may ask the server to report the changes under a folder of a
+// share (SMB2 CHANGE_NOTIFY, MS-SMB2 2.2.35). The request stays pending until
+// something changes, so a watch is one long-lived request, sent again after
+// each answer. Like the Previous Versions, it goes through the Windows SMB
+// redirector (see notify_windows.go): ReadDirectoryChangesW on a UNC path
+// sends CHANGE_NOTIFY with SMB2_WATCH_TREE.
+
+// ChangeAction is what happened to a remote path (FILE_ACTION_*, MS-FSCC 2.7.1).
+type ChangeAction uint32
+
+const (
+	ChangeAdded      ChangeAction = 1
+	ChangeRemoved    ChangeAction = 2
+	ChangeModified   ChangeAction = 3
+	ChangeRenamedOld ChangeAction = 4 // Old name of a renamed file
+	ChangeRenamedNew ChangeAction = 5 // New name of a renamed file
+)
+
+// String returns the action as written in logs.
+func (a ChangeAction) String() string {
+	switch a {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	case ChangeRenamedOld:
+		return "renamed from"
+	case ChangeRenamedNew:
+		return "renamed to"
+	default:
+		return fmt.Sprintf("action %d", uint32(a))
+	}
+}
+
+// RemoteChange is a change reported under a watched folder.
+type RemoteChange struct {
+	Path   string // Relative to the watched folder, "/"-separated
+	Action ChangeAction
+}
+
+// ErrChangesLost is reported when the server dropped changes (too many at
+// once, STATUS_NOTIFY_ENUM_DIR): the watched folder must be scanned again.
+var ErrChangesLost = errors.New("remote changes were lost, the folder must be scanned again")
+
+// notifyBufferSize is the size of the answers asked for: servers refuse more
+// than 64 KiB.
+const notifyBufferSize = 64 << 10
+
+// notifyFilter is the changes watched (FILE_NOTIFY_CHANGE_*): names of files
+// and folders, attributes, sizes and last writes.
+const notifyFilter = 0x01 | 0x02 | 0x04 | 0x08 | 0x10
+
+// RemoteWatcher reports the changes under a remote folder until it is closed
+// (see WatchRemote).
+type RemoteWatcher struct {
+	Changes <-chan []RemoteChange // Changes of each answer of the server
+	Errors  <-chan error          // ErrChangesLost, or why the watch ended (Changes is then closed)
+
+	once  sync.Once
+	close func()
+}
+
+// Close ends the watch. The channels are closed once the pending request is
+// cancelled.
+func (w *RemoteWatcher) Close() {
+	w.once.Do(w.close)
+}
+
+// decodeNotifyInformation decodes a chain of FILE_NOTIFY_INFORMATION
+// (MS-FSCC 2.7.1).
+func decodeNotifyInformation(data []byte) ([]RemoteChange, error) {
+	var changes []RemoteChange
+	for len(data) > 0 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("truncated change notification")
+		}
+		next := binary.LittleEndian.Uint32(data)
+		action := binary.LittleEndian.Uint32(data[4:])
+		nameLen := binary.LittleEndian.Uint32(data[8:])
+		if nameLen%2 != 0 || 12+int(nameLen) > len(data) || (next != 0 && (next < 12+nameLen || int(next) >= len(data))) {
+			return nil, fmt.Errorf("malformed change notification")
+		}
+		name := make([]uint16, nameLen/2)
+		for i := range name {
+			name[i] = binary.LittleEndian.Uint16(data[12+2*i:])
+		}
+		changes = append(changes, RemoteChange{
+			Path:   strings.ReplaceAll(string(utf16.Decode(name)), `\`, "/"),
+			Action: ChangeAction(action),
+		})
+		if next == 0 {
+			break
+		}
+		data = data[next:]
+	}
+	return changes, nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package smb
+
+import (
+	"errors"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+// WatchRemote reports the changes under a remote folder. Changes are read
+// through the Windows SMB redirector: other platforms don't support it.
+func WatchRemote(remote smbpath.UNCPath) (*RemoteWatcher, error) {
+	return nil, errors.New("remote change notifications are only available on Windows")
+}
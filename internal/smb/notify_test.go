@@ -0,0 +1,57 @@
+package smb
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// notifyEntry encodes a FILE_NOTIFY_INFORMATION, padded to 4 bytes unless last.
+func notifyEntry(action ChangeAction, name string, last bool) []byte {
+	encoded := utf16.Encode([]rune(name))
+	size := 12 + 2*len(encoded)
+	if !last {
+		size = (size + 3) &^ 3
+	}
+	b := make([]byte, size)
+	if !last {
+		binary.LittleEndian.PutUint32(b, uint32(size))
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(action))
+	binary.LittleEndian.PutUint32(b[8:], uint32(2*len(encoded)))
+	for i, c := range encoded {
+		binary.LittleEndian.PutUint16(b[12+2*i:], c)
+	}
+	return b
+}
+
+func TestDecodeNotifyInformation(t *testing.T) {
+	var data []byte
+	data = append(data, notifyEntry(ChangeModified, `Projets\rapport été.docx`, false)...)
+	data = append(data, notifyEntry(ChangeRenamedOld, `a.txt`, false)...)
+	data = append(data, notifyEntry(ChangeRenamedNew, `b.txt`, true)...)
+
+	got, err := decodeNotifyInformation(data)
+	if err != nil {
+		t.Fatalf("decodeNotifyInformation: %v", err)
+	}
+	want := []RemoteChange{
+		{Path: "Projets/rapport été.docx", Action: ChangeModified},
+		{Path: "a.txt", Action: ChangeRenamedOld},
+		{Path: "b.txt", Action: ChangeRenamedNew},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range [][]byte{
+		data[:8],                   // Truncated header
+		data[:len(data)-2],         // Truncated name of the last entry
+		notifyEntry(1, "x", false), // Next entry past the end
+	} {
+		if _, err := decodeNotifyInformation(bad); err == nil {
+			t.Errorf("decodeNotifyInformation(%x): expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+//go:build windows
+
+package smb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"golang.org/x/sys/windows"
+)
+
+// remoteWatch is the state of a watch, on the heap: the pending request
+// writes to buf and ov until it ends.
+type remoteWatch struct {
+	remote smbpath.UNCPath
+	dir    windows.Handle
+	event  windows.Handle // Signaled when the request ends
+	stop   windows.Handle // Signaled by Close
+	quit   chan struct{}  // Closed by Close
+	buf    []byte
+	ov     windows.Overlapped
+
+	changes chan []RemoteChange
+	errors  chan error
+}
+
+// WatchRemote reports the changes under a remote folder and its subfolders
+// until the watcher is closed or the connection is lost. The share is read
+// through the Windows SMB redirector, with the session of the signed-in
+// Windows user rather than the credentials of a client.
+func WatchRemote(remote smbpath.UNCPath) (*RemoteWatcher, error) {
+	path, err := windows.UTF16PtrFromString(remote.String())
+	if err != nil {
+		return nil, err
+	}
+	dir, err := windows.CreateFile(path, windows.FILE_LIST_DIRECTORY,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OVERLAPPED, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", remote, err)
+	}
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(dir)
+		return nil, err
+	}
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(event)
+		windows.CloseHandle(dir)
+		return nil, err
+	}
+
+	rw := &remoteWatch{
+		remote:  remote,
+		dir:     dir,
+		event:   event,
+		stop:    stop,
+		quit:    make(chan struct{}),
+		buf:     make([]byte, notifyBufferSize),
+		changes: make(chan []RemoteChange, 16),
+		errors:  make(chan error, 1),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rw.loop()
+	}()
+
+	return &RemoteWatcher{
+		Changes: rw.changes,
+		Errors:  rw.errors,
+		close: func() {
+			close(rw.quit)
+			windows.SetEvent(rw.stop)
+			<-done
+		},
+	}, nil
+}
+
+// loop sends a change notification request after each answer.
+func (rw *remoteWatch) loop() {
+	defer func() {
+		windows.CloseHandle(rw.dir)
+		windows.CloseHandle(rw.event)
+		windows.CloseHandle(rw.stop)
+		close(rw.changes)
+		close(rw.errors)
+	}()
+
+	for {
+		n, err := rw.read()
+		switch {
+		case err == errWatchStopped:
+			return
+		case err == windows.ERROR_NOTIFY_ENUM_DIR, err == nil && n == 0:
+			if !rw.report(ErrChangesLost) {
+				return
+			}
+			continue
+		case err != nil:
+			rw.report(fmt.Errorf("watch of %s ended: %w", rw.remote, err))
+			return
+		}
+
+		changes, err := decodeNotifyInformation(rw.buf[:n])
+		if err != nil {
+			if !rw.report(ErrChangesLost) {
+				return
+			}
+			continue
+		}
+		select {
+		case rw.changes <- changes:
+		case <-rw.quit:
+			return
+		}
+	}
+}
+
+// errWatchStopped ends the loop once Close cancelled the pending request.
+var errWatchStopped = errors.New("watch stopped")
+
+// read sends one request and waits for its answer, or for Close.
+func (rw *remoteWatch) read() (uint32, error) {
+	rw.ov = windows.Overlapped{HEvent: rw.event}
+	if err := windows.ResetEvent(rw.event); err != nil {
+		return 0, err
+	}
+	err := windows.ReadDirectoryChanges(rw.dir, &rw.buf[0], uint32(len(rw.buf)), true, notifyFilter, nil, &rw.ov, 0)
+	if err != nil && err != windows.ERROR_IO_PENDING {
+		return 0, err
+	}
+
+	var n uint32
+	fired, err := windows.WaitForMultipleObjects([]windows.Handle{rw.event, rw.stop}, false, windows.INFINITE)
+	if err != nil || fired != windows.WAIT_OBJECT_0 {
+		// The buffer stays in use until the cancelled request ends
+		windows.CancelIoEx(rw.dir, &rw.ov)
+		windows.GetOverlappedResult(rw.dir, &rw.ov, &n, true)
+		return 0, errWatchStopped
+	}
+	if err := windows.GetOverlappedResult(rw.dir, &rw.ov, &n, false); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// report sends an error unless Close was called.
+func (rw *remoteWatch) report(err error) bool {
+	select {
+	case rw.errors <- err:
+		return true
+	case <-rw.quit:
+		return false
+	}
+}
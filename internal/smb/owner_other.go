@@ -0,0 +1,14 @@
+//go:build !windows
+
+package smb
+
+import "errors"
+
+// errOwnerUnsupported is returned by Owner outside Windows.
+var errOwnerUnsupported = errors.New("remote file owners are only available on Windows")
+
+// Owner returns the owner of a remote file. Security descriptors are read
+// through the Windows SMB redirector: other platforms don't support it.
+func (c *SMBClient) Owner(remotePath string) (string, error) {
+	return "", errOwnerUnsupported
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package smb
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// Owner returns the owner of a remote file as DOMAIN\user (its SID if the
+// account can't be resolved). The security descriptor is read through the
+// Windows SMB redirector, with the session of the signed-in Windows user
+// rather than the credentials of the client.
+func (c *SMBClient) Owner(remotePath string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(c.RedirectorPath(remotePath), windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "", fmt.Errorf("failed to query owner of %s: %w", remotePath, err)
+	}
+	owner, _, err := sd.Owner()
+	if err != nil || owner == nil {
+		return "", fmt.Errorf("no owner for %s: %v", remotePath, err)
+	}
+
+	account, domain, _, err := owner.LookupAccount(c.server)
+	if err != nil {
+		return owner.String(), nil
+	}
+	if domain == "" {
+		return account, nil
+	}
+	return domain + `\` + account, nil
+}
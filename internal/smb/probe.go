@@ -0,0 +1,111 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"go.uber.org/zap"
+)
+
+// ErrEncryptionRequired refuses a connection to a server configured to
+// require encryption that didn't negotiate it: nothing is transferred in
+// cleartext.
+var ErrEncryptionRequired = errors.New("the server did not negotiate SMB3 encryption, which is required for it")
+
+// Stages of a connection test, in order
+const (
+	ProbeConnect  = "Connect"
+	ProbeSignIn   = "Sign in"
+	ProbeSecurity = "Security"
+	ProbeShares   = "Shares"
+)
+
+// ProbeStage is a step of a connection test.
+type ProbeStage struct {
+	Name   string // ProbeConnect, ProbeSignIn...
+	Detail string // What the step found
+	Err    error  // Why the step failed (nil = passed)
+}
+
+// ProbeResult is the outcome of a connection test.
+type ProbeResult struct {
+	Stages   []ProbeStage  // Steps run, the last one failed if the test did
+	Security SecurityLevel // Negotiated after signing in ("" if not reached)
+	Shares   []string      // Shares found
+}
+
+// ProbeServer tests a server step by step: connect, sign in, check the
+// signing and encryption negotiated (failing if requireEncryption and the
+// session isn't encrypted), then list the shares. Returns the stages run and
+// the error of the one that failed.
+func ProbeServer(server string, port int, username, password, domain string,
+	requireEncryption bool, logger *zap.Logger) (*ProbeResult, error) {
+
+	if server == "" {
+		return nil, fmt.Errorf("server cannot be empty")
+	}
+	if port == 0 {
+		port = smbpath.DefaultPort
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	result := &ProbeResult{}
+	fail := func(stage string, err error) (*ProbeResult, error) {
+		result.Stages = append(result.Stages, ProbeStage{Name: stage, Err: err})
+		logger.Debug("connection test failed",
+			zap.String("server", server),
+			zap.String("stage", stage),
+			zap.Error(err))
+		return result, err
+	}
+	pass := func(stage, detail string) {
+		result.Stages = append(result.Stages, ProbeStage{Name: stage, Detail: detail})
+	}
+
+	addr := net.JoinHostPort(server, strconv.Itoa(port))
+	conn, err := dialServer(context.Background(), server, port, logger)
+	if err != nil {
+		return fail(ProbeConnect, fmt.Errorf("failed to connect to %s: %w", addr, err))
+	}
+	defer conn.Close()
+	pass(ProbeConnect, "Connected to "+conn.RemoteAddr().String())
+
+	observer := newSecurityObserver(conn)
+	dialer := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     username,
+			Password: password,
+			Domain:   domain,
+		},
+	}
+	session, err := dialer.Dial(observer)
+	if err != nil {
+		return fail(ProbeSignIn, fmt.Errorf("failed to create SMB session: %w", err))
+	}
+	defer session.Logoff()
+	pass(ProbeSignIn, "Signed in as "+username)
+
+	// Shares encrypting their own traffic are only known once mounted: the
+	// sync checks them again
+	result.Security = observer.level()
+	if requireEncryption && result.Security != SecurityEncrypted {
+		return fail(ProbeSecurity, fmt.Errorf("%w (negotiated: %s)", ErrEncryptionRequired, result.Security))
+	}
+	pass(ProbeSecurity, "Messages "+string(result.Security))
+
+	shares, err := session.ListSharenames()
+	if err != nil {
+		return fail(ProbeShares, fmt.Errorf("failed to list shares: %w", err))
+	}
+	result.Shares = shares
+	pass(ProbeShares, fmt.Sprintf("%d shares found", len(shares)))
+
+	return result, nil
+}
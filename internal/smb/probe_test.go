@@ -0,0 +1,46 @@
+package smb
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestProbeServer_Stages(t *testing.T) {
+	tests := []struct {
+		name   string
+		fail   map[string]bool
+		passed []string // Stages passed before the failing one
+		failed string
+	}{
+		{name: "unreachable", fail: map[string]bool{"192.168.1.10": true}, failed: ProbeConnect},
+		{name: "not an SMB server", passed: []string{ProbeConnect}, failed: ProbeSignIn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Connections that do connect are closed at once by the peer
+			fakeNetwork(t, []string{"192.168.1.10"}, nil, tt.fail)
+
+			result, err := ProbeServer("nas.local", 445, "user", "secret", "", true, zap.NewNop())
+			if err == nil {
+				t.Fatal("ProbeServer succeeded, want an error")
+			}
+			if len(result.Stages) != len(tt.passed)+1 {
+				t.Fatalf("got %d stages, want %d", len(result.Stages), len(tt.passed)+1)
+			}
+			for i, name := range tt.passed {
+				if stage := result.Stages[i]; stage.Name != name || stage.Err != nil {
+					t.Errorf("stage %d = %s (%v), want %s passed", i, stage.Name, stage.Err, name)
+				}
+			}
+			last := result.Stages[len(result.Stages)-1]
+			if last.Name != tt.failed || last.Err != err {
+				t.Errorf("last stage = %s (%v), want %s failed with %v", last.Name, last.Err, tt.failed, err)
+			}
+			if result.Security != "" {
+				t.Errorf("Security = %q, want none before signing in", result.Security)
+			}
+		})
+	}
+}
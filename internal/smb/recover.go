@@ -0,0 +1,175 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// --- Session Recovery ---
+//
+// When the server reboots or drops the session mid-run, every operation on
+// the old session fails. The operation that notices passes the generation of
+// the session it ran on to Recover, which reconnects once for all of them,
+// waiting for the server to come back.
+
+// Waiting for the server to come back after losing the session
+const (
+	recoverTimeout      = 5 * time.Minute
+	recoverInitialDelay = 2 * time.Second
+	recoverMaxDelay     = 30 * time.Second
+)
+
+// NTSTATUS codes of a session or share the server no longer knows
+const (
+	ntStatusInvalidHandle          = 0xC0000008
+	ntStatusFileClosed             = 0xC0000128
+	ntStatusNetworkNameDeleted     = 0xC00000C9
+	ntStatusUserSessionDeleted     = 0xC0000203
+	ntStatusConnectionDisconnected = 0xC000020C
+	ntStatusConnectionReset        = 0xC000020D
+	ntStatusNetworkSessionExpired  = 0xC000035C
+)
+
+// IsStaleSession reports whether an error means the session or the share
+// was lost (server rebooted, connection dropped): the operation can run
+// again once reconnected.
+func IsStaleSession(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var respErr *smb2.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.Code {
+		case ntStatusInvalidHandle, ntStatusFileClosed, ntStatusNetworkNameDeleted,
+			ntStatusUserSessionDeleted, ntStatusConnectionDisconnected,
+			ntStatusConnectionReset, ntStatusNetworkSessionExpired:
+			return true
+		}
+		return false
+	}
+
+	var transportErr *smb2.TransportError
+	if errors.As(err, &transportErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNABORTED) {
+		return true
+	}
+
+	// Operations started while another one is reconnecting
+	return isClosedConnectionError(err) || strings.Contains(err.Error(), "not connected to SMB server")
+}
+
+// Generation identifies the current session: it changes on every
+// (re)connection.
+func (c *SMBClient) Generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// Recover re-establishes the session after an operation failed with a stale
+// session error on session generation gen. It retries connecting until the
+// server is back, for up to recoverTimeout. Operations failing on the same
+// session share the attempt: it returns at once if another already
+// reconnected, or with the error of the attempt that gave up.
+func (c *SMBClient) Recover(ctx context.Context, gen uint64) error {
+	c.recoverMu.Lock()
+	defer c.recoverMu.Unlock()
+
+	c.mu.Lock()
+	if c.generation != gen {
+		c.mu.Unlock()
+		return nil // Reconnected by another operation
+	}
+	if c.recoverErr != nil {
+		err := c.recoverErr
+		c.mu.Unlock()
+		return err
+	}
+	c.dropLocked()
+	c.mu.Unlock()
+
+	c.logger.Warn("SMB session lost, reconnecting",
+		zap.String("server", c.server),
+		zap.String("share", c.share))
+
+	deadline := time.Now().Add(recoverTimeout)
+	delay := recoverInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := c.Connect()
+		if err == nil {
+			metrics.SMBReconnects.Inc()
+			c.logger.Info("SMB session recovered",
+				zap.String("server", c.server),
+				zap.Int("attempts", attempt))
+			return nil
+		}
+		if !IsStaleSession(err) && !isUnreachable(err) {
+			return c.giveUp(gen, err) // Refused: credentials, security...
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return c.giveUp(gen, fmt.Errorf("server still unreachable after %s: %w", recoverTimeout, err))
+		}
+
+		c.logger.Debug("server not back yet",
+			zap.String("server", c.server),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err() // Not recorded: a later run may recover
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, recoverMaxDelay)
+	}
+}
+
+// giveUp records why the session of generation gen could not be recovered,
+// so the operations still failing on it don't wait again.
+func (c *SMBClient) giveUp(gen uint64, err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == gen {
+		c.recoverErr = err
+	}
+	c.logger.Error("failed to recover SMB session",
+		zap.String("server", c.server),
+		zap.Error(err))
+	return err
+}
+
+// dropLocked forgets a lost session without talking to the server (it may
+// not answer): closing the connection fails the operations still waiting on
+// it. The caller holds c.mu.
+func (c *SMBClient) dropLocked() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.fs = nil
+	c.session = nil
+	c.dialer = nil
+	c.connected = false
+}
+
+// isUnreachable reports whether connecting failed because the server is
+// not reachable yet (rebooting).
+func isUnreachable(err error) bool {
+	var netErr net.Error
+	var opErr *net.OpError
+	return errors.As(err, &netErr) || errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH)
+}
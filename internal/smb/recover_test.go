@@ -0,0 +1,56 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/hirochachacha/go-smb2"
+	"go.uber.org/zap"
+)
+
+func TestIsStaleSession(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"session deleted", fmt.Errorf("upload: %w", &smb2.ResponseError{Code: ntStatusUserSessionDeleted}), true},
+		{"share deleted", &smb2.ResponseError{Code: ntStatusNetworkNameDeleted}, true},
+		{"access denied", &smb2.ResponseError{Code: 0xC0000022}, false},
+		{"transport", &smb2.TransportError{Err: io.EOF}, true},
+		{"connection closed", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"reconnecting", errors.New("not connected to SMB server"), true},
+		{"local", errors.New("open C:\\a.txt: permission denied"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStaleSession(tt.err); got != tt.want {
+				t.Errorf("IsStaleSession(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecover_SharedAndRecorded(t *testing.T) {
+	client, err := NewSMBClient(&ClientConfig{Server: "nas.local", Share: "docs", Username: "user"}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.generation = 2
+
+	// Another operation already reconnected: nothing to do
+	if err := client.Recover(context.Background(), 1); err != nil {
+		t.Errorf("Recover of an old generation = %v, want nil", err)
+	}
+
+	// A session that could not be recovered is not waited for again
+	gaveUp := errors.New("server still unreachable")
+	client.giveUp(2, gaveUp)
+	if err := client.Recover(context.Background(), 2); !errors.Is(err, gaveUp) {
+		t.Errorf("Recover = %v, want the recorded error", err)
+	}
+}
@@ -0,0 +1,221 @@
+package smb
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// --- Negotiated Security ---
+//
+// go-smb2 negotiates signing and encryption with the server but doesn't say
+// what was agreed. The client reads it from the responses it receives: the
+// session and share flags asking for encryption, encrypted (transform)
+// messages, and the signature flag of the other messages.
+//
+// A server that used to sign or encrypt and suddenly doesn't may be
+// misconfigured, or a man in the middle may be downgrading the connection:
+// the best level seen per server is recorded and each new connection is
+// checked against it (see SetSecurityCheck).
+
+// SecurityLevel is the protection negotiated for the messages of a connection.
+type SecurityLevel string
+
+const (
+	SecurityNone      SecurityLevel = "none"      // Neither signed nor encrypted
+	SecuritySigned    SecurityLevel = "signed"    // Signed: tampering is detected
+	SecurityEncrypted SecurityLevel = "encrypted" // Encrypted (SMB 3)
+)
+
+// rank orders the levels ("" = unknown, below none).
+func (l SecurityLevel) rank() int {
+	switch l {
+	case SecurityNone:
+		return 1
+	case SecuritySigned:
+		return 2
+	case SecurityEncrypted:
+		return 3
+	}
+	return 0
+}
+
+// Below reports whether the level protects less than other (an unknown level
+// is below every known one).
+func (l SecurityLevel) Below(other SecurityLevel) bool {
+	return l.rank() < other.rank()
+}
+
+// Label describes the level for the user.
+func (l SecurityLevel) Label() string {
+	switch l {
+	case SecurityEncrypted:
+		return "encrypted"
+	case SecuritySigned:
+		return "signed (not encrypted)"
+	case SecurityNone:
+		return "unsigned, unencrypted"
+	}
+	return "unknown"
+}
+
+// SecurityCheck is called after each connection with the negotiated level of
+// the server (host as configured). An error refuses the connection.
+type SecurityCheck func(server string, level SecurityLevel) error
+
+var (
+	securityCheckMu sync.RWMutex
+	securityCheck   SecurityCheck
+)
+
+// SetSecurityCheck sets the check of the negotiated security of every
+// connection of the process (nil = none).
+func SetSecurityCheck(check SecurityCheck) {
+	securityCheckMu.Lock()
+	defer securityCheckMu.Unlock()
+	securityCheck = check
+}
+
+// checkSecurity runs the security check, if any.
+func checkSecurity(server string, level SecurityLevel) error {
+	securityCheckMu.RLock()
+	check := securityCheck
+	securityCheckMu.RUnlock()
+	if check == nil {
+		return nil
+	}
+	return check(server, level)
+}
+
+// SMB2 wire values read by the observer (MS-SMB2 2.2)
+const (
+	smb2HeaderSize         = 64
+	smb2FlagResponse       = 0x1
+	smb2FlagSigned         = 0x8
+	smb2SessionSetup       = 0x1
+	smb2TreeConnect        = 0x3
+	smb2SessionEncryptData = 0x4
+	smb2ShareEncryptData   = 0x8000
+
+	// observedFrameBytes is the start of each frame the observer reads: the
+	// header and the flags of session setup and tree connect responses
+	observedFrameBytes = smb2HeaderSize + 8
+)
+
+// securityObserver reads the negotiated security from the responses received
+// on a connection, without changing them.
+type securityObserver struct {
+	net.Conn
+
+	mu      sync.Mutex
+	prefix  []byte // NetBIOS length of the current frame, while incomplete
+	head    []byte // Start of the current frame
+	want    int    // Bytes of the frame start to read into head
+	skip    int    // Bytes of the current frame left after head
+	authed  bool   // Session established
+	encrypt bool   // Session or share encrypted, or encrypted messages seen
+	signed  bool   // Last message of the established session was signed
+}
+
+// newSecurityObserver wraps a connection to an SMB server.
+func newSecurityObserver(conn net.Conn) *securityObserver {
+	return &securityObserver{Conn: conn}
+}
+
+// Read reads from the connection, observing the frames read.
+func (o *securityObserver) Read(p []byte) (int, error) {
+	n, err := o.Conn.Read(p)
+	if n > 0 {
+		o.mu.Lock()
+		o.observe(p[:n])
+		o.mu.Unlock()
+	}
+	return n, err
+}
+
+// observe splits the bytes read into frames (a 4-byte length, then the
+// message) and inspects the start of each.
+func (o *securityObserver) observe(data []byte) {
+	for len(data) > 0 {
+		switch {
+		case o.want > 0: // Start of the frame
+			n := min(o.want-len(o.head), len(data))
+			o.head = append(o.head, data[:n]...)
+			data = data[n:]
+			if len(o.head) == o.want {
+				o.inspect(o.head)
+				o.head = o.head[:0]
+				o.want = 0
+			}
+
+		case o.skip > 0: // Rest of the frame
+			n := min(o.skip, len(data))
+			o.skip -= n
+			data = data[n:]
+
+		default: // Length of the next frame
+			n := min(4-len(o.prefix), len(data))
+			o.prefix = append(o.prefix, data[:n]...)
+			data = data[n:]
+			if len(o.prefix) == 4 {
+				size := int(binary.BigEndian.Uint32(o.prefix) & 0xFFFFFF)
+				o.prefix = o.prefix[:0]
+				o.want = min(size, observedFrameBytes)
+				o.skip = size - o.want
+			}
+		}
+	}
+}
+
+// inspect reads the security of a message from its start.
+func (o *securityObserver) inspect(msg []byte) {
+	if len(msg) < 4 {
+		return
+	}
+	if msg[0] == 0xFD && string(msg[1:4]) == "SMB" {
+		o.encrypt = true // Transform header: encrypted message
+		return
+	}
+	if msg[0] != 0xFE || string(msg[1:4]) != "SMB" || len(msg) < smb2HeaderSize {
+		return
+	}
+
+	status := binary.LittleEndian.Uint32(msg[8:12])
+	command := binary.LittleEndian.Uint16(msg[12:14])
+	flags := binary.LittleEndian.Uint32(msg[16:20])
+	if flags&smb2FlagResponse == 0 {
+		return
+	}
+
+	switch command {
+	case smb2SessionSetup:
+		if status != 0 || len(msg) < smb2HeaderSize+4 {
+			return // More processing required: not established yet
+		}
+		o.authed = true
+		if binary.LittleEndian.Uint16(msg[smb2HeaderSize+2:])&smb2SessionEncryptData != 0 {
+			o.encrypt = true
+		}
+	case smb2TreeConnect:
+		if status == 0 && len(msg) >= smb2HeaderSize+8 &&
+			binary.LittleEndian.Uint32(msg[smb2HeaderSize+4:])&smb2ShareEncryptData != 0 {
+			o.encrypt = true
+		}
+	}
+	if o.authed {
+		o.signed = flags&smb2FlagSigned != 0
+	}
+}
+
+// level returns the security negotiated so far.
+func (o *securityObserver) level() SecurityLevel {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case o.encrypt:
+		return SecurityEncrypted
+	case o.signed:
+		return SecuritySigned
+	}
+	return SecurityNone
+}
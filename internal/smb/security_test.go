@@ -0,0 +1,140 @@
+package smb
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// smb2Response returns a framed SMB2 response (NetBIOS length + header +
+// body) for the observer.
+func smb2Response(command uint16, status, flags uint32, body []byte) []byte {
+	msg := make([]byte, smb2HeaderSize, smb2HeaderSize+len(body))
+	copy(msg, []byte{0xFE, 'S', 'M', 'B'})
+	binary.LittleEndian.PutUint32(msg[8:], status)
+	binary.LittleEndian.PutUint16(msg[12:], command)
+	binary.LittleEndian.PutUint32(msg[16:], flags|smb2FlagResponse)
+	msg = append(msg, body...)
+
+	frame := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+// transformFrame returns a framed encrypted message.
+func transformFrame(size int) []byte {
+	msg := make([]byte, size)
+	copy(msg, []byte{0xFD, 'S', 'M', 'B'})
+	frame := make([]byte, 4, 4+size)
+	binary.BigEndian.PutUint32(frame, uint32(size))
+	return append(frame, msg...)
+}
+
+func TestSecurityObserver(t *testing.T) {
+	const moreProcessing = 0xC0000016
+	sessionBody := func(flags uint16) []byte {
+		body := make([]byte, 8)
+		binary.LittleEndian.PutUint16(body[2:], flags)
+		return body
+	}
+	treeBody := func(flags uint32) []byte {
+		body := make([]byte, 16)
+		binary.LittleEndian.PutUint32(body[4:], flags)
+		return body
+	}
+	negotiate := smb2Response(0, 0, 0, make([]byte, 128))
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   SecurityLevel
+	}{
+		{
+			name: "signed",
+			frames: [][]byte{
+				negotiate,
+				smb2Response(smb2SessionSetup, moreProcessing, 0, sessionBody(0)),
+				smb2Response(smb2SessionSetup, 0, smb2FlagSigned, sessionBody(0)),
+				smb2Response(smb2TreeConnect, 0, smb2FlagSigned, treeBody(0)),
+			},
+			want: SecuritySigned,
+		},
+		{
+			name: "unsigned",
+			frames: [][]byte{
+				negotiate,
+				smb2Response(smb2SessionSetup, 0, 0, sessionBody(0)),
+				smb2Response(smb2TreeConnect, 0, 0, treeBody(0)),
+			},
+			want: SecurityNone,
+		},
+		{
+			name: "session encrypted",
+			frames: [][]byte{
+				negotiate,
+				smb2Response(smb2SessionSetup, 0, smb2FlagSigned, sessionBody(smb2SessionEncryptData)),
+				transformFrame(200),
+			},
+			want: SecurityEncrypted,
+		},
+		{
+			name: "share encrypted",
+			frames: [][]byte{
+				negotiate,
+				smb2Response(smb2SessionSetup, 0, smb2FlagSigned, sessionBody(0)),
+				smb2Response(smb2TreeConnect, 0, smb2FlagSigned, treeBody(smb2ShareEncryptData)),
+			},
+			want: SecurityEncrypted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream []byte
+			for _, f := range tt.frames {
+				stream = append(stream, f...)
+			}
+
+			// Whole stream at once, then one byte per read
+			whole := newSecurityObserver(nil)
+			whole.observe(stream)
+			if got := whole.level(); got != tt.want {
+				t.Errorf("level = %q, want %q", got, tt.want)
+			}
+			split := newSecurityObserver(nil)
+			for i := range stream {
+				split.observe(stream[i : i+1])
+			}
+			if got := split.level(); got != tt.want {
+				t.Errorf("level read byte by byte = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityLevel_Below(t *testing.T) {
+	if !SecuritySigned.Below(SecurityEncrypted) || !SecurityNone.Below(SecuritySigned) {
+		t.Error("levels should be ordered none < signed < encrypted")
+	}
+	if SecurityEncrypted.Below(SecurityEncrypted) || SecuritySigned.Below("") {
+		t.Error("a level is not below itself nor below an unknown level")
+	}
+}
+
+func TestCheckSecurity(t *testing.T) {
+	defer SetSecurityCheck(nil)
+
+	if err := checkSecurity("nas", SecurityNone); err != nil {
+		t.Errorf("no check set: got %v", err)
+	}
+	refused := errors.New("downgraded")
+	SetSecurityCheck(func(server string, level SecurityLevel) error {
+		if level.Below(SecurityEncrypted) {
+			return refused
+		}
+		return nil
+	})
+	if err := checkSecurity("nas", SecuritySigned); !errors.Is(err, refused) {
+		t.Errorf("checkSecurity = %v, want the error of the check", err)
+	}
+}
@@ -0,0 +1,78 @@
+package smb
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// --- File Times ---
+//
+// A copied file gets the time it was written: uploads the time of the
+// server, downloads the local time, so "recent" conflict resolution compares
+// sync times instead of edit times. With times preserved, uploads set the
+// LastWriteTime of the remote file to the local modification time (on the
+// temp file, renames keep it) and downloads restore the remote LastWriteTime
+// on the local file. Some servers reject setting file times (SetInfo
+// FileBasicInformation): the first rejection is logged and the client stops
+// trying for the rest of the session; such servers can be listed in
+// sync.preserve_timestamps: false.
+
+// timesFS is the part of the share used to set file times.
+type timesFS interface {
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// SetPreserveTimes sets whether transfers keep the modification time of the
+// source file.
+func (c *SMBClient) SetPreserveTimes(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.preserveTimes = enabled
+}
+
+// preservesTimes reports whether transfers keep the modification time of the
+// source file.
+func (c *SMBClient) preservesTimes() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.preserveTimes
+}
+
+// setRemoteTime sets the LastWriteTime of a remote file. A rejection does not
+// fail the transfer: see timesRejected.
+func (c *SMBClient) setRemoteTime(fs timesFS, remotePath string, mtime time.Time, log *zap.Logger) {
+	if err := fs.Chtimes(remotePath, mtime, mtime); err != nil {
+		c.timesRejected(remotePath, err, log)
+	}
+}
+
+// timesRejected stops setting remote file times for the session after the
+// server rejected it.
+func (c *SMBClient) timesRejected(remotePath string, err error, log *zap.Logger) {
+	c.mu.Lock()
+	first := c.preserveTimes
+	c.preserveTimes = false
+	c.mu.Unlock()
+
+	if first {
+		log.Warn("server rejected setting file times, uploaded files keep the server time",
+			zap.String("server", c.server),
+			zap.String("path", remotePath),
+			zap.Error(err))
+	}
+}
+
+// setLocalTime sets the modification time of a downloaded file to the
+// LastWriteTime of the remote one (logged if it fails).
+func setLocalTime(localPath string, mtime time.Time, log *zap.Logger) {
+	if mtime.IsZero() {
+		return
+	}
+	if err := os.Chtimes(localPath, mtime, mtime); err != nil {
+		log.Warn("failed to restore modification time",
+			zap.String("local", localPath),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,114 @@
+package smb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+// --- Previous Versions ---
+//
+// NAS and Windows servers keep snapshots of their shares (Volume Shadow
+// Copies, ZFS or Btrfs snapshots exposed by Samba). The server lists them
+// with FSCTL_SRV_ENUMERATE_SNAPSHOTS as "@GMT-YYYY.MM.DD-HH.MM.SS" tokens,
+// and a file of a snapshot is read by inserting the token after the share:
+// \\nas\docs\@GMT-2025.01.31-10.00.00\report.docx. Like the Previous
+// Versions tab of Explorer, both go through the Windows SMB redirector (see
+// versions_windows.go).
+
+// snapshotTokenLayout is the time layout of a snapshot token (UTC).
+const snapshotTokenLayout = "@GMT-2006.01.02-15.04.05"
+
+// PreviousVersion is a copy of a remote file kept by a snapshot of the share.
+type PreviousVersion struct {
+	Token    string    // Snapshot token (@GMT-2025.01.31-10.00.00)
+	Snapshot time.Time // When the snapshot was taken
+	ModTime  time.Time // Last modification of the file in the snapshot
+	Size     int64
+}
+
+// ParseSnapshotToken returns the time of a snapshot token.
+func ParseSnapshotToken(token string) (time.Time, error) {
+	t, err := time.Parse(snapshotTokenLayout, token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid snapshot token %q (expected @GMT-YYYY.MM.DD-HH.MM.SS)", token)
+	}
+	return t, nil
+}
+
+// snapshotPath returns the path of p in a snapshot of its share.
+func snapshotPath(p smbpath.UNCPath, token string) string {
+	return smbpath.UNCPath{Host: p.Host, Share: p.Share, Path: joinSharePath(token, p.Path)}.String()
+}
+
+// joinSharePath joins two "\"-separated paths inside a share.
+func joinSharePath(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return a + `\` + b
+}
+
+// decodeSnapshotArray returns the tokens of the SRV_SNAPSHOT_ARRAY returned
+// by FSCTL_SRV_ENUMERATE_SNAPSHOTS (MS-SMB2 2.2.32.2), and the size of the
+// token list when data is too small to hold it (0 when complete).
+func decodeSnapshotArray(data []byte) (tokens []string, needed int, err error) {
+	if len(data) < 12 {
+		return nil, 0, fmt.Errorf("snapshot array too short (%d bytes)", len(data))
+	}
+	count := binary.LittleEndian.Uint32(data[0:4])
+	returned := binary.LittleEndian.Uint32(data[4:8])
+	size := int(binary.LittleEndian.Uint32(data[8:12]))
+	if returned < count || 12+size > len(data) {
+		return nil, size, nil
+	}
+
+	// Multi-string of UTF-16 tokens, each NUL-terminated
+	list := data[12 : 12+size]
+	units := make([]uint16, len(list)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(list[2*i:])
+	}
+	for _, s := range strings.Split(string(utf16.Decode(units)), "\x00") {
+		if s != "" {
+			tokens = append(tokens, s)
+		}
+	}
+	return tokens, 0, nil
+}
+
+// distinctVersions sorts versions newest first and keeps one per content:
+// consecutive snapshots of an unchanged file hold the same version, and the
+// version identical to the current file is no previous version.
+func distinctVersions(versions []PreviousVersion, current *PreviousVersion) []PreviousVersion {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Snapshot.After(versions[j].Snapshot)
+	})
+
+	same := func(a, b *PreviousVersion) bool {
+		return a.Size == b.Size && a.ModTime.Equal(b.ModTime)
+	}
+	distinct := versions[:0]
+	last := current
+	for i := range versions {
+		if last != nil && same(&versions[i], last) {
+			continue
+		}
+		distinct = append(distinct, versions[i])
+		last = &versions[i]
+	}
+	return distinct
+}
+
+// VersionCopyName returns the name a previous version is restored under,
+// next to the current file: "report (2025-01-31 10.00).docx".
+func VersionCopyName(name string, v PreviousVersion) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s (%s)%s", strings.TrimSuffix(name, ext), v.ModTime.Local().Format("2006-01-02 15.04"), ext)
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package smb
+
+import (
+	"errors"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+// errVersionsUnsupported is returned by the Previous Versions outside Windows.
+var errVersionsUnsupported = errors.New("previous versions are only available on Windows")
+
+// ListPreviousVersions lists the versions of a remote file kept by the
+// snapshots of its share. Snapshots are read through the Windows SMB
+// redirector: other platforms don't support it.
+func ListPreviousVersions(remote smbpath.UNCPath, creds *Credentials) ([]PreviousVersion, error) {
+	return nil, errVersionsUnsupported
+}
+
+// RestorePreviousVersion copies a previous version of a remote file to
+// localPath (Windows only).
+func RestorePreviousVersion(remote smbpath.UNCPath, creds *Credentials, v PreviousVersion, localPath string) error {
+	return errVersionsUnsupported
+}
@@ -0,0 +1,138 @@
+package smb
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+)
+
+func TestParseSnapshotToken(t *testing.T) {
+	got, err := ParseSnapshotToken("@GMT-2025.01.31-10.00.00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, token := range []string{"", "GMT-2025.01.31-10.00.00", "@GMT-2025-01-31T10:00:00"} {
+		if _, err := ParseSnapshotToken(token); err == nil {
+			t.Errorf("ParseSnapshotToken(%q) succeeded", token)
+		}
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	token := "@GMT-2025.01.31-10.00.00"
+	tests := []struct {
+		p    smbpath.UNCPath
+		want string
+	}{
+		{smbpath.UNCPath{Host: "nas", Share: "docs", Path: `work\report.docx`}, `\\nas\docs\@GMT-2025.01.31-10.00.00\work\report.docx`},
+		{smbpath.UNCPath{Host: "nas", Share: "docs"}, `\\nas\docs\@GMT-2025.01.31-10.00.00`},
+	}
+	for _, tt := range tests {
+		if got := snapshotPath(tt.p, token); got != tt.want {
+			t.Errorf("snapshotPath(%v) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+// snapshotArray builds a SRV_SNAPSHOT_ARRAY holding tokens, in a buffer of
+// bufSize bytes (0 = exact size).
+func snapshotArray(tokens []string, bufSize int) []byte {
+	var units []uint16
+	for _, token := range tokens {
+		units = append(units, utf16.Encode([]rune(token))...)
+		units = append(units, 0)
+	}
+	units = append(units, 0)
+	size := 2 * len(units)
+
+	if bufSize == 0 {
+		bufSize = 12 + size
+	}
+	data := make([]byte, bufSize)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(tokens)))
+	binary.LittleEndian.PutUint32(data[8:12], uint32(size))
+	if 12+size > bufSize {
+		return data // Returned = 0: too small for the list
+	}
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(tokens)))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[12+2*i:], u)
+	}
+	return data
+}
+
+func TestDecodeSnapshotArray(t *testing.T) {
+	tokens := []string{"@GMT-2025.01.31-10.00.00", "@GMT-2025.02.01-10.00.00"}
+
+	got, needed, err := decodeSnapshotArray(snapshotArray(tokens, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needed != 0 || len(got) != 2 || got[0] != tokens[0] || got[1] != tokens[1] {
+		t.Errorf("got %q (needed %d), want %q", got, needed, tokens)
+	}
+
+	// First call, with the header only
+	got, needed, err = decodeSnapshotArray(snapshotArray(tokens, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil || needed != 2*(2*25+1) {
+		t.Errorf("got %q (needed %d), want no token and the list size", got, needed)
+	}
+
+	// No snapshot
+	got, needed, err = decodeSnapshotArray(snapshotArray(nil, 0))
+	if err != nil || needed != 0 || len(got) != 0 {
+		t.Errorf("got %q (needed %d, %v), want no token", got, needed, err)
+	}
+
+	if _, _, err := decodeSnapshotArray(make([]byte, 8)); err == nil {
+		t.Error("decoding a truncated header succeeded")
+	}
+}
+
+func TestDistinctVersions(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 10, 0, 0, 0, time.UTC) }
+	current := &PreviousVersion{ModTime: day(5), Size: 300}
+	versions := []PreviousVersion{
+		{Token: "d1", Snapshot: day(1), ModTime: day(1), Size: 100},
+		{Token: "d6", Snapshot: day(6), ModTime: day(5), Size: 300}, // Same as current
+		{Token: "d3", Snapshot: day(3), ModTime: day(2), Size: 200},
+		{Token: "d2", Snapshot: day(2), ModTime: day(2), Size: 200}, // Unchanged since d2
+		{Token: "d4", Snapshot: day(4), ModTime: day(4), Size: 200},
+	}
+
+	got := distinctVersions(versions, current)
+	want := []string{"d4", "d3", "d1"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d versions, want %v", len(got), want)
+	}
+	for i, v := range got {
+		if v.Token != want[i] {
+			t.Errorf("version %d = %s, want %s", i, v.Token, want[i])
+		}
+	}
+}
+
+func TestVersionCopyName(t *testing.T) {
+	modTime := time.Date(2025, 1, 31, 10, 0, 0, 0, time.Local)
+	v := PreviousVersion{ModTime: modTime}
+	tests := map[string]string{
+		"report.docx":    "report (2025-01-31 10.00).docx",
+		"archive.tar.gz": "archive.tar (2025-01-31 10.00).gz",
+		"Makefile":       "Makefile (2025-01-31 10.00)",
+	}
+	for name, want := range tests {
+		if got := VersionCopyName(name, v); got != want {
+			t.Errorf("VersionCopyName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
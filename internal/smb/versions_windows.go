@@ -0,0 +1,197 @@
+//go:build windows
+
+package smb
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/partialfile"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"golang.org/x/sys/windows"
+)
+
+// fsctlSrvEnumerateSnapshots lists the snapshots of the volume of a file
+// (MS-SMB2 2.2.31).
+const fsctlSrvEnumerateSnapshots = 0x00144064
+
+var (
+	mpr                        = windows.NewLazySystemDLL("mpr.dll")
+	procWNetAddConnection2W    = mpr.NewProc("WNetAddConnection2W")
+	procWNetCancelConnection2W = mpr.NewProc("WNetCancelConnection2W")
+)
+
+// netResource is the NETRESOURCEW structure of WNetAddConnection2W.
+type netResource struct {
+	Scope       uint32
+	Type        uint32
+	DisplayType uint32
+	Usage       uint32
+	LocalName   *uint16
+	RemoteName  *uint16
+	Comment     *uint16
+	Provider    *uint16
+}
+
+const resourceTypeDisk = 0x1
+
+// connectShare connects the Windows SMB redirector to the share of remote
+// with the credentials of the job, so that snapshots are read with the
+// job's account rather than the session of the signed-in Windows user.
+// The returned function drops the connection.
+func connectShare(remote smbpath.UNCPath, creds *Credentials) (func(), error) {
+	if creds == nil || creds.Username == "" {
+		return nil, fmt.Errorf("no credentials stored for %s", remote.Host)
+	}
+	share := smbpath.UNCPath{Host: remote.Host, Share: remote.Share}.String()
+	user := creds.Username
+	if creds.Domain != "" {
+		user = creds.Domain + `\` + user
+	}
+
+	sharePtr, err := windows.UTF16PtrFromString(share)
+	if err != nil {
+		return nil, err
+	}
+	userPtr, err := windows.UTF16PtrFromString(user)
+	if err != nil {
+		return nil, err
+	}
+	passwordPtr, err := windows.UTF16PtrFromString(creds.Password)
+	if err != nil {
+		return nil, err
+	}
+	res := netResource{Type: resourceTypeDisk, RemoteName: sharePtr}
+	r, _, _ := procWNetAddConnection2W.Call(uintptr(unsafe.Pointer(&res)),
+		uintptr(unsafe.Pointer(passwordPtr)), uintptr(unsafe.Pointer(userPtr)), 0)
+	switch err := windows.Errno(r); err {
+	case 0:
+	case windows.ERROR_SESSION_CREDENTIAL_CONFLICT:
+		return nil, fmt.Errorf("%s is already connected in Windows with another account: disconnect it (net use %s /delete) and retry", share, share)
+	default:
+		return nil, fmt.Errorf("failed to connect to %s: %w", share, err)
+	}
+
+	return func() {
+		procWNetCancelConnection2W.Call(uintptr(unsafe.Pointer(sharePtr)), 0, 0)
+	}, nil
+}
+
+// ListPreviousVersions lists the versions of a remote file or folder kept by
+// the snapshots of its share, newest first, skipping snapshots holding the
+// same version as a newer one or as the current file. The share is read
+// through the Windows SMB redirector, connected with creds.
+func ListPreviousVersions(remote smbpath.UNCPath, creds *Credentials) ([]PreviousVersion, error) {
+	disconnect, err := connectShare(remote, creds)
+	if err != nil {
+		return nil, err
+	}
+	defer disconnect()
+
+	tokens, err := enumerateSnapshots(remote.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots of %s: %w", remote, err)
+	}
+
+	var current *PreviousVersion
+	if info, err := os.Stat(remote.String()); err == nil {
+		current = &PreviousVersion{ModTime: info.ModTime(), Size: info.Size()}
+	}
+
+	versions := make([]PreviousVersion, 0, len(tokens))
+	for _, token := range tokens {
+		snapshot, err := ParseSnapshotToken(token)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(snapshotPath(remote, token))
+		if err != nil {
+			continue // Not in this snapshot (created later, or deleted then)
+		}
+		versions = append(versions, PreviousVersion{
+			Token:    token,
+			Snapshot: snapshot,
+			ModTime:  info.ModTime(),
+			Size:     info.Size(),
+		})
+	}
+	return distinctVersions(versions, current), nil
+}
+
+// enumerateSnapshots returns the snapshot tokens of the share holding path.
+func enumerateSnapshots(path string) ([]string, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := windows.CreateFile(pathPtr, windows.GENERIC_READ,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(handle)
+
+	// A first call returns the size of the list, a second one the list
+	buf := make([]byte, 16)
+	for attempt := 0; attempt < 3; attempt++ {
+		var returned uint32
+		if err := windows.DeviceIoControl(handle, fsctlSrvEnumerateSnapshots, nil, 0,
+			&buf[0], uint32(len(buf)), &returned, nil); err != nil {
+			return nil, err
+		}
+		tokens, needed, err := decodeSnapshotArray(buf[:returned])
+		if err != nil || needed == 0 {
+			return tokens, err
+		}
+		buf = make([]byte, 12+needed)
+	}
+	return nil, fmt.Errorf("snapshot list kept growing")
+}
+
+// RestorePreviousVersion copies a previous version of a remote file to
+// localPath, with the modification time of the version. An existing file is
+// not overwritten. The share is connected with creds, as for
+// ListPreviousVersions.
+func RestorePreviousVersion(remote smbpath.UNCPath, creds *Credentials, v PreviousVersion, localPath string) error {
+	if _, err := os.Lstat(localPath); err == nil {
+		return fmt.Errorf("%s already exists", localPath)
+	}
+
+	disconnect, err := connectShare(remote, creds)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	src, err := os.Open(snapshotPath(remote, v.Token))
+	if err != nil {
+		return fmt.Errorf("failed to open version of %s: %w", v.Snapshot.Local().Format("2006-01-02 15:04"), err)
+	}
+	defer src.Close()
+
+	// Written aside, then renamed: no partial file if the copy fails. The
+	// scanner skips the temporary name, so it is never uploaded.
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), partialfile.VersionPrefix+"*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp.Name(), v.ModTime, v.ModTime)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), localPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to restore version: %w", err)
+	}
+	return nil
+}
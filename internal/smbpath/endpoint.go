@@ -0,0 +1,91 @@
+package smbpath
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultPort is the standard SMB port.
+const DefaultPort = 445
+
+// RemoteEndpoint is where a job syncs to: the server, the share, the folder
+// inside the share and the keyring entry holding the credentials.
+type RemoteEndpoint struct {
+	Host         string `json:"host"`           // Name or address (IPv6 without brackets)
+	Port         int    `json:"port,omitempty"` // 0 = DefaultPort
+	Share        string `json:"share"`          // Share name
+	Path         string `json:"path,omitempty"` // Folder inside the share, "\"-separated ("" = root)
+	CredentialID string `json:"credential_id"`  // Keyring entry of the credentials (server host)
+}
+
+// EndpointFromUNC returns the endpoint of a remote path (\\host\share\path)
+// on the given port (0 = default), with the credentials stored for its host.
+func EndpointFromUNC(remotePath string, port int) RemoteEndpoint {
+	p := SplitUNC(remotePath)
+	return RemoteEndpoint{
+		Host:         p.Host,
+		Port:         port,
+		Share:        p.Share,
+		Path:         p.Path,
+		CredentialID: p.Host,
+	}
+}
+
+// UNC returns the remote path of the endpoint.
+func (e RemoteEndpoint) UNC() UNCPath {
+	return UNCPath{Host: e.Host, Share: e.Share, Path: e.Path}
+}
+
+// Addr returns the host:port address to dial ("[fe80::1]:445" for IPv6).
+func (e RemoteEndpoint) Addr() string {
+	port := e.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(e.Host, strconv.Itoa(port))
+}
+
+// String returns the remote path, with the port when it is not the default one.
+func (e RemoteEndpoint) String() string {
+	if e.Port == 0 || e.Port == DefaultPort {
+		return e.UNC().String()
+	}
+	return e.UNC().String() + " (port " + strconv.Itoa(e.Port) + ")"
+}
+
+// ParseHostPort splits a server typed by a user into its host and port
+// (0 if none): "nas", "nas:1445", "192.168.1.10:445", "fe80::1" and
+// "[fe80::1]:445" are accepted. A bare IPv6 address is never split on its
+// colons.
+func ParseHostPort(s string) (host string, port int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", 0, fmt.Errorf("server cannot be empty")
+	}
+
+	// Bare IPv6 address (several colons, no brackets)
+	if net.ParseIP(s) != nil {
+		return s, 0, nil
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return s[1 : len(s)-1], 0, nil
+	}
+	if !strings.Contains(s, ":") {
+		return s, 0, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid server %q: %w", s, err)
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q in server %q", portStr, s)
+	}
+	if host == "" {
+		return "", 0, fmt.Errorf("invalid server %q: missing host", s)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,61 @@
+package smbpath
+
+import "testing"
+
+func TestParseHostPort(t *testing.T) {
+	tests := []struct {
+		in   string
+		host string
+		port int
+	}{
+		{"nas", "nas", 0},
+		{" nas.local ", "nas.local", 0},
+		{"nas:1445", "nas", 1445},
+		{"192.168.1.10:445", "192.168.1.10", 445},
+		{"fe80::1", "fe80::1", 0},
+		{"2001:db8::10", "2001:db8::10", 0},
+		{"[fe80::1]", "fe80::1", 0},
+		{"[2001:db8::10]:1445", "2001:db8::10", 1445},
+	}
+	for _, tt := range tests {
+		host, port, err := ParseHostPort(tt.in)
+		if err != nil {
+			t.Errorf("ParseHostPort(%q): %v", tt.in, err)
+			continue
+		}
+		if host != tt.host || port != tt.port {
+			t.Errorf("ParseHostPort(%q) = %q, %d, want %q, %d", tt.in, host, port, tt.host, tt.port)
+		}
+	}
+
+	for _, in := range []string{"", "nas:", "nas:port", "nas:70000", ":445", "[fe80::1]:x"} {
+		if _, _, err := ParseHostPort(in); err == nil {
+			t.Errorf("ParseHostPort(%q) should fail", in)
+		}
+	}
+}
+
+func TestRemoteEndpoint(t *testing.T) {
+	e := EndpointFromUNC(`\\fe80::1\docs\work`, 0)
+	want := RemoteEndpoint{Host: "fe80::1", Share: "docs", Path: "work", CredentialID: "fe80::1"}
+	if e != want {
+		t.Fatalf("EndpointFromUNC = %+v, want %+v", e, want)
+	}
+	if err := e.UNC().Validate(); err != nil {
+		t.Errorf("IPv6 host rejected: %v", err)
+	}
+	if got := e.Addr(); got != "[fe80::1]:445" {
+		t.Errorf("Addr() = %q, want [fe80::1]:445", got)
+	}
+	if got := e.String(); got != `\\fe80::1\docs\work` {
+		t.Errorf("String() = %q", got)
+	}
+
+	e = EndpointFromUNC(`\\nas\docs`, 1445)
+	if got := e.Addr(); got != "nas:1445" {
+		t.Errorf("Addr() = %q, want nas:1445", got)
+	}
+	if got := e.String(); got != `\\nas\docs (port 1445)` {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,153 @@
+// Package smbpath handles the remote paths of the jobs: UNC paths typed by
+// users and the endpoint (server, port, share, folder) a job syncs to. It
+// has no dependencies, so the database stores endpoints without importing
+// the SMB client.
+package smbpath
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// UNCPath is a remote path (\\host\share\path) split into its components.
+type UNCPath struct {
+	Host  string // Server name or address
+	Share string // Share name
+	Path  string // Folder inside the share, "\"-separated ("" = share root)
+}
+
+// Characters Windows refuses in host names, share names and file names.
+const (
+	invalidHostChars  = `\/:*?"<>|`
+	invalidShareChars = `\/[]:|<>+=;,*?"`
+	invalidNameChars  = `\/:*?"<>|`
+)
+
+// SplitUNC splits a remote path typed by a user without validating it.
+// It accepts backslashes or forward slashes, any number of leading, repeated
+// or trailing separators, a missing leading "\\" and an "smb://" prefix:
+// "//nas/docs/work/", `nas\docs\\work` and "smb://nas/docs/work" all give
+// {nas docs work}.
+func SplitUNC(s string) UNCPath {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "smb://") {
+		s = s[6:]
+	}
+
+	parts := strings.FieldsFunc(s, isSeparator)
+
+	var p UNCPath
+	if len(parts) >= 1 {
+		p.Host = parts[0]
+	}
+	if len(parts) >= 2 {
+		p.Share = parts[1]
+	}
+	if len(parts) >= 3 {
+		p.Path = strings.Join(parts[2:], `\`)
+	}
+	return p
+}
+
+// ParseUNC splits and validates a remote path. The host and the share are
+// required; the path inside the share is optional.
+func ParseUNC(s string) (UNCPath, error) {
+	p := SplitUNC(s)
+	if err := p.Validate(); err != nil {
+		return UNCPath{}, err
+	}
+	return p, nil
+}
+
+// JoinUNC builds and validates the remote path of a folder of a share.
+// folder may use either separator and may be empty (share root).
+func JoinUNC(host, share, folder string) (UNCPath, error) {
+	p := UNCPath{
+		Host:  strings.TrimSpace(host),
+		Share: strings.TrimSpace(share),
+		Path:  CleanSharePath(folder),
+	}
+	if err := p.Validate(); err != nil {
+		return UNCPath{}, err
+	}
+	return p, nil
+}
+
+// CleanSharePath normalizes a folder inside a share: "\"-separated,
+// without leading, repeated or trailing separators.
+func CleanSharePath(folder string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(folder), isSeparator)
+	return strings.Join(parts, `\`)
+}
+
+// Validate checks that the host, share and folder names are usable.
+func (p UNCPath) Validate() error {
+	if p.Host == "" {
+		return fmt.Errorf("remote path must start with \\\\server\\share")
+	}
+	if p.Share == "" {
+		return fmt.Errorf("remote path %s has no share (expected \\\\%s\\share)", p, p.Host)
+	}
+	// IPv6 literals are the only hosts allowed to contain ':'
+	if net.ParseIP(p.Host) == nil && (strings.ContainsAny(p.Host, invalidHostChars) || strings.ContainsFunc(p.Host, isSpaceOrControl)) {
+		return fmt.Errorf("invalid server name %q", p.Host)
+	}
+	if strings.ContainsAny(p.Share, invalidShareChars) || strings.ContainsFunc(p.Share, isControl) {
+		return fmt.Errorf("invalid share name %q", p.Share)
+	}
+	if p.Path == "" {
+		return nil
+	}
+	for _, name := range strings.Split(p.Path, `\`) {
+		switch {
+		case name == "." || name == "..":
+			return fmt.Errorf("remote path cannot contain %q", name)
+		case strings.ContainsAny(name, invalidNameChars) || strings.ContainsFunc(name, isControl):
+			return fmt.Errorf("invalid folder name %q in remote path", name)
+		case strings.TrimRight(name, " .") != name:
+			return fmt.Errorf("folder name %q cannot end with a space or a dot", name)
+		}
+	}
+	return nil
+}
+
+// String returns the canonical form \\host\share[\path].
+func (p UNCPath) String() string {
+	s := `\\` + p.Host + `\` + p.Share
+	if p.Path != "" {
+		s += `\` + p.Path
+	}
+	return s
+}
+
+// SlashPath returns the path inside the share with forward slashes, as used
+// for SMB operations.
+func (p UNCPath) SlashPath() string {
+	return strings.ReplaceAll(p.Path, `\`, "/")
+}
+
+// Equal reports whether two paths designate the same folder
+// (names are case-insensitive on SMB servers).
+func (p UNCPath) Equal(o UNCPath) bool {
+	return strings.EqualFold(p.Host, o.Host) &&
+		strings.EqualFold(p.Share, o.Share) &&
+		strings.EqualFold(p.Path, o.Path)
+}
+
+// SameUNC reports whether two remote paths, as typed, designate the same folder.
+func SameUNC(a, b string) bool {
+	return SplitUNC(a).Equal(SplitUNC(b))
+}
+
+func isSeparator(r rune) bool {
+	return r == '\\' || r == '/'
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func isSpaceOrControl(r rune) bool {
+	return r == ' ' || isControl(r)
+}
@@ -0,0 +1,79 @@
+package smbpath
+
+import "testing"
+
+func TestParseUNC(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      UNCPath
+		canonical string
+	}{
+		{`\\nas\docs`, UNCPath{"nas", "docs", ""}, `\\nas\docs`},
+		{`\\nas\docs\`, UNCPath{"nas", "docs", ""}, `\\nas\docs`},
+		{`\\nas\docs\work\2025`, UNCPath{"nas", "docs", `work\2025`}, `\\nas\docs\work\2025`},
+		{"//nas/docs/work/2025/", UNCPath{"nas", "docs", `work\2025`}, `\\nas\docs\work\2025`},
+		{`nas\docs\\work`, UNCPath{"nas", "docs", "work"}, `\\nas\docs\work`},
+		{"  smb://192.168.1.10/Docs/My Files ", UNCPath{"192.168.1.10", "Docs", "My Files"}, `\\192.168.1.10\Docs\My Files`},
+		{`\\nas\docs$\a/b\c`, UNCPath{"nas", "docs$", `a\b\c`}, `\\nas\docs$\a\b\c`},
+	}
+	for _, tt := range tests {
+		got, err := ParseUNC(tt.in)
+		if err != nil {
+			t.Errorf("ParseUNC(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUNC(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.canonical {
+			t.Errorf("ParseUNC(%q).String() = %q, want %q", tt.in, got.String(), tt.canonical)
+		}
+	}
+}
+
+func TestParseUNC_Invalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		`\\`,
+		`\\nas`,
+		`\\nas\`,
+		`\\na s\docs`,
+		`\\nas:445\docs`,
+		`\\nas\do;cs`,
+		`\\nas\docs\..\other`,
+		`\\nas\docs\.\work`,
+		`\\nas\docs\a*b`,
+		`\\nas\docs\work.`,
+		`\\nas\docs\work \sub`,
+	} {
+		if p, err := ParseUNC(in); err == nil {
+			t.Errorf("ParseUNC(%q) = %+v, expected error", in, p)
+		}
+	}
+}
+
+func TestJoinUNC(t *testing.T) {
+	p, err := JoinUNC("nas", "docs", "/work/2025/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != `\\nas\docs\work\2025` || p.SlashPath() != "work/2025" {
+		t.Errorf("JoinUNC = %s (%s)", p, p.SlashPath())
+	}
+
+	if p, err := JoinUNC("nas", "docs", ""); err != nil || p.String() != `\\nas\docs` {
+		t.Errorf("JoinUNC(share root) = %s, %v", p, err)
+	}
+	if _, err := JoinUNC("nas", "", "work"); err == nil {
+		t.Error("JoinUNC without share: expected error")
+	}
+}
+
+func TestSameUNC(t *testing.T) {
+	if !SameUNC(`\\NAS\Docs\Work\`, "//nas/docs/work") {
+		t.Error("same folder typed differently not recognized")
+	}
+	if SameUNC(`\\nas\docs\work`, `\\nas\docs\work2`) {
+		t.Error("different folders reported as the same")
+	}
+}
@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// --- NTFS Permissions ---
+//
+// Jobs syncing ACLs copy the owner and the permissions (DACL) of each
+// transferred file to its copy: the local security descriptor to the server
+// after an upload, the remote one to the PC after a download. Remote
+// descriptors go through the Windows SMB redirector, with the session of the
+// signed-in Windows user. Inherited entries are not copied: the destination
+// inherits those of its own folder. When the PC and the server belong to
+// different domains, sync.acl_sid_map translates the SIDs (local -> remote,
+// reversed for downloads). A descriptor that can't be copied is logged and
+// doesn't fail the transfer.
+
+type syncACLsKey struct{}
+
+// withSyncACLs records whether the run of ctx copies security descriptors.
+func withSyncACLs(ctx context.Context, req *SyncRequest) context.Context {
+	if !req.SyncACLs {
+		return ctx
+	}
+	return context.WithValue(ctx, syncACLsKey{}, true)
+}
+
+// syncACLs reports whether the run of ctx copies security descriptors.
+func syncACLs(ctx context.Context) bool {
+	enabled, _ := ctx.Value(syncACLsKey{}).(bool)
+	return enabled
+}
+
+// SIDMap translates the SIDs of security descriptors copied to the server.
+// A key is either a full SID or a domain SID (S-1-5-21-x-y-z), which then
+// translates every account of the domain, keeping its RID.
+type SIDMap map[string]string
+
+// SetSIDMap sets the SID translation of security descriptors copied to the
+// server (local -> remote).
+func (ex *Executor) SetSIDMap(m SIDMap) {
+	upload := make(SIDMap, len(m))
+	download := make(SIDMap, len(m))
+	for from, to := range m {
+		from, to = strings.ToUpper(strings.TrimSpace(from)), strings.ToUpper(strings.TrimSpace(to))
+		upload[from] = to
+		download[to] = from
+	}
+	ex.sidUpload, ex.sidDownload = upload, download
+	if len(m) > 0 {
+		ex.logger.Info("SID translation configured", zap.Int("entries", len(m)))
+	}
+}
+
+// sidPattern matches the SIDs of an SDDL string (well-known accounts use
+// two-letter aliases such as BA or SY, which need no translation).
+var sidPattern = regexp.MustCompile(`S-1-\d+(-\d+)*`)
+
+// translateSDDL replaces the SIDs of an SDDL string found in m, exactly or
+// by their domain part.
+func translateSDDL(sddl string, m SIDMap) string {
+	if len(m) == 0 {
+		return sddl
+	}
+	return sidPattern.ReplaceAllStringFunc(sddl, func(sid string) string {
+		if to, ok := m[sid]; ok {
+			return to
+		}
+		// Longest matching domain
+		for i := strings.LastIndexByte(sid, '-'); i > 0; i = strings.LastIndexByte(sid[:i], '-') {
+			if to, ok := m[sid[:i]]; ok {
+				return to + sid[i:]
+			}
+		}
+		return sid
+	})
+}
+
+// copyACL copies the security descriptor of a transferred file to its
+// destination when the run syncs ACLs.
+func (ex *Executor) copyACL(ctx context.Context, action *SyncAction, smbClient *smb.SMBClient) {
+	if !syncACLs(ctx) || smbClient == nil {
+		return
+	}
+
+	src, dst, m := action.FilePath, smbClient.RedirectorPath(action.RemotePath), ex.sidUpload
+	switch action.Action {
+	case cache.ActionUpload:
+	case cache.ActionDownload:
+		src, dst, m = dst, src, ex.sidDownload
+	default:
+		return
+	}
+
+	sddl, err := readSecurityDescriptor(src)
+	if err == nil {
+		err = writeSecurityDescriptor(dst, translateSDDL(sddl, m))
+	}
+	if err != nil {
+		ex.log(ctx).Warn("failed to copy permissions",
+			zap.String("path", action.FilePath),
+			zap.String("action", string(action.Action)),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package sync
+
+import "errors"
+
+// errACLsUnsupported is returned by ACL sync outside Windows.
+var errACLsUnsupported = errors.New("NTFS permissions are only available on Windows")
+
+// readSecurityDescriptor reads NTFS security descriptors, which other
+// platforms don't support.
+func readSecurityDescriptor(path string) (string, error) {
+	return "", errACLsUnsupported
+}
+
+// writeSecurityDescriptor applies NTFS security descriptors, which other
+// platforms don't support.
+func writeSecurityDescriptor(path, sddl string) error {
+	return errACLsUnsupported
+}
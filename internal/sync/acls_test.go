@@ -0,0 +1,65 @@
+package sync
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestTranslateSDDL(t *testing.T) {
+	m := SIDMap{
+		"S-1-5-21-1-2-3":      "S-1-5-21-7-8-9",
+		"S-1-5-21-1-2-3-1105": "S-1-5-21-7-8-9-2001",
+	}
+
+	tests := []struct {
+		name string
+		sddl string
+		m    SIDMap
+		want string
+	}{
+		{
+			name: "exact SID",
+			sddl: "O:S-1-5-21-1-2-3-1105D:(A;;FA;;;S-1-5-21-1-2-3-1105)",
+			m:    m,
+			want: "O:S-1-5-21-7-8-9-2001D:(A;;FA;;;S-1-5-21-7-8-9-2001)",
+		},
+		{
+			name: "domain SID keeps the RID",
+			sddl: "O:S-1-5-21-1-2-3-1200D:(A;;FR;;;S-1-5-21-1-2-3-513)",
+			m:    m,
+			want: "O:S-1-5-21-7-8-9-1200D:(A;;FR;;;S-1-5-21-7-8-9-513)",
+		},
+		{
+			name: "well-known aliases and unknown SIDs untouched",
+			sddl: "O:BAD:(A;;FA;;;SY)(A;;FR;;;S-1-5-21-4-5-6-1000)",
+			m:    m,
+			want: "O:BAD:(A;;FA;;;SY)(A;;FR;;;S-1-5-21-4-5-6-1000)",
+		},
+		{
+			name: "no map",
+			sddl: "O:S-1-5-21-1-2-3-1105D:(A;;FA;;;BA)",
+			want: "O:S-1-5-21-1-2-3-1105D:(A;;FA;;;BA)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translateSDDL(tt.sddl, tt.m); got != tt.want {
+				t.Errorf("translateSDDL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSIDMap_ReversesForDownloads(t *testing.T) {
+	ex := NewExecutor(1, zap.NewNop())
+	ex.SetSIDMap(SIDMap{" s-1-5-21-1-2-3 ": "S-1-5-21-7-8-9"})
+
+	if got := translateSDDL("O:S-1-5-21-1-2-3-500", ex.sidUpload); got != "O:S-1-5-21-7-8-9-500" {
+		t.Errorf("upload = %q", got)
+	}
+	if got := translateSDDL("O:S-1-5-21-7-8-9-500", ex.sidDownload); got != "O:S-1-5-21-1-2-3-500" {
+		t.Errorf("download = %q", got)
+	}
+}
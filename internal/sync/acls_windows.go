@@ -0,0 +1,50 @@
+//go:build windows
+
+package sync
+
+import (
+	"fmt"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+// aclSecurityInfo is the part of security descriptors copied by ACL sync.
+const aclSecurityInfo = windows.OWNER_SECURITY_INFORMATION | windows.DACL_SECURITY_INFORMATION
+
+// readSecurityDescriptor returns the owner and permissions of a file (local
+// or UNC path) as SDDL.
+func readSecurityDescriptor(path string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(longpath.Fix(path), windows.SE_FILE_OBJECT, aclSecurityInfo)
+	if err != nil {
+		return "", fmt.Errorf("failed to read security descriptor of %s: %w", path, err)
+	}
+	return sd.String(), nil
+}
+
+// writeSecurityDescriptor applies the owner and permissions of an SDDL
+// string to a file. Setting another account as owner needs the restore
+// privilege: without it, only the permissions are applied.
+func writeSecurityDescriptor(path, sddl string) error {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return fmt.Errorf("invalid security descriptor %q: %w", sddl, err)
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+
+	err = windows.SetNamedSecurityInfo(longpath.Fix(path), windows.SE_FILE_OBJECT, aclSecurityInfo, owner, nil, dacl, nil)
+	if err == windows.ERROR_INVALID_OWNER || err == windows.ERROR_ACCESS_DENIED || err == windows.ERROR_PRIVILEGE_NOT_HELD {
+		err = windows.SetNamedSecurityInfo(longpath.Fix(path), windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply security descriptor to %s: %w", path, err)
+	}
+	return nil
+}
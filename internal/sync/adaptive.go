@@ -0,0 +1,127 @@
+package sync
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultAdaptiveInterval is how often the adaptive parallelism measures the
+// throughput of the transfers before adjusting their number.
+const DefaultAdaptiveInterval = 5 * time.Second
+
+// adaptiveGain is the throughput change treated as significant: below it,
+// adding a transfer did not pay off and removing one did not cost anything.
+const adaptiveGain = 0.10
+
+// AdaptiveParallelism lets the executor adjust the number of concurrent
+// transfers of a run between Min and Max from the measured throughput,
+// starting from its number of workers. More transfers help on high-latency
+// links and servers with fast disks; past a point they only compete for the
+// same bandwidth or the same disk.
+type AdaptiveParallelism struct {
+	Min      int
+	Max      int
+	Interval time.Duration // 0 = DefaultAdaptiveInterval
+}
+
+// concurrencyTuner caps the actions the workers of a pool run at once and
+// moves the cap one step at a time (hill climbing): it keeps going in a
+// direction while the aggregate throughput improves, goes back when it
+// drops, and drops a transfer when one more brought nothing (lower
+// throughput per transfer for the same total).
+type concurrencyTuner struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	min    int
+	max    int
+	limit  int // Actions allowed at once
+	active int // Actions running
+
+	lastRate  float64 // Bytes per second measured at the previous limit (0 = none)
+	direction int     // +1 or -1: last move of the limit
+}
+
+// newConcurrencyTuner creates a tuner starting at start, clamped to bounds.
+func newConcurrencyTuner(start int, bounds AdaptiveParallelism) *concurrencyTuner {
+	t := &concurrencyTuner{min: max(bounds.Min, 1), direction: 1}
+	t.max = max(bounds.Max, t.min)
+	t.limit = min(max(start, t.min), t.max)
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// acquire waits until the worker may run an action, or ctx is done.
+func (t *concurrencyTuner) acquire(ctx context.Context) error {
+	// Wake waiters when the context is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		t.mu.Lock()
+		t.cond.Broadcast()
+		t.mu.Unlock()
+	})
+	defer stop()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.active >= t.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t.cond.Wait()
+	}
+	t.active++
+	return nil
+}
+
+// release ends an action started with acquire.
+func (t *concurrencyTuner) release() {
+	t.mu.Lock()
+	t.active--
+	t.cond.Broadcast()
+	t.mu.Unlock()
+}
+
+// current returns the number of actions allowed at once.
+func (t *concurrencyTuner) current() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limit
+}
+
+// observe takes the bytes transferred during elapsed at the current limit and
+// returns the new limit. Periods without completed transfers carry no signal
+// (large files still in progress, deletions) and change nothing.
+func (t *concurrencyTuner) observe(bytes int64, elapsed time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if bytes <= 0 || elapsed <= 0 {
+		return t.limit
+	}
+	rate := float64(bytes) / elapsed.Seconds()
+
+	switch {
+	case t.lastRate == 0:
+		// First measure: try one more transfer
+		t.direction = 1
+	case rate > t.lastRate*(1+adaptiveGain):
+		// Better: keep going the same way
+	case rate < t.lastRate*(1-adaptiveGain):
+		t.direction = -t.direction
+	case t.direction > 0:
+		// One more transfer brought nothing: each one got slower
+		t.direction = -1
+	default:
+		// One less transfer cost nothing: stay there
+		t.lastRate = rate
+		return t.limit
+	}
+
+	t.lastRate = rate
+	next := min(max(t.limit+t.direction, t.min), t.max)
+	if next != t.limit {
+		t.limit = next
+		t.cond.Broadcast()
+	}
+	return t.limit
+}
@@ -0,0 +1,144 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestConcurrencyTuner_ClimbsWhileThroughputImproves(t *testing.T) {
+	tuner := newConcurrencyTuner(2, AdaptiveParallelism{Min: 1, Max: 8})
+
+	// MB per second measured at each limit
+	rates := []int64{10, 20, 30, 31, 30}
+	want := []int{3, 4, 5, 4, 4}
+	for i, rate := range rates {
+		got := tuner.observe(rate<<20, time.Second)
+		if got != want[i] {
+			t.Fatalf("step %d (%d MB/s): expected limit %d, got %d", i, rate, want[i], got)
+		}
+	}
+}
+
+func TestConcurrencyTuner_BacksOffWhenThroughputDrops(t *testing.T) {
+	tuner := newConcurrencyTuner(4, AdaptiveParallelism{Min: 1, Max: 8})
+
+	tuner.observe(40<<20, time.Second) // -> 5
+	if got := tuner.observe(20<<20, time.Second); got != 4 {
+		t.Fatalf("expected limit back to 4 after a drop, got %d", got)
+	}
+	// Still dropping (other traffic): keep going down
+	if got := tuner.observe(10<<20, time.Second); got != 5 {
+		t.Fatalf("expected limit 5 after a second drop, got %d", got)
+	}
+}
+
+func TestConcurrencyTuner_StaysWithinBounds(t *testing.T) {
+	tuner := newConcurrencyTuner(10, AdaptiveParallelism{Min: 2, Max: 3})
+	if got := tuner.current(); got != 3 {
+		t.Fatalf("expected start clamped to 3, got %d", got)
+	}
+
+	for i := int64(1); i <= 5; i++ {
+		if got := tuner.observe(i*10<<20, time.Second); got != 3 {
+			t.Fatalf("expected limit to stay at max 3, got %d", got)
+		}
+	}
+	for i := int64(5); i >= 1; i-- {
+		tuner.observe(i<<20, time.Second)
+		if got := tuner.current(); got < 2 || got > 3 {
+			t.Fatalf("limit %d outside bounds [2, 3]", got)
+		}
+	}
+}
+
+func TestConcurrencyTuner_IgnoresPeriodsWithoutTransfers(t *testing.T) {
+	tuner := newConcurrencyTuner(4, AdaptiveParallelism{Min: 1, Max: 8})
+	if got := tuner.observe(0, time.Second); got != 4 {
+		t.Errorf("expected limit unchanged without transfers, got %d", got)
+	}
+	if tuner.lastRate != 0 {
+		t.Errorf("expected no throughput recorded, got %f", tuner.lastRate)
+	}
+}
+
+func TestConcurrencyTuner_AcquireBlocksAtLimit(t *testing.T) {
+	tuner := newConcurrencyTuner(1, AdaptiveParallelism{Min: 1, Max: 4})
+	ctx := context.Background()
+
+	if err := tuner.acquire(ctx); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if err := tuner.acquire(ctx); err != nil {
+			t.Errorf("acquire failed: %v", err)
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second acquire should block at the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Raising the limit lets the waiting worker through
+	tuner.observe(10<<20, time.Second)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second acquire should proceed once the limit is raised")
+	}
+	tuner.release()
+	tuner.release()
+}
+
+func TestConcurrencyTuner_AcquireCancellation(t *testing.T) {
+	tuner := newConcurrencyTuner(1, AdaptiveParallelism{Min: 1, Max: 1})
+	if err := tuner.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tuner.acquire(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected an error after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire should return after cancellation")
+	}
+}
+
+func TestNewEngine_ParallelExecutionOptIn(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		cfg := &config.Config{}
+		cfg.Sync.Performance.ParallelExecution = enabled
+		cfg.Sync.Performance.ParallelTransfers = 4
+		cfg.Sync.Performance.AdaptiveTransfers = true
+		cfg.Sync.Performance.MaxParallelTransfers = 16
+
+		engine, err := NewEngine(cfg, newFakeEngine(t).db, zap.NewNop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		executor := engine.executor.(*Executor)
+		wantWorkers := 0
+		if enabled {
+			wantWorkers = 4
+		}
+		if executor.numWorkers != wantWorkers || (executor.adaptive != nil) != enabled {
+			t.Errorf("parallel_execution=%v: %d workers (adaptive %v), want %d", enabled, executor.numWorkers, executor.adaptive != nil, wantWorkers)
+		}
+		engine.Close()
+	}
+}
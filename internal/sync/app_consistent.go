@@ -0,0 +1,213 @@
+package sync
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"go.uber.org/zap"
+)
+
+// --- Application-Consistent Groups ---
+//
+// Some applications keep a document in several files written together: a
+// SQLite database and its -wal/-shm/-journal files, an Access database and its
+// lock file. Others keep a single file open and write it for hours (Outlook
+// .pst/.ost, OneNote). Copying such files while the application writes them
+// gives the other side a corrupt document, so the actions on a group wait
+// until it is quiescent on both sides:
+//   - no companion file shows a transaction in progress (a rollback journal or
+//     a write-ahead log not checkpointed yet) and no lock file exists;
+//   - no file of the group changed during the quiet period;
+//   - locally, no application keeps the main file open for writing (Windows),
+//     unless the job reads locked files from a shadow copy (shadow_copy.go).
+// A group that isn't quiescent is skipped as a whole and synced by a later
+// run, so its files always reach the other side together.
+
+// appFormat describes the files an application writes together.
+type appFormat struct {
+	name       string
+	extensions []string          // Extensions of the main file
+	companions []string          // Suffixes appended to the main file name
+	pending    []string          // Companions holding writes in progress when not empty
+	locks      map[string]string // Main file extension -> lock file extension
+	exclusive  bool              // The application keeps the main file open for writing
+}
+
+// appFormats are the known multi-file and long-written formats.
+var appFormats = []appFormat{
+	{
+		name:       "SQLite",
+		extensions: []string{".db", ".db3", ".sqlite", ".sqlite3"},
+		companions: []string{"-wal", "-shm", "-journal"},
+		pending:    []string{"-wal", "-journal"},
+	},
+	{
+		name:       "Access",
+		extensions: []string{".accdb", ".mdb"},
+		locks:      map[string]string{".accdb": ".laccdb", ".mdb": ".ldb"},
+	},
+	{name: "Outlook", extensions: []string{".pst", ".ost"}, exclusive: true},
+	{name: "OneNote", extensions: []string{".one", ".onetoc2"}, exclusive: true},
+}
+
+// hasExtension reports whether the format's main files have extension ext
+// (lower case).
+func (f *appFormat) hasExtension(ext string) bool {
+	for _, e := range f.extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// appGroupOf returns the main file of the group a job-relative path belongs
+// to, and its format (ok = false for files outside any group).
+func appGroupOf(relPath string) (main string, format *appFormat, ok bool) {
+	lower := strings.ToLower(relPath)
+	for i := range appFormats {
+		f := &appFormats[i]
+		if f.hasExtension(path.Ext(lower)) {
+			return relPath, f, true
+		}
+		for _, suffix := range f.companions {
+			if strings.HasSuffix(lower, suffix) {
+				main := relPath[:len(relPath)-len(suffix)]
+				if f.hasExtension(path.Ext(strings.ToLower(main))) {
+					return main, f, true
+				}
+			}
+		}
+		for mainExt, lockExt := range f.locks {
+			if path.Ext(lower) == lockExt {
+				return relPath[:len(relPath)-len(lockExt)] + mainExt, f, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// members returns the files of the group of a main file: the main file,
+// then its companions and lock file.
+func (f *appFormat) members(main string) []string {
+	members := []string{main}
+	for _, suffix := range f.companions {
+		members = append(members, main+suffix)
+	}
+	ext := path.Ext(main)
+	if lockExt, ok := f.locks[strings.ToLower(ext)]; ok {
+		members = append(members, main[:len(main)-len(ext)]+lockExt)
+	}
+	return members
+}
+
+// busyReason returns why the files of a group (from a local or remote scan)
+// are being written, or "" if they are quiescent.
+func (f *appFormat) busyReason(main string, files map[string]*cache.FileInfo, now time.Time, quiet time.Duration) string {
+	for _, member := range f.members(main) {
+		info, ok := files[member]
+		if !ok {
+			continue
+		}
+		switch {
+		case f.isLock(member):
+			return "open in " + f.name
+		case f.isPending(main, member) && info.Size > 0:
+			return "transaction in progress"
+		case quiet > 0 && now.Sub(info.MTime) < quiet:
+			return "changed recently"
+		}
+	}
+	return ""
+}
+
+// isLock reports whether a member of a group is its lock file.
+func (f *appFormat) isLock(member string) bool {
+	ext := strings.ToLower(path.Ext(member))
+	for _, lockExt := range f.locks {
+		if ext == lockExt {
+			return true
+		}
+	}
+	return false
+}
+
+// isPending reports whether a member of a group holds writes in progress
+// when not empty.
+func (f *appFormat) isPending(main, member string) bool {
+	for _, suffix := range f.pending {
+		if member == main+suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// deferBusyGroups removes the decisions on the files of application groups
+// still being written, on either side. Returns the decisions to execute and
+// one skipped action per removed decision.
+func (e *Engine) deferBusyGroups(ctx context.Context, req *SyncRequest,
+	decisions []*cache.SyncDecision, localFiles, remoteFiles map[string]*cache.FileInfo) ([]*cache.SyncDecision, []*SyncAction) {
+
+	if !e.config.Sync.AppConsistent {
+		return decisions, nil
+	}
+	quiet := time.Duration(e.config.Sync.AppQuietSeconds) * time.Second
+	now := timeNow()
+
+	// Busy groups, by main file ("" = quiescent)
+	busy := make(map[string]string)
+	var ready []*cache.SyncDecision
+	var deferred []*SyncAction
+	for _, decision := range decisions {
+		main, format, ok := appGroupOf(decision.LocalPath)
+		if !ok {
+			ready = append(ready, decision)
+			continue
+		}
+
+		reason, checked := busy[main]
+		if !checked {
+			reason = format.busyReason(main, localFiles, now, quiet)
+			if reason == "" {
+				reason = format.busyReason(main, remoteFiles, now, quiet)
+			}
+			// Jobs reading locked files upload it from a shadow copy instead
+			if reason == "" && format.exclusive && !req.ReadLockedFiles && localFiles[main] != nil &&
+				fileInUse(filepath.Join(req.LocalPath, filepath.FromSlash(main))) {
+				reason = "open in " + format.name
+			}
+			busy[main] = reason
+			if reason != "" {
+				e.log(ctx).Info("application files still being written, syncing them later",
+					zap.String("path", main),
+					zap.String("format", format.name),
+					zap.String("reason", reason))
+			}
+		}
+		if reason == "" {
+			ready = append(ready, decision)
+			continue
+		}
+
+		var size int64
+		if decision.LocalInfo != nil {
+			size = decision.LocalInfo.Size
+		} else if decision.RemoteInfo != nil {
+			size = decision.RemoteInfo.Size
+		}
+		deferred = append(deferred, &SyncAction{
+			FilePath:   filepath.Join(req.LocalPath, filepath.FromSlash(decision.LocalPath)),
+			RemotePath: decision.RemotePath,
+			Action:     decision.Action,
+			Status:     ActionStatusSkipped,
+			Size:       size,
+			Timestamp:  timeNow(),
+		})
+	}
+	return ready, deferred
+}
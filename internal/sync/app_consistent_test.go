@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestAppGroupOf(t *testing.T) {
+	tests := []struct {
+		path   string
+		main   string
+		format string
+	}{
+		{"data/app.db", "data/app.db", "SQLite"},
+		{"data/app.db-wal", "data/app.db", "SQLite"},
+		{"data/App.SQLITE-journal", "data/App.SQLITE", "SQLite"},
+		{"Compta.accdb", "Compta.accdb", "Access"},
+		{"Compta.laccdb", "Compta.accdb", "Access"},
+		{"old.ldb", "old.mdb", "Access"},
+		{"mail/archive.pst", "mail/archive.pst", "Outlook"},
+		{"Notes/Work.one", "Notes/Work.one", "OneNote"},
+		{"notes.txt", "", ""},
+		{"backup.txt-wal", "", ""},
+	}
+	for _, tt := range tests {
+		main, format, ok := appGroupOf(tt.path)
+		if !ok {
+			if tt.format != "" {
+				t.Errorf("appGroupOf(%q): no group, want %s", tt.path, tt.format)
+			}
+			continue
+		}
+		if main != tt.main || format.name != tt.format {
+			t.Errorf("appGroupOf(%q) = %q (%s), want %q (%s)", tt.path, main, format.name, tt.main, tt.format)
+		}
+	}
+}
+
+func TestDeferBusyGroups(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	origNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = origNow }()
+
+	old := now.Add(-time.Hour)
+	local := map[string]*cache.FileInfo{
+		"idle.db":          {Size: 4096, MTime: old},
+		"idle.db-shm":      {Size: 32768, MTime: old},
+		"open.db":          {Size: 4096, MTime: old},
+		"open.db-wal":      {Size: 1024, MTime: old},
+		"fresh.sqlite":     {Size: 4096, MTime: now.Add(-5 * time.Second)},
+		"Compta.accdb":     {Size: 8192, MTime: old},
+		"notes.txt":        {Size: 10, MTime: now},
+		"remote-lock.mdb":  {Size: 8192, MTime: old},
+		"empty-wal.db":     {Size: 4096, MTime: old},
+		"empty-wal.db-wal": {Size: 0, MTime: old},
+	}
+	remote := map[string]*cache.FileInfo{
+		"remote-lock.ldb": {Size: 64, MTime: old},
+		"Compta.laccdb":   {Size: 64, MTime: old},
+	}
+
+	upload := func(path string) *cache.SyncDecision {
+		return &cache.SyncDecision{LocalPath: path, RemotePath: path, Action: cache.ActionUpload, LocalInfo: local[path]}
+	}
+	decisions := []*cache.SyncDecision{
+		upload("idle.db"),
+		upload("idle.db-shm"),
+		upload("open.db"),
+		upload("open.db-wal"),
+		upload("fresh.sqlite"),
+		upload("Compta.accdb"),
+		upload("notes.txt"),
+		{LocalPath: "remote-lock.mdb", RemotePath: "remote-lock.mdb", Action: cache.ActionDeleteRemote},
+		upload("empty-wal.db"),
+		upload("empty-wal.db-wal"),
+	}
+
+	cfg := &config.Config{}
+	cfg.Sync.AppConsistent = true
+	cfg.Sync.AppQuietSeconds = 30
+	e := &Engine{logger: zap.NewNop(), config: cfg}
+	req := &SyncRequest{LocalPath: "sync"}
+
+	ready, deferred := e.deferBusyGroups(context.Background(), req, decisions, local, remote)
+	var readyPaths []string
+	for _, d := range ready {
+		readyPaths = append(readyPaths, d.LocalPath)
+	}
+	want := []string{"idle.db", "idle.db-shm", "notes.txt", "empty-wal.db", "empty-wal.db-wal"}
+	if len(readyPaths) != len(want) {
+		t.Fatalf("ready = %v, want %v", readyPaths, want)
+	}
+	for i := range want {
+		if readyPaths[i] != want[i] {
+			t.Fatalf("ready = %v, want %v", readyPaths, want)
+		}
+	}
+	if len(deferred) != 5 {
+		t.Fatalf("expected 5 deferred actions, got %d", len(deferred))
+	}
+	for _, action := range deferred {
+		if action.Status != ActionStatusSkipped {
+			t.Errorf("deferred action %s: status %s, want skipped", action.RemotePath, action.Status)
+		}
+	}
+	if deferred[0].RemotePath != "open.db" || deferred[0].Size != 4096 {
+		t.Errorf("deferred[0] = %+v, want open.db with size 4096", deferred[0])
+	}
+	if deferred[4].Action != cache.ActionDeleteRemote {
+		t.Errorf("deferred deletion has action %s", deferred[4].Action)
+	}
+
+	// Disabled: everything runs
+	cfg.Sync.AppConsistent = false
+	ready, deferred = e.deferBusyGroups(context.Background(), req, decisions, local, remote)
+	if len(ready) != len(decisions) || len(deferred) != 0 {
+		t.Errorf("disabled: %d ready, %d deferred, want %d and 0", len(ready), len(deferred), len(decisions))
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package sync
+
+import (
+	"os"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// setLocalAttributes emulates Windows attributes on other platforms:
+// only the read-only bit is applied, through the write permissions.
+func setLocalAttributes(path string, attrs, mask uint32) error {
+	if mask&cache.AttrReadOnly == 0 {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	perm := info.Mode().Perm()
+	if attrs&cache.AttrReadOnly != 0 {
+		perm &^= 0222
+	} else {
+		perm |= 0200
+	}
+	if perm == info.Mode().Perm() {
+		return nil
+	}
+
+	return os.Chmod(path, perm)
+}
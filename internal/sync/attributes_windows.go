@@ -0,0 +1,36 @@
+//go:build windows
+
+package sync
+
+import (
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+// setLocalAttributes replaces the bits in mask of a local file's attributes with
+// those of attrs, leaving other bits untouched.
+func setLocalAttributes(path string, attrs, mask uint32) error {
+	pathPtr, err := windows.UTF16PtrFromString(longpath.Fix(path))
+	if err != nil {
+		return err
+	}
+
+	current, err := windows.GetFileAttributes(pathPtr)
+	if err != nil {
+		return err
+	}
+
+	updated := current&^mask | attrs&mask
+	// FILE_ATTRIBUTE_NORMAL is only valid alone
+	if updated&^windows.FILE_ATTRIBUTE_NORMAL != 0 {
+		updated &^= windows.FILE_ATTRIBUTE_NORMAL
+	}
+	if updated == 0 {
+		updated = windows.FILE_ATTRIBUTE_NORMAL
+	}
+	if updated == current {
+		return nil
+	}
+
+	return windows.SetFileAttributes(pathPtr, updated)
+}
@@ -0,0 +1,118 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
+)
+
+// --- Audit Log ---
+
+// conflictResolvedReason starts the reason of the decisions made by the
+// conflict resolver.
+const conflictResolvedReason = "conflict resolved"
+
+// replacesFile reports whether a transfer overwrites an existing file.
+func replacesFile(d *cache.SyncDecision) bool {
+	switch d.Action {
+	case cache.ActionUpload:
+		return d.RemoteInfo != nil
+	case cache.ActionDownload:
+		return d.LocalInfo != nil
+	}
+	return false
+}
+
+// auditEvent returns the audit event of an executed action, "" for actions
+// that destroy nothing (new files, moves, attributes).
+func auditEvent(action *SyncAction) string {
+	if action.Status != ActionStatusSuccess {
+		return ""
+	}
+	if strings.HasPrefix(action.Reason, conflictResolvedReason) {
+		return database.AuditConflict
+	}
+	switch action.Action {
+	case cache.ActionDeleteLocal:
+		return database.AuditDeleteLocal
+	case cache.ActionDeleteRemote:
+		return database.AuditDeleteRemote
+	case cache.ActionUpload:
+		if action.Replaces {
+			return database.AuditOverwriteRemote
+		}
+	case cache.ActionDownload:
+		if action.Replaces {
+			return database.AuditOverwriteLocal
+		}
+	}
+	return ""
+}
+
+// auditEntries lists the files deleted or overwritten by the actions of a run
+// and the conflicts they resolved, by actor.
+func auditEntries(req *SyncRequest, runID string, actions []*SyncAction, actor string) []*database.AuditEntry {
+	var entries []*database.AuditEntry
+	for _, action := range actions {
+		event := auditEvent(action)
+		if event == "" {
+			continue
+		}
+		detail := action.Reason
+		if action.ConflictCopy != "" {
+			detail += fmt.Sprintf(" (losing version kept as %s)", filepath.Base(action.ConflictCopy))
+		}
+		entries = append(entries, &database.AuditEntry{
+			JobID:     req.JobID,
+			RunID:     runID,
+			OpID:      action.OpID,
+			Event:     event,
+			Path:      toRelativePath(action.FilePath, req.LocalPath),
+			Detail:    detail,
+			Actor:     actor,
+			Timestamp: action.Timestamp,
+		})
+	}
+	return entries
+}
+
+// auditActor returns the account and the computer running the syncs, as
+// DOMAIN\user@COMPUTER.
+func auditActor() string {
+	name := os.Getenv("USERNAME")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+}
+
+// recordAudit appends the destructive actions of a committed batch to the
+// audit log, and copies them to the Windows Event Log with
+// logging.audit_event_log.
+func (e *Engine) recordAudit(ctx context.Context, req *SyncRequest, actions []*SyncAction) error {
+	entries := auditEntries(req, correlation.RunID(ctx), actions, auditActor())
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := e.db.AppendAudit(entries); err != nil {
+		return err
+	}
+	if e.config.Logging.AuditEventLog {
+		for _, entry := range entries {
+			eventlog.Info(eventlog.EventAuditEntry, fmt.Sprintf(
+				"Job %d: %s %s. %s [by %s, run %s, op %s, audit entry %d, hash %s]",
+				entry.JobID, entry.Event, entry.Path, entry.Detail, entry.Actor,
+				entry.RunID, entry.OpID, entry.ID, entry.Hash))
+		}
+	}
+	return nil
+}
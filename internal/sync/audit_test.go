@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+)
+
+func TestAuditEntries(t *testing.T) {
+	req := &SyncRequest{JobID: 7, LocalPath: "/sync"}
+	actions := []*SyncAction{
+		{FilePath: "/sync/new.txt", Action: cache.ActionUpload, Status: ActionStatusSuccess},
+		{FilePath: "/sync/edited.txt", Action: cache.ActionUpload, Replaces: true, Status: ActionStatusSuccess},
+		{FilePath: "/sync/pulled.txt", Action: cache.ActionDownload, Replaces: true, Status: ActionStatusSuccess},
+		{FilePath: "/sync/gone.txt", Action: cache.ActionDeleteLocal, Status: ActionStatusSuccess, Reason: "file deleted remotely"},
+		{FilePath: "/sync/failed.txt", Action: cache.ActionDeleteRemote, Status: ActionStatusFailed},
+		{FilePath: "/sync/both.txt", Action: cache.ActionUpload, Replaces: true, Status: ActionStatusSuccess,
+			Reason: "conflict resolved: local newer", ConflictCopy: "docs/both (conflict).txt"},
+	}
+
+	entries := auditEntries(req, "run1", actions, `CORP\me@PC`)
+	want := map[string]string{
+		"edited.txt": database.AuditOverwriteRemote,
+		"pulled.txt": database.AuditOverwriteLocal,
+		"gone.txt":   database.AuditDeleteLocal,
+		"both.txt":   database.AuditConflict,
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for _, e := range entries {
+		if want[e.Path] != e.Event {
+			t.Errorf("%s: event %q, want %q", e.Path, e.Event, want[e.Path])
+		}
+		if e.JobID != 7 || e.RunID != "run1" || e.Actor != `CORP\me@PC` {
+			t.Errorf("%s: unexpected entry %+v", e.Path, e)
+		}
+	}
+	if last := entries[len(entries)-1]; last.Detail != "conflict resolved: local newer (losing version kept as both (conflict).txt)" {
+		t.Errorf("conflict detail = %q", last.Detail)
+	}
+}
+
+func TestEngine_AuditInterruptedRun(t *testing.T) {
+	engine := newFakeEngine(t, WithCacheManager(&fakeCache{}), WithExecutor(&interruptingExecutor{after: 2}))
+	req := &SyncRequest{JobID: 1, LocalPath: t.TempDir(), RemotePath: `\\nas\share`, Mode: SyncModeMirror}
+	decisions := []*cache.SyncDecision{
+		{LocalPath: "a.txt", RemotePath: "a.txt", Action: cache.ActionDeleteRemote},
+		{LocalPath: "b.txt", RemotePath: "b.txt", Action: cache.ActionDeleteRemote},
+		{LocalPath: "c.txt", RemotePath: "c.txt", Action: cache.ActionDeleteRemote},
+	}
+	if _, err := engine.executeActions(context.Background(), req, decisions, nil, nil, nil); err == nil {
+		t.Fatal("expected interrupted execution")
+	}
+
+	// The deletions committed before the interruption are audited
+	entries, err := engine.db.GetAuditLog(req.JobID, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Event != database.AuditDeleteRemote {
+		t.Errorf("expected the 2 committed deletions audited, got %+v", entries)
+	}
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"context"
+	"sync"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// memoryBudget bounds the memory reserved by in-flight actions.
+// Workers block in acquire until enough budget is released, which in turn
+// fills the bounded job queue and blocks the producer (backpressure).
+type memoryBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int64 // 0 = unlimited
+	inUse int64
+	peak  int64
+}
+
+// newMemoryBudget creates a budget of limit bytes (0 = unlimited)
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit < 0 {
+		limit = 0
+	}
+	b := &memoryBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire reserves n bytes, blocking until they are available or ctx is done.
+// A request larger than the whole budget is clamped so it can run alone.
+// Returns the number of bytes actually reserved, to pass to release.
+func (b *memoryBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	if b.limit > 0 && n > b.limit {
+		n = b.limit
+	}
+
+	// Wake waiters when the context is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	})
+	defer stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.limit > 0 && b.inUse+n > b.limit {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		b.cond.Wait()
+	}
+
+	b.inUse += n
+	if b.inUse > b.peak {
+		b.peak = b.inUse
+	}
+	return n, nil
+}
+
+// release returns n bytes to the budget
+func (b *memoryBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.inUse -= n
+	if b.inUse < 0 {
+		b.inUse = 0
+	}
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// usage returns the bytes currently reserved, the peak and the limit
+func (b *memoryBudget) usage() (inUse, peak, limit int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inUse, b.peak, b.limit
+}
+
+// actionMemoryCost estimates the memory an action keeps in flight:
+// one transfer buffer for uploads and downloads (capped by file size), one
+// chunk per stream for parallel chunked transfers, nothing for deletes.
+func (ex *Executor) actionMemoryCost(decision *cache.SyncDecision) int64 {
+	bufferSize := int64(ex.bufferSizeMB) * 1024 * 1024
+
+	var info *cache.FileInfo
+	switch decision.Action {
+	case cache.ActionUpload:
+		info = decision.LocalInfo
+	case cache.ActionDownload:
+		info = decision.RemoteInfo
+	default:
+		return 0
+	}
+
+	if info == nil {
+		return bufferSize
+	}
+	if ex.parallel.Applies(info.Size) {
+		return min(info.Size, ex.parallel.ChunkSize*int64(ex.parallel.Streams))
+	}
+	return min(info.Size, bufferSize)
+}
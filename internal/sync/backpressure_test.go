@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+func TestMemoryBudget_BlocksUntilReleased(t *testing.T) {
+	budget := newMemoryBudget(100)
+	ctx := context.Background()
+
+	first, err := budget.acquire(ctx, 80)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		n, err := budget.acquire(ctx, 50)
+		if err != nil {
+			t.Errorf("acquire failed: %v", err)
+		}
+		budget.release(n)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second acquire should block while budget is exhausted")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	budget.release(first)
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second acquire should proceed after release")
+	}
+
+	inUse, peak, limit := budget.usage()
+	if inUse != 0 {
+		t.Errorf("expected 0 bytes in use, got %d", inUse)
+	}
+	if peak != 80 {
+		t.Errorf("expected peak 80, got %d", peak)
+	}
+	if limit != 100 {
+		t.Errorf("expected limit 100, got %d", limit)
+	}
+}
+
+func TestMemoryBudget_ClampsOversizedRequest(t *testing.T) {
+	budget := newMemoryBudget(100)
+
+	n, err := budget.acquire(context.Background(), 500)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("expected request clamped to 100, got %d", n)
+	}
+	budget.release(n)
+}
+
+func TestMemoryBudget_Cancellation(t *testing.T) {
+	budget := newMemoryBudget(100)
+	if _, err := budget.acquire(context.Background(), 100); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := budget.acquire(ctx, 10)
+		done <- err
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected error after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire should return after cancellation")
+	}
+}
+
+func TestMemoryBudget_Unlimited(t *testing.T) {
+	budget := newMemoryBudget(0)
+
+	for i := 0; i < 3; i++ {
+		if _, err := budget.acquire(context.Background(), 1<<30); err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+	}
+}
+
+func TestActionMemoryCost(t *testing.T) {
+	executor := NewExecutor(4, zap.NewNop())
+	bufferSize := int64(4 * 1024 * 1024)
+
+	tests := []struct {
+		name     string
+		decision *cache.SyncDecision
+		want     int64
+	}{
+		{"small upload", &cache.SyncDecision{Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Size: 1000}}, 1000},
+		{"large download", &cache.SyncDecision{Action: cache.ActionDownload, RemoteInfo: &cache.FileInfo{Size: 1 << 30}}, bufferSize},
+		{"unknown size", &cache.SyncDecision{Action: cache.ActionDownload}, bufferSize},
+		{"delete", &cache.SyncDecision{Action: cache.ActionDeleteRemote, RemoteInfo: &cache.FileInfo{Size: 1 << 30}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := executor.actionMemoryCost(tt.decision); got != tt.want {
+				t.Errorf("actionMemoryCost() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionMemoryCost_ParallelTransfer(t *testing.T) {
+	executor := NewExecutor(4, zap.NewNop())
+	executor.SetParallelTransfer(smb.ParallelTransfer{MinSize: 64 << 20, ChunkSize: 8 << 20, Streams: 4})
+
+	large := &cache.SyncDecision{Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Size: 1 << 30}}
+	if got := executor.actionMemoryCost(large); got != 32<<20 {
+		t.Errorf("parallel upload cost = %d, want one chunk per stream (%d)", got, 32<<20)
+	}
+	// Below MinSize the file is copied with a single buffer
+	small := &cache.SyncDecision{Action: cache.ActionDownload, RemoteInfo: &cache.FileInfo{Size: 32 << 20}}
+	if got := executor.actionMemoryCost(small); got != 4<<20 {
+		t.Errorf("serial download cost = %d, want %d", got, 4<<20)
+	}
+}
+
+func TestSetBackpressure_QueueSize(t *testing.T) {
+	executor := NewExecutor(4, zap.NewNop())
+	executor.SetBackpressure(7, 64)
+
+	pool := NewWorkerPool(2, executor, zap.NewNop())
+	stats := pool.GetStats()
+
+	if stats.QueueCapacity != 7 {
+		t.Errorf("expected queue capacity 7, got %d", stats.QueueCapacity)
+	}
+	if stats.InFlightLimit != 64*1024*1024 {
+		t.Errorf("expected in-flight limit 64 MB, got %d", stats.InFlightLimit)
+	}
+}
@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCommitBatchSize is the number of completed actions between two cache commits
+const DefaultCommitBatchSize = 100
+
+// CommitFunc persists a batch of completed actions (typically into files_state)
+type CommitFunc func(actions []*SyncAction) error
+
+// actionBatcher accumulates completed actions and commits them every batchSize
+// actions, so a crash mid-run only loses the current batch.
+// A nil batcher ignores all calls.
+type actionBatcher struct {
+	mu        sync.Mutex
+	batchSize int
+	commit    CommitFunc
+	pending   []*SyncAction
+	committed int
+	logger    *zap.Logger
+}
+
+// newActionBatcher returns nil if commit is nil
+func newActionBatcher(batchSize int, commit CommitFunc, logger *zap.Logger) *actionBatcher {
+	if commit == nil {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultCommitBatchSize
+	}
+	return &actionBatcher{
+		batchSize: batchSize,
+		commit:    commit,
+		pending:   make([]*SyncAction, 0, batchSize),
+		logger:    logger,
+	}
+}
+
+// add records a completed action and commits the batch once it is full
+func (b *actionBatcher) add(action *SyncAction) {
+	if b == nil || action == nil || action.Status != ActionStatusSuccess {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, action)
+	if len(b.pending) >= b.batchSize {
+		// A failed commit keeps the actions pending, they are retried with the next batch
+		if err := b.flushLocked(); err != nil {
+			b.logger.Warn("cache commit failed, will retry with next batch",
+				zap.Int("pending", len(b.pending)),
+				zap.Error(err))
+		}
+	}
+}
+
+// flush commits the remaining actions
+func (b *actionBatcher) flush() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *actionBatcher) flushLocked() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	if err := b.commit(b.pending); err != nil {
+		return err
+	}
+
+	b.committed += len(b.pending)
+	b.logger.Debug("committed completed actions to cache",
+		zap.Int("batch", len(b.pending)),
+		zap.Int("total_committed", b.committed))
+	b.pending = make([]*SyncAction, 0, b.batchSize)
+	return nil
+}
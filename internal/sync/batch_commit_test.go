@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"go.uber.org/zap"
+)
+
+func TestActionBatcher_CommitsEveryBatch(t *testing.T) {
+	var batches []int
+	batcher := newActionBatcher(3, func(actions []*SyncAction) error {
+		batches = append(batches, len(actions))
+		return nil
+	}, zap.NewNop())
+
+	for i := 0; i < 7; i++ {
+		batcher.add(&SyncAction{Status: ActionStatusSuccess})
+	}
+	// Failed actions are never committed
+	batcher.add(&SyncAction{Status: ActionStatusFailed})
+
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches before flush, got %d", len(batches))
+	}
+
+	if err := batcher.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if len(batches) != 3 || batches[2] != 1 {
+		t.Errorf("expected final batch of 1, got %v", batches)
+	}
+}
+
+func TestActionBatcher_RetriesFailedCommit(t *testing.T) {
+	calls := 0
+	var committed int
+	batcher := newActionBatcher(2, func(actions []*SyncAction) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database is locked")
+		}
+		committed += len(actions)
+		return nil
+	}, zap.NewNop())
+
+	for i := 0; i < 4; i++ {
+		batcher.add(&SyncAction{Status: ActionStatusSuccess})
+	}
+	if err := batcher.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if committed != 4 {
+		t.Errorf("expected 4 actions committed after retry, got %d", committed)
+	}
+}
+
+func TestActionBatcher_Nil(t *testing.T) {
+	batcher := newActionBatcher(10, nil, zap.NewNop())
+	if batcher != nil {
+		t.Fatal("expected nil batcher without commit func")
+	}
+
+	// Must not panic
+	batcher.add(&SyncAction{Status: ActionStatusSuccess})
+	if err := batcher.flush(); err != nil {
+		t.Errorf("flush on nil batcher returned %v", err)
+	}
+}
+
+func TestExecuteWithCommit_Sequential(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var decisions []*cache.SyncDecision
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(tmpDir, "file"+string(rune('a'+i))+".txt")
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		decisions = append(decisions, &cache.SyncDecision{
+			LocalPath: path,
+			Action:    cache.ActionDeleteLocal,
+		})
+	}
+
+	executor := NewExecutor(4, zap.NewNop())
+	executor.SetCommitBatchSize(2)
+
+	var batches []int
+	actions, err := executor.ExecuteWithCommit(context.Background(), decisions, nil, nil, func(batch []*SyncAction) error {
+		batches = append(batches, len(batch))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if len(actions) != 5 {
+		t.Fatalf("expected 5 actions, got %d", len(actions))
+	}
+
+	// 2 + 2 during execution, 1 on final flush
+	if len(batches) != 3 || batches[0] != 2 || batches[1] != 2 || batches[2] != 1 {
+		t.Errorf("unexpected commit batches: %v", batches)
+	}
+}
@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// ChangeCapError is returned by Sync when a run would change more files or
+// bytes than SyncRequest.MaxChangedFiles/MaxChangedBytes allow. Nothing was
+// executed; the run must be approved (SyncRequest.ApproveChanges) to proceed.
+type ChangeCapError struct {
+	Files    int   // Files the run would change
+	Bytes    int64 // Bytes the run would transfer
+	MaxFiles int   // Configured cap (0 = no limit)
+	MaxBytes int64 // Configured cap (0 = no limit)
+}
+
+func (e *ChangeCapError) Error() string {
+	var exceeded []string
+	if e.MaxFiles > 0 && e.Files > e.MaxFiles {
+		exceeded = append(exceeded, fmt.Sprintf("%d files changed (limit %d)", e.Files, e.MaxFiles))
+	}
+	if e.MaxBytes > 0 && e.Bytes > e.MaxBytes {
+		exceeded = append(exceeded, fmt.Sprintf("%d bytes transferred (limit %d)", e.Bytes, e.MaxBytes))
+	}
+	return "approval required: " + strings.Join(exceeded, ", ")
+}
+
+// Unwrap lets errors.Is match ErrChangeCapExceeded.
+func (e *ChangeCapError) Unwrap() error {
+	return ErrChangeCapExceeded
+}
+
+// checkChangeCap returns a *ChangeCapError if the decisions exceed the caps of
+// the request. Placeholders created instead of downloads count as changed
+// files but transfer nothing.
+func checkChangeCap(req *SyncRequest, decisions []*cache.SyncDecision) error {
+	if req.ApproveChanges || (req.MaxChangedFiles <= 0 && req.MaxChangedBytes <= 0) {
+		return nil
+	}
+
+	capErr := &ChangeCapError{
+		Files:    len(decisions),
+		MaxFiles: req.MaxChangedFiles,
+		MaxBytes: req.MaxChangedBytes,
+	}
+	for _, d := range decisions {
+		switch {
+		case d.Action == cache.ActionUpload && d.LocalInfo != nil:
+			capErr.Bytes += d.LocalInfo.Size
+		case d.Action == cache.ActionDownload && d.RemoteInfo != nil && !req.FilesOnDemand:
+			capErr.Bytes += d.RemoteInfo.Size
+		}
+	}
+
+	if (capErr.MaxFiles > 0 && capErr.Files > capErr.MaxFiles) ||
+		(capErr.MaxBytes > 0 && capErr.Bytes > capErr.MaxBytes) {
+		return capErr
+	}
+	return nil
+}
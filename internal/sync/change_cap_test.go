@@ -0,0 +1,48 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+func TestCheckChangeCap(t *testing.T) {
+	decisions := []*cache.SyncDecision{
+		{LocalPath: "a.docx", Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Size: 600}},
+		{LocalPath: "b.docx", Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Size: 600}},
+		{LocalPath: "c.pdf", Action: cache.ActionDownload, RemoteInfo: &cache.FileInfo{Size: 1000}},
+		{LocalPath: "d.txt", Action: cache.ActionDeleteRemote},
+	}
+
+	tests := []struct {
+		name    string
+		req     SyncRequest
+		wantErr bool
+	}{
+		{"no cap", SyncRequest{}, false},
+		{"under file cap", SyncRequest{MaxChangedFiles: 4}, false},
+		{"over file cap", SyncRequest{MaxChangedFiles: 3}, true},
+		{"over byte cap", SyncRequest{MaxChangedBytes: 2000}, true},
+		{"placeholders transfer nothing", SyncRequest{MaxChangedBytes: 2000, FilesOnDemand: true}, false},
+		{"approved", SyncRequest{MaxChangedFiles: 1, ApproveChanges: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkChangeCap(&tt.req, decisions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if err == nil {
+				return
+			}
+			var capErr *ChangeCapError
+			if !errors.As(err, &capErr) || !errors.Is(err, ErrChangeCapExceeded) {
+				t.Fatalf("expected a ChangeCapError, got %T", err)
+			}
+			if capErr.Files != 4 || capErr.Bytes != 2200 {
+				t.Errorf("expected 4 files and 2200 bytes, got %d and %d", capErr.Files, capErr.Bytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+)
+
+// maxConflictCopies caps the numbered names tried for the copy of a conflict
+// ("report (conflict from PC1 2025-01-31) (2).docx"...).
+const maxConflictCopies = 100
+
+// numberedCopyPath returns the n-th name tried for a conflict copy (n >= 2
+// adds " (n)" before the extension).
+func numberedCopyPath(path string, n int) string {
+	if n < 2 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(path, ext), n, ext)
+}
+
+// keepLocalConflictCopy copies the local losing version of a conflict to
+// copyPath (numbered if taken) before a download overwrites it. The copy
+// keeps the modification time of the original. Returns the path of the copy.
+func keepLocalConflictCopy(path, copyPath string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open conflict loser: %w", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", fmt.Errorf("stat conflict loser: %w", err)
+	}
+
+	for n := 1; n <= maxConflictCopies; n++ {
+		target := numberedCopyPath(copyPath, n)
+		dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("create conflict copy: %w", err)
+		}
+
+		_, err = io.Copy(dst, src)
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(target)
+			return "", fmt.Errorf("write conflict copy: %w", err)
+		}
+		_ = os.Chtimes(target, info.ModTime(), info.ModTime())
+		return target, nil
+	}
+	return "", fmt.Errorf("no free name for the conflict copy %s", copyPath)
+}
+
+// keepRemoteConflictCopy renames the remote losing version of a conflict to
+// copyPath (numbered if taken) before an upload replaces it. Returns the path
+// of the copy.
+func keepRemoteConflictCopy(ctx context.Context, smbClient *smb.SMBClient, path, copyPath string) (string, error) {
+	for n := 1; n <= maxConflictCopies; n++ {
+		target := numberedCopyPath(copyPath, n)
+		if _, err := smbClient.GetMetadata(target); err == nil {
+			continue // Taken
+		}
+		if err := smbClient.RenameContext(ctx, path, target); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+	return "", fmt.Errorf("no free name for the conflict copy %s", copyPath)
+}
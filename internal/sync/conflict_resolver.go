@@ -2,10 +2,14 @@ package sync
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
 	"go.uber.org/zap"
 )
 
@@ -29,10 +33,23 @@ const (
 	ConflictResolutionKeepBoth ConflictResolutionPolicy = "keep_both"
 )
 
+// DefaultConflictNamePattern names the server copy kept by keep_both:
+// "report.docx" -> "report.server.docx"
+const DefaultConflictNamePattern = "{name}.server{ext}"
+
+// ConflictCopyNamePattern names the losing version of a conflict resolved by
+// "recent", kept next to the winner: "report.docx" -> "report (conflict from
+// PC1 2025-01-31).docx". {host} is the computer that resolved the conflict.
+const ConflictCopyNamePattern = "{name} (conflict from {host} {date}){ext}"
+
 // ConflictResolver resolves sync conflicts based on a policy
 type ConflictResolver struct {
-	policy ConflictResolutionPolicy
-	logger *zap.Logger
+	policy      ConflictResolutionPolicy
+	namePattern string      // Name of the server copy kept by keep_both
+	copies      bool        // Keep the losing version of "recent" conflicts
+	host        string      // This computer, for {host} in namePattern
+	clock       clock.Clock // Dates conflict copies
+	logger      *zap.Logger
 }
 
 // NewConflictResolver creates a new conflict resolver
@@ -45,12 +62,64 @@ func NewConflictResolver(policy string, logger *zap.Logger) (*ConflictResolver,
 		logger = zap.NewNop()
 	}
 
+	host, _ := os.Hostname()
+
 	return &ConflictResolver{
-		policy: ConflictResolutionPolicy(policy),
-		logger: logger,
+		policy:      ConflictResolutionPolicy(policy),
+		namePattern: DefaultConflictNamePattern,
+		copies:      true,
+		host:        host,
+		clock:       clock.System,
+		logger:      logger,
 	}, nil
 }
 
+// SetNamePattern sets how the server copy kept by keep_both is named.
+// Placeholders: {name} (file name without extension, required), {ext}
+// (extension with its dot), {host} (this computer), {date} (2006-01-02)
+// and {time} (150405). "" restores DefaultConflictNamePattern.
+func (cr *ConflictResolver) SetNamePattern(pattern string) error {
+	if pattern == "" {
+		pattern = DefaultConflictNamePattern
+	}
+	if err := ValidateConflictNamePattern(pattern); err != nil {
+		return err
+	}
+	cr.namePattern = pattern
+	return nil
+}
+
+// SetConflictCopies sets whether the "recent" policy keeps the losing version
+// of a conflict under ConflictCopyNamePattern (the default) rather than
+// overwriting it.
+func (cr *ConflictResolver) SetConflictCopies(enabled bool) {
+	cr.copies = enabled
+}
+
+// SetClock sets the clock dating conflict copies.
+func (cr *ConflictResolver) SetClock(c clock.Clock) {
+	cr.clock = clock.Or(c)
+}
+
+// ValidateConflictNamePattern checks that a conflict name pattern yields a
+// valid file name distinct from the original.
+func ValidateConflictNamePattern(pattern string) error {
+	if !strings.Contains(pattern, "{name}") {
+		return fmt.Errorf("conflict name pattern %q must contain {name}", pattern)
+	}
+	literal := pattern
+	for _, placeholder := range []string{"{name}", "{ext}", "{host}", "{date}", "{time}"} {
+		literal = strings.ReplaceAll(literal, placeholder, "")
+	}
+	if strings.TrimSpace(literal) == "" {
+		return fmt.Errorf("conflict name pattern %q must add text to the file name", pattern)
+	}
+	if strings.ContainsAny(literal, `\/:*?"<>|{}`) {
+		return fmt.Errorf("conflict name pattern %q contains characters not allowed in file names", pattern)
+	}
+	return nil
+}
+
 // ResolveConflicts processes a list of sync decisions and resolves conflicts
 // Returns:
 // - resolved: decisions that have been resolved
@@ -136,7 +205,8 @@ func (cr *ConflictResolver) resolveByMostRecent(decision *cache.SyncDecision) *c
 		NeedsResolution: false,
 	}
 
-	if localTime.After(remoteTime) {
+	// Times within the FAT/SMB tolerance are the same
+	if scanner.NewerMTime(localTime, remoteTime) {
 		// Local is newer - upload to remote
 		resolved.Action = cache.ActionUpload
 		resolved.Reason = fmt.Sprintf("conflict resolved: local newer (local: %s, remote: %s)",
@@ -148,7 +218,7 @@ func (cr *ConflictResolver) resolveByMostRecent(decision *cache.SyncDecision) *c
 			zap.Time("local_time", localTime),
 			zap.Time("remote_time", remoteTime),
 		)
-	} else if remoteTime.After(localTime) {
+	} else if scanner.NewerMTime(remoteTime, localTime) {
 		// Remote is newer - download to local
 		resolved.Action = cache.ActionDownload
 		resolved.Reason = fmt.Sprintf("conflict resolved: remote newer (local: %s, remote: %s)",
@@ -183,6 +253,17 @@ func (cr *ConflictResolver) resolveByMostRecent(decision *cache.SyncDecision) *c
 		)
 	}
 
+	// The loser is kept on its side, next to the winner, and synced to the
+	// other side by the next run
+	if cr.copies {
+		switch resolved.Action {
+		case cache.ActionUpload:
+			resolved.ConflictCopy = conflictCopyPath(decision.RemotePath, ConflictCopyNamePattern, cr.host, cr.clock.Now())
+		case cache.ActionDownload:
+			resolved.ConflictCopy = conflictCopyPath(decision.LocalPath, ConflictCopyNamePattern, cr.host, cr.clock.Now())
+		}
+	}
+
 	return resolved
 }
 
@@ -226,10 +307,11 @@ func (cr *ConflictResolver) resolveByRemote(decision *cache.SyncDecision) *cache
 	return resolved
 }
 
-// resolveByKeepBoth keeps both files by downloading server version with .server suffix
+// resolveByKeepBoth keeps both files by downloading server version under the
+// conflict name pattern
 func (cr *ConflictResolver) resolveByKeepBoth(decision *cache.SyncDecision) *cache.SyncDecision {
-	// Create renamed path: file.txt -> file.server.txt
-	renamedPath := addServerSuffix(decision.LocalPath)
+	// Create renamed path: file.txt -> file.server.txt (default pattern)
+	renamedPath := conflictCopyPath(decision.LocalPath, cr.namePattern, cr.host, cr.clock.Now())
 
 	resolved := &cache.SyncDecision{
 		LocalPath:       renamedPath, // Download to renamed path
@@ -351,16 +433,22 @@ func (cr *ConflictResolver) resolveModificationVsDeletion(decision *cache.SyncDe
 	return resolved
 }
 
-// addServerSuffix adds .server before the file extension
-// e.g., "document.pdf" -> "document.server.pdf"
-// e.g., "file" -> "file.server"
-func addServerSuffix(path string) string {
+// conflictCopyPath names the server copy of path after pattern
+// e.g., "document.pdf" -> "document.server.pdf" (default pattern)
+// e.g., "file" -> "file (conflict PC1 2025-01-31)" ("{name} (conflict {host} {date}){ext}")
+func conflictCopyPath(path, pattern, host string, now time.Time) string {
 	dir := filepath.Dir(path)
 	filename := filepath.Base(path)
 	ext := filepath.Ext(filename)
 	nameWithoutExt := strings.TrimSuffix(filename, ext)
 
-	newFilename := nameWithoutExt + ".server" + ext
+	newFilename := strings.NewReplacer(
+		"{name}", nameWithoutExt,
+		"{ext}", ext,
+		"{host}", sanitizeFileNamePart(host),
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+	).Replace(pattern)
 
 	if dir == "." {
 		return newFilename
@@ -368,6 +456,16 @@ func addServerSuffix(path string) string {
 	return filepath.Join(dir, newFilename)
 }
 
+// sanitizeFileNamePart replaces the characters not allowed in file names.
+func sanitizeFileNamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
 // GetPolicy returns the current conflict resolution policy
 func (cr *ConflictResolver) GetPolicy() ConflictResolutionPolicy {
 	return cr.policy
@@ -1,10 +1,13 @@
 package sync
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
 	"go.uber.org/zap"
 )
 
@@ -475,3 +478,125 @@ func TestSeparateConflictsEmpty(t *testing.T) {
 		t.Errorf("expected 0 normal, got %d", len(normal))
 	}
 }
+
+func TestConflictCopyPath(t *testing.T) {
+	now := time.Date(2025, 1, 31, 14, 5, 9, 0, time.Local)
+	tests := []struct {
+		path    string
+		pattern string
+		want    string
+	}{
+		{"document.pdf", DefaultConflictNamePattern, "document.server.pdf"},
+		{"file", DefaultConflictNamePattern, "file.server"},
+		{filepath.Join("docs", "report.docx"), "{name} (conflict {host} {date}){ext}",
+			filepath.Join("docs", "report (conflict PC_1 2025-01-31).docx")},
+		{"notes.txt", "{name}-{date}-{time}{ext}", "notes-2025-01-31-140509.txt"},
+	}
+	for _, tt := range tests {
+		if got := conflictCopyPath(tt.path, tt.pattern, "PC:1", now); got != tt.want {
+			t.Errorf("conflictCopyPath(%q, %q) = %q, want %q", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSetNamePattern(t *testing.T) {
+	resolver, _ := NewConflictResolver("keep_both", zap.NewNop())
+	for _, pattern := range []string{"{ext}.server", "{name}{ext}", "{name}/copy{ext}"} {
+		if err := resolver.SetNamePattern(pattern); err == nil {
+			t.Errorf("expected pattern %q rejected", pattern)
+		}
+	}
+	if err := resolver.SetNamePattern("{name} ({host}){ext}"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := resolver.SetNamePattern(""); err != nil || resolver.namePattern != DefaultConflictNamePattern {
+		t.Errorf("expected the default pattern, got %q (%v)", resolver.namePattern, err)
+	}
+}
+
+func TestResolveConflictsByRecent_ConflictCopy(t *testing.T) {
+	resolver, _ := NewConflictResolver("recent", zap.NewNop())
+	resolver.host = "PC1"
+	resolver.SetClock(clock.NewFake(time.Date(2025, 1, 31, 9, 0, 0, 0, time.Local)))
+
+	now := time.Now()
+	conflict := func(localMTime, remoteMTime time.Time) *cache.SyncDecision {
+		return &cache.SyncDecision{
+			LocalPath:       filepath.Join("C:", "job", "report.docx"),
+			RemotePath:      filepath.Join("share", "job", "report.docx"),
+			LocalInfo:       &cache.FileInfo{Path: "report.docx", Size: 100, MTime: localMTime},
+			RemoteInfo:      &cache.FileInfo{Path: "report.docx", Size: 110, MTime: remoteMTime},
+			NeedsResolution: true,
+		}
+	}
+
+	resolved, _ := resolver.ResolveConflicts([]*cache.SyncDecision{
+		conflict(now.Add(time.Minute), now), // Local wins: the remote version is kept
+		conflict(now, now.Add(time.Minute)), // Remote wins: the local version is kept
+	})
+	if want := filepath.Join("share", "job", "report (conflict from PC1 2025-01-31).docx"); resolved[0].ConflictCopy != want {
+		t.Errorf("upload ConflictCopy = %q, want %q", resolved[0].ConflictCopy, want)
+	}
+	if want := filepath.Join("C:", "job", "report (conflict from PC1 2025-01-31).docx"); resolved[1].ConflictCopy != want {
+		t.Errorf("download ConflictCopy = %q, want %q", resolved[1].ConflictCopy, want)
+	}
+
+	resolver.SetConflictCopies(false)
+	resolved, _ = resolver.ResolveConflicts([]*cache.SyncDecision{conflict(now.Add(time.Minute), now)})
+	if resolved[0].ConflictCopy != "" {
+		t.Errorf("expected no conflict copy when disabled, got %q", resolved[0].ConflictCopy)
+	}
+}
+
+func TestKeepLocalConflictCopy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.docx")
+	copyPath := filepath.Join(dir, "report (conflict from PC1 2025-01-31).docx")
+	mtime := time.Date(2025, 1, 30, 18, 0, 0, 0, time.Local)
+	if err := os.WriteFile(path, []byte("local version"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := keepLocalConflictCopy(path, copyPath)
+	if err != nil || first != copyPath {
+		t.Fatalf("keepLocalConflictCopy = %q, %v", first, err)
+	}
+	second, err := keepLocalConflictCopy(path, copyPath)
+	if want := filepath.Join(dir, "report (conflict from PC1 2025-01-31) (2).docx"); err != nil || second != want {
+		t.Fatalf("second copy = %q, %v, want %q", second, err, want)
+	}
+
+	data, _ := os.ReadFile(first)
+	info, _ := os.Stat(first)
+	if string(data) != "local version" || !info.ModTime().Equal(mtime) {
+		t.Errorf("copy should keep the content and mtime, got %q at %v", data, info.ModTime())
+	}
+}
+
+func TestConflictRecords(t *testing.T) {
+	req := &SyncRequest{JobID: 7, LocalPath: filepath.Join("C:", "job")}
+	result := &SyncResult{RunID: "run1", Actions: []*SyncAction{
+		{Action: cache.ActionUpload, Status: ActionStatusSuccess, FilePath: filepath.Join("C:", "job", "docs", "a.txt"),
+			ConflictCopy: "share/job/docs/a (conflict from PC1 2025-01-31).txt", ConflictOwner: `CORP\bob`},
+		{Action: cache.ActionDownload, Status: ActionStatusSuccess, FilePath: filepath.Join("C:", "job", "b.txt"),
+			ConflictCopy: filepath.Join("C:", "job", "b (conflict from PC1 2025-01-31).txt")},
+		{Action: cache.ActionDownload, Status: ActionStatusFailed, FilePath: filepath.Join("C:", "job", "c.txt"),
+			ConflictCopy: filepath.Join("C:", "job", "c (conflict from PC1 2025-01-31).txt")},
+		{Action: cache.ActionUpload, Status: ActionStatusSuccess, FilePath: filepath.Join("C:", "job", "d.txt")},
+	}}
+
+	records := conflictRecords(req, result)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if r := records[0]; r.JobID != 7 || r.RunID != "run1" || r.Path != "docs/a.txt" ||
+		r.CopyPath != "docs/a (conflict from PC1 2025-01-31).txt" || r.Winner != "local" || r.ChangedBy != `CORP\bob` {
+		t.Errorf("unexpected upload record: %+v", r)
+	}
+	if r := records[1]; r.Path != "b.txt" || r.CopyPath != "b (conflict from PC1 2025-01-31).txt" || r.Winner != "remote" {
+		t.Errorf("unexpected download record: %+v", r)
+	}
+}
@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// --- Action Dependencies ---
+//
+// Workers run the actions of a phase concurrently. Some actions must still
+// wait for others, on the same side (local or remote):
+//   - transfers into a folder the run creates wait for the one transfer that
+//     creates it, the smallest under the folder, instead of racing to create
+//     it;
+//   - deletions wait for the transfers of their side, so a moved file is
+//     written before the old one goes, but not for the transfers of the
+//     other side;
+//   - the deletion of a folder waits for the deletions inside it.
+//
+// Dependents run once their dependencies complete, whether they succeeded or
+// not: the order only avoids races.
+
+// actionGraph holds the dependencies between the decisions of a phase. Node i
+// is decision i; the two nodes after the decisions are barriers, completed once
+// every transfer of their side is.
+type actionGraph struct {
+	size       int     // Decisions (the barriers come after)
+	deps       [][]int // Node -> nodes it waits for
+	dependents [][]int // Node -> nodes waiting for it
+}
+
+// buildActionGraph returns the dependencies between decisions.
+func buildActionGraph(decisions []*cache.SyncDecision) *actionGraph {
+	n := len(decisions)
+	g := &actionGraph{
+		size:       n,
+		deps:       make([][]int, n+2),
+		dependents: make([][]int, n+2),
+	}
+	barrier := func(remote bool) int {
+		if remote {
+			return n + 1
+		}
+		return n
+	}
+
+	// The transfer creating each folder, per side (true = remote)
+	creators := map[bool]map[string]int{false: {}, true: {}}
+	// The deletion of each path, per side
+	deleted := map[bool]map[string]int{false: {}, true: {}}
+	for i, d := range decisions {
+		if path, remote, ok := writtenPath(d); ok {
+			for _, dir := range jobFolders(d, path) {
+				if c, ok := creators[remote][dir]; !ok || lighterTransfer(decisions, i, c) {
+					creators[remote][dir] = i
+				}
+			}
+		} else if path, remote, ok := deletedPath(d); ok {
+			deleted[remote][pathKey(path)] = i
+		}
+	}
+
+	for i, d := range decisions {
+		if path, remote, ok := writtenPath(d); ok {
+			g.addDep(barrier(remote), i)
+			// The creator of the nearest folder this transfer doesn't create:
+			// the creator of each folder is the lightest transfer under it,
+			// so dependencies always point to lighter transfers
+			for _, dir := range jobFolders(d, path) {
+				if c := creators[remote][dir]; c != i {
+					g.addDep(i, c)
+					break
+				}
+			}
+		} else if path, remote, ok := deletedPath(d); ok {
+			g.addDep(i, barrier(remote))
+			for dir := parentKey(pathKey(path)); dir != ""; dir = parentKey(dir) {
+				if parent, ok := deleted[remote][dir]; ok {
+					g.addDep(parent, i)
+					break
+				}
+			}
+		}
+	}
+	return g
+}
+
+// addDep records that node waits for dep.
+func (g *actionGraph) addDep(node, dep int) {
+	g.deps[node] = append(g.deps[node], dep)
+	g.dependents[dep] = append(g.dependents[dep], node)
+}
+
+// order returns the decisions in an order respecting their dependencies,
+// otherwise keeping their order.
+func (g *actionGraph) order() []int {
+	order := make([]int, 0, g.size)
+	visited := make([]bool, len(g.deps))
+	var visit func(node int)
+	visit = func(node int) {
+		if visited[node] {
+			return
+		}
+		visited[node] = true
+		for _, dep := range g.deps[node] {
+			visit(dep)
+		}
+		if node < g.size {
+			order = append(order, node)
+		}
+	}
+	for i := 0; i < g.size; i++ {
+		visit(i)
+	}
+	return order
+}
+
+// graphRun tracks the decisions of a graph ready to run as others complete.
+// Not safe for concurrent use, except for receiving from ready.
+type graphRun struct {
+	graph   *actionGraph
+	pending []int    // Node -> dependencies not completed yet
+	ready   chan int // Decisions whose dependencies are completed
+}
+
+// start returns a run of the graph, with the decisions free of dependencies
+// ready, in order.
+func (g *actionGraph) start() *graphRun {
+	r := &graphRun{
+		graph:   g,
+		pending: make([]int, len(g.deps)),
+		ready:   make(chan int, g.size), // Each decision is ready once: never blocks
+	}
+	var roots []int
+	for node, deps := range g.deps {
+		r.pending[node] = len(deps)
+		if len(deps) == 0 {
+			roots = append(roots, node)
+		}
+	}
+	for _, node := range roots {
+		r.release(node)
+	}
+	return r
+}
+
+// done marks a decision completed, making ready the decisions waiting only
+// for it.
+func (r *graphRun) done(node int) {
+	for _, dependent := range r.graph.dependents[node] {
+		if r.pending[dependent]--; r.pending[dependent] == 0 {
+			r.release(dependent)
+		}
+	}
+}
+
+// release makes a node ready; barriers complete at once.
+func (r *graphRun) release(node int) {
+	if node >= r.graph.size {
+		r.done(node)
+		return
+	}
+	r.ready <- node
+}
+
+// jobFolders returns the folders of a written path inside the job, nearest
+// first, as path keys (none for files at the job root or without state).
+func jobFolders(d *cache.SyncDecision, path string) []string {
+	info := transferSource(d)
+	if info == nil {
+		return nil
+	}
+	depth := strings.Count(strings.Trim(strings.ReplaceAll(info.Path, `\`, "/"), "/"), "/")
+
+	var dirs []string
+	for dir := parentKey(pathKey(path)); dir != "" && len(dirs) < depth; dir = parentKey(dir) {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// lighterTransfer reports whether decision i transfers less than decision j
+// (the earlier one on ties).
+func lighterTransfer(decisions []*cache.SyncDecision, i, j int) bool {
+	si, sj := transferSource(decisions[i]).Size, transferSource(decisions[j]).Size
+	if si != sj {
+		return si < sj
+	}
+	return i < j
+}
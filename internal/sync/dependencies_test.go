@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// dependencyDecisions returns uploads into a new folder, the deletion of a
+// remote folder and its file (folder first), and a local deletion.
+func dependencyDecisions() []*cache.SyncDecision {
+	upload := func(relPath string, size int64) *cache.SyncDecision {
+		d := orderingDecision(cache.ActionUpload, relPath)
+		d.LocalInfo = &cache.FileInfo{Path: relPath, Size: size}
+		return d
+	}
+	return []*cache.SyncDecision{
+		upload("Photos/a.jpg", 100),
+		upload("Photos/b.jpg", 10),
+		orderingDecision(cache.ActionDeleteRemote, "Old"),
+		orderingDecision(cache.ActionDeleteRemote, "Old/x.txt"),
+		orderingDecision(cache.ActionDeleteLocal, "tmp.txt"),
+		upload("notes.txt", 1), // At the job root: creates no folder
+	}
+}
+
+func TestActionGraphOrder(t *testing.T) {
+	got := buildActionGraph(dependencyDecisions()).order()
+	// b.jpg creates Photos before a.jpg; the remote deletions wait for every
+	// upload, Old/x.txt before Old
+	want := []int{1, 0, 5, 3, 2, 4}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestActionGraphRun(t *testing.T) {
+	run := buildActionGraph(dependencyDecisions()).start()
+	ready := func() []int {
+		var nodes []int
+		for len(run.ready) > 0 {
+			nodes = append(nodes, <-run.ready)
+		}
+		return nodes
+	}
+	step := func(name string, want ...int) {
+		t.Helper()
+		if got := ready(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ready = %v, want %v", name, got, want)
+		}
+	}
+
+	// The local deletion doesn't wait for remote transfers
+	step("start", 1, 5, 4)
+	run.done(4)
+	step("local deletion done")
+	run.done(1)
+	step("folder created", 0)
+	run.done(0)
+	step("a.jpg done")
+	run.done(5)
+	step("remote transfers done", 3)
+	run.done(3)
+	step("folder emptied", 2)
+}
@@ -3,11 +3,16 @@ package sync
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/metrics"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
 	"go.uber.org/zap"
 )
@@ -19,19 +24,32 @@ type Engine struct {
 	logger *zap.Logger
 
 	// Components
-	scanner  *scanner.Scanner
-	cache    *cache.CacheManager
-	detector *cache.ChangeDetector
-	executor *Executor
+	scanner  Scanner
+	cache    CacheManager
+	detector ChangeDetector
+	executor ActionExecutor
+
+	// uploadHook checks files before upload (nil = disabled)
+	uploadHook UploadHook
+
+	// fileTypes holds the rules by file type, e.g. types never uploaded (nil = none)
+	fileTypes *filetype.Policy
+
+	// transferOrder is the order of the transfers of a run, recorded in its result
+	transferOrder TransferOrder
+
+	// clock dates runs, conflict copies and transfers (a fake one in tests)
+	clock clock.Clock
 
 	// State
 	mu      sync.RWMutex
-	syncing map[int64]context.CancelFunc // Maps job ID to cancel function
+	syncing map[int64]*runningSync // Syncs in progress by job ID
 	closed  bool
 }
 
-// NewEngine creates a new sync engine
-func NewEngine(cfg *config.Config, db *database.DB, logger *zap.Logger) (*Engine, error) {
+// NewEngine creates a new sync engine. Options replace its default components
+// (scanner, cache manager, change detector, executor).
+func NewEngine(cfg *config.Config, db *database.DB, logger *zap.Logger, opts ...EngineOption) (*Engine, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config cannot be nil")
 	}
@@ -42,33 +60,94 @@ func NewEngine(cfg *config.Config, db *database.DB, logger *zap.Logger) (*Engine
 		logger = zap.NewNop()
 	}
 
-	// Create scanner
-	scan, err := scanner.NewScanner(cfg, db, logger.Named("scanner"))
+	// Create upload hook (antivirus scan before upload)
+	uploadHook, err := NewUploadHook(cfg.Security.UploadScan, logger.Named("upload_scan"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload scan hook: %w", err)
+	}
+
+	// Rules by file type
+	fileTypes, err := filetype.NewPolicy(cfg.Sync.FileTypes.NeverDehydrate,
+		cfg.Sync.FileTypes.Compress, cfg.Sync.FileTypes.ExcludeUpload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file type policy: %w", err)
+	}
+
+	// Transfer order (an invalid setting falls back to the default order)
+	perf := cfg.Sync.Performance
+	transferOrder, err := NewTransferOrder(perf.TransferOrder, perf.FolderPriority)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create scanner: %w", err)
+		logger.Warn("invalid transfer order, using default", zap.Error(err))
+		transferOrder = TransferOrder{Strategy: TransferOrderDefault}
 	}
 
-	// Create cache manager
+	e := &Engine{
+		db:      db,
+		config:  cfg,
+		logger:  logger,
+		syncing: make(map[int64]*runningSync),
+		closed:  false,
+
+		uploadHook:    uploadHook,
+		fileTypes:     fileTypes,
+		transferOrder: transferOrder,
+		clock:         clock.System,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	// Create scanner
+	if e.scanner == nil {
+		scan, err := scanner.NewScanner(cfg, db, logger.Named("scanner"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scanner: %w", err)
+		}
+		e.scanner = scan
+	}
+
+	// Create cache manager (the default change detector always reads the database cache)
 	cacheManager := cache.NewCacheManager(db, logger.Named("cache"))
+	if e.cache == nil {
+		e.cache = cacheManager
+	}
 
 	// Create change detector
-	changeDetector := cache.NewChangeDetector(cacheManager, logger.Named("detector"))
+	if e.detector == nil {
+		e.detector = cache.NewChangeDetector(cacheManager, logger.Named("detector"))
+	}
 
 	// Create executor
-	bufferSizeMB := cfg.Sync.Performance.BufferSizeMB
-	executor := NewExecutor(bufferSizeMB, logger.Named("executor"))
-
-	return &Engine{
-		db:       db,
-		config:   cfg,
-		logger:   logger,
-		scanner:  scan,
-		cache:    cacheManager,
-		detector: changeDetector,
-		executor: executor,
-		syncing:  make(map[int64]context.CancelFunc),
-		closed:   false,
-	}, nil
+	if e.executor == nil {
+		bufferSizeMB := cfg.Sync.Performance.BufferSizeMB
+		executor := NewExecutor(bufferSizeMB, logger.Named("executor"))
+		executor.SetRetryPolicy(retryPolicy(cfg.Sync.Retry, logger.Named("executor").Named("retry")))
+		executor.SetBackpressure(cfg.Sync.Performance.QueueSize, cfg.Sync.Performance.MaxInFlightMB)
+		// Actions run one at a time unless parallel execution is enabled
+		if cfg.Sync.Performance.ParallelExecution {
+			executor.SetParallelMode(cfg.Sync.Performance.ParallelTransfers)
+			if cfg.Sync.Performance.AdaptiveTransfers {
+				executor.SetAdaptiveParallelism(adaptiveParallelism(cfg.Sync.Performance))
+			}
+		}
+		executor.SetSmallFileBatch(smallFileBatch(cfg.Sync.Performance))
+		executor.SetParallelTransfer(parallelTransfer(cfg.Sync.Performance))
+		if cfg.Sync.SharedShare {
+			executor.SetRemoteOwners(true)
+		}
+		executor.SetSIDMap(cfg.Sync.ACLSIDMap)
+		executor.SetTransferOrder(transferOrder)
+		executor.SetClock(e.clock)
+		executor.SetBandwidthLimits(BandwidthLimits{
+			UploadKBps:      cfg.Sync.Performance.MaxUploadKBps,
+			DownloadKBps:    cfg.Sync.Performance.MaxDownloadKBps,
+			MeteredKBps:     cfg.Sync.Performance.MeteredKBps,
+			ThrottleMetered: cfg.Sync.Performance.ThrottleMetered,
+		})
+		e.executor = executor
+	}
+
+	return e, nil
 }
 
 // Sync performs a synchronization for the given request
@@ -77,6 +156,15 @@ func (e *Engine) Sync(ctx context.Context, req *SyncRequest) (*SyncResult, error
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid sync request: %w", err)
 	}
+	req.Subtree, _ = NormalizeSubtree(req.Subtree)
+
+	// Tag every log line of the run, from the engine down to the SMB client
+	runID := correlation.NewRunID()
+	ctx = correlation.WithRunID(ctx, runID)
+	if req.JobLog {
+		correlation.StartJobLog(runID, req.JobID)
+		defer correlation.EndJobLog(runID)
+	}
 
 	// Check if engine is closed
 	e.mu.RLock()
@@ -95,8 +183,17 @@ func (e *Engine) Sync(ctx context.Context, req *SyncRequest) (*SyncResult, error
 
 	// Create cancellable context
 	syncCtx, cancel := context.WithCancel(ctx)
-	e.syncing[req.JobID] = cancel
+	e.syncing[req.JobID] = &runningSync{
+		cancel: cancel,
+		info: RunningSync{
+			JobID:     req.JobID,
+			RunID:     runID,
+			Subtree:   req.Subtree,
+			StartedAt: e.clock.Now(),
+		},
+	}
 	e.mu.Unlock()
+	metrics.SyncsStarted.Inc()
 
 	// Ensure cleanup
 	defer func() {
@@ -107,26 +204,39 @@ func (e *Engine) Sync(ctx context.Context, req *SyncRequest) (*SyncResult, error
 
 	// Initialize result
 	result := NewSyncResult(req.JobID)
+	result.Subtree = req.Subtree
+	result.RunID = runID
+	result.TransferOrder = e.transferOrder.String()
 
-	e.logger.Info("starting sync",
+	e.log(ctx).Info("starting sync",
 		zap.Int64("job_id", req.JobID),
 		zap.String("mode", string(req.Mode)),
 		zap.String("local_path", req.LocalPath),
 		zap.String("remote_path", req.RemotePath),
+		zap.String("subtree", req.Subtree),
 	)
 
 	// Execute sync phases
 	if err := e.executeSync(syncCtx, req, result); err != nil {
-		e.logger.Error("sync failed", zap.Error(err))
+		e.log(ctx).Error("sync failed", zap.Error(err))
 		result.Status = SyncStatusFailed
 		result.Finalize()
+		metrics.SyncsFailed.Inc()
 		return result, err
 	}
 
 	// Finalize result
 	result.Finalize()
+	switch result.Status {
+	case SyncStatusSuccess:
+		metrics.SyncsSucceeded.Inc()
+	case SyncStatusPartial:
+		metrics.SyncsPartial.Inc()
+	default:
+		metrics.SyncsFailed.Inc()
+	}
 
-	e.logger.Info("sync completed",
+	e.log(ctx).Info("sync completed",
 		zap.Int64("job_id", req.JobID),
 		zap.String("status", string(result.Status)),
 		zap.Int("uploaded", result.FilesUploaded),
@@ -135,6 +245,7 @@ func (e *Engine) Sync(ctx context.Context, req *SyncRequest) (*SyncResult, error
 		zap.Int("deleted", result.FilesDeleted),
 		zap.Int("errors", result.FilesError),
 		zap.Int("conflicts", result.ConflictsFound),
+		zap.String("transfer_order", result.TransferOrder),
 		zap.Duration("duration", result.Duration),
 	)
 
@@ -156,6 +267,11 @@ func (e *Engine) executeSync(ctx context.Context, req *SyncRequest, result *Sync
 	}
 	defer smbClient.Disconnect()
 
+	// Resume an interrupted run from its saved plan instead of rescanning
+	if pending := e.loadResumePlan(ctx, req, smbClient); pending != nil {
+		return e.resumeSync(ctx, req, result, smbClient, job, pending)
+	}
+
 	// Phase 2: Scanning
 	e.reportProgress(req, &SyncProgress{
 		Phase:      "scanning",
@@ -189,11 +305,22 @@ func (e *Engine) executeSync(ctx context.Context, req *SyncRequest, result *Sync
 		result.AddConflict(conflict)
 	}
 
-	e.logger.Info("change detection completed",
+	e.log(ctx).Info("change detection completed",
 		zap.Int("actions", len(decisions)),
 		zap.Int("conflicts", len(conflicts)),
 	)
 
+	// Stop before changing anything when the run exceeds its safety cap
+	// or looks like ransomware encrypting the local files
+	if !req.DryRun {
+		if err := checkChangeCap(req, decisions); err != nil {
+			return err
+		}
+		if err := e.checkRansomware(req, decisions); err != nil {
+			return err
+		}
+	}
+
 	// Phase 4: Execution
 	if len(decisions) > 0 && !req.DryRun {
 		e.reportProgress(req, &SyncProgress{
@@ -221,7 +348,7 @@ func (e *Engine) executeSync(ctx context.Context, req *SyncRequest, result *Sync
 
 		// Create placeholders for downloads in Files On Demand mode
 		if len(downloadDecisions) > 0 {
-			e.logger.Info("creating placeholders instead of downloading (Files On Demand mode)",
+			e.log(ctx).Info("creating placeholders instead of downloading (Files On Demand mode)",
 				zap.Int("count", len(downloadDecisions)),
 			)
 
@@ -238,19 +365,41 @@ func (e *Engine) executeSync(ctx context.Context, req *SyncRequest, result *Sync
 			// Call placeholder callback
 			created, err := req.PlaceholderCallback(placeholderFiles)
 			if err != nil {
-				e.logger.Error("failed to create placeholders", zap.Error(err))
+				e.log(ctx).Error("failed to create placeholders", zap.Error(err))
 				// Continue with other actions
 			} else {
 				result.PlaceholdersCreated = created
-				e.logger.Info("placeholders created",
+				e.log(ctx).Info("placeholders created",
 					zap.Int("count", created),
 				)
 			}
 		}
 
+		// Hold back application files still being written, drop the uploads
+		// of excluded file types, then check the others with the upload hook
+		// before executing anything
+		otherDecisions, deferred := e.deferBusyGroups(ctx, req, otherDecisions, localFiles, remoteFiles)
+		for _, action := range deferred {
+			result.AddAction(action)
+		}
+		otherDecisions, excluded := e.excludeFileTypes(ctx, req, otherDecisions)
+		for _, action := range excluded {
+			result.AddAction(action)
+		}
+		otherDecisions, screened, err := e.screenUploads(ctx, req, otherDecisions)
+		if err != nil {
+			return fmt.Errorf("upload scan failed: %w", err)
+		}
+		for _, action := range screened {
+			result.AddAction(action)
+			if action.Status == ActionStatusFailed {
+				result.AddError(NewSyncError(action.FilePath, "scan", action.Error, 1))
+			}
+		}
+
 		// Execute non-download actions (uploads, deletes)
 		if len(otherDecisions) > 0 {
-			actions, err := e.executeActions(ctx, req, otherDecisions, smbClient, job)
+			actions, err := e.executeActions(ctx, req, otherDecisions, smbClient, job, remoteFiles)
 			if err != nil {
 				return fmt.Errorf("execution failed: %w", err)
 			}
@@ -259,13 +408,13 @@ func (e *Engine) executeSync(ctx context.Context, req *SyncRequest, result *Sync
 			for _, action := range actions {
 				result.AddAction(action)
 				if action.Error != nil {
-					syncErr := NewSyncError(action.FilePath, string(action.Action), action.Error, 1)
+					syncErr := NewSyncError(action.FilePath, string(action.Action), action.Error, action.Attempts)
 					result.AddError(syncErr)
 				}
 			}
 		}
 	} else if req.DryRun {
-		e.logger.Info("dry run mode - skipping execution",
+		e.log(ctx).Info("dry run mode - skipping execution",
 			zap.Int("actions", len(decisions)),
 		)
 	}
@@ -278,15 +427,22 @@ func (e *Engine) executeSync(ctx context.Context, req *SyncRequest, result *Sync
 	})
 
 	if err := e.finalizeSync(ctx, req, result, job, localFiles, remoteFiles); err != nil {
-		e.logger.Error("finalization failed", zap.Error(err))
+		e.log(ctx).Error("finalization failed", zap.Error(err))
 		// Don't return error, sync already completed
 	}
 
 	return nil
 }
 
-// reportProgress reports progress to callback if provided
+// log returns the engine logger with the correlation IDs of ctx.
+func (e *Engine) log(ctx context.Context) *zap.Logger {
+	return correlation.Logger(ctx, e.logger)
+}
+
+// reportProgress records progress for ListRunning and reports it to the
+// callback if provided
 func (e *Engine) reportProgress(req *SyncRequest, progress *SyncProgress) {
+	e.trackProgress(req.JobID, progress)
 	if req.ProgressCallback != nil {
 		req.ProgressCallback(progress)
 	}
@@ -305,12 +461,12 @@ func (e *Engine) CancelSync(jobID int64) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	cancel, exists := e.syncing[jobID]
+	run, exists := e.syncing[jobID]
 	if !exists {
 		return ErrSyncNotFound
 	}
 
-	cancel()
+	run.cancel()
 	return nil
 }
 
@@ -324,9 +480,16 @@ func (e *Engine) Close() error {
 	}
 
 	// Cancel all running syncs
-	for jobID, cancel := range e.syncing {
+	for jobID, run := range e.syncing {
 		e.logger.Info("cancelling sync on close", zap.Int64("job_id", jobID))
-		cancel()
+		run.cancel()
+	}
+
+	// Release the upload hook (e.g. AMSI context)
+	if closer, ok := e.uploadHook.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			e.logger.Warn("failed to close upload hook", zap.Error(err))
+		}
 	}
 
 	e.closed = true
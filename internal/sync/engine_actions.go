@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -39,6 +40,18 @@ func (e *Engine) prepareSync(ctx context.Context, req *SyncRequest) (*smb.SMBCli
 		return nil, nil, fmt.Errorf("failed to create SMB client: %w", err)
 	}
 
+	// Remote timestamp source: from the request, or from the server settings
+	mtimeSource := req.RemoteMTimeSource
+	if mtimeSource == "" {
+		if srv, err := e.db.GetSMBServerByHost(server); err == nil && srv != nil {
+			mtimeSource = smb.MTimeSource(srv.MTimeSource)
+		}
+	}
+	smbClient.SetMTimeSource(mtimeSource)
+	smbClient.SetParallelTransfer(parallelTransfer(e.config.Sync.Performance))
+	smbClient.SetChunkAlignment(int64(e.config.Sync.Performance.ChunkAlignmentMB) * 1024 * 1024)
+	smbClient.SetPreserveTimes(e.config.Sync.PreserveTimestamps)
+
 	// Connect to SMB server
 	if err := smbClient.Connect(); err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to SMB server: %w", err)
@@ -53,7 +66,7 @@ func (e *Engine) prepareSync(ctx context.Context, req *SyncRequest) (*smb.SMBCli
 		return nil, nil, fmt.Errorf("failed to update job status: %w", err)
 	}
 
-	e.logger.Info("preparation completed",
+	e.log(ctx).Info("preparation completed",
 		zap.String("server", server),
 		zap.String("share", share),
 	)
@@ -108,7 +121,7 @@ func (e *Engine) detectChanges(ctx context.Context, req *SyncRequest,
 		}
 	}
 
-	e.logger.Info("initial change detection completed",
+	e.log(ctx).Info("initial change detection completed",
 		zap.Int("total_decisions", len(allDecisions)),
 		zap.Int("executable", len(decisions)),
 		zap.Int("conflicts", len(initialConflicts)),
@@ -116,14 +129,23 @@ func (e *Engine) detectChanges(ctx context.Context, req *SyncRequest,
 
 	// Resolve conflicts if there are any and a resolution policy is set
 	if len(initialConflicts) > 0 && req.ConflictResolution != "" {
-		resolver, err := NewConflictResolver(req.ConflictResolution, e.logger.Named("conflict_resolver"))
+		resolver, err := NewConflictResolver(req.ConflictResolution, e.log(ctx).Named("conflict_resolver"))
 		if err != nil {
-			e.logger.Warn("failed to create conflict resolver",
+			e.log(ctx).Warn("failed to create conflict resolver",
 				zap.Error(err),
 				zap.String("policy", req.ConflictResolution),
 			)
 			conflicts = initialConflicts
 		} else {
+			resolver.SetClock(e.clock)
+			if err := resolver.SetNamePattern(e.config.Sync.ConflictNamePattern); err != nil {
+				e.log(ctx).Warn("invalid conflict name pattern, using default",
+					zap.Error(err),
+					zap.String("default", DefaultConflictNamePattern),
+				)
+			}
+			resolver.SetConflictCopies(e.config.Sync.ConflictCopies)
+
 			// Attempt to resolve conflicts
 			resolved, unresolved := resolver.ResolveConflicts(initialConflicts)
 
@@ -131,7 +153,7 @@ func (e *Engine) detectChanges(ctx context.Context, req *SyncRequest,
 			decisions = append(decisions, resolved...)
 			conflicts = unresolved
 
-			e.logger.Info("conflict resolution applied",
+			e.log(ctx).Info("conflict resolution applied",
 				zap.Int("initial_conflicts", len(initialConflicts)),
 				zap.Int("resolved", len(resolved)),
 				zap.Int("unresolved", len(unresolved)),
@@ -144,8 +166,11 @@ func (e *Engine) detectChanges(ctx context.Context, req *SyncRequest,
 
 	// Filter decisions based on sync mode
 	decisions = e.filterDecisionsByMode(req.Mode, decisions)
+	if req.DownloadOnly {
+		decisions = downloadsOnly(decisions)
+	}
 
-	e.logger.Info("change detection completed",
+	e.log(ctx).Info("change detection completed",
 		zap.Int("total_decisions", len(allDecisions)),
 		zap.Int("executable", len(decisions)),
 		zap.Int("final_conflicts", len(conflicts)),
@@ -170,6 +195,14 @@ func (e *Engine) filterDecisionsByMode(mode SyncMode, decisions []*cache.SyncDec
 			include = mode.AllowsDownload() // Only delete local if we can sync from remote
 		case cache.ActionDeleteRemote:
 			include = mode.AllowsUpload() // Only delete remote if we can sync to remote
+		case cache.ActionSetAttrLocal:
+			include = mode.AllowsDownload()
+		case cache.ActionSetAttrRemote:
+			include = mode.AllowsUpload()
+		case cache.ActionRenameLocal:
+			include = mode.AllowsDownload()
+		case cache.ActionRenameRemote:
+			include = mode.AllowsUpload()
 		default:
 			include = false
 		}
@@ -182,9 +215,26 @@ func (e *Engine) filterDecisionsByMode(mode SyncMode, decisions []*cache.SyncDec
 	return filtered
 }
 
+// downloadsOnly keeps the downloads of decisions.
+func downloadsOnly(decisions []*cache.SyncDecision) []*cache.SyncDecision {
+	filtered := make([]*cache.SyncDecision, 0, len(decisions))
+	for _, decision := range decisions {
+		if decision.Action == cache.ActionDownload {
+			filtered = append(filtered, decision)
+		}
+	}
+	return filtered
+}
+
 // executeActions handles Phase 4: Execution
+// Successful actions are committed to the cache in batches while executing.
 func (e *Engine) executeActions(ctx context.Context, req *SyncRequest,
-	decisions []*cache.SyncDecision, smbClient *smb.SMBClient, job *database.SyncJob) ([]*SyncAction, error) {
+	decisions []*cache.SyncDecision, smbClient *smb.SMBClient, job *database.SyncJob,
+	remoteFiles map[string]*cache.FileInfo) ([]*SyncAction, error) {
+
+	// Save the plan (with relative paths) so an interrupted run can resume it
+	plan := e.saveResumePlan(ctx, req, decisions)
+	e.logRetriedFiles(ctx, req, decisions)
 
 	// Convert relative paths to absolute/full paths for execution
 	// LocalPath needs to be absolute for file operations (e.g., D:/SYNC/file.txt)
@@ -205,6 +255,14 @@ func (e *Engine) executeActions(ctx context.Context, req *SyncRequest,
 		if remoteBasePath != "" && !strings.HasPrefix(decision.RemotePath, remoteBasePath) {
 			decision.RemotePath = remoteBasePath + "/" + decision.RemotePath
 		}
+
+		// Same for the old paths of a renamed file
+		if decision.OldLocalPath != "" && !filepath.IsAbs(decision.OldLocalPath) {
+			decision.OldLocalPath = filepath.Join(localBasePath, decision.OldLocalPath)
+		}
+		if decision.OldRemotePath != "" && remoteBasePath != "" && !strings.HasPrefix(decision.OldRemotePath, remoteBasePath) {
+			decision.OldRemotePath = remoteBasePath + "/" + decision.OldRemotePath
+		}
 	}
 
 	// Create progress callback
@@ -212,11 +270,34 @@ func (e *Engine) executeActions(ctx context.Context, req *SyncRequest,
 		e.reportProgress(req, progress)
 	}
 
-	// Execute using executor
-	actions, err := e.executor.Execute(ctx, decisions, smbClient, progressFn)
+	// Commit completed actions incrementally so a crash doesn't lose the whole run
+	commitFn := func(batch []*SyncAction) error {
+		if err := e.updateCacheFromActions(req.JobID, req.LocalPath, batch, remoteFiles); err != nil {
+			return err
+		}
+		plan.markDone(batch)
+		// Audited as committed, so interrupted and failed runs are audited too
+		if err := e.recordAudit(ctx, req, batch); err != nil {
+			e.log(ctx).Warn("failed to record audit log", zap.Error(err))
+		}
+		return nil
+	}
+
+	// Execute using executor (folder priorities are relative to the job root)
+	ctx = withTransferRoot(ctx, localBasePath)
+	ctx = withJobBandwidth(ctx, req)
+	ctx = withVerifyTransfers(ctx, req)
+	ctx = withSyncACLs(ctx, req)
+	ctx = withSyncStreams(ctx, req)
+	ctx, shadows := withShadowCopies(ctx, req)
+	defer shadows.release(e.log(ctx))
+	actions, err := e.executor.ExecuteWithCommit(ctx, decisions, smbClient, progressFn, commitFn)
 	if err != nil {
 		return nil, fmt.Errorf("execution failed: %w", err)
 	}
+	if ctx.Err() == nil {
+		plan.finish()
+	}
 
 	return actions, nil
 }
@@ -224,16 +305,12 @@ func (e *Engine) executeActions(ctx context.Context, req *SyncRequest,
 // finalizeSync handles Phase 5: Finalization
 func (e *Engine) finalizeSync(ctx context.Context, req *SyncRequest, result *SyncResult, job *database.SyncJob,
 	localFiles, remoteFiles map[string]*cache.FileInfo) error {
-	// Update cache for successful actions
+	// Cache for successful actions is already committed during execution
 	if !req.DryRun {
-		if err := e.updateCacheFromActions(req.JobID, req.LocalPath, result.Actions); err != nil {
-			return fmt.Errorf("failed to update cache: %w", err)
-		}
-
 		// Initialize cache for files that are already in sync (exist on both sides with same content)
 		// This is critical for bidirectional sync to detect remote deletions correctly
 		if err := e.initializeCacheForInSyncFiles(req.JobID, localFiles, remoteFiles); err != nil {
-			e.logger.Warn("failed to initialize cache for in-sync files", zap.Error(err))
+			e.log(ctx).Warn("failed to initialize cache for in-sync files", zap.Error(err))
 			// Non-fatal error, continue
 		}
 	}
@@ -248,12 +325,33 @@ func (e *Engine) finalizeSync(ctx context.Context, req *SyncRequest, result *Syn
 		Duration:         int(result.Duration.Seconds()),
 		Status:           string(result.Status),
 		ErrorSummary:     formatErrorSummary(result.Errors),
+		RunID:            result.RunID,
 	}
 
 	if err := e.db.InsertSyncHistory(history); err != nil {
 		return fmt.Errorf("failed to insert sync history: %w", err)
 	}
 
+	// Record executed actions with their correlation IDs
+	if !req.DryRun {
+		if err := e.db.InsertSyncActions(actionRecords(req, result)); err != nil {
+			e.log(ctx).Warn("failed to record sync actions", zap.Error(err))
+			// Non-fatal error, continue
+		}
+		if err := e.db.InsertConflicts(conflictRecords(req, result)); err != nil {
+			e.log(ctx).Warn("failed to record conflict copies", zap.Error(err))
+			// Non-fatal error, continue
+		}
+		if err := e.recordFailedFiles(ctx, req, result); err != nil {
+			e.log(ctx).Warn("failed to record failed files", zap.Error(err))
+			// Non-fatal error, continue
+		}
+		if err := e.recordRemoteUsage(req, remoteFiles); err != nil {
+			e.log(ctx).Warn("failed to record remote usage", zap.Error(err))
+			// Non-fatal error, continue
+		}
+	}
+
 	// Update job status
 	var finalStatus string
 	switch result.Status {
@@ -274,12 +372,63 @@ func (e *Engine) finalizeSync(ctx context.Context, req *SyncRequest, result *Syn
 		return fmt.Errorf("failed to update job last run: %w", err)
 	}
 
-	e.logger.Info("finalization completed")
+	e.log(ctx).Info("finalization completed")
 	return nil
 }
 
-// updateCacheFromActions updates cache based on successful actions
-func (e *Engine) updateCacheFromActions(jobID int64, localBasePath string, actions []*SyncAction) error {
+// actionRecords converts the executed actions of a run to sync_actions records.
+func actionRecords(req *SyncRequest, result *SyncResult) []*database.SyncActionRecord {
+	records := make([]*database.SyncActionRecord, 0, len(result.Actions))
+	for _, action := range result.Actions {
+		record := &database.SyncActionRecord{
+			JobID:     req.JobID,
+			RunID:     result.RunID,
+			OpID:      action.OpID,
+			Action:    string(action.Action),
+			Path:      toRelativePath(action.FilePath, req.LocalPath),
+			Status:    string(action.Status),
+			Bytes:     action.BytesTransferred,
+			ChangedBy: action.ChangedBy,
+			Timestamp: action.Timestamp,
+		}
+		if action.Error != nil {
+			record.Error = action.Error.Error()
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// conflictRecords lists the conflicts of a run whose losing version was kept,
+// with job-relative paths. The copy sits next to the file it was taken from.
+func conflictRecords(req *SyncRequest, result *SyncResult) []*database.ConflictRecord {
+	var records []*database.ConflictRecord
+	for _, action := range result.Actions {
+		if action.ConflictCopy == "" || action.Status != ActionStatusSuccess {
+			continue
+		}
+		relPath := toRelativePath(action.FilePath, req.LocalPath)
+		winner := "local"
+		if action.Action == cache.ActionDownload {
+			winner = "remote"
+		}
+		records = append(records, &database.ConflictRecord{
+			JobID:     req.JobID,
+			RunID:     result.RunID,
+			Path:      relPath,
+			CopyPath:  path.Join(path.Dir(relPath), filepath.Base(action.ConflictCopy)),
+			Winner:    winner,
+			ChangedBy: action.ConflictOwner,
+			Timestamp: action.Timestamp,
+		})
+	}
+	return records
+}
+
+// updateCacheFromActions updates cache based on successful actions.
+// Remote timestamps from the scan are recorded for downloaded files (uploads change them)
+// and files moved locally after a move on the server.
+func (e *Engine) updateCacheFromActions(jobID int64, localBasePath string, actions []*SyncAction, remoteFiles map[string]*cache.FileInfo) error {
 	updates := make(map[string]*cache.FileInfo)
 	remotePaths := make(map[string]string)
 
@@ -291,12 +440,23 @@ func (e *Engine) updateCacheFromActions(jobID int64, localBasePath string, actio
 		// Convert absolute path back to relative for cache storage
 		relPath := toRelativePath(action.FilePath, localBasePath)
 
-		updates[relPath] = &cache.FileInfo{
-			Path:  relPath,
-			Size:  action.Size,
-			MTime: timeNow(), // Current time after sync
-			Hash:  "",        // Hash will be computed on next scan if needed
+		info := &cache.FileInfo{
+			Path:        relPath,
+			Size:        action.Size,
+			MTime:       action.MTime,      // Local file after the transfer
+			Hash:        action.Hash,       // Computed during transfer (empty for deletes)
+			Attributes:  action.Attributes, // Tracked bits after the action (0 = not tracked)
+			RemoteOwner: action.ChangedBy,
+			VerifiedAt:  action.VerifiedAt,
+		}
+		if info.MTime.IsZero() {
+			info.MTime = e.clock.Now() // Current time after sync
+		}
+		if remoteInfo, ok := remoteFiles[relPath]; ok && remoteInfo != nil && (action.Action == cache.ActionDownload || action.Action == cache.ActionRenameLocal) {
+			info.RemoteWriteTime = remoteInfo.RemoteWriteTime
+			info.RemoteChangeTime = remoteInfo.RemoteChangeTime
 		}
+		updates[relPath] = info
 		remotePaths[relPath] = action.RemotePath
 	}
 
@@ -337,10 +497,13 @@ func (e *Engine) initializeCacheForInSyncFiles(jobID int64, localFiles, remoteFi
 
 		// Add to cache
 		updates[path] = &cache.FileInfo{
-			Path:  path,
-			Size:  localInfo.Size,
-			MTime: localInfo.MTime,
-			Hash:  localInfo.Hash,
+			Path:             path,
+			Size:             localInfo.Size,
+			MTime:            localInfo.MTime,
+			Hash:             localInfo.Hash,
+			RemoteWriteTime:  remoteInfo.RemoteWriteTime,
+			RemoteChangeTime: remoteInfo.RemoteChangeTime,
+			Attributes:       localInfo.Attributes,
 		}
 		remotePaths[path] = path
 	}
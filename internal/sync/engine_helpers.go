@@ -4,53 +4,20 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smbpath"
+	"go.uber.org/zap"
 )
 
 // parseUNCPath parses a UNC path into server, share, and relative path components.
 // Format: \\server\share\path or //server/share/path
 // Returns server, share, and the remaining path (empty if at share root)
 func parseUNCPath(uncPath string) (server, share, relPath string) {
-	path := uncPath
-
-	// Remove leading slashes
-	for len(path) > 0 && (path[0] == '/' || path[0] == '\\') {
-		path = path[1:]
-	}
-
-	// Split by / or \
-	var parts []string
-	current := ""
-	for _, c := range path {
-		if c == '/' || c == '\\' {
-			if current != "" {
-				parts = append(parts, current)
-				current = ""
-			}
-		} else {
-			current += string(c)
-		}
-	}
-	if current != "" {
-		parts = append(parts, current)
-	}
-
-	if len(parts) >= 1 {
-		server = parts[0]
-	}
-	if len(parts) >= 2 {
-		share = parts[1]
-	}
-	if len(parts) >= 3 {
-		// Join remaining parts with forward slash for SMB operations
-		for i := 2; i < len(parts); i++ {
-			if relPath != "" {
-				relPath += "/"
-			}
-			relPath += parts[i]
-		}
-	}
-
-	return server, share, relPath
+	p := smbpath.SplitUNC(uncPath)
+	return p.Host, p.Share, p.SlashPath()
 }
 
 // formatErrorSummary creates a summary string from errors
@@ -101,3 +68,51 @@ func toRelativePath(absPath, basePath string) string {
 
 	return relPath
 }
+
+// adaptiveParallelism returns the bounds of the adaptive number of transfers of the config.
+func adaptiveParallelism(perf config.PerformanceConfig) AdaptiveParallelism {
+	return AdaptiveParallelism{
+		Min:      perf.MinParallelTransfers,
+		Max:      perf.MaxParallelTransfers,
+		Interval: time.Duration(perf.AdaptiveIntervalSeconds) * time.Second,
+	}
+}
+
+// retryPolicy returns the per-file retry policy of the config, the default
+// policy when no attempts are set.
+func retryPolicy(retry config.RetryConfig, logger *zap.Logger) *RetryPolicy {
+	policy := DefaultRetryPolicy(logger)
+	if retry.MaxAttempts <= 0 {
+		return policy
+	}
+	policy.MaxRetries = retry.MaxAttempts - 1
+	if retry.InitialDelaySeconds > 0 {
+		policy.InitialDelay = time.Duration(retry.InitialDelaySeconds) * time.Second
+	}
+	if retry.MaxDelaySeconds > 0 {
+		policy.MaxDelay = time.Duration(retry.MaxDelaySeconds) * time.Second
+	}
+	if policy.MaxDelay < policy.InitialDelay {
+		policy.MaxDelay = policy.InitialDelay
+	}
+	return policy
+}
+
+// smallFileBatch returns the small file upload batch settings of the config.
+func smallFileBatch(perf config.PerformanceConfig) SmallFileBatch {
+	return SmallFileBatch{
+		MaxSize:  int64(perf.SmallFileBatchKB) * 1024,
+		MinFiles: perf.SmallFileBatchMin,
+		Streams:  perf.SmallFileBatchStreams,
+	}
+}
+
+// parallelTransfer returns the parallel chunked transfer settings of the config.
+func parallelTransfer(perf config.PerformanceConfig) smb.ParallelTransfer {
+	const mb = 1024 * 1024
+	return smb.ParallelTransfer{
+		MinSize:   int64(perf.ParallelChunkMinMB) * mb,
+		ChunkSize: int64(perf.ParallelChunkSizeMB) * mb,
+		Streams:   perf.ParallelChunkStreams,
+	}
+}
@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// fakeCache keeps the baseline in memory
+type fakeCache struct {
+	files   map[string]*cache.FileInfo
+	updates []map[string]*cache.FileInfo
+}
+
+func (c *fakeCache) GetAllCachedFiles(jobID int64) (map[string]*cache.FileInfo, error) {
+	return c.files, nil
+}
+
+func (c *fakeCache) GetCachedState(jobID int64, localPath string) (*cache.FileInfo, error) {
+	return c.files[localPath], nil
+}
+
+func (c *fakeCache) UpdateCacheBatch(jobID int64, updates map[string]*cache.FileInfo, remotePaths map[string]string) error {
+	c.updates = append(c.updates, updates)
+	return nil
+}
+
+// fakeDetector returns fixed decisions
+type fakeDetector struct {
+	decisions []*cache.SyncDecision
+}
+
+func (d *fakeDetector) BatchDetermineSyncActions(jobID int64, files map[string]*cache.FileInfo, remoteFiles map[string]*cache.FileInfo) ([]*cache.SyncDecision, error) {
+	return d.decisions, nil
+}
+
+// fakeExecutor succeeds every decision and commits them in one batch
+type fakeExecutor struct {
+	executed []*cache.SyncDecision
+}
+
+func (ex *fakeExecutor) ExecuteWithCommit(ctx context.Context, decisions []*cache.SyncDecision, smbClient *smb.SMBClient,
+	progressFn ProgressCallback, commitFn CommitFunc) ([]*SyncAction, error) {
+	ex.executed = decisions
+	actions := make([]*SyncAction, 0, len(decisions))
+	for _, d := range decisions {
+		actions = append(actions, &SyncAction{
+			FilePath:   d.LocalPath,
+			RemotePath: d.RemotePath,
+			Action:     d.Action,
+			Status:     ActionStatusSuccess,
+			Size:       10,
+			Hash:       "h-" + filepath.Base(d.LocalPath),
+		})
+	}
+	if commitFn != nil {
+		if err := commitFn(actions); err != nil {
+			return actions, err
+		}
+	}
+	return actions, nil
+}
+
+func newFakeEngine(t *testing.T, opts ...EngineOption) *Engine {
+	t.Helper()
+	db, err := database.Open(database.Config{
+		Path:             filepath.Join(t.TempDir(), "test.db"),
+		EncryptionKey:    "test-key-32-chars-long-123456",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	engine, err := NewEngine(&config.Config{}, db, zap.NewNop(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func TestEngine_DetectChanges(t *testing.T) {
+	detector := &fakeDetector{decisions: []*cache.SyncDecision{
+		{LocalPath: "up.txt", Action: cache.ActionUpload},
+		{LocalPath: "down.txt", Action: cache.ActionDownload},
+		{LocalPath: "gone.txt", Action: cache.ActionDeleteLocal},
+		{LocalPath: "both.txt", Action: cache.ActionConflict, NeedsResolution: true},
+	}}
+	engine := newFakeEngine(t, WithChangeDetector(detector))
+
+	tests := []struct {
+		name         string
+		mode         SyncMode
+		downloadOnly bool
+		wantPaths    []string
+	}{
+		{"mirror", SyncModeMirror, false, []string{"up.txt", "down.txt", "gone.txt"}},
+		{"upload", SyncModeUpload, false, []string{"up.txt"}},
+		{"download", SyncModeDownload, false, []string{"down.txt", "gone.txt"}},
+		{"download only", SyncModeMirror, true, []string{"down.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &SyncRequest{JobID: 1, Mode: tt.mode, DownloadOnly: tt.downloadOnly}
+			decisions, conflicts, err := engine.detectChanges(context.Background(), req, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("detectChanges failed: %v", err)
+			}
+			if len(decisions) != len(tt.wantPaths) {
+				t.Fatalf("expected %d decisions, got %d", len(tt.wantPaths), len(decisions))
+			}
+			for i, want := range tt.wantPaths {
+				if decisions[i].LocalPath != want {
+					t.Errorf("decision %d: expected %s, got %s", i, want, decisions[i].LocalPath)
+				}
+			}
+			if len(conflicts) != 1 || conflicts[0].LocalPath != "both.txt" {
+				t.Errorf("expected the unresolved conflict, got %v", conflicts)
+			}
+		})
+	}
+}
+
+func TestEngine_ExecuteActionsCommitsToCache(t *testing.T) {
+	fc := &fakeCache{}
+	executor := &fakeExecutor{}
+	engine := newFakeEngine(t, WithCacheManager(fc), WithExecutor(executor))
+
+	localBase := t.TempDir()
+	req := &SyncRequest{JobID: 1, LocalPath: localBase, RemotePath: `\\nas\share\docs`}
+	decisions := []*cache.SyncDecision{
+		{LocalPath: "a.txt", RemotePath: "a.txt", Action: cache.ActionUpload},
+		{LocalPath: "sub/b.txt", RemotePath: "sub/b.txt", Action: cache.ActionDownload},
+	}
+	remoteTime := time.Unix(42, 0)
+	remoteFiles := map[string]*cache.FileInfo{
+		"sub/b.txt": {Path: "sub/b.txt", RemoteWriteTime: remoteTime},
+	}
+
+	actions, err := engine.executeActions(context.Background(), req, decisions, nil, nil, remoteFiles)
+	if err != nil {
+		t.Fatalf("executeActions failed: %v", err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	}
+
+	// The executor gets full paths
+	if got := executor.executed[0].LocalPath; got != filepath.Join(localBase, "a.txt") {
+		t.Errorf("expected absolute local path, got %s", got)
+	}
+	if got := executor.executed[1].RemotePath; got != "docs/sub/b.txt" {
+		t.Errorf("expected remote path within share, got %s", got)
+	}
+
+	// The cache gets relative paths and remote timestamps of downloads
+	if len(fc.updates) != 1 {
+		t.Fatalf("expected 1 cache commit, got %d", len(fc.updates))
+	}
+	committed := fc.updates[0]
+	if info := committed["a.txt"]; info == nil || info.Hash != "h-a.txt" || !info.RemoteWriteTime.IsZero() {
+		t.Errorf("unexpected cache entry for upload: %+v", info)
+	}
+	if info := committed["sub/b.txt"]; info == nil || !info.RemoteWriteTime.Equal(remoteTime) {
+		t.Errorf("unexpected cache entry for download: %+v", info)
+	}
+}
+
+func TestEngine_InitializeCacheForInSyncFiles(t *testing.T) {
+	fc := &fakeCache{files: map[string]*cache.FileInfo{
+		"cached.txt": {Path: "cached.txt", Size: 1, Hash: "c"},
+	}}
+	engine := newFakeEngine(t, WithCacheManager(fc))
+
+	localFiles := map[string]*cache.FileInfo{
+		"cached.txt":  {Path: "cached.txt", Size: 1, Hash: "c"},
+		"same.txt":    {Path: "same.txt", Size: 2, Hash: "s"},
+		"differs.txt": {Path: "differs.txt", Size: 3, Hash: "l"},
+		"local.txt":   {Path: "local.txt", Size: 4},
+	}
+	remoteTime := time.Unix(7, 0)
+	remoteFiles := map[string]*cache.FileInfo{
+		"cached.txt":  {Path: "cached.txt", Size: 1},
+		"same.txt":    {Path: "same.txt", Size: 2, RemoteWriteTime: remoteTime},
+		"differs.txt": {Path: "differs.txt", Size: 3, Hash: "r"},
+	}
+
+	if err := engine.initializeCacheForInSyncFiles(1, localFiles, remoteFiles); err != nil {
+		t.Fatalf("initializeCacheForInSyncFiles failed: %v", err)
+	}
+	if len(fc.updates) != 1 || len(fc.updates[0]) != 1 {
+		t.Fatalf("expected one batch with only same.txt, got %v", fc.updates)
+	}
+	if info := fc.updates[0]["same.txt"]; info == nil || info.Hash != "s" || !info.RemoteWriteTime.Equal(remoteTime) {
+		t.Errorf("unexpected cache entry: %+v", info)
+	}
+}
+
+func TestEngine_FinalizeSyncRecordsActions(t *testing.T) {
+	engine := newFakeEngine(t, WithCacheManager(&fakeCache{}))
+	req := &SyncRequest{JobID: 1, LocalPath: `C:\data`, Mode: SyncModeMirror}
+
+	result := NewSyncResult(req.JobID)
+	result.RunID = "run1"
+	result.Status = SyncStatusPartial
+	result.AddAction(&SyncAction{FilePath: `C:\data\a.txt`, Action: cache.ActionUpload, Status: ActionStatusSuccess, OpID: "op1", BytesTransferred: 10})
+	result.AddAction(&SyncAction{FilePath: `C:\data\b.txt`, Action: cache.ActionDownload, Status: ActionStatusFailed, OpID: "op2", Error: context.DeadlineExceeded})
+
+	if err := engine.finalizeSync(context.Background(), req, result, nil, nil, nil); err != nil {
+		t.Fatalf("finalizeSync failed: %v", err)
+	}
+
+	records, err := engine.db.GetSyncActionsByRun("run1")
+	if err != nil {
+		t.Fatalf("GetSyncActionsByRun failed: %v", err)
+	}
+	if len(records) != 2 || records[0].OpID != "op1" || records[0].Bytes != 10 || records[1].Error == "" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
@@ -18,12 +19,22 @@ func (e *Engine) scanFiles(ctx context.Context, req *SyncRequest, smbClient *smb
 	cachedFiles map[string]*cache.FileInfo,
 	err error,
 ) {
+	// Folders left out of a selective sync are neither listed nor synced,
+	// on either side, and their cached files are not seen as deleted
+	selection, err := e.jobSelection(req.JobID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Scan local files
-	e.logger.Info("scanning local files", zap.String("path", req.LocalPath))
+	e.log(ctx).Info("scanning local files", zap.String("path", req.LocalPath))
 	scanResult, err := e.scanner.Scan(ctx, scanner.ScanRequest{
-		JobID:      req.JobID,
-		BasePath:   req.LocalPath,
-		RemoteBase: req.RemotePath,
+		JobID:           req.JobID,
+		BasePath:        req.LocalPath,
+		RemoteBase:      req.RemotePath,
+		ExclusionGroups: req.ExclusionGroups,
+		Subtree:         req.Subtree,
+		Selection:       selection,
 	})
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("local scan failed: %w", err)
@@ -35,32 +46,35 @@ func (e *Engine) scanFiles(ctx context.Context, req *SyncRequest, smbClient *smb
 	for _, file := range scanResult.NewFiles {
 		relPath := toRelativePath(file.LocalPath, localBasePath)
 		localFiles[relPath] = &cache.FileInfo{
-			Path:  relPath,
-			Size:  file.Size,
-			MTime: file.MTime,
-			Hash:  file.Hash,
+			Path:       relPath,
+			Size:       file.Size,
+			MTime:      file.MTime,
+			Hash:       file.Hash,
+			Attributes: cache.NormalizeAttributes(file.Attributes),
 		}
 	}
 	for _, file := range scanResult.ModifiedFiles {
 		relPath := toRelativePath(file.LocalPath, localBasePath)
 		localFiles[relPath] = &cache.FileInfo{
-			Path:  relPath,
-			Size:  file.Size,
-			MTime: file.MTime,
-			Hash:  file.Hash,
+			Path:       relPath,
+			Size:       file.Size,
+			MTime:      file.MTime,
+			Hash:       file.Hash,
+			Attributes: cache.NormalizeAttributes(file.Attributes),
 		}
 	}
 	for _, file := range scanResult.UnchangedFiles {
 		relPath := toRelativePath(file.LocalPath, localBasePath)
 		localFiles[relPath] = &cache.FileInfo{
-			Path:  relPath,
-			Size:  file.Size,
-			MTime: file.MTime,
-			Hash:  file.Hash,
+			Path:       relPath,
+			Size:       file.Size,
+			MTime:      file.MTime,
+			Hash:       file.Hash,
+			Attributes: cache.NormalizeAttributes(file.Attributes),
 		}
 	}
 
-	e.logger.Info("local scan completed",
+	e.log(ctx).Info("local scan completed",
 		zap.Int("files", len(localFiles)),
 	)
 
@@ -69,12 +83,12 @@ func (e *Engine) scanFiles(ctx context.Context, req *SyncRequest, smbClient *smb
 	// remote state information to detect deletions (ActionDeleteRemote).
 	// The filtering of downloads happens later in filterDecisionsByMode.
 	var usedManifest bool
-	e.logger.Info("scanning remote files", zap.String("path", req.RemotePath))
-	remoteFiles, usedManifest, err = e.scanRemote(ctx, smbClient, req.RemotePath)
+	e.log(ctx).Info("scanning remote files", zap.String("path", req.RemotePath))
+	remoteFiles, usedManifest, err = e.scanRemote(ctx, smbClient, req.JobID, req.RemotePath, req.Subtree, selection)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("remote scan failed: %w", err)
 	}
-	e.logger.Info("remote scan completed",
+	e.log(ctx).Info("remote scan completed",
 		zap.Int("files", len(remoteFiles)),
 		zap.Bool("used_manifest", usedManifest),
 	)
@@ -84,8 +98,10 @@ func (e *Engine) scanFiles(ctx context.Context, req *SyncRequest, smbClient *smb
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to load cache: %w", err)
 	}
+	filterSubtree(cachedFiles, req.Subtree)
+	filterSelection(cachedFiles, selection)
 
-	e.logger.Info("cache loaded",
+	e.log(ctx).Info("cache loaded",
 		zap.Int("files", len(cachedFiles)),
 	)
 
@@ -94,15 +110,52 @@ func (e *Engine) scanFiles(ctx context.Context, req *SyncRequest, smbClient *smb
 	if usedManifest && len(cachedFiles) > 0 {
 		fallbackCount := e.verifyCachedFilesViaSMB(ctx, smbClient, req.RemotePath, cachedFiles, remoteFiles)
 		if fallbackCount > 0 {
-			e.logger.Info("SMB fallback verification completed",
+			e.log(ctx).Info("SMB fallback verification completed",
 				zap.Int("files_verified", fallbackCount),
 			)
 		}
 	}
 
+	// Files excluded by the job rules are neither downloaded nor deleted:
+	// the local walk skipped them, so drop them from the other sides too
+	filterExcluded(scanResult.Exclusions, localFiles, remoteFiles, cachedFiles)
+
+	// Files outside the pattern are left out of the run, on every side
+	if req.Pattern != "" {
+		pattern, err := scanner.CompileGlob(req.Pattern)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid pattern %q: %w", req.Pattern, err)
+		}
+		filterPattern(localFiles, pattern)
+		filterPattern(remoteFiles, pattern)
+		filterPattern(cachedFiles, pattern)
+	}
+
+	// Attribute bits only take part in change detection when attribute sync is enabled
+	if !req.SyncAttributes {
+		clearAttributes(localFiles)
+		clearAttributes(remoteFiles)
+	}
+
 	return localFiles, remoteFiles, cachedFiles, nil
 }
 
+// jobSelection returns the selective sync of a job.
+func (e *Engine) jobSelection(jobID int64) (scanner.Selection, error) {
+	rules, err := e.db.GetSyncSelection(jobID)
+	if err != nil {
+		return scanner.Selection{}, fmt.Errorf("failed to load selective sync: %w", err)
+	}
+	return scanner.SelectionFromRules(rules), nil
+}
+
+// clearAttributes marks attributes as not tracked for all files
+func clearAttributes(files map[string]*cache.FileInfo) {
+	for _, info := range files {
+		info.Attributes = 0
+	}
+}
+
 // verifyCachedFilesViaSMB checks files that are in cache but not in remoteFiles via direct SMB.
 // This handles the case where the Anemone manifest hasn't been updated yet.
 // Returns the number of files verified and added to remoteFiles.
@@ -121,7 +174,7 @@ func (e *Engine) verifyCachedFilesViaSMB(ctx context.Context, smbClient *smb.SMB
 		return 0
 	}
 
-	e.logger.Debug("checking cached files not in manifest via SMB",
+	e.log(ctx).Debug("checking cached files not in manifest via SMB",
 		zap.Int("count", len(missingFiles)),
 	)
 
@@ -133,7 +186,7 @@ func (e *Engine) verifyCachedFilesViaSMB(ctx context.Context, smbClient *smb.SMB
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
-			e.logger.Debug("SMB fallback cancelled", zap.Int("verified", verified))
+			e.log(ctx).Debug("SMB fallback cancelled", zap.Int("verified", verified))
 			return verified
 		default:
 		}
@@ -148,23 +201,26 @@ func (e *Engine) verifyCachedFilesViaSMB(ctx context.Context, smbClient *smb.SMB
 		metadata, err := smbClient.GetMetadata(smbPath)
 		if err != nil {
 			// File doesn't exist on remote - this is a real deletion
-			e.logger.Debug("cached file not found on remote (deleted)",
+			e.log(ctx).Debug("cached file not found on remote (deleted)",
 				zap.String("path", filePath),
 			)
 			continue
 		}
 
 		// File exists on remote but not in manifest - add to remoteFiles
-		e.logger.Debug("cached file found via SMB fallback",
+		e.log(ctx).Debug("cached file found via SMB fallback",
 			zap.String("path", filePath),
 			zap.Int64("size", metadata.Size),
 		)
 
 		remoteFiles[filePath] = &cache.FileInfo{
-			Path:  filePath,
-			Size:  metadata.Size,
-			MTime: metadata.ModTime,
-			Hash:  "", // No hash from SMB metadata, will rely on size/mtime
+			Path:             filePath,
+			Size:             metadata.Size,
+			MTime:            metadata.ModTime,
+			Hash:             "", // No hash from SMB metadata, will rely on size/mtime
+			RemoteWriteTime:  metadata.WriteTime,
+			RemoteChangeTime: metadata.ChangeTime,
+			Attributes:       cache.NormalizeAttributes(metadata.Attributes),
 		}
 		verified++
 	}
@@ -173,8 +229,9 @@ func (e *Engine) verifyCachedFilesViaSMB(ctx context.Context, smbClient *smb.SMB
 }
 
 // scanRemote scans remote files using Anemone manifest if available, otherwise falls back to SMB scan.
+// Only files inside subtree ("" = whole job) and selection are returned, keyed by job-relative path.
 // Returns the remote files map, a bool indicating if manifest was used, and any error.
-func (e *Engine) scanRemote(ctx context.Context, smbClient *smb.SMBClient, basePath string) (map[string]*cache.FileInfo, bool, error) {
+func (e *Engine) scanRemote(ctx context.Context, smbClient *smb.SMBClient, jobID int64, basePath, subtree string, selection scanner.Selection) (map[string]*cache.FileInfo, bool, error) {
 	// Extract relative path from UNC path (ListRemote expects path relative to share)
 	// basePath is UNC format: \\server\share\path -> we need just "path" (or "." for root)
 	_, _, relPath := parseUNCPath(basePath)
@@ -182,46 +239,89 @@ func (e *Engine) scanRemote(ctx context.Context, smbClient *smb.SMBClient, baseP
 		relPath = "." // Use "." for share root
 	}
 
-	e.logger.Debug("scanning remote with relative path",
+	e.log(ctx).Debug("scanning remote with relative path",
 		zap.String("unc_path", basePath),
 		zap.String("relative_path", relPath),
 	)
 
 	// Try to load Anemone manifest first (much faster)
-	manifestReader := NewManifestReader(smbClient, e.logger.Named("manifest"))
+	manifestReader := NewManifestReader(smbClient, e.log(ctx).Named("manifest"))
 	manifestResult := manifestReader.ReadManifest(ctx, relPath)
 
 	if manifestResult.Found && manifestResult.Error == nil {
 		// Manifest found - use it directly
-		e.logger.Info("using Anemone manifest for remote scan",
+		e.log(ctx).Info("using Anemone manifest for remote scan",
 			zap.String("share", manifestResult.Manifest.ShareName),
 			zap.Int("file_count", manifestResult.Manifest.FileCount),
 			zap.Int64("total_size", manifestResult.Manifest.TotalSize),
 			zap.Duration("duration", manifestResult.Duration),
 		)
-		return manifestResult.Manifest.ToFileInfoMap(), true, nil
+		files := manifestResult.Manifest.ToFileInfoMap()
+		filterSubtree(files, subtree)
+		filterSelection(files, selection)
+		return files, true, nil
 	}
 
 	if manifestResult.Error != nil {
-		e.logger.Warn("failed to read manifest, falling back to SMB scan",
+		e.log(ctx).Warn("failed to read manifest, falling back to SMB scan",
 			zap.Error(manifestResult.Error),
 		)
 	} else {
-		e.logger.Info("manifest not found, using SMB scan (slower)",
+		e.log(ctx).Info("manifest not found, using SMB scan (slower)",
 			zap.String("hint", "Install Anemone Server for faster sync"),
 		)
 	}
 
-	// Fallback to traditional SMB recursive scan
-	files, err := e.scanRemoteSMB(ctx, smbClient, relPath)
+	// Fallback to traditional SMB recursive scan. Scans of a folder of the job
+	// (e.g. after a change notification) always list it: the folder may hold
+	// a file rewritten in place, which doesn't change its time
+	if subtree != "" {
+		files, err := e.scanRemoteSubtree(ctx, smbClient, relPath, subtree, selection)
+		return files, false, err
+	}
+	files, err := e.scanRemoteSMB(ctx, smbClient, relPath, selection, "", jobID)
 	return files, false, err
 }
 
-// scanRemoteSMB scans remote files recursively using SMB (fallback method).
-func (e *Engine) scanRemoteSMB(ctx context.Context, smbClient *smb.SMBClient, relPath string) (map[string]*cache.FileInfo, error) {
+// scanRemoteSubtree scans only relPath/subtree over SMB and returns files keyed
+// by job-relative path. A subtree missing on the remote is empty.
+func (e *Engine) scanRemoteSubtree(ctx context.Context, smbClient *smb.SMBClient, relPath, subtree string, selection scanner.Selection) (map[string]*cache.FileInfo, error) {
+	scanRoot := subtree
+	if relPath != "." {
+		scanRoot = relPath + "/" + subtree
+	}
+
+	if _, err := smbClient.GetMetadata(scanRoot); err != nil {
+		if isNotFoundError(err) {
+			e.log(ctx).Info("remote subtree does not exist, treating as empty",
+				zap.String("path", scanRoot))
+			return make(map[string]*cache.FileInfo), nil
+		}
+		return nil, fmt.Errorf("remote scan failed: %w", err)
+	}
+
+	scanned, err := e.scanRemoteSMB(ctx, smbClient, scanRoot, selection, subtree, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*cache.FileInfo, len(scanned))
+	for p, info := range scanned {
+		jobPath := subtree + "/" + p
+		info.Path = jobPath
+		files[jobPath] = info
+	}
+	return files, nil
+}
+
+// scanRemoteSMB scans remote files recursively using SMB (fallback method),
+// skipping the folders outside selection. prefix is the job-relative folder of relPath.
+// With a cacheJobID (0 = none), the folders unchanged since the previous scan
+// of that job are not listed again (remote listing cache).
+func (e *Engine) scanRemoteSMB(ctx context.Context, smbClient *smb.SMBClient, relPath string, selection scanner.Selection, prefix string, cacheJobID int64) (map[string]*cache.FileInfo, error) {
 	// Create progress callback for remote scanning
 	progressCallback := func(progress RemoteScanProgress) {
-		e.logger.Debug("remote scan progress",
+		e.log(ctx).Debug("remote scan progress",
 			zap.Int("files", progress.FilesFound),
 			zap.Int("dirs", progress.DirsScanned),
 			zap.Int64("bytes", progress.BytesDiscovered),
@@ -230,7 +330,19 @@ func (e *Engine) scanRemoteSMB(ctx context.Context, smbClient *smb.SMBClient, re
 	}
 
 	// Create remote scanner
-	scanner := NewRemoteScanner(smbClient, e.logger.Named("remote_scanner"), progressCallback)
+	scanner := NewRemoteScanner(smbClient, e.log(ctx).Named("remote_scanner"), progressCallback)
+	scanner.SetSelection(selection, prefix)
+
+	perf := e.config.Sync.Performance
+	cacheListings := cacheJobID != 0 && e.db != nil && perf.RemoteListingCache
+	if cacheListings {
+		previous, err := e.db.GetRemoteState(cacheJobID)
+		if err != nil {
+			e.log(ctx).Warn("failed to load remote listing cache, listing every folder", zap.Error(err))
+			previous = nil
+		}
+		scanner.SetListingCache(previous, time.Duration(perf.RemoteListingMaxAgeHours)*time.Hour)
+	}
 
 	// Perform scan with relative path (not full UNC path)
 	result, err := scanner.Scan(ctx, relPath)
@@ -238,10 +350,18 @@ func (e *Engine) scanRemoteSMB(ctx context.Context, smbClient *smb.SMBClient, re
 		return nil, fmt.Errorf("remote scan failed: %w", err)
 	}
 
+	// An interrupted scan keeps the previous cache
+	if cacheListings && result.Listings != nil && ctx.Err() == nil {
+		if err := e.db.ReplaceRemoteState(cacheJobID, result.Listings); err != nil {
+			e.log(ctx).Warn("failed to save remote listing cache", zap.Error(err))
+		}
+	}
+
 	// Log scan results
-	e.logger.Info("remote SMB scan completed",
+	e.log(ctx).Info("remote SMB scan completed",
 		zap.Int("files", result.TotalFiles),
 		zap.Int("dirs", result.TotalDirs),
+		zap.Int("dirs_reused", result.ReusedDirs),
 		zap.Int64("bytes", result.TotalBytes),
 		zap.Duration("duration", result.Duration),
 		zap.Int("errors", len(result.Errors)),
@@ -250,16 +370,16 @@ func (e *Engine) scanRemoteSMB(ctx context.Context, smbClient *smb.SMBClient, re
 
 	// Warn about any errors encountered
 	if len(result.Errors) > 0 {
-		e.logger.Warn("remote scan encountered errors",
+		e.log(ctx).Warn("remote scan encountered errors",
 			zap.Int("error_count", len(result.Errors)),
 		)
 		for i, scanErr := range result.Errors {
 			if i < 5 { // Log first 5 errors
-				e.logger.Warn("remote scan error", zap.Error(scanErr))
+				e.log(ctx).Warn("remote scan error", zap.Error(scanErr))
 			}
 		}
 		if len(result.Errors) > 5 {
-			e.logger.Warn("additional errors omitted", zap.Int("count", len(result.Errors)-5))
+			e.log(ctx).Warn("additional errors omitted", zap.Int("count", len(result.Errors)-5))
 		}
 	}
 
@@ -1,11 +1,15 @@
 package sync
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"syscall"
 	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 )
 
 // Common sync errors
@@ -16,6 +20,7 @@ var (
 	ErrInvalidRemotePath         = errors.New("invalid remote path")
 	ErrInvalidSyncMode           = errors.New("invalid sync mode")
 	ErrInvalidConflictResolution = errors.New("invalid conflict resolution policy")
+	ErrInvalidSubtree            = errors.New("invalid subtree: must be a folder inside the job")
 
 	// State errors
 	ErrSyncInProgress = errors.New("sync already in progress for this job")
@@ -23,8 +28,12 @@ var (
 	ErrEngineClosed   = errors.New("sync engine is closed")
 
 	// Operation errors
-	ErrSyncAborted      = errors.New("sync was aborted")
-	ErrContextCancelled = errors.New("context cancelled")
+	ErrSyncAborted         = errors.New("sync was aborted")
+	ErrContextCancelled    = errors.New("context cancelled")
+	ErrUploadVetoed        = errors.New("upload vetoed by scanner")
+	ErrChangeCapExceeded   = errors.New("too many changes for one run")
+	ErrRansomwareSuspected = errors.New("suspected ransomware activity")
+	ErrVerifyMismatch      = errors.New("checksum mismatch after transfer")
 )
 
 // ErrorCategory classifies error types
@@ -41,6 +50,8 @@ const (
 	ErrorCategorySMB ErrorCategory = "smb"
 	// ErrorCategoryPermission indicates permission errors
 	ErrorCategoryPermission ErrorCategory = "permission"
+	// ErrorCategoryIntegrity indicates a copy differing from its source
+	ErrorCategoryIntegrity ErrorCategory = "integrity"
 	// ErrorCategoryUnknown indicates unknown error type
 	ErrorCategoryUnknown ErrorCategory = "unknown"
 )
@@ -51,6 +62,11 @@ func ClassifyError(err error) (ErrorCategory, bool) {
 		return ErrorCategoryUnknown, false
 	}
 
+	// A copy corrupted in transit is sent again
+	if errors.Is(err, ErrVerifyMismatch) {
+		return ErrorCategoryIntegrity, true
+	}
+
 	// Check for specific error types
 	if IsNetworkError(err) {
 		return ErrorCategoryNetwork, true // Network errors are generally retryable
@@ -75,6 +91,116 @@ func ClassifyError(err error) (ErrorCategory, bool) {
 	return ErrorCategoryUnknown, false
 }
 
+// ErrorCode identifies what went wrong in terms a user can act on. The GUI
+// maps it to a localized message (see package errmsg); logs keep the raw error.
+type ErrorCode string
+
+const (
+	ErrorCodeAuthFailed         ErrorCode = "auth_failed"         // Server refused the credentials
+	ErrorCodePasswordExpired    ErrorCode = "password_expired"    // Account password must be changed
+	ErrorCodeAccountLocked      ErrorCode = "account_locked"      // Account locked out by the server
+	ErrorCodeCredentialsMissing ErrorCode = "credentials_missing" // No saved credentials for the server
+	ErrorCodeServerUnreachable  ErrorCode = "server_unreachable"  // Server down, offline or firewalled
+	ErrorCodeShareNotFound      ErrorCode = "share_not_found"     // Share name unknown to the server
+	ErrorCodeAccessDenied       ErrorCode = "access_denied"       // Missing rights on a file or folder
+	ErrorCodeDiskFull           ErrorCode = "disk_full"           // No space left locally or on the server
+	ErrorCodeFileLocked         ErrorCode = "file_locked"         // File open in another program
+	ErrorCodeFileNotFound       ErrorCode = "file_not_found"      // File or folder disappeared
+	ErrorCodePathTooLong        ErrorCode = "path_too_long"       // Path exceeds the Windows limit
+	ErrorCodeUploadVetoed       ErrorCode = "upload_vetoed"       // Upload refused by the scanner
+	ErrorCodeChangeCapExceeded  ErrorCode = "change_cap_exceeded" // Run paused by the change cap
+	ErrorCodeRansomware         ErrorCode = "ransomware"          // Run paused by ransomware detection
+	ErrorCodeSyncInProgress     ErrorCode = "sync_in_progress"    // Job already syncing
+	ErrorCodeCancelled          ErrorCode = "cancelled"           // Sync stopped by the user or shutdown
+	ErrorCodeEncryptionRequired ErrorCode = "encryption_required" // Server not encrypting, its settings require it
+	ErrorCodeVerifyFailed       ErrorCode = "verify_failed"       // Copy still differing from its source after retries
+	ErrorCodeUnknown            ErrorCode = "unknown"
+)
+
+// NTSTATUS codes mapped to an ErrorCode
+const (
+	ntStatusAccessDenied       = 0xC0000022
+	ntStatusObjectNameNotFound = 0xC0000034
+	ntStatusSharingViolation   = 0xC0000043
+	ntStatusLogonFailure       = 0xC000006D
+	ntStatusPasswordExpired    = 0xC0000071
+	ntStatusDiskFull           = 0xC000007F
+	ntStatusBadNetworkPath     = 0xC00000BE
+	ntStatusBadNetworkName     = 0xC00000CC
+	ntStatusAccountLockedOut   = 0xC0000234
+)
+
+// ErrorCodeOf returns the ErrorCode of an error returned by the engine or
+// the SMB client (ErrorCodeUnknown if it can't be told).
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ErrorCodeUnknown
+	}
+
+	switch {
+	case errors.Is(err, ErrUploadVetoed):
+		return ErrorCodeUploadVetoed
+	case errors.Is(err, ErrChangeCapExceeded):
+		return ErrorCodeChangeCapExceeded
+	case errors.Is(err, ErrRansomwareSuspected):
+		return ErrorCodeRansomware
+	case errors.Is(err, ErrSyncInProgress):
+		return ErrorCodeSyncInProgress
+	case errors.Is(err, ErrSyncAborted), errors.Is(err, ErrContextCancelled), errors.Is(err, context.Canceled):
+		return ErrorCodeCancelled
+	case errors.Is(err, smb.ErrEncryptionRequired):
+		return ErrorCodeEncryptionRequired
+	case errors.Is(err, ErrVerifyMismatch):
+		return ErrorCodeVerifyFailed
+	}
+
+	var respErr *smb2.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.Code {
+		case ntStatusLogonFailure:
+			return ErrorCodeAuthFailed
+		case ntStatusPasswordExpired:
+			return ErrorCodePasswordExpired
+		case ntStatusAccountLockedOut:
+			return ErrorCodeAccountLocked
+		case ntStatusBadNetworkName:
+			return ErrorCodeShareNotFound
+		case ntStatusBadNetworkPath:
+			return ErrorCodeServerUnreachable
+		case ntStatusAccessDenied:
+			return ErrorCodeAccessDenied
+		case ntStatusDiskFull:
+			return ErrorCodeDiskFull
+		case ntStatusSharingViolation:
+			return ErrorCodeFileLocked
+		case ntStatusObjectNameNotFound:
+			return ErrorCodeFileNotFound
+		}
+	}
+
+	msg := err.Error()
+	switch {
+	case contains(msg, "failed to load credentials"):
+		return ErrorCodeCredentialsMissing
+	case contains(msg, "logon failure"), contains(msg, "logon is invalid"):
+		return ErrorCodeAuthFailed
+	case contains(msg, "no space left"), contains(msg, "disk full"), contains(msg, "not enough space"):
+		return ErrorCodeDiskFull
+	case contains(msg, "path too long"), contains(msg, "file name is too long"):
+		return ErrorCodePathTooLong
+	case contains(msg, "used by another process"), contains(msg, "file is locked"):
+		return ErrorCodeFileLocked
+	case IsPermissionError(err):
+		return ErrorCodeAccessDenied
+	case errors.Is(err, os.ErrNotExist):
+		return ErrorCodeFileNotFound
+	case IsNetworkError(err):
+		return ErrorCodeServerUnreachable
+	}
+
+	return ErrorCodeUnknown
+}
+
 // IsNetworkError returns true if the error is network-related
 func IsNetworkError(err error) bool {
 	if err == nil {
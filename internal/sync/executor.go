@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/metrics"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	"go.uber.org/zap"
 )
@@ -15,9 +19,34 @@ type Executor struct {
 	logger       *zap.Logger
 	bufferSizeMB int
 	retryPolicy  *RetryPolicy
-	numWorkers   int // Number of workers for parallel execution (0 = sequential)
+	numWorkers   int                  // Number of workers for parallel execution (0 = sequential)
+	adaptive     *AdaptiveParallelism // Bounds of the adaptive number of workers (nil = fixed)
+	queueSize    int                  // Bounded queue between detection and workers (0 = numWorkers*2)
+	budget       *memoryBudget        // Ceiling for in-flight transfer buffers
+
+	commitBatchSize int // Completed actions between two cache commits
+
+	order TransferOrder // Order of the transfers within an action kind
+
+	smallFiles *SmallFileBatch // Upload batches of small files (nil = disabled)
+
+	parallel smb.ParallelTransfer // Chunked transfers of large files (memory cost of an action)
+
+	throttle  *executorBandwidth   // Rate limits shared by all runs (nil = unlimited)
+	isMetered func() (bool, error) // Connection cost check (replaced by tests)
+
+	faults *faultInjector // Failure injection (faultinject builds only, nil = disabled)
+
+	remoteOwners bool // Record who changed remote files (shares written by several users)
+
+	sidUpload, sidDownload SIDMap // SID translation of copied security descriptors (see acls.go)
+
+	clock clock.Clock // Times transfers and waits between retries
 }
 
+// DefaultMaxInFlightMB is the default memory ceiling for in-flight transfer buffers
+const DefaultMaxInFlightMB = 256
+
 // NewExecutor creates a new executor
 func NewExecutor(bufferSizeMB int, logger *zap.Logger) *Executor {
 	if logger == nil {
@@ -33,9 +62,67 @@ func NewExecutor(bufferSizeMB int, logger *zap.Logger) *Executor {
 		bufferSizeMB: bufferSizeMB,
 		retryPolicy:  DefaultRetryPolicy(logger.Named("retry")),
 		numWorkers:   0, // Default to sequential execution
+		budget:       newMemoryBudget(DefaultMaxInFlightMB * 1024 * 1024),
+
+		commitBatchSize: DefaultCommitBatchSize,
+		order:           TransferOrder{Strategy: TransferOrderDefault},
+		isMetered:       bandwidth.IsMetered,
+		faults:          loadFaultInjector(logger),
+		clock:           clock.System,
 	}
 }
 
+// SetClock sets the clock timing transfers and the waits between retries.
+func (ex *Executor) SetClock(c clock.Clock) {
+	ex.clock = clock.Or(c)
+	if ex.retryPolicy != nil {
+		ex.retryPolicy.Clock = ex.clock
+	}
+}
+
+// SetCommitBatchSize sets how many completed actions are committed to the cache at once
+func (ex *Executor) SetCommitBatchSize(size int) {
+	if size <= 0 {
+		size = DefaultCommitBatchSize
+	}
+	ex.commitBatchSize = size
+}
+
+// SetTransferOrder sets the order in which transfers are executed
+func (ex *Executor) SetTransferOrder(order TransferOrder) {
+	ex.order = order
+	ex.logger.Info("transfer order configured", zap.String("order", order.String()))
+}
+
+// TransferOrder returns the order in which transfers are executed
+func (ex *Executor) TransferOrder() TransferOrder {
+	return ex.order
+}
+
+// SetBackpressure bounds the work queued for the workers and the memory
+// reserved by in-flight actions. When either limit is reached, submission
+// blocks until workers catch up.
+// queueSize 0 keeps the default (2 per worker); maxInFlightMB 0 disables the memory ceiling.
+func (ex *Executor) SetBackpressure(queueSize int, maxInFlightMB int) {
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	if maxInFlightMB < 0 {
+		maxInFlightMB = 0
+	}
+	ex.queueSize = queueSize
+	ex.budget = newMemoryBudget(int64(maxInFlightMB) * 1024 * 1024)
+	ex.logger.Info("backpressure configured",
+		zap.Int("queue_size", queueSize),
+		zap.Int("max_in_flight_mb", maxInFlightMB))
+}
+
+// SetParallelTransfer sets the chunked transfer settings of the SMB client,
+// so that the memory budget charges every chunk in flight.
+func (ex *Executor) SetParallelTransfer(p smb.ParallelTransfer) {
+	ex.parallel = p
+}
+
 // SetRetryPolicy sets a custom retry policy
 func (ex *Executor) SetRetryPolicy(policy *RetryPolicy) {
 	ex.retryPolicy = policy
@@ -51,6 +138,21 @@ func (ex *Executor) SetParallelMode(numWorkers int) {
 	ex.logger.Info("parallel mode configured", zap.Int("workers", numWorkers))
 }
 
+// SetAdaptiveParallelism lets parallel runs adjust their number of concurrent
+// transfers from the measured throughput, within the bounds of adaptive and
+// starting from the workers set by SetParallelMode. Max <= 0 disables it.
+func (ex *Executor) SetAdaptiveParallelism(adaptive AdaptiveParallelism) {
+	if adaptive.Max <= 0 {
+		ex.adaptive = nil
+		ex.logger.Info("adaptive parallelism disabled")
+		return
+	}
+	ex.adaptive = &adaptive
+	ex.logger.Info("adaptive parallelism configured",
+		zap.Int("min", adaptive.Min),
+		zap.Int("max", adaptive.Max))
+}
+
 // Execute executes a batch of sync decisions
 // Uses parallel execution if numWorkers > 0, otherwise sequential
 func (ex *Executor) Execute(
@@ -59,29 +161,93 @@ func (ex *Executor) Execute(
 	smbClient *smb.SMBClient,
 	progressFn ProgressCallback,
 ) ([]*SyncAction, error) {
+	return ex.ExecuteWithCommit(ctx, decisions, smbClient, progressFn, nil)
+}
+
+// ExecuteWithCommit executes a batch of sync decisions like Execute, and passes
+// successful actions to commitFn every commitBatchSize completions (and once more
+// at the end, even if execution is cancelled), keeping the cache consistent mid-run.
+func (ex *Executor) ExecuteWithCommit(
+	ctx context.Context,
+	decisions []*cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	progressFn ProgressCallback,
+	commitFn CommitFunc,
+) ([]*SyncAction, error) {
 
 	if len(decisions) == 0 {
 		return []*SyncAction{}, nil
 	}
 
-	// Prioritize actions to minimize data loss risk
-	decisions = ex.prioritizeActions(decisions)
+	// Transfers wait on the global, job and metered connection limits
+	ctx = ex.withBandwidth(ctx)
+
+	// Prioritize actions to minimize data loss risk, then by transfer order
+	decisions = ex.prioritizeActions(decisions, transferRoot(ctx))
+
+	batcher := newActionBatcher(ex.commitBatchSize, commitFn, ex.log(ctx))
+	defer func() {
+		if err := batcher.flush(); err != nil {
+			ex.log(ctx).Error("failed to commit completed actions to cache", zap.Error(err))
+		}
+	}()
+
+	// Deletions freeing the path of a transfer first, then transfers, and the
+	// other deletions once every transfer of their side has completed (see
+	// ordering.go and dependencies.go)
+	phases := splitPhases(decisions)
+	actions, err := ex.executePhase(ctx, phases.early, smbClient, progressFn, batcher)
+	if err != nil {
+		return actions, err
+	}
+
+	// Bursts of small uploads go first, in a batch; failed files are retried alone
+	transfers := phases.transfers
+	if ex.smallFiles != nil && smbClient != nil && ex.faults == nil {
+		if small, rest := ex.smallFiles.split(transfers); len(small) > 0 {
+			batched, failed := ex.executeSmallFiles(ctx, small, smbClient, progressFn, batcher)
+			actions = append(actions, batched...)
+			transfers = append(failed, rest...)
+		}
+	}
+
+	work := append(append(make([]*cache.SyncDecision, 0, len(transfers)+len(phases.deletions)), transfers...), phases.deletions...)
+	done, err := ex.executePhase(ctx, work, smbClient, progressFn, batcher)
+	if err != nil {
+		return append(actions, done...), err
+	}
+
+	transferred, deleted := done[:len(transfers)], done[len(transfers):]
+	transferred, err = ex.retryBlockedTransfers(ctx, transfers, transferred, phases.deletions, deleted, smbClient, progressFn, batcher)
+	return append(append(actions, transferred...), deleted...), err
+}
+
+// execute runs decisions in parallel if numWorkers > 0, otherwise sequentially,
+// respecting their dependencies (see dependencies.go). Actions are returned
+// index for index with decisions (nil if cancelled before running).
+func (ex *Executor) execute(
+	ctx context.Context,
+	decisions []*cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	progressFn ProgressCallback,
+	batcher *actionBatcher,
+) ([]*SyncAction, error) {
 
 	// Use parallel execution if configured
 	if ex.numWorkers > 0 {
-		ex.logger.Info("executing sync actions in parallel",
+		ex.log(ctx).Info("executing sync actions in parallel",
 			zap.Int("count", len(decisions)),
 			zap.Int("workers", ex.numWorkers),
 		)
-		return ExecuteParallel(ctx, decisions, smbClient, ex, ex.numWorkers, progressFn, ex.logger)
+		return executeParallel(ctx, decisions, smbClient, ex, ex.numWorkers, progressFn, batcher, ex.log(ctx))
 	}
 
 	// Sequential execution
-	ex.logger.Info("executing sync actions sequentially",
+	ex.log(ctx).Info("executing sync actions sequentially",
 		zap.Int("count", len(decisions)),
 	)
 
-	actions := make([]*SyncAction, 0, len(decisions))
+	actions := make([]*SyncAction, len(decisions))
 	var bytesTransferred int64
 
 	// Calculate total bytes to transfer for progress reporting
@@ -95,11 +261,13 @@ func (ex *Executor) Execute(
 	}
 
 	// Execute actions sequentially
-	for i, decision := range decisions {
+	for i, index := range buildActionGraph(decisions).order() {
+		decision := decisions[index]
+
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
-			ex.logger.Warn("execution cancelled",
+			ex.log(ctx).Warn("execution cancelled",
 				zap.Int("completed", i),
 				zap.Int("total", len(decisions)),
 			)
@@ -107,8 +275,14 @@ func (ex *Executor) Execute(
 		default:
 		}
 
+		reserved, err := ex.budget.acquire(ctx, ex.actionMemoryCost(decision))
+		if err != nil {
+			return actions, err
+		}
+
 		// Report progress
 		if progressFn != nil {
+			inFlight, _, limit := ex.budget.usage()
 			progressFn(&SyncProgress{
 				Phase:            "executing",
 				CurrentFile:      decision.LocalPath,
@@ -118,13 +292,16 @@ func (ex *Executor) Execute(
 				BytesTotal:       bytesTotal,
 				CurrentAction:    fmt.Sprintf("%s: %s", decision.Action, decision.LocalPath),
 				Percentage:       35 + float64(i)/float64(len(decisions))*60, // 35-95%
+				InFlightBytes:    inFlight,
+				InFlightLimit:    limit,
 			})
 		}
 
 		// Execute action
 		action, err := ex.executeAction(ctx, decision, smbClient)
+		ex.budget.release(reserved)
 		if err != nil {
-			ex.logger.Error("action failed",
+			ex.log(ctx).Error("action failed",
 				zap.String("action", string(decision.Action)),
 				zap.String("path", decision.LocalPath),
 				zap.Error(err),
@@ -136,17 +313,19 @@ func (ex *Executor) Execute(
 			bytesTransferred += action.BytesTransferred
 		}
 
-		actions = append(actions, action)
+		actions[index] = action
+		countTransferred(action)
+		batcher.add(action)
 	}
 
 	successCount := 0
 	for _, action := range actions {
-		if action.Status == ActionStatusSuccess {
+		if action != nil && action.Status == ActionStatusSuccess {
 			successCount++
 		}
 	}
 
-	ex.logger.Info("execution completed",
+	ex.log(ctx).Info("execution completed",
 		zap.Int("total", len(actions)),
 		zap.Int("success", successCount),
 		zap.Int("failed", len(actions)-successCount),
@@ -156,6 +335,24 @@ func (ex *Executor) Execute(
 	return actions, nil
 }
 
+// log returns the executor logger with the correlation IDs of ctx.
+func (ex *Executor) log(ctx context.Context) *zap.Logger {
+	return correlation.Logger(ctx, ex.logger)
+}
+
+// countTransferred adds the bytes of a completed transfer to the metrics.
+func countTransferred(action *SyncAction) {
+	if action == nil || action.Status != ActionStatusSuccess {
+		return
+	}
+	switch action.Action {
+	case cache.ActionUpload:
+		metrics.BytesUploaded.Add(action.BytesTransferred)
+	case cache.ActionDownload:
+		metrics.BytesDownloaded.Add(action.BytesTransferred)
+	}
+}
+
 // executeAction executes a single sync action
 func (ex *Executor) executeAction(
 	ctx context.Context,
@@ -163,40 +360,92 @@ func (ex *Executor) executeAction(
 	smbClient *smb.SMBClient,
 ) (*SyncAction, error) {
 
+	// Tag the logs of this file operation, down to the SMB client
+	opID := correlation.NewOpID()
+	ctx = correlation.WithOpID(ctx, opID)
+
 	action := &SyncAction{
 		FilePath:   decision.LocalPath,
 		RemotePath: decision.RemotePath,
 		Action:     decision.Action,
+		Reason:     decision.Reason,
+		Replaces:   replacesFile(decision),
 		Status:     ActionStatusExecuting,
-		Timestamp:  timeNow(),
+		OpID:       opID,
+		Timestamp:  ex.clock.Now(),
 	}
 
-	startTime := timeNow()
+	startTime := ex.clock.Now()
+
+	// Operations failing on a lost session run again once reconnected
+	var session sessionRecoverer
+	if smbClient != nil {
+		session = smbClient
+	}
 
 	// Wrap action execution with retry logic
 	operationName := fmt.Sprintf("%s:%s", decision.Action, decision.LocalPath)
 	err := ex.retryPolicy.Retry(ctx, operationName, func() error {
-		switch decision.Action {
-		case cache.ActionUpload:
-			return ex.executeUpload(ctx, decision, smbClient, action)
+		action.Attempts++
+		return withSessionRecovery(ctx, session, ex.log(ctx), func() error {
+			return ex.runAction(ctx, decision, smbClient, action)
+		})
+	})
 
-		case cache.ActionDownload:
-			return ex.executeDownload(ctx, decision, smbClient, action)
+	action.Duration = ex.clock.Now().Sub(startTime)
 
-		case cache.ActionDeleteLocal:
-			return ex.executeDeleteLocal(ctx, decision, action)
+	return action, err
+}
 
-		case cache.ActionDeleteRemote:
-			return ex.executeDeleteRemote(ctx, decision, smbClient, action)
+// runAction runs a sync action once.
+func (ex *Executor) runAction(
+	ctx context.Context,
+	decision *cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	action *SyncAction,
+) error {
+	if err := ex.faults.beforeTransfer(ctx, decision.Action); err != nil {
+		return WrapSyncError(err, decision.LocalPath, string(decision.Action))
+	}
 
-		default:
-			return fmt.Errorf("unknown action: %s", decision.Action)
+	switch decision.Action {
+	case cache.ActionUpload:
+		if err := ex.executeUpload(ctx, decision, smbClient, action); err != nil {
+			return err
 		}
-	})
+		ex.copyStreams(ctx, action, smbClient)
+		ex.copyACL(ctx, action, smbClient)
+		return ex.verifyTransfer(ctx, action, smbClient)
+
+	case cache.ActionDownload:
+		if err := ex.executeDownload(ctx, decision, smbClient, action); err != nil {
+			return err
+		}
+		ex.copyStreams(ctx, action, smbClient)
+		ex.copyACL(ctx, action, smbClient)
+		return ex.verifyTransfer(ctx, action, smbClient)
 
-	action.Duration = timeNow().Sub(startTime)
+	case cache.ActionDeleteLocal:
+		return ex.executeDeleteLocal(ctx, decision, action)
 
-	return action, err
+	case cache.ActionDeleteRemote:
+		return ex.executeDeleteRemote(ctx, decision, smbClient, action)
+
+	case cache.ActionSetAttrLocal:
+		return ex.executeSetAttrLocal(ctx, decision, action)
+
+	case cache.ActionSetAttrRemote:
+		return ex.executeSetAttrRemote(ctx, decision, smbClient, action)
+
+	case cache.ActionRenameLocal:
+		return ex.executeRenameLocal(ctx, decision, action)
+
+	case cache.ActionRenameRemote:
+		return ex.executeRenameRemote(ctx, decision, smbClient, action)
+
+	default:
+		return fmt.Errorf("unknown action: %s", decision.Action)
+	}
 }
 
 // executeUpload uploads a file from local to remote
@@ -214,21 +463,71 @@ func (ex *Executor) executeUpload(
 	}
 
 	action.Size = info.Size()
+	action.MTime = info.ModTime()
 
 	// Upload file
-	ex.logger.Debug("uploading file",
+	ex.log(ctx).Debug("uploading file",
 		zap.String("local", decision.LocalPath),
 		zap.String("remote", decision.RemotePath),
 		zap.Int64("size", action.Size),
 	)
 
-	if err := smbClient.Upload(decision.LocalPath, decision.RemotePath); err != nil {
+	// A read-only remote file can't be replaced
+	if decision.RemoteInfo != nil && decision.RemoteInfo.Attributes&cache.AttrReadOnly != 0 {
+		if err := smbClient.SetReadOnlyContext(ctx, decision.RemotePath, false); err != nil {
+			ex.log(ctx).Warn("failed to clear remote read-only attribute", zap.String("path", decision.RemotePath), zap.Error(err))
+		}
+	}
+
+	// The losing version of a conflict is moved aside rather than replaced
+	// (not again when retrying a transfer that failed verification)
+	if decision.ConflictCopy != "" && action.ConflictCopy == "" {
+		action.ConflictOwner = ex.remoteOwner(ctx, smbClient, decision.RemotePath)
+		copyPath, err := keepRemoteConflictCopy(ctx, smbClient, decision.RemotePath, decision.ConflictCopy)
+		if err != nil {
+			return WrapSyncError(err, decision.RemotePath, "conflict_copy")
+		}
+		action.ConflictCopy = copyPath
+		ex.log(ctx).Info("remote conflict loser kept",
+			zap.String("path", decision.RemotePath),
+			zap.String("copy", copyPath),
+		)
+	}
+
+	hash, err := smbClient.UploadWithHashContext(ctx, decision.LocalPath, decision.RemotePath)
+	if err != nil && isSharingViolation(err) {
+		if shadows := shadowCopiesOf(ctx); shadows != nil {
+			hash, err = ex.uploadFromShadowCopy(ctx, shadows, decision, smbClient)
+		}
+	}
+	if err != nil {
+		// Put the loser back, a retry moves it aside again
+		if action.ConflictCopy != "" {
+			if renameErr := smbClient.RenameContext(ctx, action.ConflictCopy, decision.RemotePath); renameErr != nil {
+				ex.log(ctx).Warn("failed to restore remote conflict loser",
+					zap.String("copy", action.ConflictCopy), zap.Error(renameErr))
+			} else {
+				action.ConflictCopy = ""
+			}
+		}
 		return WrapSyncError(err, decision.LocalPath, "upload")
 	}
+	action.Hash = hash
+	action.ChangedBy = ex.uploadOwner(smbClient)
+
+	// Carry the read-only bit over when attribute sync is enabled
+	if decision.LocalInfo != nil && decision.LocalInfo.Attributes != 0 {
+		if decision.LocalInfo.Attributes&cache.AttrReadOnly != 0 {
+			if err := smbClient.SetReadOnlyContext(ctx, decision.RemotePath, true); err != nil {
+				ex.log(ctx).Warn("failed to set remote read-only attribute", zap.String("path", decision.RemotePath), zap.Error(err))
+			}
+		}
+		action.Attributes = decision.LocalInfo.Attributes
+	}
 
 	action.BytesTransferred = action.Size
 
-	ex.logger.Info("file uploaded",
+	ex.log(ctx).Info("file uploaded",
 		zap.String("path", decision.LocalPath),
 		zap.Int64("size", action.Size),
 		zap.Duration("duration", action.Duration),
@@ -251,25 +550,60 @@ func (ex *Executor) executeDownload(
 	}
 
 	// Download file
-	ex.logger.Debug("downloading file",
+	ex.log(ctx).Debug("downloading file",
 		zap.String("remote", decision.RemotePath),
 		zap.String("local", decision.LocalPath),
 		zap.Int64("size", action.Size),
 	)
 
-	if err := smbClient.Download(decision.RemotePath, decision.LocalPath); err != nil {
+	// A read-only local file can't be replaced
+	if decision.LocalInfo != nil && decision.LocalInfo.Attributes&cache.AttrReadOnly != 0 {
+		if err := setLocalAttributes(decision.LocalPath, 0, cache.AttrReadOnly); err != nil {
+			ex.log(ctx).Warn("failed to clear local read-only attribute", zap.String("path", decision.LocalPath), zap.Error(err))
+		}
+	}
+
+	// The losing version of a conflict is copied aside (once across retries)
+	if decision.ConflictCopy != "" && action.ConflictCopy == "" {
+		copyPath, err := keepLocalConflictCopy(decision.LocalPath, decision.ConflictCopy)
+		if err != nil {
+			return WrapSyncError(err, decision.LocalPath, "conflict_copy")
+		}
+		action.ConflictCopy = copyPath
+		ex.log(ctx).Info("local conflict loser kept",
+			zap.String("path", decision.LocalPath),
+			zap.String("copy", copyPath),
+		)
+	}
+
+	hash, err := smbClient.DownloadWithHashContext(ctx, decision.RemotePath, decision.LocalPath)
+	if err != nil {
 		return WrapSyncError(err, decision.LocalPath, "download")
 	}
+	action.Hash = hash
+	action.ChangedBy = ex.remoteOwner(ctx, smbClient, decision.RemotePath)
+	if action.ConflictCopy != "" {
+		action.ConflictOwner = action.ChangedBy
+	}
+
+	// Apply remote attribute bits when attribute sync is enabled
+	if decision.RemoteInfo != nil && decision.RemoteInfo.Attributes != 0 {
+		if err := setLocalAttributes(decision.LocalPath, decision.RemoteInfo.Attributes, cache.TrackedAttributes); err != nil {
+			ex.log(ctx).Warn("failed to apply remote attributes", zap.String("path", decision.LocalPath), zap.Error(err))
+		}
+		action.Attributes = decision.RemoteInfo.Attributes
+	}
 
 	// Get actual size after download
 	info, err := os.Stat(decision.LocalPath)
 	if err == nil {
 		action.Size = info.Size()
+		action.MTime = info.ModTime()
 	}
 
 	action.BytesTransferred = action.Size
 
-	ex.logger.Info("file downloaded",
+	ex.log(ctx).Info("file downloaded",
 		zap.String("path", decision.LocalPath),
 		zap.Int64("size", action.Size),
 		zap.Duration("duration", action.Duration),
@@ -285,7 +619,7 @@ func (ex *Executor) executeDeleteLocal(
 	action *SyncAction,
 ) error {
 
-	ex.logger.Debug("deleting local file",
+	ex.log(ctx).Debug("deleting local file",
 		zap.String("path", decision.LocalPath),
 	)
 
@@ -299,13 +633,13 @@ func (ex *Executor) executeDeleteLocal(
 	if err := os.Remove(decision.LocalPath); err != nil {
 		// Ignore "file not found" errors (race condition acceptable)
 		if os.IsNotExist(err) {
-			ex.logger.Debug("file already deleted", zap.String("path", decision.LocalPath))
+			ex.log(ctx).Debug("file already deleted", zap.String("path", decision.LocalPath))
 			return nil
 		}
 		return WrapSyncError(err, decision.LocalPath, "delete_local")
 	}
 
-	ex.logger.Info("local file deleted",
+	ex.log(ctx).Info("local file deleted",
 		zap.String("path", decision.LocalPath),
 	)
 
@@ -320,7 +654,7 @@ func (ex *Executor) executeDeleteRemote(
 	action *SyncAction,
 ) error {
 
-	ex.logger.Debug("deleting remote file",
+	ex.log(ctx).Debug("deleting remote file",
 		zap.String("path", decision.RemotePath),
 	)
 
@@ -330,39 +664,90 @@ func (ex *Executor) executeDeleteRemote(
 	}
 
 	// Delete file
-	if err := smbClient.Delete(decision.RemotePath); err != nil {
+	if err := smbClient.DeleteContext(ctx, decision.RemotePath); err != nil {
 		// Check if file not found (acceptable race condition)
 		if isFileNotFoundError(err) {
-			ex.logger.Debug("remote file already deleted", zap.String("path", decision.RemotePath))
+			ex.log(ctx).Debug("remote file already deleted", zap.String("path", decision.RemotePath))
 			return nil
 		}
 		return WrapSyncError(err, decision.RemotePath, "delete_remote")
 	}
 
-	ex.logger.Info("remote file deleted",
+	ex.log(ctx).Info("remote file deleted",
+		zap.String("path", decision.RemotePath),
+	)
+
+	return nil
+}
+
+// executeSetAttrLocal applies remote attribute bits to an in-sync local file
+func (ex *Executor) executeSetAttrLocal(
+	ctx context.Context,
+	decision *cache.SyncDecision,
+	action *SyncAction,
+) error {
+
+	if decision.LocalInfo == nil || decision.RemoteInfo == nil {
+		return fmt.Errorf("attribute sync requires local and remote state")
+	}
+
+	diff := (decision.LocalInfo.Attributes ^ decision.RemoteInfo.Attributes) & cache.TrackedAttributes
+	if err := setLocalAttributes(decision.LocalPath, decision.RemoteInfo.Attributes, diff); err != nil {
+		return WrapSyncError(err, decision.LocalPath, "set_attributes_local")
+	}
+
+	// Content is unchanged: keep size and hash in the cache
+	action.Size = decision.LocalInfo.Size
+	action.Hash = decision.LocalInfo.Hash
+	action.Attributes = decision.RemoteInfo.Attributes
+
+	ex.log(ctx).Info("local attributes updated",
+		zap.String("path", decision.LocalPath),
+		zap.Uint32("attributes", decision.RemoteInfo.Attributes),
+	)
+
+	return nil
+}
+
+// executeSetAttrRemote applies the local read-only bit to an in-sync remote file
+func (ex *Executor) executeSetAttrRemote(
+	ctx context.Context,
+	decision *cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	action *SyncAction,
+) error {
+
+	if decision.LocalInfo == nil {
+		return fmt.Errorf("attribute sync requires local state")
+	}
+
+	readOnly := decision.LocalInfo.Attributes&cache.AttrReadOnly != 0
+	if err := smbClient.SetReadOnlyContext(ctx, decision.RemotePath, readOnly); err != nil {
+		return WrapSyncError(err, decision.RemotePath, "set_attributes_remote")
+	}
+
+	// Content is unchanged: keep size and hash in the cache
+	action.Size = decision.LocalInfo.Size
+	action.Hash = decision.LocalInfo.Hash
+	action.Attributes = decision.LocalInfo.Attributes
+
+	ex.log(ctx).Info("remote attributes updated",
 		zap.String("path", decision.RemotePath),
+		zap.Bool("read_only", readOnly),
 	)
 
 	return nil
 }
 
-// prioritizeActions sorts actions to minimize data loss risk
-// Priority order: Downloads → Uploads → Deletes
-func (ex *Executor) prioritizeActions(decisions []*cache.SyncDecision) []*cache.SyncDecision {
+// prioritizeActions sorts actions to minimize data loss risk (downloads,
+// uploads, metadata, then deletions), each kind in the configured transfer order.
+// root is the local job root, for folder priorities.
+func (ex *Executor) prioritizeActions(decisions []*cache.SyncDecision, root string) []*cache.SyncDecision {
 	// Create a copy to avoid modifying the original
 	prioritized := make([]*cache.SyncDecision, len(decisions))
 	copy(prioritized, decisions)
 
-	// Simple bubble sort by priority (good enough for now)
-	n := len(prioritized)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if actionPriority(prioritized[j].Action) > actionPriority(prioritized[j+1].Action) {
-				prioritized[j], prioritized[j+1] = prioritized[j+1], prioritized[j]
-			}
-		}
-	}
-
+	ex.order.sortDecisions(prioritized, root)
 	return prioritized
 }
 
@@ -373,10 +758,12 @@ func actionPriority(action cache.SyncAction) int {
 		return 1 // Download first (get remote data)
 	case cache.ActionUpload:
 		return 2 // Upload second (send local data)
+	case cache.ActionSetAttrLocal, cache.ActionSetAttrRemote, cache.ActionRenameLocal, cache.ActionRenameRemote:
+		return 3 // Metadata-only changes after content
 	case cache.ActionDeleteLocal, cache.ActionDeleteRemote:
-		return 3 // Delete last (minimize data loss)
+		return 4 // Delete last (minimize data loss)
 	default:
-		return 4 // Unknown actions last
+		return 5 // Unknown actions last
 	}
 }
 
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// --- Failed Files ---
+//
+// The executor retries an action failing on a transient error (see the retry
+// settings of the config). A file still failing after its retries, or failing
+// on an error no retry fixes, is recorded in failed_files: it isn't in the
+// cache, so the next run detects and retries it, and the record keeps how many
+// runs in a row it failed. A file is forgotten once an action on it succeeds.
+
+// failedFileRecords lists the files of a run whose action failed and those
+// synced, with job-relative paths.
+func failedFileRecords(req *SyncRequest, result *SyncResult) (failed []*database.FailedFile, synced []string) {
+	for _, action := range result.Actions {
+		relPath := toRelativePath(action.FilePath, req.LocalPath)
+		switch action.Status {
+		case ActionStatusSuccess:
+			synced = append(synced, relPath)
+		case ActionStatusFailed:
+			if action.Error == nil {
+				continue
+			}
+			failed = append(failed, &database.FailedFile{
+				JobID:        req.JobID,
+				Path:         relPath,
+				Action:       string(action.Action),
+				Error:        action.Error.Error(),
+				Attempts:     max(action.Attempts, 1),
+				LastFailedAt: action.Timestamp,
+			})
+		}
+	}
+	return failed, synced
+}
+
+// recordFailedFiles records the files of a run still failing after their
+// retries and forgets those synced.
+func (e *Engine) recordFailedFiles(ctx context.Context, req *SyncRequest, result *SyncResult) error {
+	failed, synced := failedFileRecords(req, result)
+	if err := e.db.RecordFailedFiles(req.JobID, failed, synced); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		e.log(ctx).Warn("files still failing after their retries, retrying them next run",
+			zap.Int64("job_id", req.JobID),
+			zap.Int("files", len(failed)))
+	}
+	return nil
+}
+
+// logRetriedFiles logs the files of a run that failed in previous runs.
+// Decisions hold job-relative paths.
+func (e *Engine) logRetriedFiles(ctx context.Context, req *SyncRequest, decisions []*cache.SyncDecision) {
+	previous, err := e.db.GetFailedFiles(req.JobID)
+	if err != nil {
+		e.log(ctx).Warn("failed to read failed files", zap.Error(err))
+		return
+	}
+	if len(previous) == 0 {
+		return
+	}
+	for _, decision := range decisions {
+		if f := previous[filepath.ToSlash(decision.LocalPath)]; f != nil {
+			e.log(ctx).Info("retrying file failed by previous runs",
+				zap.String("path", f.Path),
+				zap.String("action", string(decision.Action)),
+				zap.Int("failed_runs", f.Runs),
+				zap.String("last_error", f.Error))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestFailedFileRecords(t *testing.T) {
+	req := &SyncRequest{JobID: 3, LocalPath: "/sync"}
+	result := &SyncResult{Actions: []*SyncAction{
+		{FilePath: "/sync/ok.txt", Action: cache.ActionUpload, Status: ActionStatusSuccess, Attempts: 2},
+		{FilePath: "/sync/docs/busy.pst", Action: cache.ActionUpload, Status: ActionStatusFailed,
+			Error: errors.New("sharing violation"), Attempts: 4},
+		{FilePath: "/sync/screened.exe", Action: cache.ActionUpload, Status: ActionStatusFailed,
+			Error: errors.New("scan failed")},
+		{FilePath: "/sync/later.db", Action: cache.ActionUpload, Status: ActionStatusSkipped},
+	}}
+
+	failed, synced := failedFileRecords(req, result)
+	if len(synced) != 1 || synced[0] != "ok.txt" {
+		t.Errorf("synced = %v, want [ok.txt]", synced)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("got %d failed files, want 2: %+v", len(failed), failed)
+	}
+	if f := failed[0]; f.Path != "docs/busy.pst" || f.JobID != 3 || f.Attempts != 4 || f.Error != "sharing violation" {
+		t.Errorf("unexpected failed file %+v", f)
+	}
+	if f := failed[1]; f.Attempts != 1 {
+		t.Errorf("action failed before running: %d attempts, want 1", f.Attempts)
+	}
+}
+
+func TestRetryPolicyFromConfig(t *testing.T) {
+	def := DefaultRetryPolicy(zap.NewNop())
+	if p := retryPolicy(config.RetryConfig{}, zap.NewNop()); p.MaxRetries != def.MaxRetries || p.InitialDelay != def.InitialDelay {
+		t.Errorf("unset retries: got %+v, want the default policy", p)
+	}
+
+	p := retryPolicy(config.RetryConfig{MaxAttempts: 6, InitialDelaySeconds: 2, MaxDelaySeconds: 60}, zap.NewNop())
+	if p.MaxRetries != 5 || p.InitialDelay != 2*time.Second || p.MaxDelay != time.Minute || !p.OnlyRetryableErrors {
+		t.Errorf("unexpected policy %+v", p)
+	}
+
+	if p := retryPolicy(config.RetryConfig{MaxAttempts: 1}, zap.NewNop()); p.MaxRetries != 0 {
+		t.Errorf("one attempt: MaxRetries = %d, want 0", p.MaxRetries)
+	}
+	if p := retryPolicy(config.RetryConfig{MaxAttempts: 3, InitialDelaySeconds: 90}, zap.NewNop()); p.MaxDelay != 90*time.Second {
+		t.Errorf("MaxDelay = %v, want raised to the initial delay", p.MaxDelay)
+	}
+}
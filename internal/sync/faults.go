@@ -0,0 +1,148 @@
+//go:build faultinject
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"go.uber.org/zap"
+)
+
+// --- Failure Injection (test builds only) ---
+//
+// Builds with the faultinject tag read ANEMONESYNC_FAULTS, a comma-separated
+// list of key=value settings, to make transfers of the executor fail or slow
+// down so that retries, partial runs and failure reporting can be exercised
+// end to end:
+//
+//	fail_nth=N      fail the Nth transfer attempt (retries count as attempts)
+//	slow_write=D    wait D (e.g. 200ms) before every transfer
+//	smb_error=CODE  error of failed transfers: an NTSTATUS name or number
+//	                (e.g. STATUS_IO_TIMEOUT or 0xC00000B5); without fail_nth,
+//	                every transfer fails with it
+//
+// Example: go build -tags faultinject ./cmd/anemonesync, then run it with
+// ANEMONESYNC_FAULTS=fail_nth=3,smb_error=STATUS_SHARING_VIOLATION.
+
+// FaultsEnvVar holds the failure injection settings
+const FaultsEnvVar = "ANEMONESYNC_FAULTS"
+
+// errInjectedFailure is returned by failed transfers when no SMB error is set
+var errInjectedFailure = errors.New("injected transfer failure")
+
+// ntStatusCodes maps the NTSTATUS names accepted by smb_error to their codes
+var ntStatusCodes = map[string]uint32{
+	"STATUS_ACCESS_DENIED":         0xC0000022,
+	"STATUS_SHARING_VIOLATION":     0xC0000043,
+	"STATUS_DISK_FULL":             0xC000007F,
+	"STATUS_IO_TIMEOUT":            0xC00000B5,
+	"STATUS_NETWORK_NAME_DELETED":  0xC00000C9,
+	"STATUS_OBJECT_NAME_NOT_FOUND": 0xC0000034,
+	"STATUS_USER_SESSION_DELETED":  0xC0000203,
+}
+
+// faultInjector makes transfers fail or slow down as configured
+type faultInjector struct {
+	failNth   int64         // Transfer attempt to fail (0 = none)
+	slowWrite time.Duration // Delay before every transfer
+	smbError  error         // Error of failed transfers (nil = errInjectedFailure)
+
+	attempts atomic.Int64 // Transfer attempts so far
+}
+
+// loadFaultInjector reads the failure injection settings from the environment
+// (nil if unset). Invalid settings are logged and ignored.
+func loadFaultInjector(logger *zap.Logger) *faultInjector {
+	spec := os.Getenv(FaultsEnvVar)
+	if spec == "" {
+		return nil
+	}
+	f, err := parseFaults(spec)
+	if err != nil {
+		logger.Error("invalid failure injection settings", zap.String("env", FaultsEnvVar), zap.Error(err))
+		return nil
+	}
+	logger.Warn("failure injection enabled",
+		zap.Int64("fail_nth", f.failNth),
+		zap.Duration("slow_write", f.slowWrite),
+		zap.Error(f.smbError),
+	)
+	return f
+}
+
+// parseFaults parses failure injection settings
+func parseFaults(spec string) (*faultInjector, error) {
+	f := &faultInjector{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %q", part)
+		}
+
+		switch strings.TrimSpace(key) {
+		case "fail_nth":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("fail_nth must be a positive number, got %q", value)
+			}
+			f.failNth = n
+		case "slow_write":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("slow_write must be a duration, got %q", value)
+			}
+			f.slowWrite = d
+		case "smb_error":
+			code, ok := ntStatusCodes[strings.ToUpper(value)]
+			if !ok {
+				n, err := strconv.ParseUint(value, 0, 32)
+				if err != nil {
+					return nil, fmt.Errorf("unknown NTSTATUS %q", value)
+				}
+				code = uint32(n)
+			}
+			f.smbError = &smb2.ResponseError{Code: code}
+		default:
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return f, nil
+}
+
+// beforeTransfer is called before every transfer attempt of the executor and
+// returns the injected error, if any.
+func (f *faultInjector) beforeTransfer(ctx context.Context, action cache.SyncAction) error {
+	if f == nil || (action != cache.ActionUpload && action != cache.ActionDownload) {
+		return nil
+	}
+
+	attempt := f.attempts.Add(1)
+	if f.slowWrite > 0 {
+		select {
+		case <-time.After(f.slowWrite):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if attempt == f.failNth || (f.failNth == 0 && f.smbError != nil) {
+		if f.smbError != nil {
+			return f.smbError
+		}
+		return errInjectedFailure
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !faultinject
+
+package sync
+
+import (
+	"context"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"go.uber.org/zap"
+)
+
+// faultInjector is only implemented in builds with the faultinject tag (see faults.go)
+type faultInjector struct{}
+
+func loadFaultInjector(logger *zap.Logger) *faultInjector { return nil }
+
+func (f *faultInjector) beforeTransfer(ctx context.Context, action cache.SyncAction) error {
+	return nil
+}
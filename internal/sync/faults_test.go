@@ -0,0 +1,118 @@
+//go:build faultinject
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"go.uber.org/zap"
+)
+
+func TestParseFaults(t *testing.T) {
+	f, err := parseFaults("fail_nth=3, slow_write=10ms,smb_error=STATUS_SHARING_VIOLATION")
+	if err != nil {
+		t.Fatalf("parseFaults failed: %v", err)
+	}
+	if f.failNth != 3 || f.slowWrite != 10*time.Millisecond {
+		t.Errorf("unexpected settings: %+v", f)
+	}
+	var respErr *smb2.ResponseError
+	if !errors.As(f.smbError, &respErr) || respErr.Code != 0xC0000043 {
+		t.Errorf("expected STATUS_SHARING_VIOLATION, got %v", f.smbError)
+	}
+
+	if f, err := parseFaults("smb_error=0xC00000B5"); err != nil || f.smbError.(*smb2.ResponseError).Code != 0xC00000B5 {
+		t.Errorf("expected numeric NTSTATUS, got %v (err=%v)", f, err)
+	}
+
+	for _, spec := range []string{"fail_nth=0", "fail_nth", "slow_write=fast", "smb_error=STATUS_NOPE", "crash=1"} {
+		if _, err := parseFaults(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestFaultInjector_FailNth(t *testing.T) {
+	f, _ := parseFaults("fail_nth=2")
+	ctx := context.Background()
+
+	if err := f.beforeTransfer(ctx, cache.ActionDeleteRemote); err != nil {
+		t.Errorf("deletes are not transfers: %v", err)
+	}
+	if err := f.beforeTransfer(ctx, cache.ActionUpload); err != nil {
+		t.Errorf("attempt 1 should pass: %v", err)
+	}
+	if err := f.beforeTransfer(ctx, cache.ActionDownload); !errors.Is(err, errInjectedFailure) {
+		t.Errorf("attempt 2 should fail, got %v", err)
+	}
+	if err := f.beforeTransfer(ctx, cache.ActionUpload); err != nil {
+		t.Errorf("attempt 3 should pass: %v", err)
+	}
+}
+
+func TestExecutor_InjectedSMBError(t *testing.T) {
+	tests := []struct {
+		name         string
+		smbError     string
+		wantAttempts int64
+	}{
+		{"transient error is retried", "STATUS_IO_TIMEOUT", 3},
+		{"permanent error is not retried", "STATUS_ACCESS_DENIED", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(FaultsEnvVar, "smb_error="+tt.smbError)
+
+			executor := NewExecutor(1, zap.NewNop())
+			if executor.faults == nil {
+				t.Fatal("expected failure injection enabled")
+			}
+			policy := DefaultRetryPolicy(zap.NewNop())
+			policy.MaxRetries = 2
+			policy.InitialDelay = time.Millisecond
+			policy.MaxDelay = time.Millisecond
+			executor.SetRetryPolicy(policy)
+
+			// Deletes still run, so a partial run can be checked
+			tempDir := t.TempDir()
+			gone := filepath.Join(tempDir, "gone.txt")
+			os.WriteFile(gone, []byte("x"), 0644)
+
+			decisions := []*cache.SyncDecision{
+				{LocalPath: filepath.Join(tempDir, "a.txt"), RemotePath: "a.txt", Action: cache.ActionUpload},
+				{LocalPath: gone, RemotePath: "gone.txt", Action: cache.ActionDeleteLocal},
+			}
+			actions, err := executor.Execute(context.Background(), decisions, nil, nil)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			statuses := map[cache.SyncAction]*SyncAction{}
+			for _, a := range actions {
+				statuses[a.Action] = a
+			}
+			upload := statuses[cache.ActionUpload]
+			if upload == nil || upload.Status != ActionStatusFailed {
+				t.Fatalf("expected failed upload, got %+v", upload)
+			}
+			var respErr *smb2.ResponseError
+			if !errors.As(upload.Error, &respErr) {
+				t.Errorf("expected SMB response error, got %v", upload.Error)
+			}
+			if got := executor.faults.attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+			if del := statuses[cache.ActionDeleteLocal]; del == nil || del.Status != ActionStatusSuccess {
+				t.Errorf("expected delete to succeed, got %+v", del)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
+	"go.uber.org/zap"
+)
+
+// FileTypes returns the per file type rules of the engine (nil = none), so
+// other modules (e.g. dehydration) apply the same rules.
+func (e *Engine) FileTypes() *filetype.Policy {
+	return e.fileTypes
+}
+
+// excludeFileTypes removes the uploads of file types the policy excludes
+// from upload, and the server side of files moved locally to such a type.
+// Returns the decisions to execute and one skipped action per
+// removed upload.
+func (e *Engine) excludeFileTypes(ctx context.Context, req *SyncRequest,
+	decisions []*cache.SyncDecision) ([]*cache.SyncDecision, []*SyncAction) {
+
+	if e.fileTypes == nil {
+		return decisions, nil
+	}
+
+	allowed := make([]*cache.SyncDecision, 0, len(decisions))
+	var excluded []*SyncAction
+	for _, decision := range decisions {
+		if decision.Action != cache.ActionUpload && decision.Action != cache.ActionRenameRemote {
+			allowed = append(allowed, decision)
+			continue
+		}
+		ok, reason := e.fileTypes.AllowsUpload(decision.LocalPath)
+		if ok {
+			allowed = append(allowed, decision)
+			continue
+		}
+		// A file moved locally to an excluded name is only deleted from the server
+		if decision.Action == cache.ActionRenameRemote {
+			allowed = append(allowed, &cache.SyncDecision{
+				LocalPath:  decision.OldLocalPath,
+				RemotePath: decision.OldRemotePath,
+				Action:     cache.ActionDeleteRemote,
+				Reason:     "file deleted locally, remove from remote",
+				RemoteInfo: decision.RemoteInfo,
+				CachedInfo: decision.CachedInfo,
+			})
+		}
+
+		e.log(ctx).Debug("upload excluded by file type",
+			zap.String("path", decision.LocalPath),
+			zap.String("reason", reason))
+		var size int64
+		if decision.LocalInfo != nil {
+			size = decision.LocalInfo.Size
+		}
+		excluded = append(excluded, &SyncAction{
+			FilePath:   filepath.Join(req.LocalPath, filepath.FromSlash(decision.LocalPath)),
+			RemotePath: decision.RemotePath,
+			Action:     cache.ActionUpload,
+			Status:     ActionStatusSkipped,
+			Size:       size,
+			Timestamp:  timeNow(),
+		})
+	}
+
+	if len(excluded) > 0 {
+		e.log(ctx).Info("uploads excluded by file type",
+			zap.Int("count", len(excluded)),
+			zap.String("policy", e.fileTypes.String()))
+	}
+	return allowed, excluded
+}
@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/filetype"
+	"go.uber.org/zap"
+)
+
+func TestExcludeFileTypes(t *testing.T) {
+	policy, err := filetype.NewPolicy(nil, nil, []string{"executable"})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	e := &Engine{logger: zap.NewNop(), fileTypes: policy}
+	req := &SyncRequest{LocalPath: "sync"}
+
+	decisions := []*cache.SyncDecision{
+		{LocalPath: "tools/setup.exe", Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Size: 100}},
+		{LocalPath: "notes.txt", Action: cache.ActionUpload},
+		{LocalPath: "remote.exe", Action: cache.ActionDownload},
+	}
+
+	allowed, excluded := e.excludeFileTypes(context.Background(), req, decisions)
+	if len(allowed) != 2 || allowed[0].LocalPath != "notes.txt" || allowed[1].LocalPath != "remote.exe" {
+		t.Errorf("allowed = %v, want notes.txt and remote.exe", allowed)
+	}
+	if len(excluded) != 1 {
+		t.Fatalf("expected 1 excluded upload, got %d", len(excluded))
+	}
+	if excluded[0].Status != ActionStatusSkipped || excluded[0].Size != 100 {
+		t.Errorf("excluded action = %+v, want skipped with size 100", excluded[0])
+	}
+
+	// No policy: nothing excluded
+	e.fileTypes = nil
+	allowed, excluded = e.excludeFileTypes(context.Background(), req, decisions)
+	if len(allowed) != 3 || len(excluded) != 0 {
+		t.Errorf("without policy: %d allowed, %d excluded, want 3 and 0", len(allowed), len(excluded))
+	}
+}
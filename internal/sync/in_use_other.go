@@ -0,0 +1,15 @@
+//go:build !windows
+
+package sync
+
+// fileInUse reports whether another process has a local file open for
+// writing: never known outside Windows.
+func fileInUse(path string) bool {
+	return false
+}
+
+// isSharingViolation reports whether err comes from opening a file another
+// process keeps open without sharing it: never outside Windows.
+func isSharingViolation(err error) bool {
+	return false
+}
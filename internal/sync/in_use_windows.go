@@ -0,0 +1,33 @@
+//go:build windows
+
+package sync
+
+import (
+	"errors"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"golang.org/x/sys/windows"
+)
+
+// fileInUse reports whether another process has a local file open for
+// writing: opening it while only sharing reads fails with a sharing violation.
+func fileInUse(path string) bool {
+	pathPtr, err := windows.UTF16PtrFromString(longpath.Fix(path))
+	if err != nil {
+		return false
+	}
+
+	handle, err := windows.CreateFile(pathPtr, windows.GENERIC_READ,
+		windows.FILE_SHARE_READ, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return isSharingViolation(err)
+	}
+	windows.CloseHandle(handle)
+	return false
+}
+
+// isSharingViolation reports whether err comes from opening a file another
+// process keeps open without sharing it.
+func isSharingViolation(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
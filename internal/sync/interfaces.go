@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+)
+
+// Scanner scans the local folder of a job (implemented by *scanner.Scanner)
+type Scanner interface {
+	Scan(ctx context.Context, req scanner.ScanRequest) (*scanner.ScanResult, error)
+}
+
+// CacheManager reads and writes the sync baseline of a job (implemented by *cache.CacheManager)
+type CacheManager interface {
+	GetAllCachedFiles(jobID int64) (map[string]*cache.FileInfo, error)
+	GetCachedState(jobID int64, localPath string) (*cache.FileInfo, error)
+	UpdateCacheBatch(jobID int64, updates map[string]*cache.FileInfo, remotePaths map[string]string) error
+}
+
+// ChangeDetector compares local, remote and cached state into sync decisions
+// (implemented by *cache.ChangeDetector)
+type ChangeDetector interface {
+	BatchDetermineSyncActions(jobID int64, files map[string]*cache.FileInfo, remoteFiles map[string]*cache.FileInfo) ([]*cache.SyncDecision, error)
+}
+
+// ActionExecutor executes sync decisions (implemented by *Executor)
+type ActionExecutor interface {
+	ExecuteWithCommit(ctx context.Context, decisions []*cache.SyncDecision, smbClient *smb.SMBClient,
+		progressFn ProgressCallback, commitFn CommitFunc) ([]*SyncAction, error)
+}
+
+// EngineOption replaces a default component of the engine, e.g. with a fake in tests
+type EngineOption func(*Engine)
+
+// WithScanner makes the engine scan local folders with s
+func WithScanner(s Scanner) EngineOption {
+	return func(e *Engine) { e.scanner = s }
+}
+
+// WithCacheManager makes the engine keep baselines in c
+func WithCacheManager(c CacheManager) EngineOption {
+	return func(e *Engine) { e.cache = c }
+}
+
+// WithChangeDetector makes the engine compute sync decisions with d
+func WithChangeDetector(d ChangeDetector) EngineOption {
+	return func(e *Engine) { e.detector = d }
+}
+
+// WithExecutor makes the engine execute sync decisions with ex
+func WithExecutor(ex ActionExecutor) EngineOption {
+	return func(e *Engine) { e.executor = ex }
+}
+
+// WithClock makes the engine read the time from c
+func WithClock(c clock.Clock) EngineOption {
+	return func(e *Engine) { e.clock = clock.Or(c) }
+}
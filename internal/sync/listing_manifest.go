@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/manifest"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+)
+
+// --- Listing Manifests ---
+
+// ListingManifest lists one side of a job (manifest.SideLocal or
+// manifest.SideRemote) for export. Files get the hash recorded by the last
+// sync when their size and time still match it. Nothing is synced and the
+// job keeps its status; an incomplete listing is an error, as a manifest
+// missing files would mislead an audit.
+func (e *Engine) ListingManifest(ctx context.Context, jobID int64, side string) (*manifest.Manifest, error) {
+	job, err := e.db.GetSyncJob(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("job %d not found", jobID)
+	}
+
+	var files map[string]*cache.FileInfo
+	root := job.LocalPath
+	switch side {
+	case manifest.SideLocal:
+		files, err = listLocalFiles(ctx, job.LocalPath)
+	case manifest.SideRemote:
+		root = job.RemotePath
+		files, err = e.listRemoteFiles(ctx, job.RemotePath)
+	default:
+		return nil, fmt.Errorf("unknown side %q (use %s or %s)", side, manifest.SideLocal, manifest.SideRemote)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := e.cache.GetAllCachedFiles(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]manifest.Entry, 0, len(files))
+	for relPath, f := range files {
+		entry := manifest.Entry{Path: relPath, Size: f.Size, MTime: f.MTime.Unix()}
+		if c := cached[relPath]; c != nil && c.Size == f.Size && c.MTime.Unix() == entry.MTime {
+			entry.Hash = c.Hash
+		}
+		entries = append(entries, entry)
+	}
+	return manifest.New(job.ID, job.Name, side, root, entries), nil
+}
+
+// ScanRemoteFiles lists the remote files of a job, keyed by job-relative
+// path, like the remote scan of a sync: from the Anemone manifest, or listing
+// only the folders changed since the last scan (remote listing cache). Nothing
+// is synced and the job keeps its status.
+func (e *Engine) ScanRemoteFiles(ctx context.Context, jobID int64) (map[string]*cache.FileInfo, error) {
+	job, err := e.db.GetSyncJob(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("job %d not found", jobID)
+	}
+	selection, err := e.jobSelection(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	smbClient, err := e.connectRemote(job.RemotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer smbClient.Disconnect()
+
+	files, _, err := e.scanRemote(ctx, smbClient, jobID, job.RemotePath, "", selection)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err // Incomplete listing
+	}
+	return files, nil
+}
+
+// listLocalFiles returns the files under root, keyed by relative path.
+func listLocalFiles(ctx context.Context, root string) (map[string]*cache.FileInfo, error) {
+	files := make(map[string]*cache.FileInfo)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath := toRelativePath(path, root)
+		files[relPath] = &cache.FileInfo{Path: relPath, Size: info.Size(), MTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// connectRemote connects to the share of a UNC path with the saved
+// credentials of its server.
+func (e *Engine) connectRemote(remotePath string) (*smb.SMBClient, error) {
+	server, share, _ := parseUNCPath(remotePath)
+	if server == "" || share == "" {
+		return nil, fmt.Errorf("invalid remote path: %s", remotePath)
+	}
+
+	smbClient, err := smb.NewSMBClientFromKeyring(server, share, e.logger.Named("smb"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SMB client: %w", err)
+	}
+	if srv, err := e.db.GetSMBServerByHost(server); err == nil && srv != nil {
+		smbClient.SetMTimeSource(smb.MTimeSource(srv.MTimeSource))
+	}
+	if err := smbClient.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to SMB server: %w", err)
+	}
+	return smbClient, nil
+}
+
+// listRemoteFiles lists the files under a UNC path, keyed by relative path.
+func (e *Engine) listRemoteFiles(ctx context.Context, remotePath string) (map[string]*cache.FileInfo, error) {
+	smbClient, err := e.connectRemote(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer smbClient.Disconnect()
+
+	_, _, relPath := parseUNCPath(remotePath)
+	if relPath == "" {
+		relPath = "." // Share root
+	}
+	result, err := NewRemoteScanner(smbClient, e.logger.Named("remote_scanner"), nil).Scan(ctx, relPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		msgs := make([]string, 0, len(result.Errors))
+		for _, err := range result.Errors {
+			msgs = append(msgs, err.Error())
+		}
+		return nil, fmt.Errorf("%d folders could not be listed: %s", len(result.Errors), strings.Join(msgs, "; "))
+	}
+	return result.Files, nil
+}
@@ -0,0 +1,31 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListLocalFiles(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, data := range map[string]string{"a.txt": "a", "sub/b.txt": "bb"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(path)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := listLocalFiles(context.Background(), root)
+	if err != nil {
+		t.Fatalf("listLocalFiles() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2 (folders are not listed)", len(files))
+	}
+	if f := files["sub/b.txt"]; f == nil || f.Size != 2 {
+		t.Errorf("sub/b.txt = %+v", f)
+	}
+}
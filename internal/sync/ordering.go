@@ -0,0 +1,220 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// --- Deletion Ordering ---
+//
+// Deletions run once every transfer of their side has completed, not merely
+// started as parallel workers would have them, so a deletion never races a
+// transfer into the same folder (see dependencies.go). Two kinds of deletions
+// run before the transfers instead, because they free the path a transfer
+// writes to:
+//   - a case-only rename: "Report.txt" replacing "report.txt" is the same file
+//     on Windows and on SMB shares, and deleting the old name after the
+//     transfer would delete the new file;
+//   - a file replaced by a folder: "a" is deleted before "a/x.txt" is written.
+//
+// The opposite case, a folder replaced by a file, can only be written once
+// the files of the folder are deleted: those transfers fail first, then are
+// retried after the deletions, once the emptied folders are removed.
+
+// executionPhases splits the decisions of a run in the order they execute.
+type executionPhases struct {
+	early     []*cache.SyncDecision // Deletions freeing the path of a transfer
+	transfers []*cache.SyncDecision // Transfers and metadata changes
+	deletions []*cache.SyncDecision // Other deletions
+}
+
+// splitPhases splits prioritized decisions into execution phases, keeping
+// their order within each phase.
+func splitPhases(decisions []*cache.SyncDecision) executionPhases {
+	// Paths written by transfers and their folders, per side (true = remote)
+	written := map[bool]map[string]bool{false: {}, true: {}}
+	folders := map[bool]map[string]bool{false: {}, true: {}}
+	for _, d := range decisions {
+		if path, remote, ok := writtenPath(d); ok {
+			key := pathKey(path)
+			written[remote][key] = true
+			for dir := parentKey(key); dir != ""; dir = parentKey(dir) {
+				folders[remote][dir] = true
+			}
+		}
+	}
+
+	var phases executionPhases
+	for _, d := range decisions {
+		path, remote, ok := deletedPath(d)
+		switch {
+		case !ok:
+			phases.transfers = append(phases.transfers, d)
+		case written[remote][pathKey(path)] || folders[remote][pathKey(path)]:
+			phases.early = append(phases.early, d)
+		default:
+			phases.deletions = append(phases.deletions, d)
+		}
+	}
+	return phases
+}
+
+// writtenPath returns the path a transfer writes to and whether it is remote.
+func writtenPath(d *cache.SyncDecision) (path string, remote, ok bool) {
+	switch d.Action {
+	case cache.ActionUpload:
+		return d.RemotePath, true, true
+	case cache.ActionDownload:
+		return d.LocalPath, false, true
+	}
+	return "", false, false
+}
+
+// deletedPath returns the path a deletion removes and whether it is remote.
+func deletedPath(d *cache.SyncDecision) (path string, remote, ok bool) {
+	switch d.Action {
+	case cache.ActionDeleteRemote:
+		return d.RemotePath, true, true
+	case cache.ActionDeleteLocal:
+		return d.LocalPath, false, true
+	}
+	return "", false, false
+}
+
+// pathKey normalizes a path for comparisons: Windows and SMB shares ignore case.
+func pathKey(path string) string {
+	return strings.ToLower(strings.TrimRight(strings.ReplaceAll(path, `\`, "/"), "/"))
+}
+
+// parentKey returns the folder of a path key ("" at the top).
+func parentKey(key string) string {
+	if i := strings.LastIndex(key, "/"); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// executePhase runs the decisions of a phase (nothing if there are none).
+func (ex *Executor) executePhase(
+	ctx context.Context,
+	decisions []*cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	progressFn ProgressCallback,
+	batcher *actionBatcher,
+) ([]*SyncAction, error) {
+	if len(decisions) == 0 {
+		return nil, nil
+	}
+	return ex.execute(ctx, decisions, smbClient, progressFn, batcher)
+}
+
+// retryBlockedTransfers retries the failed transfers writing to a folder the
+// deletions emptied (a folder replaced by a file), after removing the emptied
+// folders. transferred holds the actions of transfers, index for index; the
+// retried actions replace the failed ones.
+func (ex *Executor) retryBlockedTransfers(
+	ctx context.Context,
+	transfers []*cache.SyncDecision,
+	transferred []*SyncAction,
+	deletions []*cache.SyncDecision,
+	deleted []*SyncAction,
+	smbClient *smb.SMBClient,
+	progressFn ProgressCallback,
+	batcher *actionBatcher,
+) ([]*SyncAction, error) {
+	var retry []*cache.SyncDecision
+	var indexes []int
+	for i, action := range transferred {
+		if action == nil || action.Status != ActionStatusFailed || i >= len(transfers) {
+			continue
+		}
+		dirs := emptiedFolders(transfers[i], deletions, deleted)
+		if len(dirs) == 0 {
+			continue
+		}
+		_, remote, _ := writtenPath(transfers[i])
+		for _, dir := range dirs {
+			ex.removeEmptyFolder(ctx, dir, remote, smbClient)
+		}
+		retry = append(retry, transfers[i])
+		indexes = append(indexes, i)
+	}
+	if len(retry) == 0 {
+		return transferred, nil
+	}
+
+	ex.log(ctx).Info("retrying transfers blocked by deleted folders", zap.Int("count", len(retry)))
+	retried, err := ex.execute(ctx, retry, smbClient, progressFn, batcher)
+	for j, action := range retried {
+		if action != nil {
+			transferred[indexes[j]] = action
+		}
+	}
+	return transferred, err
+}
+
+// emptiedFolders returns the folders, deepest first, between the path a
+// transfer writes to and the files the deletions removed under it (nil if
+// none were removed).
+func emptiedFolders(transfer *cache.SyncDecision, deletions []*cache.SyncDecision, deleted []*SyncAction) []string {
+	target, remote, ok := writtenPath(transfer)
+	if !ok {
+		return nil
+	}
+	targetKey := pathKey(target)
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for i, d := range deletions {
+		if i >= len(deleted) || deleted[i] == nil || deleted[i].Status != ActionStatusSuccess {
+			continue
+		}
+		path, delRemote, _ := deletedPath(d)
+		if delRemote != remote || !strings.HasPrefix(pathKey(path), targetKey+"/") {
+			continue
+		}
+		for dir := parentPath(path); len(dir) >= len(targetKey); dir = parentPath(dir) {
+			if !seen[pathKey(dir)] {
+				seen[pathKey(dir)] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	sort.SliceStable(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	return dirs
+}
+
+// parentPath returns the folder of a local or remote path ("" at the top).
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(strings.TrimRight(path, `/\`), `/\`); i > 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// removeEmptyFolder removes a folder emptied by the deletions of the run.
+// Folders still holding files are left alone.
+func (ex *Executor) removeEmptyFolder(ctx context.Context, dir string, remote bool, smbClient *smb.SMBClient) {
+	var err error
+	if remote {
+		if smbClient == nil {
+			return
+		}
+		err = smbClient.DeleteContext(ctx, dir)
+	} else {
+		err = os.Remove(dir)
+	}
+	if err != nil {
+		ex.log(ctx).Debug("emptied folder not removed",
+			zap.String("path", dir),
+			zap.Bool("remote", remote),
+			zap.Error(err))
+	}
+}
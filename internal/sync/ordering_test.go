@@ -0,0 +1,165 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// orderingDecision returns a decision for a job-relative path, local under
+// C:/job and remote under share/job.
+func orderingDecision(action cache.SyncAction, relPath string) *cache.SyncDecision {
+	return &cache.SyncDecision{
+		LocalPath:  "C:/job/" + relPath,
+		RemotePath: "share/job/" + relPath,
+		Action:     action,
+	}
+}
+
+func TestSplitPhases(t *testing.T) {
+	tests := []struct {
+		name      string
+		decisions []*cache.SyncDecision
+		early     []string
+		transfers []string
+		deletions []string
+	}{
+		{
+			name: "delete remote + new local",
+			decisions: []*cache.SyncDecision{
+				orderingDecision(cache.ActionUpload, "new_file.txt"),
+				orderingDecision(cache.ActionDeleteLocal, "old_file.txt"),
+			},
+			transfers: []string{"new_file.txt"},
+			deletions: []string{"old_file.txt"},
+		},
+		{
+			name: "case-only rename, local",
+			decisions: []*cache.SyncDecision{
+				orderingDecision(cache.ActionUpload, "Docs/Report.txt"),
+				orderingDecision(cache.ActionDeleteRemote, "docs/report.txt"),
+			},
+			early:     []string{"docs/report.txt"},
+			transfers: []string{"Docs/Report.txt"},
+		},
+		{
+			name: "case-only rename, remote",
+			decisions: []*cache.SyncDecision{
+				orderingDecision(cache.ActionDownload, "PHOTO.JPG"),
+				orderingDecision(cache.ActionDeleteLocal, "photo.jpg"),
+			},
+			early:     []string{"photo.jpg"},
+			transfers: []string{"PHOTO.JPG"},
+		},
+		{
+			name: "file replaced by a folder",
+			decisions: []*cache.SyncDecision{
+				orderingDecision(cache.ActionUpload, "build/out/app.exe"),
+				orderingDecision(cache.ActionUpload, "build/readme.txt"),
+				orderingDecision(cache.ActionDeleteRemote, "build"),
+			},
+			early:     []string{"build"},
+			transfers: []string{"build/out/app.exe", "build/readme.txt"},
+		},
+		{
+			name: "folder replaced by a file",
+			decisions: []*cache.SyncDecision{
+				orderingDecision(cache.ActionUpload, "data"),
+				orderingDecision(cache.ActionDeleteRemote, "data/a.txt"),
+				orderingDecision(cache.ActionDeleteRemote, "data/sub/b.txt"),
+			},
+			transfers: []string{"data"},
+			deletions: []string{"data/a.txt", "data/sub/b.txt"},
+		},
+		{
+			name: "rename across folders and sides",
+			decisions: []*cache.SyncDecision{
+				orderingDecision(cache.ActionDownload, "new/doc.txt"),
+				orderingDecision(cache.ActionUpload, "moved/doc.txt"),
+				orderingDecision(cache.ActionSetAttrRemote, "kept.txt"),
+				orderingDecision(cache.ActionDeleteLocal, "old/doc.txt"),
+				orderingDecision(cache.ActionDeleteRemote, "moved"),       // Remote file "moved" replaced by a folder
+				orderingDecision(cache.ActionDeleteLocal, "moved"),        // Local side: nothing written there
+				orderingDecision(cache.ActionDeleteRemote, "new/doc.txt"), // Downloaded, not uploaded: other side
+			},
+			early:     []string{"moved"},
+			transfers: []string{"new/doc.txt", "moved/doc.txt", "kept.txt"},
+			deletions: []string{"old/doc.txt", "moved", "new/doc.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phases := splitPhases(tt.decisions)
+			check := func(phase string, got []*cache.SyncDecision, want []string) {
+				var paths []string
+				for _, d := range got {
+					paths = append(paths, d.RemotePath[len("share/job/"):])
+				}
+				if !reflect.DeepEqual(paths, want) {
+					t.Errorf("%s = %v, want %v", phase, paths, want)
+				}
+			}
+			check("early", phases.early, tt.early)
+			check("transfers", phases.transfers, tt.transfers)
+			check("deletions", phases.deletions, tt.deletions)
+		})
+	}
+}
+
+func TestEmptiedFolders(t *testing.T) {
+	transfer := orderingDecision(cache.ActionUpload, "Data")
+	deletions := []*cache.SyncDecision{
+		orderingDecision(cache.ActionDeleteRemote, "data/a.txt"),
+		orderingDecision(cache.ActionDeleteRemote, "data/sub/deep/b.txt"),
+		orderingDecision(cache.ActionDeleteRemote, "data/failed/c.txt"),
+		orderingDecision(cache.ActionDeleteLocal, "Data/local.txt"), // Other side
+		orderingDecision(cache.ActionDeleteRemote, "database.txt"),  // Not inside
+	}
+	deleted := []*SyncAction{
+		{Status: ActionStatusSuccess},
+		{Status: ActionStatusSuccess},
+		{Status: ActionStatusFailed},
+		{Status: ActionStatusSuccess},
+		{Status: ActionStatusSuccess},
+	}
+
+	got := emptiedFolders(transfer, deletions, deleted)
+	want := []string{"share/job/data/sub/deep", "share/job/data/sub", "share/job/data"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("emptiedFolders = %v, want %v", got, want)
+	}
+
+	if got := emptiedFolders(orderingDecision(cache.ActionUpload, "other"), deletions, deleted); got != nil {
+		t.Errorf("expected no emptied folders, got %v", got)
+	}
+}
+
+func TestRemoveEmptyFolder(t *testing.T) {
+	ex := NewExecutor(4, nil)
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "empty")
+	full := filepath.Join(dir, "full")
+	for _, d := range []string{empty, full} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(full, "kept.txt"), []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ex.removeEmptyFolder(t.Context(), empty, false, nil)
+	ex.removeEmptyFolder(t.Context(), full, false, nil)
+
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Error("emptied folder should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(full, "kept.txt")); err != nil {
+		t.Error("folders holding files should be left alone")
+	}
+}
@@ -0,0 +1,40 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// SetRemoteOwners sets whether transfers record the owner of the remote
+// files, for shares written by several users: files_state keeps it, and the
+// change report and conflicts show who changed a file. Reading an owner costs
+// a query per downloaded file.
+func (ex *Executor) SetRemoteOwners(enabled bool) {
+	ex.remoteOwners = enabled
+	ex.logger.Info("remote owners configured", zap.Bool("enabled", enabled))
+}
+
+// remoteOwner returns the owner of a remote file when owners are recorded
+// ("" otherwise, or if it can't be read).
+func (ex *Executor) remoteOwner(ctx context.Context, smbClient *smb.SMBClient, remotePath string) string {
+	if !ex.remoteOwners || smbClient == nil {
+		return ""
+	}
+	owner, err := smbClient.Owner(remotePath)
+	if err != nil {
+		ex.log(ctx).Debug("remote owner not read", zap.String("path", remotePath), zap.Error(err))
+		return ""
+	}
+	return owner
+}
+
+// uploadOwner returns the owner of the files uploaded by the client when
+// owners are recorded: its own account.
+func (ex *Executor) uploadOwner(smbClient *smb.SMBClient) string {
+	if !ex.remoteOwners || smbClient == nil {
+		return ""
+	}
+	return smbClient.GetAccount()
+}
@@ -0,0 +1,36 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+)
+
+func TestRemoteOwners(t *testing.T) {
+	client, err := smb.NewSMBClient(&smb.ClientConfig{
+		Server:   "nas",
+		Share:    "data",
+		Username: "bob",
+		Password: "secret",
+		Domain:   "CORP",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewSMBClient failed: %v", err)
+	}
+
+	ex := NewExecutor(4, nil)
+	if owner := ex.uploadOwner(client); owner != "" {
+		t.Errorf("expected no owner while disabled, got %q", owner)
+	}
+	if owner := ex.remoteOwner(t.Context(), client, "a.txt"); owner != "" {
+		t.Errorf("expected no owner while disabled, got %q", owner)
+	}
+
+	ex.SetRemoteOwners(true)
+	if owner := ex.uploadOwner(client); owner != `CORP\bob` {
+		t.Errorf("uploadOwner = %q, want CORP\\bob", owner)
+	}
+	if owner := ex.uploadOwner(nil); owner != "" {
+		t.Errorf("expected no owner without a client, got %q", owner)
+	}
+}
@@ -0,0 +1,251 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// DefaultRansomwareMinFiles is the number of renamed or scrambled files from
+// which a run is considered ransomware activity
+const DefaultRansomwareMinFiles = 20
+
+const (
+	ransomwareEntropyBits = 7.5  // Bits per byte above which a sample looks encrypted
+	ransomwareSampleSize  = 4096 // Bytes read from each modified file
+	ransomwareMaxSamples  = 200  // Modified files sampled per run
+	ransomwareNoteFolders = 3    // Folders receiving the same note
+	ransomwareMaxExamples = 5    // Files listed in the error
+)
+
+// commonExtensions are extensions a bulk rename by the user usually targets;
+// renames to any other extension look like ransomware.
+var commonExtensions = map[string]bool{
+	".txt": true, ".md": true, ".csv": true, ".log": true, ".json": true, ".xml": true, ".html": true, ".htm": true,
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
+	".odt": true, ".ods": true, ".odp": true, ".rtf": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".tif": true, ".tiff": true,
+	".heic": true, ".webp": true, ".svg": true, ".raw": true, ".cr2": true, ".nef": true,
+	".mp3": true, ".wav": true, ".flac": true, ".m4a": true, ".mp4": true, ".mov": true, ".avi": true, ".mkv": true,
+	".zip": true, ".7z": true, ".rar": true, ".bak": true, ".old": true, ".tmp": true,
+}
+
+// lowEntropyExtensions hold uncompressed content: random-looking bytes in
+// these files mean they were encrypted.
+var lowEntropyExtensions = map[string]bool{
+	".txt": true, ".md": true, ".csv": true, ".log": true, ".json": true, ".xml": true, ".html": true, ".htm": true,
+	".rtf": true, ".doc": true, ".xls": true, ".ppt": true, ".svg": true, ".bmp": true, ".wav": true,
+	".sql": true, ".ini": true, ".cfg": true, ".yaml": true, ".yml": true, ".tex": true,
+	".ps1": true, ".bat": true, ".cmd": true, ".py": true, ".js": true, ".go": true, ".c": true, ".cpp": true, ".cs": true,
+}
+
+// ransomNoteKeywords appear in the names of ransom notes dropped in every folder
+var ransomNoteKeywords = []string{
+	"decrypt", "ransom", "restore_files", "restore-files", "restore_my_files", "recover_files",
+	"recover-files", "how_to_recover", "how_to_back_files", "your_files", "readme_for_decrypt",
+}
+
+// RansomwareError is returned by Sync when the local changes look like
+// ransomware at work: nothing was executed, the run must be approved
+// (SyncRequest.ApproveChanges) to propagate them.
+type RansomwareError struct {
+	Reasons  []string // What looked like ransomware
+	Examples []string // Some of the files involved
+}
+
+func (e *RansomwareError) Error() string {
+	msg := "suspected ransomware activity: " + strings.Join(e.Reasons, "; ")
+	if len(e.Examples) > 0 {
+		msg += " (e.g. " + strings.Join(e.Examples, ", ") + ")"
+	}
+	return msg
+}
+
+// Unwrap lets errors.Is match ErrRansomwareSuspected.
+func (e *RansomwareError) Unwrap() error {
+	return ErrRansomwareSuspected
+}
+
+// checkRansomware looks for signs of ransomware in the changes a run would
+// send to the server: many files renamed to the same unusual extension,
+// many modified files whose content became random, or ransom notes dropped
+// in several folders. Returns a *RansomwareError if any is found.
+func (e *Engine) checkRansomware(req *SyncRequest, decisions []*cache.SyncDecision) error {
+	settings := e.config.Security.RansomwareDetection
+	if !settings.Enabled || req.ApproveChanges || !req.Mode.AllowsUpload() {
+		return nil
+	}
+	minFiles := settings.MinFiles
+	if minFiles <= 0 {
+		minFiles = DefaultRansomwareMinFiles
+	}
+	return detectRansomware(req.LocalPath, decisions, minFiles)
+}
+
+// detectRansomware implements checkRansomware on the decisions of a run
+// (paths relative to localBase).
+func detectRansomware(localBase string, decisions []*cache.SyncDecision, minFiles int) error {
+	var uploads []*cache.SyncDecision
+	deleted := make(map[string]bool)
+	for _, d := range decisions {
+		switch d.Action {
+		case cache.ActionUpload:
+			uploads = append(uploads, d)
+		case cache.ActionDeleteRemote:
+			p := strings.ToLower(filepath.ToSlash(d.LocalPath))
+			deleted[p] = true
+			deleted[strings.TrimSuffix(p, path.Ext(p))] = true
+		}
+	}
+
+	result := &RansomwareError{}
+	examples := func(paths []string) {
+		for _, p := range paths {
+			if len(result.Examples) >= ransomwareMaxExamples {
+				return
+			}
+			result.Examples = append(result.Examples, p)
+		}
+	}
+
+	// New files replacing deleted ones under an unusual extension (a.docx -> a.docx.locked)
+	renamed := make(map[string][]string)
+	for _, d := range uploads {
+		if d.CachedInfo != nil {
+			continue
+		}
+		p := strings.ToLower(filepath.ToSlash(d.LocalPath))
+		ext := path.Ext(p)
+		if ext == "" || commonExtensions[ext] {
+			continue
+		}
+		if stem := strings.TrimSuffix(p, ext); deleted[stem] {
+			renamed[ext] = append(renamed[ext], d.LocalPath)
+		}
+	}
+	for _, ext := range sortedKeys(renamed) {
+		if files := renamed[ext]; len(files) >= minFiles {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("%d files renamed to *%s", len(files), ext))
+			examples(files)
+		}
+	}
+
+	// Modified text-like files that now look encrypted
+	var scrambled []string
+	sampled := 0
+	for _, d := range uploads {
+		if d.CachedInfo == nil || sampled >= ransomwareMaxSamples {
+			continue
+		}
+		if !lowEntropyExtensions[strings.ToLower(filepath.Ext(d.LocalPath))] {
+			continue
+		}
+		localPath := d.LocalPath
+		if !filepath.IsAbs(localPath) {
+			localPath = filepath.Join(localBase, localPath)
+		}
+		entropy, ok := sampleEntropy(localPath)
+		if !ok {
+			continue
+		}
+		sampled++
+		if entropy >= ransomwareEntropyBits {
+			scrambled = append(scrambled, d.LocalPath)
+		}
+	}
+	if len(scrambled) >= minFiles {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%d modified files look encrypted", len(scrambled)))
+		examples(scrambled)
+	}
+
+	// The same ransom note in several folders
+	notes := make(map[string][]string)
+	for _, d := range uploads {
+		if d.CachedInfo != nil {
+			continue
+		}
+		name := strings.ToLower(path.Base(filepath.ToSlash(d.LocalPath)))
+		if isRansomNoteName(name) {
+			notes[name] = append(notes[name], d.LocalPath)
+		}
+	}
+	for _, name := range sortedKeys(notes) {
+		if files := notes[name]; len(files) >= ransomwareNoteFolders {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("ransom note %q in %d folders", name, len(files)))
+			examples(files)
+		}
+	}
+
+	if len(result.Reasons) > 0 {
+		return result
+	}
+	return nil
+}
+
+// isRansomNoteName reports whether a lowercase file name looks like a ransom note.
+func isRansomNoteName(name string) bool {
+	switch path.Ext(name) {
+	case ".txt", ".html", ".htm", ".hta", ".rtf", ".url":
+	default:
+		return false
+	}
+	for _, keyword := range ransomNoteKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleEntropy returns the Shannon entropy in bits per byte of the start of
+// a file. ok is false if the file can't be read or is too small to judge.
+func sampleEntropy(path string) (entropy float64, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	buf := make([]byte, ransomwareSampleSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, false
+	}
+	if n < 512 {
+		return 0, false
+	}
+	return shannonEntropy(buf[:n]), true
+}
+
+// shannonEntropy returns the entropy of data in bits per byte (0 to 8).
+func shannonEntropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
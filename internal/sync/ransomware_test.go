@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+func TestDetectRansomware_Renames(t *testing.T) {
+	var decisions []*cache.SyncDecision
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("docs/report%d.docx", i)
+		decisions = append(decisions,
+			&cache.SyncDecision{LocalPath: name, Action: cache.ActionDeleteRemote},
+			&cache.SyncDecision{LocalPath: name + ".locked", Action: cache.ActionUpload},
+		)
+	}
+
+	err := detectRansomware(t.TempDir(), decisions, 5)
+	var ransomErr *RansomwareError
+	if !errors.As(err, &ransomErr) || !errors.Is(err, ErrRansomwareSuspected) {
+		t.Fatalf("expected a RansomwareError, got %v", err)
+	}
+	if len(ransomErr.Reasons) != 1 || !strings.Contains(ransomErr.Reasons[0], "*.locked") {
+		t.Errorf("unexpected reasons: %v", ransomErr.Reasons)
+	}
+
+	// Below the threshold, or renamed to a common extension
+	if err := detectRansomware(t.TempDir(), decisions, 6); err != nil {
+		t.Errorf("expected no detection below the threshold, got %v", err)
+	}
+	for _, d := range decisions {
+		if d.Action == cache.ActionUpload {
+			d.LocalPath = strings.TrimSuffix(d.LocalPath, ".docx.locked") + ".pdf"
+		}
+	}
+	if err := detectRansomware(t.TempDir(), decisions, 5); err != nil {
+		t.Errorf("expected no detection for a rename to .pdf, got %v", err)
+	}
+}
+
+func TestDetectRansomware_Entropy(t *testing.T) {
+	dir := t.TempDir()
+	random := make([]byte, 2048)
+	var decisions []*cache.SyncDecision
+	for i := 0; i < 4; i++ {
+		rand.Read(random)
+		name := fmt.Sprintf("notes%d.txt", i)
+		if err := os.WriteFile(filepath.Join(dir, name), random, 0644); err != nil {
+			t.Fatal(err)
+		}
+		decisions = append(decisions, &cache.SyncDecision{LocalPath: name, Action: cache.ActionUpload, CachedInfo: &cache.FileInfo{}})
+	}
+	if err := detectRansomware(dir, decisions, 4); err == nil {
+		t.Error("expected detection of encrypted text files")
+	}
+
+	// Plain text is fine
+	text := []byte(strings.Repeat("Meeting notes: nothing unusual here.\n", 60))
+	for _, d := range decisions {
+		os.WriteFile(filepath.Join(dir, d.LocalPath), text, 0644)
+	}
+	if err := detectRansomware(dir, decisions, 4); err != nil {
+		t.Errorf("expected no detection for plain text, got %v", err)
+	}
+}
+
+func TestDetectRansomware_Notes(t *testing.T) {
+	var decisions []*cache.SyncDecision
+	for _, folder := range []string{"a", "b", "c"} {
+		decisions = append(decisions, &cache.SyncDecision{LocalPath: folder + "/HOW_TO_DECRYPT.txt", Action: cache.ActionUpload})
+	}
+	if err := detectRansomware(t.TempDir(), decisions, 20); err == nil {
+		t.Error("expected detection of ransom notes")
+	}
+	if err := detectRansomware(t.TempDir(), decisions[:2], 20); err != nil {
+		t.Errorf("expected no detection for notes in 2 folders, got %v", err)
+	}
+}
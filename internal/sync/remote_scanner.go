@@ -3,12 +3,15 @@ package sync
 import (
 	"context"
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	"go.uber.org/zap"
 )
@@ -18,10 +21,17 @@ type SMBClientInterface interface {
 	ListRemote(path string) ([]smb.RemoteFileInfo, error)
 }
 
+// remoteStatter is implemented by clients that read the metadata of a single
+// path: cached listings are only reused with one (see SetListingCache).
+type remoteStatter interface {
+	GetMetadata(path string) (*smb.RemoteFileInfo, error)
+}
+
 // RemoteScanProgress represents progress during remote scanning
 type RemoteScanProgress struct {
 	FilesFound      int
 	DirsScanned     int
+	DirsReused      int // Folders whose cached listing was reused
 	CurrentDir      string
 	BytesDiscovered int64
 	Errors          int
@@ -35,10 +45,15 @@ type RemoteScanResult struct {
 	Files           map[string]*cache.FileInfo
 	TotalFiles      int
 	TotalDirs       int
+	ReusedDirs      int // Folders not listed again (see SetListingCache)
 	TotalBytes      int64
 	Duration        time.Duration
 	Errors          []error
 	PartialSuccess  bool // True if scan completed with some errors
+
+	// Files and folders seen by the scan, to pass to the next one
+	// (nil unless SetListingCache was called)
+	Listings []*database.RemoteStateEntry
 }
 
 // RemoteScanner scans remote SMB shares recursively
@@ -47,10 +62,22 @@ type RemoteScanner struct {
 	logger   *zap.Logger
 	callback RemoteScanCallback
 
+	// Selective sync: folders outside it are not listed
+	selection scanner.Selection
+	prefix    string // Job-relative folder of the scanned path ("" = job root)
+
+	// Listing cache: folders unchanged since the previous scan are not listed
+	statter  remoteStatter
+	previous map[string]*database.RemoteStateEntry   // Previous scan, by path relative to the scanned path
+	children map[string][]*database.RemoteStateEntry // Entries of previous, by folder
+	maxAge   time.Duration                           // Listings older than this are refreshed
+	listings map[string]*database.RemoteStateEntry   // This scan (nil = cache off)
+
 	// Stats (protected by mutex)
 	mu              sync.RWMutex
 	filesFound      int
 	dirsScanned     int
+	dirsReused      int
 	bytesDiscovered int64
 	errors          []error
 }
@@ -69,6 +96,51 @@ func NewRemoteScanner(client SMBClientInterface, logger *zap.Logger, callback Re
 	}
 }
 
+// SetSelection restricts the scan to the selective sync of the job. prefix is
+// the job-relative folder of the path passed to Scan ("" = job root); paths
+// in the result stay relative to the scanned path.
+func (rs *RemoteScanner) SetSelection(selection scanner.Selection, prefix string) {
+	rs.selection = selection
+	rs.prefix = prefix
+}
+
+// SetListingCache lets the scan reuse the listing of the previous scan (from
+// RemoteScanResult.Listings) for the folders whose modification time did not
+// change since, if listed less than maxAge ago: adding, removing or renaming
+// an entry changes the time of its folder, rewriting a file in place doesn't.
+// The client must also read the metadata of a path (GetMetadata), to get the
+// time of the scanned folder and of the subfolders of a reused listing.
+func (rs *RemoteScanner) SetListingCache(previous map[string]*database.RemoteStateEntry, maxAge time.Duration) {
+	statter, ok := rs.client.(remoteStatter)
+	if !ok {
+		return
+	}
+	rs.statter = statter
+	rs.previous = previous
+	rs.maxAge = maxAge
+	rs.children = make(map[string][]*database.RemoteStateEntry)
+	for _, entry := range previous {
+		if entry.Path != "" {
+			rs.children[parentPath(entry.Path)] = append(rs.children[parentPath(entry.Path)], entry)
+		}
+	}
+}
+
+// jobPath returns the job-relative path of a path relative to the scanned path.
+func (rs *RemoteScanner) jobPath(relativePath string) string {
+	if rs.prefix == "" {
+		return relativePath
+	}
+	return rs.prefix + "/" + relativePath
+}
+
+// scanRelativePath returns the path of an entry relative to the scanned path.
+func scanRelativePath(entryPath, basePath string) string {
+	// Normalize slashes before comparing (entry.Path may use \ on Windows)
+	rel := strings.TrimPrefix(filepath.ToSlash(entryPath), filepath.ToSlash(basePath))
+	return strings.TrimPrefix(rel, "/")
+}
+
 // Scan scans a remote path recursively and returns all files found
 func (rs *RemoteScanner) Scan(ctx context.Context, basePath string) (*RemoteScanResult, error) {
 	startTime := time.Now()
@@ -79,6 +151,7 @@ func (rs *RemoteScanner) Scan(ctx context.Context, basePath string) (*RemoteScan
 	rs.mu.Lock()
 	rs.filesFound = 0
 	rs.dirsScanned = 0
+	rs.dirsReused = 0
 	rs.bytesDiscovered = 0
 	rs.errors = make([]error, 0)
 	rs.mu.Unlock()
@@ -87,9 +160,18 @@ func (rs *RemoteScanner) Scan(ctx context.Context, basePath string) (*RemoteScan
 	basePath = strings.TrimSuffix(basePath, "/")
 	basePath = strings.TrimSuffix(basePath, "\\")
 
+	// The time of the scanned folder decides whether its cached listing is reused
+	var rootTime time.Time
+	if rs.statter != nil {
+		rs.listings = make(map[string]*database.RemoteStateEntry)
+		if info, err := rs.statter.GetMetadata(basePath); err == nil {
+			rootTime = info.ModTime
+		}
+	}
+
 	// Scan recursively
 	files := make(map[string]*cache.FileInfo)
-	if err := rs.scanDir(ctx, basePath, basePath, files); err != nil {
+	if err := rs.scanDir(ctx, basePath, basePath, rootTime, files); err != nil {
 		// Check if it's a partial failure
 		if len(files) > 0 {
 			rs.logger.Warn("remote scan completed with errors",
@@ -107,17 +189,25 @@ func (rs *RemoteScanner) Scan(ctx context.Context, basePath string) (*RemoteScan
 	result := &RemoteScanResult{
 		Files:          files,
 		TotalFiles:     rs.filesFound,
-		TotalDirs:      rs.dirsScanned,
+		TotalDirs:      rs.dirsScanned + rs.dirsReused,
+		ReusedDirs:     rs.dirsReused,
 		TotalBytes:     rs.bytesDiscovered,
 		Duration:       duration,
 		Errors:         rs.errors,
 		PartialSuccess: len(rs.errors) > 0 && len(files) > 0,
 	}
 	rs.mu.RUnlock()
+	if rs.listings != nil {
+		result.Listings = make([]*database.RemoteStateEntry, 0, len(rs.listings))
+		for _, entry := range rs.listings {
+			result.Listings = append(result.Listings, entry)
+		}
+	}
 
 	rs.logger.Info("remote scan completed",
 		zap.Int("files", result.TotalFiles),
 		zap.Int("dirs", result.TotalDirs),
+		zap.Int("dirs_reused", result.ReusedDirs),
 		zap.Int64("bytes", result.TotalBytes),
 		zap.Duration("duration", duration),
 		zap.Int("errors", len(result.Errors)),
@@ -127,8 +217,9 @@ func (rs *RemoteScanner) Scan(ctx context.Context, basePath string) (*RemoteScan
 	return result, nil
 }
 
-// scanDir scans a single directory recursively
-func (rs *RemoteScanner) scanDir(ctx context.Context, currentPath string, basePath string, files map[string]*cache.FileInfo) error {
+// scanDir scans a single directory recursively. dirTime is its modification
+// time (zero = unknown, the folder is listed).
+func (rs *RemoteScanner) scanDir(ctx context.Context, currentPath string, basePath string, dirTime time.Time, files map[string]*cache.FileInfo) error {
 	// Check context cancellation
 	select {
 	case <-ctx.Done():
@@ -136,17 +227,28 @@ func (rs *RemoteScanner) scanDir(ctx context.Context, currentPath string, basePa
 	default:
 	}
 
-	// List directory contents
-	entries, err := rs.client.ListRemote(currentPath)
-	if err != nil {
-		rs.addError(fmt.Errorf("failed to list directory %s: %w", currentPath, err))
-		return err
+	// List directory contents, unless unchanged since the previous scan
+	relDir := scanRelativePath(currentPath, basePath)
+	entries, listedAt, reused := rs.cachedListing(currentPath, relDir, dirTime)
+	if !reused {
+		var err error
+		entries, err = rs.client.ListRemote(currentPath)
+		if err != nil {
+			rs.addError(fmt.Errorf("failed to list directory %s: %w", currentPath, err))
+			return err
+		}
+		listedAt = time.Now().Unix()
 	}
+	rs.recordListing(relDir, dirTime, listedAt, entries, basePath)
 
 	// Update stats
 	rs.mu.Lock()
-	rs.dirsScanned++
-	dirsScanned := rs.dirsScanned
+	if reused {
+		rs.dirsReused++
+	} else {
+		rs.dirsScanned++
+	}
+	dirsScanned := rs.dirsScanned + rs.dirsReused
 	rs.mu.Unlock()
 
 	// Report progress
@@ -164,8 +266,15 @@ func (rs *RemoteScanner) scanDir(ctx context.Context, currentPath string, basePa
 		}
 
 		if entry.IsDir {
+			// Folders outside the selective sync are never listed
+			if !rs.selection.IsEmpty() && rs.selection.SkipDir(rs.jobPath(scanRelativePath(entry.Path, basePath))) {
+				rs.logger.Debug("skipping unselected directory",
+					zap.String("path", entry.Path))
+				continue
+			}
+
 			// Recurse into subdirectory
-			if err := rs.scanDir(ctx, entry.Path, basePath, files); err != nil {
+			if err := rs.scanDir(ctx, entry.Path, basePath, entry.ModTime, files); err != nil {
 				// Continue scanning other directories even if one fails
 				rs.logger.Warn("failed to scan subdirectory",
 					zap.String("path", entry.Path),
@@ -181,21 +290,22 @@ func (rs *RemoteScanner) scanDir(ctx context.Context, currentPath string, basePa
 			}
 
 			// Add file to result
-			// Normalize slashes before comparing (entry.Path may use \ on Windows)
-			entryPath := filepath.ToSlash(entry.Path)
-			basePathNorm := filepath.ToSlash(basePath)
-			relativePath := strings.TrimPrefix(entryPath, basePathNorm)
-			relativePath = strings.TrimPrefix(relativePath, "/")
-
+			relativePath := scanRelativePath(entry.Path, basePath)
 			if relativePath == "" {
 				relativePath = filepath.Base(entry.Path)
 			}
+			if !rs.selection.Contains(rs.jobPath(relativePath)) {
+				continue
+			}
 
 			files[relativePath] = &cache.FileInfo{
-				Path:  relativePath,
-				Size:  entry.Size,
-				MTime: entry.ModTime,
-				Hash:  "", // Hash not available from remote listing
+				Path:             relativePath,
+				Size:             entry.Size,
+				MTime:            entry.ModTime,
+				Hash:             "", // Hash not available from remote listing
+				RemoteWriteTime:  entry.WriteTime,
+				RemoteChangeTime: entry.ChangeTime,
+				Attributes:       cache.NormalizeAttributes(entry.Attributes),
 			}
 
 			// Update stats
@@ -220,6 +330,100 @@ func (rs *RemoteScanner) scanDir(ctx context.Context, currentPath string, basePa
 	return nil
 }
 
+// cachedListing returns the entries of a folder from the previous scan, with
+// the time of that listing, when its modification time did not change and
+// the listing is recent enough. The times of its subfolders are read again:
+// changes inside a subfolder don't change the time of the folder.
+func (rs *RemoteScanner) cachedListing(currentPath, relDir string, dirTime time.Time) ([]smb.RemoteFileInfo, int64, bool) {
+	if rs.previous == nil || dirTime.IsZero() {
+		return nil, 0, false
+	}
+	dir, ok := rs.previous[relDir]
+	if !ok || !dir.IsDir || dir.ListedAt == 0 || dir.MTime != unixNano(dirTime) {
+		return nil, 0, false
+	}
+	if rs.maxAge > 0 && time.Since(time.Unix(dir.ListedAt, 0)) >= rs.maxAge {
+		return nil, 0, false
+	}
+
+	children := rs.children[relDir]
+	entries := make([]smb.RemoteFileInfo, 0, len(children))
+	for _, child := range children {
+		entry := smb.RemoteFileInfo{
+			Name:       path.Base(child.Path),
+			Path:       childPath(currentPath, path.Base(child.Path)),
+			Size:       child.Size,
+			ModTime:    fromUnixNano(child.MTime),
+			IsDir:      child.IsDir,
+			WriteTime:  fromUnixNano(child.WriteTime),
+			ChangeTime: fromUnixNano(child.ChangeTime),
+			Attributes: uint32(child.Attributes),
+		}
+		if entry.IsDir && (rs.selection.IsEmpty() || !rs.selection.SkipDir(rs.jobPath(child.Path))) {
+			info, err := rs.statter.GetMetadata(entry.Path)
+			if err != nil || !info.IsDir {
+				return nil, 0, false // Gone or unreadable: list the folder
+			}
+			entry.ModTime = info.ModTime
+		}
+		entries = append(entries, entry)
+	}
+	return entries, dir.ListedAt, true
+}
+
+// recordListing keeps a folder and its entries for the next scan. Its
+// subfolders are kept without their listing until they are scanned too.
+func (rs *RemoteScanner) recordListing(relDir string, dirTime time.Time, listedAt int64, entries []smb.RemoteFileInfo, basePath string) {
+	if rs.listings == nil {
+		return
+	}
+	rs.listings[relDir] = &database.RemoteStateEntry{
+		Path:     relDir,
+		IsDir:    true,
+		MTime:    unixNano(dirTime),
+		ListedAt: listedAt,
+	}
+	for _, entry := range entries {
+		relativePath := scanRelativePath(entry.Path, basePath)
+		if relativePath == "" {
+			continue
+		}
+		rs.listings[relativePath] = &database.RemoteStateEntry{
+			Path:       relativePath,
+			IsDir:      entry.IsDir,
+			Size:       entry.Size,
+			MTime:      unixNano(entry.ModTime),
+			WriteTime:  unixNano(entry.WriteTime),
+			ChangeTime: unixNano(entry.ChangeTime),
+			Attributes: int64(entry.Attributes),
+		}
+	}
+}
+
+// childPath returns the path of an entry of a folder, as ListRemote builds it.
+func childPath(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// unixNano returns t in nanoseconds since the Unix epoch, 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano is the reverse of unixNano.
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
 // addError adds an error to the error list (thread-safe)
 func (rs *RemoteScanner) addError(err error) {
 	rs.mu.Lock()
@@ -237,6 +441,7 @@ func (rs *RemoteScanner) reportProgress(currentDir string) {
 	progress := RemoteScanProgress{
 		FilesFound:      rs.filesFound,
 		DirsScanned:     rs.dirsScanned,
+		DirsReused:      rs.dirsReused,
 		CurrentDir:      currentDir,
 		BytesDiscovered: rs.bytesDiscovered,
 		Errors:          len(rs.errors),
@@ -254,6 +459,7 @@ func (rs *RemoteScanner) GetStats() RemoteScanProgress {
 	return RemoteScanProgress{
 		FilesFound:      rs.filesFound,
 		DirsScanned:     rs.dirsScanned,
+		DirsReused:      rs.dirsReused,
 		BytesDiscovered: rs.bytesDiscovered,
 		Errors:          len(rs.errors),
 	}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
 	"testing"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	scannerpkg "github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 	"go.uber.org/zap"
 )
@@ -200,6 +203,50 @@ func TestRemoteScannerRecursive(t *testing.T) {
 	}
 }
 
+func TestRemoteScannerSelection(t *testing.T) {
+	mock := newMockSMBClient()
+	mock.addFile("/share", "root.txt", 10)
+	mock.addDir("/share", "Photos")
+	mock.addFile("/share/Photos", "a.jpg", 100)
+	mock.addDir("/share/Photos", "Raw")
+	mock.addFile("/share/Photos/Raw", "a.cr2", 1000)
+	mock.addDir("/share", "Videos")
+	mock.addFile("/share/Videos", "a.mp4", 5000)
+
+	scanner := NewRemoteScanner(mock, zap.NewNop(), nil)
+	scanner.SetSelection(scannerpkg.Selection{
+		Include: []string{"Photos"},
+		Exclude: []string{"Photos/Raw"},
+	}, "")
+
+	result, err := scanner.Scan(context.Background(), "/share")
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+
+	if len(result.Files) != 1 || result.Files["Photos/a.jpg"] == nil {
+		t.Errorf("expected only Photos/a.jpg, got %v", result.Files)
+	}
+	// Unselected folders are never listed
+	if mock.listCallCount != 2 {
+		t.Errorf("expected 2 directories listed (/share, Photos), got %d", mock.listCallCount)
+	}
+
+	// Scanning a subtree: selection rules stay relative to the job
+	mock.listCallCount = 0
+	scanner.SetSelection(scannerpkg.Selection{Exclude: []string{"Photos/Raw"}}, "Photos")
+	result, err = scanner.Scan(context.Background(), "/share/Photos")
+	if err != nil {
+		t.Fatalf("subtree scan failed: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files["a.jpg"] == nil {
+		t.Errorf("expected only a.jpg, got %v", result.Files)
+	}
+	if mock.listCallCount != 1 {
+		t.Errorf("expected 1 directory listed, got %d", mock.listCallCount)
+	}
+}
+
 func TestRemoteScannerEmptyDirectory(t *testing.T) {
 	mock := newMockSMBClient()
 	// Don't add any files
@@ -363,3 +410,90 @@ func TestRemoteScannerResultDuration(t *testing.T) {
 
 	t.Logf("scan duration: %v", result.Duration)
 }
+
+// statMockSMBClient also reads the metadata of a path, from the listing of
+// its folder (root holds the time of the scanned folder).
+type statMockSMBClient struct {
+	*mockSMBClient
+	root time.Time
+}
+
+func (m *statMockSMBClient) GetMetadata(p string) (*smb.RemoteFileInfo, error) {
+	if p == "/share" {
+		return &smb.RemoteFileInfo{Path: p, IsDir: true, ModTime: m.root}, nil
+	}
+	for _, entry := range m.files[path.Dir(p)] {
+		if entry.Path == p {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("not found: %s", p)
+}
+
+// touchDir sets the modification time of a subfolder of /share.
+func (m *statMockSMBClient) touchDir(p string, mtime time.Time) {
+	entries := m.files[path.Dir(p)]
+	for i := range entries {
+		if entries[i].Path == p {
+			entries[i].ModTime = mtime
+		}
+	}
+}
+
+func TestRemoteScannerListingCache(t *testing.T) {
+	mock := &statMockSMBClient{mockSMBClient: newMockSMBClient(), root: time.Unix(1000, 0)}
+	mock.addFile("/share", "root.txt", 10)
+	mock.addDir("/share", "docs")
+	mock.addFile("/share/docs", "a.txt", 20)
+	mock.addDir("/share/docs", "old")
+	mock.addFile("/share/docs/old", "b.txt", 30)
+
+	scan := func(previous []*database.RemoteStateEntry, maxAge time.Duration) *RemoteScanResult {
+		t.Helper()
+		cached := make(map[string]*database.RemoteStateEntry)
+		for _, entry := range previous {
+			cached[entry.Path] = entry
+		}
+		scanner := NewRemoteScanner(mock, zap.NewNop(), nil)
+		scanner.SetListingCache(cached, maxAge)
+		mock.listCallCount = 0
+		result, err := scanner.Scan(context.Background(), "/share")
+		if err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		return result
+	}
+
+	first := scan(nil, time.Hour)
+	if mock.listCallCount != 3 || first.TotalFiles != 3 || len(first.Listings) != 6 {
+		t.Fatalf("first scan: %d listings, %d files, %d entries kept", mock.listCallCount, first.TotalFiles, len(first.Listings))
+	}
+
+	// Nothing changed: no folder is listed again
+	second := scan(first.Listings, time.Hour)
+	if mock.listCallCount != 0 || second.ReusedDirs != 3 || second.TotalDirs != 3 {
+		t.Errorf("unchanged scan: %d listings, %d folders reused", mock.listCallCount, second.ReusedDirs)
+	}
+	if len(second.Files) != 3 || second.Files["docs/old/b.txt"] == nil || second.Files["docs/old/b.txt"].Size != 30 {
+		t.Errorf("unchanged scan files: %+v", second.Files)
+	}
+
+	// A file added deep down only lists its folder, found from the times of
+	// the subfolders of the reused listings
+	mock.addFile("/share/docs/old", "c.txt", 40)
+	mock.touchDir("/share/docs/old", time.Now().Add(time.Minute))
+	third := scan(second.Listings, time.Hour)
+	if mock.listCallCount != 1 || third.Files["docs/old/c.txt"] == nil || third.TotalFiles != 4 {
+		t.Errorf("changed scan: %d listings, files %+v", mock.listCallCount, third.Files)
+	}
+
+	// Listings older than maxAge are refreshed
+	for _, entry := range third.Listings {
+		if entry.IsDir {
+			entry.ListedAt -= 7200
+		}
+	}
+	if scan(third.Listings, time.Hour); mock.listCallCount != 3 {
+		t.Errorf("expired scan: %d listings, want 3", mock.listCallCount)
+	}
+}
@@ -0,0 +1,91 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// Files moved on one side are moved on the other instead of being deleted
+// and transferred again (see cache.detectRenames). A moved placeholder stays
+// a placeholder, and the cached state of the file follows it.
+
+// executeRenameLocal moves a local file to the path it was moved to on the server
+func (ex *Executor) executeRenameLocal(
+	ctx context.Context,
+	decision *cache.SyncDecision,
+	action *SyncAction,
+) error {
+
+	ex.log(ctx).Debug("moving local file",
+		zap.String("from", decision.OldLocalPath),
+		zap.String("to", decision.LocalPath),
+	)
+
+	// os.Rename replaces an existing file: never overwrite one created since
+	// the scan (the same file is found for a case-only rename)
+	if dest, err := os.Lstat(decision.LocalPath); err == nil {
+		if src, err := os.Lstat(decision.OldLocalPath); err != nil || !os.SameFile(src, dest) {
+			return WrapSyncError(fmt.Errorf("destination already exists"), decision.LocalPath, "rename_local")
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(decision.LocalPath), 0755); err != nil {
+		return WrapSyncError(err, decision.LocalPath, "rename_local")
+	}
+	// Moved as is: a placeholder stays a placeholder
+	if err := os.Rename(decision.OldLocalPath, decision.LocalPath); err != nil {
+		return WrapSyncError(err, decision.OldLocalPath, "rename_local")
+	}
+	renamedState(decision, action)
+
+	ex.log(ctx).Info("local file moved",
+		zap.String("from", decision.OldLocalPath),
+		zap.String("to", decision.LocalPath),
+	)
+
+	return nil
+}
+
+// executeRenameRemote moves a remote file to the path it was moved to locally
+func (ex *Executor) executeRenameRemote(
+	ctx context.Context,
+	decision *cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	action *SyncAction,
+) error {
+
+	ex.log(ctx).Debug("moving remote file",
+		zap.String("from", decision.OldRemotePath),
+		zap.String("to", decision.RemotePath),
+	)
+
+	if err := smbClient.MoveContext(ctx, decision.OldRemotePath, decision.RemotePath); err != nil {
+		return WrapSyncError(err, decision.OldRemotePath, "rename_remote")
+	}
+	renamedState(decision, action)
+
+	ex.log(ctx).Info("remote file moved",
+		zap.String("from", decision.OldRemotePath),
+		zap.String("to", decision.RemotePath),
+	)
+
+	return nil
+}
+
+// renamedState carries the cached state of a moved file over to its new path:
+// content, attributes, owner and verification are unchanged by a rename.
+func renamedState(decision *cache.SyncDecision, action *SyncAction) {
+	action.RenamedFrom = decision.OldLocalPath
+	if cached := decision.CachedInfo; cached != nil {
+		action.Size = cached.Size
+		action.Hash = cached.Hash
+		action.Attributes = cached.Attributes
+		action.ChangedBy = cached.RemoteOwner
+		action.VerifiedAt = cached.VerifiedAt
+	}
+}
@@ -0,0 +1,52 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+func TestExecuteRenameLocal(t *testing.T) {
+	ex := NewExecutor(4, nil)
+	dir := t.TempDir()
+
+	oldPath := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(oldPath, []byte("photo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	decision := &cache.SyncDecision{
+		LocalPath:    filepath.Join(dir, "2025", "photo.jpg"),
+		OldLocalPath: oldPath,
+		Action:       cache.ActionRenameLocal,
+		CachedInfo:   &cache.FileInfo{Size: 5, Hash: "abc", RemoteOwner: `CORP\alice`, VerifiedAt: verified},
+	}
+
+	action, err := ex.executeAction(t.Context(), decision, nil)
+	if err != nil {
+		t.Fatalf("rename_local failed: %v", err)
+	}
+	if data, err := os.ReadFile(decision.LocalPath); err != nil || string(data) != "photo" {
+		t.Errorf("moved file = %q, %v", data, err)
+	}
+	if localExists(oldPath) {
+		t.Error("old path should be gone")
+	}
+	if action.RenamedFrom != oldPath || action.Hash != "abc" || action.ChangedBy != `CORP\alice` || !action.VerifiedAt.Equal(verified) {
+		t.Errorf("cached state not carried over: %+v", action)
+	}
+
+	// A file created at the destination since the scan is not overwritten
+	if err := os.WriteFile(oldPath, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ex.executeAction(t.Context(), decision, nil); err == nil {
+		t.Error("expected error when the destination exists")
+	}
+	if data, _ := os.ReadFile(decision.LocalPath); string(data) != "photo" {
+		t.Errorf("destination overwritten: %q", data)
+	}
+}
@@ -0,0 +1,290 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// ResumePlanMinActions is the number of actions from which the action plan of
+// a sync is saved, so that an interrupted run resumes its remaining actions
+// instead of rescanning both sides.
+const ResumePlanMinActions = 200
+
+// resumePlanMaxAge is the age after which a saved plan is considered too old
+// to be trusted.
+const resumePlanMaxAge = 24 * time.Hour
+
+// resumePlan tracks the actions of a saved plan while they are executed.
+type resumePlan struct {
+	db        *database.DB
+	jobID     int64
+	localBase string
+	seqs      map[string]int // action|relative path -> position in the plan
+	logger    *zap.Logger
+}
+
+// resumeKey identifies an action of a plan by its type and job-relative path.
+func resumeKey(action cache.SyncAction, localPath, localBase string) string {
+	if !filepath.IsAbs(localPath) {
+		localPath = filepath.Join(localBase, localPath)
+	}
+	return string(action) + "|" + toRelativePath(localPath, localBase)
+}
+
+// saveResumePlan persists the decisions about to be executed, replacing the
+// plan of a previous run. Returns nil (and drops any previous plan) for dry
+// runs, partial runs (Pattern, DownloadOnly) and runs below ResumePlanMinActions.
+func (e *Engine) saveResumePlan(ctx context.Context, req *SyncRequest, decisions []*cache.SyncDecision) *resumePlan {
+	if req.DryRun || req.partial() || len(decisions) < ResumePlanMinActions {
+		if err := e.db.DeleteResumePlan(req.JobID); err != nil {
+			e.log(ctx).Warn("failed to delete resume plan", zap.Error(err))
+		}
+		return nil
+	}
+
+	plan := &resumePlan{
+		db:        e.db,
+		jobID:     req.JobID,
+		localBase: filepath.Clean(req.LocalPath),
+		seqs:      make(map[string]int, len(decisions)),
+		logger:    e.log(ctx),
+	}
+	encoded := make([]string, 0, len(decisions))
+	for _, d := range decisions {
+		data, err := json.Marshal(d)
+		if err != nil {
+			e.log(ctx).Warn("failed to encode resume plan", zap.Error(err))
+			return nil
+		}
+		plan.seqs[resumeKey(d.Action, d.LocalPath, plan.localBase)] = len(encoded)
+		encoded = append(encoded, string(data))
+	}
+
+	record := &database.ResumePlan{JobID: req.JobID, Mode: string(req.Mode), Subtree: req.Subtree}
+	if err := e.db.SaveResumePlan(record, encoded); err != nil {
+		e.log(ctx).Warn("failed to save resume plan", zap.Error(err))
+		return nil
+	}
+	return plan
+}
+
+// markDone records the successful actions of a committed batch.
+func (p *resumePlan) markDone(batch []*SyncAction) {
+	if p == nil {
+		return
+	}
+	seqs := make([]int, 0, len(batch))
+	for _, action := range batch {
+		if action.Status != ActionStatusSuccess {
+			continue
+		}
+		if seq, ok := p.seqs[resumeKey(action.Action, action.FilePath, p.localBase)]; ok {
+			seqs = append(seqs, seq)
+		}
+	}
+	if err := p.db.MarkResumeActionsDone(p.jobID, seqs); err != nil {
+		p.logger.Warn("failed to update resume plan", zap.Error(err))
+	}
+}
+
+// finish drops the plan once every action was attempted.
+func (p *resumePlan) finish() {
+	if p == nil {
+		return
+	}
+	if err := p.db.DeleteResumePlan(p.jobID); err != nil {
+		p.logger.Warn("failed to delete resume plan", zap.Error(err))
+	}
+}
+
+// loadResumePlan returns the remaining decisions of an interrupted run of the
+// same job, mode and subtree, or nil if the run must scan. The plan is only
+// used if every remaining action still matches the local files and the remote
+// files (read through remote); otherwise it is dropped and the sync falls back
+// to a full scan.
+func (e *Engine) loadResumePlan(ctx context.Context, req *SyncRequest, remote remoteStatter) []*cache.SyncDecision {
+	if req.DryRun || req.partial() {
+		return nil
+	}
+
+	plan, err := e.db.GetResumePlan(req.JobID)
+	if err != nil {
+		e.log(ctx).Warn("failed to load resume plan", zap.Error(err))
+		return nil
+	}
+	if plan == nil {
+		return nil
+	}
+
+	discard := func(reason string) []*cache.SyncDecision {
+		e.log(ctx).Info("discarding resume plan, running a full scan",
+			zap.Int64("job_id", req.JobID),
+			zap.String("reason", reason),
+		)
+		if err := e.db.DeleteResumePlan(req.JobID); err != nil {
+			e.log(ctx).Warn("failed to delete resume plan", zap.Error(err))
+		}
+		return nil
+	}
+
+	switch {
+	case plan.Mode != string(req.Mode) || plan.Subtree != req.Subtree:
+		return discard("plan was made for another mode or folder")
+	case e.clock.Now().Sub(plan.CreatedAt) > resumePlanMaxAge:
+		return discard("plan is older than " + resumePlanMaxAge.String())
+	}
+
+	localBase := filepath.Clean(req.LocalPath)
+	decisions := make([]*cache.SyncDecision, 0, len(plan.Pending))
+	for _, pending := range plan.Pending {
+		var d cache.SyncDecision
+		if err := json.Unmarshal([]byte(pending.Decision), &d); err != nil {
+			return discard(fmt.Sprintf("action %d unreadable: %v", pending.Seq, err))
+		}
+
+		localPath := d.LocalPath
+		if !filepath.IsAbs(localPath) {
+			localPath = filepath.Join(localBase, localPath)
+		}
+		if d.Action == cache.ActionDeleteLocal && !localExists(localPath) {
+			continue // Already deleted
+		}
+		if d.Action == cache.ActionRenameLocal {
+			// LocalInfo is the state of the file at its old path
+			localPath = d.OldLocalPath
+			if !filepath.IsAbs(localPath) {
+				localPath = filepath.Join(localBase, localPath)
+			}
+			if !localExists(localPath) {
+				continue // Already moved
+			}
+		}
+		if !localUnchanged(localPath, d.LocalInfo) {
+			return discard(fmt.Sprintf("%s changed since the interrupted run", d.LocalPath))
+		}
+
+		remotePath := d.RemotePath
+		if d.Action == cache.ActionRenameRemote {
+			// RemoteInfo is the state of the file at its old path
+			remotePath = d.OldRemotePath
+		}
+		info, err := remote.GetMetadata(remotePath)
+		if err != nil && !isNotFoundError(err) && !errors.Is(err, os.ErrNotExist) {
+			return discard(fmt.Sprintf("cannot check %s on the server: %v", remotePath, err))
+		}
+		if info == nil && (d.Action == cache.ActionDeleteRemote || d.Action == cache.ActionRenameRemote) {
+			continue // Already deleted or moved
+		}
+		if !remoteUnchanged(info, d.RemoteInfo) {
+			return discard(fmt.Sprintf("%s changed on the server since the interrupted run", remotePath))
+		}
+		decisions = append(decisions, &d)
+	}
+	if len(decisions) == 0 {
+		return discard("no remaining action")
+	}
+
+	e.log(ctx).Info("resuming interrupted sync",
+		zap.Int64("job_id", req.JobID),
+		zap.Int("remaining", len(decisions)),
+		zap.Int("planned", plan.Total),
+		zap.Time("planned_at", plan.CreatedAt),
+	)
+	return decisions
+}
+
+// localExists reports whether path exists.
+func localExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// localUnchanged reports whether the local file is still in the state seen by
+// the scan: absent if info is nil, same size and modification time otherwise.
+func localUnchanged(path string, info *cache.FileInfo) bool {
+	stat, err := os.Stat(path)
+	if info == nil {
+		return os.IsNotExist(err)
+	}
+	return err == nil && !stat.IsDir() && stat.Size() == info.Size && stat.ModTime().Equal(info.MTime)
+}
+
+// remoteUnchanged reports whether the remote file (nil if absent) is still in
+// the state seen by the scan, with the tolerance of the change detection.
+func remoteUnchanged(current *smb.RemoteFileInfo, info *cache.FileInfo) bool {
+	if info == nil || current == nil {
+		return info == nil && current == nil
+	}
+	return !current.IsDir && current.Size == info.Size && scanner.SameMTime(current.ModTime, info.MTime)
+}
+
+// resumeSync executes the remaining decisions of an interrupted run (see
+// loadResumePlan) in place of the scanning and detection phases.
+func (e *Engine) resumeSync(ctx context.Context, req *SyncRequest, result *SyncResult,
+	smbClient *smb.SMBClient, job *database.SyncJob, decisions []*cache.SyncDecision) error {
+
+	result.Resumed = true
+	result.TotalFiles = len(decisions)
+
+	// Remote state seen by the interrupted run, for the cache of downloaded files
+	remoteFiles := make(map[string]*cache.FileInfo, len(decisions))
+	for _, d := range decisions {
+		if d.RemoteInfo != nil {
+			remoteFiles[filepath.ToSlash(d.LocalPath)] = d.RemoteInfo
+		}
+	}
+
+	e.reportProgress(req, &SyncProgress{
+		Phase:          "executing",
+		Message:        "Resuming interrupted sync...",
+		Percentage:     35,
+		FilesTotal:     len(decisions),
+		FilesProcessed: 0,
+	})
+
+	decisions, screened, err := e.screenUploads(ctx, req, decisions)
+	if err != nil {
+		return fmt.Errorf("upload scan failed: %w", err)
+	}
+	for _, action := range screened {
+		result.AddAction(action)
+		if action.Status == ActionStatusFailed {
+			result.AddError(NewSyncError(action.FilePath, "scan", action.Error, 1))
+		}
+	}
+
+	// Also replaces the plan with the actions still to do (or drops it)
+	actions, err := e.executeActions(ctx, req, decisions, smbClient, job, remoteFiles)
+	if err != nil {
+		return fmt.Errorf("execution failed: %w", err)
+	}
+	for _, action := range actions {
+		result.AddAction(action)
+		if action.Error != nil {
+			result.AddError(NewSyncError(action.FilePath, string(action.Action), action.Error, action.Attempts))
+		}
+	}
+
+	e.reportProgress(req, &SyncProgress{
+		Phase:      "finalizing",
+		Message:    "Finalizing sync...",
+		Percentage: 95,
+	})
+
+	// In-sync files were already recorded by the scanning run
+	if err := e.finalizeSync(ctx, req, result, job, nil, nil); err != nil {
+		e.log(ctx).Error("finalization failed", zap.Error(err))
+	}
+	return nil
+}
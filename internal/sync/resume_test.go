@@ -0,0 +1,156 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+)
+
+// interruptingExecutor succeeds the first decisions, commits them, then stops
+// as if the sync was cancelled
+type interruptingExecutor struct {
+	after int
+}
+
+func (ex *interruptingExecutor) ExecuteWithCommit(ctx context.Context, decisions []*cache.SyncDecision, smbClient *smb.SMBClient,
+	progressFn ProgressCallback, commitFn CommitFunc) ([]*SyncAction, error) {
+	actions := make([]*SyncAction, 0, ex.after)
+	for _, d := range decisions[:ex.after] {
+		actions = append(actions, &SyncAction{
+			FilePath:   d.LocalPath,
+			RemotePath: d.RemotePath,
+			Action:     d.Action,
+			Status:     ActionStatusSuccess,
+		})
+	}
+	if err := commitFn(actions); err != nil {
+		return actions, err
+	}
+	return actions, context.Canceled
+}
+
+// fakeStatter serves the metadata of the remote files it knows about
+type fakeStatter map[string]*smb.RemoteFileInfo
+
+func (f fakeStatter) GetMetadata(path string) (*smb.RemoteFileInfo, error) {
+	if info, ok := f[path]; ok {
+		return info, nil
+	}
+	return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+}
+
+func TestEngine_ResumeInterruptedSync(t *testing.T) {
+	localDir := t.TempDir()
+	uploadPath := filepath.Join(localDir, "new.txt")
+	if err := os.WriteFile(uploadPath, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ := os.Stat(uploadPath)
+
+	// Remote deletions (the local files are gone) plus one upload, done last;
+	// the server holds the files not deleted yet and an older new.txt
+	remoteMTime := stat.ModTime().Add(-time.Hour)
+	server := func() fakeStatter {
+		files := fakeStatter{"new.txt": {Path: "new.txt", Size: 3, ModTime: remoteMTime}}
+		for i := 150; i < ResumePlanMinActions; i++ {
+			name := fmt.Sprintf("old%03d.txt", i)
+			files[name] = &smb.RemoteFileInfo{Path: name, Size: 10, ModTime: remoteMTime}
+		}
+		return files
+	}
+	newDecisions := func() []*cache.SyncDecision {
+		var decisions []*cache.SyncDecision
+		for i := 0; i < ResumePlanMinActions; i++ {
+			name := fmt.Sprintf("old%03d.txt", i)
+			decisions = append(decisions, &cache.SyncDecision{
+				LocalPath:  name,
+				RemotePath: name,
+				Action:     cache.ActionDeleteRemote,
+				RemoteInfo: &cache.FileInfo{Path: name, Size: 10, MTime: remoteMTime},
+			})
+		}
+		return append(decisions, &cache.SyncDecision{
+			LocalPath:  "new.txt",
+			RemotePath: "new.txt",
+			Action:     cache.ActionUpload,
+			LocalInfo:  &cache.FileInfo{Path: "new.txt", Size: stat.Size(), MTime: stat.ModTime()},
+			RemoteInfo: &cache.FileInfo{Path: "new.txt", Size: 3, MTime: remoteMTime},
+		})
+	}
+
+	interrupt := func(t *testing.T) *Engine {
+		engine := newFakeEngine(t, WithCacheManager(&fakeCache{}), WithExecutor(&interruptingExecutor{after: 150}))
+		req := &SyncRequest{JobID: 1, LocalPath: localDir, RemotePath: `\\nas\share`, Mode: SyncModeMirror}
+		if _, err := engine.executeActions(context.Background(), req, newDecisions(), nil, nil, nil); err == nil {
+			t.Fatal("expected interrupted execution")
+		}
+		return engine
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		engine := interrupt(t)
+		req := &SyncRequest{JobID: 1, LocalPath: localDir, RemotePath: `\\nas\share`, Mode: SyncModeMirror}
+
+		pending := engine.loadResumePlan(context.Background(), req, server())
+		if len(pending) != ResumePlanMinActions+1-150 {
+			t.Fatalf("expected %d remaining actions, got %d", ResumePlanMinActions+1-150, len(pending))
+		}
+		if pending[0].LocalPath != "old150.txt" || pending[len(pending)-1].LocalPath != "new.txt" {
+			t.Errorf("unexpected remaining actions: %s ... %s", pending[0].LocalPath, pending[len(pending)-1].LocalPath)
+		}
+
+		executor := &fakeExecutor{}
+		engine.executor = executor
+		result := NewSyncResult(req.JobID)
+		if err := engine.resumeSync(context.Background(), req, result, nil, nil, pending); err != nil {
+			t.Fatalf("resumeSync failed: %v", err)
+		}
+		if !result.Resumed || len(executor.executed) != len(pending) {
+			t.Errorf("expected the %d remaining actions executed, got %d (resumed=%v)", len(pending), len(executor.executed), result.Resumed)
+		}
+		if plan, _ := engine.db.GetResumePlan(req.JobID); plan != nil {
+			t.Error("expected plan deleted after the resumed run")
+		}
+	})
+
+	t.Run("other mode", func(t *testing.T) {
+		engine := interrupt(t)
+		req := &SyncRequest{JobID: 1, LocalPath: localDir, RemotePath: `\\nas\share`, Mode: SyncModeUpload}
+		if pending := engine.loadResumePlan(context.Background(), req, server()); pending != nil {
+			t.Errorf("expected no resume for another mode, got %d actions", len(pending))
+		}
+	})
+
+	t.Run("remote change", func(t *testing.T) {
+		engine := interrupt(t)
+		files := server()
+		files["new.txt"] = &smb.RemoteFileInfo{Path: "new.txt", Size: 12, ModTime: remoteMTime.Add(time.Minute)}
+		req := &SyncRequest{JobID: 1, LocalPath: localDir, RemotePath: `\\nas\share`, Mode: SyncModeMirror}
+		if pending := engine.loadResumePlan(context.Background(), req, files); pending != nil {
+			t.Errorf("expected a full scan after a remote change, got %d actions", len(pending))
+		}
+		if plan, _ := engine.db.GetResumePlan(req.JobID); plan != nil {
+			t.Error("expected stale plan deleted")
+		}
+	})
+
+	t.Run("local change", func(t *testing.T) {
+		engine := interrupt(t)
+		if err := os.WriteFile(uploadPath, []byte("modified content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		req := &SyncRequest{JobID: 1, LocalPath: localDir, RemotePath: `\\nas\share`, Mode: SyncModeMirror}
+		if pending := engine.loadResumePlan(context.Background(), req, server()); pending != nil {
+			t.Errorf("expected a full scan after a local change, got %d actions", len(pending))
+		}
+		if plan, _ := engine.db.GetResumePlan(req.JobID); plan != nil {
+			t.Error("expected stale plan deleted")
+		}
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
 	"go.uber.org/zap"
 )
 
@@ -33,6 +35,9 @@ type RetryPolicy struct {
 
 	// Logger for retry events
 	Logger *zap.Logger
+
+	// Clock times the waits between attempts (nil = system clock)
+	Clock clock.Clock
 }
 
 // DefaultRetryPolicy returns a sensible default retry policy
@@ -90,6 +95,7 @@ func (p *RetryPolicy) Retry(ctx context.Context, operation string, fn RetryableF
 	if p.Logger == nil {
 		p.Logger = zap.NewNop()
 	}
+	logger := correlation.Logger(ctx, p.Logger)
 
 	attempt := 0
 
@@ -101,7 +107,7 @@ func (p *RetryPolicy) Retry(ctx context.Context, operation string, fn RetryableF
 		if err == nil {
 			// Success
 			if attempt > 1 {
-				p.Logger.Info("operation succeeded after retries",
+				logger.Info("operation succeeded after retries",
 					zap.String("operation", operation),
 					zap.Int("attempts", attempt),
 				)
@@ -112,7 +118,7 @@ func (p *RetryPolicy) Retry(ctx context.Context, operation string, fn RetryableF
 		// Check if we should retry
 		if !p.shouldRetry(attempt, err) {
 			if attempt > 1 {
-				p.Logger.Error("operation failed after retries",
+				logger.Error("operation failed after retries",
 					zap.String("operation", operation),
 					zap.Int("attempts", attempt),
 					zap.Error(err),
@@ -124,7 +130,7 @@ func (p *RetryPolicy) Retry(ctx context.Context, operation string, fn RetryableF
 		// Calculate delay with backoff and jitter
 		delay := p.calculateDelay(attempt)
 
-		p.Logger.Warn("operation failed, retrying",
+		logger.Warn("operation failed, retrying",
 			zap.String("operation", operation),
 			zap.Int("attempt", attempt),
 			zap.Int("max_retries", p.MaxRetries),
@@ -136,7 +142,7 @@ func (p *RetryPolicy) Retry(ctx context.Context, operation string, fn RetryableF
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("retry aborted: %w", ctx.Err())
-		case <-time.After(delay):
+		case <-clock.Or(p.Clock).After(delay):
 			// Continue to next attempt
 		}
 	}
@@ -197,6 +203,7 @@ func (p *RetryPolicy) RetryWithCallback(
 	if p.Logger == nil {
 		p.Logger = zap.NewNop()
 	}
+	logger := correlation.Logger(ctx, p.Logger)
 
 	var lastErr error
 	attempt := 0
@@ -220,7 +227,7 @@ func (p *RetryPolicy) RetryWithCallback(
 		if err == nil {
 			// Success
 			if attempt > 1 {
-				p.Logger.Info("operation succeeded after retries",
+				logger.Info("operation succeeded after retries",
 					zap.String("operation", operation),
 					zap.Int("attempts", attempt),
 				)
@@ -233,7 +240,7 @@ func (p *RetryPolicy) RetryWithCallback(
 		// Check if we should retry
 		if !p.shouldRetry(attempt, err) {
 			if attempt > 1 {
-				p.Logger.Error("operation failed after retries",
+				logger.Error("operation failed after retries",
 					zap.String("operation", operation),
 					zap.Int("attempts", attempt),
 					zap.Error(err),
@@ -245,7 +252,7 @@ func (p *RetryPolicy) RetryWithCallback(
 		// Calculate delay with backoff and jitter
 		delay := p.calculateDelay(attempt)
 
-		p.Logger.Warn("operation failed, retrying",
+		logger.Warn("operation failed, retrying",
 			zap.String("operation", operation),
 			zap.Int("attempt", attempt),
 			zap.Int("max_retries", p.MaxRetries),
@@ -257,7 +264,7 @@ func (p *RetryPolicy) RetryWithCallback(
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("retry aborted: %w", ctx.Err())
-		case <-time.After(delay):
+		case <-clock.Or(p.Clock).After(delay):
 			// Continue to next attempt
 		}
 	}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/juste-un-gars/anemone_sync_windows/internal/clock"
 	"go.uber.org/zap"
 )
 
@@ -107,6 +109,56 @@ func TestRetrySuccessAfterFailures(t *testing.T) {
 	}
 }
 
+func TestRetryWaitsOnClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	policy := &RetryPolicy{
+		MaxRetries:          2,
+		InitialDelay:        time.Hour,
+		MaxDelay:            time.Hour,
+		Multiplier:          1.0,
+		OnlyRetryableErrors: true,
+		Logger:              zap.NewNop(),
+		Clock:               fake,
+	}
+
+	var attempts atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		done <- policy.Retry(context.Background(), "test-op", func() error {
+			if attempts.Add(1) < 3 {
+				return syscall.ECONNREFUSED
+			}
+			return nil
+		})
+	}()
+
+	waitPending := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for fake.Pending() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("retry never waited on the clock")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitPending()
+	fake.Advance(59 * time.Minute)
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("retried before the delay: %d attempts", got)
+	}
+	fake.Advance(time.Minute)
+	waitPending()
+	fake.Advance(time.Hour)
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
 func TestRetryMaxRetriesExceeded(t *testing.T) {
 	policy := &RetryPolicy{
 		MaxRetries:          2,
@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunningSync describes a sync in progress.
+type RunningSync struct {
+	JobID     int64
+	RunID     string // Correlation ID of the run in the logs
+	Subtree   string // Folder the run is restricted to ("" = whole job)
+	StartedAt time.Time
+	Progress  SyncProgress // Last progress reported (empty Phase before the first report)
+}
+
+// runningSync is the engine state of a sync in progress.
+type runningSync struct {
+	cancel context.CancelFunc
+	info   RunningSync
+}
+
+// ListRunning returns the syncs in progress, oldest first.
+func (e *Engine) ListRunning() []RunningSync {
+	e.mu.RLock()
+	running := make([]RunningSync, 0, len(e.syncing))
+	for _, run := range e.syncing {
+		running = append(running, run.info)
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].StartedAt.Before(running[j].StartedAt)
+	})
+	return running
+}
+
+// CancelAll cancels every sync in progress and returns how many were running.
+// Cancelled syncs return from Sync once their current operation stops.
+func (e *Engine) CancelAll() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for jobID, run := range e.syncing {
+		e.logger.Info("cancelling sync", zap.Int64("job_id", jobID))
+		run.cancel()
+	}
+	return len(e.syncing)
+}
+
+// trackProgress records the last progress of a running sync for ListRunning.
+func (e *Engine) trackProgress(jobID int64, progress *SyncProgress) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if run, ok := e.syncing[jobID]; ok {
+		run.info.Progress = *progress
+	}
+}
@@ -0,0 +1,39 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEngine_ListRunningAndCancelAll(t *testing.T) {
+	e := newFakeEngine(t)
+
+	now := time.Now()
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel1()
+	defer cancel2()
+	e.syncing[2] = &runningSync{cancel: cancel2, info: RunningSync{JobID: 2, StartedAt: now}}
+	e.syncing[1] = &runningSync{cancel: cancel1, info: RunningSync{JobID: 1, StartedAt: now.Add(-time.Minute)}}
+
+	e.reportProgress(&SyncRequest{JobID: 2}, &SyncProgress{Phase: "executing", Percentage: 40})
+
+	running := e.ListRunning()
+	if len(running) != 2 {
+		t.Fatalf("ListRunning() returned %d syncs, want 2", len(running))
+	}
+	if running[0].JobID != 1 || running[1].JobID != 2 {
+		t.Errorf("ListRunning() order = %d, %d, want oldest first (1, 2)", running[0].JobID, running[1].JobID)
+	}
+	if p := running[1].Progress; p.Phase != "executing" || p.Percentage != 40 {
+		t.Errorf("progress of job 2 = %q %.0f%%, want executing 40%%", p.Phase, p.Percentage)
+	}
+
+	if n := e.CancelAll(); n != 2 {
+		t.Errorf("CancelAll() = %d, want 2", n)
+	}
+	if ctx1.Err() == nil || ctx2.Err() == nil {
+		t.Error("CancelAll() did not cancel every sync")
+	}
+}
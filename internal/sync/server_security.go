@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/eventlog"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// --- Negotiated Security ---
+
+// ServerSecurity checks the signing or encryption negotiated by new SMB
+// connections against the servers of the database: servers requiring
+// encryption refuse unencrypted connections, and the level is compared with
+// the best one seen on the server before. Its Check is installed with
+// smb.SetSecurityCheck, by the GUI and the CLI alike.
+type ServerSecurity struct {
+	db     *database.DB
+	logger *zap.Logger
+
+	// OnRecorded, if set, is called when a better level is recorded for a server
+	OnRecorded func(serverID int64, level smb.SecurityLevel)
+	// OnDowngrade, if set, is called once per server and level when a server
+	// negotiates less than before
+	OnDowngrade func(server string, level, recorded smb.SecurityLevel)
+
+	mu     sync.Mutex
+	alerts map[int64]smb.SecurityLevel // Last downgrade reported per server
+}
+
+// NewServerSecurity creates the security check of the servers of db.
+func NewServerSecurity(db *database.DB, logger *zap.Logger) *ServerSecurity {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ServerSecurity{
+		db:     db,
+		logger: logger.Named("server-security"),
+		alerts: make(map[int64]smb.SecurityLevel),
+	}
+}
+
+// Check checks the level negotiated by a new connection to a server (host as
+// configured). Hosts that are not configured (e.g. testing a new server) are
+// not checked.
+func (s *ServerSecurity) Check(host string, level smb.SecurityLevel) error {
+	server, err := s.db.GetSMBServerByHost(host)
+	if err != nil {
+		return fmt.Errorf("failed to read server security settings: %w", err)
+	}
+	if server == nil {
+		return nil
+	}
+
+	if err := s.compare(server, level); err != nil {
+		return err
+	}
+	if server.RequireEncryption && level != smb.SecurityEncrypted {
+		return fmt.Errorf("%w (negotiated: %s)", smb.ErrEncryptionRequired, level.Label())
+	}
+	return nil
+}
+
+// compare records the best level seen on a server. A server negotiating less
+// is reported (once per level until the process restarts), and refused if it
+// requires the recorded level.
+func (s *ServerSecurity) compare(server *database.SMBServer, level smb.SecurityLevel) error {
+	name := server.Name
+	if name == "" {
+		name = server.Host
+	}
+
+	recorded := smb.SecurityLevel(server.SecurityLevel)
+	if recorded.Below(level) {
+		if err := s.db.SetSMBServerSecurityLevel(server.ID, string(level)); err != nil {
+			s.logger.Warn("Failed to record server security", zap.Error(err))
+		}
+		s.logger.Info("Recorded server security",
+			zap.String("server", name),
+			zap.String("security", string(level)))
+		if s.OnRecorded != nil {
+			s.OnRecorded(server.ID, level)
+		}
+		return nil
+	}
+	if !level.Below(recorded) {
+		return nil
+	}
+
+	s.mu.Lock()
+	alerted := s.alerts[server.ID] == level
+	s.alerts[server.ID] = level
+	s.mu.Unlock()
+
+	s.logger.Warn("Server security downgraded",
+		zap.String("server", name),
+		zap.String("security", string(level)),
+		zap.String("recorded", string(recorded)),
+		zap.Bool("refused", server.RequireSecurity))
+	if !alerted {
+		eventlog.Warning(eventlog.EventSecurityDowngrade, fmt.Sprintf(
+			"Server '%s' negotiated %s connections, it used to negotiate %s. "+
+				"The server may have been reconfigured, or someone may be intercepting the connection.",
+			name, level.Label(), recorded.Label()))
+		if s.OnDowngrade != nil {
+			s.OnDowngrade(name, level, recorded)
+		}
+	}
+
+	if server.RequireSecurity {
+		return fmt.Errorf("server negotiated %s, this server requires %s (see its settings)",
+			level.Label(), recorded.Label())
+	}
+	return nil
+}
+
+// Forget forgets the downgrades reported for a server, after its recorded
+// level was reset.
+func (s *ServerSecurity) Forget(serverID int64) {
+	s.mu.Lock()
+	delete(s.alerts, serverID)
+	s.mu.Unlock()
+}
@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+func TestServerSecurity_Check(t *testing.T) {
+	db := newFakeEngine(t).db
+	server := &database.SMBServer{Name: "NAS", Host: "nas.local", Port: 445}
+	if err := db.CreateSMBServer(server); err != nil {
+		t.Fatal(err)
+	}
+	reload := func() *database.SMBServer {
+		s, err := db.GetSMBServer(server.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	check := NewServerSecurity(db, zap.NewNop())
+	var downgrades int
+	check.OnDowngrade = func(string, smb.SecurityLevel, smb.SecurityLevel) { downgrades++ }
+
+	// Unknown servers are not checked
+	if err := check.Check("other.local", smb.SecurityNone); err != nil {
+		t.Errorf("unknown server: %v", err)
+	}
+
+	// The first connection records the level, the host is matched as configured
+	if err := check.Check("NAS.local", smb.SecuritySigned); err != nil {
+		t.Fatalf("first connection: %v", err)
+	}
+	if got := reload().SecurityLevel; got != string(smb.SecuritySigned) {
+		t.Errorf("recorded level: got %q, want signed", got)
+	}
+
+	// A downgrade is reported once per level, and allowed by default
+	for i := 0; i < 2; i++ {
+		if err := check.Check("nas.local", smb.SecurityNone); err != nil {
+			t.Errorf("downgrade refused without RequireSecurity: %v", err)
+		}
+	}
+	if downgrades != 1 {
+		t.Errorf("expected 1 downgrade reported, got %d", downgrades)
+	}
+
+	// Refused once the server requires its recorded level
+	s := reload()
+	s.RequireSecurity = true
+	if err := db.UpdateSMBServer(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Check("nas.local", smb.SecurityNone); err == nil {
+		t.Error("expected downgrade refused with RequireSecurity")
+	}
+	if err := check.Check("nas.local", smb.SecuritySigned); err != nil {
+		t.Errorf("recorded level refused: %v", err)
+	}
+
+	// Servers requiring encryption refuse anything else
+	s = reload()
+	s.RequireSecurity = false
+	s.RequireEncryption = true
+	if err := db.UpdateSMBServer(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Check("nas.local", smb.SecuritySigned); !errors.Is(err, smb.ErrEncryptionRequired) {
+		t.Errorf("expected ErrEncryptionRequired, got %v", err)
+	}
+	if err := check.Check("nas.local", smb.SecurityEncrypted); err != nil {
+		t.Errorf("encrypted connection refused: %v", err)
+	}
+	if got := reload().SecurityLevel; got != string(smb.SecurityEncrypted) {
+		t.Errorf("recorded level: got %q, want encrypted", got)
+	}
+}
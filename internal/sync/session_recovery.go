@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// maxSessionRecoveries bounds the reconnections for a single operation: a
+// server dropping the session again right away is not coming back cleanly.
+const maxSessionRecoveries = 2
+
+// sessionRecoverer re-establishes a lost SMB session (*smb.SMBClient,
+// replaced by tests).
+type sessionRecoverer interface {
+	Generation() uint64
+	Recover(ctx context.Context, gen uint64) error
+}
+
+// withSessionRecovery runs an operation, and when it fails because the
+// session was lost (NAS rebooted mid-run), reconnects and runs it again
+// rather than failing it and every operation after it. The reconnection is
+// shared with the other operations failing at the same time.
+func withSessionRecovery(ctx context.Context, client sessionRecoverer, logger *zap.Logger, fn func() error) error {
+	if client == nil {
+		return fn()
+	}
+
+	for recoveries := 0; ; recoveries++ {
+		gen := client.Generation()
+		err := fn()
+		if err == nil || recoveries == maxSessionRecoveries || !smb.IsStaleSession(err) || ctx.Err() != nil {
+			return err
+		}
+
+		logger.Warn("SMB session lost during operation, reconnecting", zap.Error(err))
+		if recoverErr := client.Recover(ctx, gen); recoverErr != nil {
+			return fmt.Errorf("%w (reconnecting failed: %v)", err, recoverErr)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/go-smb2"
+	"go.uber.org/zap"
+)
+
+// fakeSession counts reconnections, failing them with err.
+type fakeSession struct {
+	gen       uint64
+	recovered []uint64 // Generations passed to Recover
+	err       error
+}
+
+func (s *fakeSession) Generation() uint64 { return s.gen }
+
+func (s *fakeSession) Recover(ctx context.Context, gen uint64) error {
+	s.recovered = append(s.recovered, gen)
+	if s.err != nil {
+		return s.err
+	}
+	s.gen++
+	return nil
+}
+
+func TestWithSessionRecovery(t *testing.T) {
+	stale := WrapSyncError(&smb2.ResponseError{Code: 0xC00000C9}, "a.txt", "upload") // Network name deleted
+
+	t.Run("recovers and runs again", func(t *testing.T) {
+		session := &fakeSession{gen: 3}
+		calls := 0
+		err := withSessionRecovery(context.Background(), session, zap.NewNop(), func() error {
+			calls++
+			if calls == 1 {
+				return stale
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Fatalf("err = %v after %d calls, want success after 2", err, calls)
+		}
+		if len(session.recovered) != 1 || session.recovered[0] != 3 {
+			t.Errorf("Recover called with %v, want the generation the operation ran on [3]", session.recovered)
+		}
+	})
+
+	t.Run("other errors are not recovered", func(t *testing.T) {
+		session := &fakeSession{}
+		denied := &smb2.ResponseError{Code: 0xC0000022} // Access denied
+		err := withSessionRecovery(context.Background(), session, zap.NewNop(), func() error { return denied })
+		if !errors.Is(err, denied) || len(session.recovered) != 0 {
+			t.Errorf("err = %v, %d recoveries, want the error and none", err, len(session.recovered))
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		session := &fakeSession{}
+		calls := 0
+		err := withSessionRecovery(context.Background(), session, zap.NewNop(), func() error {
+			calls++
+			return stale
+		})
+		if err == nil || calls != maxSessionRecoveries+1 {
+			t.Errorf("err = %v after %d calls, want a failure after %d", err, calls, maxSessionRecoveries+1)
+		}
+	})
+
+	t.Run("recovery fails", func(t *testing.T) {
+		session := &fakeSession{err: errors.New("server still unreachable")}
+		err := withSessionRecovery(context.Background(), session, zap.NewNop(), func() error { return stale })
+		if err == nil || !strings.Contains(err.Error(), "server still unreachable") {
+			t.Errorf("err = %v, want the operation error with why reconnecting failed", err)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/longpath"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// --- Volume Shadow Copies ---
+//
+// Some programs keep their files open without sharing them (Outlook .pst,
+// databases), so uploading them fails with a sharing violation. Jobs reading
+// locked files upload such a file from a Volume Shadow Copy of its volume
+// instead: a read-only snapshot created the first time the run meets a locked
+// file on the volume, shared by its later locked files and deleted once the
+// transfers of the run are done. The snapshot holds the file as a power loss
+// would have left it. Creating snapshots needs administrator rights; a volume
+// whose snapshot can't be created isn't retried during the run and its locked
+// files fail as before.
+
+// errShadowCopyUnsupported is returned for paths not on a local drive.
+var errShadowCopyUnsupported = errors.New("shadow copies are only available for local drives")
+
+// shadowCopyErrors describes the return values of Win32_ShadowCopy.Create.
+var shadowCopyErrors = map[int]string{
+	1:  "access denied",
+	2:  "invalid argument",
+	3:  "volume not found",
+	4:  "volume not supported",
+	6:  "not enough storage for the snapshot",
+	8:  "maximum number of shadow copies reached",
+	9:  "another shadow copy is being created",
+	10: "shadow copy provider vetoed the operation",
+}
+
+// shadowCopyID matches the IDs of shadow copies.
+var shadowCopyID = regexp.MustCompile(`^\{[0-9A-Fa-f]{8}(-[0-9A-Fa-f]{4}){3}-[0-9A-Fa-f]{12}\}$`)
+
+type shadowCopiesKey struct{}
+
+// shadowCopy is a snapshot of a volume.
+type shadowCopy struct {
+	id     string // Snapshot ID, to delete it
+	device string // Device holding the snapshot (\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopyN)
+}
+
+// shadowCopies are the snapshots of a run, by volume ("C:").
+type shadowCopies struct {
+	mu     sync.Mutex
+	copies map[string]*shadowCopy
+	failed map[string]error
+}
+
+// withShadowCopies records the snapshots of the run of ctx when the job reads
+// locked files. The returned set must be released once the run's transfers
+// are done (nil when the job doesn't read locked files).
+func withShadowCopies(ctx context.Context, req *SyncRequest) (context.Context, *shadowCopies) {
+	if !req.ReadLockedFiles {
+		return ctx, nil
+	}
+	s := &shadowCopies{
+		copies: make(map[string]*shadowCopy),
+		failed: make(map[string]error),
+	}
+	return context.WithValue(ctx, shadowCopiesKey{}, s), s
+}
+
+// shadowCopiesOf returns the snapshots of the run of ctx (nil when the job
+// doesn't read locked files).
+func shadowCopiesOf(ctx context.Context) *shadowCopies {
+	s, _ := ctx.Value(shadowCopiesKey{}).(*shadowCopies)
+	return s
+}
+
+// path returns the path of localPath in the snapshot of its volume, creating
+// the snapshot if the run has none yet.
+func (s *shadowCopies) path(ctx context.Context, localPath string) (string, error) {
+	volume := driveOf(localPath)
+	if volume == "" {
+		return "", errShadowCopyUnsupported
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.failed[volume]; err != nil {
+		return "", err
+	}
+	snapshot := s.copies[volume]
+	if snapshot == nil {
+		var err error
+		snapshot, err = createShadowCopy(ctx, volume)
+		if err != nil {
+			err = fmt.Errorf("failed to create shadow copy of %s: %w", volume, err)
+			s.failed[volume] = err
+			return "", err
+		}
+		s.copies[volume] = snapshot
+	}
+	return shadowPath(snapshot.device, localPath), nil
+}
+
+// release deletes the snapshots of the run.
+func (s *shadowCopies) release(logger *zap.Logger) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for volume, snapshot := range s.copies {
+		if err := deleteShadowCopy(snapshot.id); err != nil {
+			logger.Warn("failed to delete shadow copy",
+				zap.String("volume", volume),
+				zap.String("id", snapshot.id),
+				zap.Error(err))
+		}
+		delete(s.copies, volume)
+	}
+}
+
+// driveOf returns the drive of a local path in upper case ("C:"), "" for
+// paths not on a drive letter (UNC paths).
+func driveOf(localPath string) string {
+	p := longpath.Strip(localPath)
+	if len(p) < 2 || p[1] != ':' {
+		return ""
+	}
+	c := p[0] | 0x20
+	if c < 'a' || c > 'z' {
+		return ""
+	}
+	return strings.ToUpper(p[:2])
+}
+
+// shadowPath returns the path of localPath in the snapshot device of its
+// volume.
+func shadowPath(device, localPath string) string {
+	rest := longpath.Strip(localPath)[2:]
+	if !strings.HasPrefix(rest, `\`) && !strings.HasPrefix(rest, "/") {
+		rest = `\` + rest
+	}
+	return strings.TrimRight(device, `\`) + rest
+}
+
+// parseShadowCopy reads the "ID|device" line printed after creating a
+// snapshot, or "error|code" when Win32_ShadowCopy.Create failed.
+func parseShadowCopy(output string) (*shadowCopy, error) {
+	line := strings.TrimSpace(output)
+	id, device, ok := strings.Cut(line, "|")
+	if !ok {
+		return nil, fmt.Errorf("unexpected output: %q", line)
+	}
+	if id == "error" {
+		code, _ := strconv.Atoi(device)
+		if reason, known := shadowCopyErrors[code]; known {
+			return nil, errors.New(reason)
+		}
+		return nil, fmt.Errorf("error %s", device)
+	}
+	if !shadowCopyID.MatchString(id) || !strings.HasPrefix(device, `\\?\GLOBALROOT\`) {
+		return nil, fmt.Errorf("unexpected output: %q", line)
+	}
+	return &shadowCopy{id: id, device: device}, nil
+}
+
+// uploadFromShadowCopy uploads a locked local file from the snapshot of its
+// volume.
+func (ex *Executor) uploadFromShadowCopy(ctx context.Context, shadows *shadowCopies,
+	decision *cache.SyncDecision, smbClient *smb.SMBClient) (string, error) {
+
+	source, err := shadows.path(ctx, decision.LocalPath)
+	if err != nil {
+		ex.log(ctx).Warn("locked file can't be read from a shadow copy",
+			zap.String("path", decision.LocalPath), zap.Error(err))
+		return "", err
+	}
+	ex.log(ctx).Info("file locked by another program, uploading it from a shadow copy",
+		zap.String("path", decision.LocalPath),
+		zap.String("snapshot", source))
+	return smbClient.UploadWithHashContext(ctx, source, decision.RemotePath)
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package sync
+
+import (
+	"context"
+	"errors"
+)
+
+// createShadowCopy creates a Volume Shadow Copy, which other platforms don't
+// support.
+func createShadowCopy(ctx context.Context, volume string) (*shadowCopy, error) {
+	return nil, errors.New("shadow copies are only available on Windows")
+}
+
+// deleteShadowCopy has no snapshot to delete outside Windows.
+func deleteShadowCopy(id string) error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShadowPath(t *testing.T) {
+	device := `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3`
+	tests := []struct {
+		localPath string
+		want      string
+	}{
+		{`C:\Users\marie\Documents\Outlook\mail.pst`, device + `\Users\marie\Documents\Outlook\mail.pst`},
+		{`\\?\D:\data\base.mdb`, device + `\data\base.mdb`},
+		{`c:mail.pst`, device + `\mail.pst`},
+	}
+	for _, tt := range tests {
+		if got := shadowPath(device, tt.localPath); got != tt.want {
+			t.Errorf("shadowPath(%q) = %q, want %q", tt.localPath, got, tt.want)
+		}
+	}
+}
+
+func TestDriveOf(t *testing.T) {
+	tests := map[string]string{
+		`c:\data\a.pst`:          "C:",
+		`\\?\D:\data\a.pst`:      "D:",
+		`\\server\share\a.pst`:   "",
+		`\\?\UNC\server\share\a`: "",
+		`/home/marie/a.pst`:      "",
+		`1:\a`:                   "",
+	}
+	for path, want := range tests {
+		if got := driveOf(path); got != want {
+			t.Errorf("driveOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestParseShadowCopy(t *testing.T) {
+	snapshot, err := parseShadowCopy("{5A1B2C3D-1234-4ABC-9DEF-0123456789AB}|\\\\?\\GLOBALROOT\\Device\\HarddiskVolumeShadowCopy7\r\n")
+	if err != nil {
+		t.Fatalf("parseShadowCopy() error = %v", err)
+	}
+	if snapshot.id != "{5A1B2C3D-1234-4ABC-9DEF-0123456789AB}" || snapshot.device != `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy7` {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+
+	if _, err := parseShadowCopy("error|1"); err == nil || err.Error() != "access denied" {
+		t.Errorf("error|1: err = %v, want access denied", err)
+	}
+	for _, output := range []string{"", "error|99", "not-an-id|\\\\?\\GLOBALROOT\\x", "{5A1B2C3D-1234-4ABC-9DEF-0123456789AB}|C:\\"} {
+		if _, err := parseShadowCopy(output); err == nil {
+			t.Errorf("parseShadowCopy(%q) should fail", output)
+		}
+	}
+}
+
+func TestWithShadowCopies(t *testing.T) {
+	ctx, shadows := withShadowCopies(context.Background(), &SyncRequest{})
+	if shadows != nil || shadowCopiesOf(ctx) != nil {
+		t.Error("jobs not reading locked files should have no shadow copies")
+	}
+	shadows.release(nil) // no-op on nil
+
+	ctx, shadows = withShadowCopies(context.Background(), &SyncRequest{ReadLockedFiles: true})
+	if shadows == nil || shadowCopiesOf(ctx) != shadows {
+		t.Fatal("shadow copies not recorded in the context")
+	}
+	if _, err := shadows.path(ctx, `\\server\share\a.pst`); !errors.Is(err, errShadowCopyUnsupported) {
+		t.Errorf("UNC path: err = %v, want errShadowCopyUnsupported", err)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// createShadowCopy creates a client-accessible snapshot of volume ("C:")
+// through WMI. Requires administrator rights.
+func createShadowCopy(ctx context.Context, volume string) (*shadowCopy, error) {
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		return nil, errors.New("administrator rights required")
+	}
+
+	script := fmt.Sprintf(`$r = Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume='%s\'; Context='ClientAccessible'}
+if ($r.ReturnValue -ne 0) { Write-Output "error|$($r.ReturnValue)"; exit }
+$s = Get-CimInstance Win32_ShadowCopy -Filter "ID='$($r.ShadowID)'"
+Write-Output "$($s.ID)|$($s.DeviceObject)"`, volume)
+	output, err := runPowerShell(ctx, script)
+	if err != nil {
+		return nil, err
+	}
+	return parseShadowCopy(output)
+}
+
+// deleteShadowCopy deletes the snapshot with ID id.
+func deleteShadowCopy(id string) error {
+	if !shadowCopyID.MatchString(id) {
+		return fmt.Errorf("invalid shadow copy ID %q", id)
+	}
+	script := fmt.Sprintf(`Get-CimInstance Win32_ShadowCopy -Filter "ID='%s'" | Remove-CimInstance`, id)
+	_, err := runPowerShell(context.Background(), script)
+	return err
+}
+
+// runPowerShell runs script in a hidden PowerShell and returns its output.
+func runPowerShell(ctx context.Context, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("powershell failed: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/correlation"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// SmallFileBatch uploads bursts of small files in batches (see
+// smb.SMBClient.UploadBatch) instead of one transfer each, e.g. the
+// "50 petits fichiers" stress scenario.
+type SmallFileBatch struct {
+	MaxSize  int64 // Largest file of a batch, in bytes (capped at smb.MaxBatchFileSize)
+	MinFiles int   // Fewer small uploads than this go through the regular path
+	Streams  int   // Files in flight at once
+}
+
+// SetSmallFileBatch enables upload batches for small files.
+// MaxSize <= 0 disables them.
+func (ex *Executor) SetSmallFileBatch(batch SmallFileBatch) {
+	if batch.MaxSize <= 0 {
+		ex.smallFiles = nil
+		ex.logger.Info("small file batches disabled")
+		return
+	}
+	batch.MaxSize = min(batch.MaxSize, smb.MaxBatchFileSize)
+	batch.MinFiles = max(batch.MinFiles, 1)
+	batch.Streams = max(batch.Streams, 1)
+	ex.smallFiles = &batch
+	ex.logger.Info("small file batches configured",
+		zap.Int64("max_size", batch.MaxSize),
+		zap.Int("min_files", batch.MinFiles),
+		zap.Int("streams", batch.Streams))
+}
+
+// split separates the uploads that can go in a batch from the other
+// decisions, whose order is kept. Files with a read-only bit on either side
+// need attribute requests, and conflict winners a rename of the loser: they
+// take the regular path.
+func (b *SmallFileBatch) split(decisions []*cache.SyncDecision) (small, rest []*cache.SyncDecision) {
+	for _, d := range decisions {
+		if d.Action == cache.ActionUpload && d.ConflictCopy == "" && d.LocalInfo != nil && d.LocalInfo.Size <= b.MaxSize &&
+			d.LocalInfo.Attributes&cache.AttrReadOnly == 0 &&
+			(d.RemoteInfo == nil || d.RemoteInfo.Attributes&cache.AttrReadOnly == 0) {
+			small = append(small, d)
+		} else {
+			rest = append(rest, d)
+		}
+	}
+	if len(small) < b.MinFiles {
+		return nil, decisions
+	}
+	return small, rest
+}
+
+// executeSmallFiles uploads small files in a batch. Returns the actions of
+// the uploaded files and the decisions of the failed ones, to retry through
+// the regular path.
+func (ex *Executor) executeSmallFiles(
+	ctx context.Context,
+	small []*cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	progressFn ProgressCallback,
+	batcher *actionBatcher,
+) ([]*SyncAction, []*cache.SyncDecision) {
+
+	ex.log(ctx).Info("uploading small files in a batch",
+		zap.Int("count", len(small)),
+		zap.Int("streams", ex.smallFiles.Streams),
+	)
+	if progressFn != nil {
+		progressFn(&SyncProgress{
+			Phase:         "executing",
+			FilesTotal:    len(small),
+			CurrentAction: fmt.Sprintf("upload batch: %d files", len(small)),
+			Percentage:    35,
+		})
+	}
+
+	files := make([]smb.BatchUpload, len(small))
+	for i, d := range small {
+		files[i] = smb.BatchUpload{
+			LocalPath:  d.LocalPath,
+			RemotePath: d.RemotePath,
+			Replace:    d.RemoteInfo != nil,
+		}
+	}
+
+	startTime := ex.clock.Now()
+	results := smbClient.UploadBatch(ctx, files, ex.smallFiles.Streams)
+	elapsed := ex.clock.Now().Sub(startTime)
+
+	actions := make([]*SyncAction, 0, len(small))
+	var failed []*cache.SyncDecision
+	for i, r := range results {
+		d := small[i]
+		if r.Err != nil {
+			ex.log(ctx).Debug("batched upload failed, retrying alone",
+				zap.String("path", d.LocalPath),
+				zap.Error(r.Err),
+			)
+			failed = append(failed, d)
+			continue
+		}
+
+		action := &SyncAction{
+			FilePath:         d.LocalPath,
+			RemotePath:       d.RemotePath,
+			Action:           d.Action,
+			Reason:           d.Reason,
+			Replaces:         replacesFile(d),
+			Status:           ActionStatusSuccess,
+			OpID:             correlation.NewOpID(),
+			Attempts:         1,
+			Hash:             r.Hash,
+			Size:             r.Size,
+			BytesTransferred: r.Size,
+			Attributes:       d.LocalInfo.Attributes,
+			MTime:            d.LocalInfo.MTime,
+			ChangedBy:        ex.uploadOwner(smbClient),
+			Duration:         elapsed / time.Duration(len(small)),
+			Timestamp:        startTime,
+		}
+		if err := ex.verifyTransfer(ctx, action, smbClient); err != nil {
+			ex.log(ctx).Debug("batched upload failed verification, retrying alone",
+				zap.String("path", d.LocalPath),
+				zap.Error(err),
+			)
+			failed = append(failed, d)
+			continue
+		}
+		ex.copyStreams(ctx, action, smbClient)
+		ex.copyACL(ctx, action, smbClient)
+		actions = append(actions, action)
+		countTransferred(action)
+		batcher.add(action)
+	}
+
+	return actions, failed
+}
@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+)
+
+func TestSmallFileBatchSplit(t *testing.T) {
+	upload := func(path string, size int64) *cache.SyncDecision {
+		return &cache.SyncDecision{LocalPath: path, Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Size: size}}
+	}
+
+	readOnly := upload("readonly", 10)
+	readOnly.LocalInfo.Attributes = cache.AttrReadOnly
+	remoteReadOnly := upload("remote-readonly", 10)
+	remoteReadOnly.RemoteInfo = &cache.FileInfo{Size: 5, Attributes: cache.AttrReadOnly}
+	replaced := upload("replaced", 10)
+	replaced.RemoteInfo = &cache.FileInfo{Size: 5}
+
+	decisions := []*cache.SyncDecision{
+		{LocalPath: "download", Action: cache.ActionDownload, RemoteInfo: &cache.FileInfo{Size: 10}},
+		upload("small1", 10),
+		upload("large", 1000),
+		readOnly,
+		upload("small2", 100),
+		remoteReadOnly,
+		replaced,
+		{LocalPath: "delete", Action: cache.ActionDeleteRemote},
+	}
+
+	batch := &SmallFileBatch{MaxSize: 100, MinFiles: 3}
+	small, rest := batch.split(decisions)
+	if got := decisionPaths(small); got != "small1,small2,replaced" {
+		t.Errorf("small = %s", got)
+	}
+	if got := decisionPaths(rest); got != "download,large,readonly,remote-readonly,delete" {
+		t.Errorf("rest = %s", got)
+	}
+
+	// Too few small files for a batch
+	batch.MinFiles = 4
+	small, rest = batch.split(decisions)
+	if len(small) != 0 || len(rest) != len(decisions) {
+		t.Errorf("expected no batch below MinFiles, got %d small files", len(small))
+	}
+}
+
+func TestSetSmallFileBatch(t *testing.T) {
+	ex := NewExecutor(4, nil)
+	if ex.smallFiles != nil {
+		t.Fatal("small file batches should be disabled by default")
+	}
+
+	ex.SetSmallFileBatch(SmallFileBatch{MaxSize: 10 * smb.MaxBatchFileSize})
+	if ex.smallFiles == nil || ex.smallFiles.MaxSize != smb.MaxBatchFileSize {
+		t.Fatalf("expected MaxSize capped at %d, got %+v", smb.MaxBatchFileSize, ex.smallFiles)
+	}
+	if ex.smallFiles.MinFiles != 1 || ex.smallFiles.Streams != 1 {
+		t.Errorf("expected MinFiles and Streams at least 1, got %+v", ex.smallFiles)
+	}
+
+	ex.SetSmallFileBatch(SmallFileBatch{})
+	if ex.smallFiles != nil {
+		t.Error("MaxSize 0 should disable small file batches")
+	}
+}
+
+func decisionPaths(decisions []*cache.SyncDecision) string {
+	paths := make([]string, len(decisions))
+	for i, d := range decisions {
+		paths[i] = d.LocalPath
+	}
+	return strings.Join(paths, ",")
+}
@@ -0,0 +1,108 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
+	"go.uber.org/zap"
+)
+
+// --- Alternate Data Streams ---
+//
+// Jobs syncing streams copy the NTFS alternate data streams of each
+// transferred file (Zone.Identifier, tags of other applications) to its copy,
+// once its main stream is transferred. Like permissions, remote streams go
+// through the Windows SMB redirector. Writing a stream changes the
+// modification time of the file, which is restored afterwards so the next
+// scan doesn't see the file as modified. A stream that can't be copied is
+// logged and doesn't fail the transfer.
+
+type syncStreamsKey struct{}
+
+// withSyncStreams records whether the run of ctx copies alternate data streams.
+func withSyncStreams(ctx context.Context, req *SyncRequest) context.Context {
+	if !req.SyncStreams {
+		return ctx
+	}
+	return context.WithValue(ctx, syncStreamsKey{}, true)
+}
+
+// syncStreams reports whether the run of ctx copies alternate data streams.
+func syncStreams(ctx context.Context) bool {
+	enabled, _ := ctx.Value(syncStreamsKey{}).(bool)
+	return enabled
+}
+
+// copyStreams copies the alternate data streams of a transferred file to its
+// destination when the run syncs streams.
+func (ex *Executor) copyStreams(ctx context.Context, action *SyncAction, smbClient *smb.SMBClient) {
+	if !syncStreams(ctx) || smbClient == nil {
+		return
+	}
+
+	src, dst := action.FilePath, smbClient.RedirectorPath(action.RemotePath)
+	switch action.Action {
+	case cache.ActionUpload:
+	case cache.ActionDownload:
+		src, dst = dst, src
+	default:
+		return
+	}
+
+	streams, err := scanner.AlternateStreams(src)
+	if err == nil && len(streams) > 0 {
+		err = copyStreamsTo(src, dst, streams)
+	}
+	if err != nil {
+		ex.log(ctx).Warn("failed to copy alternate data streams",
+			zap.String("path", action.FilePath),
+			zap.String("action", string(action.Action)),
+			zap.Error(err),
+		)
+		return
+	}
+	if len(streams) > 0 {
+		ex.log(ctx).Debug("alternate data streams copied",
+			zap.String("path", action.FilePath),
+			zap.Int("streams", len(streams)),
+		)
+	}
+}
+
+// copyStreamsTo copies streams of src to dst, keeping the modification time
+// of dst.
+func copyStreamsTo(src, dst string, streams []scanner.Stream) error {
+	info, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	for _, s := range streams {
+		if err := copyStream(s.Path(src), s.Path(dst)); err != nil {
+			return fmt.Errorf("stream %s: %w", s.Name, err)
+		}
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func copyStream(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
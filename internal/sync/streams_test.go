@@ -0,0 +1,47 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+)
+
+func TestCopyStreamsTo_KeepsModTime(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.exe")
+	dst := filepath.Join(dir, "dst.exe")
+	stream := scanner.Stream{Name: "Zone.Identifier"}
+
+	// Outside NTFS, stream paths are plain files next to the file
+	for path, data := range map[string]string{src: "main", stream.Path(src): "[ZoneTransfer]\r\nZoneId=3", dst: "main"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(dst, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyStreamsTo(src, dst, []scanner.Stream{stream}); err != nil {
+		t.Fatalf("copyStreamsTo() error = %v", err)
+	}
+
+	got, err := os.ReadFile(stream.Path(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "[ZoneTransfer]\r\nZoneId=3" {
+		t.Errorf("stream = %q", got)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
@@ -0,0 +1,104 @@
+package sync
+
+import (
+	"path"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+)
+
+// NormalizeSubtree cleans a job-relative folder for SyncRequest.Subtree:
+// forward slashes, no leading or trailing slash. "" and "." mean the whole job.
+// Returns ErrInvalidSubtree for drive-qualified paths or paths leaving the job.
+func NormalizeSubtree(subtree string) (string, error) {
+	subtree = strings.ReplaceAll(subtree, "\\", "/")
+	if strings.Contains(subtree, ":") {
+		return "", ErrInvalidSubtree
+	}
+
+	// Reject ".." rather than silently syncing another folder than the one requested
+	for _, part := range strings.Split(subtree, "/") {
+		if part == ".." {
+			return "", ErrInvalidSubtree
+		}
+	}
+
+	cleaned := path.Clean("/" + subtree)
+	return strings.Trim(cleaned, "/"), nil
+}
+
+// CommonSubtree returns the deepest folder containing both job-relative folders
+// ("" = job root).
+func CommonSubtree(a, b string) string {
+	if a == "" || b == "" {
+		return ""
+	}
+	partsA := strings.Split(a, "/")
+	partsB := strings.Split(b, "/")
+
+	n := 0
+	for n < len(partsA) && n < len(partsB) && strings.EqualFold(partsA[n], partsB[n]) {
+		n++
+	}
+	return strings.Join(partsA[:n], "/")
+}
+
+// filterSelection removes the files outside a selective sync.
+func filterSelection(files map[string]*cache.FileInfo, selection scanner.Selection) {
+	if selection.IsEmpty() {
+		return
+	}
+	for relPath := range files {
+		if !selection.Contains(relPath) {
+			delete(files, relPath)
+		}
+	}
+}
+
+// filterPattern removes the files whose path doesn't match pattern (nil keeps everything).
+func filterPattern(files map[string]*cache.FileInfo, pattern *scanner.Pattern) {
+	if pattern == nil {
+		return
+	}
+	for relPath := range files {
+		if !pattern.MatchPath(relPath) {
+			delete(files, relPath)
+		}
+	}
+}
+
+// filterExcluded removes the files excluded by the rules of the job (nil
+// keeps everything) from every side. A path excluded on one side is removed
+// from all of them: with size rules the sides may disagree, and a file kept on
+// one side only would look created or deleted.
+func filterExcluded(exclusions *scanner.JobExclusions, sides ...map[string]*cache.FileInfo) {
+	if exclusions == nil {
+		return
+	}
+	excluded := make(map[string]bool)
+	for _, files := range sides {
+		for relPath, file := range files {
+			if exclusions.Excludes(relPath, file.Size) {
+				excluded[relPath] = true
+			}
+		}
+	}
+	for relPath := range excluded {
+		for _, files := range sides {
+			delete(files, relPath)
+		}
+	}
+}
+
+// filterSubtree removes the files outside subtree ("" keeps everything).
+func filterSubtree(files map[string]*cache.FileInfo, subtree string) {
+	if subtree == "" {
+		return
+	}
+	for relPath := range files {
+		if !scanner.InSubtree(relPath, subtree) {
+			delete(files, relPath)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	scannerpkg "github.com/juste-un-gars/anemone_sync_windows/internal/scanner"
+)
+
+func TestNormalizeSubtree(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{".", "", false},
+		{"/", "", false},
+		{"Docs/Reports", "Docs/Reports", false},
+		{`Docs\Reports\`, "Docs/Reports", false},
+		{"/Docs//Reports/./", "Docs/Reports", false},
+		{"../Other", "", true},
+		{`Docs\..\..\Other`, "", true},
+		{`C:\Users\me\Docs`, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeSubtree(tt.input)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSubtree) {
+				t.Errorf("NormalizeSubtree(%q): expected ErrInvalidSubtree, got %v", tt.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NormalizeSubtree(%q): unexpected error %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeSubtree(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestCommonSubtree(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"ProjectA", "ProjectA", "ProjectA"},
+		{"ProjectA/src", "ProjectA/docs", "ProjectA"},
+		{"ProjectA/src", "projecta/src/lib", "ProjectA/src"},
+		{"ProjectA", "ProjectB", ""},
+		{"ProjectA", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := CommonSubtree(tt.a, tt.b); got != tt.want {
+			t.Errorf("CommonSubtree(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFilterSubtree(t *testing.T) {
+	files := map[string]*cache.FileInfo{
+		"root.txt":           {Path: "root.txt"},
+		"ProjectA/a.txt":     {Path: "ProjectA/a.txt"},
+		"ProjectA/sub/b.txt": {Path: "ProjectA/sub/b.txt"},
+		"ProjectAB/c.txt":    {Path: "ProjectAB/c.txt"},
+	}
+
+	filterSubtree(files, "ProjectA")
+
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files in subtree, got %d", len(files))
+	}
+	if _, ok := files["ProjectAB/c.txt"]; ok {
+		t.Error("sibling folder with the same prefix should be filtered out")
+	}
+}
+
+func TestFilterPattern(t *testing.T) {
+	files := map[string]*cache.FileInfo{
+		"root.txt":                 {Path: "root.txt"},
+		"Projects/Active/a.txt":    {Path: "Projects/Active/a.txt"},
+		"Projects/Active/sub/b.md": {Path: "Projects/Active/sub/b.md"},
+		"Projects/Archive/c.txt":   {Path: "Projects/Archive/c.txt"},
+	}
+
+	pattern, err := scannerpkg.CompileGlob("projects/active/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterPattern(files, pattern)
+
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files matching the pattern, got %d", len(files))
+	}
+	if _, ok := files["Projects/Active/sub/b.md"]; !ok {
+		t.Error("files in subfolders should match **")
+	}
+}
+
+func TestFilterExcluded(t *testing.T) {
+	rules, errs := scannerpkg.ParseRules("*.iso\nsize>1KB\n")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	exclusions := scannerpkg.NewJobExclusions(rules)
+
+	local := map[string]*cache.FileInfo{
+		"doc.txt":   {Path: "doc.txt", Size: 10},
+		"grown.bin": {Path: "grown.bin", Size: 10},
+	}
+	remote := map[string]*cache.FileInfo{
+		"doc.txt":   {Path: "doc.txt", Size: 10},
+		"disk.iso":  {Path: "disk.iso", Size: 10},
+		"grown.bin": {Path: "grown.bin", Size: 4096},
+	}
+	cached := map[string]*cache.FileInfo{
+		"doc.txt":   {Path: "doc.txt", Size: 10},
+		"disk.iso":  {Path: "disk.iso", Size: 10},
+		"grown.bin": {Path: "grown.bin", Size: 10},
+	}
+	filterExcluded(exclusions, local, remote, cached)
+
+	// Excluded on the remote side only: left out everywhere, not deleted locally
+	for _, files := range []map[string]*cache.FileInfo{local, remote, cached} {
+		if len(files) != 1 || files["doc.txt"] == nil {
+			t.Errorf("expected only doc.txt, got %v", files)
+		}
+	}
+
+	filterExcluded(nil, remote)
+	if len(remote) != 1 {
+		t.Error("nil exclusions should keep everything")
+	}
+}
@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+	"go.uber.org/zap"
+)
+
+// BandwidthLimits caps the transfer rate of the executor, shared by all the
+// runs it executes (0 = unlimited).
+type BandwidthLimits struct {
+	UploadKBps   int
+	DownloadKBps int
+
+	// MeteredKBps caps each direction while Windows reports the connection
+	// as metered, for jobs with ThrottleMetered (every job if ThrottleMetered
+	// is set here). 0 = no metered limit.
+	MeteredKBps     int
+	ThrottleMetered bool
+}
+
+// executorBandwidth holds the shared limiters of an executor.
+type executorBandwidth struct {
+	limits        BandwidthLimits
+	upload        *bandwidth.Limiter
+	download      *bandwidth.Limiter
+	meteredUpload *bandwidth.Limiter
+	meteredDown   *bandwidth.Limiter
+}
+
+// SetBandwidthLimits caps the transfer rate of all the runs of the executor.
+// Jobs may add their own limits on top (SyncRequest.MaxUploadKBps...).
+func (ex *Executor) SetBandwidthLimits(limits BandwidthLimits) {
+	ex.throttle = &executorBandwidth{
+		limits:        limits,
+		upload:        bandwidth.KBps(limits.UploadKBps),
+		download:      bandwidth.KBps(limits.DownloadKBps),
+		meteredUpload: bandwidth.KBps(limits.MeteredKBps),
+		meteredDown:   bandwidth.KBps(limits.MeteredKBps),
+	}
+	ex.logger.Info("bandwidth limits configured",
+		zap.Int("upload_kbps", limits.UploadKBps),
+		zap.Int("download_kbps", limits.DownloadKBps),
+		zap.Int("metered_kbps", limits.MeteredKBps),
+		zap.Bool("throttle_metered", limits.ThrottleMetered))
+}
+
+// withBandwidth adds the limiters of the executor to those of the run in ctx.
+// The connection cost is checked once per run.
+func (ex *Executor) withBandwidth(ctx context.Context) context.Context {
+	bw := ex.throttle
+	if bw == nil {
+		return ctx
+	}
+	ctx = bandwidth.WithLimiter(ctx, bandwidth.Upload, bw.upload)
+	ctx = bandwidth.WithLimiter(ctx, bandwidth.Download, bw.download)
+
+	if bw.meteredUpload == nil || (!bw.limits.ThrottleMetered && !throttleMetered(ctx)) {
+		return ctx
+	}
+	metered, err := ex.isMetered()
+	if err != nil {
+		ex.log(ctx).Debug("connection cost unknown, metered limit not applied", zap.Error(err))
+		return ctx
+	}
+	if !metered {
+		return ctx
+	}
+	ex.log(ctx).Info("metered connection, transfers throttled",
+		zap.Int("metered_kbps", bw.limits.MeteredKBps))
+	ctx = bandwidth.WithLimiter(ctx, bandwidth.Upload, bw.meteredUpload)
+	return bandwidth.WithLimiter(ctx, bandwidth.Download, bw.meteredDown)
+}
+
+type throttleMeteredKey struct{}
+
+// withJobBandwidth adds the bandwidth limits of a job to the context of its run.
+func withJobBandwidth(ctx context.Context, req *SyncRequest) context.Context {
+	ctx = bandwidth.WithLimiter(ctx, bandwidth.Upload, bandwidth.KBps(req.MaxUploadKBps))
+	ctx = bandwidth.WithLimiter(ctx, bandwidth.Download, bandwidth.KBps(req.MaxDownloadKBps))
+	if req.ThrottleMetered {
+		ctx = context.WithValue(ctx, throttleMeteredKey{}, true)
+	}
+	return ctx
+}
+
+// throttleMetered reports whether the run of ctx is throttled on metered connections.
+func throttleMetered(ctx context.Context) bool {
+	throttle, _ := ctx.Value(throttleMeteredKey{}).(bool)
+	return throttle
+}
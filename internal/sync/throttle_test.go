@@ -0,0 +1,57 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/bandwidth"
+)
+
+func TestExecutorBandwidth(t *testing.T) {
+	ex := NewExecutor(4, nil)
+	metered := false
+	ex.isMetered = func() (bool, error) { return metered, nil }
+	ex.SetBandwidthLimits(BandwidthLimits{UploadKBps: 1024, MeteredKBps: 128})
+
+	count := func(ctx context.Context) (up, down int) {
+		return len(bandwidth.Limiters(ctx, bandwidth.Upload)), len(bandwidth.Limiters(ctx, bandwidth.Download))
+	}
+
+	// Global upload limit only
+	ctx := ex.withBandwidth(context.Background())
+	if up, down := count(ctx); up != 1 || down != 0 {
+		t.Errorf("global limits: %d upload, %d download limiters, want 1, 0", up, down)
+	}
+
+	// Job limits are added to the global ones
+	req := &SyncRequest{MaxUploadKBps: 512, MaxDownloadKBps: 2048}
+	ctx = ex.withBandwidth(withJobBandwidth(context.Background(), req))
+	if up, down := count(ctx); up != 2 || down != 1 {
+		t.Errorf("job limits: %d upload, %d download limiters, want 2, 1", up, down)
+	}
+
+	// Metered limit: only on a metered connection, for jobs asking for it
+	metered = true
+	ctx = ex.withBandwidth(withJobBandwidth(context.Background(), req))
+	if up, down := count(ctx); up != 2 || down != 1 {
+		t.Errorf("metered, job not throttled: %d upload, %d download limiters, want 2, 1", up, down)
+	}
+	req.ThrottleMetered = true
+	ctx = ex.withBandwidth(withJobBandwidth(context.Background(), req))
+	if up, down := count(ctx); up != 3 || down != 2 {
+		t.Errorf("metered, job throttled: %d upload, %d download limiters, want 3, 2", up, down)
+	}
+	metered = false
+	ctx = ex.withBandwidth(withJobBandwidth(context.Background(), req))
+	if up, down := count(ctx); up != 2 || down != 1 {
+		t.Errorf("not metered: %d upload, %d download limiters, want 2, 1", up, down)
+	}
+
+	// Global throttling applies to every job
+	ex.SetBandwidthLimits(BandwidthLimits{MeteredKBps: 128, ThrottleMetered: true})
+	metered = true
+	ctx = ex.withBandwidth(context.Background())
+	if up, down := count(ctx); up != 1 || down != 1 {
+		t.Errorf("global metered throttling: %d upload, %d download limiters, want 1, 1", up, down)
+	}
+}
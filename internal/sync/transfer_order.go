@@ -0,0 +1,149 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// TransferOrderStrategy selects the order of the transfers of a run.
+// Whatever the strategy, downloads run before uploads, and deletions last.
+type TransferOrderStrategy string
+
+const (
+	// TransferOrderDefault keeps the order of change detection
+	TransferOrderDefault TransferOrderStrategy = "default"
+	// TransferOrderSmallestFirst transfers small files first (many quick wins)
+	TransferOrderSmallestFirst TransferOrderStrategy = "smallest_first"
+	// TransferOrderNewestFirst transfers the most recently modified files first
+	TransferOrderNewestFirst TransferOrderStrategy = "newest_first"
+	// TransferOrderFolderPriority transfers the files of the listed folders
+	// first, in the order of the list
+	TransferOrderFolderPriority TransferOrderStrategy = "folder_priority"
+)
+
+// TransferOrder is the transfer ordering used by the executor.
+type TransferOrder struct {
+	Strategy TransferOrderStrategy
+	Folders  []string // Folders relative to the job root (folder_priority only)
+}
+
+// NewTransferOrder validates a strategy name ("" = default) and its folder list.
+func NewTransferOrder(strategy string, folders []string) (TransferOrder, error) {
+	order := TransferOrder{Strategy: TransferOrderStrategy(strategy)}
+	switch order.Strategy {
+	case "":
+		order.Strategy = TransferOrderDefault
+	case TransferOrderDefault, TransferOrderSmallestFirst, TransferOrderNewestFirst:
+	case TransferOrderFolderPriority:
+		for _, folder := range folders {
+			folder, err := NormalizeSubtree(folder)
+			if err != nil {
+				return TransferOrder{}, fmt.Errorf("invalid priority folder: %w", err)
+			}
+			if folder != "" {
+				order.Folders = append(order.Folders, folder)
+			}
+		}
+		if len(order.Folders) == 0 {
+			return TransferOrder{}, fmt.Errorf("transfer order %q needs at least one folder", strategy)
+		}
+	default:
+		return TransferOrder{}, fmt.Errorf("unknown transfer order %q (use default, smallest_first, newest_first or folder_priority)", strategy)
+	}
+	return order, nil
+}
+
+// String describes the ordering for the run summary, e.g. "folder_priority (Docs, Photos)".
+func (o TransferOrder) String() string {
+	if o.Strategy == "" {
+		return string(TransferOrderDefault)
+	}
+	if o.Strategy == TransferOrderFolderPriority {
+		return fmt.Sprintf("%s (%s)", o.Strategy, strings.Join(o.Folders, ", "))
+	}
+	return string(o.Strategy)
+}
+
+// sortDecisions orders decisions by action kind (see actionPriority), then by the
+// strategy. root is the local job root the folder priorities are relative to.
+// The sort is stable: ties keep the order of change detection.
+func (o TransferOrder) sortDecisions(decisions []*cache.SyncDecision, root string) {
+	var key func(d *cache.SyncDecision) int64
+	switch o.Strategy {
+	case TransferOrderSmallestFirst:
+		key = func(d *cache.SyncDecision) int64 {
+			if info := transferSource(d); info != nil {
+				return info.Size
+			}
+			return 0
+		}
+	case TransferOrderNewestFirst:
+		key = func(d *cache.SyncDecision) int64 {
+			if info := transferSource(d); info != nil {
+				return -info.MTime.UnixNano()
+			}
+			return 0
+		}
+	case TransferOrderFolderPriority:
+		key = func(d *cache.SyncDecision) int64 {
+			return int64(o.folderRank(d.LocalPath, root))
+		}
+	}
+
+	sort.SliceStable(decisions, func(i, j int) bool {
+		pi, pj := actionPriority(decisions[i].Action), actionPriority(decisions[j].Action)
+		if pi != pj || key == nil {
+			return pi < pj
+		}
+		return key(decisions[i]) < key(decisions[j])
+	})
+}
+
+// folderRank returns the index of the first priority folder holding path,
+// or len(Folders) if none does.
+func (o TransferOrder) folderRank(path, root string) int {
+	rel := filepath.ToSlash(path)
+	if root != "" {
+		if r, err := filepath.Rel(root, path); err == nil {
+			rel = filepath.ToSlash(r)
+		}
+	}
+	for i, folder := range o.Folders {
+		if strings.EqualFold(rel, folder) || len(rel) > len(folder) &&
+			strings.EqualFold(rel[:len(folder)], folder) && rel[len(folder)] == '/' {
+			return i
+		}
+	}
+	return len(o.Folders)
+}
+
+// transferSource returns the state of the file a decision copies from
+// (nil for deletions and metadata-only changes).
+func transferSource(d *cache.SyncDecision) *cache.FileInfo {
+	switch d.Action {
+	case cache.ActionUpload:
+		return d.LocalInfo
+	case cache.ActionDownload:
+		return d.RemoteInfo
+	}
+	return nil
+}
+
+type transferRootKey struct{}
+
+// withTransferRoot records the local job root of a run, which folder
+// priorities are relative to once decision paths are absolute.
+func withTransferRoot(ctx context.Context, root string) context.Context {
+	return context.WithValue(ctx, transferRootKey{}, root)
+}
+
+// transferRoot returns the local job root recorded by withTransferRoot ("" = none).
+func transferRoot(ctx context.Context) string {
+	root, _ := ctx.Value(transferRootKey{}).(string)
+	return root
+}
@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+func TestNewTransferOrder(t *testing.T) {
+	tests := []struct {
+		strategy string
+		folders  []string
+		want     string
+		wantErr  bool
+	}{
+		{"", nil, "default", false},
+		{"smallest_first", nil, "smallest_first", false},
+		{"newest_first", []string{"Ignored"}, "newest_first", false},
+		{"folder_priority", []string{`Docs\`, "/Photos/2025", ""}, "folder_priority (Docs, Photos/2025)", false},
+		{"folder_priority", nil, "", true},
+		{"folder_priority", []string{"../Other"}, "", true},
+		{"largest_first", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		order, err := NewTransferOrder(tt.strategy, tt.folders)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewTransferOrder(%q, %v): expected error", tt.strategy, tt.folders)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewTransferOrder(%q, %v): unexpected error %v", tt.strategy, tt.folders, err)
+			continue
+		}
+		if got := order.String(); got != tt.want {
+			t.Errorf("NewTransferOrder(%q, %v) = %q, want %q", tt.strategy, tt.folders, got, tt.want)
+		}
+	}
+}
+
+func TestPrioritizeActions_TransferOrder(t *testing.T) {
+	root := filepath.Join("sync", "job")
+	now := time.Now()
+	upload := func(name string, size int64, age time.Duration) *cache.SyncDecision {
+		return &cache.SyncDecision{
+			LocalPath: filepath.Join(root, name),
+			Action:    cache.ActionUpload,
+			LocalInfo: &cache.FileInfo{Size: size, MTime: now.Add(-age)},
+		}
+	}
+	decisions := []*cache.SyncDecision{
+		{LocalPath: filepath.Join(root, "old.txt"), Action: cache.ActionDeleteRemote},
+		upload(filepath.Join("Photos", "big.jpg"), 5000, time.Hour),
+		upload("notes.txt", 10, 48*time.Hour),
+		upload(filepath.Join("Docs", "report.pdf"), 300, time.Minute),
+		{LocalPath: filepath.Join(root, "remote.txt"), Action: cache.ActionDownload,
+			RemoteInfo: &cache.FileInfo{Size: 99999, MTime: now.Add(-72 * time.Hour)}},
+	}
+
+	tests := []struct {
+		strategy string
+		folders  []string
+		want     []string
+	}{
+		{"default", nil, []string{"remote.txt", "big.jpg", "notes.txt", "report.pdf", "old.txt"}},
+		{"smallest_first", nil, []string{"remote.txt", "notes.txt", "report.pdf", "big.jpg", "old.txt"}},
+		{"newest_first", nil, []string{"remote.txt", "report.pdf", "big.jpg", "notes.txt", "old.txt"}},
+		{"folder_priority", []string{"docs", "Photos"}, []string{"remote.txt", "report.pdf", "big.jpg", "notes.txt", "old.txt"}},
+	}
+
+	for _, tt := range tests {
+		order, err := NewTransferOrder(tt.strategy, tt.folders)
+		if err != nil {
+			t.Fatalf("NewTransferOrder(%q): %v", tt.strategy, err)
+		}
+		ex := NewExecutor(4, nil)
+		ex.SetTransferOrder(order)
+
+		got := ex.prioritizeActions(decisions, root)
+		for i, d := range got {
+			if name := filepath.Base(d.LocalPath); name != tt.want[i] {
+				t.Errorf("%s: position %d = %s, want %s", tt.strategy, i, name, tt.want[i])
+			}
+		}
+	}
+
+	// The input keeps its order
+	if filepath.Base(decisions[0].LocalPath) != "old.txt" {
+		t.Error("prioritizeActions modified its input")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
 )
 
 // SyncMode defines the direction of synchronization
@@ -52,6 +53,72 @@ type SyncRequest struct {
 	// PlaceholderCallback is called when placeholders need to be created.
 	// Only used when FilesOnDemand is true.
 	PlaceholderCallback PlaceholderCallback
+
+	// RemoteMTimeSource selects which remote timestamp drives change detection
+	// (per server setting, default: LastWriteTime)
+	RemoteMTimeSource smb.MTimeSource
+
+	// SyncAttributes propagates read-only and archive bits of in-sync files
+	// as metadata-only operations instead of ignoring them
+	SyncAttributes bool
+
+	// ExclusionGroups overrides the default state of curated exclusion groups
+	// (group name -> enabled), e.g. {"vcs": false} to sync .git folders
+	ExclusionGroups map[string]bool
+
+	// Subtree restricts the sync to a folder relative to LocalPath/RemotePath
+	// (e.g. "ProjectA/Docs", "" = whole job). Files outside it are neither
+	// scanned nor changed.
+	Subtree string
+
+	// Pattern restricts the sync to the files whose job-relative path matches
+	// the glob ("**" spans folders, e.g. "Projects/Active/**", "" = all files).
+	// Files outside it are neither changed nor seen as deleted.
+	Pattern string
+
+	// DownloadOnly only executes downloads: nothing is uploaded, deleted or
+	// changed on the server, e.g. to warm a new machine with the server content
+	DownloadOnly bool
+
+	// MaxChangedFiles and MaxChangedBytes cap the files changed and bytes
+	// transferred by one run (0 = no limit). A run exceeding a cap executes
+	// nothing and fails with a *ChangeCapError, e.g. when ransomware-encrypted
+	// files would be mirrored to the server.
+	MaxChangedFiles int
+	MaxChangedBytes int64
+
+	// ApproveChanges lets the run exceed the caps (user approval)
+	ApproveChanges bool
+
+	// MaxUploadKBps and MaxDownloadKBps cap the transfer rate of the job, on
+	// top of the limits of the executor (0 = no job limit)
+	MaxUploadKBps   int
+	MaxDownloadKBps int
+
+	// ThrottleMetered applies the metered connection limit of the executor
+	// while Windows reports the connection as metered
+	ThrottleMetered bool
+
+	// VerifyTransfers reads each uploaded or downloaded file back and
+	// compares it with the hash computed while transferring, retrying the
+	// transfer on mismatch (costs a second read of every transferred file)
+	VerifyTransfers bool
+
+	// SyncACLs copies the owner and NTFS permissions of each transferred file
+	// to its copy (Windows only, see acls.go)
+	SyncACLs bool
+
+	// SyncStreams copies the NTFS alternate data streams of each transferred
+	// file to its copy (Windows only, see streams.go)
+	SyncStreams bool
+
+	// ReadLockedFiles uploads the local files other programs keep locked from
+	// a Volume Shadow Copy snapshot (Windows only, see shadow_copy.go)
+	ReadLockedFiles bool
+
+	// JobLog also writes the log lines of the run to the job's own log file
+	// (see correlation.StartJobLog)
+	JobLog bool
 }
 
 // PlaceholderCallback is called to create placeholders for remote files.
@@ -73,6 +140,21 @@ type SyncResult struct {
 	// Status indicates the overall sync outcome
 	Status SyncStatus
 
+	// RunID identifies the run in logs and in the sync_actions table
+	RunID string
+
+	// Subtree is the folder the sync was restricted to ("" = whole job).
+	// File counts only cover this folder.
+	Subtree string
+
+	// Resumed is true when the run executed the remaining actions of an
+	// interrupted sync instead of scanning
+	Resumed bool
+
+	// TransferOrder is the order the transfers were executed in
+	// (e.g. "smallest_first", "folder_priority (Docs, Photos)")
+	TransferOrder string
+
 	// Timestamps
 	StartTime time.Time
 	EndTime   time.Time
@@ -83,10 +165,15 @@ type SyncResult struct {
 	FilesUploaded      int // Files uploaded to remote
 	FilesDownloaded    int // Files downloaded from remote
 	FilesDeleted       int // Files deleted (local or remote)
+	FilesRenamed       int // Files moved instead of transferred again (local or remote)
 	FilesSkipped       int // Files skipped (unchanged)
 	FilesError         int // Files with errors
 	ConflictsFound     int // Conflicts detected
 	PlaceholdersCreated int // Placeholders created (Files On Demand mode)
+	AttributesUpdated  int // Files whose read-only/archive bits were synced
+	FilesVetoed        int // Uploads refused by the upload hook
+	FilesVerified      int // Transfers read back and matching their source
+	VerifyMismatches   int // Transfer attempts read back different from their source
 
 	// Data transfer
 	BytesTransferred int64 // Total bytes transferred
@@ -120,18 +207,68 @@ type SyncAction struct {
 	// Action is the sync action taken
 	Action cache.SyncAction
 
+	// Reason is why change detection or conflict resolution chose the action
+	Reason string
+
+	// Replaces is true when a transfer overwrites an existing file on its
+	// destination
+	Replaces bool
+
 	// Status is the result of this action
 	Status ActionStatus
 
+	// OpID identifies the operation in logs and in the sync_actions table
+	OpID string
+
 	// Size is the file size in bytes
 	Size int64
 
 	// BytesTransferred is the actual bytes transferred
 	BytesTransferred int64
 
+	// Hash is the SHA-256 computed while transferring (uploads and downloads only)
+	Hash string
+
+	// VerifiedAt is when the destination was read back and matched Hash
+	// (zero = not verified)
+	VerifiedAt time.Time
+
+	// VerifyMismatches counts the attempts whose destination was read back
+	// different from Hash (each one was retried)
+	VerifyMismatches int
+
+	// Attributes are the tracked attribute bits after the action (0 = not tracked)
+	Attributes uint32
+
+	// MTime is the modification time of the local file after a transfer
+	// (zero = not known, the time of the sync is recorded)
+	MTime time.Time
+
+	// RenamedFrom is the local path a renamed file was moved from
+	// (rename_local and rename_remote only)
+	RenamedFrom string
+
+	// ConflictCopy is where the losing version of a conflict was kept before
+	// being overwritten: a local path for downloads, a remote one for uploads
+	// ("" = none)
+	ConflictCopy string
+
+	// ChangedBy is the owner of the remote version after the action, as
+	// DOMAIN\user: the author of a download, this client's account for an
+	// upload ("" = owners not recorded)
+	ChangedBy string
+
+	// ConflictOwner is the owner of the remote version of a conflict ("" =
+	// none or not recorded)
+	ConflictOwner string
+
 	// Error if action failed
 	Error error
 
+	// Attempts is how many times the action ran, retries included
+	// (0 = not executed)
+	Attempts int
+
 	// Duration of the action
 	Duration time.Duration
 
@@ -153,6 +290,8 @@ const (
 	ActionStatusFailed ActionStatus = "failed"
 	// ActionStatusSkipped indicates action was skipped
 	ActionStatusSkipped ActionStatus = "skipped"
+	// ActionStatusVetoed indicates an upload refused by the upload hook (e.g. antivirus)
+	ActionStatusVetoed ActionStatus = "vetoed"
 )
 
 // SyncError represents an error during sync
@@ -205,6 +344,13 @@ type SyncProgress struct {
 
 	// Message for display (optional)
 	Message string
+
+	// QueuedActions waiting for a free worker (parallel execution only)
+	QueuedActions int
+
+	// InFlightBytes reserved by running actions, and the configured ceiling (0 = unlimited)
+	InFlightBytes int64
+	InFlightLimit int64
 }
 
 // ProgressCallback is called to report progress updates
@@ -227,7 +373,8 @@ func (s ActionStatus) String() string {
 
 // IsTerminal returns true if the action status is terminal (success/failed/skipped)
 func (s ActionStatus) IsTerminal() bool {
-	return s == ActionStatusSuccess || s == ActionStatusFailed || s == ActionStatusSkipped
+	return s == ActionStatusSuccess || s == ActionStatusFailed || s == ActionStatusSkipped ||
+		s == ActionStatusVetoed
 }
 
 // Validate validates the sync request
@@ -247,9 +394,18 @@ func (r *SyncRequest) Validate() error {
 	if !IsValidConflictResolution(r.ConflictResolution) {
 		return ErrInvalidConflictResolution
 	}
+	if _, err := NormalizeSubtree(r.Subtree); err != nil {
+		return err
+	}
 	return nil
 }
 
+// partial reports whether the run only executes part of the changes of its
+// folder, so its plan can't stand for a regular run.
+func (r *SyncRequest) partial() bool {
+	return r.Pattern != "" || r.DownloadOnly
+}
+
 // IsValid returns true if the sync mode is valid
 func (m SyncMode) IsValid() bool {
 	switch m {
@@ -309,7 +465,7 @@ func (r *SyncResult) Finalize() {
 		} else {
 			r.Status = SyncStatusPartial
 		}
-	} else if r.ConflictsFound > 0 {
+	} else if r.ConflictsFound > 0 || r.FilesVetoed > 0 {
 		r.Status = SyncStatusPartial
 	} else {
 		r.Status = SyncStatusSuccess
@@ -331,6 +487,7 @@ func (r *SyncResult) AddConflict(conflict *cache.SyncDecision) {
 // AddAction adds an action to the sync result
 func (r *SyncResult) AddAction(action *SyncAction) {
 	r.Actions = append(r.Actions, action)
+	r.VerifyMismatches += action.VerifyMismatches
 
 	// Update counters based on action
 	if action.Status == ActionStatusSuccess {
@@ -338,13 +495,28 @@ func (r *SyncResult) AddAction(action *SyncAction) {
 		case cache.ActionUpload:
 			r.FilesUploaded++
 			r.BytesTransferred += action.BytesTransferred
+			r.countVerified(action)
 		case cache.ActionDownload:
 			r.FilesDownloaded++
 			r.BytesTransferred += action.BytesTransferred
+			r.countVerified(action)
 		case cache.ActionDeleteLocal, cache.ActionDeleteRemote:
 			r.FilesDeleted++
+		case cache.ActionRenameLocal, cache.ActionRenameRemote:
+			r.FilesRenamed++
+		case cache.ActionSetAttrLocal, cache.ActionSetAttrRemote:
+			r.AttributesUpdated++
 		}
 	} else if action.Status == ActionStatusSkipped {
 		r.FilesSkipped++
+	} else if action.Status == ActionStatusVetoed {
+		r.FilesVetoed++
+	}
+}
+
+// countVerified counts a transfer read back after completing.
+func (r *SyncResult) countVerified(action *SyncAction) {
+	if !action.VerifiedAt.IsZero() {
+		r.FilesVerified++
 	}
 }
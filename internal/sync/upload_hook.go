@@ -0,0 +1,270 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// Upload scan types (config security.upload_scan.type)
+const (
+	UploadScanCommand = "command"
+	UploadScanAMSI    = "amsi"
+)
+
+// DefaultUploadScanTimeout bounds a single file scan
+const DefaultUploadScanTimeout = 60 * time.Second
+
+// UploadVerdict is the result of an upload hook check.
+type UploadVerdict struct {
+	Allowed bool   // False if the file must not be uploaded
+	Reason  string // Why the file was vetoed (e.g. threat name)
+}
+
+// UploadHook inspects a local file before it is uploaded, typically with an
+// antivirus. An error means the file could not be checked: it is not uploaded
+// and is checked again on the next sync.
+type UploadHook interface {
+	Name() string
+	Check(ctx context.Context, path string) (UploadVerdict, error)
+}
+
+// NewUploadHook creates the upload hook described by cfg.
+// Returns nil if the scan is disabled.
+func NewUploadHook(cfg config.UploadScanConfig, logger *zap.Logger) (UploadHook, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultUploadScanTimeout
+	}
+
+	switch cfg.Type {
+	case UploadScanCommand, "":
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("upload scan command is not set")
+		}
+		return &CommandUploadHook{
+			Command:       cfg.Command,
+			Args:          cfg.Args,
+			VetoExitCodes: cfg.VetoExitCodes,
+			Timeout:       timeout,
+		}, nil
+	case UploadScanAMSI:
+		return newAMSIUploadHook(int64(cfg.MaxSizeMB)*1024*1024, logger)
+	default:
+		return nil, fmt.Errorf("unknown upload scan type: %s", cfg.Type)
+	}
+}
+
+// CommandUploadHook runs an external scanner for each file.
+// Exit code 0 allows the upload. VetoExitCodes lists the codes meaning the
+// file is infected; any other non-zero code is a scan failure. If
+// VetoExitCodes is empty, every non-zero code vetoes the file.
+type CommandUploadHook struct {
+	Command       string
+	Args          []string // "{path}" is replaced by the file path, which is appended if absent
+	VetoExitCodes []int
+	Timeout       time.Duration
+}
+
+// Name returns the scanner executable name.
+func (h *CommandUploadHook) Name() string {
+	return filepath.Base(h.Command)
+}
+
+// Check runs the scanner on path.
+func (h *CommandUploadHook) Check(ctx context.Context, path string) (UploadVerdict, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, h.Command, h.commandArgs(path)...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err == nil {
+		return UploadVerdict{Allowed: true}, nil
+	}
+	if ctx.Err() != nil {
+		return UploadVerdict{}, fmt.Errorf("%s: scan of %s timed out or was cancelled: %w", h.Name(), path, ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return UploadVerdict{}, fmt.Errorf("%s: failed to run scanner: %w", h.Name(), err)
+	}
+
+	code := exitErr.ExitCode()
+	if !h.isVetoCode(code) {
+		return UploadVerdict{}, fmt.Errorf("%s: scan of %s failed with exit code %d", h.Name(), path, code)
+	}
+
+	reason := firstLine(output.String())
+	if reason == "" {
+		reason = fmt.Sprintf("exit code %d", code)
+	}
+	return UploadVerdict{Allowed: false, Reason: reason}, nil
+}
+
+// commandArgs substitutes the file path in the configured arguments.
+func (h *CommandUploadHook) commandArgs(path string) []string {
+	args := make([]string, 0, len(h.Args)+1)
+	substituted := false
+	for _, arg := range h.Args {
+		if strings.Contains(arg, "{path}") {
+			arg = strings.ReplaceAll(arg, "{path}", path)
+			substituted = true
+		}
+		args = append(args, arg)
+	}
+	if !substituted {
+		args = append(args, path)
+	}
+	return args
+}
+
+func (h *CommandUploadHook) isVetoCode(code int) bool {
+	if len(h.VetoExitCodes) == 0 {
+		return code != 0
+	}
+	for _, veto := range h.VetoExitCodes {
+		if code == veto {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns the first non-empty line of s.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// SetUploadHook sets the hook checking files before upload (nil disables it).
+func (e *Engine) SetUploadHook(hook UploadHook) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.uploadHook = hook
+}
+
+// screenUploads checks upload decisions with the upload hook and removes the
+// files it vetoes. A vetoed file is skipped without scanning again while its
+// hash is unchanged. Returns the decisions to execute and one action per
+// removed upload (ActionStatusVetoed, or ActionStatusFailed if the check failed).
+func (e *Engine) screenUploads(ctx context.Context, req *SyncRequest,
+	decisions []*cache.SyncDecision) ([]*cache.SyncDecision, []*SyncAction, error) {
+
+	e.mu.RLock()
+	hook := e.uploadHook
+	e.mu.RUnlock()
+	if hook == nil {
+		return decisions, nil, nil
+	}
+
+	vetoes, err := e.db.GetUploadVetoes(req.JobID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load upload vetoes: %w", err)
+	}
+
+	allowed := make([]*cache.SyncDecision, 0, len(decisions))
+	var screened []*SyncAction
+
+	for _, decision := range decisions {
+		if decision.Action != cache.ActionUpload {
+			allowed = append(allowed, decision)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		var hash string
+		var size int64
+		if decision.LocalInfo != nil {
+			hash = decision.LocalInfo.Hash
+			size = decision.LocalInfo.Size
+		}
+		localPath := filepath.Join(req.LocalPath, filepath.FromSlash(decision.LocalPath))
+
+		action := &SyncAction{
+			FilePath:   localPath,
+			RemotePath: decision.RemotePath,
+			Action:     cache.ActionUpload,
+			Size:       size,
+			Timestamp:  timeNow(),
+		}
+
+		veto, wasVetoed := vetoes[decision.LocalPath]
+		if wasVetoed && hash != "" && veto.Hash == hash {
+			action.Status = ActionStatusVetoed
+			action.Error = fmt.Errorf("%w: %s", ErrUploadVetoed, veto.Reason)
+			screened = append(screened, action)
+			continue
+		}
+
+		verdict, err := hook.Check(ctx, localPath)
+		if err != nil {
+			e.log(ctx).Warn("upload scan failed, file not uploaded",
+				zap.String("path", decision.LocalPath),
+				zap.String("scanner", hook.Name()),
+				zap.Error(err))
+			action.Status = ActionStatusFailed
+			action.Error = err
+			screened = append(screened, action)
+			continue
+		}
+
+		if !verdict.Allowed {
+			e.log(ctx).Warn("upload vetoed by scanner",
+				zap.String("path", decision.LocalPath),
+				zap.String("scanner", hook.Name()),
+				zap.String("reason", verdict.Reason))
+			if hash != "" {
+				if err := e.db.SaveUploadVeto(&database.UploadVeto{
+					JobID:  req.JobID,
+					Path:   decision.LocalPath,
+					Hash:   hash,
+					Reason: verdict.Reason,
+				}); err != nil {
+					e.log(ctx).Warn("failed to record upload veto", zap.Error(err))
+				}
+			}
+			action.Status = ActionStatusVetoed
+			action.Error = fmt.Errorf("%w: %s", ErrUploadVetoed, verdict.Reason)
+			screened = append(screened, action)
+			continue
+		}
+
+		// Content changed and is now clean
+		if wasVetoed {
+			if err := e.db.DeleteUploadVeto(req.JobID, decision.LocalPath); err != nil {
+				e.log(ctx).Warn("failed to clear upload veto", zap.Error(err))
+			}
+		}
+		allowed = append(allowed, decision)
+	}
+
+	return allowed, screened, nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package sync
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// newAMSIUploadHook fails: AMSI only exists on Windows.
+func newAMSIUploadHook(maxSize int64, logger *zap.Logger) (UploadHook, error) {
+	return nil, fmt.Errorf("AMSI upload scan is only available on Windows")
+}
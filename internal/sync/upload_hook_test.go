@@ -0,0 +1,185 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/config"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// fakeUploadHook vetoes files whose name contains "evil"
+type fakeUploadHook struct {
+	checked []string
+}
+
+func (h *fakeUploadHook) Name() string { return "fake" }
+
+func (h *fakeUploadHook) Check(ctx context.Context, path string) (UploadVerdict, error) {
+	h.checked = append(h.checked, filepath.Base(path))
+	if strings.Contains(path, "evil") {
+		return UploadVerdict{Allowed: false, Reason: "Test.Virus"}, nil
+	}
+	return UploadVerdict{Allowed: true}, nil
+}
+
+func TestScreenUploads(t *testing.T) {
+	tempDir := t.TempDir()
+	db, err := database.Open(database.Config{
+		Path:             filepath.Join(tempDir, "test.db"),
+		EncryptionKey:    "test-key-32-chars-long-123456",
+		CreateIfNotExist: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	job := &database.SyncJob{
+		Name:               "job",
+		LocalPath:          tempDir,
+		RemotePath:         `\\nas\share`,
+		ServerCredentialID: "nas_user",
+		SyncMode:           "mirror",
+		TriggerMode:        "manual",
+		ConflictResolution: "recent",
+		Enabled:            true,
+	}
+	if err := db.CreateSyncJob(job); err != nil {
+		t.Fatalf("CreateSyncJob failed: %v", err)
+	}
+
+	engine, err := NewEngine(&config.Config{}, db, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	defer engine.Close()
+
+	hook := &fakeUploadHook{}
+	engine.SetUploadHook(hook)
+
+	req := &SyncRequest{JobID: job.ID, LocalPath: tempDir}
+	decisions := func(evilHash string) []*cache.SyncDecision {
+		return []*cache.SyncDecision{
+			{LocalPath: "clean.txt", Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Hash: "c1"}},
+			{LocalPath: "dir/evil.exe", Action: cache.ActionUpload, LocalInfo: &cache.FileInfo{Hash: evilHash}},
+			{LocalPath: "gone.txt", Action: cache.ActionDeleteRemote},
+		}
+	}
+
+	allowed, screened, err := engine.screenUploads(context.Background(), req, decisions("e1"))
+	if err != nil {
+		t.Fatalf("screenUploads failed: %v", err)
+	}
+	if len(allowed) != 2 || len(screened) != 1 {
+		t.Fatalf("expected 2 allowed and 1 vetoed, got %d and %d", len(allowed), len(screened))
+	}
+	if screened[0].Status != ActionStatusVetoed || !errors.Is(screened[0].Error, ErrUploadVetoed) {
+		t.Errorf("expected vetoed action, got %s (%v)", screened[0].Status, screened[0].Error)
+	}
+
+	// Same content: excluded without scanning again
+	hook.checked = nil
+	_, screened, _ = engine.screenUploads(context.Background(), req, decisions("e1"))
+	if len(screened) != 1 || len(hook.checked) != 1 || hook.checked[0] != "clean.txt" {
+		t.Errorf("expected vetoed file skipped without scan, checked %v", hook.checked)
+	}
+
+	// Changed content is scanned again
+	hook.checked = nil
+	_, _, _ = engine.screenUploads(context.Background(), req, decisions("e2"))
+	if len(hook.checked) != 2 {
+		t.Errorf("expected changed file to be scanned again, checked %v", hook.checked)
+	}
+
+	// Without a hook nothing is filtered
+	engine.SetUploadHook(nil)
+	allowed, screened, _ = engine.screenUploads(context.Background(), req, decisions("e2"))
+	if len(allowed) != 3 || len(screened) != 0 {
+		t.Errorf("expected all decisions without hook, got %d allowed", len(allowed))
+	}
+}
+
+func TestSyncResult_VetoedIsPartial(t *testing.T) {
+	result := NewSyncResult(1)
+	result.TotalFiles = 2
+	result.AddAction(&SyncAction{Action: cache.ActionUpload, Status: ActionStatusSuccess})
+	result.AddAction(&SyncAction{Action: cache.ActionUpload, Status: ActionStatusVetoed})
+	result.Finalize()
+
+	if result.FilesVetoed != 1 || result.FilesUploaded != 1 {
+		t.Errorf("expected 1 uploaded and 1 vetoed, got %d and %d", result.FilesUploaded, result.FilesVetoed)
+	}
+	if result.Status != SyncStatusPartial {
+		t.Errorf("expected partial status, got %s", result.Status)
+	}
+}
+
+// TestUploadScanHelperProcess is run as the external scanner by TestCommandUploadHook
+func TestUploadScanHelperProcess(t *testing.T) {
+	if os.Getenv("ANEMONE_UPLOAD_SCAN_HELPER") != "1" {
+		return
+	}
+	path := os.Args[len(os.Args)-1]
+	switch {
+	case strings.Contains(path, "evil"):
+		fmt.Println("Threat found: Test.Virus")
+		os.Exit(2)
+	case strings.Contains(path, "broken"):
+		os.Exit(5)
+	}
+	os.Exit(0)
+}
+
+func TestCommandUploadHook(t *testing.T) {
+	t.Setenv("ANEMONE_UPLOAD_SCAN_HELPER", "1")
+
+	hook := &CommandUploadHook{
+		Command:       os.Args[0],
+		Args:          []string{"-test.run=TestUploadScanHelperProcess", "--", "{path}"},
+		VetoExitCodes: []int{2},
+		Timeout:       DefaultUploadScanTimeout,
+	}
+
+	verdict, err := hook.Check(context.Background(), "clean.txt")
+	if err != nil || !verdict.Allowed {
+		t.Errorf("expected clean file allowed, got %+v (err=%v)", verdict, err)
+	}
+
+	verdict, err = hook.Check(context.Background(), "evil.exe")
+	if err != nil || verdict.Allowed || verdict.Reason != "Threat found: Test.Virus" {
+		t.Errorf("expected infected file vetoed with scanner output, got %+v (err=%v)", verdict, err)
+	}
+
+	// Exit codes outside VetoExitCodes are scan failures, not vetoes
+	if _, err := hook.Check(context.Background(), "broken.bin"); err == nil {
+		t.Error("expected error for unexpected exit code")
+	}
+}
+
+func TestNewUploadHook(t *testing.T) {
+	hook, err := NewUploadHook(config.UploadScanConfig{}, zap.NewNop())
+	if err != nil || hook != nil {
+		t.Errorf("expected no hook when disabled, got %v (err=%v)", hook, err)
+	}
+
+	if _, err := NewUploadHook(config.UploadScanConfig{Enabled: true, Type: UploadScanCommand}, zap.NewNop()); err == nil {
+		t.Error("expected error for command hook without command")
+	}
+
+	hook, err = NewUploadHook(config.UploadScanConfig{Enabled: true, Command: "scan.exe", Args: []string{"/file"}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewUploadHook failed: %v", err)
+	}
+	args := hook.(*CommandUploadHook).commandArgs(`C:\data\a.txt`)
+	if len(args) != 2 || args[1] != `C:\data\a.txt` {
+		t.Errorf("expected path appended to args, got %v", args)
+	}
+}
@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+	"go.uber.org/zap"
+)
+
+// --- Remote Usage ---
+//
+// What takes space on the server: the size of each top-level folder of a job
+// and its largest files. Every whole-job sync saves the usage of the files
+// its remote scan found; RemoteUsage scans again on demand, reusing the
+// Anemone manifest or the remote listing cache like a sync.
+
+// UsageLargestFiles is how many of the largest files a usage report keeps.
+const UsageLargestFiles = 20
+
+// AnalyzeRemoteUsage sums the remote files of a job (keyed by job-relative
+// path) by top-level folder and keeps the largest ones. Files at the root of
+// the job are summed under the folder "".
+func AnalyzeRemoteUsage(jobID int64, files map[string]*cache.FileInfo, scannedAt time.Time) *database.RemoteUsage {
+	usage := &database.RemoteUsage{JobID: jobID, ScannedAt: scannedAt}
+	folders := make(map[string]*database.UsageEntry)
+	largest := make([]database.UsageEntry, 0, len(files))
+
+	for relPath, f := range files {
+		usage.TotalFiles++
+		usage.TotalBytes += f.Size
+
+		folder, _, nested := strings.Cut(relPath, "/")
+		if !nested {
+			folder = ""
+		}
+		entry := folders[folder]
+		if entry == nil {
+			entry = &database.UsageEntry{Path: folder}
+			folders[folder] = entry
+		}
+		entry.Files++
+		entry.Bytes += f.Size
+
+		largest = append(largest, database.UsageEntry{Path: relPath, Files: 1, Bytes: f.Size})
+	}
+
+	bySize := func(a, b database.UsageEntry) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	}
+	for _, entry := range folders {
+		usage.Folders = append(usage.Folders, *entry)
+	}
+	slices.SortFunc(usage.Folders, bySize)
+	slices.SortFunc(largest, bySize)
+	usage.LargestFiles = largest[:min(len(largest), UsageLargestFiles)]
+	return usage
+}
+
+// recordRemoteUsage saves the usage of the remote files found by a whole-job
+// scan.
+func (e *Engine) recordRemoteUsage(req *SyncRequest, remoteFiles map[string]*cache.FileInfo) error {
+	if req.Subtree != "" || remoteFiles == nil {
+		return nil // Folder of the job only, or resumed run without a scan
+	}
+	return e.db.SaveRemoteUsage(AnalyzeRemoteUsage(req.JobID, remoteFiles, e.clock.Now()))
+}
+
+// RemoteUsage scans the remote folder of a job, saves its usage and returns
+// it. Nothing is synced and the job keeps its status.
+func (e *Engine) RemoteUsage(ctx context.Context, jobID int64) (*database.RemoteUsage, error) {
+	files, err := e.ScanRemoteFiles(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := AnalyzeRemoteUsage(jobID, files, e.clock.Now())
+	if err := e.db.SaveRemoteUsage(usage); err != nil {
+		e.log(ctx).Warn("failed to save remote usage", zap.Error(err))
+	}
+	return usage, nil
+}
@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"github.com/juste-un-gars/anemone_sync_windows/internal/database"
+)
+
+func TestAnalyzeRemoteUsage(t *testing.T) {
+	files := map[string]*cache.FileInfo{
+		"notes.txt":               {Size: 10},
+		"Photos/2024/beach.jpg":   {Size: 300},
+		"Photos/2024/city.jpg":    {Size: 200},
+		"Videos/trip.mp4":         {Size: 900},
+		"Documents/a.docx":        {Size: 50},
+		"Documents/Archive/b.pdf": {Size: 50},
+	}
+	for i := range UsageLargestFiles {
+		files[fmt.Sprintf("Logs/%02d.log", i)] = &cache.FileInfo{Size: 1}
+	}
+
+	scannedAt := time.Now()
+	usage := AnalyzeRemoteUsage(4, files, scannedAt)
+	if usage.JobID != 4 || !usage.ScannedAt.Equal(scannedAt) {
+		t.Errorf("usage = %+v", usage)
+	}
+	if usage.TotalFiles != 6+UsageLargestFiles || usage.TotalBytes != 1510+UsageLargestFiles {
+		t.Errorf("totals = %d files, %d bytes", usage.TotalFiles, usage.TotalBytes)
+	}
+
+	wantFolders := []database.UsageEntry{
+		{Path: "Videos", Files: 1, Bytes: 900},
+		{Path: "Photos", Files: 2, Bytes: 500},
+		{Path: "Documents", Files: 2, Bytes: 100},
+		{Path: "Logs", Files: UsageLargestFiles, Bytes: UsageLargestFiles},
+		{Path: "", Files: 1, Bytes: 10},
+	}
+	if fmt.Sprint(usage.Folders) != fmt.Sprint(wantFolders) {
+		t.Errorf("folders = %v, want %v", usage.Folders, wantFolders)
+	}
+
+	if len(usage.LargestFiles) != UsageLargestFiles {
+		t.Fatalf("%d largest files, want %d", len(usage.LargestFiles), UsageLargestFiles)
+	}
+	first, last := usage.LargestFiles[0], usage.LargestFiles[UsageLargestFiles-1]
+	if first.Path != "Videos/trip.mp4" || first.Bytes != 900 {
+		t.Errorf("largest file = %+v", first)
+	}
+	// Equal sizes are ordered by path: the last logs are left out
+	if last.Path != "Logs/13.log" {
+		t.Errorf("last kept file = %+v, want Logs/13.log", last)
+	}
+}
@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+	"go.uber.org/zap"
+)
+
+// --- Transfer Verification ---
+//
+// The hash computed while transferring proves what was sent, not what was
+// stored: a failing disk, NAS or network filter can still write something
+// else. Jobs verifying their transfers read the destination back after each
+// upload or download and compare it with that hash. A mismatch fails the
+// attempt, which the retry policy runs again. Mismatches are counted in the
+// sync result even when a retry succeeds: their rate tells about the health
+// of the hardware between the PC and the disks of the server.
+
+type verifyTransfersKey struct{}
+
+// withVerifyTransfers records whether the run of ctx verifies its transfers.
+func withVerifyTransfers(ctx context.Context, req *SyncRequest) context.Context {
+	if !req.VerifyTransfers {
+		return ctx
+	}
+	return context.WithValue(ctx, verifyTransfersKey{}, true)
+}
+
+// verifyTransfers reports whether the run of ctx verifies its transfers.
+func verifyTransfers(ctx context.Context) bool {
+	verify, _ := ctx.Value(verifyTransfersKey{}).(bool)
+	return verify
+}
+
+// remoteHasher reads remote files back (*smb.SMBClient, replaced by tests).
+type remoteHasher interface {
+	HashFileContext(ctx context.Context, remotePath string) (string, error)
+}
+
+// verifyTransfer reads the destination of a completed upload or download
+// back and compares it with the hash of the transfer, marking the action
+// verified. It does nothing unless the run verifies its transfers.
+func (ex *Executor) verifyTransfer(ctx context.Context, action *SyncAction, remote remoteHasher) error {
+	if !verifyTransfers(ctx) || action.Hash == "" {
+		return nil
+	}
+
+	var stored string
+	var err error
+	switch action.Action {
+	case cache.ActionUpload:
+		stored, err = remote.HashFileContext(ctx, action.RemotePath)
+	case cache.ActionDownload:
+		stored, err = hashLocalFile(action.FilePath)
+	default:
+		return nil
+	}
+	if err != nil {
+		return WrapSyncError(err, action.FilePath, "verify")
+	}
+
+	if stored != action.Hash {
+		action.VerifyMismatches++
+		ex.log(ctx).Warn("transferred file differs from its source",
+			zap.String("path", action.FilePath),
+			zap.String("action", string(action.Action)),
+			zap.String("expected", action.Hash),
+			zap.String("stored", stored),
+			zap.Int("mismatches", action.VerifyMismatches),
+		)
+		return WrapSyncError(fmt.Errorf("%w (expected %.12s, read back %.12s)", ErrVerifyMismatch, action.Hash, stored),
+			action.FilePath, "verify")
+	}
+
+	action.VerifiedAt = ex.clock.Now()
+	return nil
+}
+
+// hashLocalFile returns the hex-encoded SHA-256 of a local file.
+func hashLocalFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
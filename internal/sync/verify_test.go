@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
+)
+
+// fakeHasher returns the hash of the remote files it holds.
+type fakeHasher map[string]string
+
+func (h fakeHasher) HashFileContext(ctx context.Context, remotePath string) (string, error) {
+	hash, ok := h[remotePath]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return hash, nil
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestVerifyTransfer(t *testing.T) {
+	ex := NewExecutor(1, nil)
+	verifying := withVerifyTransfers(context.Background(), &SyncRequest{VerifyTransfers: true})
+
+	local := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(local, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	remote := fakeHasher{"a.txt": sha256Hex("content"), "bad.txt": sha256Hex("corrupted")}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		action   *SyncAction
+		mismatch bool
+		verified bool
+	}{
+		{"upload matches", verifying, &SyncAction{Action: cache.ActionUpload, RemotePath: "a.txt", Hash: sha256Hex("content")}, false, true},
+		{"upload corrupted", verifying, &SyncAction{Action: cache.ActionUpload, RemotePath: "bad.txt", Hash: sha256Hex("content")}, true, false},
+		{"download matches", verifying, &SyncAction{Action: cache.ActionDownload, FilePath: local, Hash: sha256Hex("content")}, false, true},
+		{"download corrupted", verifying, &SyncAction{Action: cache.ActionDownload, FilePath: local, Hash: sha256Hex("other")}, true, false},
+		{"delete not verified", verifying, &SyncAction{Action: cache.ActionDeleteRemote, RemotePath: "gone.txt"}, false, false},
+		{"disabled", context.Background(), &SyncAction{Action: cache.ActionUpload, RemotePath: "bad.txt", Hash: sha256Hex("content")}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ex.verifyTransfer(tt.ctx, tt.action, remote)
+			if tt.mismatch {
+				if !errors.Is(err, ErrVerifyMismatch) {
+					t.Fatalf("got %v, want a mismatch", err)
+				}
+				if _, retryable := ClassifyError(err); !retryable {
+					t.Error("mismatch not retryable")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if verified := !tt.action.VerifiedAt.IsZero(); verified != tt.verified {
+				t.Errorf("verified = %v, want %v", verified, tt.verified)
+			}
+			wantMismatches := 0
+			if tt.mismatch {
+				wantMismatches = 1
+			}
+			if tt.action.VerifyMismatches != wantMismatches {
+				t.Errorf("VerifyMismatches = %d, want %d", tt.action.VerifyMismatches, wantMismatches)
+			}
+		})
+	}
+
+	// A destination that can't be read back fails the attempt
+	missing := &SyncAction{Action: cache.ActionUpload, RemotePath: "missing.txt", Hash: sha256Hex("content")}
+	if err := ex.verifyTransfer(verifying, missing, remote); err == nil || errors.Is(err, ErrVerifyMismatch) {
+		t.Errorf("got %v, want a read error", err)
+	}
+}
+
+func TestSyncResult_CountsVerification(t *testing.T) {
+	result := NewSyncResult(1)
+	now := time.Now()
+
+	// Verified after a corrupted first attempt
+	result.AddAction(&SyncAction{Action: cache.ActionUpload, Status: ActionStatusSuccess, VerifiedAt: now, VerifyMismatches: 1})
+	result.AddAction(&SyncAction{Action: cache.ActionDownload, Status: ActionStatusSuccess, VerifiedAt: now})
+	// Not verified
+	result.AddAction(&SyncAction{Action: cache.ActionUpload, Status: ActionStatusSuccess})
+	// Corrupted on every attempt
+	result.AddAction(&SyncAction{Action: cache.ActionDownload, Status: ActionStatusFailed, VerifyMismatches: 3})
+
+	if result.FilesVerified != 2 {
+		t.Errorf("FilesVerified = %d, want 2", result.FilesVerified)
+	}
+	if result.VerifyMismatches != 4 {
+		t.Errorf("VerifyMismatches = %d, want 4", result.VerifyMismatches)
+	}
+}
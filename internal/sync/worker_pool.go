@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/juste-un-gars/anemone_sync_windows/internal/cache"
 	"github.com/juste-un-gars/anemone_sync_windows/internal/smb"
@@ -16,6 +17,8 @@ type WorkerPool struct {
 	numWorkers int
 	logger     *zap.Logger
 	executor   *Executor
+	tuner      *concurrencyTuner // Adaptive limit of the running actions (nil = numWorkers)
+	interval   time.Duration     // Throughput measure period of the tuner
 
 	// Channels
 	jobs    chan *SyncJob
@@ -27,6 +30,7 @@ type WorkerPool struct {
 	stopped  bool
 	wg       sync.WaitGroup
 	cancels  []context.CancelFunc
+	tuneDone chan struct{} // Closed by Stop to end the tuner
 
 	// Statistics (atomic)
 	jobsSubmitted  int64
@@ -64,13 +68,34 @@ func NewWorkerPool(numWorkers int, executor *Executor, logger *zap.Logger) *Work
 		panic("executor cannot be nil")
 	}
 
+	// Adaptive parallelism: up to Max workers, numWorkers of them running at first
+	var tuner *concurrencyTuner
+	var interval time.Duration
+	if adaptive := executor.adaptive; adaptive != nil {
+		tuner = newConcurrencyTuner(numWorkers, *adaptive)
+		numWorkers = tuner.max
+		interval = adaptive.Interval
+		if interval <= 0 {
+			interval = DefaultAdaptiveInterval
+		}
+	}
+
+	// Bounded queues: Submit blocks once workers fall behind
+	queueSize := executor.queueSize
+	if queueSize <= 0 {
+		queueSize = numWorkers * 2 // Buffer for smoother flow
+	}
+
 	return &WorkerPool{
 		numWorkers: numWorkers,
 		logger:     logger,
 		executor:   executor,
-		jobs:       make(chan *SyncJob, numWorkers*2), // Buffer for smoother flow
-		results:    make(chan *SyncJobResult, numWorkers*2),
+		tuner:      tuner,
+		interval:   interval,
+		jobs:       make(chan *SyncJob, queueSize),
+		results:    make(chan *SyncJobResult, queueSize),
 		cancels:    make([]context.CancelFunc, 0),
+		tuneDone:   make(chan struct{}),
 	}
 }
 
@@ -100,6 +125,10 @@ func (wp *WorkerPool) Start(ctx context.Context) error {
 		go wp.worker(workerCtx, i)
 	}
 
+	if wp.tuner != nil {
+		go wp.tune(ctx)
+	}
+
 	return nil
 }
 
@@ -116,6 +145,7 @@ func (wp *WorkerPool) Stop() {
 
 	// Close jobs channel to signal no more jobs
 	close(wp.jobs)
+	close(wp.tuneDone)
 
 	wp.mu.Unlock()
 
@@ -173,6 +203,7 @@ func (wp *WorkerPool) Results() <-chan *SyncJobResult {
 
 // GetStats returns current worker pool statistics
 func (wp *WorkerPool) GetStats() WorkerPoolStats {
+	inFlight, peak, limit := wp.executor.budget.usage()
 	return WorkerPoolStats{
 		JobsSubmitted:  atomic.LoadInt64(&wp.jobsSubmitted),
 		JobsCompleted:  atomic.LoadInt64(&wp.jobsCompleted),
@@ -180,6 +211,12 @@ func (wp *WorkerPool) GetStats() WorkerPoolStats {
 		JobsFailed:     atomic.LoadInt64(&wp.jobsFailed),
 		BytesProcessed: atomic.LoadInt64(&wp.bytesProcessed),
 		NumWorkers:     wp.numWorkers,
+		ActiveLimit:    wp.activeLimit(),
+		QueuedJobs:     len(wp.jobs),
+		QueueCapacity:  cap(wp.jobs),
+		InFlightBytes:  inFlight,
+		PeakInFlight:   peak,
+		InFlightLimit:  limit,
 	}
 }
 
@@ -191,6 +228,12 @@ type WorkerPoolStats struct {
 	JobsFailed     int64
 	BytesProcessed int64
 	NumWorkers     int
+	ActiveLimit    int   // Actions allowed at once (NumWorkers unless adaptive)
+	QueuedJobs     int   // Jobs waiting in the bounded queue
+	QueueCapacity  int   // Size of the bounded queue
+	InFlightBytes  int64 // Memory currently reserved by running actions
+	PeakInFlight   int64 // Highest InFlightBytes seen
+	InFlightLimit  int64 // Memory ceiling (0 = unlimited)
 }
 
 // worker is the main worker goroutine
@@ -212,8 +255,28 @@ func (wp *WorkerPool) worker(ctx context.Context, workerID int) {
 				return
 			}
 
+			// Reserve memory for the action; blocks while the budget is exhausted
+			reserved, err := wp.executor.budget.acquire(ctx, wp.executor.actionMemoryCost(job.Decision))
+			if err != nil {
+				wp.results <- &SyncJobResult{JobID: job.ID, Error: err}
+				continue
+			}
+
+			// Wait for a slot under the adaptive limit
+			if wp.tuner != nil {
+				if err := wp.tuner.acquire(ctx); err != nil {
+					wp.executor.budget.release(reserved)
+					wp.results <- &SyncJobResult{JobID: job.ID, Error: err}
+					continue
+				}
+			}
+
 			// Process job
 			result := wp.processJob(ctx, workerID, job)
+			if wp.tuner != nil {
+				wp.tuner.release()
+			}
+			wp.executor.budget.release(reserved)
 
 			// Always try to send result (don't lose results due to context cancellation)
 			wp.results <- result
@@ -221,6 +284,44 @@ func (wp *WorkerPool) worker(ctx context.Context, workerID int) {
 	}
 }
 
+// activeLimit returns the number of actions allowed at once.
+func (wp *WorkerPool) activeLimit() int {
+	if wp.tuner == nil {
+		return wp.numWorkers
+	}
+	return wp.tuner.current()
+}
+
+// tune measures the throughput of the completed actions every interval and
+// adjusts the number of actions running at once, until the pool stops.
+func (wp *WorkerPool) tune(ctx context.Context) {
+	ticker := time.NewTicker(wp.interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	lastBytes := atomic.LoadInt64(&wp.bytesProcessed)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wp.tuneDone:
+			return
+		case now := <-ticker.C:
+			bytes := atomic.LoadInt64(&wp.bytesProcessed)
+			before := wp.tuner.current()
+			after := wp.tuner.observe(bytes-lastBytes, now.Sub(last))
+			if after != before {
+				wp.logger.Info("transfer concurrency adjusted",
+					zap.Int("from", before),
+					zap.Int("to", after),
+					zap.Int64("bytes_per_sec", int64(float64(bytes-lastBytes)/now.Sub(last).Seconds())),
+				)
+			}
+			last, lastBytes = now, bytes
+		}
+	}
+}
+
 // processJob processes a single job
 func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *SyncJob) *SyncJobResult {
 	wp.logger.Debug("processing job",
@@ -237,9 +338,12 @@ func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *SyncJob
 	atomic.AddInt64(&wp.jobsCompleted, 1)
 	if err != nil {
 		atomic.AddInt64(&wp.jobsFailed, 1)
+		action.Status = ActionStatusFailed
+		action.Error = err
 	} else {
 		atomic.AddInt64(&wp.jobsSucceeded, 1)
 		atomic.AddInt64(&wp.bytesProcessed, action.BytesTransferred)
+		action.Status = ActionStatusSuccess
 	}
 
 	return &SyncJobResult{
@@ -249,7 +353,9 @@ func (wp *WorkerPool) processJob(ctx context.Context, workerID int, job *SyncJob
 	}
 }
 
-// ExecuteParallel executes a batch of decisions in parallel using the worker pool
+// ExecuteParallel executes a batch of decisions in parallel using the worker pool.
+// A decision is submitted once the decisions it depends on have completed
+// (see dependencies.go).
 func ExecuteParallel(
 	ctx context.Context,
 	decisions []*cache.SyncDecision,
@@ -259,6 +365,20 @@ func ExecuteParallel(
 	progressFn ProgressCallback,
 	logger *zap.Logger,
 ) ([]*SyncAction, error) {
+	return executeParallel(ctx, decisions, smbClient, executor, numWorkers, progressFn, nil, logger)
+}
+
+// executeParallel is ExecuteParallel with incremental cache commits through batcher
+func executeParallel(
+	ctx context.Context,
+	decisions []*cache.SyncDecision,
+	smbClient *smb.SMBClient,
+	executor *Executor,
+	numWorkers int,
+	progressFn ProgressCallback,
+	batcher *actionBatcher,
+	logger *zap.Logger,
+) ([]*SyncAction, error) {
 
 	if len(decisions) == 0 {
 		return []*SyncAction{}, nil
@@ -283,6 +403,9 @@ func ExecuteParallel(
 		}
 	}
 
+	// Decisions become ready as their dependencies complete
+	run := buildActionGraph(decisions).start()
+
 	// Launch result collector goroutine
 	actions := make([]*SyncAction, len(decisions))
 	var collectorWg sync.WaitGroup
@@ -295,10 +418,13 @@ func ExecuteParallel(
 		var bytesTransferred int64
 
 		for result := range pool.Results() {
-			// Store action
+			// Store action and release its dependents
 			if result.JobID >= 0 && result.JobID < len(actions) {
 				actions[result.JobID] = result.Action
+				run.done(result.JobID)
 			}
+			countTransferred(result.Action)
+			batcher.add(result.Action)
 
 			completed++
 			if result.Action != nil {
@@ -307,6 +433,7 @@ func ExecuteParallel(
 
 			// Report progress
 			if progressFn != nil {
+				stats := pool.GetStats()
 				progressFn(&SyncProgress{
 					Phase:            "executing",
 					FilesProcessed:   completed,
@@ -314,6 +441,9 @@ func ExecuteParallel(
 					BytesTransferred: bytesTransferred,
 					BytesTotal:       bytesTotal,
 					Percentage:       35 + float64(completed)/float64(len(decisions))*60, // 35-95%
+					QueuedActions:    stats.QueuedJobs,
+					InFlightBytes:    stats.InFlightBytes,
+					InFlightLimit:    stats.InFlightLimit,
 				})
 			}
 
@@ -327,11 +457,20 @@ func ExecuteParallel(
 		}
 	}()
 
-	// Submit all jobs
-	for i, decision := range decisions {
+	// Submit all jobs, each once it is ready
+	for submitted := 0; submitted < len(decisions); submitted++ {
+		var i int
+		select {
+		case i = <-run.ready:
+		case <-ctx.Done():
+			pool.Stop()
+			collectorWg.Wait()
+			return actions, ctx.Err()
+		}
+
 		job := &SyncJob{
 			ID:        i,
-			Decision:  decision,
+			Decision:  decisions[i],
 			SMBClient: smbClient,
 		}
 
@@ -355,6 +494,7 @@ func ExecuteParallel(
 		zap.Int64("succeeded", stats.JobsSucceeded),
 		zap.Int64("failed", stats.JobsFailed),
 		zap.Int64("bytes", stats.BytesProcessed),
+		zap.Int64("peak_in_flight", stats.PeakInFlight),
 	)
 
 	return actions, nil
@@ -420,3 +420,37 @@ func TestSetParallelMode(t *testing.T) {
 		t.Errorf("expected 0 workers for negative value, got %d", executor.numWorkers)
 	}
 }
+
+func TestWorkerPoolAdaptive(t *testing.T) {
+	executor := NewExecutor(4, zap.NewNop())
+	executor.SetRetryPolicy(NoRetryPolicy())
+	executor.SetAdaptiveParallelism(AdaptiveParallelism{Min: 1, Max: 6, Interval: 10 * time.Millisecond})
+
+	pool := NewWorkerPool(2, executor, zap.NewNop())
+	stats := pool.GetStats()
+	if stats.NumWorkers != 6 {
+		t.Errorf("expected 6 workers (adaptive max), got %d", stats.NumWorkers)
+	}
+	if stats.ActiveLimit != 2 {
+		t.Errorf("expected 2 actions allowed at first, got %d", stats.ActiveLimit)
+	}
+
+	decisions := make([]*cache.SyncDecision, 20)
+	for i := range decisions {
+		decisions[i] = &cache.SyncDecision{
+			LocalPath:  fmt.Sprintf("file%d.txt", i),
+			RemotePath: fmt.Sprintf("file%d.txt", i),
+			Action:     cache.ActionNone,
+		}
+	}
+
+	actions, err := ExecuteParallel(context.Background(), decisions, nil, executor, 2, nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("parallel execution failed: %v", err)
+	}
+	for i, action := range actions {
+		if action == nil {
+			t.Errorf("action %d is nil", i)
+		}
+	}
+}
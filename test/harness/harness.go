@@ -25,6 +25,9 @@ type Harness struct {
 	Reporter *Reporter
 	Verbose  bool
 
+	// Simulated slow link for the harness SMB operations (zero = disabled)
+	Network NetworkSimulation
+
 	// Direct SMB connection for file operations (setup/cleanup)
 	smbConn    net.Conn
 	smbSession *smb2.Session
@@ -226,6 +229,7 @@ func (h *Harness) connectSMB() error {
 	if err != nil {
 		return fmt.Errorf("cannot connect to %s:445: %w", h.Config.RemoteHost, err)
 	}
+	conn = newSlowConn(conn, h.Network)
 	h.smbConn = conn
 
 	// Create session
@@ -672,6 +676,7 @@ func (h *Harness) hashRemoteFile(path string) (string, error) {
 // executeActions executes a list of test actions.
 func (h *Harness) executeActions(ctx context.Context, job string, actions []Action) error {
 	writer := NewWriter(h.Config, h.smbShare, h.Config.UseMappedDrive)
+	writer.network = h.Network
 
 	for _, action := range actions {
 		if ctx.Err() != nil {
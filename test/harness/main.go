@@ -18,6 +18,8 @@ func Main() {
 		scenarioFilter = flag.String("scenario", "", "Run only this scenario (1.1, 2.3, etc.)")
 		verbose        = flag.Bool("v", false, "Verbose output")
 		listOnly       = flag.Bool("list", false, "List all scenarios without running")
+		latency        = flag.Duration("latency", 0, "Simulated network latency per SMB request (e.g. 50ms)")
+		bandwidth      = flag.Int("bandwidth", 0, "Simulated network bandwidth in KB/s (0 = unlimited)")
 	)
 	flag.Parse()
 
@@ -43,12 +45,18 @@ func Main() {
 		}
 	}
 
+	network := NetworkSimulation{Latency: *latency, BandwidthKBps: *bandwidth}
+	if network.Enabled() {
+		fmt.Printf("Réseau lent simulé: %s\n", network)
+	}
+
 	// Create harness
 	harness, err := New(cfg, *verbose)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Erreur création harness: %v\n", err)
 		os.Exit(1)
 	}
+	harness.Network = network
 	defer harness.Close()
 
 	// Test connection
@@ -77,6 +85,7 @@ func Main() {
 				fmt.Fprintf(os.Stderr, "Erreur création harness: %v\n", err)
 				os.Exit(1)
 			}
+			harness.Network = network
 			if err := harness.TestConnection(); err != nil {
 				fmt.Fprintf(os.Stderr, "Erreur connexion: %v\n", err)
 				os.Exit(1)
@@ -0,0 +1,76 @@
+package harness
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// NetworkSimulation slows down the harness SMB operations, so that
+// timing-sensitive behavior (recent-wins tiebreakers, debounce, progress and
+// ETA) can be reproduced deterministically on a fast LAN.
+type NetworkSimulation struct {
+	Latency       time.Duration // Added to every request sent to the server
+	BandwidthKBps int           // Throughput limit in KB/s (0 = unlimited)
+}
+
+// Enabled reports whether the simulation changes anything.
+func (n NetworkSimulation) Enabled() bool {
+	return n.Latency > 0 || n.BandwidthKBps > 0
+}
+
+// String describes the simulated link.
+func (n NetworkSimulation) String() string {
+	bandwidth := "illimité"
+	if n.BandwidthKBps > 0 {
+		bandwidth = fmt.Sprintf("%d Ko/s", n.BandwidthKBps)
+	}
+	return fmt.Sprintf("latence %v, débit %s", n.Latency, bandwidth)
+}
+
+// transferTime returns the time needed to move size bytes at the simulated bandwidth.
+func (n NetworkSimulation) transferTime(size int) time.Duration {
+	if n.BandwidthKBps <= 0 {
+		return 0
+	}
+	return time.Duration(int64(size) * int64(time.Second) / int64(n.BandwidthKBps*1024))
+}
+
+// wait sleeps for a request of size bytes: latency plus transfer time.
+func (n NetworkSimulation) wait(size int) {
+	if d := n.Latency + n.transferTime(size); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// slowConn applies a NetworkSimulation to a connection: every write pays the
+// latency, reads and writes are paced to the bandwidth.
+type slowConn struct {
+	net.Conn
+	network NetworkSimulation
+}
+
+// newSlowConn wraps conn, or returns it unchanged when the simulation is disabled.
+func newSlowConn(conn net.Conn, network NetworkSimulation) net.Conn {
+	if !network.Enabled() {
+		return conn
+	}
+	return &slowConn{Conn: conn, network: network}
+}
+
+// Write delays the data by the latency and its transfer time.
+func (c *slowConn) Write(p []byte) (int, error) {
+	c.network.wait(len(p))
+	return c.Conn.Write(p)
+}
+
+// Read paces received data to the bandwidth.
+func (c *slowConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if d := c.network.transferTime(n); d > 0 {
+			time.Sleep(d)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package harness
+
+import "fmt"
+
+// readPlaceholderState is not available outside Windows.
+func readPlaceholderState(fullPath string) (placeholderState, error) {
+	return placeholderState{}, fmt.Errorf("état Cloud Files disponible uniquement sous Windows")
+}
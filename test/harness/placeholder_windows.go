@@ -0,0 +1,18 @@
+//go:build windows
+
+package harness
+
+import "github.com/juste-un-gars/anemone_sync_windows/internal/cloudfiles"
+
+// readPlaceholderState returns the Cloud Files state of a local file.
+func readPlaceholderState(fullPath string) (placeholderState, error) {
+	state, err := cloudfiles.GetFilePlaceholderState(fullPath)
+	if err != nil {
+		return placeholderState{}, err
+	}
+	return placeholderState{
+		IsPlaceholder: state&cloudfiles.CF_PLACEHOLDER_STATE_PLACEHOLDER != 0,
+		IsPartial:     state&cloudfiles.CF_PLACEHOLDER_STATE_PARTIAL != 0,
+		InSync:        state&cloudfiles.CF_PLACEHOLDER_STATE_IN_SYNC != 0,
+	}, nil
+}
@@ -2,6 +2,7 @@ package harness
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -78,6 +79,7 @@ func getMirrorScenarios() []Scenario {
 				{Type: "file_exists", Side: "local", Path: "new_remote.txt", Expected: true},
 				{Type: "file_exists", Side: "remote", Path: "new_remote.txt", Expected: true},
 				{Type: "files_match", Side: "both", Path: "new_remote.txt"},
+				{Type: "placeholder_state", Side: "local", Path: "new_remote.txt", State: StateHydrated},
 			},
 		},
 		{
@@ -150,7 +152,7 @@ func getMirrorScenarios() []Scenario {
 		},
 		{
 			ID:          "1.7",
-			Name:        "Suppression remote + nouveau local (BUG CONNU)",
+			Name:        "Suppression remote + nouveau local",
 			Description: "Suppression d'un fichier remote ET création d'un nouveau local en même temps",
 			Job:         "TEST1",
 			Mode:        "mirror",
@@ -182,6 +184,60 @@ func getMirrorScenarios() []Scenario {
 				{Type: "files_match", Side: "both", Path: "subdir/nested/file.txt"},
 			},
 		},
+		{
+			ID:          "1.9",
+			Name:        "Renommage de casse local",
+			Description: "Un renommage qui ne change que la casse ne doit pas supprimer le fichier sur le serveur",
+			Job:         "TEST1",
+			Mode:        "mirror",
+			Setup: []Action{
+				{Type: "create", Side: "both", Path: "rapport.txt", Content: "rapport"},
+			},
+			Actions: []Action{
+				{Type: "rename", Side: "local", Path: "rapport.txt", NewPath: "Rapport.txt"},
+			},
+			Expect: []Expectation{
+				{Type: "file_exists", Side: "remote", Path: "Rapport.txt", Expected: true},
+				{Type: "content_equals", Side: "both", Path: "Rapport.txt", Content: "rapport"},
+			},
+		},
+		{
+			ID:          "1.10",
+			Name:        "Fichier remplacé par un dossier",
+			Description: "Suppression d'un fichier et création d'un dossier du même nom en même temps",
+			Job:         "TEST1",
+			Mode:        "mirror",
+			Setup: []Action{
+				{Type: "create", Side: "both", Path: "export", Content: "ancien export"},
+			},
+			Actions: []Action{
+				{Type: "delete", Side: "local", Path: "export"},
+				{Type: "create", Side: "local", Path: "export/data.csv", Content: "a;b;c"},
+			},
+			Expect: []Expectation{
+				{Type: "file_exists", Side: "remote", Path: "export/data.csv", Expected: true},
+				{Type: "files_match", Side: "both", Path: "export/data.csv"},
+			},
+		},
+		{
+			ID:          "1.11",
+			Name:        "Dossier remplacé par un fichier",
+			Description: "Suppression d'un dossier et création d'un fichier du même nom en même temps",
+			Job:         "TEST1",
+			Mode:        "mirror",
+			Setup: []Action{
+				{Type: "create", Side: "both", Path: "notes/a.txt", Content: "a"},
+				{Type: "create", Side: "both", Path: "notes/sub/b.txt", Content: "b"},
+			},
+			Actions: []Action{
+				{Type: "delete", Side: "local", Path: "notes"},
+				{Type: "create", Side: "local", Path: "notes", Content: "notes en un fichier"},
+			},
+			Expect: []Expectation{
+				{Type: "file_not_exists", Side: "remote", Path: "notes/a.txt"},
+				{Type: "content_equals", Side: "both", Path: "notes", Content: "notes en un fichier"},
+			},
+		},
 	}
 }
 
@@ -266,6 +322,7 @@ func getPullScenarios() []Scenario {
 			Expect: []Expectation{
 				{Type: "file_exists", Side: "local", Path: "pull_file.txt", Expected: true},
 				{Type: "files_match", Side: "both", Path: "pull_file.txt"},
+				{Type: "placeholder_state", Side: "local", Path: "pull_file.txt", State: StateHydrated},
 			},
 		},
 		{
@@ -543,7 +600,54 @@ func getEdgeCaseScenarios() []Scenario {
 				{Type: "files_match", Side: "both", Path: ".hidden/secret.txt"},
 			},
 		},
+		{
+			ID:          "6.7",
+			Name:        "Chemin long local (400 caractères)",
+			Description: "Fichier local au-delà de MAX_PATH (260) dans des dossiers imbriqués",
+			Job:         "TEST6",
+			Mode:        "mirror",
+			Actions: []Action{
+				{Type: "create", Side: "local", Path: longLocalPath, Content: "chemin long local"},
+			},
+			Expect: []Expectation{
+				{Type: "file_exists", Side: "remote", Path: longLocalPath, Expected: true},
+				{Type: "files_match", Side: "both", Path: longLocalPath},
+			},
+		},
+		{
+			ID:          "6.8",
+			Name:        "Chemin long remote (400 caractères)",
+			Description: "Fichier distant au-delà de MAX_PATH (260) téléchargé dans des dossiers imbriqués",
+			Job:         "TEST6",
+			Mode:        "mirror",
+			Actions: []Action{
+				{Type: "create", Side: "remote", Path: longRemotePath, Content: "chemin long remote"},
+			},
+			Expect: []Expectation{
+				{Type: "file_exists", Side: "local", Path: longRemotePath, Expected: true},
+				{Type: "files_match", Side: "both", Path: longRemotePath},
+			},
+		},
+	}
+}
+
+// Paths of 400 characters relative to the job folder, beyond MAX_PATH once
+// joined to it
+var (
+	longLocalPath  = longPath("long_local", 400)
+	longRemotePath = longPath("long_remote", 400)
+)
+
+// longPath returns a relative path of exactly length characters, made of
+// nested folders under root and ending with a file.
+func longPath(root string, length int) string {
+	const folder = "/dossier_imbrique_0123456789"
+	const file = "/fichier.txt"
+	path := root
+	for len(path)+len(folder)+len(file) <= length {
+		path += folder
 	}
+	return path + "/fichier" + strings.Repeat("_", length-len(path)-len(file)) + ".txt"
 }
 
 // getResilienceScenarios returns TEST7 scenarios (network resilience - interactive).
@@ -10,11 +10,61 @@ import (
 
 // Expectation defines what we expect after a sync.
 type Expectation struct {
-	Type     string `json:"type"`     // file_exists, file_not_exists, content_equals, files_match
-	Side     string `json:"side"`     // local, remote, both
-	Path     string `json:"path"`     // file path
-	Content  string `json:"content"`  // expected content (for content_equals)
-	Expected bool   `json:"expected"` // expected result (for exists checks)
+	Type     string `json:"type"`            // file_exists, file_not_exists, content_equals, files_match, placeholder_state
+	Side     string `json:"side"`            // local, remote, both
+	Path     string `json:"path"`            // file path
+	Content  string `json:"content"`         // expected content (for content_equals)
+	Expected bool   `json:"expected"`        // expected result (for exists checks)
+	State    string `json:"state,omitempty"` // expected Cloud Files state (for placeholder_state, local only)
+}
+
+// Cloud Files states checked by placeholder_state expectations.
+const (
+	StatePlaceholder    = "placeholder"     // File is a placeholder (hydrated or not)
+	StateNotPlaceholder = "not_placeholder" // Regular file
+	StateHydrated       = "hydrated"        // Content is on disk (regular file or full placeholder)
+	StateDehydrated     = "dehydrated"      // Placeholder without its content
+	StateInSync         = "in_sync"         // Placeholder marked in sync with the server
+)
+
+// placeholderState is the Cloud Files state of a local file.
+type placeholderState struct {
+	IsPlaceholder bool
+	IsPartial     bool
+	InSync        bool
+}
+
+// String describes the state for reports.
+func (s placeholderState) String() string {
+	if !s.IsPlaceholder {
+		return StateNotPlaceholder
+	}
+	desc := StatePlaceholder + "," + StateHydrated
+	if s.IsPartial {
+		desc = StatePlaceholder + "," + StateDehydrated
+	}
+	if s.InSync {
+		desc += "," + StateInSync
+	}
+	return desc
+}
+
+// matches reports whether the state satisfies the expected one.
+func (s placeholderState) matches(expected string) (bool, error) {
+	switch expected {
+	case StatePlaceholder:
+		return s.IsPlaceholder, nil
+	case StateNotPlaceholder:
+		return !s.IsPlaceholder, nil
+	case StateHydrated:
+		return !s.IsPlaceholder || !s.IsPartial, nil
+	case StateDehydrated:
+		return s.IsPlaceholder && s.IsPartial, nil
+	case StateInSync:
+		return s.IsPlaceholder && s.InSync, nil
+	default:
+		return false, fmt.Errorf("unknown placeholder state: %s", expected)
+	}
 }
 
 // Validation represents a validation result.
@@ -90,6 +140,11 @@ func (v *Validator) validateSide(job string, exp Expectation, side string) ([]Va
 		return v.validateContentEquals(job, exp.Path, side, exp.Content)
 	case "files_match":
 		return v.validateFilesMatch(job, exp.Path)
+	case "placeholder_state":
+		if side != "local" {
+			return nil, fmt.Errorf("placeholder_state only applies to the local side")
+		}
+		return v.validatePlaceholderState(job, exp.Path, exp.State)
 	default:
 		return nil, fmt.Errorf("unknown expectation type: %s", exp.Type)
 	}
@@ -298,6 +353,42 @@ func (v *Validator) validateFilesMatch(job, path string) ([]Validation, error) {
 	return []Validation{validation}, resultErr
 }
 
+// validatePlaceholderState checks the Cloud Files state of a local file.
+func (v *Validator) validatePlaceholderState(job, path, expected string) ([]Validation, error) {
+	fullPath := filepath.Join(v.config.LocalPath(job), path)
+	state, err := readPlaceholderState(fullPath)
+	if err != nil {
+		return []Validation{{
+			Check:    "placeholder_state",
+			Path:     path,
+			Side:     "local",
+			Expected: expected,
+			Actual:   fmt.Sprintf("error: %v", err),
+			Passed:   false,
+		}}, err
+	}
+
+	passed, err := state.matches(expected)
+	if err != nil {
+		return nil, err
+	}
+	validation := Validation{
+		Check:    "placeholder_state",
+		Path:     path,
+		Side:     "local",
+		Expected: expected,
+		Actual:   state.String(),
+		Passed:   passed,
+	}
+
+	var resultErr error
+	if !passed {
+		resultErr = fmt.Errorf("fichier %s devrait être %s mais est %s", path, expected, state)
+	}
+
+	return []Validation{validation}, resultErr
+}
+
 // ListLocalFiles returns all files in a local directory.
 func (v *Validator) ListLocalFiles(job string) ([]string, error) {
 	var files []string
@@ -25,6 +25,7 @@ type Writer struct {
 	config         *Config
 	smbShare       *smb2.Share
 	useMappedDrive bool
+	network        NetworkSimulation // Applied to remote operations on a mapped drive
 }
 
 // NewWriter creates a new writer.
@@ -65,6 +66,9 @@ func (w *Writer) executeRemoteAsLocal(job string, action Action) error {
 	basePath := w.config.RemotePathForJob(job)
 	fullPath := filepath.Join(basePath, action.Path)
 
+	// A mapped drive bypasses the harness SMB connection: simulate the link here
+	w.network.wait(len(action.Content))
+
 	switch action.Type {
 	case "create", "modify":
 		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {